@@ -20,6 +20,10 @@ import (
 // swagger:model DfGetTask
 type DfGetTask struct {
 
+	// This attribute represents the peer applies for being a seed node of the task.
+	//
+	AsSeed bool `json:"asSeed,omitempty"`
+
 	// CID means the client ID. It maps to the specific dfget process.
 	// When user wishes to download an image/file, user would start a dfget process to do this.
 	// This dfget is treated a client and carries a client ID.
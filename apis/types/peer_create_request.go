@@ -20,6 +20,11 @@ import (
 // swagger:model PeerCreateRequest
 type PeerCreateRequest struct {
 
+	// The data center which the peer locates in. It's used to select the
+	// target peers of a preheat task by IDC.
+	//
+	IDC string `json:"IDC,omitempty"`
+
 	// IP address which peer client carries
 	// Format: ipv4
 	IP strfmt.IPv4 `json:"IP,omitempty"`
@@ -29,6 +34,11 @@ type PeerCreateRequest struct {
 	// Format: hostname
 	HostName strfmt.Hostname `json:"hostName,omitempty"`
 
+	// The labels of the peer host. They're used to select the
+	// target peers of a preheat task by labels.
+	//
+	Labels map[string]string `json:"labels,omitempty"`
+
 	// when registering, dfget will setup one uploader process.
 	// This one acts as a server for peer pulling tasks.
 	// This port is which this server listens on.
@@ -37,6 +47,16 @@ type PeerCreateRequest struct {
 	// Minimum: 15000
 	Port int32 `json:"port,omitempty"`
 
+	// The tenant which the peer belongs to. It's taken from the X-Dragonfly-Tenant
+	// or X-Dragonfly-Tenant-Token header, and empty means the default tenant.
+	//
+	Tenant string `json:"tenant,omitempty"`
+
+	// The bandwidth in bytes per second which the peer serves the other peers with,
+	// and 0 means unknown.
+	//
+	UploadRate int64 `json:"uploadRate,omitempty"`
+
 	// version number of dfget binary.
 	Version string `json:"version,omitempty"`
 }
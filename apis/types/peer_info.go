@@ -21,6 +21,11 @@ type PeerInfo struct {
 	// ID of peer
 	ID string `json:"ID,omitempty"`
 
+	// The data center which the peer locates in. It's used to select the
+	// target peers of a preheat task by IDC.
+	//
+	IDC string `json:"IDC,omitempty"`
+
 	// IP address which peer client carries.
 	// (TODO) make IP field contain more information, for example
 	// WAN/LAN IP address for supernode to recognize.
@@ -37,6 +42,11 @@ type PeerInfo struct {
 	// Format: hostname
 	HostName strfmt.Hostname `json:"hostName,omitempty"`
 
+	// The labels of the peer host. They're used to select the
+	// target peers of a preheat task by labels.
+	//
+	Labels map[string]string `json:"labels,omitempty"`
+
 	// when registering, dfget will setup one uploader process.
 	// This one acts as a server for peer pulling tasks.
 	// This port is which this server listens on.
@@ -45,6 +55,19 @@ type PeerInfo struct {
 	// Minimum: 15000
 	Port int32 `json:"port,omitempty"`
 
+	// The health score in [0, 1] of the peer serving the other peers.
+	// It's omitted if the peer hasn't downloaded any task from supernode.
+	//
+	Score *float64 `json:"score,omitempty"`
+
+	// the tenant which the peer belongs to
+	Tenant string `json:"tenant,omitempty"`
+
+	// The bandwidth in bytes per second which the peer serves the other peers with,
+	// and 0 means unknown.
+	//
+	UploadRate int64 `json:"uploadRate,omitempty"`
+
 	// version number of dfget binary
 	Version string `json:"version,omitempty"`
 }
@@ -27,9 +27,13 @@ type PieceErrorRequest struct {
 	//
 	DstPid string `json:"dstPid,omitempty"`
 
-	// the error type when failed to download from supernode that dfget will report to supernode
+	// the error type when failed to download a piece that dfget will report to supernode.
+	// TIMEOUT, CONNECTION_REFUSED, SHORT_READ and SERVER_ERROR are the network errors
+	// or the HTTP 5xx errors of the target peer.
+	// PIECE_SLOW means that a hedged request to another peer completed first,
+	// which isn't a failure of the target peer.
 	//
-	// Enum: [FILE_NOT_EXIST FILE_MD5_NOT_MATCH]
+	// Enum: [FILE_NOT_EXIST FILE_MD5_NOT_MATCH TIMEOUT CONNECTION_REFUSED SHORT_READ SERVER_ERROR PIECE_SLOW]
 	ErrorType string `json:"errorType,omitempty"`
 
 	// the MD5 value of piece which returned by the supernode that
@@ -74,7 +78,7 @@ var pieceErrorRequestTypeErrorTypePropEnum []interface{}
 
 func init() {
 	var res []string
-	if err := json.Unmarshal([]byte(`["FILE_NOT_EXIST","FILE_MD5_NOT_MATCH"]`), &res); err != nil {
+	if err := json.Unmarshal([]byte(`["FILE_NOT_EXIST","FILE_MD5_NOT_MATCH","TIMEOUT","CONNECTION_REFUSED","SHORT_READ","SERVER_ERROR","PIECE_SLOW"]`), &res); err != nil {
 		panic(err)
 	}
 	for _, v := range res {
@@ -89,6 +93,21 @@ const (
 
 	// PieceErrorRequestErrorTypeFILEMD5NOTMATCH captures enum value "FILE_MD5_NOT_MATCH"
 	PieceErrorRequestErrorTypeFILEMD5NOTMATCH string = "FILE_MD5_NOT_MATCH"
+
+	// PieceErrorRequestErrorTypeTIMEOUT captures enum value "TIMEOUT"
+	PieceErrorRequestErrorTypeTIMEOUT string = "TIMEOUT"
+
+	// PieceErrorRequestErrorTypeCONNECTIONREFUSED captures enum value "CONNECTION_REFUSED"
+	PieceErrorRequestErrorTypeCONNECTIONREFUSED string = "CONNECTION_REFUSED"
+
+	// PieceErrorRequestErrorTypeSHORTREAD captures enum value "SHORT_READ"
+	PieceErrorRequestErrorTypeSHORTREAD string = "SHORT_READ"
+
+	// PieceErrorRequestErrorTypeSERVERERROR captures enum value "SERVER_ERROR"
+	PieceErrorRequestErrorTypeSERVERERROR string = "SERVER_ERROR"
+
+	// PieceErrorRequestErrorTypePIECESLOW captures enum value "PIECE_SLOW"
+	PieceErrorRequestErrorTypePIECESLOW string = "PIECE_SLOW"
 )
 
 // prop value enum
@@ -6,8 +6,11 @@ package types
 // Editing this file might prove futile when you re-run the swagger generate command
 
 import (
+	"strconv"
+
 	strfmt "github.com/go-openapi/strfmt"
 
+	"github.com/go-openapi/errors"
 	"github.com/go-openapi/swag"
 )
 
@@ -15,6 +18,12 @@ import (
 // swagger:model PieceInfo
 type PieceInfo struct {
 
+	// The alternative peers which also have the piece. When downloading the piece from
+	// the target peer takes too long, dfget can send a hedged request to one of them.
+	// Only pID, peerIP, peerPort and path are set in the alternatives.
+	//
+	Alternates []*PieceInfo `json:"alternates"`
+
 	// the peerID that dfget task should download from
 	PID string `json:"pID,omitempty"`
 
@@ -50,6 +59,40 @@ type PieceInfo struct {
 
 // Validate validates this piece info
 func (m *PieceInfo) Validate(formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.validateAlternates(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *PieceInfo) validateAlternates(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Alternates) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.Alternates); i++ {
+		if swag.IsZero(m.Alternates[i]) { // not required
+			continue
+		}
+
+		if m.Alternates[i] != nil {
+			if err := m.Alternates[i].Validate(formats); err != nil {
+				if ve, ok := err.(*errors.Validation); ok {
+					return ve.ValidateName("alternates" + "." + strconv.Itoa(i))
+				}
+				return err
+			}
+		}
+
+	}
+
 	return nil
 }
 
@@ -7,6 +7,7 @@ package types
 
 import (
 	"encoding/json"
+	"strconv"
 
 	strfmt "github.com/go-openapi/strfmt"
 
@@ -35,6 +36,21 @@ type PreheatCreateRequest struct {
 	//
 	Identifier string `json:"identifier,omitempty"`
 
+	// The selector of the target peers which download the file in the background after it's
+	// preheated into the supernode CDN. Only the supernode CDN is preheated if it's empty.
+	//
+	Peers *PreheatPeerSelector `json:"peers,omitempty"`
+
+	// The platforms of the images to be preheated in the format of os/architecture[/variant],
+	// e.g. linux/arm64/v8. Only the images of the platforms in a multi-platform image are
+	// preheated, and all of them are preheated if it's empty.
+	//
+	Platforms []string `json:"platforms"`
+
+	// Preheat the referrers of the images too, such as the signatures and the SBOMs.
+	//
+	Referrers bool `json:"referrers,omitempty"`
+
 	// this must be image or file
 	//
 	// Required: true
@@ -51,6 +67,14 @@ type PreheatCreateRequest struct {
 func (m *PreheatCreateRequest) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validatePeers(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validatePlatforms(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateType(formats); err != nil {
 		res = append(res, err)
 	}
@@ -65,6 +89,41 @@ func (m *PreheatCreateRequest) Validate(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *PreheatCreateRequest) validatePeers(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Peers) { // not required
+		return nil
+	}
+
+	if m.Peers != nil {
+		if err := m.Peers.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("peers")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *PreheatCreateRequest) validatePlatforms(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Platforms) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.Platforms); i++ {
+
+		if err := validate.Pattern("platforms"+"."+strconv.Itoa(i), "body", string(m.Platforms[i]), `^[^/]+/[^/]+(/[^/]+)?$`); err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+}
+
 var preheatCreateRequestTypeTypePropEnum []interface{}
 
 func init() {
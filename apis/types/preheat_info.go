@@ -6,6 +6,8 @@ package types
 // Editing this file might prove futile when you re-run the swagger generate command
 
 import (
+	"strconv"
+
 	strfmt "github.com/go-openapi/strfmt"
 
 	"github.com/go-openapi/errors"
@@ -23,10 +25,33 @@ type PreheatInfo struct {
 	//
 	ID string `json:"ID,omitempty"`
 
+	// the structured error of preheat task when failed
+	Error *PreheatError `json:"error,omitempty"`
+
 	// the preheat task finish time
 	// Format: date-time
 	FinishTime strfmt.DateTime `json:"finishTime,omitempty"`
 
+	// The status of the blobs preheated by an image preheat task, including the layers
+	// and the configs of the images and the blobs of the referrers.
+	//
+	Layers []*PreheatLayerStatus `json:"layers"`
+
+	// The count of the pieces which have been downloaded into the supernode CDN.
+	// For an image preheat task, it's the sum of all its layers.
+	//
+	FinishedPieceCount int32 `json:"finishedPieceCount,omitempty"`
+
+	// The total count of the pieces to be preheated. It's 0 when the length
+	// of the source file is unknown yet.
+	//
+	PieceTotal int32 `json:"pieceTotal,omitempty"`
+
+	// The status of the target peers. For an image preheat task, a peer is SUCCESS
+	// when all the layers are downloaded by it, and FAILED when any layer fails.
+	//
+	Peers []*PreheatPeerStatus `json:"peers"`
+
 	// the preheat task start time
 	// Format: date-time
 	StartTime strfmt.DateTime `json:"startTime,omitempty"`
@@ -48,10 +73,22 @@ type PreheatInfo struct {
 func (m *PreheatInfo) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateError(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateFinishTime(formats); err != nil {
 		res = append(res, err)
 	}
 
+	if err := m.validateLayers(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validatePeers(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateStartTime(formats); err != nil {
 		res = append(res, err)
 	}
@@ -66,6 +103,24 @@ func (m *PreheatInfo) Validate(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *PreheatInfo) validateError(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Error) { // not required
+		return nil
+	}
+
+	if m.Error != nil {
+		if err := m.Error.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("error")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (m *PreheatInfo) validateFinishTime(formats strfmt.Registry) error {
 
 	if swag.IsZero(m.FinishTime) { // not required
@@ -79,6 +134,56 @@ func (m *PreheatInfo) validateFinishTime(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *PreheatInfo) validateLayers(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Layers) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.Layers); i++ {
+		if swag.IsZero(m.Layers[i]) { // not required
+			continue
+		}
+
+		if m.Layers[i] != nil {
+			if err := m.Layers[i].Validate(formats); err != nil {
+				if ve, ok := err.(*errors.Validation); ok {
+					return ve.ValidateName("layers" + "." + strconv.Itoa(i))
+				}
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
+func (m *PreheatInfo) validatePeers(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Peers) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.Peers); i++ {
+		if swag.IsZero(m.Peers[i]) { // not required
+			continue
+		}
+
+		if m.Peers[i] != nil {
+			if err := m.Peers[i].Validate(formats); err != nil {
+				if ve, ok := err.(*errors.Validation); ok {
+					return ve.ValidateName("peers" + "." + strconv.Itoa(i))
+				}
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
 func (m *PreheatInfo) validateStartTime(formats strfmt.Registry) error {
 
 	if swag.IsZero(m.StartTime) { // not required
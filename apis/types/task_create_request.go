@@ -18,6 +18,11 @@ import (
 // swagger:model TaskCreateRequest
 type TaskCreateRequest struct {
 
+	// This attribute represents that the peer applies for being a seed node of the task,
+	// which means the peer is a long-lived process or already has the resource.
+	//
+	AsSeed bool `json:"asSeed,omitempty"`
+
 	// CID means the client ID. It maps to the specific dfget process.
 	// When user wishes to download an image/file, user would start a dfget process to do this.
 	// This dfget is treated a client and carries a client ID.
@@ -102,6 +107,11 @@ type TaskCreateRequest struct {
 	// --filter parameter of dfget. The usage of it is that different rawURL may generate the same taskID.
 	//
 	TaskURL string `json:"taskURL,omitempty"`
+
+	// The tenant of the peer which creates the task. The peers are only scheduled to
+	// download pieces from the peers of the same tenant unless both tenants are shared.
+	//
+	Tenant string `json:"tenant,omitempty"`
 	// peer Pattern p2p or cdn
 	PeerPattern config.Pattern `json:"peerPattern,omitempty"`
 }
@@ -19,6 +19,11 @@ type TaskCreateResponse struct {
 	// ID of the created task.
 	ID string `json:"ID,omitempty"`
 
+	// This attribute represents that the peer has been elected as a seed node of the task,
+	// and the other peers will download the pieces from it first.
+	//
+	AsSeed bool `json:"asSeed,omitempty"`
+
 	// cdn source
 	CdnSource CdnSource `json:"cdnSource,omitempty"`
 
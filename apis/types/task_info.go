@@ -86,6 +86,11 @@ type TaskInfo struct {
 	// --filter parameter of dfget. The usage of it is that different rawURL may generate the same taskID.
 	//
 	TaskURL string `json:"taskURL,omitempty"`
+
+	// The tenant which creates the task, and the CDN disk usage of the task
+	// is counted in the quota of it.
+	//
+	Tenant string `json:"tenant,omitempty"`
 }
 
 // Validate validates this task info
@@ -17,6 +17,11 @@ import (
 // swagger:model TaskRegisterRequest
 type TaskRegisterRequest struct {
 
+	// The data center which the peer locates in. It's used to select the
+	// target peers of a preheat task by IDC.
+	//
+	IDC string `json:"IDC,omitempty"`
+
 	// IP address which peer client carries
 	// Format: ipv4
 	IP strfmt.IPv4 `json:"IP,omitempty"`
@@ -39,6 +44,12 @@ type TaskRegisterRequest struct {
 	// Min Length: 1
 	CallSystem string `json:"callSystem,omitempty"`
 
+	// tells whether the client supports the supernode cluster. Only the client
+	// supporting it is redirected to the supernode which owns the task, and the
+	// others are served by the supernode they register to.
+	//
+	Cluster bool `json:"cluster,omitempty"`
+
 	// tells whether it is a call from dfdaemon. dfdaemon is a long running
 	// process which works for container engines. It translates the image
 	// pulling request into raw requests into those dfget recognizes.
@@ -62,6 +73,11 @@ type TaskRegisterRequest struct {
 	// Min Length: 1
 	HostName string `json:"hostName,omitempty"`
 
+	// The labels of the peer host. They're used to select the
+	// target peers of a preheat task by labels.
+	//
+	Labels map[string]string `json:"labels,omitempty"`
+
 	// special attribute of remote source file. This field is used with taskURL to generate new taskID to
 	// identify different downloading task of remote source file. For example, if user A and user B uses
 	// the same taskURL and taskID to download file, A and B will share the same peer network to distribute files.
@@ -117,6 +133,11 @@ type TaskRegisterRequest struct {
 	//
 	TaskURL string `json:"taskURL,omitempty"`
 
+	// The bandwidth in bytes per second which the peer serves the other peers with.
+	// Supernode prefers the peers with more bandwidth as seed nodes, and 0 means unknown.
+	//
+	UploadRate int64 `json:"uploadRate,omitempty"`
+
 	// version number of dfget binary.
 	Version string `json:"version,omitempty"`
 
@@ -42,6 +42,8 @@ type APIClient struct {
 	HTTPCli *http.Client
 	// version of the server talks to
 	version string
+	// authToken is sent as a bearer token if it's not empty.
+	authToken string
 }
 
 // TLSConfig contains information of TLS which users can specify.
@@ -84,6 +86,17 @@ func NewAPIClient(host string, tls TLSConfig) (CommonAPIClient, error) {
 	}, nil
 }
 
+// NewAPIClientWithAuthToken initializes a new API client for the given host
+// which authenticates itself with the token.
+func NewAPIClientWithAuthToken(host string, tls TLSConfig, token string) (CommonAPIClient, error) {
+	cli, err := NewAPIClient(host, tls)
+	if err != nil {
+		return nil, err
+	}
+	cli.(*APIClient).authToken = token
+	return cli, nil
+}
+
 // generateTLSConfig configures TLS for API Client.
 func generateTLSConfig(host string, tls TLSConfig) *tls.Config {
 	// init tls config
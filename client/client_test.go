@@ -17,7 +17,11 @@
 package client
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -42,3 +46,20 @@ func TestNewAPIClient(t *testing.T) {
 		t.Logf("client info %+v", cli)
 	}
 }
+
+func TestNewAPIClientWithAuthToken(t *testing.T) {
+	cli, err := NewAPIClientWithAuthToken("http://localhost:2476", TLSConfig{}, "foo")
+	assert.NoError(t, err)
+
+	apiClient := cli.(*APIClient)
+	apiClient.HTTPCli = newMockClient(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "Bearer foo", req.Header.Get("Authorization"))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte("OK"))),
+		}, nil
+	})
+
+	_, err = apiClient.Ping(context.Background())
+	assert.NoError(t, err)
+}
@@ -27,12 +27,27 @@ type CommonAPIClient interface {
 	PreheatAPIClient
 	PeerAPIClient
 	TaskAPIClient
+	SystemAPIClient
+}
+
+// SystemAPIClient defines methods of supernode system related client.
+type SystemAPIClient interface {
+	Ping(ctx context.Context) (string, error)
+	Version(ctx context.Context) (*types.DragonflyVersion, error)
+	Metrics(ctx context.Context) (string, error)
 }
 
 // PreheatAPIClient defines methods of Container client.
 type PreheatAPIClient interface {
 	PreheatCreate(ctx context.Context, request *types.PreheatCreateRequest) (preheatCreateResponse *types.PreheatCreateResponse, err error)
 	PreheatInfo(ctx context.Context, id string) (preheatInfoResponse *types.PreheatInfo, err error)
+	PreheatList(ctx context.Context) ([]*types.PreheatInfo, error)
+	PreheatDelete(ctx context.Context, id string) error
+	PreheatScheduleCreate(ctx context.Context, request *types.PreheatScheduleCreateRequest) (*types.PreheatSchedule, error)
+	PreheatScheduleInfo(ctx context.Context, id string) (*types.PreheatSchedule, error)
+	PreheatScheduleList(ctx context.Context) ([]*types.PreheatSchedule, error)
+	PreheatScheduleDelete(ctx context.Context, id string) error
+	PreheatScheduleEnable(ctx context.Context, id string, enable bool) (*types.PreheatSchedule, error)
 }
 
 // PeerAPIClient defines methods of peer related client.
@@ -40,13 +55,16 @@ type PeerAPIClient interface {
 	PeerCreate(ctx context.Context, request *types.PeerCreateRequest) (peerCreateResponse *types.PeerCreateResponse, err error)
 	PeerDelete(ctx context.Context, id string) error
 	PeerInfo(ctx context.Context, id string) (peerInfoResponse *types.PeerInfo, err error)
-	PeerList(ctx context.Context, id string) (peersInfoResponse []*types.PeerInfo, err error)
+	PeerList(ctx context.Context) (peersInfoResponse []*types.PeerInfo, err error)
 }
 
 // TaskAPIClient defines methods of task related client.
 type TaskAPIClient interface {
 	TaskCreate(ctx context.Context, request *types.TaskCreateRequest) (taskCreateResponse *types.TaskCreateResponse, err error)
-	TaskDelete(ctx context.Context, id string) error
+	TaskDelete(ctx context.Context, id string, full bool) error
 	TaskInfo(ctx context.Context, id string) (taskInfoResponse *types.TaskInfo, err error)
+	TaskList(ctx context.Context, opts *TaskListOptions) (tasks []*types.TaskInfo, err error)
+	TaskBulkDelete(ctx context.Context, opts *TaskBulkDeleteOptions) (tasks []*types.TaskInfo, err error)
+	TaskDistribution(ctx context.Context, id string, compact bool) (*types.TaskDistribution, error)
 	TaskUpdate(ctx context.Context, id string, config *types.TaskUpdateRequest) error
 }
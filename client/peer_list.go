@@ -23,7 +23,7 @@ import (
 )
 
 // PeerList lists detailed information of all peers in supernode.
-func (client *APIClient) PeerList(ctx context.Context, id string) (peersInfoResponse []*types.PeerInfo, err error) {
+func (client *APIClient) PeerList(ctx context.Context) (peersInfoResponse []*types.PeerInfo, err error) {
 	resp, err := client.get(ctx, "/api/v1/peers", nil, nil)
 	if err != nil {
 		return nil, err
@@ -31,7 +31,7 @@ func (client *APIClient) PeerList(ctx context.Context, id string) (peersInfoResp
 
 	peers := []*types.PeerInfo{}
 
-	err = decodeBody(peers, resp.Body)
+	err = decodeBody(&peers, resp.Body)
 	ensureCloseReader(resp)
 
 	return peers, err
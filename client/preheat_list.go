@@ -23,7 +23,7 @@ import (
 )
 
 // PreheatList lists detailed information of preheat tasks.
-func (client *APIClient) PreheatList(ctx context.Context, id string) ([]*types.PreheatInfo, error) {
+func (client *APIClient) PreheatList(ctx context.Context) ([]*types.PreheatInfo, error) {
 	resp, err := client.get(ctx, "/preheats", nil, nil)
 	if err != nil {
 		return nil, err
@@ -31,7 +31,7 @@ func (client *APIClient) PreheatList(ctx context.Context, id string) ([]*types.P
 
 	preheats := []*types.PreheatInfo{}
 
-	err = decodeBody(preheats, resp.Body)
+	err = decodeBody(&preheats, resp.Body)
 	ensureCloseReader(resp)
 
 	return preheats, err
@@ -0,0 +1,66 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreheatList(t *testing.T) {
+	client := &APIClient{
+		HTTPCli: newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, "/preheats", req.URL.Path)
+			b, err := json.Marshal([]*types.PreheatInfo{{ID: "a"}, {ID: "b"}})
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader(b)),
+			}, nil
+		}),
+	}
+
+	preheats, err := client.PreheatList(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(preheats))
+	assert.Equal(t, "b", preheats[1].ID)
+}
+
+func TestPreheatDelete(t *testing.T) {
+	client := &APIClient{
+		HTTPCli: newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, http.MethodDelete, req.Method)
+			assert.Equal(t, "/preheats/foo", req.URL.Path)
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+			}, nil
+		}),
+	}
+
+	assert.Nil(t, client.PreheatDelete(context.Background(), "foo"))
+}
@@ -14,27 +14,25 @@
  * limitations under the License.
  */
 
-package p2p
+package client
 
 import (
 	"context"
-	"errors"
-	"io"
 
-	"github.com/dragonflyoss/Dragonfly/dfdaemon/config"
+	"github.com/dragonflyoss/Dragonfly/apis/types"
 )
 
-type Client struct {
-}
+// PreheatScheduleCreate creates a preheat schedule.
+func (client *APIClient) PreheatScheduleCreate(ctx context.Context, request *types.PreheatScheduleCreateRequest) (*types.PreheatSchedule, error) {
+	resp, err := client.post(ctx, "/preheat-schedules", nil, request, nil)
+	if err != nil {
+		return nil, err
+	}
 
-func (c *Client) DownloadContext(ctx context.Context, url string, header map[string][]string, name string) (string, error) {
-	return "", errors.New("Not Implementation")
-}
+	schedule := &types.PreheatSchedule{}
 
-func (c *Client) DownloadStreamContext(ctx context.Context, url string, header map[string][]string, name string) (io.Reader, error) {
-	return nil, errors.New("Not Implementation")
-}
+	err = decodeBody(schedule, resp.Body)
+	ensureCloseReader(resp)
 
-func NewClient(cfg config.DFGetConfig) *Client {
-	return &Client{}
+	return schedule, err
 }
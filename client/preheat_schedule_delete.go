@@ -0,0 +1,30 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+)
+
+// PreheatScheduleDelete deletes the specified preheat schedule in supernode.
+func (client *APIClient) PreheatScheduleDelete(ctx context.Context, id string) error {
+	resp, err := client.delete(ctx, "/preheat-schedules/"+id, nil, nil)
+	if err != nil {
+		return err
+	}
+	return ensureCloseReader(resp)
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreheatScheduleEnable(t *testing.T) {
+	var paths []string
+	client := &APIClient{
+		HTTPCli: newMockClient(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, http.MethodPost, req.Method)
+			paths = append(paths, req.URL.Path)
+			b, err := json.Marshal(&types.PreheatSchedule{ID: "foo"})
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader(b)),
+			}, nil
+		}),
+	}
+
+	schedule, err := client.PreheatScheduleEnable(context.Background(), "foo", true)
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", schedule.ID)
+	_, err = client.PreheatScheduleEnable(context.Background(), "foo", false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/preheat-schedules/foo/enable", "/preheat-schedules/foo/disable"}, paths)
+}
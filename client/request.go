@@ -135,6 +135,9 @@ func (client *APIClient) newRequest(method, path string, query url.Values, body
 	for k, v := range header {
 		req.Header[k] = v
 	}
+	if client.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+client.authToken)
+	}
 
 	return req, err
 }
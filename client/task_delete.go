@@ -18,11 +18,18 @@ package client
 
 import (
 	"context"
+	"net/url"
 )
 
-// TaskDelete deletes the specified task in supernode.
-func (client *APIClient) TaskDelete(ctx context.Context, id string) error {
-	resp, err := client.delete(ctx, "/tasks/"+id, nil, nil)
+// TaskDelete deletes the specified task in supernode,
+// and the cdn files of the task are also evicted if full is true.
+func (client *APIClient) TaskDelete(ctx context.Context, id string, full bool) error {
+	query := url.Values{}
+	if full {
+		query.Set("full", "true")
+	}
+
+	resp, err := client.delete(ctx, "/api/v1/tasks/"+id, query, nil)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,43 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+)
+
+// TaskDistribution gets the distribution of the pieces of the task among the peers,
+// and the details are omitted if compact is true.
+func (client *APIClient) TaskDistribution(ctx context.Context, id string, compact bool) (*types.TaskDistribution, error) {
+	query := url.Values{}
+	if compact {
+		query.Set("compact", "true")
+	}
+
+	resp, err := client.get(ctx, "/api/v1/tasks/"+id+"/distribution", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer ensureCloseReader(resp)
+
+	distribution := &types.TaskDistribution{}
+	err = decodeBody(distribution, resp.Body)
+	return distribution, err
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+)
+
+// TaskFilter describes which tasks should be selected when listing or
+// deleting tasks in bulk. The zero value of a field means no restriction.
+type TaskFilter struct {
+	// URLPattern is a regular expression the raw URL of tasks should match.
+	URLPattern string
+	// CdnStatus is the list of acceptable cdn statuses.
+	CdnStatus []string
+	// Tenant is the tenant which creates the tasks.
+	Tenant string
+	// MinAge and MaxAge bound the duration since tasks were accessed last time.
+	MinAge time.Duration
+	MaxAge time.Duration
+	// MinSize and MaxSize bound the source file length of tasks, such as 100MB.
+	MinSize string
+	MaxSize string
+}
+
+// TaskListOptions contains the filter and the paging options of listing tasks.
+type TaskListOptions struct {
+	TaskFilter
+	PageNum    int
+	PageSize   int
+	SortKey    string
+	SortDirect string
+}
+
+// TaskBulkDeleteOptions contains the filter and the options of deleting tasks in bulk.
+type TaskBulkDeleteOptions struct {
+	TaskFilter
+	// Full evicts the cdn files of tasks as well.
+	Full bool
+	// All allows deleting all the tasks when no filter is given.
+	All bool
+	// DryRun returns the matched tasks without deleting them.
+	DryRun bool
+}
+
+func (f *TaskFilter) query() url.Values {
+	query := url.Values{}
+	if f.URLPattern != "" {
+		query.Set("url", f.URLPattern)
+	}
+	if len(f.CdnStatus) > 0 {
+		query.Set("cdnStatus", strings.Join(f.CdnStatus, ","))
+	}
+	if f.Tenant != "" {
+		query.Set("tenant", f.Tenant)
+	}
+	if f.MinAge > 0 {
+		query.Set("minAge", f.MinAge.String())
+	}
+	if f.MaxAge > 0 {
+		query.Set("maxAge", f.MaxAge.String())
+	}
+	if f.MinSize != "" {
+		query.Set("minSize", f.MinSize)
+	}
+	if f.MaxSize != "" {
+		query.Set("maxSize", f.MaxSize)
+	}
+	return query
+}
+
+// TaskList lists the tasks in supernode which match the options.
+func (client *APIClient) TaskList(ctx context.Context, opts *TaskListOptions) (tasks []*types.TaskInfo, err error) {
+	query := url.Values{}
+	if opts != nil {
+		query = opts.query()
+		if opts.PageNum > 0 {
+			query.Set("pageNum", strconv.Itoa(opts.PageNum))
+		}
+		if opts.PageSize > 0 {
+			query.Set("pageSize", strconv.Itoa(opts.PageSize))
+		}
+		if opts.SortKey != "" {
+			query.Set("sortKey", opts.SortKey)
+		}
+		if opts.SortDirect != "" {
+			query.Set("sortDirect", opts.SortDirect)
+		}
+	}
+
+	resp, err := client.get(ctx, "/api/v1/tasks", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer ensureCloseReader(resp)
+
+	err = decodeBody(&tasks, resp.Body)
+	return tasks, err
+}
+
+// TaskBulkDelete deletes the tasks in supernode which match the options
+// and returns them.
+func (client *APIClient) TaskBulkDelete(ctx context.Context, opts *TaskBulkDeleteOptions) (tasks []*types.TaskInfo, err error) {
+	query := url.Values{}
+	if opts != nil {
+		query = opts.query()
+		if opts.Full {
+			query.Set("full", "true")
+		}
+		if opts.All {
+			query.Set("all", "true")
+		}
+		if opts.DryRun {
+			query.Set("dryRun", "true")
+		}
+	}
+
+	resp, err := client.delete(ctx, "/api/v1/tasks", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer ensureCloseReader(resp)
+
+	err = decodeBody(&tasks, resp.Body)
+	return tasks, err
+}
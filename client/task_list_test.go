@@ -0,0 +1,131 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTaskListMockClient(t *testing.T, method string, expectedQuery map[string]string) *http.Client {
+	return newMockClient(func(req *http.Request) (*http.Response, error) {
+		if req.Method != method {
+			return nil, fmt.Errorf("expected method '%s', got '%s'", method, req.Method)
+		}
+		if req.URL.Path != "/api/v1/tasks" {
+			return nil, fmt.Errorf("expected URL '/api/v1/tasks', got '%s'", req.URL)
+		}
+		query := req.URL.Query()
+		assert.Equal(t, len(expectedQuery), len(query))
+		for k, v := range expectedQuery {
+			assert.Equal(t, v, query.Get(k), k)
+		}
+
+		b, err := json.Marshal([]*types.TaskInfo{{ID: "a"}, {ID: "b"}})
+		if err != nil {
+			return nil, err
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader(b)),
+		}, nil
+	})
+}
+
+func TestTaskList(t *testing.T) {
+	client := &APIClient{
+		HTTPCli: newTaskListMockClient(t, http.MethodGet, map[string]string{
+			"url":        "^http://a.com/",
+			"cdnStatus":  "SUCCESS,FAILED",
+			"minAge":     "1h0m0s",
+			"maxSize":    "1GB",
+			"pageNum":    "1",
+			"pageSize":   "10",
+			"sortKey":    "fileLength",
+			"sortDirect": "DESC",
+		}),
+	}
+
+	tasks, err := client.TaskList(context.Background(), &TaskListOptions{
+		TaskFilter: TaskFilter{
+			URLPattern: "^http://a.com/",
+			CdnStatus:  []string{types.TaskInfoCdnStatusSUCCESS, types.TaskInfoCdnStatusFAILED},
+			MinAge:     time.Hour,
+			MaxSize:    "1GB",
+		},
+		PageNum:    1,
+		PageSize:   10,
+		SortKey:    "fileLength",
+		SortDirect: "DESC",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(tasks))
+	assert.Equal(t, "a", tasks[0].ID)
+}
+
+func TestTaskBulkDelete(t *testing.T) {
+	client := &APIClient{
+		HTTPCli: newTaskListMockClient(t, http.MethodDelete, map[string]string{
+			"tenant": "foo",
+			"full":   "true",
+			"dryRun": "true",
+		}),
+	}
+
+	tasks, err := client.TaskBulkDelete(context.Background(), &TaskBulkDeleteOptions{
+		TaskFilter: TaskFilter{Tenant: "foo"},
+		Full:       true,
+		DryRun:     true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(tasks))
+}
+
+func TestTaskDistribution(t *testing.T) {
+	client := &APIClient{
+		HTTPCli: newMockClient(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path != "/api/v1/tasks/foo/distribution" {
+				return nil, fmt.Errorf("unexpected URL '%s'", req.URL)
+			}
+			assert.Equal(t, "true", req.URL.Query().Get("compact"))
+
+			b, err := json.Marshal(&types.TaskDistribution{TaskID: "foo", SuperLoad: 2})
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader(b)),
+			}, nil
+		}),
+	}
+
+	distribution, err := client.TaskDistribution(context.Background(), "foo", true)
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", distribution.TaskID)
+	assert.Equal(t, int32(2), distribution.SuperLoad)
+}
@@ -24,7 +24,7 @@ import (
 
 // TaskUpdate updates a task in supernode.
 func (client *APIClient) TaskUpdate(ctx context.Context, id string, config *types.TaskUpdateRequest) error {
-	resp, err := client.put(ctx, "/tasks/"+id, nil, config, nil)
+	resp, err := client.put(ctx, "/api/v1/tasks/"+id, nil, config, nil)
 	if err != nil {
 		return err
 	}
@@ -36,8 +36,9 @@ func ensureCloseReader(resp *Response) error {
 		defer resp.Body.Close()
 
 		// Close body ReadCloser to make Transport reuse the connection.
-		_, err := io.CopyN(ioutil.Discard, resp.Body, 512)
-		return err
+		if _, err := io.CopyN(ioutil.Discard, resp.Body, 512); err != nil && err != io.EOF {
+			return err
+		}
 	}
 	return nil
 }
@@ -0,0 +1,119 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/cmd"
+	"github.com/dragonflyoss/Dragonfly/pkg/printer"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Config contains the configurations of dfctl, which are read from the
+// configuration file and overridden by the flags.
+type Config struct {
+	// Supernodes are the addresses of supernodes, such as 127.0.0.1:8002.
+	// The commands call the first one except the supernode health and version commands.
+	Supernodes []string `yaml:"supernodes"`
+
+	// AuthToken is the bearer token to call the supernode APIs
+	// when the authentication of supernode is enabled.
+	AuthToken string `yaml:"authToken"`
+
+	// Output is the output format, must be one of table, json and yaml.
+	Output string `yaml:"output"`
+
+	// Timeout is the timeout of each request to the supernode.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// NewConfig returns the default config of dfctl.
+func NewConfig() *Config {
+	return &Config{
+		Supernodes: []string{"127.0.0.1:8002"},
+		Output:     printer.FormatTable,
+		Timeout:    10 * time.Second,
+	}
+}
+
+// Validate validates the config.
+func (c *Config) Validate() error {
+	if len(c.Supernodes) == 0 {
+		return fmt.Errorf("at least one supernode is required")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout %v must be positive", c.Timeout)
+	}
+	return printer.ValidateFormat(c.Output)
+}
+
+func getConfigFromViper(v *viper.Viper) (*Config, error) {
+	cfg := NewConfig()
+	if err := v.Unmarshal(cfg); err != nil {
+		return nil, errors.Wrap(err, "unmarshal yaml")
+	}
+	return cfg, nil
+}
+
+// configView is the printable view of Config.
+type configView struct {
+	Supernodes []string `json:"supernodes"`
+	AuthToken  string   `json:"authToken,omitempty"`
+	Output     string   `json:"output"`
+	Timeout    string   `json:"timeout"`
+}
+
+func newConfigView(cfg *Config) *configView {
+	view := &configView{
+		Supernodes: cfg.Supernodes,
+		Output:     cfg.Output,
+		Timeout:    cfg.Timeout.String(),
+	}
+	// never print the secret
+	if cfg.AuthToken != "" {
+		view.AuthToken = "******"
+	}
+	return view
+}
+
+// newConfigCommand returns the command to manage the configurations of dfctl.
+func newConfigCommand() *cobra.Command {
+	configCmd := cmd.NewConfigCommand("dfctl", func() (interface{}, error) {
+		return NewConfig(), nil
+	})
+
+	configCmd.AddCommand(&cobra.Command{
+		Use:           "view",
+		Short:         "Print the configurations of dfctl merged from the configuration file, flags and environment variables",
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			return printer.Fprint(printer.Printer.Out, cfg.Output, newConfigView(cfg), nil)
+		},
+	})
+	return configCmd
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/printer"
+
+	"github.com/spf13/cobra"
+)
+
+// newPeerCommand returns the command to manage the peers in supernode.
+func newPeerCommand() *cobra.Command {
+	peerCmd := &cobra.Command{
+		Use:   "peer",
+		Short: "Manage the peers in supernode",
+		Args:  cobra.NoArgs,
+	}
+
+	peerCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the peers in supernode",
+		Args:  cobra.NoArgs,
+		RunE: runE(func(r *runner, args []string) error {
+			ctx, cancel := r.context()
+			defer cancel()
+
+			peers, err := r.client.PeerList(ctx)
+			if err != nil {
+				return err
+			}
+			return r.print(peers, peerTable(peers))
+		}),
+	})
+
+	peerCmd.AddCommand(&cobra.Command{
+		Use:   "info ID",
+		Short: "Get the detailed information of a peer",
+		Args:  cobra.ExactArgs(1),
+		RunE: runE(func(r *runner, args []string) error {
+			ctx, cancel := r.context()
+			defer cancel()
+
+			peer, err := r.client.PeerInfo(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			return r.print(peer, nil)
+		}),
+	})
+
+	peerCmd.AddCommand(&cobra.Command{
+		Use:   "deregister ID...",
+		Short: "Deregister the peers from supernode",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: runE(func(r *runner, args []string) error {
+			ctx, cancel := r.context()
+			defer cancel()
+
+			for _, id := range args {
+				if err := r.client.PeerDelete(ctx, id); err != nil {
+					return err
+				}
+				printer.Println(id)
+			}
+			return nil
+		}),
+	})
+
+	return peerCmd
+}
+
+func peerTable(peers []*types.PeerInfo) *printer.Table {
+	table := &printer.Table{
+		Header: []string{"ID", "IP", "PORT", "HOSTNAME", "VERSION", "TENANT", "SCORE", "CREATED"},
+	}
+	for _, p := range peers {
+		score := "-"
+		if p.Score != nil {
+			score = strconv.FormatFloat(*p.Score, 'f', 2, 64)
+		}
+		table.Rows = append(table.Rows, []string{
+			p.ID,
+			p.IP.String(),
+			strconv.Itoa(int(p.Port)),
+			p.HostName.String(),
+			p.Version,
+			p.Tenant,
+			score,
+			formatTime(time.Time(p.Created)),
+		})
+	}
+	return table
+}
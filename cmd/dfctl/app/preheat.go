@@ -0,0 +1,307 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/printer"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// defaultWatchInterval is the default interval to poll the status of a preheat task.
+const defaultWatchInterval = 2 * time.Second
+
+// newPreheatCommand returns the command to manage the preheat tasks in supernode.
+func newPreheatCommand() *cobra.Command {
+	preheatCmd := &cobra.Command{
+		Use:   "preheat",
+		Short: "Manage the preheat tasks in supernode",
+		Args:  cobra.NoArgs,
+	}
+
+	preheatCmd.AddCommand(newPreheatCreateCommand())
+	preheatCmd.AddCommand(newPreheatWatchCommand())
+	preheatCmd.AddCommand(newPreheatScheduleCommand())
+
+	preheatCmd.AddCommand(&cobra.Command{
+		Use:   "layers ID",
+		Short: "Get the status of the layers preheated by an image preheat task",
+		Args:  cobra.ExactArgs(1),
+		RunE: runE(func(r *runner, args []string) error {
+			ctx, cancel := r.context()
+			defer cancel()
+
+			preheat, err := r.client.PreheatInfo(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			return r.print(preheat.Layers, preheatLayersTable(preheat.Layers))
+		}),
+	})
+
+	preheatCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the preheat tasks in supernode",
+		Args:  cobra.NoArgs,
+		RunE: runE(func(r *runner, args []string) error {
+			ctx, cancel := r.context()
+			defer cancel()
+
+			preheats, err := r.client.PreheatList(ctx)
+			if err != nil {
+				return err
+			}
+			return r.print(preheats, preheatTable(preheats))
+		}),
+	})
+
+	preheatCmd.AddCommand(&cobra.Command{
+		Use:   "delete ID...",
+		Short: "Delete the preheat tasks",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: runE(func(r *runner, args []string) error {
+			ctx, cancel := r.context()
+			defer cancel()
+
+			for _, id := range args {
+				if err := r.client.PreheatDelete(ctx, id); err != nil {
+					return fmt.Errorf("failed to delete preheat task %s: %v", id, err)
+				}
+				printer.Println(id)
+			}
+			return nil
+		}),
+	})
+
+	return preheatCmd
+}
+
+// preheatRequestFlags are the flags to build the request of a preheat task,
+// which are shared by the commands creating the preheat tasks and schedules.
+type preheatRequestFlags struct {
+	request *types.PreheatCreateRequest
+	typ     string
+	url     string
+	headers []string
+	peers   *types.PreheatPeerSelector
+}
+
+func newPreheatRequestFlags(flagSet *pflag.FlagSet) *preheatRequestFlags {
+	f := &preheatRequestFlags{
+		request: &types.PreheatCreateRequest{},
+		peers:   &types.PreheatPeerSelector{},
+	}
+	flagSet.StringVar(&f.typ, "type", types.PreheatCreateRequestTypeImage,
+		"the type of the preheat task, must be in [image file]")
+	flagSet.StringVar(&f.url, "url", "",
+		"the image url or the file url to be preheated")
+	flagSet.StringVar(&f.request.Filter, "filter", "",
+		"the query parameters to be filtered when generating the task ID, separated by &")
+	flagSet.StringVar(&f.request.Identifier, "identifier", "",
+		"the identifier to generate the task ID together with the url")
+	flagSet.StringSliceVar(&f.headers, "header", nil,
+		"the http headers to download the url, in the format of key:value")
+	flagSet.StringSliceVar(&f.peers.PeerIds, "peer", nil,
+		"the IDs of the peers which download the file in the background after it's preheated into the supernode")
+	flagSet.StringToStringVar(&f.peers.Labels, "peer-label", nil,
+		"select the peers which have all the labels(key=value) to download the file in the background")
+	flagSet.Int32Var(&f.peers.PerIdc, "peers-per-idc", 0,
+		"select the given count of random peers in every IDC to download the file in the background")
+	flagSet.StringSliceVar(&f.request.Platforms, "platform", nil,
+		"the platforms of the multi-platform image to be preheated, in the format of os/architecture[/variant]")
+	flagSet.BoolVar(&f.request.Referrers, "referrers", false,
+		"preheat the referrers of the image too, such as the signatures and the SBOMs")
+	return f
+}
+
+// build returns the validated request of the preheat task.
+func (f *preheatRequestFlags) build() (*types.PreheatCreateRequest, error) {
+	request := f.request
+	request.Type = &f.typ
+	request.URL = &f.url
+	request.Headers = make(map[string]string)
+	for _, h := range f.headers {
+		kv := strings.SplitN(h, ":", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid header %q, must be in the format of key:value", h)
+		}
+		request.Headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	if len(f.peers.PeerIds) > 0 || len(f.peers.Labels) > 0 || f.peers.PerIdc > 0 {
+		request.Peers = f.peers
+	}
+	if err := request.Validate(strfmt.Default); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func newPreheatCreateCommand() *cobra.Command {
+	var (
+		requestFlags *preheatRequestFlags
+		watch        bool
+	)
+
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a preheat task to download the image or file into the supernode in advance",
+		Args:  cobra.NoArgs,
+		RunE: runE(func(r *runner, args []string) error {
+			request, err := requestFlags.build()
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := r.context()
+			defer cancel()
+			resp, err := r.client.PreheatCreate(ctx, request)
+			if err != nil {
+				return err
+			}
+			if !watch {
+				printer.Println(resp.ID)
+				return nil
+			}
+			return r.watchPreheat(resp.ID, defaultWatchInterval)
+		}),
+	}
+
+	flagSet := createCmd.Flags()
+	requestFlags = newPreheatRequestFlags(flagSet)
+	flagSet.BoolVar(&watch, "watch", false,
+		"watch the status of the preheat task until it finishes")
+	return createCmd
+}
+
+func newPreheatWatchCommand() *cobra.Command {
+	var interval time.Duration
+	watchCmd := &cobra.Command{
+		Use:   "watch ID",
+		Short: "Watch the status of a preheat task until it finishes",
+		Args:  cobra.ExactArgs(1),
+		RunE: runE(func(r *runner, args []string) error {
+			return r.watchPreheat(args[0], interval)
+		}),
+	}
+
+	watchCmd.Flags().DurationVar(&interval, "interval", defaultWatchInterval,
+		"the interval to poll the status of the preheat task")
+	return watchCmd
+}
+
+// watchPreheat polls the preheat task and prints its status whenever it changes
+// until the task succeeds or fails.
+func (r *runner) watchPreheat(id string, interval time.Duration) error {
+	var lastStatus types.PreheatStatus
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), r.cfg.Timeout)
+		preheat, err := r.client.PreheatInfo(ctx, id)
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if preheat.Status != lastStatus {
+			lastStatus = preheat.Status
+			if err := r.print(preheat, preheatTable([]*types.PreheatInfo{preheat})); err != nil {
+				return err
+			}
+		}
+
+		switch preheat.Status {
+		case types.PreheatStatusSUCCESS:
+			return nil
+		case types.PreheatStatusFAILED:
+			return fmt.Errorf("preheat task %s failed: %s", id, preheatError(preheat))
+		}
+		time.Sleep(interval)
+	}
+}
+
+func preheatTable(preheats []*types.PreheatInfo) *printer.Table {
+	table := &printer.Table{
+		Header: []string{"ID", "STATUS", "PIECES", "PEERS", "START TIME", "FINISH TIME", "ERROR"},
+	}
+	for _, p := range preheats {
+		table.Rows = append(table.Rows, []string{
+			p.ID,
+			string(p.Status),
+			fmt.Sprintf("%d/%d", p.FinishedPieceCount, p.PieceTotal),
+			preheatPeers(p),
+			formatTime(time.Time(p.StartTime)),
+			formatTime(time.Time(p.FinishTime)),
+			preheatError(p),
+		})
+	}
+	return table
+}
+
+func preheatLayersTable(layers []*types.PreheatLayerStatus) *printer.Table {
+	table := &printer.Table{
+		Header: []string{"ID", "KIND", "PLATFORM", "DIGEST", "STATUS", "PIECES", "ERROR"},
+	}
+	for _, l := range layers {
+		status := string(l.Status)
+		if l.Cached {
+			status += "(cached)"
+		}
+		table.Rows = append(table.Rows, []string{
+			l.ID,
+			l.Kind,
+			l.Platform,
+			l.Digest,
+			status,
+			fmt.Sprintf("%d/%d", l.FinishedPieceCount, l.PieceTotal),
+			l.ErrorMsg,
+		})
+	}
+	return table
+}
+
+// preheatPeers returns the count of the target peers which have downloaded the file
+// and the count of all the target peers.
+func preheatPeers(p *types.PreheatInfo) string {
+	if len(p.Peers) == 0 {
+		return "-"
+	}
+	succeeded := 0
+	for _, peer := range p.Peers {
+		if peer.Status == types.PreheatStatusSUCCESS {
+			succeeded++
+		}
+	}
+	return fmt.Sprintf("%d/%d", succeeded, len(p.Peers))
+}
+
+// preheatError returns the readable error of the failed preheat task.
+func preheatError(p *types.PreheatInfo) string {
+	if p.Error == nil {
+		return p.ErrorMsg
+	}
+	if p.Error.URL != "" {
+		return fmt.Sprintf("%s: %s (%s)", p.Error.Code, p.Error.Message, p.Error.URL)
+	}
+	return fmt.Sprintf("%s: %s", p.Error.Code, p.Error.Message)
+}
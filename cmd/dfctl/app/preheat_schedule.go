@@ -0,0 +1,205 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/printer"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/spf13/cobra"
+)
+
+// newPreheatScheduleCommand returns the command to manage the preheat schedules in supernode.
+func newPreheatScheduleCommand() *cobra.Command {
+	scheduleCmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Manage the preheat schedules which create the preheat tasks periodically",
+		Args:  cobra.NoArgs,
+	}
+
+	scheduleCmd.AddCommand(newPreheatScheduleCreateCommand())
+
+	scheduleCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the preheat schedules in supernode",
+		Args:  cobra.NoArgs,
+		RunE: runE(func(r *runner, args []string) error {
+			ctx, cancel := r.context()
+			defer cancel()
+
+			schedules, err := r.client.PreheatScheduleList(ctx)
+			if err != nil {
+				return err
+			}
+			return r.print(schedules, preheatScheduleTable(schedules))
+		}),
+	})
+
+	scheduleCmd.AddCommand(&cobra.Command{
+		Use:   "runs ID",
+		Short: "Get the latest runs of a preheat schedule",
+		Args:  cobra.ExactArgs(1),
+		RunE: runE(func(r *runner, args []string) error {
+			ctx, cancel := r.context()
+			defer cancel()
+
+			schedule, err := r.client.PreheatScheduleInfo(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			return r.print(schedule.Runs, preheatScheduleRunsTable(schedule.Runs))
+		}),
+	})
+
+	scheduleCmd.AddCommand(newPreheatScheduleEnableCommand(true))
+	scheduleCmd.AddCommand(newPreheatScheduleEnableCommand(false))
+
+	scheduleCmd.AddCommand(&cobra.Command{
+		Use:   "delete ID...",
+		Short: "Delete the preheat schedules, the preheat tasks created by them are kept",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: runE(func(r *runner, args []string) error {
+			ctx, cancel := r.context()
+			defer cancel()
+
+			for _, id := range args {
+				if err := r.client.PreheatScheduleDelete(ctx, id); err != nil {
+					return fmt.Errorf("failed to delete preheat schedule %s: %v", id, err)
+				}
+				printer.Println(id)
+			}
+			return nil
+		}),
+	})
+
+	return scheduleCmd
+}
+
+func newPreheatScheduleCreateCommand() *cobra.Command {
+	var (
+		request      = &types.PreheatScheduleCreateRequest{}
+		requestFlags *preheatRequestFlags
+		cron         string
+	)
+
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a preheat schedule to preheat the image or file periodically",
+		Args:  cobra.NoArgs,
+		RunE: runE(func(r *runner, args []string) error {
+			preheat, err := requestFlags.build()
+			if err != nil {
+				return err
+			}
+			request.Cron = &cron
+			request.Preheat = preheat
+			if err := request.Validate(strfmt.Default); err != nil {
+				return err
+			}
+
+			ctx, cancel := r.context()
+			defer cancel()
+			schedule, err := r.client.PreheatScheduleCreate(ctx, request)
+			if err != nil {
+				return err
+			}
+			return r.print(schedule, preheatScheduleTable([]*types.PreheatSchedule{schedule}))
+		}),
+	}
+
+	flagSet := createCmd.Flags()
+	flagSet.StringVar(&cron, "cron", "",
+		"the cron expression of the schedule in the local time of supernode, such as \"0 2 * * *\", \"@daily\" and \"@every 6h\"")
+	flagSet.StringVar(&request.Name, "name", "",
+		"the readable name of the schedule")
+	flagSet.BoolVar(&request.Disabled, "disabled", false,
+		"create the schedule disabled, it doesn't preheat until it's enabled")
+	requestFlags = newPreheatRequestFlags(flagSet)
+	return createCmd
+}
+
+func newPreheatScheduleEnableCommand(enable bool) *cobra.Command {
+	use, short := "enable ID...", "Enable the preheat schedules, the next runs of which are calculated from now"
+	if !enable {
+		use, short = "disable ID...", "Disable the preheat schedules, the running preheat tasks created by them aren't affected"
+	}
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.MinimumNArgs(1),
+		RunE: runE(func(r *runner, args []string) error {
+			ctx, cancel := r.context()
+			defer cancel()
+
+			schedules := make([]*types.PreheatSchedule, 0, len(args))
+			for _, id := range args {
+				schedule, err := r.client.PreheatScheduleEnable(ctx, id, enable)
+				if err != nil {
+					return fmt.Errorf("failed to update preheat schedule %s: %v", id, err)
+				}
+				schedules = append(schedules, schedule)
+			}
+			return r.print(schedules, preheatScheduleTable(schedules))
+		}),
+	}
+}
+
+func preheatScheduleTable(schedules []*types.PreheatSchedule) *printer.Table {
+	table := &printer.Table{
+		Header: []string{"ID", "NAME", "CRON", "ENABLED", "URL", "NEXT RUN", "LAST RUN"},
+	}
+	for _, s := range schedules {
+		var url, lastRun string
+		if s.Preheat != nil && s.Preheat.URL != nil {
+			url = *s.Preheat.URL
+		}
+		if len(s.Runs) > 0 {
+			lastRun = string(s.Runs[len(s.Runs)-1].Status)
+		}
+		table.Rows = append(table.Rows, []string{
+			s.ID,
+			s.Name,
+			s.Cron,
+			fmt.Sprintf("%t", s.Enabled),
+			url,
+			formatTime(time.Time(s.NextRunTime)),
+			lastRun,
+		})
+	}
+	return table
+}
+
+func preheatScheduleRunsTable(runs []*types.PreheatScheduleRun) *printer.Table {
+	table := &printer.Table{
+		Header: []string{"PREHEAT ID", "URL", "STATUS", "START TIME", "FINISH TIME", "ERROR"},
+	}
+	for _, run := range runs {
+		table.Rows = append(table.Rows, []string{
+			run.PreheatID,
+			run.URL,
+			string(run.Status),
+			formatTime(time.Time(run.StartTime)),
+			formatTime(time.Time(run.FinishTime)),
+			run.ErrorMsg,
+		})
+	}
+	return table
+}
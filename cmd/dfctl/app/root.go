@@ -0,0 +1,233 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/client"
+	"github.com/dragonflyoss/Dragonfly/pkg/cmd"
+	"github.com/dragonflyoss/Dragonfly/pkg/printer"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultConfigFilePath is the default path of the configuration file of dfctl.
+	DefaultConfigFilePath = "/etc/dragonfly/dfctl.yml"
+
+	// AuthTokenEnv is the environment variable of the token to call the supernode APIs,
+	// which is shared with dfget.
+	AuthTokenEnv = "DF_AUTH_TOKEN"
+)
+
+var dfctlViper = viper.GetViper()
+
+var dfctlDescription = `dfctl is the command line tool to manage the supernodes of Dragonfly.
+It can list, inspect and delete the tasks and peers, manage the preheat tasks and
+check the health, version and metrics of the supernodes.
+The addresses of supernodes and the auth token can be set in the configuration file.`
+
+var rootCmd = &cobra.Command{
+	Use:               "dfctl",
+	Short:             "The command line tool to manage the supernodes of Dragonfly",
+	Long:              dfctlDescription,
+	Args:              cobra.NoArgs,
+	DisableAutoGenTag: true, // disable displaying auto generation tag in cli docs
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+}
+
+func init() {
+	setupFlags(rootCmd)
+
+	rootCmd.AddCommand(newTaskCommand())
+	rootCmd.AddCommand(newPeerCommand())
+	rootCmd.AddCommand(newPreheatCommand())
+	rootCmd.AddCommand(newSupernodeCommand())
+	rootCmd.AddCommand(newConfigCommand())
+	rootCmd.AddCommand(cmd.NewGenDocCommand("dfctl"))
+	rootCmd.AddCommand(cmd.NewVersionCommand("dfctl"))
+}
+
+// setupFlags setups flags shared by all the commands.
+func setupFlags(cmd *cobra.Command) {
+	defaultConfig := NewConfig()
+	flagSet := cmd.PersistentFlags()
+
+	flagSet.String("config", DefaultConfigFilePath,
+		"the path of dfctl's configuration file")
+	flagSet.StringSliceP("supernode", "s", defaultConfig.Supernodes,
+		"the addresses of supernodes, such as 127.0.0.1:8002, the first one is used except the supernode health and version commands")
+	flagSet.String("auth-token", "",
+		"the bearer token to call the supernode APIs when the authentication of supernode is enabled, it can also be set by the environment variable "+AuthTokenEnv)
+	flagSet.StringP("output", "o", defaultConfig.Output,
+		"the output format, must be one of [table json yaml]")
+	flagSet.Duration("timeout", defaultConfig.Timeout,
+		"the timeout of each request to the supernode")
+
+	if err := bindRootFlags(dfctlViper); err != nil {
+		panic(err)
+	}
+}
+
+func bindRootFlags(v *viper.Viper) error {
+	flags := []struct {
+		key  string
+		flag string
+	}{
+		{
+			key:  "config",
+			flag: "config",
+		},
+		{
+			key:  "supernodes",
+			flag: "supernode",
+		},
+		{
+			key:  "authToken",
+			flag: "auth-token",
+		},
+		{
+			key:  "output",
+			flag: "output",
+		},
+		{
+			key:  "timeout",
+			flag: "timeout",
+		},
+	}
+
+	for _, f := range flags {
+		if err := v.BindPFlag(f.key, rootCmd.PersistentFlags().Lookup(f.flag)); err != nil {
+			return err
+		}
+	}
+
+	return v.BindEnv("authToken", AuthTokenEnv)
+}
+
+func readConfigFile(v *viper.Viper, cmd *cobra.Command) error {
+	v.SetConfigFile(v.GetString("config"))
+	v.SetConfigType("yaml")
+
+	if err := v.ReadInConfig(); err != nil {
+		// when the default config file is not found, ignore the error
+		if os.IsNotExist(err) && !cmd.Flag("config").Changed {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// loadConfig reads the configuration file and returns the config
+// overridden by the flags and the environment variables.
+func loadConfig(cmd *cobra.Command) (*Config, error) {
+	if err := readConfigFile(dfctlViper, cmd); err != nil {
+		return nil, errors.Wrap(err, "read config file")
+	}
+	cfg, err := getConfigFromViper(dfctlViper)
+	if err != nil {
+		return nil, errors.Wrap(err, "get config from viper")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// runner contains the config and the client shared by the commands.
+type runner struct {
+	cfg    *Config
+	client client.CommonAPIClient
+}
+
+// runE returns a cobra RunE function which creates the client of the
+// first supernode and calls run with it.
+func runE(run func(r *runner, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		cli, err := newAPIClient(cfg, cfg.Supernodes[0])
+		if err != nil {
+			return err
+		}
+		return run(&runner{cfg: cfg, client: cli}, args)
+	}
+}
+
+// context returns a context which is canceled after the timeout of requests.
+func (r *runner) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), r.cfg.Timeout)
+}
+
+// print prints obj in the output format, the table is used in the table format.
+func (r *runner) print(obj interface{}, table *printer.Table) error {
+	return printer.Fprint(printer.Printer.Out, r.cfg.Output, obj, table)
+}
+
+// newAPIClient returns the client of the supernode, and the scheme
+// http is used if the address doesn't contain a scheme.
+func newAPIClient(cfg *Config, supernode string) (client.CommonAPIClient, error) {
+	if !strings.Contains(supernode, "://") {
+		supernode = "http://" + supernode
+	}
+	return client.NewAPIClientWithAuthToken(supernode, client.TLSConfig{}, cfg.AuthToken)
+}
+
+// formatTime formats the time in RFC3339, and returns empty if it's zero.
+func formatTime(t time.Time) string {
+	if t.IsZero() || t.Unix() <= 0 {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// Execute will process dfctl.
+func Execute() {
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", errorMessage(err))
+		os.Exit(1)
+	}
+}
+
+// errorMessage extracts the readable message from the error response
+// of supernode, and falls back to the original error.
+func errorMessage(err error) string {
+	respErr, ok := errors.Cause(err).(client.RespError)
+	if !ok {
+		return err.Error()
+	}
+
+	errResp := &types.ErrorResponse{}
+	if e := json.Unmarshal([]byte(respErr.Error()), errResp); e != nil || errResp.Message == "" {
+		return fmt.Sprintf("%s (status code %d)", strings.TrimSpace(respErr.Error()), respErr.Code())
+	}
+	return fmt.Sprintf("%s (status code %d)", errResp.Message, respErr.Code())
+}
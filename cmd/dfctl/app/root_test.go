@@ -0,0 +1,191 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/printer"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+)
+
+type rootTestSuite struct {
+	suite.Suite
+}
+
+func (ts *rootTestSuite) TestConfigFile() {
+	r := ts.Require()
+	fs := afero.NewMemMapFs()
+
+	configName := "dfctl.yml"
+	file, err := fs.Create(configName)
+	r.Nil(err)
+	file.WriteString("supernodes:\n- 10.10.10.1:8002\n- 10.10.10.2:8002\noutput: json\ntimeout: 3s")
+	file.Close()
+
+	// config file exists, should use config file
+	{
+		v := viper.New()
+		v.SetFs(fs)
+		rootCmd.PersistentFlags().Set("config", configName)
+		r.Nil(bindRootFlags(v))
+		r.Nil(readConfigFile(v, rootCmd))
+		cfg, err := getConfigFromViper(v)
+		r.Nil(err)
+		r.Equal([]string{"10.10.10.1:8002", "10.10.10.2:8002"}, cfg.Supernodes)
+		r.Equal(printer.FormatJSON, cfg.Output)
+		r.Equal(3*time.Second, cfg.Timeout)
+		r.Nil(cfg.Validate())
+	}
+
+	// config file specified by flag doesn't exist, should return error
+	{
+		v := viper.New()
+		v.SetFs(fs)
+		rootCmd.PersistentFlags().Set("config", "xxx")
+		r.Nil(bindRootFlags(v))
+		r.True(os.IsNotExist(errors.Cause(readConfigFile(v, rootCmd))))
+		cfg, err := getConfigFromViper(v)
+		r.Nil(err)
+		r.Equal(NewConfig().Supernodes, cfg.Supernodes)
+	}
+}
+
+func (ts *rootTestSuite) TestAuthTokenEnv() {
+	r := ts.Require()
+	v := viper.New()
+	r.Nil(bindRootFlags(v))
+
+	os.Setenv(AuthTokenEnv, "secret")
+	defer os.Unsetenv(AuthTokenEnv)
+
+	cfg, err := getConfigFromViper(v)
+	r.Nil(err)
+	r.Equal("secret", cfg.AuthToken)
+	r.Equal("******", newConfigView(cfg).AuthToken)
+}
+
+func (ts *rootTestSuite) TestValidate() {
+	r := ts.Require()
+
+	r.Nil(NewConfig().Validate())
+
+	cfg := NewConfig()
+	cfg.Supernodes = nil
+	r.NotNil(cfg.Validate())
+
+	cfg = NewConfig()
+	cfg.Timeout = 0
+	r.NotNil(cfg.Validate())
+
+	cfg = NewConfig()
+	cfg.Output = "xml"
+	r.NotNil(cfg.Validate())
+}
+
+func (ts *rootTestSuite) TestErrorMessage() {
+	r := ts.Require()
+
+	r.Equal("foo", errorMessage(errors.New("foo")))
+
+	err := errors.Wrap(newRespError(`{"code":404,"message":"task not found"}`, 404), "get task")
+	r.Equal("task not found (status code 404)", errorMessage(err))
+
+	r.Equal("internal error (status code 500)", errorMessage(newRespError("internal error\n", 500)))
+}
+
+func (ts *rootTestSuite) TestSummarizeMetrics() {
+	r := ts.Require()
+	metrics := `# HELP dragonfly_supernode_peers Current count of peers.
+# TYPE dragonfly_supernode_peers gauge
+dragonfly_supernode_peers{peer="a"} 1
+dragonfly_supernode_peers{peer="b"} 2
+# HELP dragonfly_supernode_http_requests_total Counter of HTTP requests.
+# TYPE dragonfly_supernode_http_requests_total counter
+dragonfly_supernode_http_requests_total{code="200"} 10
+# HELP dragonfly_supernode_http_request_duration_seconds Histogram of latencies for HTTP requests.
+# TYPE dragonfly_supernode_http_request_duration_seconds histogram
+dragonfly_supernode_http_request_duration_seconds_bucket{le="1"} 3
+dragonfly_supernode_http_request_duration_seconds_bucket{le="+Inf"} 4
+dragonfly_supernode_http_request_duration_seconds_sum 2.5
+dragonfly_supernode_http_request_duration_seconds_count 4
+# HELP go_goroutines Number of goroutines that currently exist.
+# TYPE go_goroutines gauge
+go_goroutines 8
+`
+	summaries, err := summarizeMetrics(metrics, "dragonfly_")
+	r.Nil(err)
+	r.Len(summaries, 3)
+	r.Equal(&metricSummary{
+		Name:   "dragonfly_supernode_http_request_duration_seconds",
+		Type:   "histogram",
+		Series: 1,
+		Count:  4,
+		Sum:    2.5,
+	}, summaries[0])
+	r.Equal(&metricSummary{
+		Name:   "dragonfly_supernode_http_requests_total",
+		Type:   "counter",
+		Series: 1,
+		Value:  10,
+	}, summaries[1])
+	r.Equal(&metricSummary{
+		Name:   "dragonfly_supernode_peers",
+		Type:   "gauge",
+		Series: 2,
+		Value:  3,
+	}, summaries[2])
+
+	summaries, err = summarizeMetrics(metrics, "")
+	r.Nil(err)
+	r.Len(summaries, 4)
+
+	_, err = summarizeMetrics("invalid metrics", "")
+	r.NotNil(err)
+}
+
+// newRespError returns the error of the client when the supernode responds
+// with the code and message.
+func newRespError(msg string, code int) error {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(code)
+		w.Write([]byte(msg))
+	}))
+	defer server.Close()
+
+	c, err := newAPIClient(NewConfig(), server.URL)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = c.TaskInfo(ctx, "foo")
+	return err
+}
+
+func TestRootCommand(t *testing.T) {
+	suite.Run(t, &rootTestSuite{})
+}
@@ -0,0 +1,251 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/printer"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/spf13/cobra"
+)
+
+// newSupernodeCommand returns the command to check the status of supernodes.
+func newSupernodeCommand() *cobra.Command {
+	supernodeCmd := &cobra.Command{
+		Use:   "supernode",
+		Short: "Check the health, version and metrics of supernodes",
+		Args:  cobra.NoArgs,
+	}
+
+	supernodeCmd.AddCommand(&cobra.Command{
+		Use:   "health",
+		Short: "Check the health of all the supernodes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			results := make([]*healthResult, 0, len(cfg.Supernodes))
+			unhealthy := 0
+			for _, supernode := range cfg.Supernodes {
+				result := checkHealth(cfg, supernode)
+				if !result.Healthy {
+					unhealthy++
+				}
+				results = append(results, result)
+			}
+			if err := printer.Fprint(printer.Printer.Out, cfg.Output, results, healthTable(results)); err != nil {
+				return err
+			}
+			if unhealthy > 0 {
+				return fmt.Errorf("%d of %d supernodes are unhealthy", unhealthy, len(results))
+			}
+			return nil
+		},
+	})
+
+	supernodeCmd.AddCommand(&cobra.Command{
+		Use:   "version",
+		Short: "Get the version of all the supernodes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			versions := make(map[string]*types.DragonflyVersion)
+			for _, supernode := range cfg.Supernodes {
+				cli, err := newAPIClient(cfg, supernode)
+				if err != nil {
+					return err
+				}
+				ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+				version, err := cli.Version(ctx)
+				cancel()
+				if err != nil {
+					return fmt.Errorf("failed to get the version of supernode %s: %v", supernode, err)
+				}
+				versions[supernode] = version
+			}
+			return printer.Fprint(printer.Printer.Out, cfg.Output, versions, versionTable(cfg.Supernodes, versions))
+		},
+	})
+
+	supernodeCmd.AddCommand(newSupernodeMetricsCommand())
+	return supernodeCmd
+}
+
+// healthResult is the result of checking the health of a supernode.
+type healthResult struct {
+	Supernode string `json:"supernode"`
+	Healthy   bool   `json:"healthy"`
+	Latency   string `json:"latency,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func checkHealth(cfg *Config, supernode string) *healthResult {
+	result := &healthResult{Supernode: supernode}
+	cli, err := newAPIClient(cfg, supernode)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	start := time.Now()
+	if _, err := cli.Ping(ctx); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Healthy = true
+	result.Latency = time.Since(start).Round(time.Microsecond).String()
+	return result
+}
+
+func healthTable(results []*healthResult) *printer.Table {
+	table := &printer.Table{
+		Header: []string{"SUPERNODE", "HEALTHY", "LATENCY", "ERROR"},
+	}
+	for _, r := range results {
+		table.Rows = append(table.Rows, []string{
+			r.Supernode, strconv.FormatBool(r.Healthy), r.Latency, r.Error,
+		})
+	}
+	return table
+}
+
+func versionTable(supernodes []string, versions map[string]*types.DragonflyVersion) *printer.Table {
+	table := &printer.Table{
+		Header: []string{"SUPERNODE", "VERSION", "REVISION", "GO VERSION", "OS/ARCH"},
+	}
+	for _, supernode := range supernodes {
+		v := versions[supernode]
+		table.Rows = append(table.Rows, []string{
+			supernode, v.Version, v.Revision, v.GoVersion, v.OS + "/" + v.Arch,
+		})
+	}
+	return table
+}
+
+// metricSummary summarizes a metric family of supernode.
+// The value is the sum of all the series for counters and gauges,
+// and the count and sum are the ones of all the series for histograms and summaries.
+type metricSummary struct {
+	Name   string  `json:"name"`
+	Type   string  `json:"type"`
+	Series int     `json:"series"`
+	Value  float64 `json:"value,omitempty"`
+	Count  uint64  `json:"count,omitempty"`
+	Sum    float64 `json:"sum,omitempty"`
+}
+
+func newSupernodeMetricsCommand() *cobra.Command {
+	var prefix string
+	metricsCmd := &cobra.Command{
+		Use:   "metrics",
+		Short: "Summarize the metrics of supernode",
+		Args:  cobra.NoArgs,
+		RunE: runE(func(r *runner, args []string) error {
+			ctx, cancel := r.context()
+			defer cancel()
+
+			metrics, err := r.client.Metrics(ctx)
+			if err != nil {
+				return err
+			}
+			summaries, err := summarizeMetrics(metrics, prefix)
+			if err != nil {
+				return err
+			}
+			return r.print(summaries, metricsTable(summaries))
+		}),
+	}
+
+	metricsCmd.Flags().StringVar(&prefix, "prefix", "dragonfly_",
+		"only summarize the metrics with the prefix, all the metrics are summarized if it's empty")
+	return metricsCmd
+}
+
+// summarizeMetrics parses the metrics in the Prometheus text format and
+// summarizes the metric families with the prefix.
+func summarizeMetrics(metrics, prefix string) ([]*metricSummary, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(metrics))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %v", err)
+	}
+
+	var summaries []*metricSummary
+	for name, family := range families {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		summary := &metricSummary{
+			Name:   name,
+			Type:   strings.ToLower(family.GetType().String()),
+			Series: len(family.GetMetric()),
+		}
+		for _, m := range family.GetMetric() {
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				summary.Value += m.GetCounter().GetValue()
+			case dto.MetricType_GAUGE:
+				summary.Value += m.GetGauge().GetValue()
+			case dto.MetricType_UNTYPED:
+				summary.Value += m.GetUntyped().GetValue()
+			case dto.MetricType_HISTOGRAM:
+				summary.Count += m.GetHistogram().GetSampleCount()
+				summary.Sum += m.GetHistogram().GetSampleSum()
+			case dto.MetricType_SUMMARY:
+				summary.Count += m.GetSummary().GetSampleCount()
+				summary.Sum += m.GetSummary().GetSampleSum()
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+	return summaries, nil
+}
+
+func metricsTable(summaries []*metricSummary) *printer.Table {
+	table := &printer.Table{
+		Header: []string{"NAME", "TYPE", "SERIES", "VALUE"},
+	}
+	for _, s := range summaries {
+		value := strconv.FormatFloat(s.Value, 'g', -1, 64)
+		if s.Type == "histogram" || s.Type == "summary" {
+			value = fmt.Sprintf("count=%d sum=%s", s.Count, strconv.FormatFloat(s.Sum, 'g', -1, 64))
+		}
+		table.Rows = append(table.Rows, []string{s.Name, s.Type, strconv.Itoa(s.Series), value})
+	}
+	return table
+}
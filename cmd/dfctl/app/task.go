@@ -0,0 +1,213 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/client"
+	"github.com/dragonflyoss/Dragonfly/pkg/printer"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// newTaskCommand returns the command to manage the tasks in supernode.
+func newTaskCommand() *cobra.Command {
+	taskCmd := &cobra.Command{
+		Use:   "task",
+		Short: "Manage the tasks in supernode",
+		Args:  cobra.NoArgs,
+	}
+
+	taskCmd.AddCommand(newTaskListCommand())
+	taskCmd.AddCommand(newTaskInfoCommand())
+	taskCmd.AddCommand(newTaskDeleteCommand())
+	taskCmd.AddCommand(newTaskDistributionCommand())
+	return taskCmd
+}
+
+// addTaskFilterFlags adds the flags to filter tasks.
+func addTaskFilterFlags(flagSet *pflag.FlagSet, filter *client.TaskFilter) {
+	flagSet.StringVar(&filter.URLPattern, "url", "",
+		"the regular expression which the raw URL of tasks should match")
+	flagSet.StringSliceVar(&filter.CdnStatus, "cdn-status", nil,
+		"the cdn status of tasks, must be in [WAITING RUNNING FAILED SUCCESS SOURCE_ERROR]")
+	flagSet.StringVar(&filter.Tenant, "tenant", "",
+		"the tenant which creates the tasks")
+	flagSet.DurationVar(&filter.MinAge, "min-age", 0,
+		"the minimum duration since the tasks were accessed last time")
+	flagSet.DurationVar(&filter.MaxAge, "max-age", 0,
+		"the maximum duration since the tasks were accessed last time")
+	flagSet.StringVar(&filter.MinSize, "min-size", "",
+		"the minimum source file length of tasks, such as 100MB")
+	flagSet.StringVar(&filter.MaxSize, "max-size", "",
+		"the maximum source file length of tasks, such as 1GB")
+}
+
+func newTaskListCommand() *cobra.Command {
+	opts := &client.TaskListOptions{}
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the tasks in supernode",
+		Args:  cobra.NoArgs,
+		RunE: runE(func(r *runner, args []string) error {
+			ctx, cancel := r.context()
+			defer cancel()
+
+			tasks, err := r.client.TaskList(ctx, opts)
+			if err != nil {
+				return err
+			}
+			return r.print(tasks, taskTable(tasks))
+		}),
+	}
+
+	flagSet := listCmd.Flags()
+	addTaskFilterFlags(flagSet, &opts.TaskFilter)
+	flagSet.IntVar(&opts.PageNum, "page-num", 0,
+		"the page number starting from 0")
+	flagSet.IntVar(&opts.PageSize, "page-size", 0,
+		"the number of tasks in a page, all the tasks are listed if it's 0")
+	flagSet.StringVar(&opts.SortKey, "sort-key", "",
+		"the key to sort the tasks by, must be in [id accessTime fileLength]")
+	flagSet.StringVar(&opts.SortDirect, "sort-direct", "",
+		"the direction to sort the tasks, must be in [ASC DESC]")
+	return listCmd
+}
+
+func newTaskInfoCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "info ID",
+		Short: "Get the detailed information of a task",
+		Args:  cobra.ExactArgs(1),
+		RunE: runE(func(r *runner, args []string) error {
+			ctx, cancel := r.context()
+			defer cancel()
+
+			task, err := r.client.TaskInfo(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			return r.print(task, nil)
+		}),
+	}
+}
+
+func newTaskDeleteCommand() *cobra.Command {
+	opts := &client.TaskBulkDeleteOptions{}
+	deleteCmd := &cobra.Command{
+		Use:   "delete [ID...]",
+		Short: "Delete the tasks with the IDs or matching the filters",
+		Long: `Delete the tasks with the IDs, or delete the tasks matching the filters if no ID is given.
+At least one filter is required to delete tasks in bulk unless --all is set.`,
+		RunE: runE(func(r *runner, args []string) error {
+			ctx, cancel := r.context()
+			defer cancel()
+
+			if len(args) == 0 {
+				tasks, err := r.client.TaskBulkDelete(ctx, opts)
+				if err != nil {
+					return err
+				}
+				return r.print(tasks, taskTable(tasks))
+			}
+
+			for _, id := range args {
+				if err := r.client.TaskDelete(ctx, id, opts.Full); err != nil {
+					return fmt.Errorf("failed to delete task %s: %v", id, err)
+				}
+				printer.Println(id)
+			}
+			return nil
+		}),
+	}
+
+	flagSet := deleteCmd.Flags()
+	addTaskFilterFlags(flagSet, &opts.TaskFilter)
+	flagSet.BoolVar(&opts.Full, "full", false,
+		"evict the cdn files of the tasks as well")
+	flagSet.BoolVar(&opts.All, "all", false,
+		"delete all the tasks if no filter is given")
+	flagSet.BoolVar(&opts.DryRun, "dry-run", false,
+		"only print the tasks matching the filters without deleting them")
+	return deleteCmd
+}
+
+func newTaskDistributionCommand() *cobra.Command {
+	var compact bool
+	distributionCmd := &cobra.Command{
+		Use:   "distribution ID",
+		Short: "Get which peers hold which pieces of a task and the load of the peers",
+		Args:  cobra.ExactArgs(1),
+		RunE: runE(func(r *runner, args []string) error {
+			ctx, cancel := r.context()
+			defer cancel()
+
+			distribution, err := r.client.TaskDistribution(ctx, args[0], compact)
+			if err != nil {
+				return err
+			}
+			return r.print(distribution, peerDistributionTable(distribution))
+		}),
+	}
+
+	distributionCmd.Flags().BoolVar(&compact, "compact", false,
+		"omit the holders of pieces, the blacklists and the downloading sources of peers")
+	return distributionCmd
+}
+
+func taskTable(tasks []*types.TaskInfo) *printer.Table {
+	table := &printer.Table{
+		Header: []string{"ID", "CDN STATUS", "FILE LENGTH", "PIECES", "TENANT", "URL"},
+	}
+	for _, t := range tasks {
+		table.Rows = append(table.Rows, []string{
+			t.ID,
+			t.CdnStatus,
+			strconv.FormatInt(t.HTTPFileLength, 10),
+			strconv.Itoa(int(t.PieceTotal)),
+			t.Tenant,
+			t.RawURL,
+		})
+	}
+	return table
+}
+
+func peerDistributionTable(distribution *types.TaskDistribution) *printer.Table {
+	table := &printer.Table{
+		Header: []string{"PEER ID", "PIECES", "PRODUCER LOAD", "CONSUMER LOAD",
+			"CLIENT ERRORS", "SERVICE ERRORS", "SCORE", "CLIENT SCORE", "OFFLINE", "ELIMINATED"},
+	}
+	for _, p := range distribution.Peers {
+		table.Rows = append(table.Rows, []string{
+			p.PeerID,
+			fmt.Sprintf("%d/%d", p.PieceCount, distribution.PieceTotal),
+			strconv.Itoa(int(p.ProducerLoad)),
+			strconv.Itoa(int(p.ConsumerLoad)),
+			strconv.Itoa(int(p.ClientErrorCount)),
+			strconv.Itoa(int(p.ServiceErrorCount)),
+			strconv.FormatFloat(p.Score, 'f', 2, 64),
+			strconv.FormatFloat(p.ClientScore, 'f', 2, 64),
+			strconv.FormatBool(p.Offline),
+			strconv.FormatBool(p.Eliminated),
+		})
+	}
+	return table
+}
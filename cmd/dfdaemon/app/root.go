@@ -27,10 +27,13 @@ import (
 	"github.com/dragonflyoss/Dragonfly/dfdaemon"
 	"github.com/dragonflyoss/Dragonfly/dfdaemon/config"
 	"github.com/dragonflyoss/Dragonfly/dfdaemon/constant"
+	dfgetConfig "github.com/dragonflyoss/Dragonfly/dfget/config"
 	"github.com/dragonflyoss/Dragonfly/pkg/cmd"
 	dferr "github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/fileutils"
 	"github.com/dragonflyoss/Dragonfly/pkg/netutils"
 	"github.com/dragonflyoss/Dragonfly/pkg/rate"
+	"github.com/dragonflyoss/Dragonfly/pkg/tracing"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
@@ -68,6 +71,12 @@ var rootCmd = &cobra.Command{
 		cfgJSON, _ := json.Marshal(cfg)
 		logrus.Infof("using config: %s", cfgJSON)
 
+		shutdownTracing, err := tracing.Init("dfdaemon", cfg.Tracing)
+		if err != nil {
+			return errors.Wrap(err, "init tracing")
+		}
+		defer shutdownTracing()
+
 		s, err := dfdaemon.NewFromConfig(*cfg)
 		if err != nil {
 			return errors.Wrap(err, "create dfdaemon from config")
@@ -99,6 +108,8 @@ func init() {
 	rf.Uint("port", 65001, "dfdaemon will listen the port")
 	rf.Uint("peerPort", 0, "peerserver will listen the port")
 	rf.Bool("streamMode", false, "dfdaemon will run in stream mode")
+	rf.String("streamBufferSize", dfgetConfig.DefaultStreamBufferSize.String(),
+		"the memory budget of dfget for the pieces which arrive out of order in stream mode, the pieces beyond it are spilled to disk, 0 means no limit")
 	rf.String("certpem", "", "cert.pem file path")
 	rf.String("keypem", "", "key.pem file path")
 
@@ -111,6 +122,10 @@ func init() {
 	rf.Var(netutils.NetLimit(), "ratelimit", "net speed limit")
 	rf.StringSlice("node", nil, "specify the addresses(host:port) of supernodes that will be passed to dfget.")
 
+	// seed config
+	rf.Bool("enableSeed", false, "enable the seed files which are cached from the source and served by range through the /seeds endpoints")
+	rf.String("seedDir", "", "the directory to store the seed files, default: {workHome}/dfdaemon/seed/")
+
 	exitOnError(bindRootFlags(viper.GetViper()), "bind root command flags")
 
 	// add sub commands
@@ -128,6 +143,12 @@ func bindRootFlags(v *viper.Viper) error {
 	if err := v.BindPFlag("registry_mirror.remote", rootCmd.Flag("registry")); err != nil {
 		return err
 	}
+	if err := v.BindPFlag("seed.enable", rootCmd.Flag("enableSeed")); err != nil {
+		return err
+	}
+	if err := v.BindPFlag("seed.dir", rootCmd.Flag("seedDir")); err != nil {
+		return err
+	}
 	v.SetEnvPrefix(DFDaemonEnvPrefix)
 	v.AutomaticEnv()
 
@@ -193,6 +214,7 @@ func getConfigFromViper(cmd *cobra.Command, v *viper.Viper) (*config.Properties,
 			reflect.TypeOf(config.CertPool{}),
 			reflect.TypeOf(time.Second),
 			reflect.TypeOf(rate.B),
+			reflect.TypeOf(fileutils.B),
 		)
 	}); err != nil {
 		return nil, errors.Wrap(err, "unmarshal yaml")
@@ -202,6 +224,10 @@ func getConfigFromViper(cmd *cobra.Command, v *viper.Viper) (*config.Properties,
 	if cfg.DFRepo == "" {
 		cfg.DFRepo = filepath.Join(cfg.WorkHome, "dfdaemon/data/")
 	}
+	// use `{WorkHome}/dfdaemon/seed/` as seed dir if it's not configured.
+	if cfg.Seed.Dir == "" {
+		cfg.Seed.Dir = filepath.Join(cfg.WorkHome, "dfdaemon/seed/")
+	}
 
 	return &cfg, cfg.Validate()
 }
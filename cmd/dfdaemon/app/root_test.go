@@ -135,6 +135,20 @@ func (ts *rootTestSuite) TestBindRootFlags() {
 	v := viper.New()
 	r.Nil(bindRootFlags(v))
 	r.Equal(v.GetString("registry"), v.GetString("registry_mirror.remote"))
+	r.Equal(v.GetBool("enableSeed"), v.GetBool("seed.enable"))
+	r.Equal(v.GetString("seedDir"), v.GetString("seed.dir"))
+}
+
+func (ts *rootTestSuite) TestSeedDir() {
+	r := ts.Require()
+	v := viper.New()
+	v.Set("dfpath", "/")
+	v.Set("localrepo", "/dfdaemon/data")
+	v.Set("workHome", "/dragonfly/home")
+	r.Nil(bindRootFlags(v))
+	cfg, err := getConfigFromViper(rootCmd, v)
+	r.Nil(err)
+	r.Equal("/dragonfly/home/dfdaemon/seed", cfg.Seed.Dir)
 }
 
 func (ts *rootTestSuite) TestAutomaticEnv() {
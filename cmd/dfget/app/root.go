@@ -26,11 +26,13 @@ import (
 
 	"github.com/dragonflyoss/Dragonfly/dfget/config"
 	"github.com/dragonflyoss/Dragonfly/dfget/core"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/progress"
 	"github.com/dragonflyoss/Dragonfly/pkg/cmd"
 	"github.com/dragonflyoss/Dragonfly/pkg/dflog"
 	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
 	"github.com/dragonflyoss/Dragonfly/pkg/printer"
 	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
+	"github.com/dragonflyoss/Dragonfly/pkg/tracing"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -43,6 +45,10 @@ type propertiesResult struct {
 	err      error
 }
 
+// tracingShutdownTimeout bounds the time to export the pending spans before
+// dfget exits, the spans are dropped if the collector doesn't respond in time.
+const tracingShutdownTimeout = 300 * time.Millisecond
+
 var filter string
 
 var cfg = config.NewConfig()
@@ -107,15 +113,62 @@ func runDfget() error {
 	}
 	logrus.Infof("get init config:%v", cfg)
 
+	// the file is written to stdout, so print messages to stderr instead.
+	if cfg.RV.StreamMode {
+		printer.Printer.Out = os.Stderr
+	}
+
+	if err := initProgress(); err != nil {
+		return err
+	}
+
+	// tracing is configured by the environment, dfdaemon sets it
+	// when it's enabled there.
+	shutdownTracing, err := tracing.Init("dfget", tracing.ConfigFromEnv())
+	if err != nil {
+		logrus.Warnf("failed to init tracing: %v", err)
+		shutdownTracing = func() {}
+	}
+
 	// enter the core process
 	dfError := core.Start(cfg)
+	if !tracing.ShutdownWithTimeout(shutdownTracing, tracingShutdownTimeout) {
+		logrus.Warnf("failed to export the spans in %v", tracingShutdownTimeout)
+	}
 	printer.Println(resultMsg(cfg, time.Now(), dfError))
+	reportSummary(cfg, dfError)
 	if dfError != nil {
 		os.Exit(dfError.Code)
 	}
 	return nil
 }
 
+// initProgress initializes the reporter which outputs machine-readable
+// progress events to stderr or the file descriptor specified by '--progress-fd'.
+func initProgress() error {
+	if cfg.ProgressFormat == "" {
+		return nil
+	}
+	fd := cfg.ProgressFD
+	if fd == 0 {
+		fd = config.DefaultProgressFD
+	}
+	out := os.NewFile(uintptr(fd), fmt.Sprintf("progress-fd-%d", fd))
+	if out == nil {
+		return fmt.Errorf("invalid progress fd: %d", fd)
+	}
+	progress.SetDefault(progress.NewReporter(out))
+	return nil
+}
+
+func reportSummary(cfg *config.Config, e *errortypes.DfError) {
+	if e != nil {
+		progress.Summary(cfg.RV.FileLength, cfg.BackSourceReason, e.Code, e)
+		return
+	}
+	progress.Summary(cfg.RV.FileLength, cfg.BackSourceReason, 0, nil)
+}
+
 func checkParameters() error {
 	if len(os.Args) < 2 {
 		return errortypes.New(-1, "Please use the command 'help' to show the help information.")
@@ -163,6 +216,23 @@ func initProperties() ([]*propertiesResult, error) {
 		cfg.ClientQueueSize = properties.ClientQueueSize
 	}
 
+	if !cfg.Cluster {
+		cfg.Cluster = properties.Cluster
+	}
+
+	if cfg.IDC == "" {
+		cfg.IDC = properties.IDC
+	}
+
+	if len(cfg.Labels) == 0 {
+		cfg.Labels = properties.Labels
+	}
+
+	// the token is read after parsing the flags, so that it's never printed as the default value.
+	if cfg.AuthToken == "" {
+		cfg.AuthToken = os.Getenv(config.AuthTokenEnv)
+	}
+
 	currentUser, err := user.Current()
 	if err != nil {
 		printer.Println(fmt.Sprintf("get user error: %s", err))
@@ -213,7 +283,7 @@ func initFlags() {
 	// url & output
 	flagSet.StringVarP(&cfg.URL, "url", "u", "", "URL of user requested downloading file(only HTTP/HTTPs supported)")
 	flagSet.StringVarP(&cfg.Output, "output", "o", "",
-		"destination path which is used to store the requested downloading file. It must contain detailed directory and specific filename, for example, '/tmp/file.mp4'")
+		"destination path which is used to store the requested downloading file. It must contain detailed directory and specific filename, for example, '/tmp/file.mp4'. Use '-' to write the file to stdout")
 
 	// localLimit & minRate & totalLimit & timeout
 	flagSet.VarP(&cfg.LocalLimit, "locallimit", "s",
@@ -236,6 +306,12 @@ func initFlags() {
 		"the cacert file which is used to verify remote server when supernode interact with the source.")
 	flagSet.StringVarP(&cfg.Pattern, "pattern", "p", "p2p",
 		"download pattern, must be p2p/cdn/source, cdn and source do not support flag --totallimit")
+	flagSet.StringVar(&cfg.Tenant, "tenant", "",
+		"the tenant which dfget belongs to, the supernode only schedules the peers of the tenants which can share with it")
+	flagSet.StringVar(&cfg.TenantToken, "tenant-token", "",
+		"the token which authenticates dfget as a member of a tenant, it takes precedence over --tenant")
+	flagSet.StringVar(&cfg.AuthToken, "auth-token", "",
+		"the bearer token to call the supernode APIs when the authentication of supernode is enabled, it can also be set by the environment variable "+config.AuthTokenEnv)
 	flagSet.StringVarP(&filter, "filter", "f", "",
 		"filter some query params of URL, use char '&' to separate different params"+
 			"\neg: -f 'key&sign' will filter 'key' and 'sign' query param"+
@@ -250,6 +326,18 @@ func initFlags() {
 		"identify whether the request is from dfdaemon")
 	flagSet.BoolVar(&cfg.Insecure, "insecure", false,
 		"identify whether supernode should skip secure verify when interact with the source.")
+	flagSet.BoolVar(&cfg.AdaptiveRate, "adaptive-rate", false,
+		"adapt the download concurrency of every peer and the download rate according to the observed throughput and RTT, the rate is still bounded by --locallimit, --minrate and --totallimit")
+	flagSet.IntVar(&cfg.HedgePercentile, "hedge-percentile", 0,
+		"send a hedged request to an alternative peer when downloading a piece takes longer than the given percentile(1-99) of the latency of the latest pieces, 0 disables it")
+	flagSet.Var(&cfg.StreamBufferSize, "stream-buffer-size",
+		"the memory budget for the pieces which arrive out of order when writing to stdout, in format of G(B)/M(B)/K(B)/B, the pieces beyond it are spilled to disk, 0 means no limit")
+	flagSet.BoolVar(&cfg.Cluster, "cluster", false,
+		"the supernodes form a cluster in which every task is owned by one of them, skip the unreachable ones and follow the redirection to the owner")
+	flagSet.StringVar(&cfg.IDC, "idc", "",
+		"the data center which the host locates in, it's reported to supernode to select the target peers of a preheat task")
+	flagSet.StringToStringVar(&cfg.Labels, "label", nil,
+		"the labels(key=value) of the host, they're reported to supernode to select the target peers of a preheat task")
 	flagSet.IntVar(&cfg.ClientQueueSize, "clientqueue", config.DefaultClientQueueSize,
 		"specify the size of client queue which controls the number of pieces that can be processed simultaneously")
 
@@ -260,6 +348,10 @@ func initFlags() {
 		"show log on console, it's conflict with '--showbar'")
 	flagSet.BoolVar(&cfg.Verbose, "verbose", false,
 		"be verbose")
+	flagSet.StringVar(&cfg.ProgressFormat, "progress-format", "",
+		"output machine-readable progress events in the given format, only 'json' is supported which emits one JSON event per line")
+	flagSet.IntVar(&cfg.ProgressFD, "progress-fd", config.DefaultProgressFD,
+		"the file descriptor which the progress events are written to, it is only useful when '--progress-format' is set")
 	flagSet.StringVar(&cfg.WorkHome, "home", cfg.WorkHome,
 		"the work home directory of dfget")
 
@@ -312,5 +404,7 @@ sign:96414-1549104994.143
 client:127.0.0.1 connected to node:127.0.0.1
 start download by dragonfly...
 download SUCCESS cost:0.026s length:141898 reason:0
+
+$ dfget -u https://www.taobao.com/files.tar -o - | tar x
 `
 }
@@ -103,6 +103,27 @@ func (suit *dfgetSuit) Test_HeaderFlags() {
 	suit.Equal(cfg.Header, []string{"Host: abc", "Date:Mon, 30 Dec 2019"})
 }
 
+func (suit *dfgetSuit) Test_AuthTokenEnv() {
+	// the flags are bound to cfg, so restore the token only.
+	defer func(token string) {
+		cfg.AuthToken = token
+	}(cfg.AuthToken)
+	os.Setenv(config.AuthTokenEnv, "secret")
+	defer os.Unsetenv(config.AuthTokenEnv)
+
+	// the token in the environment isn't shown in the help
+	suit.Equal(rootCmd.Flags().Lookup("auth-token").DefValue, "")
+
+	cfg.AuthToken = ""
+	initProperties()
+	suit.Equal(cfg.AuthToken, "secret")
+
+	// the flag takes precedence over the environment
+	rootCmd.Flags().Parse([]string{"--auth-token", "token"})
+	initProperties()
+	suit.Equal(cfg.AuthToken, "token")
+}
+
 func (suit *dfgetSuit) Test_transFilter() {
 	var cases = []string{
 		"a&b&c",
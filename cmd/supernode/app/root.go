@@ -30,6 +30,7 @@ import (
 	"github.com/dragonflyoss/Dragonfly/pkg/netutils"
 	"github.com/dragonflyoss/Dragonfly/pkg/rate"
 	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
+	"github.com/dragonflyoss/Dragonfly/pkg/tracing"
 	"github.com/dragonflyoss/Dragonfly/supernode/config"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon"
 
@@ -91,6 +92,12 @@ var rootCmd = &cobra.Command{
 			return err
 		}
 
+		// initialize audit logger.
+		auditLogger := logrus.New()
+		if err := initLog(auditLogger, "audit.log", cfg.LogConfig); err != nil {
+			return err
+		}
+
 		// set supernode advertise ip
 		if stringutils.IsEmptyStr(cfg.AdvertiseIP) {
 			if err := setAdvertiseIP(cfg); err != nil {
@@ -103,9 +110,17 @@ var rootCmd = &cobra.Command{
 		cfg.SetCIDPrefix(cfg.AdvertiseIP)
 
 		logrus.Debugf("get supernode config: %+v", cfg)
+
+		// initialize tracing.
+		shutdownTracing, err := tracing.Init("supernode", cfg.Tracing)
+		if err != nil {
+			return errors.Wrap(err, "init tracing")
+		}
+		defer shutdownTracing()
+
 		logrus.Info("start to run supernode")
 
-		d, err := daemon.New(cfg, dfgetLogger)
+		d, err := daemon.New(cfg, dfgetLogger, auditLogger)
 		if err != nil {
 			logrus.Errorf("failed to initialize daemon in supernode: %v", err)
 			return err
@@ -128,6 +143,7 @@ func init() {
 	rootCmd.AddCommand(cmd.NewGenDocCommand("supernode"))
 	rootCmd.AddCommand(cmd.NewVersionCommand("supernode"))
 	rootCmd.AddCommand(cmd.NewConfigCommand("supernode", getDefaultConfig))
+	rootCmd.AddCommand(newTokenCommand())
 }
 
 // setupFlags setups flags for command line.
@@ -146,7 +162,7 @@ func setupFlags(cmd *cobra.Command) {
 		"the path of supernode's configuration file")
 
 	flagSet.String("cdn-pattern", config.CDNPatternLocal,
-		"cdn pattern, must be in [\"local\", \"source\"]. Default: local")
+		"cdn pattern, must be in [\"local\", \"source\", \"parent\"]. Default: local")
 
 	flagSet.Int("port", defaultBaseProperties.ListenPort,
 		"listenPort is the port that supernode server listens on")
@@ -0,0 +1,86 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+	"github.com/dragonflyoss/Dragonfly/supernode/server/auth"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// defaultTokenTTL is the default time to live of the generated tokens.
+const defaultTokenTTL = 30 * 24 * time.Hour
+
+// newTokenCommand returns the command which generates the HMAC tokens
+// signed by the hmacSecret in the supernode config.
+func newTokenCommand() *cobra.Command {
+	var (
+		name  string
+		roles []string
+		ttl   time.Duration
+	)
+
+	tokenCmd := &cobra.Command{
+		Use:               "token",
+		Short:             "Generate an HMAC token to call the supernode APIs",
+		Args:              cobra.NoArgs,
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.New()
+			if err := v.BindPFlag("config", cmd.Flag("config")); err != nil {
+				return err
+			}
+			if err := readConfigFile(v, cmd); err != nil {
+				return errors.Wrap(err, "read config file")
+			}
+			cfg, err := getConfigFromViper(v)
+			if err != nil {
+				return errors.Wrap(err, "get config from viper")
+			}
+
+			rs, err := auth.ParseRoles(roles)
+			if err != nil {
+				return err
+			}
+			token, err := auth.GenerateHMACToken(cfg.Auth.HMACSecret, name, rs, ttl)
+			if err != nil {
+				return errors.Wrap(err, "generate token")
+			}
+			fmt.Println(token)
+			return nil
+		},
+	}
+
+	flagSet := tokenCmd.Flags()
+	flagSet.String("config", config.DefaultSupernodeConfigFilePath,
+		"the path of supernode's configuration file which contains the hmacSecret")
+	flagSet.StringVar(&name, "name", "",
+		"the name of the caller which is written to the audit log")
+	flagSet.StringSliceVar(&roles, "role", []string{string(auth.RolePeer)},
+		"the roles granted to the token, must be in [readonly, peer, admin]")
+	flagSet.DurationVar(&ttl, "ttl", defaultTokenTTL,
+		"the time to live of the token")
+
+	return tokenCmd
+}
@@ -27,7 +27,9 @@ import (
 	"github.com/dragonflyoss/Dragonfly/dfdaemon/constant"
 	"github.com/dragonflyoss/Dragonfly/pkg/dflog"
 	dferr "github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/fileutils"
 	"github.com/dragonflyoss/Dragonfly/pkg/rate"
+	"github.com/dragonflyoss/Dragonfly/pkg/tracing"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
@@ -114,6 +116,18 @@ type Properties struct {
 	LocalIP    string          `yaml:"localIP" json:"localIP"`
 	PeerPort   int             `yaml:"peerPort" json:"peerPort"`
 	StreamMode bool            `yaml:"streamMode" json:"streamMode"`
+
+	// StreamBufferSize is the memory budget of dfget for the pieces which
+	// arrive out of order in stream mode, the pieces beyond it are spilled to disk.
+	StreamBufferSize fileutils.Fsize `yaml:"streamBufferSize" json:"streamBufferSize"`
+
+	// Tracing configures where the spans of dfdaemon and the dfget
+	// processes it starts are exported.
+	Tracing tracing.Config `yaml:"tracing" json:"tracing"`
+
+	// Seed configures the seed files which dfdaemon caches from the
+	// source and serves by range through the /seeds endpoints.
+	Seed SeedConfig `yaml:"seed" json:"seed"`
 }
 
 // Validate validates the config
@@ -153,6 +167,9 @@ func (p *Properties) DFGetConfig() DFGetConfig {
 		DFPath:     p.DFPath,
 		LocalIP:    p.LocalIP,
 		PeerPort:   p.PeerPort,
+		Tracing:    p.Tracing,
+
+		StreamBufferSize: p.StreamBufferSize,
 	}
 	if p.HijackHTTPS != nil {
 		dfgetConfig.HostsConfig = p.HijackHTTPS.Hosts
@@ -178,8 +195,11 @@ type DFGetConfig struct {
 	DFRepo      string        `yaml:"localrepo"`
 	DFPath      string        `yaml:"dfpath"`
 	HostsConfig []*HijackHost `yaml:"hosts" json:"hosts"`
-	PeerPort    int           `yaml:"peerPort"`
-	LocalIP     string        `yaml:"localIP"`
+	PeerPort    int            `yaml:"peerPort"`
+	LocalIP     string         `yaml:"localIP"`
+	Tracing     tracing.Config `yaml:"tracing"`
+
+	StreamBufferSize fileutils.Fsize `yaml:"streamBufferSize"`
 }
 
 // RegistryMirror configures the mirror of the official docker registry
@@ -221,6 +241,27 @@ type HijackConfig struct {
 	Hosts []*HijackHost `yaml:"hosts" json:"hosts"`
 }
 
+// SeedConfig configures the seed manager of dfdaemon.
+type SeedConfig struct {
+	// Enable enables the seed manager and the /seeds endpoints.
+	Enable bool `yaml:"enable" json:"enable"`
+	// Dir is the directory to store the seed files,
+	// `{WorkHome}/dfdaemon/seed/` is used if it's empty.
+	Dir string `yaml:"dir" json:"dir"`
+	// TotalLimit is the max number of seeds, the least recently used one
+	// is evicted when it's reached.
+	TotalLimit int `yaml:"totalLimit" json:"totalLimit"`
+	// ConcurrentLimit is the max number of seeds prefetched at the same time.
+	ConcurrentLimit int `yaml:"concurrentLimit" json:"concurrentLimit"`
+	// BlockOrder is the order of the size of the blocks the seed files are
+	// cached by, e.g. 22 means 4MB. It should be limited to [10, 31].
+	BlockOrder uint32 `yaml:"blockOrder" json:"blockOrder"`
+	// OpenMemoryCache caches the downloading block in memory before writing it to the disk.
+	OpenMemoryCache bool `yaml:"openMemoryCache" json:"openMemoryCache"`
+	// DownloadRate limits the rate of prefetching, 0 means no limit.
+	DownloadRate rate.Rate `yaml:"downloadRate" json:"downloadRate"`
+}
+
 // HijackHost is a hijack rule for the hosts that matches Regx.
 type HijackHost struct {
 	Regx     *Regexp   `yaml:"regx" json:"regx"`
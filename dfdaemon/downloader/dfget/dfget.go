@@ -17,18 +17,27 @@
 package dfget
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	netUrl "net/url"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"syscall"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dragonflyoss/Dragonfly/dfdaemon/config"
 	"github.com/dragonflyoss/Dragonfly/dfdaemon/constant"
 	"github.com/dragonflyoss/Dragonfly/dfdaemon/exception"
+	dfgetConfig "github.com/dragonflyoss/Dragonfly/dfget/config"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/progress"
+	"github.com/dragonflyoss/Dragonfly/pkg/tracing"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -45,20 +54,196 @@ func NewGetter(cfg config.DFGetConfig) *DFGetter {
 
 // DownloadContext downloads the resources as specified in url.
 func (dfGetter *DFGetter) DownloadContext(ctx context.Context, url string, header map[string][]string, name string) (string, error) {
+	dstPath, _, err := dfGetter.DownloadBackSourceContext(ctx, url, header, name)
+	return dstPath, err
+}
+
+// DownloadBackSourceContext downloads the resources as specified in url,
+// and returns whether dfget fell back to download it from the source.
+func (dfGetter *DFGetter) DownloadBackSourceContext(ctx context.Context, url string, header map[string][]string, name string) (string, bool, error) {
 	startTime := time.Now()
 	dstPath := filepath.Join(dfGetter.config.DFRepo, name)
 	cmd := dfGetter.getCommand(ctx, url, header, dstPath)
-	err := cmd.Run()
-	if cmd.ProcessState.Success() {
+	progressReader, progressWriter, err := pipeProgress(cmd)
+	if err != nil {
+		return "", false, err
+	}
+
+	var watcher *progressWatcher
+	if err = cmd.Start(); err == nil {
+		progressWriter.Close()
+		watcher = watchProgress(progressReader)
+		err = cmd.Wait()
+		watcher.wait()
+	} else {
+		progressReader.Close()
+		progressWriter.Close()
+	}
+	// the exit code is -1 if dfget failed to start or was killed
+	code := cmd.ProcessState.ExitCode()
+	dfgetMetrics.duration.WithLabelValues().Observe(time.Since(startTime).Seconds())
+	dfgetMetrics.invocations.WithLabelValues(strconv.Itoa(code)).Inc()
+	if code == 0 {
 		log.Infof("dfget url:%s [SUCCESS] cost:%.3fs", url, time.Since(startTime).Seconds())
-		return dstPath, nil
+		return dstPath, watcher.BackSource(), nil
+	}
+	if code == constant.CodeReqAuth {
+		return "", false, &exception.AuthError{}
+	}
+	return "", false, fmt.Errorf("dfget fail(%s):%v", cmd.ProcessState.String(), err)
+}
+
+// DownloadStreamContext downloads the resources as specified in url, and
+// returns the content which dfget writes to stdout in order.
+func (dfGetter *DFGetter) DownloadStreamContext(ctx context.Context, url string, header map[string][]string, name string) (io.Reader, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	cmd := dfGetter.getCommand(ctx, url, header, dfgetConfig.StdoutOutput)
+	cmd.Args = append(cmd.Args, "--stream-buffer-size", dfGetter.config.StreamBufferSize.String())
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	progressReader, progressWriter, err := pipeProgress(cmd)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	startTime := time.Now()
+	if err := cmd.Start(); err != nil {
+		cancel()
+		progressReader.Close()
+		progressWriter.Close()
+		return nil, err
+	}
+	progressWriter.Close()
+
+	return &streamReader{
+		url:       url,
+		cmd:       cmd,
+		stdout:    stdout,
+		cancel:    cancel,
+		progress:  watchProgress(progressReader),
+		startTime: startTime,
+	}, nil
+}
+
+// streamReader reads the content from the stdout of dfget, and waits for
+// dfget to exit when the content is read completely or it's closed.
+type streamReader struct {
+	url       string
+	cmd       *exec.Cmd
+	stdout    io.Reader
+	cancel    func()
+	progress  *progressWatcher
+	startTime time.Time
+
+	once sync.Once
+	err  error
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	n, err := r.stdout.Read(p)
+	if err == io.EOF {
+		// dfget may fail after writing part of the content.
+		if err := r.wait(); err != nil {
+			return n, err
+		}
 	}
-	if value, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok {
-		if value.ExitStatus() == constant.CodeReqAuth {
-			return "", &exception.AuthError{}
+	return n, err
+}
+
+// Close kills dfget if it's still running and releases the resources.
+func (r *streamReader) Close() error {
+	r.cancel()
+	r.wait()
+	return nil
+}
+
+func (r *streamReader) wait() error {
+	r.once.Do(func() {
+		err := r.cmd.Wait()
+		r.progress.wait()
+		r.cancel()
+
+		code := r.cmd.ProcessState.ExitCode()
+		dfgetMetrics.duration.WithLabelValues().Observe(time.Since(r.startTime).Seconds())
+		dfgetMetrics.invocations.WithLabelValues(strconv.Itoa(code)).Inc()
+		if code == 0 {
+			log.Infof("dfget stream url:%s [SUCCESS] cost:%.3fs", r.url, time.Since(r.startTime).Seconds())
+			return
 		}
+		r.err = fmt.Errorf("dfget fail(%s):%v", r.cmd.ProcessState.String(), err)
+	})
+	return r.err
+}
+
+// BackSource returns whether dfget has fallen back to download the content from the source.
+func (r *streamReader) BackSource() bool {
+	return r.progress.BackSource()
+}
+
+// pipeProgress asks dfget to write the progress events to a pipe,
+// and the writer should be closed after dfget starts.
+func pipeProgress(cmd *exec.Cmd) (*os.File, *os.File, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
 	}
-	return "", fmt.Errorf("dfget fail(%s):%v", cmd.ProcessState.String(), err)
+	cmd.Args = append(cmd.Args,
+		"--progress-format", dfgetConfig.ProgressFormatJSON,
+		// the first one of ExtraFiles is the fd 3 of dfget
+		"--progress-fd", "3")
+	cmd.ExtraFiles = []*os.File{w}
+	return r, w, nil
+}
+
+// progressWatcher reads the progress events of dfget until it exits.
+type progressWatcher struct {
+	backSource int32
+	done       chan struct{}
+}
+
+// watchProgress reads the progress events from r in the background,
+// and records the usage of the stream buffer when dfget exits.
+func watchProgress(r io.ReadCloser) *progressWatcher {
+	w := &progressWatcher{done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		defer r.Close()
+		var last *progress.Event
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			e := &progress.Event{}
+			if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+				continue
+			}
+			switch e.Type {
+			case progress.EventBackSource:
+				atomic.StoreInt32(&w.backSource, 1)
+			case progress.EventStreamBuffer:
+				last = e
+			}
+		}
+		// the values of the events are accumulated, and only the last one is recorded.
+		if last != nil {
+			dfgetMetrics.streamBufferPeak.WithLabelValues().Observe(float64(last.BufferPeak))
+			dfgetMetrics.streamBufferSpilled.WithLabelValues().Add(float64(last.SpilledBytes))
+		}
+	}()
+	return w
+}
+
+// BackSource returns whether dfget has reported falling back to the source.
+func (w *progressWatcher) BackSource() bool {
+	return atomic.LoadInt32(&w.backSource) == 1
+}
+
+// wait waits until dfget closes the progress pipe.
+func (w *progressWatcher) wait() {
+	<-w.done
 }
 
 // getCommand returns the command to download the given resource.
@@ -108,5 +293,11 @@ func (dfGetter *DFGetter) getCommand(
 		}
 	}
 
-	return exec.CommandContext(ctx, dfGetter.config.DFPath, args...)
+	cmd = exec.CommandContext(ctx, dfGetter.config.DFPath, args...)
+	// dfget exports its spans as dfdaemon does and continues the trace
+	// of the request.
+	if env := append(dfGetter.config.Tracing.Environ(), tracing.Environ(ctx)...); len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	return cmd
 }
@@ -0,0 +1,104 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfget
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dragonflyoss/Dragonfly/dfdaemon/config"
+
+	"github.com/prometheus/client_golang/prometheus"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDfget writes the content to stdout, the event in $EVENT if any
+// and a streamBuffer event to fd 3.
+const fakeDfget = `#!/bin/sh
+printf 'content'
+[ -n "$EVENT" ] && echo "{\"type\":\"$EVENT\"}" >&3
+echo '{"type":"streamBuffer","bufferPeak":1048576,"spilledBytes":512}' >&3
+exit $EXIT_CODE
+`
+
+func TestDownloadStreamContext(t *testing.T) {
+	dfgetMetrics = newMetrics(prometheus.NewRegistry())
+	workHome, err := ioutil.TempDir("", "dfdaemon-dfget-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(workHome)
+	dfpath := filepath.Join(workHome, "dfget")
+	assert.Nil(t, ioutil.WriteFile(dfpath, []byte(fakeDfget), 0755))
+
+	getter := NewGetter(config.DFGetConfig{DFPath: dfpath, StreamBufferSize: 1024})
+	reader, err := getter.DownloadStreamContext(context.Background(), "http://a.b/c", nil, "name")
+	assert.Nil(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "content", string(content))
+	assert.Equal(t, float64(512), prom_testutil.ToFloat64(dfgetMetrics.streamBufferSpilled))
+	assert.Equal(t, float64(1), prom_testutil.ToFloat64(dfgetMetrics.invocations.WithLabelValues("0")))
+	assert.False(t, reader.(*streamReader).BackSource())
+
+	os.Setenv("EVENT", "backSource")
+	defer os.Unsetenv("EVENT")
+	reader, err = getter.DownloadStreamContext(context.Background(), "http://a.b/c", nil, "name")
+	assert.Nil(t, err)
+	_, err = ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.True(t, reader.(*streamReader).BackSource())
+
+	// the failure after writing part of the content is returned
+	os.Setenv("EXIT_CODE", "1")
+	defer os.Unsetenv("EXIT_CODE")
+	reader, err = getter.DownloadStreamContext(context.Background(), "http://a.b/c", nil, "name")
+	assert.Nil(t, err)
+	_, err = ioutil.ReadAll(reader)
+	assert.NotNil(t, err)
+	assert.Nil(t, reader.(*streamReader).Close())
+}
+
+func TestDownloadBackSourceContext(t *testing.T) {
+	dfgetMetrics = newMetrics(prometheus.NewRegistry())
+	workHome, err := ioutil.TempDir("", "dfdaemon-dfget-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(workHome)
+	dfpath := filepath.Join(workHome, "dfget")
+	assert.Nil(t, ioutil.WriteFile(dfpath, []byte(fakeDfget), 0755))
+
+	getter := NewGetter(config.DFGetConfig{DFPath: dfpath, DFRepo: workHome})
+	path, backSource, err := getter.DownloadBackSourceContext(context.Background(), "http://a.b/c", nil, "name")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(workHome, "name"), path)
+	assert.False(t, backSource)
+	assert.Equal(t, float64(512), prom_testutil.ToFloat64(dfgetMetrics.streamBufferSpilled))
+
+	os.Setenv("EVENT", "backSource")
+	defer os.Unsetenv("EVENT")
+	_, backSource, err = getter.DownloadBackSourceContext(context.Background(), "http://a.b/c", nil, "name")
+	assert.Nil(t, err)
+	assert.True(t, backSource)
+
+	os.Setenv("EXIT_CODE", "1")
+	defer os.Unsetenv("EXIT_CODE")
+	_, backSource, err = getter.DownloadBackSourceContext(context.Background(), "http://a.b/c", nil, "name")
+	assert.NotNil(t, err)
+	assert.False(t, backSource)
+}
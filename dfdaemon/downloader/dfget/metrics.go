@@ -0,0 +1,54 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfget
+
+import (
+	"github.com/dragonflyoss/Dragonfly/dfdaemon/constant"
+	"github.com/dragonflyoss/Dragonfly/pkg/metricsutils"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var dfgetMetrics = newMetrics(nil)
+
+// metrics defines some prometheus metrics for monitoring the dfget invocations of dfdaemon.
+type metrics struct {
+	duration    *prometheus.HistogramVec
+	invocations *prometheus.CounterVec
+
+	streamBufferPeak    *prometheus.HistogramVec
+	streamBufferSpilled *prometheus.CounterVec
+}
+
+func newMetrics(register prometheus.Registerer) *metrics {
+	return &metrics{
+		duration: metricsutils.NewHistogram(constant.Subsystem, "dfget_duration_seconds",
+			"Histogram of the duration of dfget invocations", []string{},
+			[]float64{.1, .2, .5, 1, 2, 5, 10, 30, 60, 120, 300, 600}, register,
+		),
+		invocations: metricsutils.NewCounter(constant.Subsystem, "dfget_invocations_total",
+			"Total number of dfget invocations by exit code", []string{"code"}, register,
+		),
+		streamBufferPeak: metricsutils.NewHistogram(constant.Subsystem, "dfget_stream_buffer_peak_bytes",
+			"Histogram of the max bytes of the out of order pieces held in memory by dfget in stream mode", []string{},
+			prometheus.ExponentialBuckets(1024*1024, 2, 12), register,
+		),
+		streamBufferSpilled: metricsutils.NewCounter(constant.Subsystem, "dfget_stream_buffer_spilled_bytes_total",
+			"Total bytes of the out of order pieces spilled to disk by dfget in stream mode", []string{}, register,
+		),
+	}
+}
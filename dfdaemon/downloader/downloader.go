@@ -34,6 +34,22 @@ type Stream interface {
 	DownloadStreamContext(ctx context.Context, url string, header map[string][]string, name string) (io.Reader, error)
 }
 
+// BackSourceInterface is implemented by the downloaders which tell whether
+// the file has been downloaded from the source instead of the P2P network.
+type BackSourceInterface interface {
+	Interface
+
+	// DownloadBackSourceContext is the same as DownloadContext, and it also
+	// returns whether dfget fell back to download the file from the source.
+	DownloadBackSourceContext(ctx context.Context, url string, header map[string][]string, name string) (path string, backSource bool, err error)
+}
+
+// BackSourceReporter is implemented by the readers returned by DownloadStreamContext
+// which tell whether dfget has fallen back to download the content from the source.
+type BackSourceReporter interface {
+	BackSource() bool
+}
+
 // Factory is a function that returns a new downloader.
 type Factory func() Interface
 type StreamFactory func() Stream
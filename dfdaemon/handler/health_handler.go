@@ -0,0 +1,147 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	dfgetConfig "github.com/dragonflyoss/Dragonfly/dfget/config"
+	"github.com/dragonflyoss/Dragonfly/pkg/fileutils"
+	"github.com/dragonflyoss/Dragonfly/pkg/httputils"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// minFreeSpace is the minimum free space of the local repo for dfdaemon to be healthy.
+	minFreeSpace = 100 * fileutils.MB
+	// pingTimeout is the timeout to ping the supernodes.
+	pingTimeout = time.Second
+)
+
+// HealthChecker checks whether dfdaemon is able to serve requests.
+type HealthChecker struct {
+	superNodes []string
+	localRepo  string
+}
+
+// NewHealthChecker returns a HealthChecker which checks the reachability of
+// the given supernodes and the free space of the local repo.
+func NewHealthChecker(superNodes []string, localRepo string) *HealthChecker {
+	return &HealthChecker{
+		superNodes: superNodes,
+		localRepo:  localRepo,
+	}
+}
+
+// checkLocalRepo checks whether the local repo has enough space for dfget to
+// store the downloaded files.
+func (hc *HealthChecker) checkLocalRepo() error {
+	if hc == nil || hc.localRepo == "" {
+		return nil
+	}
+	free, err := fileutils.GetFreeSpace(hc.localRepo)
+	if err != nil {
+		return errors.Wrapf(err, "get free space of local repo %s", hc.localRepo)
+	}
+	if free < minFreeSpace {
+		return errors.Errorf("free space %s of local repo %s is less than %s",
+			free, hc.localRepo, fileutils.Fsize(minFreeSpace))
+	}
+	return nil
+}
+
+// checkSuperNodes checks whether any of the supernodes is reachable. It passes
+// if no supernode is configured, since dfget will use its own configuration then.
+// The supernodes are pinged in parallel, and the check fails if none of them
+// responds within pingTimeout.
+func (hc *HealthChecker) checkSuperNodes() error {
+	if hc == nil || len(hc.superNodes) == 0 {
+		return nil
+	}
+	nodes, err := dfgetConfig.ParseNodesSlice(hc.superNodes)
+	if err != nil {
+		return errors.Wrap(err, "parse supernodes")
+	}
+
+	// the channel is buffered so that the pings never block after the check returns
+	results := make(chan error, len(nodes))
+	for _, node := range nodes {
+		go func(node string) {
+			code, _, err := httputils.Get(fmt.Sprintf("http://%s/_ping", node), pingTimeout)
+			if err == nil && code != http.StatusOK {
+				err = errors.Errorf("unexpected status code %d", code)
+			}
+			if err != nil {
+				err = fmt.Errorf("%s: %v", node, err)
+			}
+			results <- err
+		}(node.Node)
+	}
+
+	timer := time.NewTimer(pingTimeout)
+	defer timer.Stop()
+	var failures []string
+	for range nodes {
+		select {
+		case err := <-results:
+			if err == nil {
+				return nil
+			}
+			failures = append(failures, err.Error())
+		case <-timer.C:
+			failures = append(failures, fmt.Sprintf("timed out after %v", pingTimeout))
+			return errors.Errorf("no supernode is reachable: %s", strings.Join(failures, "; "))
+		}
+	}
+	return errors.Errorf("no supernode is reachable: %s", strings.Join(failures, "; "))
+}
+
+// healthz reports whether dfdaemon is alive, and it always passes as long as
+// dfdaemon responds. A full local repo only makes dfdaemon not ready, since
+// restarting it doesn't free the space.
+func (hc *HealthChecker) healthz(w http.ResponseWriter, r *http.Request) {
+	respondHealth(w)
+}
+
+// readyz reports whether dfdaemon is ready to proxy requests with dfget,
+// which requires both the local repo and a supernode available.
+func (hc *HealthChecker) readyz(w http.ResponseWriter, r *http.Request) {
+	respondHealth(w, hc.checkLocalRepo, hc.checkSuperNodes)
+}
+
+func respondHealth(w http.ResponseWriter, checks ...func() error) {
+	w.Header().Set("Content-Type", "text/plain;charset=utf-8")
+	for _, check := range checks {
+		if err := check(); err != nil {
+			logrus.Warnf("health check failed: %v", err)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			if _, err := w.Write([]byte(err.Error())); err != nil {
+				logrus.Errorf("failed to respond information: %v", err)
+			}
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte("ok")); err != nil {
+		logrus.Errorf("failed to respond information: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthz(t *testing.T) {
+	repo, err := ioutil.TempDir("", "dfdaemon-health")
+	assert.Nil(t, err)
+	defer os.RemoveAll(repo)
+
+	for _, tc := range []struct {
+		checker *HealthChecker
+		code    int
+	}{
+		{checker: nil, code: http.StatusOK},
+		{checker: NewHealthChecker(nil, repo), code: http.StatusOK},
+		// the local repo is checked only by readyz
+		{checker: NewHealthChecker(nil, filepath.Join(repo, "not-exist")), code: http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		tc.checker.healthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		assert.Equal(t, tc.code, w.Code)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	repo, err := ioutil.TempDir("", "dfdaemon-health")
+	assert.Nil(t, err)
+	defer os.RemoveAll(repo)
+	supernode := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_ping" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("OK"))
+	}))
+	defer supernode.Close()
+	up := strings.TrimPrefix(supernode.URL, "http://")
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	unreachable := strings.TrimPrefix(down.URL, "http://")
+
+	for _, tc := range []struct {
+		checker *HealthChecker
+		code    int
+	}{
+		{checker: NewHealthChecker(nil, repo), code: http.StatusOK},
+		{checker: NewHealthChecker([]string{up}, repo), code: http.StatusOK},
+		{checker: NewHealthChecker([]string{unreachable, up}, repo), code: http.StatusOK},
+		{checker: NewHealthChecker([]string{unreachable}, repo), code: http.StatusServiceUnavailable},
+		{checker: NewHealthChecker([]string{up}, filepath.Join(repo, "not-exist")), code: http.StatusServiceUnavailable},
+	} {
+		w := httptest.NewRecorder()
+		tc.checker.readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		assert.Equal(t, tc.code, w.Code, w.Body.String())
+	}
+}
+
+func TestReadyzPingInParallel(t *testing.T) {
+	repo, err := ioutil.TempDir("", "dfdaemon-health")
+	assert.Nil(t, err)
+	defer os.RemoveAll(repo)
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hung.Close()
+	defer close(release)
+	node := strings.TrimPrefix(hung.URL, "http://")
+
+	// the hung supernodes are pinged under one deadline instead of one by one
+	start := time.Now()
+	w := httptest.NewRecorder()
+	NewHealthChecker([]string{node, node, node}, repo).readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.True(t, time.Since(start) < 2*pingTimeout, time.Since(start).String())
+}
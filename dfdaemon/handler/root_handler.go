@@ -21,17 +21,27 @@ import (
 	// pprof will inject handlers for users to profile this program
 	_ "net/http/pprof"
 
+	"github.com/dragonflyoss/Dragonfly/dfdaemon/seed"
 	"github.com/dragonflyoss/Dragonfly/version"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-// New returns a new http mux for dfdaemon.
-func New() *http.ServeMux {
+// New returns a new http mux for dfdaemon. The health endpoints use the given
+// checker, and always pass if it's nil. The seed endpoints are registered only
+// if the seed manager is given.
+func New(checker *HealthChecker, seeds seed.Manager) *http.ServeMux {
 	s := http.DefaultServeMux
 	s.HandleFunc("/args", getArgs)
 	s.HandleFunc("/env", getEnv)
 	s.HandleFunc("/debug/version", version.Handler)
 	s.HandleFunc("/metrics", promhttp.Handler().ServeHTTP)
+	s.HandleFunc("/healthz", checker.healthz)
+	s.HandleFunc("/readyz", checker.readyz)
+	if seeds != nil {
+		sh := &seedHandler{manager: seeds}
+		s.HandleFunc(seedsPath, sh.handleSeeds)
+		s.HandleFunc(seedsPath+"/", sh.handleSeed)
+	}
 	return s
 }
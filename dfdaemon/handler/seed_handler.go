@@ -0,0 +1,266 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/dfdaemon/seed"
+	"github.com/dragonflyoss/Dragonfly/pkg/digest"
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/httputils"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	seedsPath = "/seeds"
+	// headTimeout is the timeout to get the length of a seed file from the source.
+	headTimeout = 30 * time.Second
+)
+
+// RegisterSeedRequest is the request to register a seed file.
+type RegisterSeedRequest struct {
+	// URL is the url of the seed file in the source.
+	URL string `json:"url"`
+	// Header is the headers to download the seed file from the source.
+	Header map[string][]string `json:"header,omitempty"`
+	// TaskID is the id of the task of the seed file in supernode.
+	TaskID string `json:"taskId,omitempty"`
+	// FullLength is the length of the seed file, it's got from
+	// the source if it's not set.
+	FullLength int64 `json:"fullLength,omitempty"`
+	// ExpireTime is how long the seed file is kept since the last access,
+	// 0 means it never expires.
+	ExpireTime string `json:"expireTime,omitempty"`
+	// PerDownloadSize is the size of the range of each request in prefetching.
+	PerDownloadSize int64 `json:"perDownloadSize,omitempty"`
+}
+
+// SeedInfo describes a seed file.
+type SeedInfo struct {
+	Key        string `json:"key"`
+	URL        string `json:"url"`
+	TaskID     string `json:"taskId,omitempty"`
+	FullLength int64  `json:"fullLength"`
+	Status     string `json:"status"`
+}
+
+// seedHandler serves the seed files of the seed manager. POST /seeds registers
+// a seed file and starts to prefetch it, and GET /seeds lists them. GET /seeds/{key}
+// downloads the seed file with the Range header supported, and DELETE /seeds/{key}
+// deletes it.
+type seedHandler struct {
+	manager seed.Manager
+}
+
+// seedKey returns the key of the seed file of the given url.
+func seedKey(url string) string {
+	return digest.Sha256(url)
+}
+
+func newSeedInfo(sd seed.Seed) *SeedInfo {
+	return &SeedInfo{
+		Key:        seedKey(sd.GetURL()),
+		URL:        sd.GetURL(),
+		TaskID:     sd.GetTaskID(),
+		FullLength: sd.GetFullSize(),
+		Status:     sd.GetStatus(),
+	}
+}
+
+func (sh *seedHandler) handleSeeds(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		sh.listSeeds(w, r)
+	case http.MethodPost:
+		sh.registerSeed(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (sh *seedHandler) handleSeed(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, seedsPath+"/")
+	if key == "" || strings.Contains(key, "/") {
+		respondSeedError(w, errors.Wrapf(errortypes.ErrDataNotFound, "seed %s", key))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		sh.downloadSeed(w, r, key)
+	case http.MethodDelete:
+		if err := sh.manager.UnRegister(key); err != nil {
+			respondSeedError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (sh *seedHandler) registerSeed(w http.ResponseWriter, r *http.Request) {
+	req := &RegisterSeedRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		respondSeedError(w, errors.Wrapf(errortypes.ErrInvalidValue, "decode request: %v", err))
+		return
+	}
+	if req.URL == "" {
+		respondSeedError(w, errors.Wrap(errortypes.ErrEmptyValue, "url"))
+		return
+	}
+	var expireTime time.Duration
+	if req.ExpireTime != "" {
+		var err error
+		if expireTime, err = time.ParseDuration(req.ExpireTime); err != nil {
+			respondSeedError(w, errors.Wrapf(errortypes.ErrInvalidValue, "expire time %s", req.ExpireTime))
+			return
+		}
+	}
+	if req.FullLength <= 0 {
+		length, err := getContentLength(req.URL, req.Header)
+		if err != nil {
+			respondSeedError(w, err)
+			return
+		}
+		req.FullLength = length
+	}
+
+	key := seedKey(req.URL)
+	sd, err := sh.manager.Register(key, seed.BaseInfo{
+		URL:           req.URL,
+		TaskID:        req.TaskID,
+		Header:        req.Header,
+		FullLength:    req.FullLength,
+		ExpireTimeDur: expireTime,
+	})
+	if err != nil {
+		respondSeedError(w, err)
+		return
+	}
+	if _, err := sh.manager.Prefetch(key, req.PerDownloadSize); err != nil {
+		respondSeedError(w, err)
+		return
+	}
+	respondSeedJSON(w, http.StatusOK, newSeedInfo(sd))
+}
+
+func (sh *seedHandler) listSeeds(w http.ResponseWriter, r *http.Request) {
+	seeds, err := sh.manager.List()
+	if err != nil {
+		respondSeedError(w, err)
+		return
+	}
+	infos := make([]*SeedInfo, 0, len(seeds))
+	for _, sd := range seeds {
+		infos = append(infos, newSeedInfo(sd))
+	}
+	respondSeedJSON(w, http.StatusOK, infos)
+}
+
+func (sh *seedHandler) downloadSeed(w http.ResponseWriter, r *http.Request, key string) {
+	sd, err := sh.manager.Get(key)
+	if err != nil {
+		respondSeedError(w, err)
+		return
+	}
+
+	fullSize := sd.GetFullSize()
+	off, size, code := int64(0), fullSize, http.StatusOK
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+		ranges, err := httputils.GetRangeSE(rangeHeader, fullSize)
+		if err != nil || len(ranges) != 1 {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", fullSize))
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		off, size, code = ranges[0].StartIndex, ranges[0].EndIndex-ranges[0].StartIndex+1, http.StatusPartialContent
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", off, off+size-1, fullSize))
+	}
+
+	rc, err := sd.Download(off, size)
+	if err != nil {
+		respondSeedError(w, err)
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(code)
+	if _, err := io.Copy(w, rc); err != nil {
+		logrus.Errorf("failed to respond seed %s: %v", key, err)
+	}
+}
+
+// getContentLength gets the length of the file from the source.
+func getContentLength(url string, header map[string][]string) (int64, error) {
+	headers := make(map[string]string, len(header))
+	for k, v := range header {
+		if len(v) > 0 {
+			headers[k] = v[0]
+		}
+	}
+	resp, err := httputils.HTTPWithHeaders(http.MethodHead, url, headers, headTimeout, nil)
+	if err != nil {
+		return 0, errors.Wrapf(err, "get length of %s", url)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, errortypes.NewHTTPError(http.StatusBadGateway,
+			fmt.Sprintf("get length of %s: unexpected status code %d", url, resp.StatusCode))
+	}
+	if resp.ContentLength <= 0 {
+		return 0, errors.Wrapf(errortypes.ErrInvalidValue, "unknown length of %s", url)
+	}
+	return resp.ContentLength, nil
+}
+
+func respondSeedJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Errorf("failed to respond information: %v", err)
+	}
+}
+
+func respondSeedError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch e := errors.Cause(err).(type) {
+	case *errortypes.HTTPError:
+		code = e.HTTPCode()
+	default:
+		if errortypes.IsDataNotFound(err) {
+			code = http.StatusNotFound
+		} else if errortypes.IsEmptyValue(err) || errortypes.IsInvalidValue(err) {
+			code = http.StatusBadRequest
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain;charset=utf-8")
+	w.WriteHeader(code)
+	if _, err := w.Write([]byte(err.Error())); err != nil {
+		logrus.Errorf("failed to respond information: %v", err)
+	}
+}
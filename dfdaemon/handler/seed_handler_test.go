@@ -0,0 +1,96 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/dfdaemon/seed"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeedHandler(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 8*1024)
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "seed", time.Time{}, bytes.NewReader(content))
+	}))
+	defer source.Close()
+
+	dir, err := ioutil.TempDir("", "dfdaemon-seed")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	m, err := seed.NewSeedManager(seed.NewSeedManagerOpt{StoreDir: dir, DownloadBlockOrder: 12})
+	assert.Nil(t, err)
+	defer m.Stop()
+	sh := &seedHandler{manager: m}
+
+	serve := func(method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		if path == seedsPath {
+			sh.handleSeeds(w, r)
+		} else {
+			sh.handleSeed(w, r)
+		}
+		return w
+	}
+
+	w := serve(http.MethodPost, seedsPath, `{"url":"`+source.URL+`/file"}`, nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	info := &SeedInfo{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), info))
+	assert.Equal(t, seedKey(source.URL+"/file"), info.Key)
+	assert.Equal(t, int64(len(content)), info.FullLength)
+
+	w = serve(http.MethodGet, seedsPath, "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var infos []*SeedInfo
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &infos))
+	assert.Equal(t, 1, len(infos))
+
+	w = serve(http.MethodGet, seedsPath+"/"+info.Key, "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, content, w.Body.Bytes())
+
+	w = serve(http.MethodGet, seedsPath+"/"+info.Key, "", map[string]string{"Range": "bytes=5000-9999"})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "bytes 5000-9999/131072", w.Header().Get("Content-Range"))
+	assert.Equal(t, content[5000:10000], w.Body.Bytes())
+
+	w = serve(http.MethodGet, seedsPath+"/"+info.Key, "", map[string]string{"Range": "bytes=200000-"})
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+
+	w = serve(http.MethodPost, seedsPath, `{"url":""}`, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(http.MethodDelete, seedsPath+"/"+info.Key, "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serve(http.MethodGet, seedsPath+"/"+info.Key, "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
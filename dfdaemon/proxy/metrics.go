@@ -0,0 +1,50 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"github.com/dragonflyoss/Dragonfly/dfdaemon/constant"
+	"github.com/dragonflyoss/Dragonfly/pkg/metricsutils"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// ruleRegistryMirror is the rule label of the requests to the registry mirror.
+	ruleRegistryMirror = "registry_mirror"
+	// ruleNone is the rule label of the requests matching no proxy rule.
+	ruleNone = "none"
+)
+
+var proxyMetrics = newMetrics(nil)
+
+// metrics defines some prometheus metrics for monitoring the proxy of dfdaemon.
+type metrics struct {
+	hijackHandshakes      *prometheus.CounterVec
+	registryMirrorRequest *prometheus.CounterVec
+}
+
+func newMetrics(register prometheus.Registerer) *metrics {
+	return &metrics{
+		hijackHandshakes: metricsutils.NewCounter(constant.Subsystem, "https_hijack_handshakes_total",
+			"Total number of TLS handshakes of the hijacked https requests by result", []string{"result"}, register,
+		),
+		registryMirrorRequest: metricsutils.NewCounter(constant.Subsystem, "registry_mirror_requests_total",
+			"Total number of requests to the registry mirror", []string{}, register,
+		),
+	}
+}
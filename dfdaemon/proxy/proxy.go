@@ -31,7 +31,6 @@ import (
 	"github.com/dragonflyoss/Dragonfly/dfdaemon/config"
 	"github.com/dragonflyoss/Dragonfly/dfdaemon/downloader"
 	"github.com/dragonflyoss/Dragonfly/dfdaemon/downloader/dfget"
-	"github.com/dragonflyoss/Dragonfly/dfdaemon/downloader/p2p"
 	"github.com/dragonflyoss/Dragonfly/dfdaemon/transport"
 	"github.com/golang/groupcache/lru"
 	"github.com/pkg/errors"
@@ -146,7 +145,7 @@ func NewFromConfig(c config.Properties) (*Proxy, error) {
 			return dfget.NewGetter(c.DFGetConfig())
 		}),
 		WithStreamDownloaderFactory(func() downloader.Stream {
-			return p2p.NewClient(c.DFGetConfig())
+			return dfget.NewGetter(c.DFGetConfig())
 		}),
 		WithStreamMode(c.StreamMode),
 	}
@@ -204,12 +203,14 @@ type Proxy struct {
 }
 
 func (proxy *Proxy) mirrorRegistry(w http.ResponseWriter, r *http.Request) {
+	proxyMetrics.registryMirrorRequest.WithLabelValues().Inc()
 	reverseProxy := httputil.NewSingleHostReverseProxy(proxy.registry.Remote.URL)
 	t, err := transport.New(
 		transport.WithDownloader(proxy.downloadFactory()),
 		transport.WithStreamDownloader(proxy.streamDownloadFactory()),
 		transport.WithTLS(proxy.registry.TLSConfig()),
 		transport.WithCondition(proxy.shouldUseDfgetForMirror),
+		transport.WithRuleLabel(func(*http.Request) string { return ruleRegistryMirror }),
 	)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("failed to get transport: %v", err), http.StatusInternalServerError)
@@ -273,6 +274,7 @@ func (proxy *Proxy) roundTripper(tlsConfig *tls.Config) http.RoundTripper {
 		transport.WithStreamDownloader(proxy.streamDownloadFactory()),
 		transport.WithTLS(tlsConfig),
 		transport.WithCondition(proxy.shouldUseDfget),
+		transport.WithRuleLabel(proxy.ruleLabel),
 	)
 	return rt
 }
@@ -300,6 +302,17 @@ func (proxy *Proxy) shouldUseDfget(req *http.Request) bool {
 	return false
 }
 
+// ruleLabel returns the regex of the first proxy rule matching the request,
+// which is used to label the metrics of the request.
+func (proxy *Proxy) ruleLabel(req *http.Request) string {
+	for _, rule := range proxy.rules {
+		if rule.Match(req.URL.String()) {
+			return rule.Regx.String()
+		}
+	}
+	return ruleNone
+}
+
 // shouldUseDfgetForMirror returns whether we should use dfget to proxy a request
 // when we use registry mirror.
 func (proxy *Proxy) shouldUseDfgetForMirror(req *http.Request) bool {
@@ -380,9 +393,11 @@ func (proxy *Proxy) handleHTTPS(w http.ResponseWriter, r *http.Request) {
 
 	sConn, err := handshake(w, sConfig)
 	if err != nil {
+		proxyMetrics.hijackHandshakes.WithLabelValues("failed").Inc()
 		logrus.Errorf("handshake failed for %s: %v", r.Host, err)
 		return
 	}
+	proxyMetrics.hijackHandshakes.WithLabelValues("success").Inc()
 	defer sConn.Close()
 
 	cConn, err := tls.Dial("tcp", r.Host, cConfig)
@@ -16,7 +16,30 @@
 
 package seed
 
-import "time"
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/digest"
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/ratelimiter"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultDownloadConcurrency = 4
+	maxDownloadConcurrency     = 16
+
+	defaultTotalLimit = 50
+	defaultGCInterval = 2 * time.Minute
+)
 
 // Manager is an interface which manages the seeds.
 type Manager interface {
@@ -51,3 +74,446 @@ type Manager interface {
 	// Stop stops the SeedManager.
 	Stop()
 }
+
+// NewSeedManagerOpt is the option of creating the seed manager.
+type NewSeedManagerOpt struct {
+	// StoreDir is the directory to store the seeds.
+	StoreDir string
+	// ConcurrentLimit is the concurrency of prefetching seeds.
+	ConcurrentLimit int
+	// TotalLimit is the max number of seeds, the least recently used seed
+	// which isn't fetching is evicted when the limit is reached.
+	TotalLimit int
+	// DownloadBlockOrder is the default block order of the seeds.
+	DownloadBlockOrder uint32
+	// OpenMemoryCache caches the downloading block in memory before writing to local fs.
+	OpenMemoryCache bool
+	// DownloadRate is the rate limit of prefetching in bytes per second, 0 means no limit.
+	DownloadRate int64
+	// GCInterval is the interval of deleting the expired seeds.
+	GCInterval time.Duration
+}
+
+// seedWrapper wraps the seed with the channels notified by the manager.
+type seedWrapper struct {
+	sd *seed
+	// expireCh is closed when the seed is expired or unregistered.
+	expireCh chan struct{}
+	// prefetchCh is closed when the queued prefetch finishes.
+	prefetchCh chan struct{}
+}
+
+type seedManager struct {
+	sync.Mutex
+	// cond is signaled when a prefetch slot is released or the limit is changed.
+	cond *sync.Cond
+
+	storeDir        string
+	totalLimit      int
+	blockOrder      uint32
+	openMemoryCache bool
+	rate            *ratelimiter.RateLimiter
+
+	seeds map[string]*seedWrapper
+
+	concurrentLimit int
+	running         int
+
+	gcInterval time.Duration
+	stopCh     chan struct{}
+	stopOnce   sync.Once
+	wg         sync.WaitGroup
+}
+
+// NewSeedManager creates the seed manager and restores the seeds in opt.StoreDir.
+func NewSeedManager(opt NewSeedManagerOpt) (Manager, error) {
+	if opt.StoreDir == "" {
+		return nil, errors.Wrap(errortypes.ErrEmptyValue, "store dir")
+	}
+	if err := os.MkdirAll(opt.StoreDir, 0755); err != nil {
+		return nil, err
+	}
+
+	if opt.TotalLimit <= 0 {
+		opt.TotalLimit = defaultTotalLimit
+	}
+	if opt.DownloadBlockOrder == 0 {
+		opt.DownloadBlockOrder = defaultBlockOrder
+	}
+	if !isValidBlockOrder(opt.DownloadBlockOrder) {
+		return nil, errors.Wrapf(errortypes.ErrInvalidValue, "block order %d", opt.DownloadBlockOrder)
+	}
+	if opt.GCInterval <= 0 {
+		opt.GCInterval = defaultGCInterval
+	}
+
+	// the rate limiter doesn't limit the rate if DownloadRate is 0.
+	rate := ratelimiter.NewRateLimiter(opt.DownloadRate, 2)
+
+	sm := &seedManager{
+		storeDir:        opt.StoreDir,
+		totalLimit:      opt.TotalLimit,
+		blockOrder:      opt.DownloadBlockOrder,
+		openMemoryCache: opt.OpenMemoryCache,
+		rate:            rate,
+		seeds:           make(map[string]*seedWrapper),
+		gcInterval:      opt.GCInterval,
+		stopCh:          make(chan struct{}),
+	}
+	sm.cond = sync.NewCond(&sm.Mutex)
+	sm.SetConcurrentLimit(opt.ConcurrentLimit)
+
+	if err := sm.restore(); err != nil {
+		return nil, err
+	}
+
+	sm.wg.Add(1)
+	go sm.gcLoop()
+	return sm, nil
+}
+
+func (sm *seedManager) Register(key string, info BaseInfo) (Seed, error) {
+	if key == "" {
+		return nil, errors.Wrap(errortypes.ErrEmptyValue, "key")
+	}
+	if info.URL == "" {
+		return nil, errors.Wrap(errortypes.ErrEmptyValue, "url")
+	}
+	if info.FullLength <= 0 {
+		return nil, errors.Wrapf(errortypes.ErrInvalidValue, "full length %d", info.FullLength)
+	}
+	if info.BlockOrder == 0 {
+		info.BlockOrder = sm.blockOrder
+	}
+	if !isValidBlockOrder(info.BlockOrder) {
+		return nil, errors.Wrapf(errortypes.ErrInvalidValue, "block order %d", info.BlockOrder)
+	}
+
+	// the evicted seed is deleted after releasing the lock,
+	// as deleting waits for its files to be removed.
+	var evicted *seed
+	defer func() {
+		if evicted == nil {
+			return
+		}
+		if err := evicted.Delete(); err != nil {
+			logrus.Errorf("failed to delete evicted seed %s: %v", evicted.key, err)
+		}
+	}()
+
+	sm.Lock()
+	defer sm.Unlock()
+
+	if sw, ok := sm.seeds[key]; ok {
+		if sw.sd.GetURL() != info.URL {
+			return nil, errors.Wrapf(errortypes.ErrInvalidValue, "seed %s has been registered with url %s", key, sw.sd.GetURL())
+		}
+		return sw.sd, nil
+	}
+
+	if len(sm.seeds) >= sm.totalLimit {
+		var err error
+		if evicted, err = sm.evict(); err != nil {
+			return nil, err
+		}
+	}
+
+	sd, err := newSeed(seedOpt{
+		key:             key,
+		dir:             sm.seedDir(key),
+		info:            info,
+		rate:            sm.rate,
+		openMemoryCache: sm.openMemoryCache,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sm.seeds[key] = &seedWrapper{sd: sd, expireCh: make(chan struct{})}
+	return sd, nil
+}
+
+func (sm *seedManager) UnRegister(key string) error {
+	sm.Lock()
+	sw, ok := sm.seeds[key]
+	if !ok {
+		sm.Unlock()
+		return errors.Wrapf(errortypes.ErrDataNotFound, "seed %s", key)
+	}
+	sm.remove(key, sw)
+	sm.Unlock()
+
+	return sw.sd.Delete()
+}
+
+func (sm *seedManager) RefreshExpireTime(key string, expireTimeDur time.Duration) error {
+	sw, err := sm.getWrapper(key)
+	if err != nil {
+		return err
+	}
+	return sw.sd.refreshExpireTime(expireTimeDur)
+}
+
+func (sm *seedManager) NotifyExpired(key string) (<-chan struct{}, error) {
+	sw, err := sm.getWrapper(key)
+	if err != nil {
+		return nil, err
+	}
+	return sw.expireCh, nil
+}
+
+func (sm *seedManager) Prefetch(key string, perDownloadSize int64) (<-chan struct{}, error) {
+	sm.Lock()
+	defer sm.Unlock()
+
+	sw, ok := sm.seeds[key]
+	if !ok {
+		return nil, errors.Wrapf(errortypes.ErrDataNotFound, "seed %s", key)
+	}
+	if sw.prefetchCh != nil {
+		select {
+		case <-sw.prefetchCh:
+			// prefetch again if the last one fails.
+			if sw.sd.GetStatus() == FinishedStatus {
+				return sw.prefetchCh, nil
+			}
+		default:
+			return sw.prefetchCh, nil
+		}
+	}
+
+	ch := make(chan struct{})
+	sw.prefetchCh = ch
+	sm.wg.Add(1)
+	go sm.prefetch(sw.sd, perDownloadSize, ch)
+	return ch, nil
+}
+
+// prefetch waits for a prefetch slot and prefetches the seed.
+func (sm *seedManager) prefetch(sd *seed, perDownloadSize int64, ch chan struct{}) {
+	defer sm.wg.Done()
+	defer close(ch)
+
+	if !sm.acquire() {
+		return
+	}
+	defer sm.release()
+
+	done, err := sd.Prefetch(perDownloadSize)
+	if err != nil {
+		logrus.Errorf("failed to prefetch seed %s: %v", sd.key, err)
+		return
+	}
+	<-done
+}
+
+func (sm *seedManager) acquire() bool {
+	sm.Lock()
+	defer sm.Unlock()
+	for sm.running >= sm.concurrentLimit {
+		select {
+		case <-sm.stopCh:
+			return false
+		default:
+		}
+		sm.cond.Wait()
+	}
+	select {
+	case <-sm.stopCh:
+		return false
+	default:
+	}
+	sm.running++
+	return true
+}
+
+func (sm *seedManager) release() {
+	sm.Lock()
+	defer sm.Unlock()
+	sm.running--
+	sm.cond.Signal()
+}
+
+func (sm *seedManager) GetPrefetchResult(key string) (PreFetchResult, error) {
+	sw, err := sm.getWrapper(key)
+	if err != nil {
+		return PreFetchResult{}, err
+	}
+	return sw.sd.GetPrefetchResult()
+}
+
+func (sm *seedManager) SetConcurrentLimit(limit int) (validLimit int) {
+	if limit <= 0 {
+		limit = defaultDownloadConcurrency
+	}
+	if limit > maxDownloadConcurrency {
+		limit = maxDownloadConcurrency
+	}
+
+	sm.Lock()
+	defer sm.Unlock()
+	sm.concurrentLimit = limit
+	sm.cond.Broadcast()
+	return limit
+}
+
+func (sm *seedManager) Get(key string) (Seed, error) {
+	sw, err := sm.getWrapper(key)
+	if err != nil {
+		return nil, err
+	}
+	return sw.sd, nil
+}
+
+func (sm *seedManager) List() ([]Seed, error) {
+	sm.Lock()
+	defer sm.Unlock()
+
+	keys := make([]string, 0, len(sm.seeds))
+	for key := range sm.seeds {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]Seed, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, sm.seeds[key].sd)
+	}
+	return result, nil
+}
+
+func (sm *seedManager) Stop() {
+	sm.stopOnce.Do(func() {
+		sm.Lock()
+		close(sm.stopCh)
+		sm.cond.Broadcast()
+		seeds := make([]*seed, 0, len(sm.seeds))
+		for _, sw := range sm.seeds {
+			seeds = append(seeds, sw.sd)
+		}
+		sm.Unlock()
+
+		for _, sd := range seeds {
+			sd.Stop()
+		}
+		sm.wg.Wait()
+	})
+}
+
+func (sm *seedManager) getWrapper(key string) (*seedWrapper, error) {
+	sm.Lock()
+	defer sm.Unlock()
+	sw, ok := sm.seeds[key]
+	if !ok {
+		return nil, errors.Wrapf(errortypes.ErrDataNotFound, "seed %s", key)
+	}
+	return sw, nil
+}
+
+// remove removes the seed from the manager and notifies the expiry,
+// it should be called with the lock held.
+func (sm *seedManager) remove(key string, sw *seedWrapper) {
+	delete(sm.seeds, key)
+	close(sw.expireCh)
+}
+
+// evict removes the least recently used seed which isn't fetching from the
+// manager and returns it to be deleted by the caller after releasing the lock,
+// it should be called with the lock held.
+func (sm *seedManager) evict() (*seed, error) {
+	var (
+		victimKey string
+		victim    *seedWrapper
+	)
+	for key, sw := range sm.seeds {
+		if sw.sd.GetStatus() == FetchingStatus {
+			continue
+		}
+		if victim == nil || sw.sd.getAccessTime().Before(victim.sd.getAccessTime()) {
+			victimKey, victim = key, sw
+		}
+	}
+	if victim == nil {
+		return nil, fmt.Errorf("the number of seeds reaches the limit %d and all of them are fetching", sm.totalLimit)
+	}
+
+	logrus.Infof("evict seed %s to register the new seed", victimKey)
+	sm.remove(victimKey, victim)
+	return victim.sd, nil
+}
+
+func (sm *seedManager) gcLoop() {
+	defer sm.wg.Done()
+	ticker := time.NewTicker(sm.gcInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sm.stopCh:
+			return
+		case <-ticker.C:
+			sm.gcExpiredSeeds()
+		}
+	}
+}
+
+// gcExpiredSeeds deletes the expired seeds.
+func (sm *seedManager) gcExpiredSeeds() {
+	now := time.Now()
+	var expired []*seed
+
+	sm.Lock()
+	for key, sw := range sm.seeds {
+		if sw.sd.isExpired(now) {
+			sm.remove(key, sw)
+			expired = append(expired, sw.sd)
+		}
+	}
+	sm.Unlock()
+
+	for _, sd := range expired {
+		logrus.Infof("seed %s is expired", sd.key)
+		if err := sd.Delete(); err != nil {
+			logrus.Errorf("failed to delete expired seed %s: %v", sd.key, err)
+		}
+	}
+}
+
+// restore restores the seeds stored in the store dir, the expired
+// and broken seeds are deleted.
+func (sm *seedManager) restore() error {
+	fis, err := ioutil.ReadDir(sm.storeDir)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+		dir := filepath.Join(sm.storeDir, fi.Name())
+		sd, err := restoreSeed(dir, sm.rate, sm.openMemoryCache)
+		if err != nil {
+			logrus.Warnf("failed to restore seed from %s, remove it: %v", dir, err)
+			os.RemoveAll(dir)
+			continue
+		}
+		if sd.isExpired(now) {
+			logrus.Infof("restored seed %s is expired, remove it", sd.key)
+			if err := sd.Delete(); err != nil {
+				logrus.Errorf("failed to delete expired seed %s: %v", sd.key, err)
+			}
+			continue
+		}
+		sm.seeds[sd.key] = &seedWrapper{sd: sd, expireCh: make(chan struct{})}
+	}
+	return nil
+}
+
+func (sm *seedManager) seedDir(key string) string {
+	return filepath.Join(sm.storeDir, digest.Sha256(key))
+}
+
+func isValidBlockOrder(order uint32) bool {
+	return order >= minBlockOrder && order <= maxBlockOrder
+}
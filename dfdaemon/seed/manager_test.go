@@ -0,0 +1,135 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package seed
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/go-check/check"
+)
+
+func (suite *SeedTestSuite) newTestManager(c *check.C, dir string, totalLimit int) Manager {
+	sm, err := NewSeedManager(NewSeedManagerOpt{
+		StoreDir:           filepath.Join(suite.cacheDir, dir),
+		ConcurrentLimit:    2,
+		TotalLimit:         totalLimit,
+		DownloadBlockOrder: 15,
+		GCInterval:         10 * time.Millisecond,
+	})
+	c.Assert(err, check.IsNil)
+	return sm
+}
+
+func (suite *SeedTestSuite) testBaseInfo(fileName string, fileLength int64) BaseInfo {
+	return BaseInfo{
+		URL:        fmt.Sprintf("http://%s/%s", suite.host, fileName),
+		TaskID:     fileName,
+		FullLength: fileLength,
+	}
+}
+
+func (suite *SeedTestSuite) TestManagerPrefetchAndRestore(c *check.C) {
+	sm := suite.newTestManager(c, "manager-restore", 0)
+
+	files := map[string]int64{"fileA": 500 * 1024, "fileB": 1024 * 1024, "fileD": 2048 * 1024}
+	chs := make(map[string]<-chan struct{})
+	for name, length := range files {
+		_, err := sm.Register(name, suite.testBaseInfo(name, length))
+		c.Assert(err, check.IsNil)
+		chs[name], err = sm.Prefetch(name, 128*1024)
+		c.Assert(err, check.IsNil)
+	}
+	for name, ch := range chs {
+		<-ch
+		result, err := sm.GetPrefetchResult(name)
+		c.Assert(err, check.IsNil)
+		c.Assert(result.Success, check.Equals, true)
+	}
+
+	// registering again returns the same seed, but a different url is invalid.
+	sd, err := sm.Register("fileA", suite.testBaseInfo("fileA", 500*1024))
+	c.Assert(err, check.IsNil)
+	c.Assert(sd.GetStatus(), check.Equals, FinishedStatus)
+	_, err = sm.Register("fileA", suite.testBaseInfo("fileB", 1024*1024))
+	c.Assert(err, check.NotNil)
+	sm.Stop()
+
+	sm = suite.newTestManager(c, "manager-restore", 0)
+	defer sm.Stop()
+	seeds, err := sm.List()
+	c.Assert(err, check.IsNil)
+	c.Assert(len(seeds), check.Equals, len(files))
+	for name, length := range files {
+		sd, err := sm.Get(name)
+		c.Assert(err, check.IsNil)
+		c.Assert(sd.GetStatus(), check.Equals, FinishedStatus)
+		suite.checkFileWithSeed(c, name, length, sd)
+	}
+}
+
+func (suite *SeedTestSuite) TestManagerExpire(c *check.C) {
+	sm := suite.newTestManager(c, "manager-expire", 0)
+	defer sm.Stop()
+
+	_, err := sm.Register("fileA", suite.testBaseInfo("fileA", 500*1024))
+	c.Assert(err, check.IsNil)
+	expireCh, err := sm.NotifyExpired("fileA")
+	c.Assert(err, check.IsNil)
+	c.Assert(sm.RefreshExpireTime("fileA", 50*time.Millisecond), check.IsNil)
+
+	select {
+	case <-expireCh:
+	case <-time.After(5 * time.Second):
+		c.Fatal("seed should be expired")
+	}
+	_, err = sm.Get("fileA")
+	c.Assert(err, check.NotNil)
+
+	_, err = sm.Register("fileB", suite.testBaseInfo("fileB", 1024*1024))
+	c.Assert(err, check.IsNil)
+	expireCh, err = sm.NotifyExpired("fileB")
+	c.Assert(err, check.IsNil)
+	c.Assert(sm.UnRegister("fileB"), check.IsNil)
+	<-expireCh
+	c.Assert(sm.UnRegister("fileB"), check.NotNil)
+}
+
+func (suite *SeedTestSuite) TestManagerEvict(c *check.C) {
+	sm := suite.newTestManager(c, "manager-evict", 2)
+	defer sm.Stop()
+
+	for _, name := range []string{"fileA", "fileB", "fileC"} {
+		_, err := sm.Register(name, suite.testBaseInfo(name, 500*1024))
+		c.Assert(err, check.IsNil)
+		time.Sleep(time.Millisecond)
+	}
+	seeds, err := sm.List()
+	c.Assert(err, check.IsNil)
+	c.Assert(len(seeds), check.Equals, 2)
+	_, err = sm.Get("fileA")
+	c.Assert(err, check.NotNil)
+}
+
+func (suite *SeedTestSuite) TestManagerSetConcurrentLimit(c *check.C) {
+	sm := suite.newTestManager(c, "manager-limit", 0)
+	defer sm.Stop()
+
+	c.Assert(sm.SetConcurrentLimit(0), check.Equals, defaultDownloadConcurrency)
+	c.Assert(sm.SetConcurrentLimit(100), check.Equals, maxDownloadConcurrency)
+	c.Assert(sm.SetConcurrentLimit(3), check.Equals, 3)
+}
@@ -16,7 +16,26 @@
 
 package seed
 
-import "io"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/bitmap"
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/httputils"
+	"github.com/dragonflyoss/Dragonfly/pkg/ratelimiter"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
 
 // Seed describes the seed file which represents the resource file defined by taskUrl.
 type Seed interface {
@@ -51,3 +70,438 @@ type Seed interface {
 	// GetHeaders gets the taskID of seed file.
 	GetTaskID() string
 }
+
+const (
+	// InitialStatus means the seed has been registered but not fully cached.
+	InitialStatus = "initial"
+	// FetchingStatus means the seed is prefetching the file to local cache.
+	FetchingStatus = "fetching"
+	// FinishedStatus means the seed file has been fully cached.
+	FinishedStatus = "finished"
+	// DeadStatus means the seed has been deleted.
+	DeadStatus = "dead"
+)
+
+const (
+	defaultBlockOrder = 22
+	minBlockOrder     = 10
+	maxBlockOrder     = 31
+
+	defaultDownloadTimeout = 5 * time.Minute
+
+	metaFileName    = "meta.json"
+	contentFileName = "content"
+)
+
+// seedMeta is the persistent state of seed which is stored in the meta file.
+type seedMeta struct {
+	Key        string    `json:"key"`
+	BaseInfo   BaseInfo  `json:"baseInfo"`
+	Status     string    `json:"status"`
+	Blocks     []byte    `json:"blocks"`
+	AccessTime time.Time `json:"accessTime"`
+}
+
+// seed is the implementation of Seed which caches the seed file in the local fs.
+// The file is divided into blocks, and the cached blocks are recorded in a bitmap
+// which is persisted with the metadata, so that the seed can be restored after restarting.
+type seed struct {
+	// the lock protects the fields of the seed.
+	sync.RWMutex
+
+	key      string
+	baseInfo BaseInfo
+	status   string
+	dir      string
+
+	blockSize int64
+	blockNum  uint32
+	blockMeta *bitmap.BitMap
+
+	openMemoryCache bool
+	cache           cacheBuffer
+	// down downloads the blocks, only the prefetching is rate limited.
+	down downloader
+
+	accessTime time.Time
+
+	prefetchCh     chan struct{}
+	prefetchResult *PreFetchResult
+	cancel         context.CancelFunc
+	wg             sync.WaitGroup
+}
+
+// seedOpt is the option of creating a seed.
+type seedOpt struct {
+	key             string
+	dir             string
+	info            BaseInfo
+	rate            *ratelimiter.RateLimiter
+	openMemoryCache bool
+}
+
+// newSeed creates a seed whose cache is stored in opt.dir.
+func newSeed(opt seedOpt) (*seed, error) {
+	if err := os.MkdirAll(opt.dir, 0755); err != nil {
+		return nil, err
+	}
+
+	sd := initSeed(opt, InitialStatus)
+	blockMeta, err := bitmap.NewBitMapWithNumBits(sd.blockNum, false)
+	if err != nil {
+		return nil, err
+	}
+	sd.blockMeta = blockMeta
+	sd.accessTime = time.Now()
+
+	if sd.cache, err = sd.openCache(true); err != nil {
+		return nil, err
+	}
+	if err := sd.saveMeta(); err != nil {
+		sd.cache.Close()
+		return nil, err
+	}
+	return sd, nil
+}
+
+// restoreSeed restores the seed from the meta file in dir.
+func restoreSeed(dir string, rate *ratelimiter.RateLimiter, openMemoryCache bool) (*seed, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, metaFileName))
+	if err != nil {
+		return nil, err
+	}
+	meta := &seedMeta{}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, err
+	}
+
+	opt := seedOpt{
+		key:             meta.Key,
+		dir:             dir,
+		info:            meta.BaseInfo,
+		rate:            rate,
+		openMemoryCache: openMemoryCache,
+	}
+	status := meta.Status
+	if status != FinishedStatus {
+		// the prefetching is interrupted, and it will be resumed by the next prefetch.
+		status = InitialStatus
+	}
+	sd := initSeed(opt, status)
+	if sd.blockMeta, err = bitmap.RestoreBitMap(meta.Blocks); err != nil {
+		return nil, err
+	}
+	sd.accessTime = meta.AccessTime
+	if status == FinishedStatus {
+		sd.prefetchCh = make(chan struct{})
+		sd.prefetchResult = &PreFetchResult{Success: true}
+		close(sd.prefetchCh)
+	}
+
+	if sd.cache, err = sd.openCache(false); err != nil {
+		return nil, err
+	}
+	return sd, nil
+}
+
+func initSeed(opt seedOpt, status string) *seed {
+	if opt.rate == nil {
+		opt.rate = ratelimiter.NewRateLimiter(0, 2)
+	}
+	blockSize := int64(1) << opt.info.BlockOrder
+	blockNum := opt.info.FullLength / blockSize
+	if opt.info.FullLength%blockSize > 0 {
+		blockNum++
+	}
+
+	return &seed{
+		key:             opt.key,
+		baseInfo:        opt.info,
+		status:          status,
+		dir:             opt.dir,
+		blockSize:       blockSize,
+		blockNum:        uint32(blockNum),
+		openMemoryCache: opt.openMemoryCache,
+		down:            newLocalDownloader(opt.info.URL, opt.info.Header, opt.rate, opt.openMemoryCache),
+	}
+}
+
+func (sd *seed) openCache(trunc bool) (cacheBuffer, error) {
+	return newFileCacheBuffer(filepath.Join(sd.dir, contentFileName), sd.baseInfo.FullLength,
+		trunc, sd.openMemoryCache, sd.baseInfo.BlockOrder)
+}
+
+func (sd *seed) Prefetch(perDownloadSize int64) (<-chan struct{}, error) {
+	sd.Lock()
+	defer sd.Unlock()
+
+	switch sd.status {
+	case DeadStatus:
+		return nil, errors.Wrapf(errortypes.ErrInvalidValue, "seed %s is dead", sd.key)
+	case FetchingStatus, FinishedStatus:
+		return sd.prefetchCh, nil
+	}
+
+	// download the whole blocks every time, and only one block
+	// can be written to cache at a time in memory cache mode.
+	blocks := (perDownloadSize + sd.blockSize - 1) / sd.blockSize
+	if blocks <= 0 || sd.openMemoryCache {
+		blocks = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sd.cancel = cancel
+	sd.status = FetchingStatus
+	sd.prefetchCh = make(chan struct{})
+	sd.prefetchResult = nil
+	sd.wg.Add(1)
+	go sd.prefetch(ctx, uint32(blocks))
+	return sd.prefetchCh, nil
+}
+
+func (sd *seed) prefetch(ctx context.Context, blocksPerDownload uint32) {
+	defer sd.wg.Done()
+
+	var (
+		err      error
+		canceled bool
+	)
+	for start := uint32(0); start < sd.blockNum && err == nil; start += blocksPerDownload {
+		select {
+		case <-ctx.Done():
+			canceled = true
+		default:
+		}
+		if canceled {
+			break
+		}
+
+		end := start + blocksPerDownload - 1
+		if end >= sd.blockNum {
+			end = sd.blockNum - 1
+		}
+		err = sd.downloadBlocks(ctx, start, end, true)
+	}
+
+	sd.Lock()
+	defer sd.Unlock()
+	result := &PreFetchResult{Success: err == nil && !canceled, Err: err, Canceled: canceled}
+	if result.Success {
+		if err := sd.cache.Sync(); err != nil {
+			result.Success, result.Err = false, err
+		}
+	}
+	if result.Success {
+		sd.status = FinishedStatus
+	} else if sd.status != DeadStatus {
+		sd.status = InitialStatus
+	}
+	if result.Err != nil {
+		logrus.Errorf("failed to prefetch seed %s: %v", sd.key, result.Err)
+	}
+	sd.prefetchResult = result
+	close(sd.prefetchCh)
+	if sd.status != DeadStatus {
+		if err := sd.saveMeta(); err != nil {
+			logrus.Errorf("failed to save meta of seed %s: %v", sd.key, err)
+		}
+	}
+}
+
+// downloadBlocks downloads the blocks in [start, end] which are not cached.
+func (sd *seed) downloadBlocks(ctx context.Context, start, end uint32, rateLimit bool) error {
+	ranges, err := sd.blockMeta.Get(start, end, false)
+	if err != nil {
+		return err
+	}
+	for _, r := range ranges {
+		off := int64(r.StartIndex) * sd.blockSize
+		endOff := (int64(r.EndIndex)+1)*sd.blockSize - 1
+		if endOff >= sd.baseInfo.FullLength {
+			endOff = sd.baseInfo.FullLength - 1
+		}
+		rangeStruct := httputils.RangeStruct{StartIndex: off, EndIndex: endOff}
+		if _, err := sd.down.DownloadToWriterAt(ctx, rangeStruct, defaultDownloadTimeout, off, sd.cache, rateLimit); err != nil {
+			return err
+		}
+		if sd.openMemoryCache {
+			// flush the block to local fs to limit the memory usage.
+			if err := sd.cache.Sync(); err != nil {
+				return err
+			}
+		}
+		sd.Lock()
+		err := sd.blockMeta.Set(r.StartIndex, r.EndIndex, true)
+		sd.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sd *seed) GetPrefetchResult() (PreFetchResult, error) {
+	sd.RLock()
+	defer sd.RUnlock()
+
+	if sd.prefetchResult == nil {
+		return PreFetchResult{}, fmt.Errorf("prefetch of seed %s is not finished", sd.key)
+	}
+	return *sd.prefetchResult, nil
+}
+
+func (sd *seed) Delete() error {
+	sd.stopPrefetch()
+
+	sd.Lock()
+	defer sd.Unlock()
+	if sd.status == DeadStatus {
+		return nil
+	}
+	sd.status = DeadStatus
+	sd.cache.Close()
+	if err := sd.cache.Remove(); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.RemoveAll(sd.dir)
+}
+
+func (sd *seed) Download(off int64, size int64) (io.ReadCloser, error) {
+	sd.Lock()
+	if sd.status == DeadStatus {
+		sd.Unlock()
+		return nil, errors.Wrapf(errortypes.ErrDataNotFound, "seed %s is dead", sd.key)
+	}
+	sd.accessTime = time.Now()
+	sd.Unlock()
+
+	fullSize := sd.baseInfo.FullLength
+	if off < 0 || off >= fullSize {
+		return nil, errortypes.NewHTTPError(http.StatusRequestedRangeNotSatisfiable, "out of range")
+	}
+	if size <= 0 || off+size > fullSize {
+		size = fullSize - off
+	}
+
+	if sd.isCached(off, size) {
+		return sd.cache.ReadStream(off, size)
+	}
+
+	// download the blocks which are not cached from the source directly into
+	// the cache, only one block can be written at a time in memory cache mode.
+	start := uint32(off / sd.blockSize)
+	end := uint32((off + size - 1) / sd.blockSize)
+	step := end - start + 1
+	if sd.openMemoryCache {
+		step = 1
+	}
+	for i := start; i <= end; i += step {
+		if err := sd.downloadBlocks(context.Background(), i, i+step-1, false); err != nil {
+			return nil, err
+		}
+	}
+	return sd.cache.ReadStream(off, size)
+}
+
+// isCached returns whether the range [off, off+size) is cached.
+func (sd *seed) isCached(off, size int64) bool {
+	sd.RLock()
+	defer sd.RUnlock()
+	start := uint32(off / sd.blockSize)
+	end := uint32((off + size - 1) / sd.blockSize)
+	ranges, err := sd.blockMeta.Get(start, end, false)
+	return err == nil && len(ranges) == 0
+}
+
+func (sd *seed) Stop() {
+	sd.stopPrefetch()
+
+	sd.Lock()
+	defer sd.Unlock()
+	if sd.status == DeadStatus {
+		return
+	}
+	if err := sd.cache.Close(); err != nil {
+		logrus.Errorf("failed to close cache of seed %s: %v", sd.key, err)
+	}
+	if err := sd.saveMeta(); err != nil {
+		logrus.Errorf("failed to save meta of seed %s: %v", sd.key, err)
+	}
+}
+
+// stopPrefetch cancels the prefetching and waits for it to exit.
+func (sd *seed) stopPrefetch() {
+	sd.Lock()
+	if sd.cancel != nil {
+		sd.cancel()
+	}
+	sd.Unlock()
+	sd.wg.Wait()
+}
+
+func (sd *seed) GetFullSize() int64 {
+	return sd.baseInfo.FullLength
+}
+
+func (sd *seed) GetStatus() string {
+	sd.RLock()
+	defer sd.RUnlock()
+	return sd.status
+}
+
+func (sd *seed) GetURL() string {
+	return sd.baseInfo.URL
+}
+
+func (sd *seed) GetHeaders() map[string][]string {
+	return sd.baseInfo.Header
+}
+
+func (sd *seed) GetTaskID() string {
+	return sd.baseInfo.TaskID
+}
+
+// refreshExpireTime updates the expire time duration and the access time of the seed.
+// The seed never expires if expireTimeDur is 0.
+func (sd *seed) refreshExpireTime(expireTimeDur time.Duration) error {
+	sd.Lock()
+	defer sd.Unlock()
+	sd.baseInfo.ExpireTimeDur = expireTimeDur
+	sd.accessTime = time.Now()
+	return sd.saveMeta()
+}
+
+func (sd *seed) isExpired(now time.Time) bool {
+	sd.RLock()
+	defer sd.RUnlock()
+	return sd.baseInfo.ExpireTimeDur > 0 && now.Sub(sd.accessTime) > sd.baseInfo.ExpireTimeDur
+}
+
+func (sd *seed) getAccessTime() time.Time {
+	sd.RLock()
+	defer sd.RUnlock()
+	return sd.accessTime
+}
+
+// saveMeta persists the metadata of the seed, it should be called with the lock held.
+func (sd *seed) saveMeta() error {
+	meta := &seedMeta{
+		Key:        sd.key,
+		BaseInfo:   sd.baseInfo,
+		Status:     sd.status,
+		Blocks:     sd.blockMeta.Encode(),
+		AccessTime: sd.accessTime,
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	// write to a temp file and rename it to keep the meta file intact.
+	metaPath := filepath.Join(sd.dir, metaFileName)
+	tmpPath := metaPath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, metaPath)
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package seed
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/go-check/check"
+)
+
+func (suite *SeedTestSuite) newTestSeed(c *check.C, name string, fileName string, fileLength int64, memoryCache bool) *seed {
+	sd, err := newSeed(seedOpt{
+		key: name,
+		dir: filepath.Join(suite.cacheDir, name),
+		info: BaseInfo{
+			URL:        fmt.Sprintf("http://%s/%s", suite.host, fileName),
+			TaskID:     name,
+			FullLength: fileLength,
+			BlockOrder: 15,
+		},
+		openMemoryCache: memoryCache,
+	})
+	c.Assert(err, check.IsNil)
+	return sd
+}
+
+func (suite *SeedTestSuite) TestSeedDownloadFromSource(c *check.C) {
+	for _, memoryCache := range []bool{false, true} {
+		name := fmt.Sprintf("seed-source-%t", memoryCache)
+		sd := suite.newTestSeed(c, name, "fileB", 1024*1024, memoryCache)
+
+		c.Assert(sd.GetStatus(), check.Equals, InitialStatus)
+		_, err := sd.GetPrefetchResult()
+		c.Assert(err, check.NotNil)
+		c.Assert(sd.isCached(0, sd.GetFullSize()), check.Equals, false)
+		suite.checkFileWithSeed(c, "fileB", 1024*1024, sd)
+		// the ranges downloaded from the source are written to the cache.
+		c.Assert(sd.isCached(0, sd.GetFullSize()), check.Equals, true)
+		c.Assert(sd.GetStatus(), check.Equals, InitialStatus)
+
+		_, err = sd.Download(1024*1024, 10)
+		c.Assert(err, check.NotNil)
+		c.Assert(sd.Delete(), check.IsNil)
+	}
+}
+
+func (suite *SeedTestSuite) TestSeedPrefetch(c *check.C) {
+	for _, memoryCache := range []bool{false, true} {
+		name := fmt.Sprintf("seed-prefetch-%t", memoryCache)
+		sd := suite.newTestSeed(c, name, "fileC", 1500*1024, memoryCache)
+
+		ch, err := sd.Prefetch(64 * 1024)
+		c.Assert(err, check.IsNil)
+		<-ch
+		result, err := sd.GetPrefetchResult()
+		c.Assert(err, check.IsNil)
+		c.Assert(result.Success, check.Equals, true)
+		c.Assert(sd.GetStatus(), check.Equals, FinishedStatus)
+		c.Assert(sd.isCached(0, sd.GetFullSize()), check.Equals, true)
+		suite.checkFileWithSeed(c, "fileC", 1500*1024, sd)
+
+		c.Assert(sd.Delete(), check.IsNil)
+		c.Assert(sd.GetStatus(), check.Equals, DeadStatus)
+		_, err = sd.Download(0, 10)
+		c.Assert(err, check.NotNil)
+	}
+}
+
+func (suite *SeedTestSuite) TestSeedRestore(c *check.C) {
+	sd := suite.newTestSeed(c, "seed-restore", "fileA", 500*1024, false)
+	ch, err := sd.Prefetch(0)
+	c.Assert(err, check.IsNil)
+	<-ch
+	sd.Stop()
+
+	restored, err := restoreSeed(sd.dir, nil, false)
+	c.Assert(err, check.IsNil)
+	defer restored.Delete()
+	c.Assert(restored.GetStatus(), check.Equals, FinishedStatus)
+	c.Assert(restored.GetURL(), check.Equals, sd.GetURL())
+	c.Assert(restored.isCached(0, restored.GetFullSize()), check.Equals, true)
+
+	rc, err := restored.Download(100, 200)
+	c.Assert(err, check.IsNil)
+	data, err := ioutil.ReadAll(rc)
+	rc.Close()
+	c.Assert(err, check.IsNil)
+	suite.checkDataWithFileServer(c, "fileA", 100, 200, data)
+}
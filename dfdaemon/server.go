@@ -25,6 +25,7 @@ import (
 	"github.com/dragonflyoss/Dragonfly/dfdaemon/config"
 	"github.com/dragonflyoss/Dragonfly/dfdaemon/handler"
 	"github.com/dragonflyoss/Dragonfly/dfdaemon/proxy"
+	"github.com/dragonflyoss/Dragonfly/dfdaemon/seed"
 	dfgetConfig "github.com/dragonflyoss/Dragonfly/dfget/config"
 	"github.com/dragonflyoss/Dragonfly/dfget/core/uploader"
 	"github.com/dragonflyoss/Dragonfly/version"
@@ -36,8 +37,10 @@ import (
 
 // Server represents the dfdaemon server.
 type Server struct {
-	server *http.Server
-	proxy  *proxy.Proxy
+	server  *http.Server
+	proxy   *proxy.Proxy
+	checker *handler.HealthChecker
+	seeds   seed.Manager
 }
 
 // Option is the functional option for creating a server.
@@ -77,6 +80,22 @@ func WithProxy(p *proxy.Proxy) Option {
 	}
 }
 
+// WithHealthChecker sets the checker used by the health endpoints.
+func WithHealthChecker(c *handler.HealthChecker) Option {
+	return func(s *Server) error {
+		s.checker = c
+		return nil
+	}
+}
+
+// WithSeedManager sets the seed manager served by the /seeds endpoints.
+func WithSeedManager(m seed.Manager) Option {
+	return func(s *Server) error {
+		s.seeds = m
+		return nil
+	}
+}
+
 // New returns a new server instance.
 func New(opts ...Option) (*Server, error) {
 	p, _ := proxy.New()
@@ -108,12 +127,28 @@ func NewFromConfig(cfg config.Properties) (*Server, error) {
 	opts := []Option{
 		WithProxy(p),
 		WithAddr(fmt.Sprintf(":%d", cfg.Port)),
+		WithHealthChecker(handler.NewHealthChecker(cfg.SuperNodes, cfg.DFRepo)),
 	}
 
 	if cfg.CertPem != "" && cfg.KeyPem != "" {
 		opts = append(opts, WithTLSFromFile(cfg.CertPem, cfg.KeyPem))
 	}
 
+	if cfg.Seed.Enable {
+		m, err := seed.NewSeedManager(seed.NewSeedManagerOpt{
+			StoreDir:           cfg.Seed.Dir,
+			ConcurrentLimit:    cfg.Seed.ConcurrentLimit,
+			TotalLimit:         cfg.Seed.TotalLimit,
+			DownloadBlockOrder: cfg.Seed.BlockOrder,
+			OpenMemoryCache:    cfg.Seed.OpenMemoryCache,
+			DownloadRate:       int64(cfg.Seed.DownloadRate),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "create seed manager")
+		}
+		opts = append(opts, WithSeedManager(m))
+	}
+
 	return New(opts...)
 }
 
@@ -133,7 +168,7 @@ func LaunchPeerServer(cfg config.Properties) error {
 // Start runs dfdaemon's http server.
 func (s *Server) Start() error {
 	var err error
-	_ = proxy.WithDirectHandler(handler.New())(s.proxy)
+	_ = proxy.WithDirectHandler(handler.New(s.checker, s.seeds))(s.proxy)
 	s.server.Handler = s.proxy
 	if s.server.TLSConfig != nil {
 		logrus.Infof("start dfdaemon https server on %s", s.server.Addr)
@@ -145,7 +180,11 @@ func (s *Server) Start() error {
 	return err
 }
 
-// Stop gracefully stops the dfdaemon http server.
+// Stop gracefully stops the dfdaemon http server and the seed manager.
 func (s *Server) Stop(ctx context.Context) error {
-	return s.server.Shutdown(ctx)
+	err := s.server.Shutdown(ctx)
+	if s.seeds != nil {
+		s.seeds.Stop()
+	}
+	return err
 }
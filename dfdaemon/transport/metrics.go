@@ -0,0 +1,136 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transport
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/dragonflyoss/Dragonfly/dfdaemon/constant"
+	"github.com/dragonflyoss/Dragonfly/dfdaemon/downloader"
+	"github.com/dragonflyoss/Dragonfly/pkg/metricsutils"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The paths a proxied request goes through.
+const (
+	// pathP2P means the request is served by dfget.
+	pathP2P = "p2p"
+	// pathDirect means the request is sent to the origin directly.
+	pathDirect = "direct"
+	// pathFallback means the request is sent to the origin after dfget failed.
+	pathFallback = "fallback"
+)
+
+// The sources where the bytes of a response come from.
+const (
+	sourceP2P    = "p2p"
+	sourceOrigin = "origin"
+)
+
+var transportMetrics = newMetrics(nil)
+
+// metrics defines some prometheus metrics for monitoring the traffic proxied by dfdaemon.
+type metrics struct {
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	responseBytes   *prometheus.CounterVec
+}
+
+func newMetrics(register prometheus.Registerer) *metrics {
+	return &metrics{
+		requests: metricsutils.NewCounter(constant.Subsystem, "proxy_requests_total",
+			"Total number of proxied requests by rule and path", []string{"rule", "path"}, register,
+		),
+		requestDuration: metricsutils.NewHistogram(constant.Subsystem, "proxy_request_duration_seconds",
+			"Histogram of the duration until the response of a proxied request is ready", []string{"rule", "path"},
+			[]float64{.01, .02, .04, .1, .2, .4, 1, 2, 4, 8, 20, 60, 120}, register,
+		),
+		responseBytes: metricsutils.NewCounter(constant.Subsystem, "proxy_response_bytes_total",
+			"Total bytes of the proxied responses by source", []string{"source"}, register,
+		),
+	}
+}
+
+// withBytesCounter wraps the body of res to count the bytes served from the given source.
+func (m *metrics) withBytesCounter(res *http.Response, source string) *http.Response {
+	// the body of a protocol switching response must stay writable for the reverse proxy
+	if res == nil || res.Body == nil || res.StatusCode == http.StatusSwitchingProtocols {
+		return res
+	}
+	res.Body = &countingReadCloser{
+		ReadCloser: res.Body,
+		counter:    m.responseBytes.WithLabelValues(source),
+	}
+	return res
+}
+
+// withDfgetBytesCounter wraps the body of res downloaded by dfget, and the bytes
+// are counted as origin if dfget has fallen back to download them from the source.
+func (m *metrics) withDfgetBytesCounter(res *http.Response) *http.Response {
+	if res == nil || res.Body == nil {
+		return res
+	}
+	reporter, ok := res.Body.(downloader.BackSourceReporter)
+	if !ok {
+		return m.withBytesCounter(res, sourceP2P)
+	}
+	res.Body = &backSourceCountingReadCloser{
+		ReadCloser: res.Body,
+		reporter:   reporter,
+		p2p:        m.responseBytes.WithLabelValues(sourceP2P),
+		origin:     m.responseBytes.WithLabelValues(sourceOrigin),
+	}
+	return res
+}
+
+// backSourceCountingReadCloser counts the bytes read from the underlying ReadCloser
+// by the source which dfget reports.
+type backSourceCountingReadCloser struct {
+	io.ReadCloser
+	reporter downloader.BackSourceReporter
+	p2p      prometheus.Counter
+	origin   prometheus.Counter
+}
+
+func (r *backSourceCountingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		// dfget reports falling back before it writes any bytes from the source.
+		if r.reporter.BackSource() {
+			r.origin.Add(float64(n))
+		} else {
+			r.p2p.Add(float64(n))
+		}
+	}
+	return n, err
+}
+
+// countingReadCloser counts the bytes read from the underlying ReadCloser.
+type countingReadCloser struct {
+	io.ReadCloser
+	counter prometheus.Counter
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.counter.Add(float64(n))
+	}
+	return n, err
+}
@@ -19,6 +19,7 @@ package transport
 import (
 	"context"
 	"crypto/tls"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -32,6 +33,7 @@ import (
 
 	"github.com/dragonflyoss/Dragonfly/dfdaemon/downloader"
 	"github.com/dragonflyoss/Dragonfly/dfdaemon/exception"
+	"github.com/dragonflyoss/Dragonfly/pkg/tracing"
 )
 
 var (
@@ -46,6 +48,7 @@ type DFRoundTripper struct {
 	Round            *http.Transport
 	Round2           http.RoundTripper
 	ShouldUseDfget   func(req *http.Request) bool
+	RuleLabel        func(req *http.Request) string
 	Downloader       downloader.Interface
 	StreamDownloader downloader.Stream
 	streamMode       bool
@@ -57,6 +60,7 @@ func New(opts ...Option) (*DFRoundTripper, error) {
 		Round:          defaultHTTPTransport(nil),
 		Round2:         http.NewFileTransport(http.Dir("/")),
 		ShouldUseDfget: NeedUseGetter,
+		RuleLabel:      defaultRuleLabel,
 	}
 
 	for _, opt := range opts {
@@ -130,23 +134,62 @@ func WithCondition(c func(r *http.Request) bool) Option {
 	}
 }
 
+// WithRuleLabel configures how to name the rule matching a request in metrics.
+func WithRuleLabel(f func(r *http.Request) string) Option {
+	return func(rt *DFRoundTripper) error {
+		rt.RuleLabel = f
+		return nil
+	}
+}
+
 // RoundTrip only process first redirect at present
 // fix resource release
-func (roundTripper *DFRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+func (roundTripper *DFRoundTripper) RoundTrip(req *http.Request) (res *http.Response, err error) {
+	startTime := time.Now()
+	// the rule should be matched before ShouldUseDfget rewrites the url
+	rule := roundTripper.RuleLabel(req)
+	path := pathDirect
+
+	// the span covers the request until the response header is ready, and
+	// it's passed to dfget through the context of the request.
+	ctx, span := tracing.StartSpan(tracing.Extract(req.Context(), req.Header),
+		"dfdaemon.RoundTrip", tracing.SpanKindServer)
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", tracing.SanitizeURL(req.URL.String()))
+	span.SetAttribute("dfdaemon.rule", rule)
+	defer func() {
+		span.SetAttribute("dfdaemon.path", path)
+		if res != nil {
+			span.SetAttribute("http.status_code", res.StatusCode)
+		}
+		span.SetError(err)
+		span.End()
+	}()
+	req = req.WithContext(ctx)
+
 	if roundTripper.ShouldUseDfget(req) {
 		// delete the Accept-Encoding header to avoid returning the same cached
 		// result for different requests
 		req.Header.Del("Accept-Encoding")
 		logrus.Debugf("round trip with dfget: %s", req.URL.String())
-		if res, err := roundTripper.download(req, req.URL.String()); err == nil || exception.IsAuthError(err) {
-			return res, err
+		if res, err = roundTripper.download(req, req.URL.String()); err == nil || exception.IsAuthError(err) {
+			path = pathP2P
+			observeRequest(rule, path, startTime)
+			return transportMetrics.withDfgetBytesCounter(res), err
 		}
+		path = pathFallback
 	}
 	logrus.Debugf("round trip directly: %s %s", req.Method, req.URL.String())
 	req.Host = req.URL.Host
 	req.Header.Set("Host", req.Host)
-	res, err := roundTripper.Round.RoundTrip(req)
-	return res, err
+	res, err = roundTripper.Round.RoundTrip(req)
+	observeRequest(rule, path, startTime)
+	return transportMetrics.withBytesCounter(res, sourceOrigin), err
+}
+
+func observeRequest(rule, path string, startTime time.Time) {
+	transportMetrics.requests.WithLabelValues(rule, path).Inc()
+	transportMetrics.requestDuration.WithLabelValues(rule, path).Observe(time.Since(startTime).Seconds())
 }
 
 // download uses dfget to download.
@@ -155,7 +198,7 @@ func (roundTripper *DFRoundTripper) download(req *http.Request, urlString string
 		return roundTripper.downloadByStream(req.Context(), urlString, req.Header, uuid.New())
 	}
 
-	dstPath, err := roundTripper.downloadByGetter(req.Context(), urlString, req.Header, uuid.New())
+	dstPath, backSource, err := roundTripper.downloadByGetter(req.Context(), urlString, req.Header, uuid.New())
 	if err != nil {
 		logrus.Errorf("download fail: %v", err)
 		return nil, err
@@ -170,6 +213,7 @@ func (roundTripper *DFRoundTripper) download(req *http.Request, urlString string
 	response, err := roundTripper.Round2.RoundTrip(fileReq)
 	if err == nil {
 		response.Header.Set("Content-Disposition", "attachment; filename="+dstPath)
+		response.Body = &fileBody{ReadCloser: response.Body, backSource: backSource}
 	} else {
 		logrus.Errorf("read response from file:%s error:%v", dstPath, err)
 	}
@@ -177,10 +221,26 @@ func (roundTripper *DFRoundTripper) download(req *http.Request, urlString string
 	return response, err
 }
 
-// downloadByGetter is used to download file by DFGetter.
-func (roundTripper *DFRoundTripper) downloadByGetter(ctx context.Context, url string, header map[string][]string, name string) (string, error) {
+// fileBody is the body of a file downloaded by dfget.
+type fileBody struct {
+	io.ReadCloser
+	backSource bool
+}
+
+// BackSource implements downloader.BackSourceReporter.
+func (b *fileBody) BackSource() bool {
+	return b.backSource
+}
+
+// downloadByGetter is used to download file by DFGetter, and it also
+// returns whether the file has been downloaded from the source.
+func (roundTripper *DFRoundTripper) downloadByGetter(ctx context.Context, url string, header map[string][]string, name string) (string, bool, error) {
 	logrus.Infof("start download url:%s to %s in repo", url, name)
-	return roundTripper.Downloader.DownloadContext(ctx, url, header, name)
+	if d, ok := roundTripper.Downloader.(downloader.BackSourceInterface); ok {
+		return d.DownloadBackSourceContext(ctx, url, header, name)
+	}
+	path, err := roundTripper.Downloader.DownloadContext(ctx, url, header, name)
+	return path, false, err
 }
 
 func (roundTripper *DFRoundTripper) downloadByStream(ctx context.Context, url string, header map[string][]string, name string) (*http.Response, error) {
@@ -191,13 +251,24 @@ func (roundTripper *DFRoundTripper) downloadByStream(ctx context.Context, url st
 		return nil, err
 	}
 
+	// close the reader if possible to release the download
+	// when the client goes away.
+	body, ok := reader.(io.ReadCloser)
+	if !ok {
+		body = ioutil.NopCloser(reader)
+	}
 	resp := &http.Response{
 		StatusCode: 200,
-		Body:       ioutil.NopCloser(reader),
+		Body:       body,
 	}
 	return resp, nil
 }
 
+// defaultRuleLabel is the default value for RuleLabel.
+func defaultRuleLabel(req *http.Request) string {
+	return "default"
+}
+
 // needUseGetter is the default value for ShouldUseDfget, which downloads all
 // images layers with dfget.
 func NeedUseGetter(req *http.Request) bool {
@@ -0,0 +1,185 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/tracing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStreamDownloader struct {
+	content    string
+	backSource bool
+	err        error
+	ctx        context.Context
+}
+
+func (d *fakeStreamDownloader) DownloadStreamContext(ctx context.Context, url string, header map[string][]string, name string) (io.Reader, error) {
+	d.ctx = ctx
+	if d.err != nil {
+		return nil, d.err
+	}
+	return &fakeStreamReader{
+		ReadCloser: ioutil.NopCloser(strings.NewReader(d.content)),
+		backSource: d.backSource,
+	}, nil
+}
+
+type fakeStreamReader struct {
+	io.ReadCloser
+	backSource bool
+}
+
+func (r *fakeStreamReader) BackSource() bool {
+	return r.backSource
+}
+
+type fakeDownloader struct {
+	dir        string
+	content    string
+	backSource bool
+}
+
+func (d *fakeDownloader) DownloadContext(ctx context.Context, url string, header map[string][]string, name string) (string, error) {
+	path, _, err := d.DownloadBackSourceContext(ctx, url, header, name)
+	return path, err
+}
+
+func (d *fakeDownloader) DownloadBackSourceContext(ctx context.Context, url string, header map[string][]string, name string) (string, bool, error) {
+	path := filepath.Join(d.dir, name)
+	return path, d.backSource, ioutil.WriteFile(path, []byte(d.content), 0644)
+}
+
+func TestRoundTripMetrics(t *testing.T) {
+	transportMetrics = newMetrics(prometheus.NewRegistry())
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("origin"))
+	}))
+	defer origin.Close()
+
+	for _, tc := range []struct {
+		useDfget bool
+		err      error
+		path     string
+		body     string
+	}{
+		{useDfget: false, path: pathDirect, body: "origin"},
+		{useDfget: true, path: pathP2P, body: "p2p"},
+		{useDfget: true, err: errors.New("dfget failed"), path: pathFallback, body: "origin"},
+	} {
+		rt, err := New(
+			WithStreamMode(true),
+			WithStreamDownloader(&fakeStreamDownloader{content: "p2p", err: tc.err}),
+			WithCondition(func(*http.Request) bool { return tc.useDfget }),
+			WithRuleLabel(func(*http.Request) string { return "rule" }),
+		)
+		assert.Nil(t, err)
+
+		req, _ := http.NewRequest(http.MethodGet, origin.URL+"/blobs/sha256:abc", nil)
+		res, err := rt.RoundTrip(req)
+		assert.Nil(t, err)
+		body, err := ioutil.ReadAll(res.Body)
+		assert.Nil(t, err)
+		res.Body.Close()
+		assert.Equal(t, tc.body, string(body))
+		assert.Equal(t, float64(1), prom_testutil.ToFloat64(transportMetrics.requests.WithLabelValues("rule", tc.path)))
+	}
+
+	assert.Equal(t, float64(len("p2p")), prom_testutil.ToFloat64(transportMetrics.responseBytes.WithLabelValues(sourceP2P)))
+	assert.Equal(t, float64(2*len("origin")), prom_testutil.ToFloat64(transportMetrics.responseBytes.WithLabelValues(sourceOrigin)))
+}
+
+func TestRoundTripBackSourceMetrics(t *testing.T) {
+	workHome, err := ioutil.TempDir("", "dfdaemon-transport-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(workHome)
+
+	for _, streamMode := range []bool{true, false} {
+		transportMetrics = newMetrics(prometheus.NewRegistry())
+		for _, backSource := range []bool{true, false} {
+			rt, err := New(
+				WithStreamMode(streamMode),
+				WithStreamDownloader(&fakeStreamDownloader{content: "stream", backSource: backSource}),
+				WithDownloader(&fakeDownloader{dir: workHome, content: "stream", backSource: backSource}),
+				WithCondition(func(*http.Request) bool { return true }),
+			)
+			assert.Nil(t, err)
+
+			req, _ := http.NewRequest(http.MethodGet, "http://registry/v2/a/blobs/sha256:abc", nil)
+			res, err := rt.RoundTrip(req)
+			assert.Nil(t, err)
+			body, err := ioutil.ReadAll(res.Body)
+			assert.Nil(t, err)
+			res.Body.Close()
+			assert.Equal(t, "stream", string(body))
+		}
+
+		// the bytes dfget downloaded from the source are counted as origin
+		assert.Equal(t, float64(len("stream")), prom_testutil.ToFloat64(transportMetrics.responseBytes.WithLabelValues(sourceP2P)))
+		assert.Equal(t, float64(len("stream")), prom_testutil.ToFloat64(transportMetrics.responseBytes.WithLabelValues(sourceOrigin)))
+	}
+}
+
+func TestRoundTripTracing(t *testing.T) {
+	workHome, err := ioutil.TempDir("", "dfdaemon-transport-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(workHome)
+	file := filepath.Join(workHome, "spans.json")
+	shutdown, err := tracing.Init("dfdaemon", tracing.Config{File: file})
+	assert.Nil(t, err)
+
+	d := &fakeStreamDownloader{content: "p2p"}
+	rt, err := New(
+		WithStreamMode(true),
+		WithStreamDownloader(d),
+		WithCondition(func(*http.Request) bool { return true }),
+	)
+	assert.Nil(t, err)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://registry/v2/a/blobs/sha256:abc", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	res, err := rt.RoundTrip(req)
+	assert.Nil(t, err)
+	res.Body.Close()
+	shutdown()
+
+	spans, err := tracing.ReadSpans(file)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, "dfdaemon.RoundTrip", spans[0].Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].ParentSpanID)
+
+	// dfget is started as a child of the span
+	sc := tracing.SpanContextFromContext(d.ctx)
+	assert.Equal(t, spans[0].TraceID, sc.TraceID.String())
+	assert.Equal(t, spans[0].SpanID, sc.SpanID.String())
+}
@@ -64,6 +64,21 @@ type Properties struct {
 	// E.g. ["192.168.33.21=1", "192.168.33.22=2"]
 	Supernodes []*NodeWeight `yaml:"nodes,omitempty" json:"nodes,omitempty"`
 
+	// Cluster indicates that the supernodes form a cluster in which every task is owned
+	// by one of them. dfget skips the unreachable supernodes and follows the redirection
+	// to the owner of the task when registering.
+	Cluster bool `yaml:"cluster,omitempty" json:"cluster,omitempty"`
+
+	// IDC the data center which the host locates in. It's reported to the supernode
+	// to select the target peers of a preheat task by IDC.
+	IDC string `yaml:"idc,omitempty" json:"idc,omitempty"`
+
+	// Labels the labels of the host. They're reported to the supernode
+	// to select the target peers of a preheat task by labels.
+	//
+	// E.g. {"zone": "east", "role": "builder"}
+	Labels map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+
 	// LocalLimit rate limit about a single download task, format: G(B)/g/M(B)/m/K(B)/k/B
 	// pure number will also be parsed as Byte.
 	LocalLimit rate.Rate `yaml:"localLimit,omitempty" json:"localLimit,omitempty"`
@@ -178,6 +193,18 @@ type Config struct {
 	// default:`p2p`.
 	Pattern string `json:"pattern,omitempty"`
 
+	// Tenant the tenant which dfget belongs to, it's sent to the supernode
+	// to isolate the peers and the quotas of different tenants.
+	Tenant string `json:"tenant,omitempty"`
+
+	// TenantToken the token which authenticates dfget as a member of a tenant.
+	// It's never printed or persisted.
+	TenantToken string `json:"-"`
+
+	// AuthToken the bearer token to call the supernode APIs when the
+	// authentication of supernode is enabled. It's never printed or persisted.
+	AuthToken string `json:"-"`
+
 	// CA certificate to verify when supernode interact with the source.
 	Cacerts []string `json:"cacert,omitempty"`
 
@@ -199,12 +226,37 @@ type Config struct {
 	// Insecure indicates whether skip secure verify when supernode interact with the source.
 	Insecure bool `json:"insecure,omitempty"`
 
+	// AdaptiveRate indicates whether to adapt the download concurrency of every peer
+	// and the download rate according to the observed throughput and RTT.
+	// The rate is still bounded by the LocalLimit, MinRate and TotalLimit.
+	AdaptiveRate bool `json:"adaptiveRate,omitempty"`
+
+	// HedgePercentile specifies the percentile of the latency of the latest downloaded pieces.
+	// If downloading a piece takes longer than it, a hedged request will be sent to an
+	// alternative peer returned by supernode, and the response which completes first wins.
+	// The hedged request is disabled if the value is 0.
+	HedgePercentile int `json:"hedgePercentile,omitempty"`
+
+	// StreamBufferSize specifies the memory budget for the pieces which arrive
+	// earlier than their turn when writing the file to a stream. The pieces beyond
+	// the budget are spilled to disk and read back in order.
+	// There is no limit if the value is 0.
+	StreamBufferSize fileutils.Fsize `json:"streamBufferSize,omitempty"`
+
 	// ShowBar shows progress bar, it's conflict with `--console`.
 	ShowBar bool `json:"showBar,omitempty"`
 
 	// Console shows log on console, it's conflict with `--showbar`.
 	Console bool `json:"console,omitempty"`
 
+	// ProgressFormat specifies the format of the machine-readable progress events,
+	// only 'json' is supported now. The progress events are disabled if it's empty.
+	ProgressFormat string `json:"progressFormat,omitempty"`
+
+	// ProgressFD specifies the file descriptor which the progress events are written to,
+	// default: 2(stderr).
+	ProgressFD int `json:"progressFD,omitempty"`
+
 	// Verbose indicates whether to be verbose.
 	// If set true, log level will be 'debug'.
 	Verbose bool `json:"verbose,omitempty"`
@@ -258,6 +310,7 @@ func NewConfig() *Config {
 	}
 	cfg.User = currentUser.Username
 	cfg.RV.FileLength = -1
+	cfg.StreamBufferSize = DefaultStreamBufferSize
 	cfg.ConfigFiles = []string{DefaultYamlConfigFile, DefaultIniConfigFile}
 	return cfg
 }
@@ -275,11 +328,37 @@ func AssertConfig(cfg *Config) (err error) {
 	if err := checkOutput(cfg); err != nil {
 		return errors.Wrapf(errortypes.ErrInvalidValue, "output: %v", err)
 	}
+
+	if err := checkProgress(cfg); err != nil {
+		return errors.Wrapf(errortypes.ErrInvalidValue, "progress: %v", err)
+	}
+
+	if cfg.HedgePercentile < 0 || cfg.HedgePercentile >= 100 {
+		return errors.Wrapf(errortypes.ErrInvalidValue, "hedge percentile: %d", cfg.HedgePercentile)
+	}
+	return nil
+}
+
+func checkProgress(cfg *Config) error {
+	if cfg.ProgressFormat != "" && cfg.ProgressFormat != ProgressFormatJSON {
+		return fmt.Errorf("unsupported progress format[%s]", cfg.ProgressFormat)
+	}
+	if cfg.ProgressFD < 0 {
+		return fmt.Errorf("invalid progress fd[%d]", cfg.ProgressFD)
+	}
 	return nil
 }
 
 // This function must be called after checkURL
 func checkOutput(cfg *Config) error {
+	if cfg.Output == StdoutOutput {
+		if cfg.Console {
+			return fmt.Errorf("it's conflict with '--console' when writing to stdout")
+		}
+		cfg.RV.StreamMode = true
+		return nil
+	}
+
 	if stringutils.IsEmptyStr(cfg.Output) {
 		url := strings.TrimRight(cfg.URL, "/")
 		idx := strings.LastIndexByte(url, '/')
@@ -331,10 +410,12 @@ type RuntimeVariable struct {
 	// RealTarget specifies the full target path whose value is equal to the `Output`.
 	RealTarget string
 
-	// StreamMode specifies that all pieces will be wrote to a Pipe, currently only support cdn mode.
+	// StreamMode specifies that all pieces will be wrote to a Pipe in order.
 	// when StreamMode is true, all data will write directly.
+	// It's enabled by `--output -` which writes the file to stdout, and the pieces
+	// are also written into the service file in p2p pattern so that other peers
+	// can still download them from this peer.
 	// the mode is prepared for this issue https://github.com/dragonflyoss/Dragonfly/issues/1164
-	// TODO: support p2p mode
 	StreamMode bool
 
 	// TargetDir is the directory of the RealTarget path.
@@ -60,7 +60,7 @@ func (suite *ConfigSuite) TestConfig_String(c *check.C) {
 	cfg.MinRate = 64 * rate.KB
 	cfg.Pattern = "p2p"
 	expected = "\"url\":\"\",\"output\":\"\",\"pattern\":\"p2p\"," +
-		"\"localLimit\":\"20MB\",\"minRate\":\"64KB\""
+		"\"streamBufferSize\":67108864,\"localLimit\":\"20MB\",\"minRate\":\"64KB\""
 	c.Assert(strings.Contains(cfg.String(), expected), check.Equals, true)
 }
 
@@ -159,6 +159,42 @@ func (suite *ConfigSuite) TestCheckOutput(c *check.C) {
 	}
 }
 
+func (suite *ConfigSuite) TestCheckOutputStdout(c *check.C) {
+	cfg.URL = "http://www.taobao.com"
+	cfg.Output = StdoutOutput
+	c.Assert(checkOutput(cfg), check.IsNil)
+	c.Assert(cfg.Output, check.Equals, StdoutOutput)
+	c.Assert(cfg.RV.StreamMode, check.Equals, true)
+
+	cfg.Console = true
+	c.Assert(checkOutput(cfg), check.NotNil)
+	cfg.Console = false
+	cfg.RV.StreamMode = false
+}
+
+func (suite *ConfigSuite) TestCheckProgress(c *check.C) {
+	var cases = []struct {
+		format string
+		fd     int
+		hasErr bool
+	}{
+		{format: "", fd: 0, hasErr: false},
+		{format: ProgressFormatJSON, fd: DefaultProgressFD, hasErr: false},
+		{format: ProgressFormatJSON, fd: 3, hasErr: false},
+		{format: "xml", fd: DefaultProgressFD, hasErr: true},
+		{format: ProgressFormatJSON, fd: -1, hasErr: true},
+	}
+
+	for _, v := range cases {
+		cfg.ProgressFormat = v.format
+		cfg.ProgressFD = v.fd
+		err := checkProgress(cfg)
+		c.Assert(err != nil, check.Equals, v.hasErr, check.Commentf("%v", v))
+	}
+	cfg.ProgressFormat = ""
+	cfg.ProgressFD = 0
+}
+
 func (suite *ConfigSuite) TestProperties_Load(c *check.C) {
 	dirName, _ := ioutil.TempDir("/tmp", "dfget-TestProperties_Load-")
 	defer os.RemoveAll(dirName)
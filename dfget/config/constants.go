@@ -19,6 +19,7 @@ package config
 import (
 	"time"
 
+	"github.com/dragonflyoss/Dragonfly/pkg/fileutils"
 	"github.com/dragonflyoss/Dragonfly/pkg/rate"
 )
 
@@ -45,6 +46,11 @@ const (
 	PatternSource = "source"
 )
 
+/* progress format */
+const (
+	ProgressFormatJSON = "json"
+)
+
 /* properties */
 const (
 	DefaultYamlConfigFile  = "/etc/dragonfly/dfget.yml"
@@ -75,6 +81,11 @@ const (
 
 	StrBytes   = "bytes"
 	StrPattern = "pattern"
+
+	// StrPreheatTimestamp and StrPreheatSignature sign the preheat requests
+	// sent by supernode with the auth token shared with dfget.
+	StrPreheatTimestamp = "X-Dragonfly-Preheat-Timestamp"
+	StrPreheatSignature = "X-Dragonfly-Preheat-Signature"
 )
 
 /* piece meta */
@@ -108,8 +119,15 @@ const (
 	RangeNotSatisfiableDesc = "range not satisfiable"
 	AddrUsedDesc            = "address already in use"
 
-	PeerHTTPPathPrefix = "/peer/file/"
-	CDNPathPrefix      = "/qtdown/"
+	// StdoutOutput is the value of `--output` which writes the file to stdout.
+	StdoutOutput = "-"
+	// StdoutTaskName is used as the name of the task file when writing to stdout.
+	StdoutTaskName = "stdout"
+
+	PeerHTTPPathPrefix  = "/peer/file/"
+	PeerHTTPPathPreheat = "/peer/preheat"
+	PeerHTTPPathVerify  = "/peer/verify"
+	CDNPathPrefix       = "/qtdown/"
 
 	LocalHTTPPathCheck  = "/check/"
 	LocalHTTPPathClient = "/client/"
@@ -123,6 +141,14 @@ const (
 	DefaultSupernodeSchema = "http"
 	DefaultSupernodeIP     = "127.0.0.1"
 	DefaultSupernodePort   = 8002
+
+	DefaultProgressFD = 2
+
+	DefaultStreamBufferSize = 64 * fileutils.MB
+
+	// AuthTokenEnv is the environment variable of the token to call the supernode APIs,
+	// it's used when `--auth-token` isn't set and passed to the peer server.
+	AuthTokenEnv = "DF_AUTH_TOKEN"
 )
 
 /* errors code */
@@ -141,6 +167,14 @@ const (
 
 	// CodeDownloadError represents failed to download file.
 	CodeDownloadError
+
+	// CodeStreamInterrupted represents the stream is cut off after
+	// some data has been written to stdout.
+	CodeStreamInterrupted
+
+	// CodeStreamWriteError represents failed to write the stream to stdout,
+	// e.g. the reading end of the pipe has been closed.
+	CodeStreamWriteError
 )
 
 const (
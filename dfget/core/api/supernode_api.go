@@ -17,14 +17,19 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
 	"time"
 
 	api_types "github.com/dragonflyoss/Dragonfly/apis/types"
 	"github.com/dragonflyoss/Dragonfly/dfget/types"
 	"github.com/dragonflyoss/Dragonfly/pkg/constants"
 	"github.com/dragonflyoss/Dragonfly/pkg/httputils"
+	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
+	"github.com/dragonflyoss/Dragonfly/pkg/tracing"
 	"github.com/pkg/errors"
 
 	"github.com/sirupsen/logrus"
@@ -42,12 +47,33 @@ const (
 	peerHeartBeatPath     = "/peer/heartbeat"
 )
 
+/* the headers which identify the tenant of dfget */
+const (
+	tenantHeader      = "X-Dragonfly-Tenant"
+	tenantTokenHeader = "X-Dragonfly-Tenant-Token"
+)
+
 // NewSupernodeAPI creates a new instance of SupernodeAPI with default value.
 func NewSupernodeAPI() SupernodeAPI {
+	return NewSupernodeAPIWithAuthToken("")
+}
+
+// NewSupernodeAPIWithAuthToken creates a new instance of SupernodeAPI which
+// sends the token as the bearer token of every request to supernode.
+func NewSupernodeAPIWithAuthToken(token string) SupernodeAPI {
+	return NewTracedSupernodeAPI(nil, token)
+}
+
+// NewTracedSupernodeAPI creates a new instance of SupernodeAPI whose requests
+// are traced as the children of the span in ctx. The requests are not traced
+// if ctx is nil.
+func NewTracedSupernodeAPI(ctx context.Context, token string) SupernodeAPI {
 	return &supernodeAPI{
 		Scheme:     "http",
 		Timeout:    5 * time.Second,
 		HTTPClient: httputils.DefaultHTTPClient,
+		AuthToken:  token,
+		ctx:        ctx,
 	}
 }
 
@@ -70,6 +96,9 @@ type supernodeAPI struct {
 	Scheme     string
 	Timeout    time.Duration
 	HTTPClient httputils.SimpleHTTPClient
+	AuthToken  string
+	// ctx carries the trace context of the requests.
+	ctx context.Context
 }
 
 var _ SupernodeAPI = &supernodeAPI{}
@@ -84,7 +113,7 @@ func (api *supernodeAPI) Register(node string, req *types.RegisterRequest) (
 	)
 	url := fmt.Sprintf("%s://%s%s",
 		api.Scheme, node, peerRegisterPath)
-	if code, body, e = api.HTTPClient.PostJSON(url, req, api.Timeout); e != nil {
+	if code, body, e = api.postJSON(url, withTenantHeaders(nil, req), req); e != nil {
 		return nil, e
 	}
 	if !httputils.HTTPStatusOk(code) {
@@ -168,7 +197,7 @@ func (api *supernodeAPI) ReportMetrics(node string, req *api_types.TaskMetricsRe
 	)
 	url := fmt.Sprintf("%s://%s%s",
 		api.Scheme, node, metricsReportPath)
-	if code, body, err = api.HTTPClient.PostJSON(url, req, api.Timeout); err != nil {
+	if code, body, err = api.postJSON(url, nil, req); err != nil {
 		return nil, err
 	}
 	if !httputils.HTTPStatusOk(code) {
@@ -190,13 +219,26 @@ func (api *supernodeAPI) get(url string, resp interface{}) error {
 	if url == "" {
 		return fmt.Errorf("invalid url")
 	}
-	if code, body, e = api.HTTPClient.Get(url, api.Timeout); e != nil {
+	span, header := api.startSpan(http.MethodGet, url, nil)
+	defer func() {
+		span.SetAttribute("http.status_code", code)
+		span.SetError(e)
+		span.End()
+	}()
+	if header = api.withAuthHeader(header); len(header) > 0 {
+		code, body, e = api.HTTPClient.GetWithHeaders(url, header, api.Timeout)
+	} else {
+		code, body, e = api.HTTPClient.Get(url, api.Timeout)
+	}
+	if e != nil {
 		return e
 	}
 	if !httputils.HTTPStatusOk(code) {
-		return fmt.Errorf("%d:%s", code, body)
+		e = fmt.Errorf("%d:%s", code, body)
+		return e
 	}
-	return json.Unmarshal(body, resp)
+	e = json.Unmarshal(body, resp)
+	return e
 }
 
 // report resource
@@ -210,7 +252,7 @@ func (api *supernodeAPI) ReportResource(node string, req *types.RegisterRequest)
 	header := map[string]string{
 		"X-report-resource": "true",
 	}
-	if code, body, err = api.HTTPClient.PostJSONWithHeaders(url, header, req, api.Timeout); err != nil {
+	if code, body, err = api.postJSON(url, withTenantHeaders(header, req), req); err != nil {
 		return nil, err
 	}
 
@@ -263,7 +305,7 @@ func (api *supernodeAPI) ApplyForSeedNode(node string, req *types.RegisterReques
 	header := map[string]string{
 		"X-report-resource": "true",
 	}
-	if code, body, err = api.HTTPClient.PostJSONWithHeaders(url, header, req, api.Timeout); err != nil {
+	if code, body, err = api.postJSON(url, withTenantHeaders(header, req), req); err != nil {
 		return nil, err
 	}
 
@@ -280,6 +322,69 @@ func (api *supernodeAPI) ApplyForSeedNode(node string, req *types.RegisterReques
 	return resp, err
 }
 
+// postJSON sends the request with the header and the authorization header.
+func (api *supernodeAPI) postJSON(url string, header map[string]string, req interface{}) (code int, body []byte, err error) {
+	span, header := api.startSpan(http.MethodPost, url, header)
+	defer func() {
+		span.SetAttribute("http.status_code", code)
+		if err == nil && !httputils.HTTPStatusOk(code) {
+			span.SetError(fmt.Errorf("%d:%s", code, body))
+		}
+		span.SetError(err)
+		span.End()
+	}()
+	if header = api.withAuthHeader(header); len(header) > 0 {
+		return api.HTTPClient.PostJSONWithHeaders(url, header, req, api.Timeout)
+	}
+	return api.HTTPClient.PostJSON(url, req, api.Timeout)
+}
+
+// startSpan starts the span of the request to supernode, and returns a copy
+// of header with the trace context added. It returns a nil span which does
+// nothing if the requests are not traced.
+func (api *supernodeAPI) startSpan(method, rawURL string, header map[string]string) (*tracing.Span, map[string]string) {
+	if api.ctx == nil {
+		return nil, header
+	}
+	name := method
+	if u, err := url.Parse(rawURL); err == nil {
+		name = method + " " + u.Path
+	}
+	ctx, span := tracing.StartSpan(api.ctx, name, tracing.SpanKindClient)
+	span.SetAttribute("http.method", method)
+	return span, tracing.InjectMap(ctx, header)
+}
+
+// withAuthHeader returns a copy of header with the authorization header added
+// if the auth token is set.
+func (api *supernodeAPI) withAuthHeader(header map[string]string) map[string]string {
+	if stringutils.IsEmptyStr(api.AuthToken) {
+		return header
+	}
+	result := make(map[string]string, len(header)+1)
+	for k, v := range header {
+		result[k] = v
+	}
+	result["Authorization"] = "Bearer " + api.AuthToken
+	return result
+}
+
+// withTenantHeaders returns a copy of header with the tenant headers of req added.
+// The original header is kept unchanged so that the token won't be logged.
+func withTenantHeaders(header map[string]string, req *types.RegisterRequest) map[string]string {
+	result := make(map[string]string, len(header)+2)
+	for k, v := range header {
+		result[k] = v
+	}
+	if !stringutils.IsEmptyStr(req.Tenant) {
+		result[tenantHeader] = req.Tenant
+	}
+	if !stringutils.IsEmptyStr(req.TenantToken) {
+		result[tenantTokenHeader] = req.TenantToken
+	}
+	return result
+}
+
 // FetchP2PNetworkInfo fetch the p2p network info from supernode.
 // @parameter
 // start: the start index for array of result
@@ -305,7 +410,7 @@ func (api *supernodeAPI) FetchP2PNetworkInfo(node string, start int, limit int,
 
 	url := fmt.Sprintf("%s://%s%s?start=%d&limit=%d",
 		api.Scheme, node, fetchP2PNetworkPath, start, limit)
-	if code, body, err = api.HTTPClient.PostJSON(url, req, api.Timeout); err != nil {
+	if code, body, err = api.postJSON(url, nil, req); err != nil {
 		return nil, err
 	}
 
@@ -336,7 +441,7 @@ func (api *supernodeAPI) HeartBeat(node string, req *api_types.HeartBeatRequest)
 	url := fmt.Sprintf("%s://%s%s",
 		api.Scheme, node, peerHeartBeatPath)
 
-	if code, body, err = api.HTTPClient.PostJSON(url, req, api.Timeout); err != nil {
+	if code, body, err = api.postJSON(url, nil, req); err != nil {
 		return nil, err
 	}
 
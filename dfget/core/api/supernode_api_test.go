@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	api_types "github.com/dragonflyoss/Dragonfly/apis/types"
 	"github.com/dragonflyoss/Dragonfly/dfget/types"
@@ -86,6 +87,55 @@ func (s *SupernodeAPITestSuite) TestSupernodeAPI_Register(c *check.C) {
 	c.Assert(r.Data.FileLength, check.Equals, res.Data.FileLength)
 }
 
+func (s *SupernodeAPITestSuite) TestSupernodeAPI_RegisterWithTenant(c *check.C) {
+	var headers map[string]string
+	res := types.RegisterResponse{BaseResponse: &types.BaseResponse{Code: constants.Success}}
+	s.mock.PostJSONWithHeadersFunc = func(url string, h map[string]string, body interface{}, timeout time.Duration) (int, []byte, error) {
+		headers = h
+		return 200, []byte(res.String()), nil
+	}
+
+	req := createRegisterRequest()
+	req.Tenant = "a"
+	req.TenantToken = "token-a"
+	r, e := s.api.Register(localhost, req)
+	c.Assert(e, check.IsNil)
+	c.Assert(r.Code, check.Equals, constants.Success)
+	c.Assert(headers, check.DeepEquals, map[string]string{
+		tenantHeader:      "a",
+		tenantTokenHeader: "token-a",
+	})
+	c.Assert(req.String(), check.Not(check.Matches), ".*token-a.*")
+}
+
+func (s *SupernodeAPITestSuite) TestSupernodeAPI_AuthToken(c *check.C) {
+	api := NewSupernodeAPIWithAuthToken("token")
+	api.(*supernodeAPI).HTTPClient = s.mock
+
+	var headers map[string]string
+	s.mock.GetWithHeadersFunc = func(url string, h map[string]string, timeout time.Duration) (int, []byte, error) {
+		headers = h
+		return 200, []byte(`{"code":611}`), nil
+	}
+	_, e := api.ServiceDown(localhost, "", "")
+	c.Assert(e, check.IsNil)
+	c.Assert(headers, check.DeepEquals, map[string]string{"Authorization": "Bearer token"})
+
+	res := types.RegisterResponse{BaseResponse: &types.BaseResponse{Code: constants.Success}}
+	s.mock.PostJSONWithHeadersFunc = func(url string, h map[string]string, body interface{}, timeout time.Duration) (int, []byte, error) {
+		headers = h
+		return 200, []byte(res.String()), nil
+	}
+	req := createRegisterRequest()
+	req.Tenant = "a"
+	_, e = api.Register(localhost, req)
+	c.Assert(e, check.IsNil)
+	c.Assert(headers, check.DeepEquals, map[string]string{
+		"Authorization": "Bearer token",
+		tenantHeader:    "a",
+	})
+}
+
 func (s *SupernodeAPITestSuite) TestSupernodeAPI_PullPieceTask(c *check.C) {
 	res := &types.PullPieceTaskResponse{BaseResponse: &types.BaseResponse{}}
 	res.Code = constants.CodePeerFinish
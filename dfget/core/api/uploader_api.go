@@ -17,6 +17,10 @@
 package api
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -40,6 +44,18 @@ type UploaderAPI interface {
 
 	// PingServer send a request to determine whether the server has started.
 	PingServer(ip string, port int) bool
+
+	// Preheat asks the peer server to download a file in the background.
+	// The request is signed with the secret, which is the auth token shared
+	// by supernode and the peer.
+	Preheat(ip string, port int, req *PreheatRequest, secret string) error
+
+	// GetPreheat gets the status of a preheat job from the peer server.
+	GetPreheat(ip string, port int, id string) (*PreheatResponse, error)
+
+	// VerifyPiece asks the peer server to verify a piece of its local file,
+	// and the file will be deleted if the piece is corrupted.
+	VerifyPiece(ip string, port int, req *VerifyPieceRequest) (*VerifyPieceResponse, error)
 }
 
 // uploaderAPI is an implementation of interface UploaderAPI.
@@ -97,3 +113,67 @@ func (u *uploaderAPI) PingServer(ip string, port int) bool {
 	code, _, _ := httputils.Get(url, u.timeout)
 	return code == http.StatusOK
 }
+
+func (u *uploaderAPI) Preheat(ip string, port int, req *PreheatRequest, secret string) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now().Unix()
+	headers := map[string]string{
+		config.StrPreheatTimestamp: strconv.FormatInt(timestamp, 10),
+		config.StrPreheatSignature: SignPreheat(secret, timestamp, data),
+	}
+
+	url := fmt.Sprintf("http://%s:%d%s", ip, port, config.PeerHTTPPathPreheat)
+	code, body, err := httputils.PostJSONWithHeaders(url, headers, json.RawMessage(data), u.timeout)
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK {
+		return fmt.Errorf("%d:%s", code, body)
+	}
+	return nil
+}
+
+func (u *uploaderAPI) GetPreheat(ip string, port int, id string) (*PreheatResponse, error) {
+	url := fmt.Sprintf("http://%s:%d%s/%s", ip, port, config.PeerHTTPPathPreheat, id)
+	code, body, err := httputils.Get(url, u.timeout)
+	if err != nil {
+		return nil, err
+	}
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("%d:%s", code, body)
+	}
+	resp := new(PreheatResponse)
+	if err := json.Unmarshal(body, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (u *uploaderAPI) VerifyPiece(ip string, port int, req *VerifyPieceRequest) (*VerifyPieceResponse, error) {
+	url := fmt.Sprintf("http://%s:%d%s", ip, port, config.PeerHTTPPathVerify)
+	code, body, err := httputils.PostJSON(url, req, u.timeout)
+	if err != nil {
+		return nil, err
+	}
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("%d:%s", code, body)
+	}
+	resp := new(VerifyPieceResponse)
+	if err := json.Unmarshal(body, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SignPreheat returns the signature of the body of a preheat request sent at
+// timestamp in seconds, which is the hex-encoded HMAC-SHA256 with the secret.
+func SignPreheat(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
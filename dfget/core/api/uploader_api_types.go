@@ -39,3 +39,47 @@ type FinishTaskRequest struct {
 	ClientID     string `request:"cid"`
 	Node         string `request:"superNode"`
 }
+
+// PreheatRequest wraps the request which is sent to uploader by supernode
+// in order to download a file into the host in the background.
+type PreheatRequest struct {
+	// ID identifies the preheat job on the uploader, it's generated by supernode.
+	ID         string   `json:"id"`
+	URL        string   `json:"url"`
+	Headers    []string `json:"headers,omitempty"`
+	Identifier string   `json:"identifier,omitempty"`
+	Filter     string   `json:"filter,omitempty"`
+	// Supernode is the address(host:port) of the supernode which the file is downloaded from.
+	// It's only a hint, the uploader downloads the file from the configured supernode
+	// which sends the request.
+	Supernode string `json:"supernode"`
+}
+
+// PreheatResponse is the status of a preheat job on the uploader.
+type PreheatResponse struct {
+	ID string `json:"id"`
+	// Status is one of RUNNING, SUCCESS and FAILED.
+	Status   string `json:"status"`
+	ErrorMsg string `json:"errorMsg,omitempty"`
+}
+
+// VerifyPieceRequest wraps the request which is sent to uploader by supernode
+// in order to verify a piece reported as corrupted by the other peers.
+type VerifyPieceRequest struct {
+	TaskFileName string `json:"taskFileName"`
+	// PieceRange is the range of the piece in the same format as downloading it.
+	PieceRange string `json:"pieceRange"`
+	PieceNum   int    `json:"pieceNum"`
+	PieceSize  int32  `json:"pieceSize"`
+	CDNSource  string `json:"cdnSource,omitempty"`
+	// PieceMd5 is the expected MD5 of the piece served by the uploader.
+	PieceMd5 string `json:"pieceMd5"`
+}
+
+// VerifyPieceResponse is the result of verifying a piece on the uploader.
+type VerifyPieceResponse struct {
+	// Corrupted indicates that the local copy doesn't match the expected MD5,
+	// and it has been deleted by the uploader.
+	Corrupted bool   `json:"corrupted"`
+	RealMd5   string `json:"realMd5,omitempty"`
+}
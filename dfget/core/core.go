@@ -18,7 +18,9 @@
 package core
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/rand"
 	"os"
@@ -32,6 +34,7 @@ import (
 	"github.com/dragonflyoss/Dragonfly/dfget/core/downloader"
 	backDown "github.com/dragonflyoss/Dragonfly/dfget/core/downloader/back_downloader"
 	p2pDown "github.com/dragonflyoss/Dragonfly/dfget/core/downloader/p2p_downloader"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/progress"
 	"github.com/dragonflyoss/Dragonfly/dfget/core/regist"
 	"github.com/dragonflyoss/Dragonfly/dfget/core/uploader"
 	"github.com/dragonflyoss/Dragonfly/dfget/locator"
@@ -43,15 +46,32 @@ import (
 	"github.com/dragonflyoss/Dragonfly/pkg/netutils"
 	"github.com/dragonflyoss/Dragonfly/pkg/printer"
 	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
+	"github.com/dragonflyoss/Dragonfly/pkg/tracing"
 	"github.com/dragonflyoss/Dragonfly/version"
 
 	"github.com/sirupsen/logrus"
 )
 
+// streamOutput is the destination of the file when writing to stdout.
+var streamOutput io.Writer = os.Stdout
+
 // Start function creates a new task and starts it to download file.
-func Start(cfg *config.Config) *errortypes.DfError {
+func Start(cfg *config.Config) (dfErr *errortypes.DfError) {
+	// the trace context is passed by dfdaemon in the environment if any
+	ctx, span := tracing.StartSpan(tracing.ExtractEnv(context.Background()),
+		"dfget.Download", tracing.SpanKindInternal)
+	defer func() {
+		span.SetAttribute("dfget.pattern", cfg.Pattern)
+		span.SetAttribute("http.url", tracing.SanitizeURL(cfg.URL))
+		if dfErr != nil {
+			span.SetAttribute("dfget.code", dfErr.Code)
+			span.SetError(dfErr)
+		}
+		span.End()
+	}()
+
 	var (
-		supernodeAPI     = api.NewSupernodeAPI()
+		supernodeAPI     = api.NewTracedSupernodeAPI(ctx, cfg.AuthToken)
 		supernodeLocator = locator.CreateLocator(cfg)
 		register         = regist.NewSupernodeRegister(cfg, supernodeAPI, supernodeLocator)
 		err              error
@@ -69,7 +89,10 @@ func Start(cfg *config.Config) *errortypes.DfError {
 		return errortypes.New(config.CodeRegisterError, err.Error())
 	}
 
-	if err = downloadFile(cfg, supernodeAPI, supernodeLocator, register, result); err != nil {
+	if err = downloadFile(ctx, cfg, supernodeAPI, supernodeLocator, register, result); err != nil {
+		if e, ok := err.(*errortypes.DfError); ok {
+			return e
+		}
 		return errortypes.New(config.CodeDownloadError, err.Error())
 	}
 
@@ -86,13 +109,16 @@ func prepare(cfg *config.Config, locator locator.SupernodeLocator) (err error) {
 	rv := &cfg.RV
 
 	rv.RealTarget = cfg.Output
-	rv.TargetDir = filepath.Dir(rv.RealTarget)
-	if err = fileutils.CreateDirectory(rv.TargetDir); err != nil {
-		return err
-	}
+	// there is no target file when writing to stdout.
+	if !rv.StreamMode {
+		rv.TargetDir = filepath.Dir(rv.RealTarget)
+		if err = fileutils.CreateDirectory(rv.TargetDir); err != nil {
+			return err
+		}
 
-	if cfg.RV.TempTarget, err = createTempTargetFile(rv.TargetDir, cfg.Sign); err != nil {
-		return err
+		if cfg.RV.TempTarget, err = createTempTargetFile(rv.TargetDir, cfg.Sign); err != nil {
+			return err
+		}
 	}
 
 	if err = fileutils.CreateDirectory(filepath.Dir(rv.MetaPath)); err != nil {
@@ -111,6 +137,9 @@ func prepare(cfg *config.Config, locator locator.SupernodeLocator) (err error) {
 	}
 	rv.Cid = getCid(rv.LocalIP, cfg.Sign)
 	rv.TaskFileName = getTaskFileName(rv.RealTarget, cfg.Sign)
+	if rv.StreamMode {
+		rv.TaskFileName = getTaskFileName(config.StdoutTaskName, cfg.Sign)
+	}
 	rv.TaskURL = netutils.FilterURLParam(cfg.URL, cfg.Filter)
 	logrus.Info("runtimeVariable: " + cfg.RV.String())
 
@@ -160,15 +189,16 @@ func registerToSuperNode(cfg *config.Config, register regist.SupernodeRegister,
 	}
 	cfg.RV.FileLength = result.FileLength
 	printer.Printf("client:%s connected to node:%s", cfg.RV.LocalIP, result.Node)
+	progress.Registered(result.TaskID, result.Node, result.FileLength, result.PieceSize)
 	return result, nil
 }
 
-func downloadFile(cfg *config.Config, supernodeAPI api.SupernodeAPI, locator locator.SupernodeLocator,
+func downloadFile(ctx context.Context, cfg *config.Config, supernodeAPI api.SupernodeAPI, locator locator.SupernodeLocator,
 	register regist.SupernodeRegister, result *regist.RegisterResult) error {
 	timeout := calculateTimeout(cfg)
 
 	success := true
-	err := doDownload(cfg, supernodeAPI, register, result, timeout)
+	err := doDownload(ctx, cfg, supernodeAPI, register, result, timeout)
 	if err != nil {
 		success = false
 	} else if cfg.RV.FileLength < 0 && fileutils.IsRegularFile(cfg.RV.RealTarget) {
@@ -194,11 +224,16 @@ func downloadFile(cfg *config.Config, supernodeAPI api.SupernodeAPI, locator loc
 	return err
 }
 
-func doDownload(cfg *config.Config, supernodeAPI api.SupernodeAPI,
+func doDownload(ctx context.Context, cfg *config.Config, supernodeAPI api.SupernodeAPI,
 	register regist.SupernodeRegister, result *regist.RegisterResult, timeout time.Duration) error {
+	if cfg.RV.StreamMode {
+		return doStream(ctx, cfg, supernodeAPI, register, result, timeout)
+	}
+
 	var getter downloader.Downloader
 	isBackDownload := false
 	if cfg.BackSourceReason > 0 {
+		progress.BackSource(cfg.BackSourceReason, nil)
 		getter = backDown.NewBackDownloader(cfg, result)
 		isBackDownload = true
 	} else {
@@ -206,7 +241,7 @@ func doDownload(cfg *config.Config, supernodeAPI api.SupernodeAPI,
 		getter = p2pDown.NewP2PDownloader(cfg, supernodeAPI, register, result)
 	}
 
-	err := downloader.DoDownloadTimeout(getter, timeout)
+	err := downloadTimeout(ctx, getter, timeout, isBackDownload)
 	// report finished task to uploader regardless of the result of downloading from dragonfly
 	reportFinishedTask(cfg, getter)
 	if err == nil {
@@ -221,13 +256,109 @@ func doDownload(cfg *config.Config, supernodeAPI api.SupernodeAPI,
 	printer.Printf("failed to download by dragonfly: %v, and start try to download from source", err)
 
 	// try to download the file from the source directly
+	progress.BackSource(cfg.BackSourceReason, err)
 	getter = backDown.NewBackDownloader(cfg, result)
-	if err := downloader.DoDownloadTimeout(getter, timeout); err != nil {
+	if err := downloadTimeout(ctx, getter, timeout, true); err != nil {
 		return fmt.Errorf("failed to download file from source: %v", err)
 	}
 	return nil
 }
 
+// doStream downloads the file and writes it to stdout in order.
+// It falls back to download from the source only if nothing has been
+// written, because the data written to stdout can't be taken back.
+func doStream(ctx context.Context, cfg *config.Config, supernodeAPI api.SupernodeAPI,
+	register regist.SupernodeRegister, result *regist.RegisterResult, timeout time.Duration) error {
+	var getter downloader.Downloader
+	isBackDownload := false
+	if cfg.BackSourceReason > 0 {
+		progress.BackSource(cfg.BackSourceReason, nil)
+		getter = backDown.NewBackDownloader(cfg, result)
+		isBackDownload = true
+	} else {
+		printer.Printf("start download by dragonfly...")
+		getter = p2pDown.NewP2PDownloader(cfg, supernodeAPI, register, result)
+	}
+
+	written, err := streamTimeout(ctx, getter, timeout, isBackDownload)
+	// report finished task to uploader regardless of the result of downloading from dragonfly
+	reportFinishedTask(cfg, getter)
+	if err == nil {
+		setStreamLength(cfg, written)
+		return nil
+	}
+
+	if isBackDownload {
+		return streamError(written, err, "failed to download file from source")
+	}
+	if _, ok := err.(*downloader.StreamWriteError); ok || written > 0 {
+		return streamError(written, err, "failed to download by dragonfly")
+	}
+
+	logrus.Errorf("failed to download by dragonfly: %v, and start try to download from source", err)
+	printer.Printf("failed to download by dragonfly: %v, and start try to download from source", err)
+
+	// try to download the file from the source directly
+	progress.BackSource(cfg.BackSourceReason, err)
+	getter = backDown.NewBackDownloader(cfg, result)
+	if written, err = streamTimeout(ctx, getter, timeout, true); err != nil {
+		return streamError(written, err, "failed to download file from source")
+	}
+	setStreamLength(cfg, written)
+	return nil
+}
+
+// downloadTimeout calls downloader.DoDownloadTimeout in a span named
+// after the way the file is downloaded.
+func downloadTimeout(ctx context.Context, getter downloader.Downloader,
+	timeout time.Duration, backSource bool) error {
+	ctx, span := tracing.StartSpan(ctx, downloadSpanName(backSource), tracing.SpanKindInternal)
+	defer span.End()
+
+	err := downloader.DoDownloadTimeout(ctx, getter, timeout)
+	span.SetError(err)
+	return err
+}
+
+// streamTimeout calls downloader.DoStreamTimeout in a span named
+// after the way the file is downloaded.
+func streamTimeout(ctx context.Context, getter downloader.Downloader,
+	timeout time.Duration, backSource bool) (int64, error) {
+	ctx, span := tracing.StartSpan(ctx, downloadSpanName(backSource), tracing.SpanKindInternal)
+	defer span.End()
+
+	written, err := downloader.DoStreamTimeout(ctx, getter, timeout, streamOutput)
+	span.SetAttribute("bytes", written)
+	span.SetError(err)
+	return written, err
+}
+
+func downloadSpanName(backSource bool) string {
+	if backSource {
+		return "dfget.BackSource"
+	}
+	return "dfget.P2PDownload"
+}
+
+// streamError returns the error with the code which tells whether the stream
+// is cut off partway or it's failed to write the stream to stdout.
+func streamError(written int64, err error, msg string) error {
+	if _, ok := err.(*downloader.StreamWriteError); ok {
+		return errortypes.Newf(config.CodeStreamWriteError, "%s: %v", msg, err)
+	}
+	if written > 0 {
+		return errortypes.Newf(config.CodeStreamInterrupted,
+			"%s: stream is cut off after %d bytes written: %v", msg, written, err)
+	}
+	return fmt.Errorf("%s: %v", msg, err)
+}
+
+func setStreamLength(cfg *config.Config, written int64) {
+	if cfg.RV.FileLength < 0 {
+		cfg.RV.FileLength = written
+	}
+}
+
 func reportFinishedTask(cfg *config.Config, getter downloader.Downloader) {
 	if cfg.RV.PeerPort <= 0 {
 		return
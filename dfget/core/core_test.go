@@ -30,11 +30,13 @@ import (
 	"time"
 
 	"github.com/dragonflyoss/Dragonfly/dfget/config"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/downloader"
 	. "github.com/dragonflyoss/Dragonfly/dfget/core/helper"
 	"github.com/dragonflyoss/Dragonfly/dfget/core/regist"
 	"github.com/dragonflyoss/Dragonfly/dfget/core/uploader"
 	"github.com/dragonflyoss/Dragonfly/dfget/locator"
 	"github.com/dragonflyoss/Dragonfly/pkg/algorithm"
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
 
 	"github.com/go-check/check"
 	"github.com/valyala/fasthttp"
@@ -73,6 +75,28 @@ func (s *CoreTestSuite) TestPrepare(c *check.C) {
 	fmt.Printf("%s\nerror:%v", buf.String(), err)
 }
 
+func (s *CoreTestSuite) TestPrepareStream(c *check.C) {
+	cfg := s.createConfig(&bytes.Buffer{})
+	cfg.Output = config.StdoutOutput
+	cfg.RV.StreamMode = true
+
+	c.Assert(prepare(cfg, nil), check.IsNil)
+	c.Assert(cfg.RV.TempTarget, check.Equals, "")
+	c.Assert(cfg.RV.TaskFileName, check.Equals, config.StdoutTaskName+"-"+cfg.Sign)
+}
+
+func (s *CoreTestSuite) TestStreamError(c *check.C) {
+	err := streamError(0, fmt.Errorf("timeout"), "failed")
+	_, ok := err.(*errortypes.DfError)
+	c.Assert(ok, check.Equals, false)
+
+	err = streamError(10, fmt.Errorf("timeout"), "failed")
+	c.Assert(err.(*errortypes.DfError).Code, check.Equals, config.CodeStreamInterrupted)
+
+	err = streamError(10, &downloader.StreamWriteError{Err: fmt.Errorf("broken pipe")}, "failed")
+	c.Assert(err.(*errortypes.DfError).Code, check.Equals, config.CodeStreamWriteError)
+}
+
 func (s *CoreTestSuite) TestRegisterToSupernode(c *check.C) {
 	cfg := s.createConfig(&bytes.Buffer{})
 	m := new(MockSupernodeAPI)
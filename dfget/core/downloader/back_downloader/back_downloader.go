@@ -27,6 +27,7 @@ import (
 
 	"github.com/dragonflyoss/Dragonfly/dfget/config"
 	"github.com/dragonflyoss/Dragonfly/dfget/core/downloader"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/progress"
 	"github.com/dragonflyoss/Dragonfly/dfget/core/regist"
 	"github.com/dragonflyoss/Dragonfly/pkg/fileutils"
 	"github.com/dragonflyoss/Dragonfly/pkg/httputils"
@@ -115,7 +116,9 @@ func (bd *BackDownloader) Run(ctx context.Context) error {
 
 	buf := make([]byte, 512*1024)
 	reader := limitreader.NewLimitReader(resp.Body, int64(bd.cfg.LocalLimit), bd.Md5 != "")
-	if _, err = io.CopyBuffer(f, reader, buf); err != nil {
+	n, err := io.CopyBuffer(f, reader, buf)
+	progress.SourceDone(n)
+	if err != nil {
 		return err
 	}
 
@@ -175,12 +178,16 @@ type autoCloseLimitReader struct {
 	closer      io.Closer
 	md5         string
 	limitReader *limitreader.LimitReader
+	read        int64
 }
 
 func (a *autoCloseLimitReader) Read(p []byte) (n int, err error) {
 	n, err = a.limitReader.Read(p)
+	a.read += int64(n)
 	// when return err, always close
 	if err != nil {
+		progress.SourceDone(a.read)
+		a.read = 0
 		if closeError := a.closer.Close(); closeError != nil {
 			err = errors.Wrapf(err, "close error: %s", closeError)
 		}
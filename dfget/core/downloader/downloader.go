@@ -25,6 +25,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/dragonflyoss/Dragonfly/dfget/config"
@@ -43,13 +44,13 @@ type Downloader interface {
 
 // DoDownloadTimeout downloads the file and waits for response during
 // the given timeout duration.
-func DoDownloadTimeout(downloader Downloader, timeout time.Duration) error {
+func DoDownloadTimeout(ctx context.Context, downloader Downloader, timeout time.Duration) error {
 	if timeout <= 0 {
 		logrus.Warnf("invalid download timeout(%.3fs), use default:(%.3fs)",
 			timeout.Seconds(), config.DefaultDownloadTimeout.Seconds())
 		timeout = config.DefaultDownloadTimeout
 	}
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 
 	var ch = make(chan error)
 	go func() {
@@ -68,6 +69,85 @@ func DoDownloadTimeout(downloader Downloader, timeout time.Duration) error {
 	return err
 }
 
+// StreamWriteError represents the failure of writing the stream to
+// the destination, e.g. the reading end of the pipe has been closed.
+type StreamWriteError struct {
+	Err error
+}
+
+func (e *StreamWriteError) Error() string {
+	return fmt.Sprintf("failed to write stream: %v", e.Err)
+}
+
+// DoStreamTimeout downloads the file in stream mode and copies the data to w
+// in order during the given timeout duration.
+// It returns the number of bytes written to w even if it fails, so that the
+// caller knows whether the stream has been cut off partway.
+func DoStreamTimeout(ctx context.Context, downloader Downloader, timeout time.Duration, w io.Writer) (int64, error) {
+	if timeout <= 0 {
+		logrus.Warnf("invalid download timeout(%.3fs), use default:(%.3fs)",
+			timeout.Seconds(), config.DefaultDownloadTimeout.Seconds())
+		timeout = config.DefaultDownloadTimeout
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sw := &streamWriter{w: w}
+	var ch = make(chan error, 1)
+	go func() {
+		reader, err := downloader.RunStream(ctx)
+		if err == nil {
+			_, err = io.Copy(sw, reader)
+		}
+		if sw.err != nil {
+			err = &StreamWriteError{Err: sw.err}
+		}
+		ch <- err
+	}()
+
+	select {
+	case err := <-ch:
+		return sw.written, err
+	case <-time.After(timeout):
+		downloader.Cleanup()
+	}
+	// the stream may be still copied in background, close the writer
+	// to make sure nothing will be written to w after returning.
+	return sw.close(), fmt.Errorf("download timeout(%.3fs)", timeout.Seconds())
+}
+
+// streamWriter counts the bytes written to w and refuses
+// to write anymore after it's closed.
+type streamWriter struct {
+	sync.Mutex
+	w       io.Writer
+	written int64
+	err     error
+	closed  bool
+}
+
+func (sw *streamWriter) Write(p []byte) (int, error) {
+	sw.Lock()
+	defer sw.Unlock()
+	if sw.closed {
+		return 0, io.ErrClosedPipe
+	}
+	n, err := sw.w.Write(p)
+	sw.written += int64(n)
+	if err != nil {
+		sw.err = err
+	}
+	return n, err
+}
+
+// close closes the writer and returns the number of bytes written.
+func (sw *streamWriter) close() int64 {
+	sw.Lock()
+	defer sw.Unlock()
+	sw.closed = true
+	return sw.written
+}
+
 // MoveFile moves a file from src to dst and
 // checks if the MD5 code is expected before that.
 func MoveFile(src string, dst string, expectMd5 string) error {
@@ -17,11 +17,13 @@
 package downloader
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -44,16 +46,47 @@ func init() {
 func (s *DownloaderTestSuite) TestDoDownloadTimeout(c *check.C) {
 	md := &MockDownloader{100}
 
-	err := DoDownloadTimeout(md, 0*time.Millisecond)
+	err := DoDownloadTimeout(context.Background(), md, 0*time.Millisecond)
 	c.Assert(err, check.IsNil)
 
-	err = DoDownloadTimeout(md, 50*time.Millisecond)
+	err = DoDownloadTimeout(context.Background(), md, 50*time.Millisecond)
 	c.Assert(err, check.NotNil)
 
-	err = DoDownloadTimeout(md, 110*time.Millisecond)
+	err = DoDownloadTimeout(context.Background(), md, 110*time.Millisecond)
 	c.Assert(err, check.IsNil)
 }
 
+func (s *DownloaderTestSuite) TestDoStreamTimeout(c *check.C) {
+	buf := &bytes.Buffer{}
+	n, err := DoStreamTimeout(context.Background(), &MockStreamDownloader{content: "hello"}, time.Second, buf)
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, int64(5))
+	c.Assert(buf.String(), check.Equals, "hello")
+
+	// the stream is cut off partway
+	buf.Reset()
+	n, err = DoStreamTimeout(context.Background(), &MockStreamDownloader{content: "hello", err: io.ErrUnexpectedEOF}, time.Second, buf)
+	c.Assert(err, check.Equals, io.ErrUnexpectedEOF)
+	c.Assert(n, check.Equals, int64(5))
+
+	// failed to write the stream
+	pr, pw := io.Pipe()
+	pr.Close()
+	n, err = DoStreamTimeout(context.Background(), &MockStreamDownloader{content: "hello"}, time.Second, pw)
+	_, ok := err.(*StreamWriteError)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(n, check.Equals, int64(0))
+
+	// nothing is written after timeout
+	buf.Reset()
+	md := &MockStreamDownloader{content: "hello", delay: 100 * time.Millisecond}
+	n, err = DoStreamTimeout(context.Background(), md, 50*time.Millisecond, buf)
+	c.Assert(err, check.NotNil)
+	c.Assert(n, check.Equals, int64(0))
+	time.Sleep(100 * time.Millisecond)
+	c.Assert(buf.Len(), check.Equals, 0)
+}
+
 func (s *DownloaderTestSuite) TestMoveFile(c *check.C) {
 	tmp, _ := ioutil.TempDir("/tmp", "dfget-TestMoveFile-")
 	defer os.RemoveAll(tmp)
@@ -96,3 +129,27 @@ func (md *MockDownloader) RunStream(ctx context.Context) (io.Reader, error) {
 
 func (md *MockDownloader) Cleanup() {
 }
+
+type MockStreamDownloader struct {
+	MockDownloader
+	content string
+	delay   time.Duration
+	err     error
+}
+
+func (md *MockStreamDownloader) RunStream(ctx context.Context) (io.Reader, error) {
+	time.Sleep(md.delay)
+	reader := io.Reader(strings.NewReader(md.content))
+	if md.err != nil {
+		reader = io.MultiReader(reader, &errReader{md.err})
+	}
+	return reader, nil
+}
+
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
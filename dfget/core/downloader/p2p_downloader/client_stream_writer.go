@@ -20,12 +20,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"time"
 
 	apiTypes "github.com/dragonflyoss/Dragonfly/apis/types"
 	"github.com/dragonflyoss/Dragonfly/dfget/config"
 	"github.com/dragonflyoss/Dragonfly/dfget/core/api"
 	"github.com/dragonflyoss/Dragonfly/dfget/core/helper"
+	"github.com/dragonflyoss/Dragonfly/pkg/fileutils"
 	"github.com/dragonflyoss/Dragonfly/pkg/limitreader"
 	"github.com/dragonflyoss/Dragonfly/pkg/queue"
 
@@ -54,6 +56,13 @@ type ClientStreamWriter struct {
 	// p2pPattern records whether the pattern equals "p2p".
 	p2pPattern bool
 
+	// serviceFilePath is the full path of the temp service file which
+	// always ends with ".service". The pieces are also written into it in
+	// p2p pattern, so that other peers can download them from this peer.
+	serviceFilePath string
+	// serviceFile holds a file object for the serviceFilePath.
+	serviceFile *os.File
+
 	// pipeWriter is the writer half of a pipe, all piece data will be wrote into pipeWriter
 	pipeWriter *io.PipeWriter
 
@@ -63,7 +72,8 @@ type ClientStreamWriter struct {
 	// limitReader supports limit rate and calculates md5
 	limitReader *limitreader.LimitReader
 
-	cache map[int]*Piece
+	// buffer holds the pieces which arrive earlier than their turn.
+	buffer *streamBuffer
 
 	// api holds an instance of SupernodeAPI to interact with supernode.
 	api api.SupernodeAPI
@@ -73,24 +83,35 @@ type ClientStreamWriter struct {
 }
 
 // NewClientStreamWriter creates and initialize a ClientStreamWriter instance.
-func NewClientStreamWriter(clientQueue, notifyQueue queue.Queue, api api.SupernodeAPI, cfg *config.Config) *ClientStreamWriter {
+func NewClientStreamWriter(serviceFilePath string, clientQueue, notifyQueue queue.Queue,
+	api api.SupernodeAPI, cfg *config.Config, cdnSource apiTypes.CdnSource) *ClientStreamWriter {
 	pr, pw := io.Pipe()
 	limitReader := limitreader.NewLimitReader(pr, int64(cfg.LocalLimit), cfg.Md5 != "")
 	clientWriter := &ClientStreamWriter{
-		clientQueue: clientQueue,
-		notifyQueue: notifyQueue,
-		pipeReader:  pr,
-		pipeWriter:  pw,
-		limitReader: limitReader,
-		api:         api,
-		cfg:         cfg,
-		cache:       make(map[int]*Piece),
+		clientQueue:     clientQueue,
+		notifyQueue:     notifyQueue,
+		serviceFilePath: serviceFilePath,
+		pipeReader:      pr,
+		pipeWriter:      pw,
+		limitReader:     limitReader,
+		api:             api,
+		cfg:             cfg,
+		buffer: newStreamBuffer(int64(cfg.StreamBufferSize), cfg.RV.DataDir,
+			cdnSource == apiTypes.CdnSourceSource),
+		cdnSource: cdnSource,
 	}
 	return clientWriter
 }
 
 func (csw *ClientStreamWriter) PreRun(ctx context.Context) (err error) {
 	csw.p2pPattern = helper.IsP2P(csw.cfg.Pattern)
+	if csw.p2pPattern && csw.serviceFilePath != "" {
+		csw.serviceFile, err = fileutils.OpenFile(csw.serviceFilePath, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0755)
+		if err != nil {
+			return err
+		}
+		csw.buffer.serviceFile = csw.serviceFile
+	}
 	csw.result = true
 	csw.finish = make(chan struct{})
 	return
@@ -111,6 +132,7 @@ func (csw *ClientStreamWriter) Run(ctx context.Context) {
 		if ok && state == reset {
 			// stream could not reset, just return error
 			csw.pipeWriter.CloseWithError(fmt.Errorf("stream writer not support reset"))
+			csw.result = false
 			continue
 		}
 		if !csw.result {
@@ -128,10 +150,25 @@ func (csw *ClientStreamWriter) Run(ctx context.Context) {
 		}
 	}
 
-	csw.pipeWriter.Close()
+	csw.buffer.close()
+	if csw.serviceFile != nil {
+		csw.serviceFile.Sync()
+		csw.serviceFile.Close()
+	}
+	if csw.result {
+		csw.pipeWriter.Close()
+	} else {
+		csw.CloseWithError(fmt.Errorf("failed to write pieces, reason: %d", csw.cfg.BackSourceReason))
+	}
 	close(csw.finish)
 }
 
+// CloseWithError closes the stream, and the subsequent reads from the
+// stream will return the error instead of io.EOF.
+func (csw *ClientStreamWriter) CloseWithError(err error) {
+	csw.pipeWriter.CloseWithError(err)
+}
+
 // Wait for Run whether is finished.
 func (csw *ClientStreamWriter) Wait() {
 	if csw.finish != nil {
@@ -141,7 +178,13 @@ func (csw *ClientStreamWriter) Wait() {
 
 func (csw *ClientStreamWriter) write(piece *Piece) error {
 	startTime := time.Now()
-	// TODO csw.p2pPattern
+	if csw.serviceFile != nil {
+		// the piece will be written twice, to the service file and the pipe.
+		piece.IncWriter()
+		if err := writePieceToFile(piece, csw.serviceFile, csw.cdnSource); err != nil {
+			return err
+		}
+	}
 
 	err := csw.writePieceToPipe(piece)
 	if err == nil {
@@ -151,35 +194,33 @@ func (csw *ClientStreamWriter) write(piece *Piece) error {
 }
 
 func (csw *ClientStreamWriter) writePieceToPipe(p *Piece) error {
+	// must write piece by order
+	// when received PieceNum is greater then pieceIndex, buffer it
+	if p.PieceNum != csw.pieceIndex {
+		if p.PieceNum < csw.pieceIndex {
+			logrus.Warnf("piece number should be greater than %d, received piece number: %d",
+				csw.pieceIndex, p.PieceNum)
+			return nil
+		}
+		return csw.buffer.put(p)
+	}
+
+	if _, err := p.WriteTo(csw.pipeWriter, csw.cdnSource == apiTypes.CdnSourceSource); err != nil {
+		return err
+	}
+	csw.pieceIndex++
+
+	// next pieces may be already in buffer, check them
 	for {
-		// must write piece by order
-		// when received PieceNum is greater then pieceIndex, cache it
-		if p.PieceNum != csw.pieceIndex {
-			if p.PieceNum < csw.pieceIndex {
-				logrus.Warnf("piece number should be greater than %d, received piece number: %d",
-					csw.pieceIndex, p.PieceNum)
-				break
-			}
-			csw.cache[p.PieceNum] = p
+		next, ok := csw.buffer.take(csw.pieceIndex)
+		if !ok {
 			break
 		}
-
-		_, err := p.WriteTo(csw.pipeWriter, csw.cdnSource == apiTypes.CdnSourceSource)
-		if err != nil {
+		if err := csw.buffer.writeTo(next, csw.pipeWriter); err != nil {
 			return err
 		}
-
 		csw.pieceIndex++
-		// next piece may be already in cache, check it
-		next, ok := csw.cache[csw.pieceIndex]
-		if ok {
-			p = next
-			delete(csw.cache, csw.pieceIndex)
-			continue
-		}
-		break
 	}
-
 	return nil
 }
 
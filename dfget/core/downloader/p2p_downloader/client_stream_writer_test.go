@@ -17,11 +17,18 @@
 package downloader
 
 import (
+	"context"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"sort"
 
+	apiTypes "github.com/dragonflyoss/Dragonfly/apis/types"
 	"github.com/dragonflyoss/Dragonfly/dfget/config"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/helper"
 	"github.com/dragonflyoss/Dragonfly/pkg/pool"
+	"github.com/dragonflyoss/Dragonfly/pkg/queue"
 
 	"github.com/go-check/check"
 )
@@ -100,7 +107,7 @@ func (s *ClientStreamWriterTestSuite) TestWrite(c *check.C) {
 	copy(cases2, cases)
 
 	cfg := &config.Config{}
-	csw := NewClientStreamWriter(nil, nil, nil, cfg)
+	csw := NewClientStreamWriter("", nil, nil, nil, cfg, "")
 	go func() {
 		for _, v := range cases2 {
 			err := csw.writePieceToPipe(v.piece)
@@ -116,6 +123,48 @@ func (s *ClientStreamWriterTestSuite) TestWrite(c *check.C) {
 	}
 }
 
+func (s *ClientStreamWriterTestSuite) TestRunWithServiceFile(c *check.C) {
+	workHome, _ := ioutil.TempDir("/tmp", "dfget-ClientStreamWriterTestSuite-")
+	defer os.RemoveAll(workHome)
+	serviceFilePath := filepath.Join(workHome, "cswtest.service")
+
+	clientQueue := queue.NewQueue(0)
+	cfg := &config.Config{Pattern: config.PatternP2P}
+	csw := NewClientStreamWriter(serviceFilePath, clientQueue, nil,
+		&helper.MockSupernodeAPI{}, cfg, apiTypes.CdnSourceSupernode)
+	c.Assert(csw.PreRun(context.Background()), check.IsNil)
+	go csw.Run(context.Background())
+
+	clientQueue.Put(&Piece{PieceNum: 1, PieceSize: 6, Content: pool.NewBufferString("000020"), writerNum: 1})
+	clientQueue.Put(&Piece{PieceNum: 0, PieceSize: 6, Content: pool.NewBufferString("000010"), writerNum: 1})
+	clientQueue.Put(last)
+
+	content, err := ioutil.ReadAll(csw)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(content), check.Equals, "12")
+	csw.Wait()
+
+	content, err = ioutil.ReadFile(serviceFilePath)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(content), check.Equals, "12")
+}
+
+func (s *ClientStreamWriterTestSuite) TestRunWithReset(c *check.C) {
+	clientQueue := queue.NewQueue(0)
+	cfg := &config.Config{Pattern: config.PatternCDN}
+	csw := NewClientStreamWriter("", clientQueue, nil,
+		&helper.MockSupernodeAPI{}, cfg, apiTypes.CdnSourceSupernode)
+	c.Assert(csw.PreRun(context.Background()), check.IsNil)
+	go csw.Run(context.Background())
+
+	clientQueue.Put(reset)
+	clientQueue.Put(last)
+
+	_, err := ioutil.ReadAll(csw)
+	c.Assert(err, check.NotNil)
+	csw.Wait()
+}
+
 func (s *ClientStreamWriterTestSuite) getString(reader io.Reader, length int) string {
 	b := make([]byte, length)
 	reader.Read(b)
@@ -233,14 +233,10 @@ func (cw *ClientWriter) write(piece *Piece) error {
 }
 
 func writePieceToFile(piece *Piece, file *os.File, cdnSource apiTypes.CdnSource) error {
-	var pieceHeader = 5
 	// the piece is not wrapped with source cdn type
 	noWrapper := (cdnSource == apiTypes.CdnSourceSource)
-	if noWrapper {
-		pieceHeader = 0
-	}
 
-	start := int64(piece.PieceNum) * (int64(piece.PieceSize) - int64(pieceHeader))
+	start := pieceOffset(piece, noWrapper)
 	if _, err := file.Seek(start, 0); err != nil {
 		return err
 	}
@@ -252,6 +248,15 @@ func writePieceToFile(piece *Piece, file *os.File, cdnSource apiTypes.CdnSource)
 	return err
 }
 
+// pieceOffset returns the offset of the raw content of the piece in the file.
+func pieceOffset(piece *Piece, noWrapper bool) int64 {
+	var pieceHeader = 5
+	if noWrapper {
+		pieceHeader = 0
+	}
+	return int64(piece.PieceNum) * (int64(piece.PieceSize) - int64(pieceHeader))
+}
+
 func startSyncWriter(q queue.Queue) queue.Queue {
 	return nil
 }
@@ -0,0 +1,201 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package downloader
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/dfget/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/pool"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// latencySampleSize is the number of the latest piece latencies
+	// used to calculate the hedge delay.
+	latencySampleSize = 64
+	// minLatencySamples is the minimal number of samples required to calculate
+	// the percentile, downloadPieceTimeout is used as the hedge delay before that.
+	minLatencySamples = 8
+)
+
+// latencyTracker records the latency of the latest downloaded pieces and
+// decides how long to wait before sending a hedged request.
+type latencyTracker struct {
+	sync.Mutex
+	percentile int
+	samples    [latencySampleSize]time.Duration
+	count      int
+}
+
+func newLatencyTracker(percentile int) *latencyTracker {
+	return &latencyTracker{percentile: percentile}
+}
+
+func (lt *latencyTracker) add(latency time.Duration) {
+	lt.Lock()
+	defer lt.Unlock()
+	lt.samples[lt.count%latencySampleSize] = latency
+	lt.count++
+}
+
+// hedgeDelay returns the percentile of the latest piece latencies.
+func (lt *latencyTracker) hedgeDelay() time.Duration {
+	lt.Lock()
+	defer lt.Unlock()
+	n := lt.count
+	if n > latencySampleSize {
+		n = latencySampleSize
+	}
+	if n < minLatencySamples {
+		return downloadPieceTimeout
+	}
+
+	sorted := make([]time.Duration, n)
+	copy(sorted, lt.samples[:n])
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(n-1)*lt.percentile/100]
+}
+
+// attemptResult is the result of downloading a piece from one source.
+type attemptResult struct {
+	client  *PowerClient
+	content *pool.Buffer
+	err     error
+}
+
+// downloadHedged downloads the piece from the scheduled peer, and sends a
+// hedged request to the alternative peer if it doesn't complete in delay
+// or fails before that. The first successful response wins and the other
+// request is cancelled.
+// The result is reported against the peer which served the piece, and the
+// scheduled peer is reported as slow if the hedged request wins. Supernode
+// releases the load reserved for the scheduled peer with the result.
+func (pc *PowerClient) downloadHedged(delay time.Duration) (*pool.Buffer, error) {
+	var (
+		results  = make(chan *attemptResult, 2)
+		primary  = pc.fork(pc.pieceTask)
+		hedged   *PowerClient
+		failed   *attemptResult
+		pending  = 1
+		timer    = time.NewTimer(delay)
+		attempts = []*PowerClient{primary}
+	)
+	defer timer.Stop()
+	defer func() {
+		for _, c := range attempts {
+			c.cancel()
+		}
+	}()
+	go primary.attempt(results)
+
+	startHedged := func() {
+		alternate := pc.pieceTask.WithSource(pc.pieceTask.Alternates[0])
+		logrus.Infof("downloading range:%s from %s:%d doesn't complete in %v, send hedged request to %s:%d",
+			pc.pieceTask.Range, pc.pieceTask.PeerIP, pc.pieceTask.PeerPort, delay,
+			alternate.PeerIP, alternate.PeerPort)
+		hedged = pc.fork(alternate)
+		attempts = append(attempts, hedged)
+		pending++
+		go hedged.attempt(results)
+	}
+
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				pc.adopt(r.client)
+				if failed != nil {
+					// report the error of the failed one instead.
+					pc.clientError = failed.client.clientError
+				} else if r.client != primary {
+					pc.initPieceSlowError(primary)
+				}
+				for _, c := range attempts {
+					if c == r.client {
+						continue
+					}
+					c.cancel()
+					if c == primary && failed == nil {
+						logrus.Infof("hedged request for range:%s to %s:%d wins, and the peer %s:%d is slow",
+							pc.pieceTask.Range, r.client.pieceTask.PeerIP, r.client.pieceTask.PeerPort,
+							primary.pieceTask.PeerIP, primary.pieceTask.PeerPort)
+					}
+				}
+				return r.content, nil
+			}
+			if failed == nil || r.client == primary {
+				failed = r
+			}
+			if hedged == nil {
+				// the scheduled peer fails before the delay,
+				// and there is no need to wait any longer.
+				timer.Stop()
+				startHedged()
+			}
+		case <-timer.C:
+			if hedged == nil {
+				startHedged()
+			}
+		}
+	}
+
+	pc.adopt(failed.client)
+	pc.pieceTask = primary.pieceTask
+	return nil, failed.err
+}
+
+// attempt downloads the piece and sends the result to results.
+func (pc *PowerClient) attempt(results chan<- *attemptResult) {
+	content, err := pc.downloadWithRateControl()
+	select {
+	case <-pc.ctx.Done():
+		if content != nil {
+			pool.ReleaseBuffer(content)
+			content = nil
+		}
+	default:
+	}
+	results <- &attemptResult{client: pc, content: content, err: err}
+}
+
+// fork returns a copy of the PowerClient which downloads the piece
+// with the given pieceTask and has its own downloading states.
+func (pc *PowerClient) fork(pieceTask *types.PullPieceTaskResponseContinueData) *PowerClient {
+	c := *pc
+	c.total = 0
+	c.readCost = 0
+	c.rtt = 0
+	c.clientError = nil
+	c.ctx, c.cancel = context.WithCancel(pc.ctx)
+	c.pieceTask = pieceTask
+	return &c
+}
+
+// adopt copies the downloading states of the attempt which decides the result.
+func (pc *PowerClient) adopt(c *PowerClient) {
+	pc.pieceTask = c.pieceTask
+	pc.total = c.total
+	pc.readCost = c.readCost
+	pc.rtt = c.rtt
+	pc.clientError = c.clientError
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/dfget/config"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/api"
+	"github.com/dragonflyoss/Dragonfly/dfget/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/constants"
+	"github.com/dragonflyoss/Dragonfly/pkg/ratelimiter"
+
+	"github.com/go-check/check"
+)
+
+type HedgeTestSuite struct {
+}
+
+func init() {
+	check.Suite(&HedgeTestSuite{})
+}
+
+func (s *HedgeTestSuite) TestLatencyTracker(c *check.C) {
+	lt := newLatencyTracker(90)
+	c.Assert(lt.hedgeDelay(), check.Equals, downloadPieceTimeout)
+
+	for i := 1; i <= 10; i++ {
+		lt.add(time.Duration(i) * time.Millisecond)
+	}
+	c.Assert(lt.hedgeDelay(), check.Equals, 9*time.Millisecond)
+
+	// only the latest samples are taken into account
+	for i := 0; i < latencySampleSize; i++ {
+		lt.add(time.Second)
+	}
+	c.Assert(lt.hedgeDelay(), check.Equals, time.Second)
+}
+
+func (s *HedgeTestSuite) TestDownloadHedgedWins(c *check.C) {
+	pc := newHedgeTestClient(map[string]time.Duration{
+		"primary":   time.Second,
+		"alternate": 0,
+	})
+
+	content, err := pc.downloadHedged(10 * time.Millisecond)
+	c.Assert(err, check.IsNil)
+	c.Assert(content.String(), check.Equals, "alternate")
+	// the result is reported against the winner, and the scheduled peer as slow
+	c.Assert(pc.pieceTask.Cid, check.Equals, "alternate-cid")
+	c.Assert(pc.pieceTask.Path, check.Equals, "alternate")
+	c.Assert(pc.ClientError(), check.NotNil)
+	c.Assert(pc.ClientError().ErrorType, check.Equals, constants.ClientErrorPieceSlow)
+	c.Assert(pc.ClientError().DstCid, check.Equals, "primary-cid")
+}
+
+func (s *HedgeTestSuite) TestDownloadHedgedLoserCancelled(c *check.C) {
+	pc := newHedgeTestClient(map[string]time.Duration{
+		"primary":   200 * time.Millisecond,
+		"alternate": 0,
+	})
+	pc.rateCtl = NewRateController(0, 0)
+	source := fmt.Sprintf("127.0.0.1:%d", port)
+
+	content, err := pc.downloadHedged(10 * time.Millisecond)
+	c.Assert(err, check.IsNil)
+	c.Assert(content.String(), check.Equals, "alternate")
+
+	// the cancelled request releases its slot without shrinking the window
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		pc.rateCtl.Lock()
+		inflight := pc.rateCtl.getSource(source).inflight
+		pc.rateCtl.Unlock()
+		if inflight == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(pc.rateCtl.Window(source) >= initPeerWindow, check.Equals, true)
+}
+
+func (s *HedgeTestSuite) TestDownloadHedgedPrimaryFailsEarly(c *check.C) {
+	pc := newHedgeTestClient(map[string]time.Duration{
+		"alternate": 0,
+	})
+
+	start := time.Now()
+	content, err := pc.downloadHedged(10 * time.Second)
+	c.Assert(err, check.IsNil)
+	c.Assert(time.Since(start) < time.Second, check.Equals, true)
+	c.Assert(content.String(), check.Equals, "alternate")
+	c.Assert(pc.pieceTask.Cid, check.Equals, "alternate-cid")
+	// the scheduled peer failed rather than being slow
+	if pc.ClientError() != nil {
+		c.Assert(pc.ClientError().ErrorType, check.Not(check.Equals), constants.ClientErrorPieceSlow)
+	}
+}
+
+func (s *HedgeTestSuite) TestDownloadHedgedPrimaryWins(c *check.C) {
+	pc := newHedgeTestClient(map[string]time.Duration{
+		"primary":   0,
+		"alternate": 0,
+	})
+
+	content, err := pc.downloadHedged(time.Second)
+	c.Assert(err, check.IsNil)
+	c.Assert(content.String(), check.Equals, "primary")
+	c.Assert(pc.pieceTask.Cid, check.Equals, "primary-cid")
+	c.Assert(pc.ClientError(), check.IsNil)
+}
+
+func (s *HedgeTestSuite) TestDownloadHedgedBothFail(c *check.C) {
+	pc := newHedgeTestClient(map[string]time.Duration{})
+
+	content, err := pc.downloadHedged(0)
+	c.Assert(content, check.IsNil)
+	c.Assert(err, check.NotNil)
+	c.Assert(pc.pieceTask.Cid, check.Equals, "primary-cid")
+}
+
+func newHedgeTestClient(delays map[string]time.Duration) *PowerClient {
+	return &PowerClient{
+		ctx:         context.Background(),
+		cfg:         &config.Config{},
+		node:        "127.0.0.1",
+		rateLimiter: ratelimiter.NewRateLimiter(0, 2),
+		downloadAPI: &hedgeMockAPI{delays: delays},
+		pieceTask: &types.PullPieceTaskResponseContinueData{
+			Range:    "0-9",
+			Cid:      "primary-cid",
+			PeerIP:   "127.0.0.1",
+			PeerPort: port,
+			Path:     "primary",
+			Alternates: []*types.PieceSource{
+				{Cid: "alternate-cid", PeerIP: "127.0.0.1", PeerPort: port, Path: "alternate"},
+			},
+		},
+	}
+}
+
+// hedgeMockAPI responds the path as the content after the delay of the path,
+// and fails if the delay of the path isn't specified.
+type hedgeMockAPI struct {
+	delays map[string]time.Duration
+}
+
+func (h *hedgeMockAPI) Download(ip string, port int, req *api.DownloadRequest, timeout time.Duration) (*http.Response, error) {
+	delay, ok := h.delays[req.Path]
+	if !ok {
+		return nil, fmt.Errorf("connection refused")
+	}
+	time.Sleep(delay)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte(req.Path))),
+	}, nil
+}
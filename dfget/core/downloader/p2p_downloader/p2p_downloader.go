@@ -106,6 +106,12 @@ type P2PDownloader struct {
 
 	// rateLimiter limits the download speed.
 	rateLimiter *ratelimiter.RateLimiter
+	// rateController adapts the download concurrency of every source
+	// and the rate of rateLimiter, it's nil if adaptive rate is disabled.
+	rateController *RateController
+	// latency records the latency of the downloaded pieces,
+	// it's nil if the hedged request is disabled.
+	latency *latencyTracker
 	// pullRateTime the time when the pull rate API is called to
 	// control the time interval between two calls to the API.
 	pullRateTime time.Time
@@ -160,6 +166,12 @@ func (p2p *P2PDownloader) init() {
 	p2p.pieceSet = make(map[string]bool)
 
 	p2p.rateLimiter = ratelimiter.NewRateLimiter(int64(p2p.cfg.LocalLimit), 2)
+	if p2p.cfg.AdaptiveRate {
+		p2p.rateController = NewRateController(int64(p2p.cfg.MinRate), int64(p2p.cfg.LocalLimit))
+	}
+	if p2p.cfg.HedgePercentile > 0 {
+		p2p.latency = newLatencyTracker(p2p.cfg.HedgePercentile)
+	}
 	p2p.pullRateTime = time.Now().Add(-3 * time.Second)
 }
 
@@ -179,11 +191,14 @@ func (p2p *P2PDownloader) RunStream(ctx context.Context) (io.Reader, error) {
 	if !p2p.streamMode {
 		return nil, fmt.Errorf("streamMode disable, should be enabled")
 	}
-	clientStreamWriter := NewClientStreamWriter(p2p.clientQueue, p2p.notifyQueue, p2p.API, p2p.cfg)
+	clientStreamWriter := NewClientStreamWriter(p2p.serviceFilePath, p2p.clientQueue, p2p.notifyQueue,
+		p2p.API, p2p.cfg, p2p.RegisterResult.CDNSource)
 	go func() {
 		err := p2p.run(ctx, clientStreamWriter)
 		if err != nil {
 			logrus.Warnf("P2PDownloader run error: %s", err)
+			// make the reader know the stream is cut off.
+			clientStreamWriter.CloseWithError(err)
 		}
 	}()
 	return clientStreamWriter, nil
@@ -223,7 +238,7 @@ func (p2p *P2PDownloader) run(ctx context.Context, pieceWriter PieceWriter) erro
 		} else {
 			code := response.Code
 			if code == constants.CodePeerContinue {
-				p2p.processPiece(response, &curItem)
+				p2p.processPiece(ctx, response, &curItem)
 			} else if code == constants.CodePeerFinish {
 				if p2p.cfg.Md5 == "" {
 					p2p.cfg.Md5 = response.FinishData().Md5
@@ -358,22 +373,36 @@ func (p2p *P2PDownloader) getPullRate(data *types.PullPieceTaskResponseContinueD
 	resp, err := uploaderAPI.ParseRate(p2p.cfg.RV.LocalIP, p2p.cfg.RV.PeerPort, req)
 	if err != nil {
 		logrus.Errorf("failed to parse rate in pull rate: %v", err)
-		p2p.rateLimiter.SetRate(ratelimiter.TransRate(int64(localRate)))
+		p2p.setRate(localRate)
 		return
 	}
 
 	reqRate, err := strconv.Atoi(resp)
 	if err != nil {
 		logrus.Errorf("failed to parse rate from resp %s: %v", resp, err)
-		p2p.rateLimiter.SetRate(ratelimiter.TransRate(int64(localRate)))
+		p2p.setRate(localRate)
 		return
 	}
 	logrus.Infof("pull rate result:%d cost:%v", reqRate, time.Since(start))
-	p2p.rateLimiter.SetRate(ratelimiter.TransRate(int64(reqRate)))
+	p2p.setRate(reqRate)
+}
+
+// setRate sets the rate of rateLimiter. If the adaptive rate is enabled,
+// the given rate is treated as the upper bound and the actual rate is
+// decided by the rateController.
+func (p2p *P2PDownloader) setRate(rate int) {
+	if p2p.rateController != nil {
+		p2p.rateController.SetMaxRate(int64(rate))
+		adaptive := p2p.rateController.Rate()
+		logrus.Debugf("adaptive rate:%d max rate:%d", adaptive, rate)
+		rate = int(adaptive)
+	}
+	p2p.rateLimiter.SetRate(ratelimiter.TransRate(int64(rate)))
 }
 
-func (p2p *P2PDownloader) startTask(data *types.PullPieceTaskResponseContinueData) {
+func (p2p *P2PDownloader) startTask(ctx context.Context, data *types.PullPieceTaskResponseContinueData) {
 	powerClient := &PowerClient{
+		ctx:         ctx,
 		taskID:      p2p.taskID,
 		node:        p2p.node,
 		pieceTask:   data,
@@ -381,12 +410,17 @@ func (p2p *P2PDownloader) startTask(data *types.PullPieceTaskResponseContinueDat
 		queue:       p2p.queue,
 		clientQueue: p2p.clientQueue,
 		rateLimiter: p2p.rateLimiter,
+		rateCtl:     p2p.rateController,
+		latency:     p2p.latency,
 		downloadAPI: api.NewDownloadAPI(),
 		headers:     p2p.headers,
 		cdnSource:   p2p.RegisterResult.CDNSource,
 		fileLength:  p2p.RegisterResult.FileLength,
 	}
-	if err := powerClient.Run(); err != nil && powerClient.ClientError() != nil {
+	// the client error may also be reported when succeeded, e.g. a hedged
+	// request wins and the scheduled peer is reported as slow.
+	powerClient.Run()
+	if powerClient.ClientError() != nil {
 		p2p.API.ReportClientError(p2p.node, powerClient.ClientError())
 	}
 }
@@ -444,7 +478,7 @@ func (p2p *P2PDownloader) getItem(latestItem *Piece) (bool, *Piece) {
 	return true, latestItem
 }
 
-func (p2p *P2PDownloader) processPiece(response *types.PullPieceTaskResponse,
+func (p2p *P2PDownloader) processPiece(ctx context.Context, response *types.PullPieceTaskResponse,
 	item *Piece) {
 	var (
 		hasTask         = false
@@ -467,7 +501,7 @@ func (p2p *P2PDownloader) processPiece(response *types.PullPieceTaskResponse,
 		if !ok {
 			p2p.pieceSet[pieceRange] = false
 			p2p.getPullRate(pieceTask)
-			go p2p.startTask(pieceTask)
+			go p2p.startTask(ctx, pieceTask)
 			hasTask = true
 		}
 	}
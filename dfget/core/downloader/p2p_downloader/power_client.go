@@ -18,15 +18,21 @@ package downloader
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
 	"strings"
+	"syscall"
 	"time"
 
 	apiTypes "github.com/dragonflyoss/Dragonfly/apis/types"
 	"github.com/dragonflyoss/Dragonfly/dfget/config"
 	"github.com/dragonflyoss/Dragonfly/dfget/core/api"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/progress"
 	"github.com/dragonflyoss/Dragonfly/dfget/types"
 	"github.com/dragonflyoss/Dragonfly/pkg/constants"
 	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
@@ -36,6 +42,7 @@ import (
 	"github.com/dragonflyoss/Dragonfly/pkg/pool"
 	"github.com/dragonflyoss/Dragonfly/pkg/queue"
 	"github.com/dragonflyoss/Dragonfly/pkg/ratelimiter"
+	"github.com/dragonflyoss/Dragonfly/pkg/tracing"
 
 	"github.com/sirupsen/logrus"
 )
@@ -48,6 +55,8 @@ const (
 
 // PowerClient downloads file from dragonfly.
 type PowerClient struct {
+	// ctx carries the trace context of the download.
+	ctx context.Context
 	// taskID is a string which represents a unique task.
 	taskID string
 	// headers is the extra HTTP headers when downloading a piece.
@@ -68,11 +77,23 @@ type PowerClient struct {
 
 	// rateLimiter limits the download speed.
 	rateLimiter *ratelimiter.RateLimiter
+	// rateCtl adapts the concurrency of downloading from the peer,
+	// it's nil if adaptive rate is disabled.
+	rateCtl *RateController
 
 	// total indicates the total length of the downloaded piece.
 	total int64
 	// readCost records how long it took to download the piece.
 	readCost time.Duration
+	// rtt records how long it took to receive the response header.
+	rtt time.Duration
+
+	// latency records the latency of the downloaded pieces to decide when to
+	// send a hedged request, it's nil if the hedged request is disabled.
+	latency *latencyTracker
+	// cancel cancels ctx to abort downloading when the hedged request loses,
+	// it's nil unless the PowerClient is forked for a hedged request.
+	cancel context.CancelFunc
 
 	// downloadAPI holds an instance of DownloadAPI.
 	downloadAPI api.DownloadAPI
@@ -87,7 +108,7 @@ type PowerClient struct {
 func (pc *PowerClient) Run() error {
 	startTime := time.Now()
 
-	content, err := pc.downloadPiece()
+	content, err := pc.download()
 
 	timeDuring := time.Since(startTime).Seconds()
 	logrus.Debugf("client range:%s cost:%.3f from peer:%s:%d, readCost:%.3f, length:%d",
@@ -103,24 +124,82 @@ func (pc *PowerClient) Run() error {
 		return err
 	}
 
+	pc.reportProgress(time.Since(startTime))
 	piece := pc.successPiece(content)
 	pc.clientQueue.Put(piece)
 	pc.queue.Put(piece)
 	return nil
 }
 
+// reportProgress reports the downloaded piece and where it came from.
+func (pc *PowerClient) reportProgress(cost time.Duration) {
+	source := progress.SourcePeer
+	if pc.pieceTask.PeerIP == "" || pc.pieceTask.PeerIP == pc.node {
+		source = progress.SourceSupernode
+	}
+	progress.PieceDone(pc.taskID, pc.pieceTask.Range, pc.pieceTask.PieceNum, source,
+		fmt.Sprintf("%s:%d", pc.pieceTask.PeerIP, pc.pieceTask.PeerPort), pc.total, cost)
+}
+
 // ClientError returns the client error if occurred
 func (pc *PowerClient) ClientError() *types.ClientErrorRequest {
 	return pc.clientError
 }
 
+// download downloads the piece, and sends hedged requests if it's enabled
+// and there are alternative peers for the piece.
+func (pc *PowerClient) download() (*pool.Buffer, error) {
+	if pc.latency == nil || len(pc.pieceTask.Alternates) == 0 {
+		return pc.downloadWithRateControl()
+	}
+	return pc.downloadHedged(pc.latency.hedgeDelay())
+}
+
+// downloadWithRateControl downloads the piece under the control of rateCtl
+// and records the latency if succeeded.
+func (pc *PowerClient) downloadWithRateControl() (*pool.Buffer, error) {
+	source := fmt.Sprintf("%s:%d", pc.pieceTask.PeerIP, pc.pieceTask.PeerPort)
+	if pc.rateCtl != nil {
+		if err := pc.rateCtl.Acquire(pc.ctx, source); err != nil {
+			return nil, err
+		}
+	}
+	startTime := time.Now()
+
+	content, err := pc.downloadPiece()
+	if pc.rateCtl != nil {
+		if err != nil && pc.ctx.Err() != nil {
+			// the downloading is cancelled rather than failed, which
+			// says nothing about the source.
+			pc.rateCtl.Cancel(source)
+		} else {
+			pc.rateCtl.Release(source, pc.total, pc.rtt, pc.readCost, err)
+		}
+	}
+	if err == nil && pc.latency != nil {
+		pc.latency.add(time.Since(startTime))
+	}
+	return content, err
+}
+
 func (pc *PowerClient) downloadPiece() (content *pool.Buffer, e error) {
 	dstIP := pc.pieceTask.PeerIP
 	peerPort := pc.pieceTask.PeerPort
 
+	ctx, span := tracing.StartSpan(pc.ctx, "dfget.DownloadPiece", tracing.SpanKindClient)
+	defer func() {
+		span.SetAttribute("piece.num", pc.pieceTask.PieceNum)
+		span.SetAttribute("piece.range", pc.pieceTask.Range)
+		span.SetAttribute("peer.ip", dstIP)
+		span.SetAttribute("bytes", pc.total)
+		span.SetError(e)
+		span.End()
+	}()
+
 	// check that the target download peer is available
 	if dstIP != "" && dstIP != pc.node {
 		if _, e = httputils.CheckConnect(dstIP, peerPort, -1); e != nil {
+			pc.initNetworkError(dstIP, e)
 			return nil, e
 		}
 	}
@@ -128,18 +207,32 @@ func (pc *PowerClient) downloadPiece() (content *pool.Buffer, e error) {
 	// send download request
 	startTime := time.Now()
 	timeout := netutils.CalculateTimeout(int64(pc.pieceTask.PieceSize), pc.cfg.MinRate, config.DefaultMinRate, 10*time.Second)
-	resp, err := pc.downloadAPI.Download(dstIP, peerPort, pc.createDownloadRequest(), timeout)
+	resp, err := pc.downloadAPI.Download(dstIP, peerPort, pc.createDownloadRequest(ctx), timeout)
 	if err != nil {
+		pc.initNetworkError(dstIP, err)
 		return nil, err
 	}
+	pc.rtt = time.Since(startTime)
 	logrus.Debugf("success to get resp timeSince(%v)", time.Since(startTime))
 	defer resp.Body.Close()
+	// closing the body aborts the reading when the request is cancelled.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			resp.Body.Close()
+		case <-done:
+		}
+	}()
 	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
 		return nil, errortypes.ErrRangeNotSatisfiable
 	}
 	if !pc.is2xxStatus(resp.StatusCode) {
 		if resp.StatusCode == http.StatusNotFound {
 			pc.initFileNotExistError()
+		} else if resp.StatusCode >= http.StatusInternalServerError {
+			pc.initPeerError(dstIP, constants.ClientErrorServerError)
 		}
 		return nil, errortypes.New(resp.StatusCode, pc.readBody(resp.Body))
 	}
@@ -159,6 +252,7 @@ func (pc *PowerClient) downloadPiece() (content *pool.Buffer, e error) {
 		}
 	}()
 	if pc.total, e = content.ReadFrom(limitReader); e != nil {
+		pc.initNetworkError(dstIP, e)
 		return nil, e
 	}
 	pc.readCost = time.Since(startTime)
@@ -179,7 +273,7 @@ func (pc *PowerClient) downloadPiece() (content *pool.Buffer, e error) {
 	return content, nil
 }
 
-func (pc *PowerClient) createDownloadRequest() *api.DownloadRequest {
+func (pc *PowerClient) createDownloadRequest(ctx context.Context) *api.DownloadRequest {
 	pieceRange := pc.pieceTask.Range
 	headers := netutils.ConvertHeaders(pc.headers)
 	if pc.cdnSource == apiTypes.CdnSourceSource {
@@ -190,6 +284,9 @@ func (pc *PowerClient) createDownloadRequest() *api.DownloadRequest {
 			headers = make(map[string]string)
 		}
 		headers[config.StrCDNSource] = string(apiTypes.CdnSourceSource)
+	} else {
+		// the trace context is only sent to the peers rather than the source
+		headers = tracing.InjectMap(ctx, headers)
 	}
 
 	return &api.DownloadRequest{
@@ -236,6 +333,68 @@ func (pc *PowerClient) initFileMd5NotMatchError(dstIP, realMd5, expectedMd5 stri
 	}
 }
 
+// initPieceSlowError reports the scheduled peer which lost to a hedged request.
+func (pc *PowerClient) initPieceSlowError(slow *PowerClient) {
+	pc.clientError = &types.ClientErrorRequest{
+		ErrorType: constants.ClientErrorPieceSlow,
+		SrcCid:    pc.cfg.RV.Cid,
+		DstCid:    slow.pieceTask.Cid,
+		DstIP:     slow.pieceTask.PeerIP,
+		TaskID:    pc.taskID,
+		Range:     slow.pieceTask.Range,
+	}
+}
+
+// initNetworkError reports the peer if it fails to serve the piece
+// because of the network.
+func (pc *PowerClient) initNetworkError(dstIP string, err error) {
+	if errType := networkErrorType(err); errType != "" {
+		pc.initPeerError(dstIP, errType)
+	}
+}
+
+func (pc *PowerClient) initPeerError(dstIP, errType string) {
+	// only the other peers are reported, the errors of the supernode
+	// are handled by itself.
+	if dstIP == "" || dstIP == pc.node {
+		return
+	}
+	pc.clientError = &types.ClientErrorRequest{
+		ErrorType: errType,
+		SrcCid:    pc.cfg.RV.Cid,
+		DstCid:    pc.pieceTask.Cid,
+		DstIP:     dstIP,
+		TaskID:    pc.taskID,
+		Range:     pc.pieceTask.Range,
+	}
+}
+
+// networkErrorType returns the client error type of err,
+// or an empty string if it isn't a network error of the peer.
+func networkErrorType(err error) string {
+	if err == io.ErrUnexpectedEOF {
+		return constants.ClientErrorShortRead
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return constants.ClientErrorTimeout
+	}
+	for {
+		switch e := err.(type) {
+		case *url.Error:
+			err = e.Err
+		case *net.OpError:
+			err = e.Err
+		case *os.SyscallError:
+			err = e.Err
+		default:
+			if err == syscall.ECONNREFUSED {
+				return constants.ClientErrorConnectionRefused
+			}
+			return ""
+		}
+	}
+}
+
 func (pc *PowerClient) is2xxStatus(code int) bool {
 	return code >= 200 && code < 300
 }
@@ -18,16 +18,21 @@ package downloader
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"syscall"
 	"time"
 
 	"github.com/dragonflyoss/Dragonfly/dfget/config"
 	"github.com/dragonflyoss/Dragonfly/dfget/core/api"
 	"github.com/dragonflyoss/Dragonfly/dfget/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/constants"
 	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
 	"github.com/dragonflyoss/Dragonfly/pkg/ratelimiter"
 
@@ -160,8 +165,51 @@ func (s *PowerClientTestSuite) TestReadBody(c *check.C) {
 	}
 }
 
+func (s *PowerClientTestSuite) TestNetworkErrorType(c *check.C) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	var cases = []struct {
+		err      error
+		expected string
+	}{
+		{err: io.ErrUnexpectedEOF, expected: constants.ClientErrorShortRead},
+		{err: refused, expected: constants.ClientErrorConnectionRefused},
+		{err: &url.Error{Op: "Get", URL: "http://127.0.0.1", Err: refused}, expected: constants.ClientErrorConnectionRefused},
+		{err: &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}, expected: constants.ClientErrorTimeout},
+		{err: &url.Error{Op: "Get", URL: "http://127.0.0.1", Err: timeoutError{}}, expected: constants.ClientErrorTimeout},
+		{err: io.EOF, expected: ""},
+		{err: fmt.Errorf("failed"), expected: ""},
+	}
+
+	for _, v := range cases {
+		c.Check(networkErrorType(v.err), check.Equals, v.expected, check.Commentf("%v", v.err))
+	}
+}
+
+func (s *PowerClientTestSuite) TestInitNetworkError(c *check.C) {
+	s.reset()
+
+	// the errors of the supernode aren't reported
+	s.powerClient.initNetworkError("127.0.0.1", io.ErrUnexpectedEOF)
+	c.Check(s.powerClient.ClientError(), check.IsNil)
+
+	s.powerClient.initNetworkError("127.0.0.2", fmt.Errorf("failed"))
+	c.Check(s.powerClient.ClientError(), check.IsNil)
+
+	s.powerClient.initNetworkError("127.0.0.2", io.ErrUnexpectedEOF)
+	c.Assert(s.powerClient.ClientError(), check.NotNil)
+	c.Check(s.powerClient.ClientError().ErrorType, check.Equals, constants.ClientErrorShortRead)
+	c.Check(s.powerClient.ClientError().DstIP, check.Equals, "127.0.0.2")
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
 func (s *PowerClientTestSuite) reset() {
 	s.powerClient = &PowerClient{
+		ctx:         context.Background(),
 		cfg:         &config.Config{RV: config.RuntimeVariable{Cid: ""}},
 		node:        "127.0.0.1",
 		rateLimiter: ratelimiter.NewRateLimiter(int64(5), 2),
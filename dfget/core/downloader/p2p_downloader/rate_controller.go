@@ -0,0 +1,258 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package downloader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// initPeerWindow is the number of pieces that can be downloaded
+	// from a source simultaneously before any sample is collected.
+	initPeerWindow = 4
+	// minPeerWindow and maxPeerWindow bound the per-source concurrency.
+	minPeerWindow = 1
+	maxPeerWindow = 16
+
+	// bwSampleSize is the number of the latest throughput samples of a source
+	// whose maximum is treated as the bottleneck bandwidth of the source.
+	bwSampleSize = 10
+	// minRTTExpiration specifies how long the minimal RTT of a source is valid.
+	minRTTExpiration = 10 * time.Second
+	// rttInflation indicates a source is overloaded if its RTT exceeds minRTT*rttInflation.
+	rttInflation = 2
+	// pacingGain is the ratio of the total rate to the sum of the bottleneck
+	// bandwidth of all sources, it's greater than 1 to probe for more bandwidth.
+	pacingGain = 1.25
+	// fullBwThreshold is the ratio of a throughput sample to the bottleneck
+	// bandwidth above which the source is regarded as not saturated yet.
+	fullBwThreshold = 0.9
+	// idleSourceExpiration specifies how long an idle source is still
+	// taken into account when calculating the rate.
+	idleSourceExpiration = 30 * time.Second
+)
+
+// sourceState holds the statistics of a source which pieces are downloaded from.
+type sourceState struct {
+	window   int
+	inflight int
+
+	bwSamples [bwSampleSize]int64
+	bwIndex   int
+	btlBw     int64
+
+	minRTT     time.Duration
+	minRTTTime time.Time
+
+	lastActive time.Time
+}
+
+func (s *sourceState) addBandwidth(bw int64) {
+	s.bwSamples[s.bwIndex%bwSampleSize] = bw
+	s.bwIndex++
+	s.btlBw = 0
+	for _, v := range s.bwSamples {
+		if v > s.btlBw {
+			s.btlBw = v
+		}
+	}
+}
+
+func (s *sourceState) updateMinRTT(rtt time.Duration, now time.Time) {
+	if s.minRTT == 0 || rtt <= s.minRTT || now.Sub(s.minRTTTime) > minRTTExpiration {
+		s.minRTT = rtt
+		s.minRTTTime = now
+	}
+}
+
+// RateController adapts the download concurrency of every source and the
+// total download rate according to the throughput and RTT observed from
+// the pieces downloaded by PowerClient.
+//
+// Every source has a congestion window which is increased additively while
+// the source keeps delivering at full speed, and decreased multiplicatively
+// when the downloading fails or the RTT inflates. The total rate is the sum
+// of the bottleneck bandwidth of all active sources multiplied by a pacing gain,
+// and it's always bounded by [minRate, maxRate].
+type RateController struct {
+	sync.Mutex
+	// released is closed and replaced when a slot is released,
+	// to wake up the Acquire calls waiting for the window.
+	released chan struct{}
+
+	sources map[string]*sourceState
+
+	minRate int64
+	maxRate int64
+}
+
+// NewRateController creates a RateController whose rate is bounded by [minRate, maxRate].
+func NewRateController(minRate, maxRate int64) *RateController {
+	return &RateController{
+		released: make(chan struct{}),
+		sources:  make(map[string]*sourceState),
+		minRate:  minRate,
+		maxRate:  maxRate,
+	}
+}
+
+// SetMaxRate updates the upper bound of the rate.
+func (rc *RateController) SetMaxRate(maxRate int64) {
+	rc.Lock()
+	defer rc.Unlock()
+	rc.maxRate = maxRate
+}
+
+// Acquire blocks until the number of the pieces being downloaded from
+// the source is less than its window, or returns the error of ctx if it's
+// done before that.
+func (rc *RateController) Acquire(ctx context.Context, source string) error {
+	for {
+		rc.Lock()
+		s := rc.getSource(source)
+		if s.inflight < s.window {
+			s.inflight++
+			s.lastActive = time.Now()
+			rc.Unlock()
+			return nil
+		}
+		released := rc.released
+		rc.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-released:
+		}
+	}
+}
+
+// Release releases the slot acquired from the source and updates the
+// statistics of the source by the result of downloading a piece.
+// The rtt is the time to receive the response header, and cost is the
+// time to download the whole piece of the given length.
+func (rc *RateController) Release(source string, length int64, rtt, cost time.Duration, err error) {
+	rc.Lock()
+	defer rc.Unlock()
+
+	now := time.Now()
+	s := rc.release(source, now)
+
+	if err != nil {
+		s.window = maxInt(s.window/2, minPeerWindow)
+		return
+	}
+	if rtt <= 0 || cost <= 0 {
+		return
+	}
+
+	if s.minRTT > 0 && rtt > s.minRTT*rttInflation {
+		// the source is overloaded, back off from it.
+		s.window = maxInt(s.window-1, minPeerWindow)
+		s.updateMinRTT(rtt, now)
+		return
+	}
+	s.updateMinRTT(rtt, now)
+
+	bw := int64(float64(length) / cost.Seconds())
+	if bw >= int64(float64(s.btlBw)*fullBwThreshold) {
+		s.window = minInt(s.window+1, maxPeerWindow)
+	}
+	s.addBandwidth(bw)
+}
+
+// Cancel releases the slot acquired from the source without updating the
+// statistics, it's used when the downloading is cancelled on purpose, such
+// as the hedged request which loses.
+func (rc *RateController) Cancel(source string) {
+	rc.Lock()
+	defer rc.Unlock()
+	rc.release(source, time.Now())
+}
+
+// release decreases the inflight count of the source and wakes up the
+// waiting Acquire calls, the lock must be held by the caller.
+func (rc *RateController) release(source string, now time.Time) *sourceState {
+	s := rc.getSource(source)
+	if s.inflight > 0 {
+		s.inflight--
+	}
+	s.lastActive = now
+	close(rc.released)
+	rc.released = make(chan struct{})
+	return s
+}
+
+// Rate returns the total download rate.
+// It returns the maxRate if there is no bandwidth sample yet.
+func (rc *RateController) Rate() int64 {
+	rc.Lock()
+	defer rc.Unlock()
+
+	var total int64
+	now := time.Now()
+	for _, s := range rc.sources {
+		if s.inflight == 0 && now.Sub(s.lastActive) > idleSourceExpiration {
+			continue
+		}
+		total += s.btlBw
+	}
+	if total == 0 {
+		return rc.maxRate
+	}
+
+	rate := int64(float64(total) * pacingGain)
+	if rc.maxRate > 0 && rate > rc.maxRate {
+		rate = rc.maxRate
+	}
+	if rate < rc.minRate {
+		rate = rc.minRate
+	}
+	return rate
+}
+
+// Window returns the current window of the source.
+func (rc *RateController) Window(source string) int {
+	rc.Lock()
+	defer rc.Unlock()
+	return rc.getSource(source).window
+}
+
+func (rc *RateController) getSource(source string) *sourceState {
+	s, ok := rc.sources[source]
+	if !ok {
+		s = &sourceState{window: initPeerWindow}
+		rc.sources[source] = s
+	}
+	return s
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-check/check"
+)
+
+type RateControllerTestSuite struct {
+}
+
+func init() {
+	check.Suite(&RateControllerTestSuite{})
+}
+
+func (s *RateControllerTestSuite) TestRateWithoutSamples(c *check.C) {
+	rc := NewRateController(100, 1000)
+	c.Assert(rc.Rate(), check.Equals, int64(1000))
+
+	rc.SetMaxRate(2000)
+	c.Assert(rc.Rate(), check.Equals, int64(2000))
+}
+
+func (s *RateControllerTestSuite) TestWindowIncrease(c *check.C) {
+	rc := NewRateController(0, 0)
+	source := "127.0.0.1:15001"
+
+	for i := 0; i < 3; i++ {
+		rc.Acquire(context.Background(), source)
+		rc.Release(source, 1000, 10*time.Millisecond, time.Second, nil)
+	}
+	c.Assert(rc.Window(source), check.Equals, initPeerWindow+3)
+
+	for i := 0; i < 2*maxPeerWindow; i++ {
+		rc.Acquire(context.Background(), source)
+		rc.Release(source, 1000, 10*time.Millisecond, time.Second, nil)
+	}
+	c.Assert(rc.Window(source), check.Equals, maxPeerWindow)
+}
+
+func (s *RateControllerTestSuite) TestWindowDecrease(c *check.C) {
+	rc := NewRateController(0, 0)
+	source := "127.0.0.1:15001"
+
+	// failure halves the window
+	rc.Acquire(context.Background(), source)
+	rc.Release(source, 0, 0, 0, fmt.Errorf("connection refused"))
+	c.Assert(rc.Window(source), check.Equals, initPeerWindow/2)
+
+	// RTT inflation decreases the window by one
+	rc.Acquire(context.Background(), source)
+	rc.Release(source, 1000, 10*time.Millisecond, time.Second, nil)
+	window := rc.Window(source)
+	rc.Acquire(context.Background(), source)
+	rc.Release(source, 1000, 100*time.Millisecond, time.Second, nil)
+	c.Assert(rc.Window(source), check.Equals, window-1)
+
+	for i := 0; i < maxPeerWindow; i++ {
+		rc.Acquire(context.Background(), source)
+		rc.Release(source, 0, 0, 0, fmt.Errorf("timeout"))
+	}
+	c.Assert(rc.Window(source), check.Equals, minPeerWindow)
+}
+
+func (s *RateControllerTestSuite) TestRateBounds(c *check.C) {
+	rc := NewRateController(5000, 10000)
+
+	rc.Acquire(context.Background(), "a")
+	rc.Release("a", 1000, time.Millisecond, time.Second, nil)
+	c.Assert(rc.Rate(), check.Equals, int64(5000))
+
+	rc.Acquire(context.Background(), "b")
+	rc.Release("b", 6000, time.Millisecond, time.Second, nil)
+	c.Assert(rc.Rate(), check.Equals, int64(8750))
+
+	rc.Acquire(context.Background(), "c")
+	rc.Release("c", 6000, time.Millisecond, time.Second, nil)
+	c.Assert(rc.Rate(), check.Equals, int64(10000))
+}
+
+func (s *RateControllerTestSuite) TestAcquireBlocking(c *check.C) {
+	rc := NewRateController(0, 0)
+	source := "127.0.0.1:15001"
+	for i := 0; i < initPeerWindow; i++ {
+		rc.Acquire(context.Background(), source)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		rc.Acquire(context.Background(), source)
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		c.Fatal("acquire should be blocked when the window is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	rc.Release(source, 1000, time.Millisecond, time.Second, nil)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		c.Fatal("acquire should succeed after release")
+	}
+}
+
+func (s *RateControllerTestSuite) TestAcquireCancelled(c *check.C) {
+	rc := NewRateController(0, 0)
+	source := "127.0.0.1:15001"
+	for i := 0; i < initPeerWindow; i++ {
+		c.Assert(rc.Acquire(context.Background(), source), check.IsNil)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	c.Assert(rc.Acquire(ctx, source), check.Equals, context.DeadlineExceeded)
+
+	// the cancelled downloading doesn't shrink the window
+	rc.Cancel(source)
+	c.Assert(rc.Window(source), check.Equals, initPeerWindow)
+	c.Assert(rc.Acquire(context.Background(), source), check.IsNil)
+}
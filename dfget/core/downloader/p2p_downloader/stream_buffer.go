@@ -0,0 +1,188 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package downloader
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/dragonflyoss/Dragonfly/dfget/core/progress"
+
+	"github.com/sirupsen/logrus"
+)
+
+// bufferedPiece is a piece held by the streamBuffer.
+type bufferedPiece struct {
+	// piece is nil if it has been spilled to disk.
+	piece *Piece
+	// offset and length locate the raw content of a spilled piece in the spill file.
+	offset int64
+	length int64
+}
+
+// streamBuffer holds the pieces which arrive earlier than their turn in stream mode.
+// The pieces are kept in memory until the memory budget is used up, and the
+// subsequent pieces are spilled to disk and read back when it's their turn.
+type streamBuffer struct {
+	// budget is the max bytes of the pieces in memory, 0 means no limit.
+	budget int64
+	// used is the bytes of the pieces in memory.
+	used   int64
+	pieces map[int]*bufferedPiece
+
+	noWrapper bool
+
+	// serviceFile contains all the pieces which have been written in p2p pattern,
+	// so the spilled pieces are read back from it directly.
+	serviceFile *os.File
+	// spillDir is the directory to create the temp spill file in when there is no serviceFile.
+	spillDir string
+	// spillFile holds the spilled pieces if there is no serviceFile.
+	spillFile *os.File
+	// spillSize is the size of spillFile.
+	spillSize int64
+
+	// spilled records the bytes spilled to disk.
+	spilled int64
+	// spilledPieces records the number of the pieces spilled to disk.
+	spilledPieces int
+	// peak records the max bytes of the pieces in memory.
+	peak int64
+}
+
+func newStreamBuffer(budget int64, spillDir string, noWrapper bool) *streamBuffer {
+	return &streamBuffer{
+		budget:    budget,
+		pieces:    make(map[int]*bufferedPiece),
+		spillDir:  spillDir,
+		noWrapper: noWrapper,
+	}
+}
+
+// put holds the piece until its turn, the piece is spilled
+// to disk if there is no enough memory budget.
+func (sb *streamBuffer) put(p *Piece) error {
+	size := p.ContentLength()
+	if sb.budget <= 0 || sb.used+size <= sb.budget {
+		sb.pieces[p.PieceNum] = &bufferedPiece{piece: p}
+		sb.addUsed(size)
+		return nil
+	}
+
+	bp, err := sb.spill(p)
+	if err != nil {
+		return err
+	}
+	sb.pieces[p.PieceNum] = bp
+	return nil
+}
+
+// take removes the piece from the buffer and returns it.
+func (sb *streamBuffer) take(pieceNum int) (*bufferedPiece, bool) {
+	bp, ok := sb.pieces[pieceNum]
+	if !ok {
+		return nil, false
+	}
+	delete(sb.pieces, pieceNum)
+	if bp.piece != nil {
+		sb.addUsed(-bp.piece.ContentLength())
+	}
+	return bp, true
+}
+
+// writeTo writes the raw content of the buffered piece to w.
+func (sb *streamBuffer) writeTo(bp *bufferedPiece, w io.Writer) error {
+	if bp.piece != nil {
+		_, err := bp.piece.WriteTo(w, sb.noWrapper)
+		return err
+	}
+
+	file := sb.serviceFile
+	if file == nil {
+		file = sb.spillFile
+	}
+	_, err := io.Copy(w, io.NewSectionReader(file, bp.offset, bp.length))
+	return err
+}
+
+// close releases the pieces in memory and removes the temp spill file.
+func (sb *streamBuffer) close() {
+	for k, bp := range sb.pieces {
+		if bp.piece != nil {
+			bp.piece.TryResetContent()
+		}
+		delete(sb.pieces, k)
+	}
+	sb.addUsed(-sb.used)
+
+	if sb.spillFile != nil {
+		sb.spillFile.Close()
+		os.Remove(sb.spillFile.Name())
+		sb.spillFile = nil
+	}
+	if sb.peak > 0 {
+		progress.StreamBuffer(sb.peak, sb.spilled, sb.spilledPieces)
+	}
+	if sb.spilled > 0 {
+		logrus.Infof("stream buffer peak memory:%d spilled:%d", sb.peak, sb.spilled)
+	}
+}
+
+func (sb *streamBuffer) spill(p *Piece) (*bufferedPiece, error) {
+	content := p.RawContent(sb.noWrapper)
+	if content == nil {
+		return nil, fmt.Errorf("piece content length less than 5 bytes")
+	}
+	bp := &bufferedPiece{length: int64(content.Len())}
+
+	if sb.serviceFile != nil {
+		// the piece has been written into the service file.
+		bp.offset = pieceOffset(p, sb.noWrapper)
+	} else {
+		if sb.spillFile == nil {
+			f, err := ioutil.TempFile(sb.spillDir, "dfget-stream-spill-")
+			if err != nil {
+				return nil, err
+			}
+			sb.spillFile = f
+		}
+		if _, err := sb.spillFile.WriteAt(content.Bytes(), sb.spillSize); err != nil {
+			return nil, err
+		}
+		bp.offset = sb.spillSize
+		sb.spillSize += bp.length
+	}
+	p.TryResetContent()
+
+	sb.spilled += bp.length
+	sb.spilledPieces++
+	if sb.spilledPieces == 1 {
+		// report the pressure once the buffer is full, and the final
+		// usage is reported when the buffer is closed.
+		progress.StreamBuffer(sb.peak, sb.spilled, sb.spilledPieces)
+	}
+	return bp, nil
+}
+
+func (sb *streamBuffer) addUsed(delta int64) {
+	sb.used += delta
+	if sb.used > sb.peak {
+		sb.peak = sb.used
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package downloader
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	apiTypes "github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/fileutils"
+	"github.com/dragonflyoss/Dragonfly/pkg/pool"
+
+	"github.com/go-check/check"
+)
+
+type StreamBufferTestSuite struct {
+	workHome string
+}
+
+func init() {
+	check.Suite(&StreamBufferTestSuite{})
+}
+
+func (s *StreamBufferTestSuite) SetUpSuite(c *check.C) {
+	s.workHome, _ = ioutil.TempDir("/tmp", "dfget-StreamBufferTestSuite-")
+}
+
+func (s *StreamBufferTestSuite) TearDownSuite(c *check.C) {
+	os.RemoveAll(s.workHome)
+}
+
+func (s *StreamBufferTestSuite) TestSpillToTempFile(c *check.C) {
+	// only one piece can be held in memory
+	sb := newStreamBuffer(6, s.workHome, false)
+	pieces := []*Piece{
+		newStreamTestPiece(1, "000020"),
+		newStreamTestPiece(2, "000030"),
+		newStreamTestPiece(3, "000040"),
+	}
+	for _, p := range pieces {
+		c.Assert(sb.put(p), check.IsNil)
+	}
+	c.Assert(sb.used, check.Equals, int64(6))
+	c.Assert(sb.spilled, check.Equals, int64(2))
+	c.Assert(sb.spillFile, check.NotNil)
+	spillPath := sb.spillFile.Name()
+
+	buf := &bytes.Buffer{}
+	for i := 1; i <= 3; i++ {
+		bp, ok := sb.take(i)
+		c.Assert(ok, check.Equals, true)
+		c.Assert(sb.writeTo(bp, buf), check.IsNil)
+	}
+	c.Assert(buf.String(), check.Equals, "234")
+	c.Assert(sb.used, check.Equals, int64(0))
+
+	_, ok := sb.take(4)
+	c.Assert(ok, check.Equals, false)
+
+	sb.close()
+	c.Assert(fileutils.PathExist(spillPath), check.Equals, false)
+}
+
+func (s *StreamBufferTestSuite) TestSpillToServiceFile(c *check.C) {
+	serviceFile, err := fileutils.OpenFile(filepath.Join(s.workHome, "sbtest.service"),
+		os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0755)
+	c.Assert(err, check.IsNil)
+	defer serviceFile.Close()
+
+	sb := newStreamBuffer(1, s.workHome, false)
+	sb.serviceFile = serviceFile
+	for i, content := range []string{"000010", "000020", "000030"} {
+		p := newStreamTestPiece(i, content)
+		p.IncWriter()
+		c.Assert(writePieceToFile(p, serviceFile, apiTypes.CdnSourceSupernode), check.IsNil)
+		if i > 0 {
+			c.Assert(sb.put(p), check.IsNil)
+		}
+	}
+	c.Assert(sb.used, check.Equals, int64(0))
+	c.Assert(sb.spillFile, check.IsNil)
+
+	buf := &bytes.Buffer{}
+	for i := 1; i <= 2; i++ {
+		bp, ok := sb.take(i)
+		c.Assert(ok, check.Equals, true)
+		c.Assert(bp.piece, check.IsNil)
+		c.Assert(sb.writeTo(bp, buf), check.IsNil)
+	}
+	c.Assert(buf.String(), check.Equals, "23")
+	sb.close()
+}
+
+func (s *StreamBufferTestSuite) TestNoLimit(c *check.C) {
+	sb := newStreamBuffer(0, s.workHome, true)
+	for i := 1; i <= 3; i++ {
+		c.Assert(sb.put(newStreamTestPiece(i, "0")), check.IsNil)
+	}
+	c.Assert(sb.used, check.Equals, int64(3))
+	c.Assert(sb.spilled, check.Equals, int64(0))
+	sb.close()
+	c.Assert(sb.used, check.Equals, int64(0))
+}
+
+func newStreamTestPiece(pieceNum int, content string) *Piece {
+	return &Piece{
+		PieceNum:  pieceNum,
+		PieceSize: int32(len(content)),
+		Content:   pool.NewBufferString(content),
+		writerNum: 1,
+	}
+}
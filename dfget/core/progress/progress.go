@@ -0,0 +1,382 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package progress reports the downloading progress of dfget as a stream of
+// machine-readable events, so that callers such as dfdaemon can follow
+// what happens during a download instead of only waiting for an exit code.
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/dfget/config"
+)
+
+/* the types of progress events */
+const (
+	EventRegistered   = "registered"
+	EventPiece        = "piece"
+	EventRate         = "rate"
+	EventBackSource   = "backSource"
+	EventStreamBuffer = "streamBuffer"
+	EventSummary      = "summary"
+)
+
+/* the sources a piece can be downloaded from */
+const (
+	SourcePeer      = "peer"
+	SourceSupernode = "supernode"
+)
+
+// defaultRateInterval is the minimal interval between two rate events.
+const defaultRateInterval = time.Second
+
+// Event is a single progress event. Every event is encoded as one line of JSON.
+type Event struct {
+	// Type is the type of the event.
+	Type string `json:"type"`
+
+	// Time is the unix timestamp in milliseconds when the event happened.
+	Time int64 `json:"time"`
+
+	// TaskID is the ID of the task registered at the supernode.
+	TaskID string `json:"taskId,omitempty"`
+
+	// Node is the supernode which the dfget registered to.
+	Node string `json:"node,omitempty"`
+
+	// FileLength is the length of the file to download.
+	FileLength int64 `json:"fileLength,omitempty"`
+
+	// PieceSize is the size of every piece assigned by the supernode.
+	PieceSize int32 `json:"pieceSize,omitempty"`
+
+	// Range is the range of the piece downloaded.
+	Range string `json:"range,omitempty"`
+
+	// PieceNum is the number of the piece downloaded, only set in piece events.
+	PieceNum *int `json:"pieceNum,omitempty"`
+
+	// Source is where the piece came from, must be 'peer' or 'supernode'.
+	Source string `json:"source,omitempty"`
+
+	// SourceAddr is the address of the peer or supernode serving the piece.
+	SourceAddr string `json:"sourceAddr,omitempty"`
+
+	// Bytes is the number of bytes of the piece.
+	Bytes int64 `json:"bytes,omitempty"`
+
+	// Cost is how long the operation took in milliseconds.
+	Cost int64 `json:"cost,omitempty"`
+
+	// Completed is the total number of bytes downloaded so far, including the
+	// bytes downloaded from the source. It restarts from zero when dfget falls
+	// back to the source, since the whole file is downloaded from the source again.
+	Completed int64 `json:"completed,omitempty"`
+
+	// Rate is the download rate in bytes per second since the last rate event.
+	Rate int64 `json:"rate,omitempty"`
+
+	// AverageRate is the download rate in bytes per second since the start.
+	AverageRate int64 `json:"averageRate,omitempty"`
+
+	// Reason is the reason of backing to source, see config.BackSourceReason*.
+	// It's only set in backSource and summary events.
+	Reason *int `json:"reason,omitempty"`
+
+	// ReasonDesc describes the Reason.
+	ReasonDesc string `json:"reasonDesc,omitempty"`
+
+	// BufferPeak is the max bytes of the pieces which arrived out of order
+	// and were held in memory in stream mode, only set in streamBuffer events.
+	BufferPeak int64 `json:"bufferPeak,omitempty"`
+
+	// SpilledBytes is the number of bytes of the pieces spilled to disk
+	// because the stream buffer was full, only set in streamBuffer events.
+	SpilledBytes int64 `json:"spilledBytes,omitempty"`
+
+	// SpilledPieces is the number of the pieces spilled to disk.
+	SpilledPieces int `json:"spilledPieces,omitempty"`
+
+	// Success indicates whether the download succeeded, only set in summary events.
+	Success *bool `json:"success,omitempty"`
+
+	// Code is the exit code of dfget, only set in summary events.
+	Code *int `json:"code,omitempty"`
+
+	// Error is the error message if something failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Reporter writes the progress events as newline-delimited JSON.
+// A nil Reporter discards all the events.
+type Reporter struct {
+	sync.Mutex
+	enc *json.Encoder
+
+	taskID        string
+	start         time.Time
+	completed     int64
+	rateInterval  time.Duration
+	lastRateTime  time.Time
+	lastRateBytes int64
+}
+
+// NewReporter creates a Reporter which writes events to out.
+func NewReporter(out io.Writer) *Reporter {
+	now := time.Now()
+	return &Reporter{
+		enc:          json.NewEncoder(out),
+		start:        now,
+		rateInterval: defaultRateInterval,
+		lastRateTime: now,
+	}
+}
+
+// Registered reports that dfget registered to the supernode successfully.
+func (r *Reporter) Registered(taskID, node string, fileLength int64, pieceSize int32) {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.taskID = taskID
+	r.emit(&Event{
+		Type:       EventRegistered,
+		TaskID:     taskID,
+		Node:       node,
+		FileLength: fileLength,
+		PieceSize:  pieceSize,
+	})
+}
+
+// PieceDone reports that a piece was downloaded successfully.
+// A rate event will follow if the last one is older than the rate interval.
+func (r *Reporter) PieceDone(taskID, pieceRange string, pieceNum int, source, sourceAddr string,
+	bytes int64, cost time.Duration) {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.completed += bytes
+	r.emit(&Event{
+		Type:       EventPiece,
+		TaskID:     taskID,
+		Range:      pieceRange,
+		PieceNum:   &pieceNum,
+		Source:     source,
+		SourceAddr: sourceAddr,
+		Bytes:      bytes,
+		Cost:       toMillis(cost),
+		Completed:  r.completed,
+	})
+
+	now := time.Now()
+	if elapsed := now.Sub(r.lastRateTime); elapsed >= r.rateInterval {
+		r.emit(&Event{
+			Type:        EventRate,
+			TaskID:      taskID,
+			Completed:   r.completed,
+			Rate:        perSecond(r.completed-r.lastRateBytes, elapsed),
+			AverageRate: perSecond(r.completed, now.Sub(r.start)),
+		})
+		r.lastRateTime = now
+		r.lastRateBytes = r.completed
+	}
+}
+
+// BackSource reports that dfget falls back to download the file from source.
+// The bytes downloaded by P2P are discarded, so the completed bytes are reset.
+func (r *Reporter) BackSource(reason int, err error) {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.completed = 0
+	r.lastRateBytes = 0
+	e := &Event{
+		Type:       EventBackSource,
+		Reason:     &reason,
+		ReasonDesc: ReasonDesc(reason),
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	r.emit(e)
+}
+
+// StreamBuffer reports the usage of the buffer for the out of order pieces
+// in stream mode. The values are accumulated since the start.
+func (r *Reporter) StreamBuffer(peak, spilledBytes int64, spilledPieces int) {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.emit(&Event{
+		Type:          EventStreamBuffer,
+		TaskID:        r.taskID,
+		BufferPeak:    peak,
+		SpilledBytes:  spilledBytes,
+		SpilledPieces: spilledPieces,
+	})
+}
+
+// SourceDone counts the bytes downloaded from the source, which are
+// included in the completed bytes of the following events.
+func (r *Reporter) SourceDone(bytes int64) {
+	if r == nil || bytes <= 0 {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.completed += bytes
+}
+
+// Summary reports the final result of the download.
+func (r *Reporter) Summary(fileLength int64, reason int, code int, err error) {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	cost := time.Since(r.start)
+	success := err == nil
+	e := &Event{
+		Type:        EventSummary,
+		TaskID:      r.taskID,
+		FileLength:  fileLength,
+		Completed:   r.completed,
+		Cost:        toMillis(cost),
+		AverageRate: perSecond(r.completed, cost),
+		Reason:      &reason,
+		ReasonDesc:  ReasonDesc(reason),
+		Success:     &success,
+		Code:        &code,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	r.emit(e)
+}
+
+func (r *Reporter) emit(e *Event) {
+	e.Time = time.Now().UnixNano() / int64(time.Millisecond)
+	// The progress output is best-effort and must never break the download.
+	r.enc.Encode(e)
+}
+
+// ReasonDesc returns a short description of the reason of backing to source.
+func ReasonDesc(reason int) string {
+	if reason >= config.ForceNotBackSourceAddition {
+		reason -= config.ForceNotBackSourceAddition
+	}
+	switch reason {
+	case config.BackSourceReasonNone:
+		return ""
+	case config.BackSourceReasonRegisterFail:
+		return "register fail"
+	case config.BackSourceReasonMd5NotMatch:
+		return "md5 not match"
+	case config.BackSourceReasonDownloadError:
+		return "download error"
+	case config.BackSourceReasonNoSpace:
+		return "no space"
+	case config.BackSourceReasonInitError:
+		return "init error"
+	case config.BackSourceReasonWriteError:
+		return "write error"
+	case config.BackSourceReasonHostSysError:
+		return "host sys error"
+	case config.BackSourceReasonNodeEmpty:
+		return "supernode empty"
+	case config.BackSourceReasonSourceError:
+		return "source error"
+	case config.BackSourceReasonUserSpecified:
+		return "user specified"
+	}
+	return "unknown"
+}
+
+func toMillis(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
+
+func perSecond(bytes int64, d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64(float64(bytes) / d.Seconds())
+}
+
+// ----------------------------------------------------------------------------
+// default reporter
+
+var (
+	defaultReporter *Reporter
+	defaultMu       sync.RWMutex
+)
+
+// SetDefault sets the Reporter used by the package-level functions.
+// Setting it to nil disables the progress output.
+func SetDefault(r *Reporter) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultReporter = r
+}
+
+// Default returns the Reporter used by the package-level functions.
+func Default() *Reporter {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultReporter
+}
+
+// Registered reports a registered event with the default Reporter.
+func Registered(taskID, node string, fileLength int64, pieceSize int32) {
+	Default().Registered(taskID, node, fileLength, pieceSize)
+}
+
+// PieceDone reports a piece event with the default Reporter.
+func PieceDone(taskID, pieceRange string, pieceNum int, source, sourceAddr string,
+	bytes int64, cost time.Duration) {
+	Default().PieceDone(taskID, pieceRange, pieceNum, source, sourceAddr, bytes, cost)
+}
+
+// BackSource reports a backSource event with the default Reporter.
+func BackSource(reason int, err error) {
+	Default().BackSource(reason, err)
+}
+
+// StreamBuffer reports a streamBuffer event with the default Reporter.
+func StreamBuffer(peak, spilledBytes int64, spilledPieces int) {
+	Default().StreamBuffer(peak, spilledBytes, spilledPieces)
+}
+
+// SourceDone counts the bytes downloaded from the source with the default Reporter.
+func SourceDone(bytes int64) {
+	Default().SourceDone(bytes)
+}
+
+// Summary reports a summary event with the default Reporter.
+func Summary(fileLength int64, reason int, code int, err error) {
+	Default().Summary(fileLength, reason, code, err)
+}
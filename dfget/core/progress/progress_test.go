@@ -0,0 +1,138 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/dfget/config"
+
+	"github.com/go-check/check"
+)
+
+func Test(t *testing.T) {
+	check.TestingT(t)
+}
+
+type ProgressTestSuite struct{}
+
+func init() {
+	check.Suite(&ProgressTestSuite{})
+}
+
+func (s *ProgressTestSuite) TestReporter(c *check.C) {
+	buf := &bytes.Buffer{}
+	r := NewReporter(buf)
+	r.rateInterval = 0
+
+	r.Registered("task", "127.0.0.1", 10, 5)
+	r.PieceDone("task", "0-4", 0, SourcePeer, "127.0.0.2:15001", 5, 10*time.Millisecond)
+	r.BackSource(config.BackSourceReasonDownloadError, fmt.Errorf("timeout"))
+	r.SourceDone(10)
+	r.Summary(10, config.BackSourceReasonDownloadError, 0, nil)
+
+	events := decodeEvents(c, buf)
+	c.Assert(len(events), check.Equals, 5)
+
+	c.Assert(events[0].Type, check.Equals, EventRegistered)
+	c.Assert(events[0].TaskID, check.Equals, "task")
+	c.Assert(events[0].PieceSize, check.Equals, int32(5))
+
+	c.Assert(events[1].Type, check.Equals, EventPiece)
+	c.Assert(events[1].Source, check.Equals, SourcePeer)
+	c.Assert(events[1].Bytes, check.Equals, int64(5))
+	c.Assert(events[1].Cost, check.Equals, int64(10))
+	c.Assert(*events[1].PieceNum, check.Equals, 0)
+	c.Assert(events[1].Code, check.IsNil)
+
+	c.Assert(events[2].Type, check.Equals, EventRate)
+	c.Assert(events[2].Completed, check.Equals, int64(5))
+
+	c.Assert(events[3].Type, check.Equals, EventBackSource)
+	c.Assert(events[3].ReasonDesc, check.Equals, "download error")
+	c.Assert(events[3].Error, check.Equals, "timeout")
+
+	c.Assert(events[4].Type, check.Equals, EventSummary)
+	c.Assert(events[4].TaskID, check.Equals, "task")
+	c.Assert(*events[4].Success, check.Equals, true)
+	c.Assert(*events[4].Code, check.Equals, 0)
+	// the bytes downloaded by P2P are discarded when backing to source
+	c.Assert(events[4].Completed, check.Equals, int64(10))
+	c.Assert(events[4].PieceNum, check.IsNil)
+}
+
+func (s *ProgressTestSuite) TestNilReporter(c *check.C) {
+	var r *Reporter
+	r.Registered("task", "127.0.0.1", 10, 5)
+	r.PieceDone("task", "0-4", 0, SourceSupernode, "127.0.0.1:8001", 5, time.Millisecond)
+	r.BackSource(config.BackSourceReasonRegisterFail, nil)
+	r.SourceDone(10)
+	r.StreamBuffer(10, 5, 1)
+	r.Summary(10, 0, 0, nil)
+}
+
+func (s *ProgressTestSuite) TestStreamBuffer(c *check.C) {
+	buf := &bytes.Buffer{}
+	r := NewReporter(buf)
+	r.Registered("task", "127.0.0.1", 10, 5)
+	r.StreamBuffer(10, 5, 1)
+
+	events := decodeEvents(c, buf)
+	c.Assert(len(events), check.Equals, 2)
+	c.Assert(events[1].Type, check.Equals, EventStreamBuffer)
+	c.Assert(events[1].TaskID, check.Equals, "task")
+	c.Assert(events[1].BufferPeak, check.Equals, int64(10))
+	c.Assert(events[1].SpilledBytes, check.Equals, int64(5))
+	c.Assert(events[1].SpilledPieces, check.Equals, 1)
+}
+
+func (s *ProgressTestSuite) TestZeroValues(c *check.C) {
+	buf := &bytes.Buffer{}
+	r := NewReporter(buf)
+	r.PieceDone("task", "0-4", 0, SourcePeer, "127.0.0.2:15001", 5, 0)
+	r.Summary(5, config.BackSourceReasonNone, 0, nil)
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+	c.Assert(lines, check.HasLen, 2)
+	c.Assert(string(lines[0]), check.Matches, `.*"pieceNum":0.*`)
+	c.Assert(string(lines[1]), check.Matches, `.*"reason":0.*`)
+	c.Assert(string(lines[1]), check.Matches, `.*"code":0.*`)
+}
+
+func (s *ProgressTestSuite) TestReasonDesc(c *check.C) {
+	c.Assert(ReasonDesc(config.BackSourceReasonNone), check.Equals, "")
+	c.Assert(ReasonDesc(config.BackSourceReasonMd5NotMatch), check.Equals, "md5 not match")
+	c.Assert(ReasonDesc(config.BackSourceReasonMd5NotMatch+config.ForceNotBackSourceAddition),
+		check.Equals, "md5 not match")
+	c.Assert(ReasonDesc(999), check.Equals, "unknown")
+}
+
+func decodeEvents(c *check.C, buf *bytes.Buffer) []*Event {
+	var events []*Event
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		e := &Event{}
+		c.Assert(json.Unmarshal(scanner.Bytes(), e), check.IsNil)
+		events = append(events, e)
+	}
+	return events
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/dragonflyoss/Dragonfly/dfget/types"
 	"github.com/dragonflyoss/Dragonfly/pkg/constants"
 	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/netutils"
 	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
 	"github.com/dragonflyoss/Dragonfly/pkg/util"
 	"github.com/dragonflyoss/Dragonfly/version"
@@ -90,8 +91,15 @@ func (s *supernodeRegister) Register(peerPort int) (*RegisterResult, *errortypes
 		resp, e = s.api.Register(nodeHost, req)
 		logrus.Infof("do register to %s, res:%s error:%v", nodeHost, resp, e)
 		if e != nil {
+			s.reportUnavailable(nodeHost)
 			continue
 		}
+		if resp.Code == constants.CodeTaskRedirect {
+			node, resp, e = s.registerToOwner(node, resp, req)
+			if e != nil {
+				continue
+			}
+		}
 		if resp.Code == constants.Success || resp.Code == constants.CodeNeedAuth ||
 			resp.Code == constants.CodeURLNotReachable {
 			break
@@ -112,6 +120,42 @@ func (s *supernodeRegister) Register(peerPort int) (*RegisterResult, *errortypes
 	return result, nil
 }
 
+// registerToOwner follows the redirection to the supernode which owns the task
+// in cluster mode. The redirection is followed only once to avoid the loop
+// when the supernodes have different views of the cluster.
+func (s *supernodeRegister) registerToOwner(node *locator.Supernode, resp *types.RegisterResponse,
+	req *types.RegisterRequest) (*locator.Supernode, *types.RegisterResponse, error) {
+	if resp.Data == nil || stringutils.IsEmptyStr(resp.Data.Owner) {
+		return node, resp, nil
+	}
+
+	ip, port := netutils.GetIPAndPortFromNode(resp.Data.Owner, config.DefaultSupernodePort)
+	owner := &locator.Supernode{
+		Schema:    config.DefaultSupernodeSchema,
+		IP:        ip,
+		Port:      port,
+		GroupName: node.GroupName,
+	}
+	ownerReq := *req
+	ownerReq.SupernodeIP = owner.IP
+	ownerHost := nodeHostStr(owner)
+	ownerResp, e := s.api.Register(ownerHost, &ownerReq)
+	logrus.Infof("follow the redirection from %s to %s, res:%s error:%v", nodeHostStr(node), ownerHost, ownerResp, e)
+	if e != nil {
+		s.reportUnavailable(ownerHost)
+		return node, resp, e
+	}
+	return owner, ownerResp, nil
+}
+
+// reportUnavailable reports the supernode which cannot be connected to the locator,
+// so that it will be skipped by the locator which supports it.
+func (s *supernodeRegister) reportUnavailable(nodeHost string) {
+	s.locator.Report(nodeHost, &locator.SupernodeMetrics{
+		Metrics: map[string]interface{}{locator.MetricAvailable: false},
+	})
+}
+
 func (s *supernodeRegister) checkResponse(resp *types.RegisterResponse, e error) *errortypes.DfError {
 	if e != nil {
 		return errortypes.New(constants.HTTPError, e.Error())
@@ -133,19 +177,25 @@ func (s *supernodeRegister) constructRegisterRequest(port int) *types.RegisterRe
 	cfg := s.cfg
 	hostname, _ := os.Hostname()
 	req := &types.RegisterRequest{
-		RawURL:     cfg.URL,
-		TaskURL:    cfg.RV.TaskURL,
-		Cid:        cfg.RV.Cid,
-		IP:         cfg.RV.LocalIP,
-		HostName:   hostname,
-		Port:       port,
-		Path:       getTaskPath(cfg.RV.TaskFileName),
-		Version:    version.DFGetVersion,
-		CallSystem: cfg.CallSystem,
-		Headers:    cfg.Header,
-		Dfdaemon:   cfg.DFDaemon,
-		Insecure:   cfg.Insecure,
-		Pattern:    cfg.Pattern,
+		RawURL:      cfg.URL,
+		TaskURL:     cfg.RV.TaskURL,
+		Cid:         cfg.RV.Cid,
+		IP:          cfg.RV.LocalIP,
+		HostName:    hostname,
+		Port:        port,
+		Path:        getTaskPath(cfg.RV.TaskFileName),
+		Version:     version.DFGetVersion,
+		CallSystem:  cfg.CallSystem,
+		Headers:     cfg.Header,
+		Dfdaemon:    cfg.DFDaemon,
+		Insecure:    cfg.Insecure,
+		Pattern:     cfg.Pattern,
+		IDC:         cfg.IDC,
+		Labels:      cfg.Labels,
+		UploadRate:  int64(cfg.TotalLimit),
+		Cluster:     cfg.Cluster,
+		Tenant:      cfg.Tenant,
+		TenantToken: cfg.TenantToken,
 	}
 	if cfg.Md5 != "" {
 		req.Md5 = cfg.Md5
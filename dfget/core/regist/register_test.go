@@ -29,7 +29,9 @@ import (
 	"github.com/dragonflyoss/Dragonfly/dfget/config"
 	. "github.com/dragonflyoss/Dragonfly/dfget/core/helper"
 	"github.com/dragonflyoss/Dragonfly/dfget/locator"
+	dfgetTypes "github.com/dragonflyoss/Dragonfly/dfget/types"
 	"github.com/dragonflyoss/Dragonfly/pkg/constants"
+	"github.com/dragonflyoss/Dragonfly/pkg/queue"
 
 	"github.com/go-check/check"
 )
@@ -117,6 +119,46 @@ func (s *RegistTestSuite) TestSupernodeRegister_Register(c *check.C) {
 	f(constants.HTTPError, "empty response, unknown error", nil)
 }
 
+func (s *RegistTestSuite) TestSupernodeRegister_Redirect(c *check.C) {
+	buf := &bytes.Buffer{}
+	cfg := s.createConfig(buf)
+	cfg.URL = "http://lowzj.com"
+	cfg.Cluster = true
+
+	nodes := []string{"127.0.0.1:8002", "127.0.0.2:8002", "127.0.0.3:8002"}
+	var registered []string
+	m := new(MockSupernodeAPI)
+	m.RegisterFunc = func(ip string, req *dfgetTypes.RegisterRequest) (*dfgetTypes.RegisterResponse, error) {
+		registered = append(registered, ip)
+		// only the dfget supporting the cluster is redirected by the supernodes
+		c.Assert(req.Cluster, check.Equals, true)
+		switch ip {
+		case nodes[0]:
+			return nil, fmt.Errorf("connection refused")
+		case nodes[1]:
+			return &dfgetTypes.RegisterResponse{
+				BaseResponse: &dfgetTypes.BaseResponse{Code: constants.CodeTaskRedirect},
+				Data:         &dfgetTypes.RegisterResponseData{Owner: nodes[2]},
+			}, nil
+		}
+		c.Assert(req.SupernodeIP, check.Equals, "127.0.0.3")
+		return &dfgetTypes.RegisterResponse{
+			BaseResponse: &dfgetTypes.BaseResponse{Code: constants.Success},
+			Data:         &dfgetTypes.RegisterResponseData{TaskID: "a", FileLength: 100, PieceSize: 10},
+		}, nil
+	}
+
+	hl, err := locator.NewHashCirclerLocator("test", nodes, queue.NewQueue(0))
+	c.Assert(err, check.IsNil)
+	register := NewSupernodeRegister(cfg, m, hl)
+	resp, e := register.Register(0)
+	c.Assert(e, check.IsNil)
+	c.Assert(resp, check.DeepEquals, &RegisterResult{
+		Node: nodes[2], URL: cfg.URL, TaskID: "a",
+		FileLength: 100, PieceSize: 10})
+	c.Assert(registered, check.DeepEquals, []string{nodes[0], nodes[1], nodes[2]})
+}
+
 func (s *RegistTestSuite) TestSupernodeRegister_constructRegisterRequest(c *check.C) {
 	buf := &bytes.Buffer{}
 	cfg := s.createConfig(buf)
@@ -131,6 +173,12 @@ func (s *RegistTestSuite) TestSupernodeRegister_constructRegisterRequest(c *chec
 	req = register.constructRegisterRequest(0)
 	c.Assert(req.Identifier, check.Equals, "")
 	c.Assert(req.Md5, check.Equals, cfg.Md5)
+
+	cfg.IDC = "hz"
+	cfg.Labels = map[string]string{"zone": "east"}
+	req = register.constructRegisterRequest(0)
+	c.Assert(req.IDC, check.Equals, "hz")
+	c.Assert(req.Labels, check.DeepEquals, map[string]string{"zone": "east"})
 }
 
 // ----------------------------------------------------------------------------
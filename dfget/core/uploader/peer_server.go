@@ -50,8 +50,9 @@ func newPeerServer(cfg *config.Config, port int) *peerServer {
 		finished: make(chan struct{}),
 		host:     cfg.RV.LocalIP,
 		port:     port,
-		api:      api.NewSupernodeAPI(),
+		api:      api.NewSupernodeAPIWithAuthToken(cfg.AuthToken),
 	}
+	s.preheatDownload = s.downloadByDfget
 
 	r := s.initRouter()
 	s.Server = &http.Server{
@@ -85,6 +86,12 @@ type peerServer struct {
 
 	// syncTaskMap stores the meta name of tasks on the host
 	syncTaskMap sync.Map
+
+	// preheatJobs stores the preheat jobs requested by supernode
+	preheatJobs sync.Map
+
+	// preheatDownload downloads the file of a preheat job
+	preheatDownload func(ctx context.Context, req *api.PreheatRequest) error
 }
 
 // taskConfig refers to some name about peer task.
@@ -118,6 +125,9 @@ func (ps *peerServer) initRouter() *mux.Router {
 	r.HandleFunc(config.LocalHTTPPathCheck+"{commonFile:.*}", ps.checkHandler).Methods("GET")
 	r.HandleFunc(config.LocalHTTPPathClient+"finish", ps.oneFinishHandler).Methods("GET")
 	r.HandleFunc(config.LocalHTTPPing, ps.pingHandler).Methods("GET")
+	r.HandleFunc(config.PeerHTTPPathPreheat, ps.preheatHandler).Methods("POST")
+	r.HandleFunc(config.PeerHTTPPathPreheat+"/{id}", ps.getPreheatHandler).Methods("GET")
+	r.HandleFunc(config.PeerHTTPPathVerify, ps.verifyHandler).Methods("POST")
 
 	return r
 }
@@ -381,6 +391,12 @@ func (ps *peerServer) uploadPiece(f *os.File, w http.ResponseWriter, up *uploadP
 	w.Header().Set(config.StrContentLength, strconv.FormatInt(up.length, 10))
 	sendHeader(w, http.StatusPartialContent)
 
+	return ps.writePiece(f, w, up, ps.rateLimiter)
+}
+
+// writePiece writes a piece of the file wrapped by meta data if padSize > 0,
+// and the writing is limited by the rateLimiter if it's not nil.
+func (ps *peerServer) writePiece(f *os.File, w io.Writer, up *uploadParam, rateLimiter *ratelimiter.RateLimiter) (e error) {
 	readLen := up.length - up.padSize
 	buf := make([]byte, 256*1024)
 
@@ -392,8 +408,8 @@ func (ps *peerServer) uploadPiece(f *os.File, w http.ResponseWriter, up *uploadP
 
 	f.Seek(up.start, 0)
 	r := io.LimitReader(f, readLen)
-	if ps.rateLimiter != nil {
-		lr := limitreader.NewLimitReaderWithLimiter(ps.rateLimiter, r, false)
+	if rateLimiter != nil {
+		lr := limitreader.NewLimitReaderWithLimiter(rateLimiter, r, false)
 		_, e = io.CopyBuffer(w, lr, buf)
 	} else {
 		_, e = io.CopyBuffer(w, r, buf)
@@ -97,6 +97,10 @@ func (pe *peerServerExecutor) StartPeerServerProcess(cfg *config.Config) (port i
 	if cfg.Verbose {
 		cmd.Args = append(cmd.Args, "--verbose")
 	}
+	// pass the auth token by the environment variable to keep it out of the process list
+	if cfg.AuthToken != "" {
+		cmd.Env = append(os.Environ(), config.AuthTokenEnv+"="+cfg.AuthToken)
+	}
 
 	var stdout io.ReadCloser
 	if stdout, err = cmd.StdoutPipe(); err != nil {
@@ -0,0 +1,292 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package uploader
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/dfget/config"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/api"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	preheatStatusRunning = "RUNNING"
+	preheatStatusSuccess = "SUCCESS"
+	preheatStatusFailed  = "FAILED"
+
+	// preheatCallSystem is the call system of the dfget processes
+	// which are started by the preheat jobs.
+	preheatCallSystem = "dragonfly_preheat"
+)
+
+// preheatJobExpireTime is how long the status of a finished preheat job is kept.
+var preheatJobExpireTime = time.Hour
+
+// preheatSignatureTTL is how long a signed preheat request is accepted,
+// which also tolerates the clock skew between supernode and the peer.
+const preheatSignatureTTL = 5 * time.Minute
+
+// maxPreheatRequestSize is the max size of the body of a preheat request.
+const maxPreheatRequestSize = 1 << 20
+
+// lookupHost resolves the hostnames of the supernodes configured.
+var lookupHost = net.LookupHost
+
+// preheatJob is a file downloading which is requested by supernode
+// to spread the file across the P2P network in advance.
+type preheatJob struct {
+	sync.Mutex
+	id       string
+	status   string
+	errorMsg string
+}
+
+func (j *preheatJob) finish(err error) {
+	j.Lock()
+	defer j.Unlock()
+	if err != nil {
+		j.status = preheatStatusFailed
+		j.errorMsg = err.Error()
+		return
+	}
+	j.status = preheatStatusSuccess
+}
+
+func (j *preheatJob) response() *api.PreheatResponse {
+	j.Lock()
+	defer j.Unlock()
+	return &api.PreheatResponse{
+		ID:       j.id,
+		Status:   j.status,
+		ErrorMsg: j.errorMsg,
+	}
+}
+
+// preheatHandler starts a preheat job which downloads the file in the background.
+// The job is only accepted from the supernodes configured with the request signed
+// by the auth token, and the file is always downloaded from the configured one,
+// which the auth token is sent to.
+func (ps *peerServer) preheatHandler(w http.ResponseWriter, r *http.Request) {
+	sendAlive(ps.cfg)
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxPreheatRequestSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := ps.verifyPreheat(r.Header, body); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	req := &api.PreheatRequest{}
+	if err := json.Unmarshal(body, req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.ID == "" || req.URL == "" {
+		http.Error(w, "invalid params", http.StatusBadRequest)
+		return
+	}
+	node := ps.configuredSupernode(req.Supernode, r.RemoteAddr)
+	if node == "" {
+		http.Error(w, fmt.Sprintf("the remote address %s isn't a supernode", r.RemoteAddr), http.StatusForbidden)
+		return
+	}
+	req.Supernode = node
+
+	job := &preheatJob{id: req.ID, status: preheatStatusRunning}
+	if _, loaded := ps.preheatJobs.LoadOrStore(req.ID, job); !loaded {
+		logrus.Infof("start preheat job %s of url %s from supernode %s", req.ID, req.URL, req.Supernode)
+		go ps.runPreheat(job, req)
+	}
+	sendSuccess(w)
+	fmt.Fprintf(w, "success")
+}
+
+// getPreheatHandler returns the status of a preheat job.
+func (ps *peerServer) getPreheatHandler(w http.ResponseWriter, r *http.Request) {
+	v, ok := ps.preheatJobs.Load(mux.Vars(r)["id"])
+	if !ok {
+		http.Error(w, "preheat job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set(config.StrContentType, "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(v.(*preheatJob).response())
+}
+
+func (ps *peerServer) runPreheat(job *preheatJob, req *api.PreheatRequest) {
+	// keep the peer server alive until the preheat job finishes
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if ps.cfg.RV.ServerAliveTime > 0 {
+		go func() {
+			ticker := time.NewTicker(ps.cfg.RV.ServerAliveTime / 2)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					sendAlive(ps.cfg)
+				}
+			}
+		}()
+	}
+
+	err := ps.preheatDownload(ctx, req)
+	if err != nil {
+		logrus.Errorf("failed to run preheat job %s of url %s: %v", req.ID, req.URL, err)
+	} else {
+		logrus.Infof("success to run preheat job %s of url %s", req.ID, req.URL)
+	}
+	job.finish(err)
+	time.AfterFunc(preheatJobExpireTime, func() {
+		ps.preheatJobs.Delete(req.ID)
+	})
+}
+
+// downloadByDfget downloads the file of a preheat job by starting a dfget process.
+// The dfget process registers the downloaded file to this peer server,
+// then the output file is removed and only the service file is kept for uploading.
+func (ps *peerServer) downloadByDfget(ctx context.Context, req *api.PreheatRequest) error {
+	dir := filepath.Join(ps.cfg.WorkHome, "preheat")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	output := filepath.Join(dir, req.ID)
+	defer os.Remove(output)
+
+	cmd := exec.CommandContext(ctx, os.Args[0],
+		"--url", req.URL,
+		"--output", output,
+		"--node", req.Supernode,
+		"--callsystem", preheatCallSystem,
+		"--home", ps.cfg.WorkHome,
+		"--ip", ps.cfg.RV.LocalIP,
+		"--notbs")
+	for _, h := range req.Headers {
+		cmd.Args = append(cmd.Args, "--header", h)
+	}
+	if req.Identifier != "" {
+		cmd.Args = append(cmd.Args, "--identifier", req.Identifier)
+	}
+	if req.Filter != "" {
+		cmd.Args = append(cmd.Args, "--filter", req.Filter)
+	}
+	if ps.cfg.AuthToken != "" {
+		cmd.Env = append(os.Environ(), config.AuthTokenEnv+"="+ps.cfg.AuthToken)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, out)
+	}
+	return nil
+}
+
+// verifyPreheat checks that the preheat request is signed with the auth token
+// within preheatSignatureTTL. The preheat jobs are refused without an auth token,
+// because anyone could make the peer download files then.
+func (ps *peerServer) verifyPreheat(header http.Header, body []byte) error {
+	if ps.cfg.AuthToken == "" {
+		return fmt.Errorf("the preheat jobs are refused without an auth token")
+	}
+	timestamp, err := strconv.ParseInt(header.Get(config.StrPreheatTimestamp), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid preheat timestamp")
+	}
+	if d := time.Since(time.Unix(timestamp, 0)); d > preheatSignatureTTL || d < -preheatSignatureTTL {
+		return fmt.Errorf("the preheat request is expired")
+	}
+	expected := api.SignPreheat(ps.cfg.AuthToken, timestamp, body)
+	if !hmac.Equal([]byte(header.Get(config.StrPreheatSignature)), []byte(expected)) {
+		return fmt.Errorf("invalid preheat signature")
+	}
+	return nil
+}
+
+// configuredSupernode returns the supernode in the config which remoteAddr comes from,
+// and the one equal to addr is preferred. An empty string is returned if remoteAddr
+// isn't any of the supernodes configured.
+func (ps *peerServer) configuredSupernode(addr, remoteAddr string) string {
+	var result string
+	for _, node := range ps.cfg.Nodes {
+		if !sameHost(node, remoteAddr) {
+			continue
+		}
+		if node == addr {
+			return node
+		}
+		if result == "" {
+			result = node
+		}
+	}
+	return result
+}
+
+// sameHost returns whether the host of addr equals to the host of remoteAddr,
+// the host of addr is resolved if it's a hostname.
+func sameHost(addr, remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	remoteHost, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		remoteHost = remoteAddr
+	}
+	if host == remoteHost {
+		return true
+	}
+
+	remoteIP := net.ParseIP(remoteHost)
+	if remoteIP == nil {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.Equal(remoteIP)
+	}
+	ips, err := lookupHost(host)
+	if err != nil {
+		logrus.Debugf("failed to resolve supernode %s: %v", host, err)
+		return false
+	}
+	for _, ip := range ips {
+		if remoteIP.Equal(net.ParseIP(ip)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/dfget/config"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/api"
+
+	"github.com/go-check/check"
+)
+
+func init() {
+	check.Suite(&PeerServerPreheatTestSuite{})
+}
+
+type PeerServerPreheatTestSuite struct {
+	workHome string
+}
+
+func (s *PeerServerPreheatTestSuite) SetUpSuite(c *check.C) {
+	s.workHome, _ = ioutil.TempDir("/tmp", "dfget-PeerServerPreheatTestSuite-")
+}
+
+func (s *PeerServerPreheatTestSuite) TearDownSuite(c *check.C) {
+	if s.workHome != "" {
+		os.RemoveAll(s.workHome)
+	}
+}
+
+func (s *PeerServerPreheatTestSuite) TestPreheatHandler(c *check.C) {
+	srv := newTestPeerServer(s.workHome)
+	srv.cfg.Nodes = []string{"10.0.0.1:8002", "127.0.0.1:8002"}
+	srv.cfg.AuthToken = "token"
+	var started int32
+	done := make(chan error)
+	srv.preheatDownload = func(ctx context.Context, req *api.PreheatRequest) error {
+		atomic.AddInt32(&started, 1)
+		c.Check(req.URL, check.Equals, "http://a.b/c")
+		// the file is downloaded from the configured supernode which sends the request
+		c.Check(req.Supernode, check.Equals, "127.0.0.1:8002")
+		return <-done
+	}
+
+	req := &api.PreheatRequest{ID: "1", URL: "http://a.b/c", Supernode: "10.0.0.1:8002"}
+	c.Assert(s.postPreheat(srv, req).Code, check.Equals, http.StatusOK)
+	// the same job is only started once
+	c.Assert(s.postPreheat(srv, req).Code, check.Equals, http.StatusOK)
+	c.Assert(s.getPreheat(c, srv, "1").Status, check.Equals, preheatStatusRunning)
+
+	done <- nil
+	s.waitPreheat(c, srv, "1", preheatStatusSuccess)
+	c.Assert(atomic.LoadInt32(&started), check.Equals, int32(1))
+
+	req.ID = "2"
+	c.Assert(s.postPreheat(srv, req).Code, check.Equals, http.StatusOK)
+	done <- fmt.Errorf("exit status 1")
+	resp := s.waitPreheat(c, srv, "2", preheatStatusFailed)
+	c.Assert(resp.ErrorMsg, check.Equals, "exit status 1")
+}
+
+func (s *PeerServerPreheatTestSuite) TestPreheatHandlerRejected(c *check.C) {
+	srv := newTestPeerServer(s.workHome)
+	srv.cfg.Nodes = []string{"10.0.0.1:8002"}
+	srv.cfg.AuthToken = "token"
+	srv.preheatDownload = func(ctx context.Context, req *api.PreheatRequest) error {
+		c.Errorf("unexpected preheat job %s", req.ID)
+		return nil
+	}
+
+	c.Assert(s.postPreheat(srv, &api.PreheatRequest{ID: "1", Supernode: "10.0.0.1:8002"}).Code,
+		check.Equals, http.StatusBadRequest)
+	// only the supernodes configured can start a preheat job
+	c.Assert(s.postPreheat(srv, &api.PreheatRequest{ID: "1", URL: "http://a.b/c", Supernode: "127.0.0.1:8002"}).Code,
+		check.Equals, http.StatusForbidden)
+	c.Assert(s.postPreheat(srv, &api.PreheatRequest{ID: "1", URL: "http://a.b/c", Supernode: "10.0.0.1:8002"}).Code,
+		check.Equals, http.StatusForbidden)
+
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, config.PeerHTTPPathPreheat+"/1", nil))
+	c.Assert(rr.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *PeerServerPreheatTestSuite) TestPreheatHandlerUnsigned(c *check.C) {
+	srv := newTestPeerServer(s.workHome)
+	srv.cfg.Nodes = []string{"127.0.0.1:8002"}
+	srv.preheatDownload = func(ctx context.Context, req *api.PreheatRequest) error {
+		c.Errorf("unexpected preheat job %s", req.ID)
+		return nil
+	}
+	req := &api.PreheatRequest{ID: "1", URL: "http://a.b/c", Supernode: "127.0.0.1:8002"}
+
+	// the preheat jobs are refused without an auth token
+	c.Assert(s.postPreheat(srv, req).Code, check.Equals, http.StatusForbidden)
+
+	srv.cfg.AuthToken = "token"
+	now := time.Now().Unix()
+	body, _ := json.Marshal(req)
+	for _, headers := range []map[string]string{
+		{},
+		{config.StrPreheatTimestamp: strconv.FormatInt(now, 10), config.StrPreheatSignature: api.SignPreheat("other", now, body)},
+		{config.StrPreheatTimestamp: strconv.FormatInt(now+1, 10), config.StrPreheatSignature: api.SignPreheat("token", now, body)},
+		{config.StrPreheatTimestamp: strconv.FormatInt(now-3600, 10), config.StrPreheatSignature: api.SignPreheat("token", now-3600, body)},
+	} {
+		c.Check(s.post(srv, body, headers).Code, check.Equals, http.StatusForbidden, check.Commentf("headers: %v", headers))
+	}
+}
+
+func (s *PeerServerPreheatTestSuite) TestSameHost(c *check.C) {
+	originLookupHost := lookupHost
+	defer func() {
+		lookupHost = originLookupHost
+	}()
+	lookupHost = func(host string) ([]string, error) {
+		if host == "supernode" {
+			return []string{"10.0.0.1", "127.0.0.1"}, nil
+		}
+		return nil, fmt.Errorf("no such host: %s", host)
+	}
+
+	c.Assert(sameHost("127.0.0.1:8002", "127.0.0.1:40000"), check.Equals, true)
+	c.Assert(sameHost("127.0.0.1", "127.0.0.1:40000"), check.Equals, true)
+	c.Assert(sameHost("10.0.0.1:8002", "127.0.0.1:40000"), check.Equals, false)
+	// the hostnames are resolved
+	c.Assert(sameHost("supernode:8002", "127.0.0.1:40000"), check.Equals, true)
+	c.Assert(sameHost("supernode:8002", "10.0.0.2:40000"), check.Equals, false)
+	c.Assert(sameHost("unknown:8002", "127.0.0.1:40000"), check.Equals, false)
+}
+
+// postPreheat posts the preheat request signed with the auth token of srv.
+func (s *PeerServerPreheatTestSuite) postPreheat(srv *peerServer, req *api.PreheatRequest) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(req)
+	now := time.Now().Unix()
+	return s.post(srv, body, map[string]string{
+		config.StrPreheatTimestamp: strconv.FormatInt(now, 10),
+		config.StrPreheatSignature: api.SignPreheat(srv.cfg.AuthToken, now, body),
+	})
+}
+
+func (s *PeerServerPreheatTestSuite) post(srv *peerServer, body []byte, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, config.PeerHTTPPathPreheat, bytes.NewReader(body))
+	r.RemoteAddr = "127.0.0.1:40000"
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, r)
+	return rr
+}
+
+func (s *PeerServerPreheatTestSuite) getPreheat(c *check.C, srv *peerServer, id string) *api.PreheatResponse {
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, config.PeerHTTPPathPreheat+"/"+id, nil))
+	c.Assert(rr.Code, check.Equals, http.StatusOK)
+	resp := &api.PreheatResponse{}
+	c.Assert(json.Unmarshal(rr.Body.Bytes(), resp), check.IsNil)
+	return resp
+}
+
+func (s *PeerServerPreheatTestSuite) waitPreheat(c *check.C, srv *peerServer, id, status string) *api.PreheatResponse {
+	for i := 0; i < 100; i++ {
+		if resp := s.getPreheat(c, srv, id); resp.Status == status {
+			return resp
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatalf("preheat job %s doesn't become %s", id, status)
+	return nil
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package uploader
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	apiTypes "github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/dfget/config"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/api"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/helper"
+
+	"github.com/sirupsen/logrus"
+)
+
+// verifyHandler verifies a piece reported as corrupted by the other peers.
+// The piece is read in the same way as uploading it, and the whole task file
+// is deleted if the MD5 of the piece doesn't match, so that the corrupted copy
+// is no longer served. The request is only accepted from the supernodes.
+func (ps *peerServer) verifyHandler(w http.ResponseWriter, r *http.Request) {
+	sendAlive(ps.cfg)
+
+	req := &api.VerifyPieceRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.TaskFileName == "" || req.PieceMd5 == "" {
+		http.Error(w, "invalid params", http.StatusBadRequest)
+		return
+	}
+	up, err := parseParams("bytes="+req.PieceRange, strconv.Itoa(req.PieceNum),
+		strconv.FormatInt(int64(req.PieceSize), 10))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tc := ps.getTaskConfig(req.TaskFileName)
+	if tc == nil {
+		http.Error(w, fmt.Sprintf("task file %s not found", req.TaskFileName), http.StatusNotFound)
+		return
+	}
+	if !ps.fromSupernode(tc, r.RemoteAddr) {
+		http.Error(w, fmt.Sprintf("the remote address %s isn't a supernode", r.RemoteAddr), http.StatusForbidden)
+		return
+	}
+
+	realMd5, err := ps.pieceMd5(req.TaskFileName, req.CDNSource, up)
+	if err != nil {
+		rangeErrorResponse(w, err)
+		logrus.Errorf("failed to verify range(%s) of file(%s): %v", req.PieceRange, req.TaskFileName, err)
+		return
+	}
+
+	resp := &api.VerifyPieceResponse{RealMd5: realMd5}
+	if realMd5 != req.PieceMd5 {
+		resp.Corrupted = true
+		serviceFile := helper.GetServiceFile(req.TaskFileName, tc.dataDir)
+		os.Remove(serviceFile)
+		ps.syncTaskMap.Delete(req.TaskFileName)
+		logrus.Warnf("piece range(%s) of task %s is corrupted, expected md5:%s real:%s, remove file:%s",
+			req.PieceRange, tc.taskID, req.PieceMd5, realMd5, serviceFile)
+	}
+
+	w.Header().Set(config.StrContentType, "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// pieceMd5 calculates the MD5 of a piece as it's uploaded to the other peers.
+func (ps *peerServer) pieceMd5(taskFileName, cdnSource string, up *uploadParam) (string, error) {
+	f, size, err := ps.getTaskFile(taskFileName)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if err := amendRange(size, cdnSource != string(apiTypes.CdnSourceSource), up); err != nil {
+		return "", err
+	}
+	h := md5.New()
+	if err := ps.writePiece(f, h, up, nil); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (ps *peerServer) getTaskConfig(taskFileName string) *taskConfig {
+	v, ok := ps.syncTaskMap.Load(taskFileName)
+	if !ok {
+		return nil
+	}
+	tc, _ := v.(*taskConfig)
+	return tc
+}
+
+// fromSupernode returns whether the remoteAddr is the supernode of the task
+// or one of the supernodes configured.
+func (ps *peerServer) fromSupernode(tc *taskConfig, remoteAddr string) bool {
+	if tc.superNode != "" && sameHost(tc.superNode, remoteAddr) {
+		return true
+	}
+	for _, node := range ps.cfg.Nodes {
+		if sameHost(node, remoteAddr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package uploader
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"github.com/dragonflyoss/Dragonfly/dfget/config"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/api"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/helper"
+
+	"github.com/go-check/check"
+)
+
+func init() {
+	check.Suite(&PeerServerVerifyTestSuite{})
+}
+
+type PeerServerVerifyTestSuite struct {
+	workHome string
+	srv      *peerServer
+}
+
+func (s *PeerServerVerifyTestSuite) SetUpTest(c *check.C) {
+	s.workHome, _ = ioutil.TempDir("/tmp", "dfget-PeerServerVerifyTestSuite-")
+	s.srv = newTestPeerServer(s.workHome)
+	s.srv.cfg.Nodes = []string{"127.0.0.1:8002"}
+	initHelper(s.srv, commonFile, s.workHome, commonFileContent)
+}
+
+func (s *PeerServerVerifyTestSuite) TearDownTest(c *check.C) {
+	if s.workHome != "" {
+		os.RemoveAll(s.workHome)
+	}
+}
+
+func (s *PeerServerVerifyTestSuite) verify(req *api.VerifyPieceRequest, remoteAddr string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(req)
+	r := httptest.NewRequest(http.MethodPost, config.PeerHTTPPathVerify, bytes.NewReader(body))
+	r.RemoteAddr = remoteAddr
+	rr := httptest.NewRecorder()
+	s.srv.Handler.ServeHTTP(rr, r)
+	return rr
+}
+
+func (s *PeerServerVerifyTestSuite) TestVerifyHandler(c *check.C) {
+	sum := md5.Sum([]byte(pc(commonFileContent)))
+	req := &api.VerifyPieceRequest{
+		TaskFileName: commonFile,
+		PieceRange:   "0-14",
+		PieceNum:     0,
+		PieceSize:    int32(defaultPieceSize),
+		PieceMd5:     hex.EncodeToString(sum[:]),
+	}
+
+	// only the supernodes can verify the pieces
+	c.Check(s.verify(req, "127.0.0.2:40000").Code, check.Equals, http.StatusForbidden)
+
+	rr := s.verify(req, "127.0.0.1:40000")
+	c.Assert(rr.Code, check.Equals, http.StatusOK)
+	resp := &api.VerifyPieceResponse{}
+	c.Assert(json.Unmarshal(rr.Body.Bytes(), resp), check.IsNil)
+	c.Check(resp.Corrupted, check.Equals, false)
+	c.Check(resp.RealMd5, check.Equals, req.PieceMd5)
+
+	// the corrupted copy is deleted
+	req.PieceMd5 = "foo"
+	rr = s.verify(req, "127.0.0.1:40000")
+	c.Assert(rr.Code, check.Equals, http.StatusOK)
+	c.Assert(json.Unmarshal(rr.Body.Bytes(), resp), check.IsNil)
+	c.Check(resp.Corrupted, check.Equals, true)
+	_, err := os.Stat(helper.GetServiceFile(commonFile, s.workHome))
+	c.Check(os.IsNotExist(err), check.Equals, true)
+	c.Check(s.verify(req, "127.0.0.1:40000").Code, check.Equals, http.StatusNotFound)
+
+	req.PieceRange = "x"
+	c.Check(s.verify(req, "127.0.0.1:40000").Code, check.Equals, http.StatusBadRequest)
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/dragonflyoss/Dragonfly/dfget/config"
@@ -36,6 +37,11 @@ const (
 	deleteEv = "delete"
 )
 
+// MetricAvailable is the key of SupernodeMetrics which reports whether
+// the supernode is available. The hashCirclerLocator disables the supernode
+// when false is reported, and enables it again when true is reported.
+const MetricAvailable = "available"
+
 type SuperNodeEvent struct {
 	evType string
 	node   string
@@ -63,6 +69,12 @@ type hashCirclerLocator struct {
 	groupName string
 	group     *SupernodeGroup
 
+	// idx is the index of the current selected supernode in the group.
+	idx int
+	// disabled records the supernodes which are disabled by the events.
+	disabled map[string]bool
+	mutex    sync.RWMutex
+
 	// evQueue will puts/polls SuperNodeEvent to disable/enable supernode.
 	evQueue queue.Queue
 }
@@ -104,6 +116,8 @@ func NewHashCirclerLocator(groupName string, nodes []string, eventQueue queue.Qu
 
 	h := &hashCirclerLocator{
 		hc:        hc,
+		idx:       -1,
+		disabled:  make(map[string]bool),
 		evQueue:   eventQueue,
 		groupName: groupName,
 		group:     group,
@@ -114,13 +128,27 @@ func NewHashCirclerLocator(groupName string, nodes []string, eventQueue queue.Qu
 	return h, nil
 }
 
+// Get returns the current selected supernode, it should be idempotent.
+// It should return nil before first calling the Next method.
 func (h *hashCirclerLocator) Get() *Supernode {
-	// not implementation
-	return nil
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return h.group.GetNode(h.idx)
 }
 
+// Next chooses the next enabled supernode in order, the disabled ones are skipped.
 func (h *hashCirclerLocator) Next() *Supernode {
-	// not implementation
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for h.idx < len(h.group.Nodes) {
+		h.idx++
+		node := h.group.GetNode(h.idx)
+		if node != nil && !h.disabled[node.String()] {
+			return node
+		}
+	}
 	return nil
 }
 
@@ -156,14 +184,46 @@ func (h *hashCirclerLocator) Size() int {
 	return len(h.group.Nodes)
 }
 
+// Report disables or enables the supernode according to the reported
+// value of MetricAvailable.
 func (h *hashCirclerLocator) Report(node string, metrics *SupernodeMetrics) {
-	return
+	if metrics == nil {
+		return
+	}
+	available, ok := metrics.Metrics[MetricAvailable].(bool)
+	if !ok {
+		return
+	}
+
+	if available {
+		h.evQueue.Put(NewEnableEvent(node))
+	} else {
+		h.evQueue.Put(NewDisableEvent(node))
+	}
 }
 
+// Refresh resets the current selected supernode.
 func (h *hashCirclerLocator) Refresh() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.idx = -1
 	return true
 }
 
+func (h *hashCirclerLocator) String() string {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	nodes := []string{}
+	for _, node := range h.group.Nodes {
+		if !h.disabled[node.String()] {
+			nodes = append(nodes, node.String())
+		}
+	}
+	return h.groupName + ":" + fmt.Sprintf("%v", nodes)
+}
+
 func (h *hashCirclerLocator) eventLoop(ctx context.Context) {
 	for {
 		select {
@@ -179,11 +239,16 @@ func (h *hashCirclerLocator) eventLoop(ctx context.Context) {
 }
 
 func (h *hashCirclerLocator) handleEvent(ev *SuperNodeEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
 	switch ev.evType {
 	case addEv:
 		h.hc.Add(ev.node)
+		delete(h.disabled, ev.node)
 	case deleteEv:
 		h.hc.Delete(ev.node)
+		h.disabled[ev.node] = true
 	default:
 	}
 
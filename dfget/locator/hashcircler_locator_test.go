@@ -40,7 +40,8 @@ func (s *hashCirclerLocatorTestSuite) TestHashCirclerLocator(c *check.C) {
 	c.Assert(err, check.IsNil)
 
 	c.Assert(hl.Get(), check.IsNil)
-	c.Assert(hl.Next(), check.IsNil)
+	c.Assert(hl.Next().String(), check.Equals, nodes[0])
+	c.Assert(hl.Get().String(), check.Equals, nodes[0])
 	c.Assert(hl.GetGroup("nonexistentName"), check.IsNil)
 	c.Assert(hl.GetGroup(testGroupName1).Name, check.Equals, testGroupName1)
 	c.Assert(hl.Size(), check.Equals, len(nodes))
@@ -127,3 +128,26 @@ func (s *hashCirclerLocatorTestSuite) TestHashCirclerLocator(c *check.C) {
 		c.Assert(originSp[i], check.Equals, sp.String())
 	}
 }
+
+func (s *hashCirclerLocatorTestSuite) TestHashCirclerLocator_Report(c *check.C) {
+	nodes := []string{"1.1.1.1:8002", "2.2.2.2:8002", "3.3.3.3:8002"}
+	hl, err := NewHashCirclerLocator(testGroupName1, nodes, queue.NewQueue(0))
+	c.Assert(err, check.IsNil)
+
+	// the unavailable supernode is skipped by Next and Select
+	hl.Report(nodes[1], &SupernodeMetrics{Metrics: map[string]interface{}{MetricAvailable: false}})
+	time.Sleep(time.Second * 2)
+	c.Assert(hl.Next().String(), check.Equals, nodes[0])
+	c.Assert(hl.Next().String(), check.Equals, nodes[2])
+	c.Assert(hl.Next(), check.IsNil)
+	for _, k := range []string{"x", "y", "z", "a", "b", "c"} {
+		c.Assert(hl.Select(k).String(), check.Not(check.Equals), nodes[1])
+	}
+
+	// it's selected again after reported as available
+	hl.Report(nodes[1], &SupernodeMetrics{Metrics: map[string]interface{}{MetricAvailable: true}})
+	time.Sleep(time.Second * 2)
+	c.Assert(hl.Refresh(), check.Equals, true)
+	c.Assert(hl.Next().String(), check.Equals, nodes[0])
+	c.Assert(hl.Next().String(), check.Equals, nodes[1])
+}
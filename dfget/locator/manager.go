@@ -20,6 +20,7 @@ import (
 	"sync"
 
 	"github.com/dragonflyoss/Dragonfly/dfget/config"
+	"github.com/dragonflyoss/Dragonfly/pkg/queue"
 )
 
 const (
@@ -41,10 +42,29 @@ var DefaultBuilder Builder = func(cfg *config.Config) SupernodeLocator {
 	if cfg == nil || len(cfg.Nodes) == 0 {
 		return NewStaticLocator(GroupDefaultName, config.GetDefaultSupernodesValue())
 	}
+	if cfg.Cluster {
+		if locator, err := newClusterLocator(cfg.Nodes); err == nil {
+			return locator
+		}
+	}
 	locator, _ := NewStaticLocatorFromStr(GroupConfigName, cfg.Nodes)
 	return locator
 }
 
+// newClusterLocator creates a hashCirclerLocator for the supernodes which form a cluster,
+// the weights of the nodes are ignored.
+func newClusterLocator(nodes []string) (SupernodeLocator, error) {
+	nodeWeight, err := config.ParseNodesSlice(nodes)
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]string, 0, len(nodeWeight))
+	for _, node := range nodeWeight {
+		addresses = append(addresses, node.Node)
+	}
+	return NewHashCirclerLocator(GroupConfigName, addresses, queue.NewQueue(0))
+}
+
 // Builder defines the constructor of SupernodeLocator.
 type Builder func(cfg *config.Config) SupernodeLocator
 
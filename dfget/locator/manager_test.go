@@ -88,3 +88,17 @@ func (s *LocatorTestSuite) Test_CreateLocator() {
 		s.Equal(c.expected, got)
 	}
 }
+
+func (s *LocatorTestSuite) Test_CreateClusterLocator() {
+	cfg := &config.Config{
+		Nodes: []string{"127.0.0.2=2", "127.0.0.1:8003"},
+	}
+	cfg.Cluster = true
+
+	got := CreateLocator(cfg)
+	s.IsType(&hashCirclerLocator{}, got)
+	s.Equal(2, got.Size())
+	s.Equal("127.0.0.1:8003", got.Next().String())
+	s.Equal("127.0.0.2:8002", got.Next().String())
+	s.Nil(got.Next())
+}
@@ -90,9 +90,32 @@ type PullPieceTaskResponseContinueData struct {
 	PeerPort  int    `json:"peerPort"`
 	Path      string `json:"path"`
 	DownLink  int    `json:"downLink"`
+
+	// Alternates are the other peers which also have the piece,
+	// dfget can send hedged requests to them when the peer above is too slow.
+	Alternates []*PieceSource `json:"alternates,omitempty"`
 }
 
 func (data *PullPieceTaskResponseContinueData) String() string {
 	b, _ := json.Marshal(data)
 	return string(b)
 }
+
+// WithSource returns a copy of the data whose piece is downloaded from the given source.
+func (data *PullPieceTaskResponseContinueData) WithSource(source *PieceSource) *PullPieceTaskResponseContinueData {
+	d := *data
+	d.Cid = source.Cid
+	d.PeerIP = source.PeerIP
+	d.PeerPort = source.PeerPort
+	d.Path = source.Path
+	d.Alternates = nil
+	return &d
+}
+
+// PieceSource describes where to download a piece from.
+type PieceSource struct {
+	Cid      string `json:"cid"`
+	PeerIP   string `json:"peerIp"`
+	PeerPort int    `json:"peerPort"`
+	Path     string `json:"path"`
+}
@@ -43,6 +43,24 @@ type RegisterRequest struct {
 	FileLength  int64    `json:"fileLength,omitempty"`
 	AsSeed      bool     `json:"asSeed,omitempty"`
 	Pattern     string   `json:"pattern"`
+
+	// IDC and Labels describe the host, the supernode selects
+	// the target peers of a preheat task by them.
+	IDC    string            `json:"idc,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// UploadRate is the bandwidth in bytes per second which the peer server
+	// serves the other peers with, the supernode prefers the peers with more
+	// bandwidth as seed nodes.
+	UploadRate int64 `json:"uploadRate,omitempty"`
+
+	// Cluster tells the supernode that dfget follows the redirection
+	// to the supernode which owns the task.
+	Cluster bool `json:"cluster,omitempty"`
+
+	// Tenant and TenantToken are sent to the supernode by headers.
+	Tenant      string `json:"-"`
+	TenantToken string `json:"-"`
 }
 
 func (r *RegisterRequest) String() string {
@@ -47,4 +47,8 @@ type RegisterResponseData struct {
 
 	// in seed pattern, if as seed, SeedTaskID is the taskID of seed file.
 	SeedTaskID string `json:"seedTaskID"`
+
+	// in cluster mode, Owner is the address of the supernode which owns the task
+	// when the code is CodeTaskRedirect.
+	Owner string `json:"owner,omitempty"`
 }
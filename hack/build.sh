@@ -7,6 +7,7 @@ set -o pipefail
 DFDAEMON_BINARY_NAME=dfdaemon
 DFGET_BINARY_NAME=dfget
 SUPERNODE_BINARY_NAME=supernode
+DFCTL_BINARY_NAME=dfctl
 PKG=github.com/dragonflyoss/Dragonfly
 BUILD_IMAGE=golang:1.13.15
 VERSION=$(git describe --tags "$(git rev-list --tags --max-count=1)")
@@ -49,6 +50,10 @@ build-supernode-local() {
     build-local ${SUPERNODE_BINARY_NAME} supernode
 }
 
+build-dfctl-local() {
+    build-local ${DFCTL_BINARY_NAME} dfctl
+}
+
 build-docker() {
     cd "${BUILD_SOURCE_HOME}" || return
     docker run                                                            \
@@ -82,6 +87,10 @@ build-supernode-docker() {
     build-docker ${SUPERNODE_BINARY_NAME} supernode
 }
 
+build-dfctl-docker() {
+    build-docker ${DFCTL_BINARY_NAME} dfctl
+}
+
 main() {
     create-dirs
     if [[ "1" == "${USE_DOCKER}" ]]
@@ -97,10 +106,14 @@ main() {
             supernode)
                 build-supernode-docker
             ;;
+            dfctl)
+                build-dfctl-docker
+            ;;
             *)
                 build-dfget-docker
                 build-dfdaemon-docker
                 build-supernode-docker
+                build-dfctl-docker
             ;;
         esac
     else
@@ -115,10 +128,14 @@ main() {
             supernode)
                 build-supernode-local
             ;;
+            dfctl)
+                build-dfctl-local
+            ;;
             *)
                 build-dfget-local
                 build-dfdaemon-local
                 build-supernode-local
+                build-dfctl-local
             ;;
         esac
     fi
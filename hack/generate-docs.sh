@@ -15,10 +15,12 @@ generate-cli-docs(){
     DFGET_BIN_PATH=../"${BUILD_PATH}"/dfget
     DFDAEMON_BIN_PATH=../"${BUILD_PATH}"/dfdaemon
     SUPERNODE_BIN_PATH=../"${BUILD_PATH}"/supernode
+    DFCTL_BIN_PATH=../"${BUILD_PATH}"/dfctl
 
     ${DFGET_BIN_PATH} gen-doc -p "${CLI_DOCS_DIR}" || return
     ${DFDAEMON_BIN_PATH} gen-doc -p "${CLI_DOCS_DIR}" || return
     ${SUPERNODE_BIN_PATH} gen-doc -p "${CLI_DOCS_DIR}" || return
+    ${DFCTL_BIN_PATH} gen-doc -p "${CLI_DOCS_DIR}" || return
     echo "Generate: CLI docs in ${CLI_DOCS_DIR}" 
 }
 
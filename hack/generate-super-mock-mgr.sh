@@ -17,7 +17,7 @@ goimports -h >/dev/null 2>&1 || go get golang.org/x/tools/cmd/goimports
 
 
 # generate mock files for supernode mgr interfaces.
-MGR_ARRAY=("cdn_mgr" "dfget_task_mgr" "peer_mgr" "progress_mgr" "scheduler_mgr")
+MGR_ARRAY=("cdn_mgr" "dfget_task_mgr" "peer_mgr" "progress_mgr" "scheduler_mgr" "task_mgr")
 for name in "${MGR_ARRAY[@]}"
 do
 	mockgen -destination "./supernode/daemon/mgr/mock/mock_$name.go" -source "supernode/daemon/mgr/$name.go" -package mock
@@ -37,6 +37,8 @@ func init() {
 	cmmap[CodeURLNotReachable] = "url is not reachable"
 	cmmap[CodeNeedAuth] = "need auth"
 	cmmap[CodeWaitAuth] = "wait auth"
+	cmmap[CodeTaskRedirect] = "task is owned by another supernode"
+	cmmap[CodeTenantQuotaExceeded] = "tenant quota exceeded"
 }
 
 // GetMsgByCode gets the description of the code.
@@ -74,6 +76,14 @@ const (
 	CodeSourceError     = 610
 	CodeGetPieceReport  = 611
 	CodeGetPeerDown     = 612
+
+	// CodeTaskRedirect represents that the task is owned by another supernode
+	// in the cluster, and dfget should register to the owner instead.
+	CodeTaskRedirect = 613
+
+	// CodeTenantQuotaExceeded represents that the tenant has exceeded its quota
+	// and can't create new tasks on the supernode for now.
+	CodeTenantQuotaExceeded = 614
 )
 
 /* the code of task result that dfget will report to supernode */
@@ -96,4 +106,15 @@ const (
 const (
 	ClientErrorFileNotExist    = "FILE_NOT_EXIST"
 	ClientErrorFileMd5NotMatch = "FILE_MD5_NOT_MATCH"
+
+	// The network errors or the HTTP 5xx errors when downloading from the other peer.
+	ClientErrorTimeout           = "TIMEOUT"
+	ClientErrorConnectionRefused = "CONNECTION_REFUSED"
+	ClientErrorShortRead         = "SHORT_READ"
+	ClientErrorServerError       = "SERVER_ERROR"
+
+	// ClientErrorPieceSlow is reported when a hedged request to another peer
+	// completed first and the request to the scheduled peer was cancelled.
+	// It lowers the health of the scheduled peer but isn't regarded as a failure.
+	ClientErrorPieceSlow = "PIECE_SLOW"
 )
@@ -61,6 +61,7 @@ const (
 	codeURLNotReachable
 	codeTaskIDDuplicate
 	codeAuthenticationRequired
+	codeTenantQuotaExceeded
 )
 
 // DfError represents a Dragonfly error.
@@ -45,6 +45,9 @@ var (
 
 	// ErrAuthenticationRequired represents the authentication is required.
 	ErrAuthenticationRequired = DfError{codeAuthenticationRequired, "authentication required"}
+
+	// ErrTenantQuotaExceeded represents the quota of the tenant is exceeded.
+	ErrTenantQuotaExceeded = DfError{codeTenantQuotaExceeded, "tenant quota exceeded"}
 )
 
 // IsSystemError checks the error is a system error or not.
@@ -91,3 +94,8 @@ func IsTaskIDDuplicate(err error) bool {
 func IsAuthenticationRequired(err error) bool {
 	return checkError(err, codeAuthenticationRequired)
 }
+
+// IsTenantQuotaExceeded checks the error is a TenantQuotaExceeded error or not.
+func IsTenantQuotaExceeded(err error) bool {
+	return checkError(err, codeTenantQuotaExceeded)
+}
@@ -88,3 +88,10 @@ func (suite *SupernodeErrorTestSuite) TestIsAuthenticationRequired(c *check.C) {
 	c.Assert(IsAuthenticationRequired(*err1), check.Equals, true)
 	c.Assert(IsAuthenticationRequired(*err2), check.Equals, false)
 }
+
+func (suite *SupernodeErrorTestSuite) TestIsTenantQuotaExceeded(c *check.C) {
+	err1 := New(15, "tenant quota exceeded")
+	err2 := New(0, "test")
+	c.Assert(IsTenantQuotaExceeded(*err1), check.Equals, true)
+	c.Assert(IsTenantQuotaExceeded(*err2), check.Equals, false)
+}
@@ -60,6 +60,26 @@ func (f *Fsize) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return nil
 }
 
+// String implements the pflag.Value interface.
+func (f Fsize) String() string {
+	return FsizeToString(f)
+}
+
+// Set implements the pflag.Value interface.
+func (f *Fsize) Set(s string) error {
+	fsize, err := StringToFSize(s)
+	if err != nil {
+		return err
+	}
+	*f = fsize
+	return nil
+}
+
+// Type implements the pflag.Value interface.
+func (f *Fsize) Type() string {
+	return "size"
+}
+
 // FsizeToString parses a Fsize value into string.
 func FsizeToString(fsize Fsize) string {
 	var (
@@ -49,6 +49,16 @@ func (suite *FsizeTestSuite) TestFsizeToString(c *check.C) {
 	}
 }
 
+func (suite *FsizeTestSuite) TestFsizeSet(c *check.C) {
+	var f Fsize
+	c.Assert(f.Set("64MB"), check.IsNil)
+	c.Assert(f, check.Equals, 64*MB)
+	c.Assert(f.String(), check.Equals, "64MB")
+
+	c.Assert(f.Set("-1"), check.NotNil)
+	c.Assert(f, check.Equals, 64*MB)
+}
+
 func (suite *FsizeTestSuite) TestStringToFSize(c *check.C) {
 	var cases = []struct {
 		fsizeStr      string
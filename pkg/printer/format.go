@@ -0,0 +1,107 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+)
+
+// The formats supported by Fprint.
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+)
+
+// Table is the tabular view of an object which is printed in the table format.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// ValidateFormat returns an error if the format is not supported by Fprint.
+func ValidateFormat(format string) error {
+	switch format {
+	case FormatTable, FormatJSON, FormatYAML:
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q, must be one of [%s %s %s]",
+		format, FormatTable, FormatJSON, FormatYAML)
+}
+
+// Fprint writes obj to w in the specified format.
+// The table is printed in the table format, and obj is printed as yaml
+// in the table format if table is nil.
+//
+// The keys in yaml are the same as the ones in json, so the objects
+// generated by swagger can be printed in yaml as well.
+func Fprint(w io.Writer, format string, obj interface{}, table *Table) error {
+	switch format {
+	case FormatTable:
+		if table == nil {
+			return printYAML(w, obj)
+		}
+		return printTable(w, table)
+	case FormatJSON:
+		b, err := json.MarshalIndent(obj, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	case FormatYAML:
+		return printYAML(w, obj)
+	}
+	return ValidateFormat(format)
+}
+
+func printTable(w io.Writer, table *Table) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	if len(table.Header) > 0 {
+		fmt.Fprintln(tw, strings.Join(table.Header, "\t"))
+	}
+	for _, row := range table.Rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func printYAML(w io.Writer, obj interface{}) error {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	// json is a subset of yaml, so decode the json to print the keys of json in yaml.
+	var v interface{}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package printer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-check/check"
+)
+
+func Test(t *testing.T) {
+	check.TestingT(t)
+}
+
+type PrinterSuite struct{}
+
+func init() {
+	check.Suite(&PrinterSuite{})
+}
+
+type object struct {
+	Name  string `json:"name"`
+	Count int    `json:"count,omitempty"`
+}
+
+func (suite *PrinterSuite) TestFprint(c *check.C) {
+	objs := []*object{{Name: "foo", Count: 1}, {Name: "barbaz"}}
+	table := &Table{
+		Header: []string{"NAME", "COUNT"},
+		Rows:   [][]string{{"foo", "1"}, {"barbaz", "0"}},
+	}
+
+	var cases = []struct {
+		format   string
+		table    *Table
+		expected string
+	}{
+		{FormatTable, table, "NAME     COUNT\nfoo      1\nbarbaz   0\n"},
+		{FormatTable, nil, "- count: 1\n  name: foo\n- name: barbaz\n"},
+		{FormatYAML, table, "- count: 1\n  name: foo\n- name: barbaz\n"},
+		{FormatJSON, table, "[\n  {\n    \"name\": \"foo\",\n    \"count\": 1\n  },\n  {\n    \"name\": \"barbaz\"\n  }\n]\n"},
+	}
+
+	for _, v := range cases {
+		buf := &bytes.Buffer{}
+		c.Assert(Fprint(buf, v.format, objs, v.table), check.IsNil)
+		c.Check(buf.String(), check.Equals, v.expected, check.Commentf("format: %s", v.format))
+	}
+
+	c.Check(Fprint(&bytes.Buffer{}, "xml", objs, nil), check.NotNil)
+}
+
+func (suite *PrinterSuite) TestValidateFormat(c *check.C) {
+	c.Check(ValidateFormat(FormatTable), check.IsNil)
+	c.Check(ValidateFormat(FormatJSON), check.IsNil)
+	c.Check(ValidateFormat(FormatYAML), check.IsNil)
+	c.Check(ValidateFormat("xml"), check.NotNil)
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package timeutils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-check/check"
+)
+
+func Test(t *testing.T) {
+	check.TestingT(t)
+}
+
+type CronSuite struct{}
+
+func init() {
+	check.Suite(&CronSuite{})
+}
+
+func (suite *CronSuite) TestCronNext(c *check.C) {
+	// 2020-01-15 is a Wednesday
+	now := time.Date(2020, 1, 15, 10, 30, 20, 0, time.UTC)
+	var cases = []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2020, 1, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2020, 1, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2020, 1, 16, 2, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2020, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2020, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 9 * * 1-5", time.Date(2020, 1, 16, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2020, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2020, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 8-18/5 * * *", time.Date(2020, 1, 15, 13, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// the day matches either the day of month or the day of week if both are restricted
+		{"0 0 20 * 5", time.Date(2020, 1, 17, 0, 0, 0, 0, time.UTC)},
+		{"5,10 3 1 3 *", time.Date(2020, 3, 1, 3, 5, 0, 0, time.UTC)},
+		{"@every 90m", now.Add(90 * time.Minute)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, v := range cases {
+		s, err := ParseCron(v.spec)
+		c.Assert(err, check.IsNil, check.Commentf("%s", v.spec))
+		c.Check(s.Next(now).Equal(v.next), check.Equals, true, check.Commentf("%s: %v", v.spec, s.Next(now)))
+	}
+}
+
+func (suite *CronSuite) TestParseCronInvalid(c *check.C) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@every",
+		"@every 1ms",
+		"@never",
+	} {
+		_, err := ParseCron(spec)
+		c.Check(err, check.NotNil, check.Commentf("%s", spec))
+	}
+}
@@ -0,0 +1,351 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxQueueSize is the max number of the ended spans waiting to be exported,
+	// the spans are dropped if the queue is full.
+	maxQueueSize = 2048
+	// maxBatchSize is the max number of the spans exported at a time.
+	maxBatchSize = 512
+	// batchTimeout is the max time a span waits to be exported.
+	batchTimeout = 5 * time.Second
+	// exportTimeout is the timeout to send the spans to the collector.
+	exportTimeout = 10 * time.Second
+)
+
+// exporter sends the encoded OTLP request to the backend.
+type exporter interface {
+	export(data []byte) error
+	close() error
+}
+
+// batchProcessor exports the ended spans in batches in background.
+type batchProcessor struct {
+	serviceName string
+	exporters   []exporter
+	queue       chan *Span
+	stop        chan struct{}
+	done        chan struct{}
+	once        sync.Once
+}
+
+func newBatchProcessor(serviceName string, exporters []exporter) *batchProcessor {
+	bp := &batchProcessor{
+		serviceName: serviceName,
+		exporters:   exporters,
+		queue:       make(chan *Span, maxQueueSize),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go bp.run()
+	return bp
+}
+
+func (bp *batchProcessor) onEnd(s *Span) {
+	select {
+	case bp.queue <- s:
+	default:
+		logrus.Debugf("tracing queue is full, drop span %s", s.name)
+	}
+}
+
+func (bp *batchProcessor) run() {
+	defer close(bp.done)
+	ticker := time.NewTicker(batchTimeout)
+	defer ticker.Stop()
+
+	var batch []*Span
+	for {
+		select {
+		case s := <-bp.queue:
+			if batch = append(batch, s); len(batch) >= maxBatchSize {
+				batch = bp.flush(batch)
+			}
+		case <-ticker.C:
+			batch = bp.flush(batch)
+		case <-bp.stop:
+			for {
+				select {
+				case s := <-bp.queue:
+					if batch = append(batch, s); len(batch) >= maxBatchSize {
+						batch = bp.flush(batch)
+					}
+				default:
+					bp.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush exports the batch and returns an empty batch to reuse.
+func (bp *batchProcessor) flush(batch []*Span) []*Span {
+	if len(batch) == 0 {
+		return batch
+	}
+	data, err := json.Marshal(encodeSpans(bp.serviceName, batch))
+	if err != nil {
+		logrus.Warnf("failed to encode %d spans: %v", len(batch), err)
+		return batch[:0]
+	}
+	for _, e := range bp.exporters {
+		if err := e.export(data); err != nil {
+			logrus.Warnf("failed to export %d spans: %v", len(batch), err)
+		}
+	}
+	return batch[:0]
+}
+
+// shutdown exports the pending spans and closes the exporters.
+func (bp *batchProcessor) shutdown() {
+	bp.once.Do(func() {
+		close(bp.stop)
+		<-bp.done
+		for _, e := range bp.exporters {
+			if err := e.close(); err != nil {
+				logrus.Warnf("failed to close tracing exporter: %v", err)
+			}
+		}
+	})
+}
+
+// otlpExporter sends the spans to the OTLP/HTTP receiver of the collector.
+type otlpExporter struct {
+	endpoint string
+	client   *http.Client
+}
+
+func newOTLPExporter(endpoint string) *otlpExporter {
+	return &otlpExporter{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: exportTimeout},
+	}
+}
+
+func (e *otlpExporter) export(data []byte) error {
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector %s responds %s", e.endpoint, resp.Status)
+	}
+	return nil
+}
+
+func (e *otlpExporter) close() error {
+	return nil
+}
+
+// fileExporter appends the spans to a local file, one request per line.
+type fileExporter struct {
+	sync.Mutex
+	file *os.File
+}
+
+func newFileExporter(path string) (*fileExporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileExporter{file: f}, nil
+}
+
+func (e *fileExporter) export(data []byte) error {
+	e.Lock()
+	defer e.Unlock()
+	// write the line at once, since the file may be shared by several processes
+	_, err := e.file.Write(append(data, '\n'))
+	return err
+}
+
+func (e *fileExporter) close() error {
+	return e.file.Close()
+}
+
+// The OTLP JSON encoding of the spans, only the used fields are defined.
+
+// ExportRequest is the OTLP request to export the spans.
+type ExportRequest struct {
+	ResourceSpans []ResourceSpans `json:"resourceSpans"`
+}
+
+// ResourceSpans is the spans of a service.
+type ResourceSpans struct {
+	Resource   Resource     `json:"resource"`
+	ScopeSpans []ScopeSpans `json:"scopeSpans"`
+}
+
+// Resource describes the service.
+type Resource struct {
+	Attributes []KeyValue `json:"attributes"`
+}
+
+// ScopeSpans is the spans of an instrumentation scope.
+type ScopeSpans struct {
+	Scope Scope      `json:"scope"`
+	Spans []SpanData `json:"spans"`
+}
+
+// Scope is the instrumentation scope.
+type Scope struct {
+	Name string `json:"name"`
+}
+
+// SpanData is an ended span.
+type SpanData struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              SpanKind   `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	Status            Status     `json:"status"`
+}
+
+// KeyValue is an attribute.
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue is the value of an attribute, only one of the fields is set.
+type AnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// Status is the status of a span.
+type Status struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// statusCodeError is the OTLP status code of the failed spans.
+const statusCodeError = 2
+
+const scopeName = "github.com/dragonflyoss/Dragonfly"
+
+func encodeSpans(serviceName string, spans []*Span) *ExportRequest {
+	data := make([]SpanData, 0, len(spans))
+	for _, s := range spans {
+		data = append(data, s.encode())
+	}
+	return &ExportRequest{
+		ResourceSpans: []ResourceSpans{{
+			Resource: Resource{
+				Attributes: []KeyValue{encodeAttribute("service.name", serviceName)},
+			},
+			ScopeSpans: []ScopeSpans{{
+				Scope: Scope{Name: scopeName},
+				Spans: data,
+			}},
+		}},
+	}
+}
+
+func (s *Span) encode() SpanData {
+	s.Lock()
+	defer s.Unlock()
+	d := SpanData{
+		TraceID:           s.sc.TraceID.String(),
+		SpanID:            s.sc.SpanID.String(),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+	}
+	if s.parent != (SpanID{}) {
+		d.ParentSpanID = s.parent.String()
+	}
+	for _, a := range s.attributes {
+		d.Attributes = append(d.Attributes, encodeAttribute(a.key, a.value))
+	}
+	if s.err != "" {
+		d.Status = Status{Code: statusCodeError, Message: s.err}
+	}
+	return d
+}
+
+func encodeAttribute(key string, value interface{}) KeyValue {
+	var v AnyValue
+	switch value := value.(type) {
+	case string:
+		v.StringValue = &value
+	case bool:
+		v.BoolValue = &value
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32:
+		s := fmt.Sprint(value)
+		v.IntValue = &s
+	case float32:
+		f := float64(value)
+		v.DoubleValue = &f
+	case float64:
+		v.DoubleValue = &value
+	default:
+		s := fmt.Sprint(value)
+		v.StringValue = &s
+	}
+	return KeyValue{Key: key, Value: v}
+}
+
+// ReadSpans reads the spans exported to the file, it's used in tests.
+func ReadSpans(path string) ([]SpanData, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spans []SpanData
+	for _, line := range bytes.Split(content, []byte{'\n'}) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		req := &ExportRequest{}
+		if err := json.Unmarshal(line, req); err != nil {
+			return nil, err
+		}
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}
+	return spans, nil
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	// TraceparentHeader is the W3C trace context header.
+	TraceparentHeader = "traceparent"
+	// EnvTraceparent is the environment variable to pass the trace context
+	// to a child process, in the same format as the traceparent header.
+	EnvTraceparent = "TRACEPARENT"
+)
+
+// traceparent formats sc in the W3C traceparent format, version 00.
+func (sc SpanContext) traceparent() string {
+	flags := 0
+	if sc.Sampled {
+		flags = 1
+	}
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, flags)
+}
+
+// parseTraceparent parses the W3C traceparent value. The future versions
+// are parsed as version 00 as required by the specification.
+func parseTraceparent(value string) (sc SpanContext, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		(parts[0] == "00" && len(parts) != 4) {
+		return sc, false
+	}
+	if !decodeHex(sc.TraceID[:], parts[1]) || !decodeHex(sc.SpanID[:], parts[2]) {
+		return sc, false
+	}
+	var flags [1]byte
+	if !decodeHex(flags[:], parts[3]) {
+		return sc, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, sc.IsValid()
+}
+
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != 2*len(dst) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// Inject sets the traceparent header of the span in ctx to h.
+func Inject(ctx context.Context, h http.Header) {
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		h.Set(TraceparentHeader, sc.traceparent())
+	}
+}
+
+// InjectMap returns a copy of header with the traceparent header of the span
+// in ctx added. The header is returned as is if there is no span in ctx.
+func InjectMap(ctx context.Context, header map[string]string) map[string]string {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return header
+	}
+	result := make(map[string]string, len(header)+1)
+	for k, v := range header {
+		result[k] = v
+	}
+	result[TraceparentHeader] = sc.traceparent()
+	return result
+}
+
+// Extract returns a copy of ctx with the span context of the traceparent header in h.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	if sc, ok := parseTraceparent(h.Get(TraceparentHeader)); ok {
+		return ContextWithRemoteSpanContext(ctx, sc)
+	}
+	return ctx
+}
+
+// Environ returns the environment variables which pass the span in ctx to a child process.
+func Environ(ctx context.Context) []string {
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		return []string{EnvTraceparent + "=" + sc.traceparent()}
+	}
+	return nil
+}
+
+// ExtractEnv returns a copy of ctx with the span context passed by the parent process.
+func ExtractEnv(ctx context.Context) context.Context {
+	if sc, ok := parseTraceparent(os.Getenv(EnvTraceparent)); ok {
+		return ContextWithRemoteSpanContext(ctx, sc)
+	}
+	return ctx
+}
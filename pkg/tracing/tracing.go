@@ -0,0 +1,341 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tracing traces the requests across dfdaemon, dfget and supernode.
+// The trace context is propagated in the W3C traceparent format, and the spans
+// are exported in the OTLP/HTTP JSON encoding to a collector or a local file.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The environment variables to configure tracing, which are mainly used to
+// pass the configuration of dfdaemon to the forked dfget.
+const (
+	// EnvEndpoint is the standard OTLP environment variable of the endpoint.
+	EnvEndpoint = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	// EnvFile is the environment variable of the file to export the spans to.
+	EnvFile = "DRAGONFLY_TRACING_FILE"
+)
+
+// Config is the configuration of tracing.
+// Tracing is disabled if neither the endpoint nor the file is set.
+type Config struct {
+	// Endpoint is the url of the OTLP/HTTP traces receiver of the collector,
+	// e.g. http://localhost:4318/v1/traces.
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+
+	// File is the path of the file to append the spans to,
+	// one OTLP JSON request per line. It's mainly used in tests.
+	File string `yaml:"file" json:"file"`
+}
+
+// Enabled returns whether the spans should be exported.
+func (c Config) Enabled() bool {
+	return c.Endpoint != "" || c.File != ""
+}
+
+// Environ returns the environment variables which pass the config to a child process.
+func (c Config) Environ() []string {
+	var env []string
+	if c.Endpoint != "" {
+		env = append(env, EnvEndpoint+"="+c.Endpoint)
+	}
+	if c.File != "" {
+		env = append(env, EnvFile+"="+c.File)
+	}
+	return env
+}
+
+// ConfigFromEnv returns the config set by the environment variables.
+func ConfigFromEnv() Config {
+	return Config{
+		Endpoint: strings.TrimSpace(os.Getenv(EnvEndpoint)),
+		File:     strings.TrimSpace(os.Getenv(EnvFile)),
+	}
+}
+
+// provider exports the ended spans, the spans are not recorded if it's nil.
+var provider struct {
+	sync.RWMutex
+	processor *batchProcessor
+}
+
+// Init starts to export the spans of the service with the given config.
+// It returns a function to flush the pending spans and stop exporting,
+// which should be called before the process exits.
+func Init(serviceName string, cfg Config) (func(), error) {
+	if !cfg.Enabled() {
+		return func() {}, nil
+	}
+
+	var exporters []exporter
+	if cfg.Endpoint != "" {
+		exporters = append(exporters, newOTLPExporter(cfg.Endpoint))
+	}
+	if cfg.File != "" {
+		e, err := newFileExporter(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, e)
+	}
+	bp := newBatchProcessor(serviceName, exporters)
+
+	provider.Lock()
+	old := provider.processor
+	provider.processor = bp
+	provider.Unlock()
+	if old != nil {
+		old.shutdown()
+	}
+
+	return func() {
+		provider.Lock()
+		if provider.processor == bp {
+			provider.processor = nil
+		}
+		provider.Unlock()
+		bp.shutdown()
+	}, nil
+}
+
+// ShutdownWithTimeout calls shutdown returned by Init and waits for it at most
+// timeout, so that a short-lived process isn't held by a slow or unreachable
+// collector. It returns false if the pending spans aren't exported in time,
+// which are dropped once the process exits.
+func ShutdownWithTimeout(shutdown func(), timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		shutdown()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// SanitizeURL returns the url without the user info, the query and the fragment,
+// which may carry the credentials, such as the signatures of the presigned urls.
+// It's used as the value of the http.url attribute.
+func SanitizeURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		if i := strings.IndexAny(rawURL, "?#"); i >= 0 {
+			return rawURL[:i]
+		}
+		return rawURL
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.ForceQuery = false
+	u.Fragment = ""
+	return u.String()
+}
+
+func getProcessor() *batchProcessor {
+	provider.RLock()
+	defer provider.RUnlock()
+	return provider.processor
+}
+
+// TraceID is the ID of a trace.
+type TraceID [16]byte
+
+// SpanID is the ID of a span.
+type SpanID [8]byte
+
+// SpanContext identifies a span across the processes.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid returns whether both the trace ID and the span ID are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// SpanKind describes the relationship between the span and its parent.
+// The values are the same as the ones of OTLP.
+type SpanKind int
+
+const (
+	// SpanKindInternal indicates an internal operation.
+	SpanKindInternal SpanKind = 1
+	// SpanKindServer indicates the handling of a remote request.
+	SpanKindServer SpanKind = 2
+	// SpanKindClient indicates a request to a remote service.
+	SpanKindClient SpanKind = 3
+)
+
+type attribute struct {
+	key   string
+	value interface{}
+}
+
+// Span is a timed operation of a trace. A nil span does nothing.
+type Span struct {
+	sync.Mutex
+	sc         SpanContext
+	parent     SpanID
+	name       string
+	kind       SpanKind
+	start      time.Time
+	end        time.Time
+	attributes []attribute
+	err        string
+	// processor is nil if the span isn't recorded.
+	processor *batchProcessor
+	ended     bool
+}
+
+type spanKey struct{}
+
+type remoteSpanKey struct{}
+
+// StartSpan starts a span as the child of the span in ctx, or a new trace
+// if there is none. The returned context carries the started span.
+// The span is not recorded if tracing isn't initialized or the parent isn't sampled,
+// but the trace context of the parent is still propagated.
+func StartSpan(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	parent := SpanContextFromContext(ctx)
+	bp := getProcessor()
+	if bp == nil || (parent.IsValid() && !parent.Sampled) {
+		span := &Span{sc: parent, name: name, kind: kind}
+		return context.WithValue(ctx, spanKey{}, span), span
+	}
+
+	span := &Span{
+		sc:        SpanContext{TraceID: parent.TraceID, Sampled: true},
+		name:      name,
+		kind:      kind,
+		start:     time.Now(),
+		processor: bp,
+	}
+	if parent.IsValid() {
+		span.parent = parent.SpanID
+	} else {
+		rand.Read(span.sc.TraceID[:])
+	}
+	rand.Read(span.sc.SpanID[:])
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SpanContextFromContext returns the context of the span in ctx, which is
+// either started locally or extracted from a remote request.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if ctx == nil {
+		return SpanContext{}
+	}
+	if span, ok := ctx.Value(spanKey{}).(*Span); ok {
+		return span.sc
+	}
+	if sc, ok := ctx.Value(remoteSpanKey{}).(SpanContext); ok {
+		return sc
+	}
+	return SpanContext{}
+}
+
+// ContextWithRemoteSpanContext returns a copy of ctx with the span context
+// of a remote parent, the spans started from it will be its children.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	if !sc.IsValid() {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteSpanKey{}, sc)
+}
+
+// SpanContext returns the context of the span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// IsRecording returns whether the span will be exported.
+func (s *Span) IsRecording() bool {
+	return s != nil && s.processor != nil
+}
+
+// SetAttribute sets an attribute of the span. The value is exported as a string
+// unless it's a string, bool, integer or float.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if !s.IsRecording() {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	for i := range s.attributes {
+		if s.attributes[i].key == key {
+			s.attributes[i].value = value
+			return
+		}
+	}
+	s.attributes = append(s.attributes, attribute{key: key, value: value})
+}
+
+// SetError marks the span failed with err, it does nothing if err is nil.
+func (s *Span) SetError(err error) {
+	if err == nil || !s.IsRecording() {
+		return
+	}
+	s.Lock()
+	s.err = err.Error()
+	s.Unlock()
+}
+
+// End ends the span and exports it, the subsequent calls do nothing.
+func (s *Span) End() {
+	if !s.IsRecording() {
+		return
+	}
+	s.Lock()
+	if s.ended {
+		s.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.Unlock()
+	s.processor.onEnd(s)
+}
+
+// String returns the hex trace ID, which is convenient to be logged.
+func (id TraceID) String() string {
+	return fmt.Sprintf("%x", id[:])
+}
+
+// String returns the hex span ID.
+func (id SpanID) String() string {
+	return fmt.Sprintf("%x", id[:])
+}
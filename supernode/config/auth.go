@@ -0,0 +1,54 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+const (
+	// AuthenticatorToken is the name of the authenticator which checks the
+	// bearer tokens listed in the static token file.
+	AuthenticatorToken = "token"
+
+	// AuthenticatorHMAC is the name of the authenticator which checks the
+	// bearer tokens signed by the HMAC secret.
+	AuthenticatorHMAC = "hmac"
+)
+
+// AuthConfig contains the properties of the authentication and authorization
+// of the supernode APIs.
+type AuthConfig struct {
+	// Enable enables the authentication and authorization of the APIs.
+	// All the APIs are open to everyone if it's false.
+	// default: false
+	Enable bool `yaml:"enable"`
+
+	// Authenticators is the names of the authenticators which are tried in order
+	// to authenticate a request, all the built-in authenticators are used if it's empty.
+	Authenticators []string `yaml:"authenticators,omitempty"`
+
+	// TokenFile is the path of the static token file used by the "token" authenticator.
+	TokenFile string `yaml:"tokenFile,omitempty"`
+
+	// HMACSecret is the secret which signs and verifies the tokens of the "hmac" authenticator.
+	HMACSecret string `yaml:"hmacSecret,omitempty"`
+
+	// AnonymousRoles is the roles granted to the requests without any credential,
+	// e.g. ["peer"] keeps the dfgets without a token working.
+	AnonymousRoles []string `yaml:"anonymousRoles,omitempty"`
+
+	// Token is the token used by the supernode itself to call the other supernodes,
+	// such as the parent supernode.
+	Token string `yaml:"token,omitempty"`
+}
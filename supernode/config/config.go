@@ -25,6 +25,7 @@ import (
 	"github.com/dragonflyoss/Dragonfly/pkg/dflog"
 	"github.com/dragonflyoss/Dragonfly/pkg/fileutils"
 	"github.com/dragonflyoss/Dragonfly/pkg/rate"
+	"github.com/dragonflyoss/Dragonfly/pkg/tracing"
 
 	"gopkg.in/yaml.v2"
 )
@@ -97,8 +98,12 @@ func NewBaseProperties() *BaseProperties {
 		DownloadPath:            filepath.Join(home, "repo", "download"),
 		PeerUpLimit:             DefaultPeerUpLimit,
 		PeerDownLimit:           DefaultPeerDownLimit,
-		EliminationLimit:        DefaultEliminationLimit,
-		FailureCountLimit:       DefaultFailureCountLimit,
+		PeerHealthThreshold:     DefaultPeerHealthThreshold,
+		PeerHealthHalfLife:      DefaultPeerHealthHalfLife,
+		HedgeAlternateLimit:     DefaultHedgeAlternateLimit,
+		SeedHotThreshold:        DefaultSeedHotThreshold,
+		CorruptionLimit:         DefaultCorruptionLimit,
+		CorruptionQuarantine:    DefaultCorruptionQuarantine,
 		LinkLimit:               DefaultLinkLimit,
 		SystemReservedBandwidth: DefaultSystemReservedBandwidth,
 		MaxBandwidth:            DefaultMaxBandwidth,
@@ -114,6 +119,7 @@ func NewBaseProperties() *BaseProperties {
 		TaskExpireTime:          DefaultTaskExpireTime,
 		PeerGCDelay:             DefaultPeerGCDelay,
 		CleanRatio:              DefaultCleanRatio,
+		StateBackend:            StateBackendMemory,
 	}
 }
 
@@ -122,14 +128,26 @@ type CDNPattern string
 const (
 	CDNPatternLocal  = "local"
 	CDNPatternSource = "source"
+	CDNPatternParent = "parent"
+)
+
+const (
+	StateBackendMemory = "memory"
+	StateBackendEtcd   = "etcd"
 )
 
 // BaseProperties contains all basic properties of supernode.
 type BaseProperties struct {
-	// CDNPattern cdn pattern which must be in ["local", "source"].
+	// CDNPattern cdn pattern which must be in ["local", "source", "parent"].
+	// With the "parent" pattern, the CDN downloads the file from ParentSupernode
+	// and falls back to the source if the parent is unavailable.
 	// default: CDNPatternLocal
 	CDNPattern CDNPattern `yaml:"cdnPattern"`
 
+	// ParentSupernode is the address(ip:listenPort) of the parent supernode
+	// which is used by the "parent" cdn pattern.
+	ParentSupernode string `yaml:"parentSupernode,omitempty"`
+
 	// ListenPort is the port supernode server listens on.
 	// default: 8002
 	ListenPort int `yaml:"listenPort"`
@@ -163,22 +181,63 @@ type BaseProperties struct {
 	// default: 4
 	PeerDownLimit int `yaml:"peerDownLimit"`
 
-	// When dfget node starts to play a role of peer, it will provide services for other peers
-	// to pull pieces. If it runs into an issue when providing services for a peer, its self failure
-	// increases by 1. When the failure limit reaches EliminationLimit, the peer will isolate itself
-	// as a unhealthy state. Then this dfget will be no longer called by other peers.
-	// default: 5
+	// EliminationLimit was the limit of failures for a peer to serve the other peers.
+	//
+	// Deprecated: the peers are scheduled by PeerHealthThreshold, and it's ignored.
 	EliminationLimit int `yaml:"eliminationLimit"`
 
-	// FailureCountLimit is the failure count limit set in supernode for dfget client.
-	// When a dfget client takes part in the peer network constructed by supernode,
-	// supernode will command the peer to start distribution task.
-	// When dfget client fails to finish distribution task, the failure count of client
-	// increases by 1. When failure count of client reaches to FailureCountLimit(default 5),
-	// dfget client will be moved to blacklist of supernode to stop playing as a peer.
-	// default: 5
+	// FailureCountLimit was the limit of failures for a peer to download from the other peers.
+	//
+	// Deprecated: the peers are scheduled by PeerHealthThreshold, and it's ignored.
 	FailureCountLimit int `yaml:"failureCountLimit"`
 
+	// PeerHealthThreshold is the health score in [0, 1] below which a peer is unhealthy.
+	// The health score of a peer as a server is calculated from the success rate, the latency
+	// of serving pieces, the corrupted pieces, the times of going offline and the last time it's
+	// active, and supernode schedules the pieces from the healthy peers randomly weighted by the score.
+	// The health score of a peer as a client is the success rate of downloading pieces, and
+	// an unhealthy client downloads the pieces from supernode.
+	// default: 0.2
+	PeerHealthThreshold float64 `yaml:"peerHealthThreshold"`
+
+	// PeerHealthHalfLife is the half-life of the events which decide the health score,
+	// so that an unhealthy peer recovers gradually after a transient failure.
+	// default: 5m
+	PeerHealthHalfLife time.Duration `yaml:"peerHealthHalfLife"`
+
+	// HedgeAlternateLimit is the max number of alternative peers returned for every piece.
+	// When downloading a piece from the scheduled peer takes too long, dfget can send a hedged
+	// request to one of the alternative peers and take the response which completes first.
+	// The hedged request will be disabled if the value is 0.
+	// default: 1
+	HedgeAlternateLimit int `yaml:"hedgeAlternateLimit"`
+
+	// SeedLimit is the max number of peers elected as seed nodes for every task.
+	// When the task becomes hot, supernode ranks the peers which are long-lived or already have
+	// the resource by their bandwidth, load and uptime weighted by the health score, and elects
+	// the top ones as seeds. The pieces are scheduled from the seeds more likely than the others.
+	// The seed pattern will be disabled if the value is 0.
+	// default: 0
+	SeedLimit int `yaml:"seedLimit"`
+
+	// SeedHotThreshold is the number of peers downloading a task at which the task is hot
+	// and the seed nodes start to be elected for it.
+	// default: 10
+	SeedHotThreshold int `yaml:"seedHotThreshold"`
+
+	// CorruptionLimit is the number of corrupted pieces a peer can serve within CorruptionQuarantine.
+	// Every piece reported as corrupted is removed from the peer at once, and the peer is asked to
+	// verify its local copy. When the limit is reached, the peer is quarantined for CorruptionQuarantine
+	// by all supernodes of the cluster and no longer serves the other peers.
+	// The quarantine will be disabled if the value is 0.
+	// default: 3
+	CorruptionLimit int `yaml:"corruptionLimit"`
+
+	// CorruptionQuarantine is the time that a peer is quarantined after serving
+	// CorruptionLimit corrupted pieces.
+	// default: 10m
+	CorruptionQuarantine time.Duration `yaml:"corruptionQuarantine"`
+
 	// LinkLimit is set for supernode to limit every piece download network speed.
 	// default: 20 MB, in format of G(B)/g/M(B)/m/K(B)/k/B, pure number will also be parsed as Byte.
 	LinkLimit rate.Rate `yaml:"linkLimit"`
@@ -203,6 +262,50 @@ type BaseProperties struct {
 	// By default, the first non-loop address is advertised.
 	AdvertiseIP string `yaml:"advertiseIP"`
 
+	// StateBackend is the backend which keeps the task, peer, dfgetTask and progress state.
+	// It should be one of "memory" and "etcd". With the "etcd" backend, the state is kept
+	// in the etcd cluster at EtcdEndpoints and shared by the supernodes using it, so that
+	// they can serve the same tasks and dfget can fail over between them without losing the progress.
+	// default: memory
+	StateBackend string `yaml:"stateBackend"`
+
+	// EtcdEndpoints is the list of the client URLs of the etcd cluster, e.g. http://192.168.0.1:2379,
+	// which is required by the etcd state backend.
+	EtcdEndpoints []string `yaml:"etcdEndpoints,omitempty"`
+
+	// EtcdUsername and EtcdPassword authenticate supernode to etcd if the auth of etcd is enabled.
+	EtcdUsername string `yaml:"etcdUsername,omitempty"`
+	EtcdPassword string `yaml:"etcdPassword,omitempty"`
+
+	// ClusterMembers is the list of supernode addresses(ip:listenPort) which form a cluster.
+	// Every task is owned by one alive member chosen by consistent hashing of the task ID,
+	// and the other members redirect dfget to the owner, so that the file is downloaded
+	// from the source only once in the cluster.
+	// The cluster mode is disabled if it's empty.
+	ClusterMembers []string `yaml:"clusterMembers,omitempty"`
+
+	// Tenants is the list of tenants which have the tokens, quotas or sharing policy.
+	// The tenant of a peer is taken from the X-Dragonfly-Tenant-Token or X-Dragonfly-Tenant
+	// header, and the peers are only scheduled to download pieces from the peers of
+	// the same tenant unless both tenants are shared.
+	// The tenants which are not in the list have no quota and are not shared.
+	Tenants []*TenantConfig `yaml:"tenants,omitempty"`
+
+	// Auth is the authentication and authorization of the supernode APIs.
+	Auth AuthConfig `yaml:"auth"`
+
+	// Webhook receives the push events from the registries to preheat the images.
+	Webhook WebhookConfig `yaml:"webhook"`
+
+	// PreheatSecret encrypts the headers of the preheat schedules kept in the state backend,
+	// which may carry the credentials of the registries. It should be the same on all the
+	// supernodes sharing the state, and the schedules with headers are refused if it's empty.
+	PreheatSecret string `yaml:"preheatSecret,omitempty"`
+
+	// Tracing exports the spans of the handled requests and the CDN downloads
+	// to the OTLP collector or a local file, it's disabled by default.
+	Tracing tracing.Config `yaml:"tracing"`
+
 	// FailAccessInterval is the interval time after failed to access the URL.
 	// unit: minutes
 	// default: 3
@@ -121,3 +121,34 @@ func (s *SupernodeConfigTestSuite) TestIsSuperPID(c *check.C) {
 	c.Assert(conf.IsSuperPID("superNodePID"), check.DeepEquals, true)
 	c.Assert(conf.IsSuperPID("Test"), check.DeepEquals, false)
 }
+
+func (s *SupernodeConfigTestSuite) TestCanShareTenants(c *check.C) {
+	conf := NewConfig()
+	conf.Tenants = []*TenantConfig{
+		{ID: "a", Shared: true},
+		{ID: "b", Shared: true},
+		{ID: "c"},
+	}
+
+	c.Assert(conf.CanShareTenants("", ""), check.Equals, true)
+	c.Assert(conf.CanShareTenants("c", "c"), check.Equals, true)
+	c.Assert(conf.CanShareTenants("a", "b"), check.Equals, true)
+	c.Assert(conf.CanShareTenants("a", "c"), check.Equals, false)
+	c.Assert(conf.CanShareTenants("a", ""), check.Equals, false)
+	c.Assert(conf.CanShareTenants("a", "d"), check.Equals, false)
+}
+
+func (s *SupernodeConfigTestSuite) TestMatchWebhookRule(c *check.C) {
+	conf := NewConfig()
+	conf.Webhook.Rules = []*WebhookRule{
+		{Registry: "*.example.com", Repository: "library/*", Tag: "^v[0-9.]+$"},
+		{Repository: "release/*", Insecure: true},
+	}
+
+	c.Assert(conf.Webhook.MatchWebhookRule("hub.example.com", "library/nginx", "v1.19"), check.Equals, conf.Webhook.Rules[0])
+	c.Assert(conf.Webhook.MatchWebhookRule("hub.example.com", "library/nginx", "latest"), check.IsNil)
+	c.Assert(conf.Webhook.MatchWebhookRule("docker.io", "library/nginx", "v1.19"), check.IsNil)
+	c.Assert(conf.Webhook.MatchWebhookRule("hub.example.com", "library/foo/bar", "v1"), check.IsNil)
+	c.Assert(conf.Webhook.MatchWebhookRule("docker.io", "release/app", "latest"), check.Equals, conf.Webhook.Rules[1])
+	c.Assert(conf.Webhook.MatchWebhookRule("docker.io", "dev/app", "latest"), check.IsNil)
+}
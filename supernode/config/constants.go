@@ -50,17 +50,32 @@ const (
 )
 
 const (
-	// DefaultFailureCountLimit indicates the default limit of failure count as a client.
-	DefaultFailureCountLimit = 5
+	// DefaultPeerHealthThreshold indicates the default health score below which
+	// a peer isn't scheduled as a server or a client.
+	DefaultPeerHealthThreshold = 0.2
 
-	// DefaultEliminationLimit indicates the default limit of failure count as a server.
-	DefaultEliminationLimit = 5
+	// DefaultPeerHealthHalfLife indicates the default half-life of the events
+	// which decide the health score of a peer.
+	DefaultPeerHealthHalfLife = 5 * time.Minute
 
 	// DefaultPeerUpLimit indicates the default limit of the load count as a server.
 	DefaultPeerUpLimit = 5
 
 	// DefaultPeerDownLimit indicates the default limit of the download task count as a client.
 	DefaultPeerDownLimit = 4
+
+	// DefaultHedgeAlternateLimit indicates the default limit of the alternative peers for a piece.
+	DefaultHedgeAlternateLimit = 1
+
+	// DefaultSeedHotThreshold indicates the default number of peers at which a task is hot.
+	DefaultSeedHotThreshold = 10
+
+	// DefaultCorruptionLimit indicates the default limit of corrupted pieces served by a peer.
+	DefaultCorruptionLimit = 3
+
+	// DefaultCorruptionQuarantine indicates the default time that a peer is quarantined
+	// after serving too many corrupted pieces.
+	DefaultCorruptionQuarantine = 10 * time.Minute
 )
 
 const (
@@ -0,0 +1,83 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"github.com/dragonflyoss/Dragonfly/pkg/fileutils"
+	"github.com/dragonflyoss/Dragonfly/pkg/rate"
+)
+
+const (
+	// TenantHeader is the HTTP header which carries the tenant ID of a peer.
+	TenantHeader = "X-Dragonfly-Tenant"
+
+	// TenantTokenHeader is the HTTP header which carries the token of a tenant.
+	TenantTokenHeader = "X-Dragonfly-Tenant-Token"
+)
+
+// TenantConfig contains the token, quota and sharing properties of a tenant.
+type TenantConfig struct {
+	// ID is the unique ID of the tenant.
+	ID string `yaml:"id"`
+
+	// Tokens are used by the peers to prove that they belong to the tenant.
+	// If it's not empty, the tenant ID in the X-Dragonfly-Tenant header is
+	// refused and the peers must carry one of the tokens in the
+	// X-Dragonfly-Tenant-Token header.
+	Tokens []string `yaml:"tokens,omitempty"`
+
+	// Shared indicates whether the peers of the tenant can download pieces
+	// from the peers of the other shared tenants, and vice versa.
+	// default: false
+	Shared bool `yaml:"shared"`
+
+	// CDNDiskQuota is the max total size of the CDN files of the tasks created by the tenant.
+	// The tenant can't create new tasks when it's exceeded.
+	// default: 0, no limit
+	CDNDiskQuota fileutils.Fsize `yaml:"cdnDiskQuota"`
+
+	// MaxConcurrentTasks is the max number of the tasks created by the tenant
+	// which are being downloaded by CDN at the same time.
+	// default: 0, no limit
+	MaxConcurrentTasks int `yaml:"maxConcurrentTasks"`
+
+	// BackSourceBandwidth is the max bandwidth that CDN can use to download
+	// the tasks created by the tenant from the source.
+	// default: 0, no limit
+	BackSourceBandwidth rate.Rate `yaml:"backSourceBandwidth"`
+}
+
+// GetTenant returns the config of the tenant, and nil if it's not configured.
+func (c *Config) GetTenant(tenant string) *TenantConfig {
+	for _, t := range c.Tenants {
+		if t != nil && t.ID == tenant {
+			return t
+		}
+	}
+	return nil
+}
+
+// CanShareTenants returns whether the peers of the two tenants can download
+// pieces from each other, which is true if they are the same tenant or both shared.
+func (c *Config) CanShareTenants(tenant1, tenant2 string) bool {
+	if tenant1 == tenant2 {
+		return true
+	}
+
+	t1, t2 := c.GetTenant(tenant1), c.GetTenant(tenant2)
+	return t1 != nil && t2 != nil && t1.Shared && t2.Shared
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"path"
+	"regexp"
+)
+
+// WebhookConfig contains the properties of the webhook which receives the push
+// events from the registries and preheats the pushed images automatically.
+type WebhookConfig struct {
+	// Enable enables the webhook APIs.
+	// default: false
+	Enable bool `yaml:"enable"`
+
+	// Secret is shared with the registries to authenticate the events, the
+	// events are refused if it's empty.
+	Secret string `yaml:"secret,omitempty"`
+
+	// Rules decides which pushed images are preheated, an image is preheated
+	// by the first rule it matches and ignored if it matches none of them.
+	Rules []*WebhookRule `yaml:"rules,omitempty"`
+}
+
+// WebhookRule filters the pushed images by the registry, repository and tag.
+type WebhookRule struct {
+	// Registry is the glob pattern of the registry host, e.g. "*.example.com".
+	// All the registries are matched if it's empty.
+	Registry string `yaml:"registry,omitempty"`
+
+	// Repository is the glob pattern of the repository, e.g. "library/*".
+	// All the repositories are matched if it's empty.
+	Repository string `yaml:"repository,omitempty"`
+
+	// Tag is the regular expression of the tag, e.g. "^v[0-9.]+$".
+	// All the tags are matched if it's empty.
+	Tag string `yaml:"tag,omitempty"`
+
+	// Insecure pulls the manifests from the registry by HTTP instead of HTTPS.
+	// default: false
+	Insecure bool `yaml:"insecure"`
+
+	// Headers are sent to the registry when preheating the images, such as
+	// the Authorization header of the private repositories.
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// Platforms are the platforms in the format of os/architecture[/variant]
+	// to be preheated of the multi-platform images, all of them are preheated if it's empty.
+	Platforms []string `yaml:"platforms,omitempty"`
+
+	// Referrers preheats the referrers of the images too, such as the signatures and the SBOMs.
+	// default: false
+	Referrers bool `yaml:"referrers"`
+}
+
+// MatchWebhookRule returns the first rule matched by the image, and nil if there is none.
+func (c *WebhookConfig) MatchWebhookRule(registry, repository, tag string) *WebhookRule {
+	for _, r := range c.Rules {
+		if r != nil && r.Match(registry, repository, tag) {
+			return r
+		}
+	}
+	return nil
+}
+
+// Match returns whether the image matches the rule.
+func (r *WebhookRule) Match(registry, repository, tag string) bool {
+	if !matchGlob(r.Registry, registry) || !matchGlob(r.Repository, repository) {
+		return false
+	}
+	if r.Tag == "" {
+		return true
+	}
+	matched, err := regexp.MatchString(r.Tag, tag)
+	return err == nil && matched
+}
+
+func matchGlob(pattern, name string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
@@ -38,6 +38,7 @@ type fileMetaData struct {
 	PieceSize   int32  `json:"pieceSize"`
 	HTTPFileLen int64  `json:"httpFileLen"`
 	Identifier  string `json:"bizId"`
+	Tenant      string `json:"tenant,omitempty"`
 
 	AccessTime   int64  `json:"accessTime"`
 	Interval     int64  `json:"interval"`
@@ -71,6 +72,7 @@ func (mm *fileMetaDataManager) writeFileMetaDataByTask(ctx context.Context, task
 		PieceSize:   task.PieceSize,
 		HTTPFileLen: task.HTTPFileLength,
 		Identifier:  task.Identifier,
+		Tenant:      task.Tenant,
 		AccessTime:  getCurrentTimeMillisFunc(),
 		FileLength:  task.FileLength,
 		Md5:         task.Md5,
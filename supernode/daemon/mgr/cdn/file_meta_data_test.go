@@ -23,6 +23,7 @@ import (
 	"os"
 
 	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
 	"github.com/dragonflyoss/Dragonfly/supernode/store"
 
 	"github.com/go-check/check"
@@ -34,6 +35,7 @@ var taskID = "00c4e7b174af7ed61c414b36ef82810ac0c98142c03e5748c00e1d1113f3c882"
 type CDNFileMetaDataTestSuite struct {
 	workHome        string
 	content         string
+	fileStore       *store.Store
 	metaDataManager *fileMetaDataManager
 
 	metaDataPathStub      *gostub.Stubs
@@ -50,6 +52,7 @@ func (s *CDNFileMetaDataTestSuite) SetUpSuite(c *check.C) {
 	s.content = "baseDir: " + s.workHome
 	fileStore, err := store.NewStore(store.LocalStorageDriver, store.NewLocalStorage, s.content)
 	c.Check(err, check.IsNil)
+	s.fileStore = fileStore
 	s.metaDataManager = newFileMetaDataManager(fileStore)
 
 	s.metaDataPathStub = gostub.Stub(&getMetaDataRawFunc, func(taskID string) *store.Raw {
@@ -140,3 +143,25 @@ func (s *CDNFileMetaDataTestSuite) TestWriteReadPieceMD5s(c *check.C) {
 	c.Check(err, check.IsNil)
 	c.Check(result, check.DeepEquals, pieceMD5s)
 }
+
+func (s *CDNFileMetaDataTestSuite) TestListCachedFiles(c *check.C) {
+	ctx := context.TODO()
+	cm := &Manager{cacheStore: s.fileStore, metaDataManager: s.metaDataManager}
+	cachedID := "10c4e7b174af7ed61c414b36ef82810ac0c98142c03e5748c00e1d1113f3c882"
+	runningID := "20c4e7b174af7ed61c414b36ef82810ac0c98142c03e5748c00e1d1113f3c882"
+
+	for _, id := range []string{cachedID, runningID} {
+		_, err := s.metaDataManager.writeFileMetaDataByTask(ctx, &types.TaskInfo{ID: id, Tenant: "a"})
+		c.Assert(err, check.IsNil)
+	}
+	err := s.metaDataManager.updateStatusAndResult(ctx, cachedID, &fileMetaData{
+		Finish:     true,
+		Success:    true,
+		FileLength: 100,
+	})
+	c.Assert(err, check.IsNil)
+
+	files, err := cm.ListCachedFiles(ctx)
+	c.Assert(err, check.IsNil)
+	c.Check(files, check.DeepEquals, []*mgr.CachedFile{{TaskID: cachedID, Tenant: "a", FileLength: 100}})
+}
@@ -20,7 +20,10 @@ import (
 	"context"
 	"crypto/md5"
 	"fmt"
+	"io"
+	"os"
 	"path"
+	"strings"
 
 	"github.com/dragonflyoss/Dragonfly/apis/types"
 	"github.com/dragonflyoss/Dragonfly/pkg/limitreader"
@@ -29,6 +32,7 @@ import (
 	"github.com/dragonflyoss/Dragonfly/pkg/rangeutils"
 	"github.com/dragonflyoss/Dragonfly/pkg/ratelimiter"
 	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
+	"github.com/dragonflyoss/Dragonfly/pkg/tracing"
 	"github.com/dragonflyoss/Dragonfly/supernode/config"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
 	"github.com/dragonflyoss/Dragonfly/supernode/httpclient"
@@ -54,6 +58,10 @@ type metrics struct {
 	cdnDownloadCount     *prometheus.CounterVec
 	cdnDownloadBytes     *prometheus.CounterVec
 	cdnDownloadFailCount *prometheus.CounterVec
+
+	cdnParentFallbackCount *prometheus.CounterVec
+
+	cdnTenantDownloadBytes *prometheus.CounterVec
 }
 
 func newMetrics(register prometheus.Registerer) *metrics {
@@ -70,18 +78,28 @@ func newMetrics(register prometheus.Registerer) *metrics {
 
 		cdnDownloadFailCount: metricsutils.NewCounter(config.SubsystemSupernode, "cdn_download_failed_total",
 			"Total failure times of cdn download", []string{}, register),
+
+		cdnParentFallbackCount: metricsutils.NewCounter(config.SubsystemSupernode, "cdn_parent_fallback_total",
+			"Total times of falling back to the source when failed to download from the parent supernode", []string{}, register),
+
+		cdnTenantDownloadBytes: metricsutils.NewCounter(config.SubsystemSupernode, "cdn_tenant_download_size_bytes_total",
+			"total file size of cdn downloaded from source in bytes by tenant", []string{"tenant"}, register),
 	}
 }
 
 func init() {
 	mgr.Register(config.CDNPatternLocal, NewManager)
+	mgr.Register(config.CDNPatternParent, NewManager)
 }
 
 // Manager is an implementation of the interface of CDNMgr.
 type Manager struct {
-	cfg             *config.Config
-	cacheStore      *store.Store
-	limiter         *ratelimiter.RateLimiter
+	cfg        *config.Config
+	cacheStore *store.Store
+	limiter    *ratelimiter.RateLimiter
+	// tenantLimiters limits the back-to-source bandwidth of the tenants
+	// which have a backSourceBandwidth configured.
+	tenantLimiters  map[string]*ratelimiter.RateLimiter
 	cdnLocker       *util.LockerPool
 	progressManager mgr.ProgressMgr
 
@@ -92,24 +110,49 @@ type Manager struct {
 	pieceMD5Manager *pieceMD5Mgr
 	writer          *superWriter
 	metrics         *metrics
+
+	// parent downloads the files from the parent supernode,
+	// it's nil unless the cdn pattern is CDNPatternParent.
+	parent *parentDownloader
+
+	// clusterManager decides the owner of a task in cluster mode, and the files
+	// of the tasks owned by the other members are downloaded through the owners.
+	clusterManager mgr.ClusterMgr
 }
 
 // NewManager returns a new Manager.
 func NewManager(cfg *config.Config, cacheStore *store.Store, progressManager mgr.ProgressMgr,
-	originClient httpclient.OriginHTTPClient, register prometheus.Registerer) (mgr.CDNMgr, error) {
-	return newManager(cfg, cacheStore, progressManager, originClient, register)
+	clusterManager mgr.ClusterMgr, originClient httpclient.OriginHTTPClient, register prometheus.Registerer) (mgr.CDNMgr, error) {
+	return newManager(cfg, cacheStore, progressManager, clusterManager, originClient, register)
 }
 
 func newManager(cfg *config.Config, cacheStore *store.Store, progressManager mgr.ProgressMgr,
-	originClient httpclient.OriginHTTPClient, register prometheus.Registerer) (*Manager, error) {
+	clusterManager mgr.ClusterMgr, originClient httpclient.OriginHTTPClient, register prometheus.Registerer) (*Manager, error) {
 	rateLimiter := ratelimiter.NewRateLimiter(ratelimiter.TransRate(int64(cfg.MaxBandwidth-cfg.SystemReservedBandwidth)), 2)
+	tenantLimiters := make(map[string]*ratelimiter.RateLimiter)
+	for _, t := range cfg.Tenants {
+		if t.BackSourceBandwidth > 0 {
+			tenantLimiters[t.ID] = ratelimiter.NewRateLimiter(ratelimiter.TransRate(int64(t.BackSourceBandwidth)), 2)
+		}
+	}
 	metaDataManager := newFileMetaDataManager(cacheStore)
 	pieceMD5Manager := newpieceMD5Mgr()
 	cdnReporter := newReporter(cfg, cacheStore, progressManager, metaDataManager, pieceMD5Manager)
+	writer := newSuperWriter(cacheStore, cdnReporter)
+
+	var parent *parentDownloader
+	if cfg.CDNPattern == config.CDNPatternParent {
+		if stringutils.IsEmptyStr(cfg.ParentSupernode) {
+			return nil, fmt.Errorf("parentSupernode is required by the %s cdn pattern", config.CDNPatternParent)
+		}
+		parent = newParentDownloader(cfg, cfg.ParentSupernode, writer)
+	}
+
 	return &Manager{
 		cfg:             cfg,
 		cacheStore:      cacheStore,
 		limiter:         rateLimiter,
+		tenantLimiters:  tenantLimiters,
 		cdnLocker:       util.NewLockerPool(),
 		progressManager: progressManager,
 		metaDataManager: metaDataManager,
@@ -117,13 +160,25 @@ func newManager(cfg *config.Config, cacheStore *store.Store, progressManager mgr
 		cdnReporter:     cdnReporter,
 		detector:        newCacheDetector(cacheStore, metaDataManager, originClient),
 		originClient:    originClient,
-		writer:          newSuperWriter(cacheStore, cdnReporter),
+		writer:          writer,
 		metrics:         newMetrics(register),
+		parent:          parent,
+		clusterManager:  clusterManager,
 	}, nil
 }
 
 // TriggerCDN will trigger CDN to download the file from sourceUrl.
-func (cm *Manager) TriggerCDN(ctx context.Context, task *types.TaskInfo) (*types.TaskInfo, error) {
+func (cm *Manager) TriggerCDN(ctx context.Context, task *types.TaskInfo) (updateTaskInfo *types.TaskInfo, err error) {
+	ctx, span := tracing.StartSpan(ctx, "cdn.TriggerCDN", tracing.SpanKindInternal)
+	defer func() {
+		span.SetAttribute("task.id", task.ID)
+		if updateTaskInfo != nil {
+			span.SetAttribute("cdn.status", updateTaskInfo.CdnStatus)
+		}
+		span.SetError(err)
+		span.End()
+	}()
+
 	httpFileLength := task.HTTPFileLength
 	if httpFileLength == 0 {
 		httpFileLength = -1
@@ -154,23 +209,46 @@ func (cm *Manager) TriggerCDN(ctx context.Context, task *types.TaskInfo) (*types
 	// get piece content size which not including the piece header and trailer
 	pieceContSize := task.PieceSize - config.PieceWrapSize
 
+	// try to download the file from the parent supernode
+	if parent := cm.parentOf(ctx, task); parent != nil {
+		updateTaskInfo, fallback, err := cm.triggerParent(ctx, parent, task, startPieceNum, httpFileLength)
+		if !fallback {
+			return updateTaskInfo, err
+		}
+	}
+
 	// start to download the source file
+	ctx, sourceSpan := tracing.StartSpan(ctx, "cdn.DownloadSource", tracing.SpanKindClient)
+	defer sourceSpan.End()
+	sourceSpan.SetAttribute("http.url", tracing.SanitizeURL(task.TaskURL))
+	sourceSpan.SetAttribute("piece.start", startPieceNum)
 	resp, err := cm.download(ctx, task.ID, task.RawURL, task.Headers, startPieceNum, httpFileLength, pieceContSize)
 	cm.metrics.cdnDownloadCount.WithLabelValues().Inc()
 	if err != nil {
 		cm.metrics.cdnDownloadFailCount.WithLabelValues().Inc()
+		sourceSpan.SetError(err)
 		return getUpdateTaskInfoWithStatusOnly(types.TaskInfoCdnStatusFAILED), err
 	}
 	defer resp.Body.Close()
+	sourceSpan.SetAttribute("http.status_code", resp.StatusCode)
 
 	cm.updateLastModifiedAndETag(ctx, task.ID, resp.Header.Get("Last-Modified"), resp.Header.Get("Etag"))
-	reader := limitreader.NewLimitReaderWithLimiterAndMD5Sum(resp.Body, cm.limiter, fileMD5)
+	var body io.Reader = resp.Body
+	if limiter, ok := cm.tenantLimiters[task.Tenant]; ok {
+		body = limitreader.NewLimitReaderWithLimiter(limiter, body, false)
+	}
+	reader := limitreader.NewLimitReaderWithLimiterAndMD5Sum(body, cm.limiter, fileMD5)
 	downloadMetadata, err := cm.writer.startWriter(ctx, cm.cfg, reader, task, startPieceNum, httpFileLength, pieceContSize)
 	if err != nil {
+		sourceSpan.SetError(err)
 		logrus.Errorf("failed to write for task %s: %v", task.ID, err)
 		return getUpdateTaskInfoWithStatusOnly(types.TaskInfoCdnStatusFAILED), err
 	}
 	cm.metrics.cdnDownloadBytes.WithLabelValues().Add(float64(downloadMetadata.realHTTPFileLength))
+	sourceSpan.SetAttribute("http.response_content_length", downloadMetadata.realHTTPFileLength)
+	if !stringutils.IsEmptyStr(task.Tenant) {
+		cm.metrics.cdnTenantDownloadBytes.WithLabelValues(task.Tenant).Add(float64(downloadMetadata.realHTTPFileLength))
+	}
 
 	realMD5 := reader.Md5()
 	success, err := cm.handleCDNResult(ctx, task, realMD5, httpFileLength, downloadMetadata.realHTTPFileLength, downloadMetadata.realFileLength)
@@ -263,6 +341,52 @@ func (cm *Manager) Delete(ctx context.Context, taskID string, force bool) error
 	return deleteTaskFiles(ctx, cm.cacheStore, taskID)
 }
 
+// ListCachedFiles returns the files cached successfully on the local disk.
+func (cm *Manager) ListCachedFiles(ctx context.Context) ([]*mgr.CachedFile, error) {
+	var files []*mgr.CachedFile
+	walkTaskIDs := make(map[string]bool)
+	walkFn := func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			logrus.Errorf("failed to access path(%s): %v", path, err)
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		taskID := strings.Split(info.Name(), ".")[0]
+		if walkTaskIDs[taskID] {
+			return nil
+		}
+		walkTaskIDs[taskID] = true
+
+		metaData, err := cm.metaDataManager.readFileMetaData(ctx, taskID)
+		if err != nil {
+			logrus.Debugf("failed to get metadata taskID(%s): %v", taskID, err)
+			return nil
+		}
+		if metaData.Finish && metaData.Success {
+			files = append(files, &mgr.CachedFile{
+				TaskID:     taskID,
+				Tenant:     metaData.Tenant,
+				FileLength: metaData.FileLength,
+			})
+		}
+		return nil
+	}
+
+	raw := &store.Raw{
+		Bucket: config.DownloadHome,
+		WalkFn: walkFn,
+	}
+	if err := cm.cacheStore.Walk(ctx, raw); err != nil {
+		if store.IsKeyNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return files, nil
+}
+
 func (cm *Manager) handleCDNResult(ctx context.Context, task *types.TaskInfo, realMd5 string, httpFileLength, realHTTPFileLength, realFileLength int64) (bool, error) {
 	var isSuccess = true
 	if !stringutils.IsEmptyStr(task.Md5) && task.Md5 != realMd5 {
@@ -0,0 +1,345 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cdn
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/api"
+	dfgetTypes "github.com/dragonflyoss/Dragonfly/dfget/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/constants"
+	"github.com/dragonflyoss/Dragonfly/pkg/fileutils"
+	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+	"github.com/dragonflyoss/Dragonfly/version"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// parentWaitInterval is the interval to pull the pieces again
+	// when the parent supernode has no piece available.
+	parentWaitInterval = time.Second
+
+	// parentPieceTimeout is the timeout to download a piece from the parent supernode.
+	parentPieceTimeout = time.Minute
+
+	// parentFailureLimit is the max number of the pieces which failed to be
+	// downloaded before falling back to the source.
+	parentFailureLimit = 10
+)
+
+// parentDownloader downloads the pieces of a task from the parent supernode
+// in the same way as dfget does in the cdn pattern, and writes them to the local storage.
+type parentDownloader struct {
+	cfg          *config.Config
+	parent       string
+	supernodeAPI api.SupernodeAPI
+	downloadAPI  api.DownloadAPI
+	writer       *superWriter
+}
+
+func newParentDownloader(cfg *config.Config, parent string, writer *superWriter) *parentDownloader {
+	return &parentDownloader{
+		cfg:          cfg,
+		parent:       parent,
+		supernodeAPI: api.NewSupernodeAPIWithAuthToken(cfg.Auth.Token),
+		downloadAPI:  api.NewDownloadAPI(),
+		writer:       writer,
+	}
+}
+
+// parentPieceResult is the result of downloading a piece from the parent supernode.
+type parentPieceResult struct {
+	piece       *dfgetTypes.PullPieceTaskResponseContinueData
+	contentSize int32
+	err         error
+}
+
+// download downloads the pieces which are not cached from the parent supernode,
+// and returns the metadata and the MD5 of the whole file.
+// The pieces before startPieceNum are treated as cached.
+func (pd *parentDownloader) download(ctx context.Context, task *types.TaskInfo, startPieceNum int) (*downloadMetadata, string, error) {
+	cid := pd.cfg.GetSuperCID(task.ID)
+	node, registerResp, err := pd.register(task, cid)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := checkParentTask(task, registerResp.Data); err != nil {
+		return nil, "", err
+	}
+	parentTaskID := registerResp.Data.TaskID
+	pieceContSize := task.PieceSize - config.PieceWrapSize
+	logrus.Infof("start to download taskID(%s) from the parent supernode %s", task.ID, node)
+
+	var (
+		req = &dfgetTypes.PullPieceTaskRequest{
+			SrcCid: cid,
+			TaskID: parentTaskID,
+			Status: constants.TaskStatusStart,
+			Result: constants.ResultInvalid,
+		}
+		results  = make(chan *parentPieceResult)
+		running  = make(map[string]bool)
+		success  = make(map[int]int32)
+		failures = 0
+	)
+	// wait for the running pieces before returning, so that no piece is written
+	// after falling back to the source.
+	defer func() {
+		for range running {
+			<-results
+		}
+	}()
+
+	for {
+		resp, err := pd.supernodeAPI.PullPieceTask(node, req)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "failed to pull piece task from %s", node)
+		}
+
+		switch resp.Code {
+		case constants.CodePeerFinish:
+			finishData := resp.FinishData()
+			if finishData == nil {
+				return nil, "", fmt.Errorf("empty finish data from %s", node)
+			}
+			return newParentDownloadMetadata(success), finishData.Md5, nil
+		case constants.CodePeerContinue:
+			for _, piece := range resp.ContinueData() {
+				if running[piece.Range] {
+					continue
+				}
+				running[piece.Range] = true
+				if piece.PieceNum < startPieceNum {
+					// the piece has been cached on local
+					go func(piece *dfgetTypes.PullPieceTaskResponseContinueData) {
+						results <- &parentPieceResult{piece: piece, contentSize: pieceContSize}
+					}(piece)
+					continue
+				}
+				go func(piece *dfgetTypes.PullPieceTaskResponseContinueData) {
+					contentSize, err := pd.downloadPiece(ctx, task, piece)
+					results <- &parentPieceResult{piece: piece, contentSize: contentSize, err: err}
+				}(piece)
+			}
+		case constants.CodePeerWait, constants.CodePeerLimited:
+			if len(running) == 0 {
+				time.Sleep(parentWaitInterval)
+				continue
+			}
+		default:
+			return nil, "", fmt.Errorf("failed to pull piece task from %s: %d %s", node, resp.Code, resp.Msg)
+		}
+
+		if len(running) == 0 {
+			time.Sleep(parentWaitInterval)
+			continue
+		}
+
+		// report the result of a piece and pull the next pieces
+		result := <-results
+		delete(running, result.piece.Range)
+		req = &dfgetTypes.PullPieceTaskRequest{
+			SrcCid: cid,
+			DstCid: result.piece.Cid,
+			TaskID: parentTaskID,
+			Range:  result.piece.Range,
+			Status: constants.TaskStatusRunning,
+			Result: constants.ResultSuc,
+		}
+		if result.err != nil {
+			failures++
+			logrus.Warnf("failed to download piece %s of taskID(%s) from %s(%d/%d): %v",
+				result.piece.Range, task.ID, result.piece.PeerIP, failures, parentFailureLimit, result.err)
+			if failures >= parentFailureLimit {
+				return nil, "", errors.Wrapf(result.err, "too many pieces failed to be downloaded from %s", node)
+			}
+			req.Result = constants.ResultFail
+			continue
+		}
+		if result.piece.PieceNum < startPieceNum {
+			req.Result = constants.ResultSemiSuc
+		}
+		success[result.piece.PieceNum] = result.contentSize
+	}
+}
+
+// register registers the task at the parent supernode, and follows the
+// redirection once if the parent runs in cluster mode and doesn't own the task.
+func (pd *parentDownloader) register(task *types.TaskInfo, cid string) (string, *dfgetTypes.RegisterResponse, error) {
+	hostname, _ := os.Hostname()
+	req := &dfgetTypes.RegisterRequest{
+		RawURL:     task.RawURL,
+		TaskURL:    task.TaskURL,
+		Cid:        cid,
+		IP:         pd.cfg.AdvertiseIP,
+		HostName:   hostname,
+		Version:    version.DFGetVersion,
+		Md5:        task.Md5,
+		Identifier: task.Identifier,
+		CallSystem: "supernode",
+		Headers:    convertHeadersToList(task.Headers),
+		Pattern:    "cdn",
+		Cluster:    true,
+	}
+	self := net.JoinHostPort(pd.cfg.AdvertiseIP, strconv.Itoa(pd.cfg.ListenPort))
+
+	node := pd.parent
+	for i := 0; i < 2; i++ {
+		req.SupernodeIP = strings.Split(node, ":")[0]
+		resp, err := pd.supernodeAPI.Register(node, req)
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "failed to register to %s", node)
+		}
+		if resp.Code == constants.CodeTaskRedirect && resp.Data != nil && !stringutils.IsEmptyStr(resp.Data.Owner) {
+			if resp.Data.Owner == self {
+				// the members disagree on the owner, e.g. one of them is regarded as down.
+				return "", nil, fmt.Errorf("redirected back by %s", node)
+			}
+			logrus.Infof("follow the redirection of taskID(%s) from %s to %s", task.ID, node, resp.Data.Owner)
+			node = resp.Data.Owner
+			continue
+		}
+		if resp.Code != constants.Success || resp.Data == nil {
+			return "", nil, fmt.Errorf("failed to register to %s: %d %s", node, resp.Code, resp.Msg)
+		}
+		return node, resp, nil
+	}
+	return "", nil, fmt.Errorf("too many redirections from %s", pd.parent)
+}
+
+// checkParentTask checks whether the task on the parent supernode can be
+// written to the local storage piece by piece.
+func checkParentTask(task *types.TaskInfo, data *dfgetTypes.RegisterResponseData) error {
+	if data.CDNSource == types.CdnSourceSource {
+		return fmt.Errorf("the parent supernode serves the task from the source")
+	}
+	if data.PieceSize != task.PieceSize {
+		return fmt.Errorf("piece size not match, parent: %d local: %d", data.PieceSize, task.PieceSize)
+	}
+	if task.HTTPFileLength > 0 && data.FileLength != task.HTTPFileLength {
+		return fmt.Errorf("file length not match, parent: %d local: %d", data.FileLength, task.HTTPFileLength)
+	}
+	return nil
+}
+
+// downloadPiece downloads a piece with its header and tailer, checks it
+// with the piece MD5 and then writes it to the storage.
+func (pd *parentDownloader) downloadPiece(ctx context.Context, task *types.TaskInfo,
+	piece *dfgetTypes.PullPieceTaskResponseContinueData) (int32, error) {
+	resp, err := pd.downloadAPI.Download(piece.PeerIP, piece.PeerPort, &api.DownloadRequest{
+		Path:       piece.Path,
+		PieceRange: piece.Range,
+		PieceNum:   piece.PieceNum,
+		PieceSize:  piece.PieceSize,
+	}, parentPieceTimeout)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	if expected := strings.Split(piece.PieceMd5, ":")[0]; expected != "" {
+		pieceMd5 := md5.New()
+		pieceMd5.Write(data)
+		if real := fileutils.GetMd5Sum(pieceMd5, nil); real != expected {
+			return 0, fmt.Errorf("piece md5 not match, expected: %s real: %s", expected, real)
+		}
+	}
+	if len(data) < config.PieceWrapSize || data[len(data)-1] != config.PieceTailChar {
+		return 0, fmt.Errorf("invalid piece with length %d", len(data))
+	}
+
+	content := data[config.PieceHeadSize : len(data)-1]
+	err = pd.writer.writePiece(ctx, &protocolContent{
+		taskID:           task.ID,
+		pieceNum:         piece.PieceNum,
+		pieceSize:        task.PieceSize,
+		pieceContentSize: int32(len(content)),
+		pieceContent:     bytes.NewBuffer(content),
+	})
+	return int32(len(content)), err
+}
+
+func newParentDownloadMetadata(success map[int]int32) *downloadMetadata {
+	metadata := &downloadMetadata{pieceCount: len(success)}
+	for _, contentSize := range success {
+		metadata.realHTTPFileLength += int64(contentSize)
+		metadata.realFileLength += int64(contentSize) + config.PieceWrapSize
+	}
+	return metadata
+}
+
+func convertHeadersToList(headers map[string]string) []string {
+	var result []string
+	for k, v := range headers {
+		result = append(result, k+":"+v)
+	}
+	return result
+}
+
+// parentOf returns the downloader of the task from the owner of the task if it's
+// owned by another member in cluster mode, so that the file is downloaded from
+// the source only once in the cluster. Otherwise, it returns the downloader from
+// the parent supernode, which is nil unless the cdn pattern is CDNPatternParent.
+func (cm *Manager) parentOf(ctx context.Context, task *types.TaskInfo) *parentDownloader {
+	if cm.clusterManager != nil && cm.clusterManager.Enabled() && !cm.clusterManager.IsOwner(ctx, task.ID) {
+		if owner := cm.clusterManager.Owner(ctx, task.ID); !stringutils.IsEmptyStr(owner) {
+			return newParentDownloader(cm.cfg, owner, cm.writer)
+		}
+	}
+	return cm.parent
+}
+
+// triggerParent downloads the file from the parent supernode.
+// The fallback is true if the file should be downloaded from the source instead.
+func (cm *Manager) triggerParent(ctx context.Context, parent *parentDownloader, task *types.TaskInfo, startPieceNum int,
+	httpFileLength int64) (updateTaskInfo *types.TaskInfo, fallback bool, err error) {
+	metadata, realMD5, err := parent.download(ctx, task, startPieceNum)
+	cm.metrics.cdnDownloadCount.WithLabelValues().Inc()
+	if err != nil {
+		cm.metrics.cdnParentFallbackCount.WithLabelValues().Inc()
+		logrus.Warnf("failed to download taskID(%s) from the parent supernode %s, fall back to the source: %v", task.ID, parent.parent, err)
+		return nil, true, err
+	}
+
+	success, err := cm.handleCDNResult(ctx, task, realMD5, httpFileLength, metadata.realHTTPFileLength, metadata.realFileLength)
+	if err != nil || !success {
+		cm.metrics.cdnDownloadFailCount.WithLabelValues().Inc()
+		return getUpdateTaskInfoWithStatusOnly(types.TaskInfoCdnStatusFAILED), false, err
+	}
+	return getUpdateTaskInfo(types.TaskInfoCdnStatusSUCCESS, realMD5, metadata.realFileLength), false, nil
+}
@@ -0,0 +1,342 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cdn
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	dfgetTypes "github.com/dragonflyoss/Dragonfly/dfget/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/constants"
+	"github.com/dragonflyoss/Dragonfly/pkg/fileutils"
+	"github.com/dragonflyoss/Dragonfly/pkg/rangeutils"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/store"
+
+	"github.com/go-check/check"
+)
+
+type ParentDownloaderTestSuite struct {
+	workHome string
+	store    *store.Store
+}
+
+func init() {
+	check.Suite(&ParentDownloaderTestSuite{})
+}
+
+func (s *ParentDownloaderTestSuite) SetUpSuite(c *check.C) {
+	s.workHome, _ = ioutil.TempDir("/tmp", "supernode-cdn-ParentDownloaderTestSuite-")
+	fileStore, err := store.NewStore(store.LocalStorageDriver, store.NewLocalStorage, "baseDir: "+s.workHome)
+	c.Assert(err, check.IsNil)
+	s.store = fileStore
+}
+
+func (s *ParentDownloaderTestSuite) TearDownSuite(c *check.C) {
+	if s.workHome != "" {
+		if err := os.RemoveAll(s.workHome); err != nil {
+			fmt.Printf("remove path: %s error", s.workHome)
+		}
+	}
+}
+
+// fakeParent serves the supernode APIs and the CDN file of a single task.
+type fakeParent struct {
+	sync.Mutex
+	taskID    string
+	pieceSize int32
+	content   string
+	file      []byte
+	pieces    []string
+	success   map[string]bool
+	server    *httptest.Server
+
+	// owner is the member which the registrations are redirected to if it's not empty.
+	owner string
+	// cluster records whether the last registration supports the cluster.
+	cluster bool
+}
+
+func newFakeParent(taskID, content string, pieceSize int32) *fakeParent {
+	fp := &fakeParent{
+		taskID:    taskID,
+		pieceSize: pieceSize,
+		content:   content,
+		success:   make(map[string]bool),
+	}
+	contSize := int(pieceSize - config.PieceWrapSize)
+	for start := 0; start < len(content); start += contSize {
+		end := start + contSize
+		if end > len(content) {
+			end = len(content)
+		}
+		piece := &bytes.Buffer{}
+		header := make([]byte, 4)
+		binary.BigEndian.PutUint32(header, getPieceHeader(int32(end-start), pieceSize))
+		piece.Write(header)
+		piece.WriteString(content[start:end])
+		piece.WriteByte(config.PieceTailChar)
+
+		offset := len(fp.file)
+		fp.file = append(fp.file, piece.Bytes()...)
+		fp.pieces = append(fp.pieces, fmt.Sprintf("%d-%d", offset, len(fp.file)-1))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/peer/registry", fp.registry)
+	mux.HandleFunc("/peer/task", fp.pullPieceTask)
+	mux.HandleFunc("/download/", fp.download)
+	fp.server = httptest.NewServer(mux)
+	return fp
+}
+
+func (fp *fakeParent) addr() string {
+	return fp.server.Listener.Addr().String()
+}
+
+func (fp *fakeParent) registry(w http.ResponseWriter, r *http.Request) {
+	req := &dfgetTypes.RegisterRequest{}
+	json.NewDecoder(r.Body).Decode(req)
+	fp.Lock()
+	fp.cluster = req.Cluster
+	fp.Unlock()
+
+	if fp.owner != "" {
+		json.NewEncoder(w).Encode(&dfgetTypes.RegisterResponse{
+			BaseResponse: &dfgetTypes.BaseResponse{Code: constants.CodeTaskRedirect},
+			Data:         &dfgetTypes.RegisterResponseData{Owner: fp.owner},
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(&dfgetTypes.RegisterResponse{
+		BaseResponse: &dfgetTypes.BaseResponse{Code: constants.Success},
+		Data: &dfgetTypes.RegisterResponseData{
+			TaskID:     fp.taskID,
+			FileLength: int64(len(fp.content)),
+			PieceSize:  fp.pieceSize,
+			CDNSource:  types.CdnSourceSupernode,
+		},
+	})
+}
+
+func (fp *fakeParent) pullPieceTask(w http.ResponseWriter, r *http.Request) {
+	fp.Lock()
+	defer fp.Unlock()
+
+	params := r.URL.Query()
+	if params.Get("result") == strconv.Itoa(constants.ResultSuc) {
+		fp.success[params.Get("range")] = true
+	}
+
+	var result interface{}
+	switch {
+	case len(fp.success) == len(fp.pieces):
+		result = map[string]interface{}{
+			"code": constants.CodePeerFinish,
+			"data": &dfgetTypes.PullPieceTaskResponseFinishData{
+				Md5:        fmt.Sprintf("%x", md5.Sum([]byte(fp.content))),
+				FileLength: int64(len(fp.file)),
+			},
+		}
+	case params.Get("status") == strconv.Itoa(constants.TaskStatusStart) || params.Get("result") == strconv.Itoa(constants.ResultFail):
+		host, port, _ := net.SplitHostPort(fp.addr())
+		peerPort, _ := strconv.Atoi(port)
+		var data []*dfgetTypes.PullPieceTaskResponseContinueData
+		for pieceNum, pieceRange := range fp.pieces {
+			start, end, _ := rangeutils.ParsePieceIndex(pieceRange)
+			pieceMd5 := md5.New()
+			pieceMd5.Write(fp.file[start : end+1])
+			data = append(data, &dfgetTypes.PullPieceTaskResponseContinueData{
+				Range:     pieceRange,
+				PieceNum:  pieceNum,
+				PieceSize: fp.pieceSize,
+				PieceMd5:  fmt.Sprintf("%s:%d", fileutils.GetMd5Sum(pieceMd5, nil), end-start+1),
+				Cid:       "cdnnode:parent",
+				PeerIP:    host,
+				PeerPort:  peerPort,
+				Path:      "/download/" + fp.taskID,
+			})
+		}
+		result = map[string]interface{}{"code": constants.CodePeerContinue, "data": data}
+	default:
+		result = map[string]interface{}{"code": constants.CodePeerWait}
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+func (fp *fakeParent) download(w http.ResponseWriter, r *http.Request) {
+	rangeStr := strings.TrimPrefix(r.Header.Get("Range"), "bytes=")
+	start, end, err := rangeutils.ParsePieceIndex(rangeStr)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusPartialContent)
+	w.Write(fp.file[start : end+1])
+}
+
+func (s *ParentDownloaderTestSuite) newParentDownloader(parent string) *parentDownloader {
+	cfg := config.NewConfig()
+	cfg.SetCIDPrefix("127.0.0.1")
+	cfg.AdvertiseIP = "127.0.0.1"
+	cfg.ParentSupernode = parent
+	return newParentDownloader(cfg, parent, newSuperWriter(s.store, nil))
+}
+
+func (s *ParentDownloaderTestSuite) TestDownload(c *check.C) {
+	content := "hello dragonfly, hello supernode"
+	task := &types.TaskInfo{
+		ID:             "6816501cbcc3bb92f0b645918c5a4b15495a63259e3e0363008f97e186509e9e",
+		RawURL:         "http://dragonfly.io/file",
+		TaskURL:        "http://dragonfly.io/file",
+		HTTPFileLength: int64(len(content)),
+		PieceSize:      10 + config.PieceWrapSize,
+	}
+	fp := newFakeParent(task.ID, content, task.PieceSize)
+	defer fp.server.Close()
+
+	pd := s.newParentDownloader(fp.addr())
+	metadata, realMD5, err := pd.download(context.Background(), task, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(realMD5, check.Equals, fmt.Sprintf("%x", md5.Sum([]byte(content))))
+	c.Assert(metadata.pieceCount, check.Equals, len(fp.pieces))
+	c.Assert(metadata.realHTTPFileLength, check.Equals, int64(len(content)))
+	c.Assert(metadata.realFileLength, check.Equals, int64(len(fp.file)))
+
+	// the pieces are written as the same as the parent
+	reader, err := s.store.Get(context.Background(), getDownloadRaw(task.ID))
+	c.Assert(err, check.IsNil)
+	data, err := ioutil.ReadAll(reader)
+	c.Assert(err, check.IsNil)
+	c.Assert(data, check.DeepEquals, fp.file)
+}
+
+func (s *ParentDownloaderTestSuite) TestDownloadPieceSizeNotMatch(c *check.C) {
+	task := &types.TaskInfo{
+		ID:        "7816501cbcc3bb92f0b645918c5a4b15495a63259e3e0363008f97e186509e9e",
+		RawURL:    "http://dragonfly.io/file",
+		PieceSize: 10 + config.PieceWrapSize,
+	}
+	fp := newFakeParent(task.ID, "hello dragonfly", 20)
+	defer fp.server.Close()
+
+	pd := s.newParentDownloader(fp.addr())
+	_, _, err := pd.download(context.Background(), task, 0)
+	c.Assert(err, check.NotNil)
+}
+
+func (s *ParentDownloaderTestSuite) TestDownloadParentUnavailable(c *check.C) {
+	task := &types.TaskInfo{
+		ID:        "8816501cbcc3bb92f0b645918c5a4b15495a63259e3e0363008f97e186509e9e",
+		RawURL:    "http://dragonfly.io/file",
+		PieceSize: 10 + config.PieceWrapSize,
+	}
+	fp := newFakeParent(task.ID, "hello dragonfly", task.PieceSize)
+	fp.server.Close()
+
+	pd := s.newParentDownloader(fp.addr())
+	_, _, err := pd.download(context.Background(), task, 0)
+	c.Assert(err, check.NotNil)
+}
+
+func (s *ParentDownloaderTestSuite) TestDownloadFollowRedirection(c *check.C) {
+	content := "hello dragonfly, hello cluster"
+	task := &types.TaskInfo{
+		ID:             "9816501cbcc3bb92f0b645918c5a4b15495a63259e3e0363008f97e186509e9e",
+		RawURL:         "http://dragonfly.io/file",
+		TaskURL:        "http://dragonfly.io/file",
+		HTTPFileLength: int64(len(content)),
+		PieceSize:      10 + config.PieceWrapSize,
+	}
+	owner := newFakeParent(task.ID, content, task.PieceSize)
+	defer owner.server.Close()
+	parent := newFakeParent(task.ID, "", task.PieceSize)
+	defer parent.server.Close()
+	parent.owner = owner.addr()
+
+	pd := s.newParentDownloader(parent.addr())
+	_, realMD5, err := pd.download(context.Background(), task, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(realMD5, check.Equals, fmt.Sprintf("%x", md5.Sum([]byte(content))))
+	c.Assert(parent.cluster, check.Equals, true)
+	c.Assert(owner.cluster, check.Equals, true)
+	c.Assert(len(owner.success), check.Equals, len(owner.pieces))
+}
+
+func (s *ParentDownloaderTestSuite) TestDownloadRedirectedBack(c *check.C) {
+	task := &types.TaskInfo{
+		ID:        "a816501cbcc3bb92f0b645918c5a4b15495a63259e3e0363008f97e186509e9e",
+		RawURL:    "http://dragonfly.io/file",
+		PieceSize: 10 + config.PieceWrapSize,
+	}
+	parent := newFakeParent(task.ID, "hello dragonfly", task.PieceSize)
+	defer parent.server.Close()
+
+	pd := s.newParentDownloader(parent.addr())
+	parent.owner = net.JoinHostPort(pd.cfg.AdvertiseIP, strconv.Itoa(pd.cfg.ListenPort))
+	_, _, err := pd.download(context.Background(), task, 0)
+	c.Assert(err, check.NotNil)
+}
+
+func (s *ParentDownloaderTestSuite) TestParentOf(c *check.C) {
+	cfg := config.NewConfig()
+	task := &types.TaskInfo{ID: "b816501cbcc3bb92f0b645918c5a4b15495a63259e3e0363008f97e186509e9e"}
+
+	cm := &Manager{cfg: cfg}
+	c.Assert(cm.parentOf(context.Background(), task), check.IsNil)
+
+	cm.clusterManager = &fakeClusterMgr{owner: "127.0.0.2:8002"}
+	c.Assert(cm.parentOf(context.Background(), task).parent, check.Equals, "127.0.0.2:8002")
+
+	// the tasks owned by this supernode are downloaded from the parent supernode if any.
+	cm.parent = newParentDownloader(cfg, "127.0.0.3:8002", nil)
+	cm.clusterManager = &fakeClusterMgr{}
+	c.Assert(cm.parentOf(context.Background(), task), check.Equals, cm.parent)
+}
+
+// fakeClusterMgr regards the owner as the owner of all tasks,
+// and this supernode as the owner if it's empty.
+type fakeClusterMgr struct {
+	mgr.ClusterMgr
+	owner string
+}
+
+func (m *fakeClusterMgr) Enabled() bool {
+	return true
+}
+
+func (m *fakeClusterMgr) Owner(ctx context.Context, taskID string) string {
+	return m.owner
+}
+
+func (m *fakeClusterMgr) IsOwner(ctx context.Context, taskID string) bool {
+	return m.owner == ""
+}
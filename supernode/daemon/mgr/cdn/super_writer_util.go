@@ -57,29 +57,34 @@ func (cw *superWriter) writerPool(ctx context.Context, wg *sync.WaitGroup, n int
 		wg.Add(1)
 		go func(i int) {
 			for job := range jobCh {
-				var pieceMd5 = md5.New()
-				if err := cw.writeToFile(ctx, job.pieceContent, job.taskID, job.pieceNum, job.pieceContentSize, job.pieceSize, pieceMd5); err != nil {
-					logrus.Errorf("failed to write taskID %s pieceNum %d file: %v", job.taskID, job.pieceNum, err)
-					// NOTE: should we redo the job?
-					continue
-				}
-
-				// report piece status
-				pieceSum := fileutils.GetMd5Sum(pieceMd5, nil)
-				pieceMd5Value := getPieceMd5Value(pieceSum, job.pieceContentSize+config.PieceWrapSize)
-				if cw.cdnReporter != nil {
-					if err := cw.cdnReporter.reportPieceStatus(ctx, job.taskID, job.pieceNum, pieceMd5Value, config.PieceSUCCESS); err != nil {
-						// NOTE: should we do this job again?
-						logrus.Errorf("failed to report piece status taskID %s pieceNum %d pieceMD5 %s: %v", job.taskID, job.pieceNum, pieceMd5Value, err)
-						continue
-					}
-				}
+				// NOTE: should we redo the job when failed?
+				cw.writePiece(ctx, job)
 			}
 			wg.Done()
 		}(i)
 	}
 }
 
+// writePiece writes the piece to the storage and reports the piece status.
+func (cw *superWriter) writePiece(ctx context.Context, job *protocolContent) error {
+	var pieceMd5 = md5.New()
+	if err := cw.writeToFile(ctx, job.pieceContent, job.taskID, job.pieceNum, job.pieceContentSize, job.pieceSize, pieceMd5); err != nil {
+		logrus.Errorf("failed to write taskID %s pieceNum %d file: %v", job.taskID, job.pieceNum, err)
+		return err
+	}
+
+	// report piece status
+	pieceSum := fileutils.GetMd5Sum(pieceMd5, nil)
+	pieceMd5Value := getPieceMd5Value(pieceSum, job.pieceContentSize+config.PieceWrapSize)
+	if cw.cdnReporter != nil {
+		if err := cw.cdnReporter.reportPieceStatus(ctx, job.taskID, job.pieceNum, pieceMd5Value, config.PieceSUCCESS); err != nil {
+			logrus.Errorf("failed to report piece status taskID %s pieceNum %d pieceMD5 %s: %v", job.taskID, job.pieceNum, pieceMd5Value, err)
+			return err
+		}
+	}
+	return nil
+}
+
 // writeToFile wraps the piece content with piece header and tailer,
 // and then writes to the storage.
 func (cw *superWriter) writeToFile(ctx context.Context, bytesBuffer *bytes.Buffer, taskID string, pieceNum int, pieceContSize, pieceSize int32, pieceMd5 hash.Hash) error {
@@ -29,7 +29,7 @@ import (
 )
 
 type CDNBuilder func(cfg *config.Config, cacheStore *store.Store, progressManager ProgressMgr,
-	originClient httpclient.OriginHTTPClient, register prometheus.Registerer) (CDNMgr, error)
+	clusterManager ClusterMgr, originClient httpclient.OriginHTTPClient, register prometheus.Registerer) (CDNMgr, error)
 
 var cdnBuilderMap = make(map[config.CDNPattern]CDNBuilder)
 
@@ -38,7 +38,7 @@ func Register(name config.CDNPattern, builder CDNBuilder) {
 }
 
 func GetCDNManager(cfg *config.Config, cacheStore *store.Store, progressManager ProgressMgr,
-	originClient httpclient.OriginHTTPClient, register prometheus.Registerer) (CDNMgr, error) {
+	clusterManager ClusterMgr, originClient httpclient.OriginHTTPClient, register prometheus.Registerer) (CDNMgr, error) {
 	name := cfg.CDNPattern
 	if name == "" {
 		name = config.CDNPatternLocal
@@ -46,10 +46,10 @@ func GetCDNManager(cfg *config.Config, cacheStore *store.Store, progressManager
 
 	cdnBuilder, ok := cdnBuilderMap[name]
 	if !ok {
-		return nil, fmt.Errorf("unexpected cdn pattern(%s) which must be in [\"local\", \"source\"]", name)
+		return nil, fmt.Errorf("unexpected cdn pattern(%s) which must be in [\"local\", \"source\", \"parent\"]", name)
 	}
 
-	return cdnBuilder(cfg, cacheStore, progressManager, originClient, register)
+	return cdnBuilder(cfg, cacheStore, progressManager, clusterManager, originClient, register)
 }
 
 // CDNMgr as an interface defines all operations against CDN and
@@ -86,4 +86,14 @@ type CDNMgr interface {
 	// Delete the cdn meta with specified taskID.
 	// The file on the disk will be deleted when the force is true.
 	Delete(ctx context.Context, taskID string, force bool) error
+
+	// ListCachedFiles returns the files cached successfully on the local disk.
+	ListCachedFiles(ctx context.Context) ([]*CachedFile, error)
+}
+
+// CachedFile describes a file cached by CDN.
+type CachedFile struct {
+	TaskID     string
+	Tenant     string
+	FileLength int64
 }
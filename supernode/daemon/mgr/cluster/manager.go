@@ -0,0 +1,235 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/algorithm"
+	"github.com/dragonflyoss/Dragonfly/pkg/hashcircler"
+	"github.com/dragonflyoss/Dragonfly/pkg/metricsutils"
+	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var _ mgr.ClusterMgr = &Manager{}
+
+const (
+	// defaultProbeInterval is the interval to probe the other members.
+	defaultProbeInterval = 5 * time.Second
+
+	// defaultProbeTimeout is the timeout of a single probe.
+	defaultProbeTimeout = 3 * time.Second
+
+	// defaultFailureThreshold is the number of the continuous failed probes
+	// after which a member is treated as down.
+	defaultFailureThreshold = 3
+)
+
+type metrics struct {
+	aliveMembers prometheus.Gauge
+}
+
+func newMetrics(register prometheus.Registerer) *metrics {
+	return &metrics{
+		aliveMembers: metricsutils.NewGauge(config.SubsystemSupernode, "cluster_alive_members",
+			"Current number of alive members in the supernode cluster", []string{}, register).WithLabelValues(),
+	}
+}
+
+// ProbeFunc checks whether the member is alive.
+type ProbeFunc func(member string) error
+
+// Manager is an implementation of the interface of ClusterMgr.
+// It keeps the members in a consistent hash circle, from which the down members
+// are removed until they come back.
+type Manager struct {
+	self    string
+	members []string
+	hc      hashcircler.HashCircler
+
+	mutex    sync.RWMutex
+	alive    map[string]bool
+	failures map[string]int
+
+	probe            ProbeFunc
+	probeInterval    time.Duration
+	failureThreshold int
+	metrics          *metrics
+}
+
+// NewManager returns a new Manager.
+func NewManager(cfg *config.Config, register prometheus.Registerer) (*Manager, error) {
+	cm := &Manager{
+		alive:            make(map[string]bool),
+		failures:         make(map[string]int),
+		probe:            httpProbe,
+		probeInterval:    defaultProbeInterval,
+		failureThreshold: defaultFailureThreshold,
+		metrics:          newMetrics(register),
+	}
+	if len(cfg.ClusterMembers) == 0 {
+		return cm, nil
+	}
+
+	if stringutils.IsEmptyStr(cfg.AdvertiseIP) {
+		return nil, fmt.Errorf("advertiseIP is required by the cluster mode")
+	}
+	cm.self = net.JoinHostPort(cfg.AdvertiseIP, strconv.Itoa(cfg.ListenPort))
+	cm.members = algorithm.DedupStringArr(append([]string{cm.self}, cfg.ClusterMembers...))
+
+	hc, err := hashcircler.NewConsistentHashCircler(cm.members, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the hash circle of cluster members")
+	}
+	cm.hc = hc
+	for _, member := range cm.members {
+		cm.alive[member] = true
+	}
+	cm.metrics.aliveMembers.Set(float64(len(cm.members)))
+	return cm, nil
+}
+
+// StartHealthCheck starts to probe the other members with a new goroutine.
+func (cm *Manager) StartHealthCheck(ctx context.Context) {
+	if !cm.Enabled() {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(cm.probeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cm.probeMembers()
+			}
+		}
+	}()
+}
+
+// Enabled returns whether the supernode runs in the cluster mode.
+func (cm *Manager) Enabled() bool {
+	return cm.hc != nil
+}
+
+// Owner returns the address of the alive member which owns the task.
+func (cm *Manager) Owner(ctx context.Context, taskID string) string {
+	if !cm.Enabled() {
+		return ""
+	}
+
+	owner, err := cm.hc.Hash(taskID)
+	if err != nil {
+		// all the other members are down, and this supernode serves the task itself.
+		return cm.self
+	}
+	return owner
+}
+
+// IsOwner returns whether the task is owned by this supernode.
+func (cm *Manager) IsOwner(ctx context.Context, taskID string) bool {
+	if !cm.Enabled() {
+		return true
+	}
+	return cm.Owner(ctx, taskID) == cm.self
+}
+
+// Members returns all members and whether they are alive.
+func (cm *Manager) Members(ctx context.Context) map[string]bool {
+	cm.mutex.RLock()
+	defer cm.mutex.RUnlock()
+
+	result := make(map[string]bool, len(cm.alive))
+	for member, alive := range cm.alive {
+		result[member] = alive
+	}
+	return result
+}
+
+// probeMembers probes the other members concurrently and updates their status.
+func (cm *Manager) probeMembers() {
+	var wg sync.WaitGroup
+	for _, member := range cm.members {
+		if member == cm.self {
+			continue
+		}
+		wg.Add(1)
+		go func(member string) {
+			defer wg.Done()
+			cm.updateMember(member, cm.probe(member))
+		}(member)
+	}
+	wg.Wait()
+}
+
+// updateMember removes the member from the hash circle when it fails continuously,
+// and adds it back once it's alive again.
+func (cm *Manager) updateMember(member string, probeErr error) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if probeErr == nil {
+		cm.failures[member] = 0
+		if !cm.alive[member] {
+			cm.alive[member] = true
+			cm.hc.Add(member)
+			cm.metrics.aliveMembers.Inc()
+			logrus.Infof("cluster member %s is alive again", member)
+		}
+		return
+	}
+
+	cm.failures[member]++
+	logrus.Debugf("failed to probe cluster member %s(%d/%d): %v",
+		member, cm.failures[member], cm.failureThreshold, probeErr)
+	if cm.alive[member] && cm.failures[member] >= cm.failureThreshold {
+		cm.alive[member] = false
+		cm.hc.Delete(member)
+		cm.metrics.aliveMembers.Dec()
+		logrus.Warnf("cluster member %s is down, its tasks will be taken over by the others: %v", member, probeErr)
+	}
+}
+
+var probeClient = &http.Client{Timeout: defaultProbeTimeout}
+
+// httpProbe checks the member by its ping API.
+func httpProbe(member string) error {
+	resp, err := probeClient.Get(fmt.Sprintf("http://%s/_ping", member))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
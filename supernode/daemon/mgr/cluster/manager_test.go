@@ -0,0 +1,143 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/digest"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+
+	"github.com/go-check/check"
+	"github.com/prometheus/client_golang/prometheus"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test(t *testing.T) {
+	check.TestingT(t)
+}
+
+func init() {
+	check.Suite(&ClusterMgrTestSuite{})
+}
+
+type ClusterMgrTestSuite struct {
+}
+
+func newTestManager(c *check.C, members ...string) *Manager {
+	cfg := config.NewConfig()
+	cfg.AdvertiseIP = "127.0.0.1"
+	cfg.ListenPort = 8002
+	cfg.ClusterMembers = members
+	cm, err := NewManager(cfg, prometheus.NewRegistry())
+	c.Assert(err, check.IsNil)
+	return cm
+}
+
+func (s *ClusterMgrTestSuite) TestDisabled(c *check.C) {
+	cm := newTestManager(c)
+	ctx := context.Background()
+
+	c.Assert(cm.Enabled(), check.Equals, false)
+	c.Assert(cm.Owner(ctx, "task"), check.Equals, "")
+	c.Assert(cm.IsOwner(ctx, "task"), check.Equals, true)
+	c.Assert(cm.Members(ctx), check.HasLen, 0)
+}
+
+func (s *ClusterMgrTestSuite) TestAdvertiseIPRequired(c *check.C) {
+	cfg := config.NewConfig()
+	cfg.ClusterMembers = []string{"127.0.0.2:8002"}
+	_, err := NewManager(cfg, prometheus.NewRegistry())
+	c.Assert(err, check.NotNil)
+}
+
+func (s *ClusterMgrTestSuite) TestOwner(c *check.C) {
+	ctx := context.Background()
+	// the self address is added if it's missing in the members
+	cm := newTestManager(c, "127.0.0.2:8002", "127.0.0.3:8002")
+	c.Assert(cm.Enabled(), check.Equals, true)
+	c.Assert(cm.Members(ctx), check.DeepEquals, map[string]bool{
+		"127.0.0.1:8002": true,
+		"127.0.0.2:8002": true,
+		"127.0.0.3:8002": true,
+	})
+
+	// the same task is always owned by the same member,
+	// and every member takes a part of the tasks.
+	owned := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		taskID := digest.Sha256(fmt.Sprintf("task-%d", i))
+		owner := cm.Owner(ctx, taskID)
+		c.Assert(cm.Owner(ctx, taskID), check.Equals, owner)
+		c.Assert(cm.IsOwner(ctx, taskID), check.Equals, owner == "127.0.0.1:8002")
+		owned[owner]++
+	}
+	c.Assert(owned, check.HasLen, 3)
+
+	// the members in another supernode have the same view of ownership
+	other := newTestManager(c, "127.0.0.1:8002", "127.0.0.2:8002", "127.0.0.3:8002")
+	for i := 0; i < 100; i++ {
+		taskID := digest.Sha256(fmt.Sprintf("task-%d", i))
+		c.Assert(other.Owner(ctx, taskID), check.Equals, cm.Owner(ctx, taskID))
+	}
+}
+
+func (s *ClusterMgrTestSuite) TestHealthCheck(c *check.C) {
+	ctx := context.Background()
+	cm := newTestManager(c, "127.0.0.2:8002", "127.0.0.3:8002")
+	down := map[string]bool{"127.0.0.2:8002": true}
+	cm.probe = func(member string) error {
+		if down[member] {
+			return fmt.Errorf("connection refused")
+		}
+		return nil
+	}
+
+	ownerOf := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		taskID := digest.Sha256(fmt.Sprintf("task-%d", i))
+		ownerOf[taskID] = cm.Owner(ctx, taskID)
+	}
+
+	// the member is still alive before reaching the failure threshold
+	for i := 0; i < defaultFailureThreshold-1; i++ {
+		cm.probeMembers()
+	}
+	c.Assert(cm.Members(ctx)["127.0.0.2:8002"], check.Equals, true)
+
+	cm.probeMembers()
+	c.Assert(cm.Members(ctx)["127.0.0.2:8002"], check.Equals, false)
+	c.Assert(int(prom_testutil.ToFloat64(cm.metrics.aliveMembers)), check.Equals, 2)
+	for taskID, owner := range ownerOf {
+		if owner == "127.0.0.2:8002" {
+			c.Assert(cm.Owner(ctx, taskID), check.Not(check.Equals), owner)
+			continue
+		}
+		c.Assert(cm.Owner(ctx, taskID), check.Equals, owner)
+	}
+
+	// the member takes its tasks back once it's alive again
+	delete(down, "127.0.0.2:8002")
+	cm.probeMembers()
+	c.Assert(cm.Members(ctx)["127.0.0.2:8002"], check.Equals, true)
+	c.Assert(int(prom_testutil.ToFloat64(cm.metrics.aliveMembers)), check.Equals, 3)
+	for taskID, owner := range ownerOf {
+		c.Assert(cm.Owner(ctx, taskID), check.Equals, owner)
+	}
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgr
+
+import (
+	"context"
+)
+
+// ClusterMgr as an interface defines all operations about the supernode cluster.
+type ClusterMgr interface {
+	// StartHealthCheck starts to probe the other members with a new goroutine.
+	StartHealthCheck(ctx context.Context)
+
+	// Enabled returns whether the supernode runs in the cluster mode.
+	Enabled() bool
+
+	// Owner returns the address(ip:listenPort) of the alive member which owns the task.
+	// An empty string will be returned if the cluster mode is disabled.
+	Owner(ctx context.Context, taskID string) string
+
+	// IsOwner returns whether the task is owned by this supernode.
+	// It always returns true if the cluster mode is disabled.
+	IsOwner(ctx context.Context, taskID string) bool
+
+	// Members returns all members and whether they are alive.
+	Members(ctx context.Context) map[string]bool
+}
@@ -18,6 +18,7 @@ package dfgettask
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -25,9 +26,9 @@ import (
 	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
 	"github.com/dragonflyoss/Dragonfly/pkg/metricsutils"
 	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
-	"github.com/dragonflyoss/Dragonfly/pkg/syncmap"
 	"github.com/dragonflyoss/Dragonfly/supernode/config"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
 	dutil "github.com/dragonflyoss/Dragonfly/supernode/daemon/util"
 
 	"github.com/pkg/errors"
@@ -64,16 +65,16 @@ func newMetrics(register prometheus.Registerer) *metrics {
 type Manager struct {
 	cfg            *config.Config
 	dfgetTaskStore *dutil.Store
-	ptoc           *syncmap.SyncMap
+	ptoc           *dutil.Store
 	metrics        *metrics
 }
 
 // NewManager returns a new Manager.
-func NewManager(cfg *config.Config, register prometheus.Registerer) (*Manager, error) {
+func NewManager(cfg *config.Config, backend state.Backend, register prometheus.Registerer) (*Manager, error) {
 	return &Manager{
 		cfg:            cfg,
-		dfgetTaskStore: dutil.NewStore(),
-		ptoc:           syncmap.NewSyncMap(),
+		dfgetTaskStore: dutil.NewBackendStore(backend, state.BucketDfgetTask, decodeDfgetTask),
+		ptoc:           dutil.NewBackendStore(backend, state.BucketPeerTask, decodeCID),
 		metrics:        newMetrics(register),
 	}, nil
 }
@@ -103,7 +104,7 @@ func (dtm *Manager) Add(ctx context.Context, dfgetTask *types.DfGetTask) error {
 
 	// TODO: should we verify that the peerID is valid here.
 
-	dtm.ptoc.Add(generatePeerKey(dfgetTask.PeerID, dfgetTask.TaskID), dfgetTask.CID)
+	dtm.ptoc.Put(generatePeerKey(dfgetTask.PeerID, dfgetTask.TaskID), dfgetTask.CID)
 	dtm.dfgetTaskStore.Put(key, dfgetTask)
 
 	// If dfget task is created by supernode cdn, don't update metrics.
@@ -122,7 +123,16 @@ func (dtm *Manager) Get(ctx context.Context, clientID, taskID string) (dfgetTask
 
 // GetCIDByPeerIDAndTaskID returns cid with specified peerID and taskID.
 func (dtm *Manager) GetCIDByPeerIDAndTaskID(ctx context.Context, peerID, taskID string) (string, error) {
-	return dtm.ptoc.GetAsString(generatePeerKey(peerID, taskID))
+	key := generatePeerKey(peerID, taskID)
+	v, err := dtm.ptoc.Get(key)
+	if err != nil {
+		return "", err
+	}
+
+	if cid, ok := v.(string); ok {
+		return cid, nil
+	}
+	return "", errors.Wrapf(errortypes.ErrConvertFailed, "key %s: %v", key, v)
 }
 
 // GetCIDsByTaskID returns cids as a string slice with specified taskID.
@@ -222,7 +232,11 @@ func (dtm *Manager) UpdateStatus(ctx context.Context, clientID, taskID, status s
 		dtm.metrics.dfgetTasksFailCount.WithLabelValues(dfgetTask.CallSystem).Inc()
 	}
 
-	return nil
+	key, err := generateKey(clientID, taskID)
+	if err != nil {
+		return err
+	}
+	return dtm.dfgetTaskStore.Put(key, dfgetTask)
 }
 
 // getDfgetTask gets a DfGetTask from dfgetTaskStore with specified clientID and taskID.
@@ -243,6 +257,22 @@ func (dtm *Manager) getDfgetTask(clientID, taskID string) (*types.DfGetTask, err
 	return nil, errors.Wrapf(errortypes.ErrConvertFailed, "clientID: %s, taskID: %s: %v", clientID, taskID, v)
 }
 
+func decodeDfgetTask(data []byte) (interface{}, error) {
+	dfgetTask := &types.DfGetTask{}
+	if err := json.Unmarshal(data, dfgetTask); err != nil {
+		return nil, err
+	}
+	return dfgetTask, nil
+}
+
+func decodeCID(data []byte) (interface{}, error) {
+	var cid string
+	if err := json.Unmarshal(data, &cid); err != nil {
+		return nil, err
+	}
+	return cid, nil
+}
+
 // generateKey generates a key for a dfgetTask.
 func generateKey(cID, taskID string) (string, error) {
 	if stringutils.IsEmptyStr(cID) {
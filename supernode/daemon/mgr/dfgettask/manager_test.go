@@ -47,7 +47,7 @@ func (s *DfgetTaskMgrTestSuite) SetUpSuite(c *check.C) {
 }
 
 func (s *DfgetTaskMgrTestSuite) TestDfgetTaskAdd(c *check.C) {
-	manager, _ := NewManager(s.cfg, prometheus.NewRegistry())
+	manager, _ := NewManager(s.cfg, nil, prometheus.NewRegistry())
 	dfgetTasks := manager.metrics.dfgetTasks
 	dfgetTasksRegisterCount := manager.metrics.dfgetTasksRegisterCount
 
@@ -116,7 +116,7 @@ func (s *DfgetTaskMgrTestSuite) TestDfgetTaskAdd(c *check.C) {
 }
 
 func (s *DfgetTaskMgrTestSuite) TestDfgetTaskUpdate(c *check.C) {
-	manager, _ := NewManager(s.cfg, prometheus.NewRegistry())
+	manager, _ := NewManager(s.cfg, nil, prometheus.NewRegistry())
 	dfgetTasksFailCount := manager.metrics.dfgetTasksFailCount
 
 	var testCases = []struct {
@@ -190,7 +190,7 @@ func (s *DfgetTaskMgrTestSuite) TestDfgetTaskUpdate(c *check.C) {
 }
 
 func (s *DfgetTaskMgrTestSuite) TestDfgetTaskDelete(c *check.C) {
-	manager, _ := NewManager(s.cfg, prometheus.NewRegistry())
+	manager, _ := NewManager(s.cfg, nil, prometheus.NewRegistry())
 	dfgetTasks := manager.metrics.dfgetTasks
 
 	var testCases = []struct {
@@ -125,6 +125,21 @@ func (mr *MockCDNMgrMockRecorder) GetStatus(ctx, taskID interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatus", reflect.TypeOf((*MockCDNMgr)(nil).GetStatus), ctx, taskID)
 }
 
+// ListCachedFiles mocks base method.
+func (m *MockCDNMgr) ListCachedFiles(ctx context.Context) ([]*mgr.CachedFile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCachedFiles", ctx)
+	ret0, _ := ret[0].([]*mgr.CachedFile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCachedFiles indicates an expected call of ListCachedFiles.
+func (mr *MockCDNMgrMockRecorder) ListCachedFiles(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCachedFiles", reflect.TypeOf((*MockCDNMgr)(nil).ListCachedFiles), ctx)
+}
+
 // TriggerCDN mocks base method.
 func (m *MockCDNMgr) TriggerCDN(ctx context.Context, taskInfo *types.TaskInfo) (*types.TaskInfo, error) {
 	m.ctrl.T.Helper()
@@ -81,6 +81,20 @@ func (mr *MockProgressMgrMockRecorder) DeletePeerIDByPieceNum(ctx, taskID, piece
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePeerIDByPieceNum", reflect.TypeOf((*MockProgressMgr)(nil).DeletePeerIDByPieceNum), ctx, taskID, pieceNum, peerID)
 }
 
+// DeleteSeed mocks base method.
+func (m *MockProgressMgr) DeleteSeed(ctx context.Context, taskID, peerID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSeed", ctx, taskID, peerID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSeed indicates an expected call of DeleteSeed.
+func (mr *MockProgressMgrMockRecorder) DeleteSeed(ctx, taskID, peerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSeed", reflect.TypeOf((*MockProgressMgr)(nil).DeleteSeed), ctx, taskID, peerID)
+}
+
 // DeleteTaskID mocks base method.
 func (m *MockProgressMgr) DeleteTaskID(ctx context.Context, taskID string, pieceTotal int) error {
 	m.ctrl.T.Helper()
@@ -170,18 +184,117 @@ func (mr *MockProgressMgrMockRecorder) GetPieceProgressByCID(ctx, taskID, client
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPieceProgressByCID", reflect.TypeOf((*MockProgressMgr)(nil).GetPieceProgressByCID), ctx, taskID, clientID, filter)
 }
 
+// GetRunningPiecesByCID mocks base method.
+func (m *MockProgressMgr) GetRunningPiecesByCID(ctx context.Context, clientID string) (map[int]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRunningPiecesByCID", ctx, clientID)
+	ret0, _ := ret[0].(map[int]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRunningPiecesByCID indicates an expected call of GetRunningPiecesByCID.
+func (mr *MockProgressMgrMockRecorder) GetRunningPiecesByCID(ctx, clientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRunningPiecesByCID", reflect.TypeOf((*MockProgressMgr)(nil).GetRunningPiecesByCID), ctx, clientID)
+}
+
+// GetSeedPeerIDs mocks base method.
+func (m *MockProgressMgr) GetSeedPeerIDs(ctx context.Context, taskID string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSeedPeerIDs", ctx, taskID)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSeedPeerIDs indicates an expected call of GetSeedPeerIDs.
+func (mr *MockProgressMgrMockRecorder) GetSeedPeerIDs(ctx, taskID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSeedPeerIDs", reflect.TypeOf((*MockProgressMgr)(nil).GetSeedPeerIDs), ctx, taskID)
+}
+
+// GetSuperLoad mocks base method.
+func (m *MockProgressMgr) GetSuperLoad(ctx context.Context, taskID string) (int32, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSuperLoad", ctx, taskID)
+	ret0, _ := ret[0].(int32)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSuperLoad indicates an expected call of GetSuperLoad.
+func (mr *MockProgressMgrMockRecorder) GetSuperLoad(ctx, taskID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSuperLoad", reflect.TypeOf((*MockProgressMgr)(nil).GetSuperLoad), ctx, taskID)
+}
+
 // InitProgress mocks base method.
-func (m *MockProgressMgr) InitProgress(ctx context.Context, taskID, peerID, clientID string, peerPattern config.Pattern) error {
+func (m *MockProgressMgr) InitProgress(ctx context.Context, taskID, peerID, clientID string, peerPattern config.Pattern, tenant string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "InitProgress", ctx, taskID, peerID, clientID, peerPattern)
+	ret := m.ctrl.Call(m, "InitProgress", ctx, taskID, peerID, clientID, peerPattern, tenant)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // InitProgress indicates an expected call of InitProgress.
-func (mr *MockProgressMgrMockRecorder) InitProgress(ctx, taskID, peerID, clientID, peerPattern interface{}) *gomock.Call {
+func (mr *MockProgressMgrMockRecorder) InitProgress(ctx, taskID, peerID, clientID, peerPattern, tenant interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitProgress", reflect.TypeOf((*MockProgressMgr)(nil).InitProgress), ctx, taskID, peerID, clientID, peerPattern, tenant)
+}
+
+// IsPeerQuarantined mocks base method.
+func (m *MockProgressMgr) IsPeerQuarantined(ctx context.Context, peerAddr string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsPeerQuarantined", ctx, peerAddr)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsPeerQuarantined indicates an expected call of IsPeerQuarantined.
+func (mr *MockProgressMgrMockRecorder) IsPeerQuarantined(ctx, peerAddr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsPeerQuarantined", reflect.TypeOf((*MockProgressMgr)(nil).IsPeerQuarantined), ctx, peerAddr)
+}
+
+// QuarantinePeer mocks base method.
+func (m *MockProgressMgr) QuarantinePeer(ctx context.Context, peerAddr string, expireTime int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QuarantinePeer", ctx, peerAddr, expireTime)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// QuarantinePeer indicates an expected call of QuarantinePeer.
+func (mr *MockProgressMgrMockRecorder) QuarantinePeer(ctx, peerAddr, expireTime interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuarantinePeer", reflect.TypeOf((*MockProgressMgr)(nil).QuarantinePeer), ctx, peerAddr, expireTime)
+}
+
+// ReportPeerError mocks base method.
+func (m *MockProgressMgr) ReportPeerError(ctx context.Context, peerID, errorType string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReportPeerError", ctx, peerID, errorType)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReportPeerError indicates an expected call of ReportPeerError.
+func (mr *MockProgressMgrMockRecorder) ReportPeerError(ctx, peerID, errorType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReportPeerError", reflect.TypeOf((*MockProgressMgr)(nil).ReportPeerError), ctx, peerID, errorType)
+}
+
+// StartFlushProgress mocks base method.
+func (m *MockProgressMgr) StartFlushProgress(ctx context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "StartFlushProgress", ctx)
+}
+
+// StartFlushProgress indicates an expected call of StartFlushProgress.
+func (mr *MockProgressMgrMockRecorder) StartFlushProgress(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitProgress", reflect.TypeOf((*MockProgressMgr)(nil).InitProgress), ctx, taskID, peerID, clientID, peerPattern)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartFlushProgress", reflect.TypeOf((*MockProgressMgr)(nil).StartFlushProgress), ctx)
 }
 
 // UpdateClientProgress mocks base method.
@@ -226,6 +339,21 @@ func (mr *MockProgressMgrMockRecorder) UpdateProgress(ctx, taskID, srcCID, srcPI
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProgress", reflect.TypeOf((*MockProgressMgr)(nil).UpdateProgress), ctx, taskID, srcCID, srcPID, dstPID, pieceNum, pieceStatus)
 }
 
+// UpdateSeeds mocks base method.
+func (m *MockProgressMgr) UpdateSeeds(ctx context.Context, taskID string, elect func([]string, bool) []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSeeds", ctx, taskID, elect)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateSeeds indicates an expected call of UpdateSeeds.
+func (mr *MockProgressMgrMockRecorder) UpdateSeeds(ctx, taskID, elect interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSeeds", reflect.TypeOf((*MockProgressMgr)(nil).UpdateSeeds), ctx, taskID, elect)
+}
+
 // UpdateSuperLoad mocks base method.
 func (m *MockProgressMgr) UpdateSuperLoad(ctx context.Context, taskID string, delta, limit int32) (bool, error) {
 	m.ctrl.T.Helper()
@@ -18,6 +18,7 @@ package peer
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -28,6 +29,7 @@ import (
 	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
 	"github.com/dragonflyoss/Dragonfly/supernode/config"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
 	dutil "github.com/dragonflyoss/Dragonfly/supernode/daemon/util"
 	"github.com/dragonflyoss/Dragonfly/supernode/util"
 
@@ -56,9 +58,9 @@ type Manager struct {
 }
 
 // NewManager returns a new Manager Object.
-func NewManager(register prometheus.Registerer) (*Manager, error) {
+func NewManager(backend state.Backend, register prometheus.Registerer) (*Manager, error) {
 	return &Manager{
-		peerStore: dutil.NewStore(),
+		peerStore: dutil.NewBackendStore(backend, state.BucketPeer, decodePeerInfo),
 		metrics:   newMetrics(register),
 	}, nil
 }
@@ -76,12 +78,16 @@ func (pm *Manager) Register(ctx context.Context, peerCreateRequest *types.PeerCr
 
 	id := generatePeerID(peerCreateRequest)
 	peerInfo := &types.PeerInfo{
-		ID:       id,
-		IP:       peerCreateRequest.IP,
-		HostName: peerCreateRequest.HostName,
-		Port:     peerCreateRequest.Port,
-		Version:  peerCreateRequest.Version,
-		Created:  strfmt.DateTime(time.Now()),
+		ID:         id,
+		IDC:        peerCreateRequest.IDC,
+		IP:         peerCreateRequest.IP,
+		HostName:   peerCreateRequest.HostName,
+		Labels:     peerCreateRequest.Labels,
+		Port:       peerCreateRequest.Port,
+		Tenant:     peerCreateRequest.Tenant,
+		UploadRate: peerCreateRequest.UploadRate,
+		Version:    peerCreateRequest.Version,
+		Created:    strfmt.DateTime(time.Now()),
 	}
 	pm.peerStore.Put(id, peerInfo)
 	pm.metrics.peers.WithLabelValues(peerInfo.IP.String()).Inc()
@@ -207,6 +213,14 @@ func getLessFunc(listResult []interface{}, desc bool) (less func(i, j int) bool)
 
 // generatePeerID generates an ID with hostname and ip.
 // Use timestamp to ensure the uniqueness.
+func decodePeerInfo(data []byte) (interface{}, error) {
+	peerInfo := &types.PeerInfo{}
+	if err := json.Unmarshal(data, peerInfo); err != nil {
+		return nil, err
+	}
+	return peerInfo, nil
+}
+
 func generatePeerID(peerInfo *types.PeerCreateRequest) string {
 	return fmt.Sprintf("%s-%s-%d", peerInfo.HostName.String(), peerInfo.IP.String(), time.Now().UnixNano())
 }
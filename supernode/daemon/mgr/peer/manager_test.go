@@ -44,12 +44,14 @@ type PeerMgrTestSuite struct {
 }
 
 func (s *PeerMgrTestSuite) TestPeerMgr(c *check.C) {
-	manager, _ := NewManager(prometheus.NewRegistry())
+	manager, _ := NewManager(nil, prometheus.NewRegistry())
 	peers := manager.metrics.peers
 	// register
 	request := &types.PeerCreateRequest{
+		IDC:      "hz",
 		IP:       "192.168.10.11",
 		HostName: "foo",
+		Labels:   map[string]string{"zone": "east"},
 		Port:     65001,
 		Version:  version.DFGetVersion,
 	}
@@ -64,8 +66,10 @@ func (s *PeerMgrTestSuite) TestPeerMgr(c *check.C) {
 	c.Check(err, check.IsNil)
 	expected := &types.PeerInfo{
 		ID:       id,
+		IDC:      request.IDC,
 		IP:       request.IP,
 		HostName: request.HostName,
+		Labels:   request.Labels,
 		Port:     request.Port,
 		Version:  request.Version,
 		Created:  info.Created,
@@ -91,7 +95,7 @@ func (s *PeerMgrTestSuite) TestPeerMgr(c *check.C) {
 }
 
 func (s *PeerMgrTestSuite) TestGet(c *check.C) {
-	manager, _ := NewManager(prometheus.NewRegistry())
+	manager, _ := NewManager(nil, prometheus.NewRegistry())
 
 	// register
 	request := &types.PeerCreateRequest{
@@ -129,7 +133,7 @@ func (s *PeerMgrTestSuite) TestGet(c *check.C) {
 }
 
 func (s *PeerMgrTestSuite) TestGetAllPeerIDs(c *check.C) {
-	manager, _ := NewManager(prometheus.NewRegistry())
+	manager, _ := NewManager(nil, prometheus.NewRegistry())
 
 	// the first data
 	request := &types.PeerCreateRequest{
@@ -160,7 +164,7 @@ func (s *PeerMgrTestSuite) TestGetAllPeerIDs(c *check.C) {
 }
 
 func (s *PeerMgrTestSuite) TestList(c *check.C) {
-	manager, _ := NewManager(prometheus.NewRegistry())
+	manager, _ := NewManager(nil, prometheus.NewRegistry())
 	// the first data
 	request := &types.PeerCreateRequest{
 		IP:       "192.168.10.11",
@@ -18,6 +18,8 @@ package mgr
 
 import (
 	"context"
+	"net"
+	"strconv"
 
 	"github.com/dragonflyoss/Dragonfly/apis/types"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/util"
@@ -43,3 +45,8 @@ type PeerMgr interface {
 	// List returns a list of peers info with filter.
 	List(ctx context.Context, filter *util.PageFilter) (peerList []*types.PeerInfo, err error)
 }
+
+// PeerAddr returns the address(ip:port) of the peer server.
+func PeerAddr(peer *types.PeerInfo) string {
+	return net.JoinHostPort(peer.IP.String(), strconv.Itoa(int(peer.Port)))
+}
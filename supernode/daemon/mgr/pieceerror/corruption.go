@@ -0,0 +1,307 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pieceerror
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	dfgetConfig "github.com/dragonflyoss/Dragonfly/dfget/config"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/api"
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/rangeutils"
+	"github.com/dragonflyoss/Dragonfly/pkg/timeutils"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// verifyTimeout is the timeout of asking a peer to verify a piece.
+const verifyTimeout = 10 * time.Second
+
+// The sources of the corrupted pieces.
+const (
+	sourceSupernode = "supernode"
+	sourcePeer      = "peer"
+)
+
+// The results of verifying a piece by the peer.
+const (
+	verifyResultCorrupted = "corrupted"
+	verifyResultIntact    = "intact"
+	verifyResultFailed    = "failed"
+)
+
+// corruptionRecord maintains the times in milliseconds when a peer server
+// served corrupted pieces. It's kept in the state backend by the address of
+// the peer server, so that the corruptions reported to all supernodes of the
+// cluster are counted together, and they are not forgotten when the peer
+// restarts with a new peerID.
+type corruptionRecord struct {
+	Times []int64 `json:"times"`
+}
+
+// add records a corruption at now, and returns true if there are at least
+// limit corruptions within the window. The record is reset then.
+func (cr *corruptionRecord) add(now, window int64, limit int) bool {
+	cr.expire(now - window)
+	cr.Times = append(cr.Times, now)
+	if len(cr.Times) < limit {
+		return false
+	}
+	cr.Times = nil
+	return true
+}
+
+// expire removes the corruptions which happened before the deadline,
+// and returns the number of the remaining ones.
+func (cr *corruptionRecord) expire(deadline int64) int {
+	i := 0
+	for i < len(cr.Times) && cr.Times[i] <= deadline {
+		i++
+	}
+	cr.Times = cr.Times[i:]
+	return len(cr.Times)
+}
+
+// isCorruption returns whether the piece downloaded doesn't match its MD5.
+func isCorruption(req *types.PieceErrorRequest) bool {
+	return req.ErrorType == types.PieceErrorRequestErrorTypeFILEMD5NOTMATCH &&
+		req.RealMd5 != req.ExpectedMd5
+}
+
+// handlePeerCorruption stops scheduling the corrupted piece from the peer at once,
+// and then asks the peer to verify its local copy in the background.
+func (em *Manager) handlePeerCorruption(ctx context.Context, req *types.PieceErrorRequest) error {
+	if !isCorruption(req) {
+		return nil
+	}
+	pieceNum := rangeutils.CalculatePieceNum(req.Range)
+	if pieceNum < 0 {
+		return errors.Wrapf(errortypes.ErrInvalidValue, "range: %s", req.Range)
+	}
+
+	if err := em.progressMgr.DeletePeerIDByPieceNum(ctx, req.TaskID, pieceNum, req.DstPid); err != nil {
+		logrus.Warnf("failed to delete the corrupted pieceNum(%d) of taskID(%s) from peerID(%s): %v",
+			pieceNum, req.TaskID, req.DstPid, err)
+	}
+
+	// the same piece may be reported by several peers downloading it at the same time.
+	added, err := em.queue.add(errorKey(em.cfg, req), req)
+	if err != nil {
+		em.metrics.droppedErrors.WithLabelValues(req.ErrorType, dropReasonFull).Inc()
+		logrus.Warnf("drop piece error request: %+v", req)
+		return err
+	}
+	if added {
+		em.metrics.pieceErrors.WithLabelValues(req.ErrorType).Inc()
+		em.metrics.corruptedPieces.WithLabelValues(sourcePeer).Inc()
+	}
+	return nil
+}
+
+// verifyPeerPiece asks the peer to verify the corrupted piece and records the
+// corruption unless the piece is intact on the peer, which means that it was
+// corrupted in transit. The verification failed is retried, and the corruption
+// is recorded if it fails finally.
+func (em *Manager) verifyPeerPiece(ctx context.Context, req *types.PieceErrorRequest) error {
+	corrupted, err := em.verifyPiece(ctx, req)
+	switch {
+	case err != nil:
+		em.metrics.peerVerifications.WithLabelValues(verifyResultFailed).Inc()
+		return errors.Wrapf(err, "failed to verify the piece on peerID(%s)", req.DstPid)
+	case corrupted:
+		em.metrics.peerVerifications.WithLabelValues(verifyResultCorrupted).Inc()
+		logrus.Warnf("the piece range(%s) of taskID(%s) is corrupted on peerID(%s) and the file has been deleted",
+			req.Range, req.TaskID, req.DstPid)
+		em.deletePeerResource(ctx, req.TaskID, req.DstPid)
+	default:
+		em.metrics.peerVerifications.WithLabelValues(verifyResultIntact).Inc()
+		logrus.Infof("the piece range(%s) of taskID(%s) is intact on peerID(%s)",
+			req.Range, req.TaskID, req.DstPid)
+		return nil
+	}
+
+	return em.recordCorruption(ctx, req.DstPid)
+}
+
+// verifyPiece asks the peer to verify the piece and returns whether it's corrupted.
+func (em *Manager) verifyPiece(ctx context.Context, req *types.PieceErrorRequest) (bool, error) {
+	peer, err := em.peerMgr.Get(ctx, req.DstPid)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get peer")
+	}
+	cid, err := em.dfgetTaskMgr.GetCIDByPeerIDAndTaskID(ctx, req.DstPid, req.TaskID)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get cid")
+	}
+	dfgetTask, err := em.dfgetTaskMgr.Get(ctx, cid, req.TaskID)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get dfgetTask")
+	}
+	task, err := em.taskMgr.Get(ctx, req.TaskID)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get task")
+	}
+
+	cdnSource := types.CdnSourceSupernode
+	if em.cfg.CDNPattern == config.CDNPatternSource {
+		cdnSource = types.CdnSourceSource
+	}
+	resp, err := em.uploaderAPI.VerifyPiece(peer.IP.String(), int(peer.Port), &api.VerifyPieceRequest{
+		TaskFileName: strings.TrimPrefix(dfgetTask.Path, dfgetConfig.PeerHTTPPathPrefix),
+		PieceRange:   req.Range,
+		PieceNum:     rangeutils.CalculatePieceNum(req.Range),
+		PieceSize:    task.PieceSize,
+		CDNSource:    string(cdnSource),
+		PieceMd5:     req.ExpectedMd5,
+	})
+	if err != nil {
+		return false, err
+	}
+	return resp.Corrupted, nil
+}
+
+// deletePeerResource stops scheduling all pieces of the task from the peer
+// because its file has been deleted.
+func (em *Manager) deletePeerResource(ctx context.Context, taskID, peerID string) {
+	if err := em.progressMgr.DeleteSeed(ctx, taskID, peerID); err != nil {
+		logrus.Warnf("failed to delete seed peerID(%s) of taskID(%s): %v", peerID, taskID, err)
+	}
+
+	task, err := em.taskMgr.Get(ctx, taskID)
+	if err != nil {
+		logrus.Warnf("failed to get taskID(%s): %v", taskID, err)
+		return
+	}
+	for pieceNum := 0; pieceNum < int(task.PieceTotal); pieceNum++ {
+		if err := em.progressMgr.DeletePeerIDByPieceNum(ctx, taskID, pieceNum, peerID); err != nil {
+			logrus.Warnf("failed to delete peerID(%s) for pieceNum(%d) of taskID(%s): %v", peerID, pieceNum, taskID, err)
+		}
+	}
+}
+
+// recordCorruption quarantines the peer server for CorruptionQuarantine if it has
+// served CorruptionLimit corrupted pieces within CorruptionQuarantine.
+func (em *Manager) recordCorruption(ctx context.Context, peerID string) error {
+	// the corrupted piece lowers the health score of the peer even if the quarantine is disabled.
+	if err := em.progressMgr.ReportPeerError(ctx, peerID, types.PieceErrorRequestErrorTypeFILEMD5NOTMATCH); err != nil {
+		logrus.Warnf("failed to report the corruption of peerID(%s): %v", peerID, err)
+	}
+
+	if em.cfg.CorruptionLimit <= 0 {
+		return nil
+	}
+
+	peer, err := em.peerMgr.Get(ctx, peerID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get peerID(%s)", peerID)
+	}
+	peerAddr := mgr.PeerAddr(peer)
+
+	em.corruptionLock.Lock()
+	defer em.corruptionLock.Unlock()
+
+	record, err := em.getCorruptionRecord(peerAddr)
+	if err != nil {
+		return err
+	}
+	now := timeutils.GetCurrentTimeMillis()
+	window := int64(em.cfg.CorruptionQuarantine / time.Millisecond)
+	quarantined := record.add(now, window, em.cfg.CorruptionLimit)
+	if err := em.putCorruptionRecord(peerAddr, record); err != nil {
+		return err
+	}
+	if !quarantined {
+		return nil
+	}
+
+	if err := em.progressMgr.QuarantinePeer(ctx, peerAddr, now+window); err != nil {
+		return errors.Wrapf(err, "failed to quarantine peer(%s)", peerAddr)
+	}
+	em.metrics.quarantinedPeers.WithLabelValues().Inc()
+	logrus.Warnf("peer(%s) of peerID(%s) is quarantined for %v because of %d corrupted pieces",
+		peerAddr, peerID, em.cfg.CorruptionQuarantine, em.cfg.CorruptionLimit)
+	return nil
+}
+
+// getCorruptionRecord returns the record of the peer server at peerAddr in the
+// state backend, and an empty record will be returned if it doesn't exist.
+func (em *Manager) getCorruptionRecord(peerAddr string) (*corruptionRecord, error) {
+	record := &corruptionRecord{}
+	data, err := em.backend.Get(state.BucketCorruption, peerAddr)
+	if errortypes.IsDataNotFound(err) {
+		return record, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the corruptions of peer(%s)", peerAddr)
+	}
+	if err := json.Unmarshal(data, record); err != nil {
+		logrus.Warnf("failed to decode the corruptions of peer(%s): %v", peerAddr, err)
+		return &corruptionRecord{}, nil
+	}
+	return record, nil
+}
+
+// putCorruptionRecord saves the record of the peer server at peerAddr into the
+// state backend, and deletes it if there is no corruption in it.
+func (em *Manager) putCorruptionRecord(peerAddr string, record *corruptionRecord) error {
+	if len(record.Times) == 0 {
+		if err := em.backend.Delete(state.BucketCorruption, peerAddr); err != nil && !errortypes.IsDataNotFound(err) {
+			return errors.Wrapf(err, "failed to delete the corruptions of peer(%s)", peerAddr)
+		}
+		return nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := em.backend.Put(state.BucketCorruption, peerAddr, data); err != nil {
+		return errors.Wrapf(err, "failed to save the corruptions of peer(%s)", peerAddr)
+	}
+	return nil
+}
+
+// deleteExpiredCorruptions deletes the records of the peer servers which have
+// no corruption within CorruptionQuarantine.
+func (em *Manager) deleteExpiredCorruptions() {
+	records, err := em.backend.List(state.BucketCorruption)
+	if err != nil {
+		logrus.Warnf("failed to list the corruptions: %v", err)
+		return
+	}
+
+	em.corruptionLock.Lock()
+	defer em.corruptionLock.Unlock()
+
+	deadline := timeutils.GetCurrentTimeMillis() - int64(em.cfg.CorruptionQuarantine/time.Millisecond)
+	for peerAddr, data := range records {
+		record := &corruptionRecord{}
+		if err := json.Unmarshal(data, record); err == nil && record.expire(deadline) > 0 {
+			continue
+		}
+		if err := em.backend.Delete(state.BucketCorruption, peerAddr); err != nil && !errortypes.IsDataNotFound(err) {
+			logrus.Warnf("failed to delete the corruptions of peer(%s): %v", peerAddr, err)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pieceerror
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/api"
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/timeutils"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/mock"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
+
+	"github.com/go-check/check"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func Test(t *testing.T) {
+	check.TestingT(t)
+}
+
+func init() {
+	check.Suite(&CorruptionTestSuite{})
+}
+
+// fakeUploaderAPI verifies the pieces as the peers do.
+type fakeUploaderAPI struct {
+	api.UploaderAPI
+	sync.Mutex
+	corrupted bool
+	err       error
+	requests  []*api.VerifyPieceRequest
+}
+
+func (f *fakeUploaderAPI) VerifyPiece(ip string, port int, req *api.VerifyPieceRequest) (*api.VerifyPieceResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.requests = append(f.requests, req)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &api.VerifyPieceResponse{Corrupted: f.corrupted}, nil
+}
+
+type CorruptionTestSuite struct {
+	mockCtl         *gomock.Controller
+	mockProgressMgr *mock.MockProgressMgr
+	uploader        *fakeUploaderAPI
+	backend         state.Backend
+	manager         *Manager
+}
+
+func (s *CorruptionTestSuite) SetUpTest(c *check.C) {
+	s.mockCtl = gomock.NewController(c)
+	s.mockProgressMgr = mock.NewMockProgressMgr(s.mockCtl)
+	taskMgr := mock.NewMockTaskMgr(s.mockCtl)
+	peerMgr := mock.NewMockPeerMgr(s.mockCtl)
+	dfgetTaskMgr := mock.NewMockDfgetTaskMgr(s.mockCtl)
+
+	peerMgr.EXPECT().Get(gomock.Any(), "peer").Return(&types.PeerInfo{IP: "127.0.0.1", Port: 15001}, nil).AnyTimes()
+	// the peer restarted and registered with a new peerID
+	peerMgr.EXPECT().Get(gomock.Any(), "restarted").Return(&types.PeerInfo{IP: "127.0.0.1", Port: 15001}, nil).AnyTimes()
+	dfgetTaskMgr.EXPECT().GetCIDByPeerIDAndTaskID(gomock.Any(), "peer", "task").Return("cid", nil).AnyTimes()
+	dfgetTaskMgr.EXPECT().Get(gomock.Any(), "cid", "task").Return(&types.DfGetTask{Path: "/peer/file/taskFile"}, nil).AnyTimes()
+	taskMgr.EXPECT().Get(gomock.Any(), "task").Return(&types.TaskInfo{PieceSize: 4, PieceTotal: 3}, nil).AnyTimes()
+	s.mockProgressMgr.EXPECT().ReportPeerError(gomock.Any(), gomock.Any(), types.PieceErrorRequestErrorTypeFILEMD5NOTMATCH).Return(nil).AnyTimes()
+
+	cfg := config.NewConfig()
+	cfg.SetSuperPID("superPid")
+	cfg.CorruptionLimit = 2
+	s.backend = state.NewMemoryBackend()
+	s.manager, _ = NewManager(cfg, nil, nil, taskMgr, peerMgr, dfgetTaskMgr, s.mockProgressMgr, s.backend, prometheus.NewRegistry())
+	s.uploader = &fakeUploaderAPI{}
+	s.manager.uploaderAPI = s.uploader
+}
+
+func (s *CorruptionTestSuite) TearDownTest(c *check.C) {
+	s.mockCtl.Finish()
+}
+
+func newCorruption(pieceRange string) *types.PieceErrorRequest {
+	return &types.PieceErrorRequest{
+		DstPid:      "peer",
+		ErrorType:   types.PieceErrorRequestErrorTypeFILEMD5NOTMATCH,
+		ExpectedMd5: "expected",
+		RealMd5:     "real",
+		Range:       pieceRange,
+		TaskID:      "task",
+	}
+}
+
+// report reports the corruption and handles it as the error pool does.
+func (s *CorruptionTestSuite) report(c *check.C, req *types.PieceErrorRequest) {
+	c.Assert(s.manager.HandlePieceError(context.Background(), req), check.IsNil)
+	c.Assert(s.manager.queue.len(), check.Equals, 1)
+	s.manager.process(context.Background(), s.manager.queue.get())
+	c.Assert(s.manager.queue.len(), check.Equals, 0)
+}
+
+func (s *CorruptionTestSuite) TestIntactPiece(c *check.C) {
+	// the corrupted piece is removed from the peer at once
+	s.mockProgressMgr.EXPECT().DeletePeerIDByPieceNum(gomock.Any(), "task", 1, "peer").Return(nil).Times(3)
+
+	req := newCorruption("4-7")
+	s.report(c, req)
+	c.Assert(s.uploader.requests, check.HasLen, 1)
+	c.Check(s.uploader.requests[0], check.DeepEquals, &api.VerifyPieceRequest{
+		TaskFileName: "taskFile",
+		PieceRange:   "4-7",
+		PieceNum:     1,
+		PieceSize:    4,
+		CDNSource:    string(types.CdnSourceSupernode),
+		PieceMd5:     "expected",
+	})
+
+	// the same piece reported again is ignored until the handling is expired
+	c.Assert(s.manager.HandlePieceError(context.Background(), req), check.IsNil)
+	c.Check(s.manager.queue.len(), check.Equals, 0)
+
+	// the piece intact on the peer isn't recorded, so the peer is never quarantined
+	s.manager.queue.expire(0)
+	s.report(c, req)
+	c.Check(s.uploader.requests, check.HasLen, 2)
+
+	// the errors which aren't corruptions are ignored
+	req.RealMd5 = req.ExpectedMd5
+	c.Assert(s.manager.HandlePieceError(context.Background(), req), check.IsNil)
+	req = newCorruption("4-7")
+	req.ErrorType = types.PieceErrorRequestErrorTypeFILENOTEXIST
+	c.Assert(s.manager.HandlePieceError(context.Background(), req), check.IsNil)
+	c.Check(s.manager.queue.len(), check.Equals, 0)
+}
+
+func (s *CorruptionTestSuite) TestQuarantinePeer(c *check.C) {
+	s.uploader.corrupted = true
+	// the peer has deleted the file, so all pieces are removed from it
+	s.mockProgressMgr.EXPECT().DeleteSeed(gomock.Any(), "task", "peer").Return(nil).Times(2)
+	s.mockProgressMgr.EXPECT().DeletePeerIDByPieceNum(gomock.Any(), "task", gomock.Any(), "peer").Return(nil).Times(8)
+
+	s.report(c, newCorruption("0-3"))
+
+	// the limit is reached with the second corruption
+	var expireTime int64
+	s.mockProgressMgr.EXPECT().QuarantinePeer(gomock.Any(), "127.0.0.1:15001", gomock.Any()).DoAndReturn(
+		func(ctx context.Context, peerID string, t int64) error {
+			expireTime = t
+			return nil
+		})
+	s.report(c, newCorruption("8-11"))
+	c.Check(expireTime-timeutils.GetCurrentTimeMillis() > int64(config.DefaultCorruptionQuarantine/time.Millisecond)-1000, check.Equals, true)
+}
+
+func (s *CorruptionTestSuite) TestVerificationFailed(c *check.C) {
+	s.uploader.err = fmt.Errorf("connection refused")
+	s.manager.cfg.CorruptionLimit = 1
+	s.mockProgressMgr.EXPECT().DeletePeerIDByPieceNum(gomock.Any(), "task", 0, "peer").Return(nil)
+
+	c.Assert(s.manager.HandlePieceError(context.Background(), newCorruption("0-3")), check.IsNil)
+	item := s.manager.queue.get()
+	// the verification is retried
+	for i := 1; i < DefaultRetryPolicy.MaxAttempts; i++ {
+		c.Check(s.manager.queue.done(item, s.manager.handleError(context.Background(), item.req), DefaultRetryPolicy), check.Equals, true)
+	}
+
+	// the peer is regarded as corrupted when the verification fails finally
+	s.mockProgressMgr.EXPECT().QuarantinePeer(gomock.Any(), "127.0.0.1:15001", gomock.Any()).Return(nil)
+	s.manager.process(context.Background(), item)
+	c.Check(s.manager.queue.len(), check.Equals, 0)
+	c.Check(s.uploader.requests, check.HasLen, DefaultRetryPolicy.MaxAttempts)
+}
+
+func (s *CorruptionTestSuite) TestCorruptionRecord(c *check.C) {
+	record := &corruptionRecord{}
+	c.Check(record.add(0, 10, 2), check.Equals, false)
+	// the first corruption is out of the window
+	c.Check(record.add(20, 10, 2), check.Equals, false)
+	c.Check(record.add(25, 10, 2), check.Equals, true)
+	c.Check(record.Times, check.HasLen, 0)
+
+	c.Check(record.add(30, 10, 2), check.Equals, false)
+	c.Check(record.expire(30), check.Equals, 0)
+}
+
+func (s *CorruptionTestSuite) TestSharedCorruptions(c *check.C) {
+	s.manager.cfg.CorruptionLimit = 3
+
+	// the corruptions recorded by another supernode are counted together,
+	// and the peer server is tracked by its address across the peerIDs.
+	other, _ := NewManager(s.manager.cfg, nil, nil, nil, s.manager.peerMgr, nil, s.mockProgressMgr, s.backend, prometheus.NewRegistry())
+	c.Assert(s.manager.recordCorruption(context.Background(), "peer"), check.IsNil)
+	c.Assert(other.recordCorruption(context.Background(), "restarted"), check.IsNil)
+
+	data, err := s.backend.Get(state.BucketCorruption, "127.0.0.1:15001")
+	c.Assert(err, check.IsNil)
+	c.Check(string(data), check.Matches, `\{"times":\[\d+,\d+\]\}`)
+
+	s.mockProgressMgr.EXPECT().QuarantinePeer(gomock.Any(), "127.0.0.1:15001", gomock.Any()).Return(nil)
+	c.Assert(s.manager.recordCorruption(context.Background(), "restarted"), check.IsNil)
+	_, err = s.backend.Get(state.BucketCorruption, "127.0.0.1:15001")
+	c.Check(errortypes.IsDataNotFound(err), check.Equals, true)
+
+	// the expired records are deleted
+	c.Assert(s.backend.Put(state.BucketCorruption, "127.0.0.1:15002", []byte(`{"times":[1]}`)), check.IsNil)
+	c.Assert(s.manager.recordCorruption(context.Background(), "peer"), check.IsNil)
+	other.deleteExpiredCorruptions()
+	records, err := s.backend.List(state.BucketCorruption)
+	c.Assert(err, check.IsNil)
+	c.Check(records, check.HasLen, 1)
+	c.Check(records["127.0.0.1:15001"], check.NotNil)
+}
@@ -36,10 +36,10 @@ func init() {
 	Register(types.PieceErrorRequestErrorTypeFILENOTEXIST, NewFileNotExistHandler)
 }
 
-func NewFileNotExistHandler(gcManager mgr.GCMgr, cdnManager mgr.CDNMgr) (Handler, error) {
+func NewFileNotExistHandler(deps *Dependencies) (Handler, error) {
 	return &FileNotExistHandler{
-		gcManager:  gcManager,
-		cdnManager: cdnManager,
+		gcManager:  deps.GCManager,
+		cdnManager: deps.CDNManager,
 	}, nil
 }
 
@@ -19,93 +19,197 @@ package pieceerror
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/dragonflyoss/Dragonfly/apis/types"
-	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/api"
+	"github.com/dragonflyoss/Dragonfly/pkg/metricsutils"
 	"github.com/dragonflyoss/Dragonfly/pkg/syncmap"
 	"github.com/dragonflyoss/Dragonfly/supernode/config"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 )
 
 var _ mgr.PieceErrorMgr = &Manager{}
 
 const (
-	ErrHandlerChanSize = 100
+	// ErrQueueSize is the max number of piece errors waiting, being handled
+	// or waiting for a retry.
+	ErrQueueSize       = 10000
 	HandleErrorPool    = 4
 	GCHandlingInterval = 1 * time.Second
 	GCHandlingDelay    = 3 * time.Second
 )
 
+// The reasons why a piece error is dropped.
+const (
+	dropReasonFull      = "full"
+	dropReasonExhausted = "exhausted"
+	dropReasonPermanent = "permanent"
+)
+
+type metrics struct {
+	pieceErrors       *prometheus.CounterVec
+	pieceErrorRetries *prometheus.CounterVec
+	droppedErrors     *prometheus.CounterVec
+	corruptedPieces   *prometheus.CounterVec
+	peerVerifications *prometheus.CounterVec
+	quarantinedPeers  *prometheus.CounterVec
+}
+
+func newMetrics(register prometheus.Registerer) *metrics {
+	return &metrics{
+		pieceErrors: metricsutils.NewCounter(config.SubsystemSupernode, "piece_errors_total",
+			"Total number of piece errors queued to be handled by type", []string{"type"}, register),
+
+		pieceErrorRetries: metricsutils.NewCounter(config.SubsystemSupernode, "piece_error_retries_total",
+			"Total number of retries of handling piece errors by type", []string{"type"}, register),
+
+		droppedErrors: metricsutils.NewCounter(config.SubsystemSupernode, "piece_errors_dropped_total",
+			"Total number of piece errors dropped by type and reason", []string{"type", "reason"}, register),
+
+		corruptedPieces: metricsutils.NewCounter(config.SubsystemSupernode, "pieces_corrupted_total",
+			"Total number of pieces reported as corrupted by source", []string{"source"}, register),
+
+		peerVerifications: metricsutils.NewCounter(config.SubsystemSupernode, "peer_piece_verifications_total",
+			"Total number of corrupted pieces verified by the peers by result", []string{"result"}, register),
+
+		quarantinedPeers: metricsutils.NewCounter(config.SubsystemSupernode, "peers_quarantined_total",
+			"Total number of peers quarantined for serving corrupted pieces", []string{}, register),
+	}
+}
+
 // handlerStore stores all registered handler.
 var handlerStore = syncmap.NewSyncMap()
 
-type handlerInitFunc func(gcManager mgr.GCMgr, cdnManager mgr.CDNMgr) (handler Handler, err error)
+// Dependencies are the managers which the handlers can use.
+type Dependencies struct {
+	GCManager   mgr.GCMgr
+	CDNManager  mgr.CDNMgr
+	ProgressMgr mgr.ProgressMgr
+}
+
+type handlerInitFunc func(deps *Dependencies) (handler Handler, err error)
 
+// Register registers the handler of the errType, the handler will be
+// initialized when the Manager starts to handle errors.
 func Register(errType string, initer handlerInitFunc) {
 	handlerStore.Add(errType, initer)
 }
 
-// TODO: make it pluggable
+// Handler handles a type of piece errors. The error returned will be retried
+// with the DefaultRetryPolicy unless it's wrapped by Permanent.
 type Handler interface {
 	Handle(ctx context.Context, pieceErrorRequest *types.PieceErrorRequest) error
 }
 
+// RetryPolicyHandler is a Handler which has its own retry policy.
+type RetryPolicyHandler interface {
+	Handler
+	RetryPolicy() *RetryPolicy
+}
+
 type Manager struct {
 	cfg      *config.Config
 	handlers map[string]Handler
 
-	gcManager  mgr.GCMgr
-	cdnManager mgr.CDNMgr
+	gcManager    mgr.GCMgr
+	cdnManager   mgr.CDNMgr
+	taskMgr      mgr.TaskMgr
+	peerMgr      mgr.PeerMgr
+	dfgetTaskMgr mgr.DfgetTaskMgr
+	progressMgr  mgr.ProgressMgr
+
+	// uploaderAPI asks the peers to verify the pieces reported as corrupted.
+	uploaderAPI api.UploaderAPI
+
+	// queue maintains the piece errors to be handled.
+	queue *workQueue
+
+	// backend keeps the times when the peer servers served corrupted pieces.
+	backend state.Backend
+	// corruptionLock serializes the updates of the corruption records.
+	corruptionLock sync.Mutex
 
-	// error handler
-	pieceErrChan       chan *types.PieceErrorRequest
-	errorHandlingStore *syncmap.SyncMap
-	handledStore       *syncmap.SyncMap
+	metrics *metrics
 }
 
-func NewManager(cfg *config.Config, gcManager mgr.GCMgr, cdnManager mgr.CDNMgr) (*Manager, error) {
+func NewManager(cfg *config.Config, gcManager mgr.GCMgr, cdnManager mgr.CDNMgr, taskMgr mgr.TaskMgr, peerMgr mgr.PeerMgr,
+	dfgetTaskMgr mgr.DfgetTaskMgr, progressMgr mgr.ProgressMgr, backend state.Backend, register prometheus.Registerer) (*Manager, error) {
 	return &Manager{
-		cfg:                cfg,
-		handlers:           make(map[string]Handler),
-		gcManager:          gcManager,
-		cdnManager:         cdnManager,
-		pieceErrChan:       make(chan *types.PieceErrorRequest, ErrHandlerChanSize),
-		errorHandlingStore: syncmap.NewSyncMap(),
-		handledStore:       syncmap.NewSyncMap(),
+		cfg:          cfg,
+		handlers:     make(map[string]Handler),
+		gcManager:    gcManager,
+		cdnManager:   cdnManager,
+		taskMgr:      taskMgr,
+		peerMgr:      peerMgr,
+		dfgetTaskMgr: dfgetTaskMgr,
+		progressMgr:  progressMgr,
+		uploaderAPI:  api.NewUploaderAPI(verifyTimeout),
+		queue:        newWorkQueue(ErrQueueSize),
+		backend:      backend,
+		metrics:      newMetrics(register),
 	}, nil
 }
 
 // HandlePieceError the peer should report the error with related info when
-// it failed to download a piece from supernode.
+// it failed to download a piece from supernode or the other peers.
 // And the supernode should handle the piece Error and do some repair operations.
 func (em *Manager) HandlePieceError(ctx context.Context, pieceErrorRequest *types.PieceErrorRequest) error {
-	// ignore the error that isn't caused by downloading from supernode
-	if !em.cfg.IsSuperPID(pieceErrorRequest.DstPid) {
-		return nil
+	if em.cfg.IsSuperPID(pieceErrorRequest.DstPid) {
+		switch pieceErrorRequest.ErrorType {
+		case types.PieceErrorRequestErrorTypeFILEMD5NOTMATCH:
+			if isCorruption(pieceErrorRequest) {
+				em.metrics.corruptedPieces.WithLabelValues(sourceSupernode).Inc()
+			}
+			return em.enqueue(pieceErrorRequest)
+		case types.PieceErrorRequestErrorTypeFILENOTEXIST:
+			return em.enqueue(pieceErrorRequest)
+		default:
+			// the network errors of supernode aren't related to the task.
+			return nil
+		}
 	}
 
-	// if the error is handling, we should ignore it.
-	_, err := em.errorHandlingStore.Get(pieceErrorRequest.TaskID)
-	if err == nil {
+	switch pieceErrorRequest.ErrorType {
+	case types.PieceErrorRequestErrorTypeFILEMD5NOTMATCH:
+		return em.handlePeerCorruption(ctx, pieceErrorRequest)
+	case types.PieceErrorRequestErrorTypeFILENOTEXIST:
+		// the file has been deleted by the peer, and it's not a failure of the peer.
 		return nil
+	default:
+		return em.enqueue(pieceErrorRequest)
 	}
-	if !errortypes.IsDataNotFound(err) {
-		logrus.Errorf("failed to get taskID(%s) from errorHandlingStore: %v", pieceErrorRequest.TaskID, err)
+}
+
+// enqueue puts the piece error into the queue, the same error being handled
+// or handled within GCHandlingDelay is ignored.
+func (em *Manager) enqueue(pieceErrorRequest *types.PieceErrorRequest) error {
+	added, err := em.queue.add(errorKey(em.cfg, pieceErrorRequest), pieceErrorRequest)
+	if err != nil {
+		em.metrics.droppedErrors.WithLabelValues(pieceErrorRequest.ErrorType, dropReasonFull).Inc()
+		logrus.Warnf("drop piece error request: %+v", pieceErrorRequest)
 		return err
 	}
+	if added {
+		em.metrics.pieceErrors.WithLabelValues(pieceErrorRequest.ErrorType).Inc()
+	}
+	return nil
+}
 
-	select {
-	case em.pieceErrChan <- pieceErrorRequest:
-		em.errorHandlingStore.Add(pieceErrorRequest.TaskID, true)
-		return nil
-	default:
-		logrus.Warnf("drop piece error request: %+v", pieceErrorRequest)
-		return fmt.Errorf("%d piece errors are being processed already", ErrHandlerChanSize)
+// errorKey dedupes the piece errors of the same type per task/peer/piece.
+// The errors of downloading from supernode are deduplicated per task
+// because supernode repairs the whole task.
+func errorKey(cfg *config.Config, req *types.PieceErrorRequest) string {
+	if cfg.IsSuperPID(req.DstPid) {
+		return fmt.Sprintf("%s@%s", req.ErrorType, req.TaskID)
 	}
+	return fmt.Sprintf("%s@%s@%s@%s", req.ErrorType, req.TaskID, req.DstPid, req.Range)
 }
 
 // StartHandleError starts a goroutine to handle the piece error.
@@ -119,12 +223,18 @@ func (em *Manager) StartHandleError(ctx context.Context) {
 	go func() {
 		ticker := time.NewTicker(GCHandlingInterval)
 		for range ticker.C {
-			em.deleteHandling(ctx)
+			em.queue.expire(GCHandlingDelay)
+			em.deleteExpiredCorruptions()
 		}
 	}()
 }
 
 func (em *Manager) initHandlers() {
+	deps := &Dependencies{
+		GCManager:   em.gcManager,
+		CDNManager:  em.cdnManager,
+		ProgressMgr: em.progressMgr,
+	}
 	rangeFunc := func(key, value interface{}) bool {
 		initFunc, ok := value.(handlerInitFunc)
 		if !ok {
@@ -136,7 +246,7 @@ func (em *Manager) initHandlers() {
 			return true
 		}
 
-		handler, err := initFunc(em.gcManager, em.cdnManager)
+		handler, err := initFunc(deps)
 		if err != nil {
 			logrus.Errorf("failed to init handler type %s: %v", errType, err)
 			return true
@@ -149,51 +259,61 @@ func (em *Manager) initHandlers() {
 	handlerStore.Range(rangeFunc)
 }
 
-func (em *Manager) deleteHandling(ctx context.Context) {
-	rangeFunc := func(key, value interface{}) bool {
-		handledTime, ok := value.(time.Time)
-		if !ok {
-			return true
-		}
-		if time.Since(handledTime) < GCHandlingDelay {
-			return true
-		}
-
-		if taskID, ok := key.(string); ok {
-			em.errorHandlingStore.Delete(taskID)
-			em.handledStore.Delete(taskID)
-		}
-		return true
-	}
-
-	em.handledStore.Range(rangeFunc)
-}
 func (em *Manager) startHandleErrorPool(ctx context.Context) {
 	for i := 0; i < HandleErrorPool; i++ {
 		go func() {
-			for per := range em.pieceErrChan {
-				if err := em.handleError(ctx, per); err != nil {
-					logrus.Errorf("failed to handle error %+v:%v", per, err)
-				}
+			for {
+				em.process(ctx, em.queue.get())
 			}
 		}()
 	}
 }
 
+// process handles the piece error in the queue, and retries it
+// with the retry policy of its handler if failed.
+func (em *Manager) process(ctx context.Context, item *workItem) {
+	per := item.req
+	err := em.handleError(ctx, per)
+	if !em.queue.done(item, err, em.getRetryPolicy(per.ErrorType)) {
+		if err != nil {
+			reason := dropReasonExhausted
+			if isPermanent(err) {
+				reason = dropReasonPermanent
+			}
+			em.metrics.droppedErrors.WithLabelValues(per.ErrorType, reason).Inc()
+			logrus.Errorf("failed to handle error %+v after %d attempts: %v", per, item.attempts, err)
+			em.giveUp(ctx, per, err)
+		}
+		return
+	}
+
+	em.metrics.pieceErrorRetries.WithLabelValues(per.ErrorType).Inc()
+	logrus.Warnf("failed to handle error %+v and will retry: %v", per, err)
+}
+
 func (em *Manager) handleError(ctx context.Context, pieceError *types.PieceErrorRequest) error {
-	// add taskID to handledStore regardless of the result of handler
-	defer func() {
-		em.handledStore.Add(pieceError.TaskID, time.Now())
-	}()
+	if !em.cfg.IsSuperPID(pieceError.DstPid) && isCorruption(pieceError) {
+		return em.verifyPeerPiece(ctx, pieceError)
+	}
 
 	handler, err := em.getHandler(ctx, pieceError.ErrorType)
 	if err != nil {
-		return errors.Wrapf(err, "failed to get handler")
+		return Permanent(errors.Wrapf(err, "failed to get handler"))
 	}
 
 	return handler.Handle(ctx, pieceError)
 }
 
+// giveUp is called when handling the piece error failed finally.
+func (em *Manager) giveUp(ctx context.Context, pieceError *types.PieceErrorRequest, err error) {
+	// the peer which can't verify the corrupted piece is regarded as corrupted.
+	if !em.cfg.IsSuperPID(pieceError.DstPid) && isCorruption(pieceError) && !isPermanent(err) {
+		if err := em.recordCorruption(ctx, pieceError.DstPid); err != nil {
+			logrus.Errorf("failed to record the corruption of peerID(%s): %v", pieceError.DstPid, err)
+		}
+	}
+}
+
 func (em *Manager) getHandler(ctx context.Context, errType string) (Handler, error) {
 	if v, ok := em.handlers[errType]; ok {
 		return v, nil
@@ -201,3 +321,10 @@ func (em *Manager) getHandler(ctx context.Context, errType string) (Handler, err
 
 	return nil, fmt.Errorf("unregistered error handler")
 }
+
+func (em *Manager) getRetryPolicy(errType string) *RetryPolicy {
+	if v, ok := em.handlers[errType].(RetryPolicyHandler); ok {
+		return v.RetryPolicy()
+	}
+	return DefaultRetryPolicy
+}
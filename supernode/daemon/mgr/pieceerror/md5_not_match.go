@@ -37,10 +37,10 @@ func init() {
 	Register(types.PieceErrorRequestErrorTypeFILEMD5NOTMATCH, NewFileMd5NotMatchHandler)
 }
 
-func NewFileMd5NotMatchHandler(gcManager mgr.GCMgr, cdnManager mgr.CDNMgr) (Handler, error) {
+func NewFileMd5NotMatchHandler(deps *Dependencies) (Handler, error) {
 	return &FileMd5NotMatchHandler{
-		gcManager:  gcManager,
-		cdnManager: cdnManager,
+		gcManager:  deps.GCManager,
+		cdnManager: deps.CDNManager,
 	}, nil
 }
 
@@ -0,0 +1,62 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pieceerror
+
+import (
+	"context"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+)
+
+var _ Handler = &PeerErrorHandler{}
+
+// PeerErrorHandler handles the network errors and the HTTP 5xx errors that
+// the peers ran into when downloading from another peer, which lower the
+// health of that peer. It also handles the slow pieces, which lower the
+// health without being counted as errors.
+type PeerErrorHandler struct {
+	progressMgr mgr.ProgressMgr
+}
+
+func init() {
+	for _, errType := range []string{
+		types.PieceErrorRequestErrorTypeTIMEOUT,
+		types.PieceErrorRequestErrorTypeCONNECTIONREFUSED,
+		types.PieceErrorRequestErrorTypeSHORTREAD,
+		types.PieceErrorRequestErrorTypeSERVERERROR,
+		types.PieceErrorRequestErrorTypePIECESLOW,
+	} {
+		Register(errType, NewPeerErrorHandler)
+	}
+}
+
+func NewPeerErrorHandler(deps *Dependencies) (Handler, error) {
+	return &PeerErrorHandler{
+		progressMgr: deps.ProgressMgr,
+	}, nil
+}
+
+func (peh *PeerErrorHandler) Handle(ctx context.Context, pieceErrorRequest *types.PieceErrorRequest) error {
+	err := peh.progressMgr.ReportPeerError(ctx, pieceErrorRequest.DstPid, pieceErrorRequest.ErrorType)
+	if errortypes.IsDataNotFound(err) {
+		// the peer has gone, there is nothing to retry.
+		return Permanent(err)
+	}
+	return err
+}
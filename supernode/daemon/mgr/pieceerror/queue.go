@@ -0,0 +1,195 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pieceerror
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+
+	"github.com/pkg/errors"
+)
+
+// RetryPolicy decides whether and when a piece error is handled again
+// after the handler failed.
+type RetryPolicy struct {
+	// MaxAttempts is the max number of times to handle an error,
+	// the error isn't retried if it's not greater than 1.
+	MaxAttempts int
+
+	// InitialBackoff is the time to wait before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the max time to wait before a retry.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor by which the backoff grows after every retry.
+	Multiplier float64
+}
+
+// DefaultRetryPolicy is the retry policy of the handlers which don't have their own.
+var DefaultRetryPolicy = &RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+}
+
+// backoff returns the time to wait before handling the error again
+// after it has been handled attempts times.
+func (p *RetryPolicy) backoff(attempts int) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < attempts; i++ {
+		backoff *= p.Multiplier
+		if backoff >= float64(p.MaxBackoff) {
+			return p.MaxBackoff
+		}
+	}
+	return time.Duration(backoff)
+}
+
+// permanentError is an error which shouldn't be retried.
+type permanentError struct {
+	error
+}
+
+// Permanent wraps the error returned by a handler so that it isn't retried.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+func isPermanent(err error) bool {
+	_, ok := errors.Cause(err).(*permanentError)
+	return ok
+}
+
+// workItem is a piece error in the workQueue.
+type workItem struct {
+	key      string
+	req      *types.PieceErrorRequest
+	attempts int
+	// doneTime is when the item was handled finally, it's zero if the item
+	// is being handled or waiting for a retry.
+	doneTime time.Time
+}
+
+// workQueue is a bounded queue of the piece errors which are deduplicated by key.
+// An item is kept in the queue while it's waiting, being handled or waiting for
+// a retry, and for a while after it's done, so that the same error reported
+// repeatedly is only handled once.
+type workQueue struct {
+	sync.Mutex
+	cond *sync.Cond
+
+	// size is the max number of the items which aren't done.
+	size    int
+	pending int
+	ready   []*workItem
+	items   map[string]*workItem
+}
+
+func newWorkQueue(size int) *workQueue {
+	q := &workQueue{
+		size:  size,
+		items: make(map[string]*workItem),
+	}
+	q.cond = sync.NewCond(q)
+	return q
+}
+
+// add puts the piece error into the queue, and returns false if there is
+// already an item of the same key.
+func (q *workQueue) add(key string, req *types.PieceErrorRequest) (bool, error) {
+	q.Lock()
+	defer q.Unlock()
+
+	if _, ok := q.items[key]; ok {
+		return false, nil
+	}
+	if q.pending >= q.size {
+		return false, fmt.Errorf("%d piece errors are being processed already", q.size)
+	}
+
+	item := &workItem{key: key, req: req}
+	q.items[key] = item
+	q.pending++
+	q.ready = append(q.ready, item)
+	q.cond.Signal()
+	return true, nil
+}
+
+// get blocks until there is an item ready to be handled.
+func (q *workQueue) get() *workItem {
+	q.Lock()
+	defer q.Unlock()
+
+	for len(q.ready) == 0 {
+		q.cond.Wait()
+	}
+	item := q.ready[0]
+	q.ready[0] = nil
+	q.ready = q.ready[1:]
+	return item
+}
+
+// done finishes handling the item with the result err. The item will be
+// handled again after the backoff of the policy if err isn't nil and can be
+// retried, and done returns true in this case.
+func (q *workQueue) done(item *workItem, err error, policy *RetryPolicy) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	item.attempts++
+	if err != nil && !isPermanent(err) && policy != nil && item.attempts < policy.MaxAttempts {
+		time.AfterFunc(policy.backoff(item.attempts), func() {
+			q.Lock()
+			defer q.Unlock()
+			q.ready = append(q.ready, item)
+			q.cond.Signal()
+		})
+		return true
+	}
+
+	item.doneTime = time.Now()
+	q.pending--
+	return false
+}
+
+// expire deletes the items which have been done for delay,
+// so that the same errors can be handled again.
+func (q *workQueue) expire(delay time.Duration) {
+	q.Lock()
+	defer q.Unlock()
+
+	for key, item := range q.items {
+		if !item.doneTime.IsZero() && time.Since(item.doneTime) >= delay {
+			delete(q.items, key)
+		}
+	}
+}
+
+// len returns the number of the items which aren't done.
+func (q *workQueue) len() int {
+	q.Lock()
+	defer q.Unlock()
+	return q.pending
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pieceerror
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/mock"
+
+	"github.com/go-check/check"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	check.Suite(&QueueTestSuite{})
+}
+
+type QueueTestSuite struct{}
+
+func (s *QueueTestSuite) TestAdd(c *check.C) {
+	q := newWorkQueue(2)
+	req := &types.PieceErrorRequest{}
+
+	added, err := q.add("a", req)
+	c.Assert(err, check.IsNil)
+	c.Check(added, check.Equals, true)
+
+	// the same error is deduplicated
+	added, err = q.add("a", req)
+	c.Assert(err, check.IsNil)
+	c.Check(added, check.Equals, false)
+
+	added, err = q.add("b", req)
+	c.Assert(err, check.IsNil)
+	c.Check(added, check.Equals, true)
+	c.Check(q.len(), check.Equals, 2)
+
+	// the queue is full
+	added, err = q.add("c", req)
+	c.Check(err, check.NotNil)
+	c.Check(added, check.Equals, false)
+
+	c.Check(q.get().key, check.Equals, "a")
+	c.Check(q.get().key, check.Equals, "b")
+}
+
+func (s *QueueTestSuite) TestDoneAndExpire(c *check.C) {
+	q := newWorkQueue(1)
+	q.add("a", &types.PieceErrorRequest{})
+
+	c.Check(q.done(q.get(), nil, DefaultRetryPolicy), check.Equals, false)
+	c.Check(q.len(), check.Equals, 0)
+
+	// the done item is kept until it expires
+	added, _ := q.add("a", &types.PieceErrorRequest{})
+	c.Check(added, check.Equals, false)
+	q.expire(time.Minute)
+	added, _ = q.add("a", &types.PieceErrorRequest{})
+	c.Check(added, check.Equals, false)
+
+	q.expire(0)
+	added, _ = q.add("a", &types.PieceErrorRequest{})
+	c.Check(added, check.Equals, true)
+}
+
+func (s *QueueTestSuite) TestRetry(c *check.C) {
+	q := newWorkQueue(1)
+	policy := &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		Multiplier:     2,
+	}
+	q.add("a", &types.PieceErrorRequest{})
+
+	item := q.get()
+	c.Check(q.done(item, fmt.Errorf("failed"), policy), check.Equals, true)
+	c.Check(q.get(), check.Equals, item)
+	c.Check(q.done(item, fmt.Errorf("failed"), policy), check.Equals, true)
+	c.Check(q.get(), check.Equals, item)
+	c.Check(q.len(), check.Equals, 1)
+
+	// the attempts are used up
+	c.Check(q.done(item, fmt.Errorf("failed"), policy), check.Equals, false)
+	c.Check(item.attempts, check.Equals, 3)
+	c.Check(q.len(), check.Equals, 0)
+}
+
+func (s *QueueTestSuite) TestPermanent(c *check.C) {
+	q := newWorkQueue(1)
+	q.add("a", &types.PieceErrorRequest{})
+
+	err := errors.Wrap(Permanent(fmt.Errorf("failed")), "wrapped")
+	c.Check(isPermanent(err), check.Equals, true)
+	c.Check(q.done(q.get(), err, DefaultRetryPolicy), check.Equals, false)
+	c.Check(q.len(), check.Equals, 0)
+
+	c.Check(Permanent(nil), check.IsNil)
+	c.Check(isPermanent(fmt.Errorf("failed")), check.Equals, false)
+}
+
+func (s *QueueTestSuite) TestBackoff(c *check.C) {
+	policy := &RetryPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+	}
+	c.Check(policy.backoff(1), check.Equals, time.Second)
+	c.Check(policy.backoff(2), check.Equals, 2*time.Second)
+	c.Check(policy.backoff(3), check.Equals, 4*time.Second)
+	c.Check(policy.backoff(4), check.Equals, 5*time.Second)
+}
+
+func (s *QueueTestSuite) TestPeerErrorHandler(c *check.C) {
+	mockCtl := gomock.NewController(c)
+	defer mockCtl.Finish()
+	progressMgr := mock.NewMockProgressMgr(mockCtl)
+	handler, _ := NewPeerErrorHandler(&Dependencies{ProgressMgr: progressMgr})
+	req := &types.PieceErrorRequest{
+		DstPid:    "peer",
+		ErrorType: types.PieceErrorRequestErrorTypeTIMEOUT,
+	}
+
+	progressMgr.EXPECT().ReportPeerError(gomock.Any(), "peer", "TIMEOUT").Return(nil)
+	c.Check(handler.Handle(context.Background(), req), check.IsNil)
+
+	// the error is retried unless the peer has gone
+	progressMgr.EXPECT().ReportPeerError(gomock.Any(), "peer", "TIMEOUT").Return(fmt.Errorf("failed"))
+	err := handler.Handle(context.Background(), req)
+	c.Check(err, check.NotNil)
+	c.Check(isPermanent(err), check.Equals, false)
+
+	progressMgr.EXPECT().ReportPeerError(gomock.Any(), "peer", "TIMEOUT").Return(errortypes.ErrDataNotFound)
+	c.Check(isPermanent(handler.Handle(context.Background(), req)), check.Equals, true)
+}
@@ -13,44 +13,56 @@
  * See the License for the specific language governing permissions and
  * limitations under the License.
  */
+
 package preheat
 
 import (
+	"context"
 	"runtime/debug"
-	"sync/atomic"
 	"time"
 
 	"github.com/dragonflyoss/Dragonfly/apis/types"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+
+	"github.com/sirupsen/logrus"
 )
 
 const TIMEOUT = 30 * 60
 
+// queryInterval is the interval to query the progress of the running preheat task.
+var queryInterval = 2 * time.Second
+
 var _ IWorker = &BaseWorker{}
 
-type IWorker interface{
+type IWorker interface {
 	Run()
 	Stop()
 	query() chan error
 	preRun() bool
-	failed(errMsg string)
+	failed(err error)
 	afterRun()
 }
 
 type BaseWorker struct {
-	Task *mgr.PreheatTask
-	Preheater Preheater
+	Task           *mgr.PreheatTask
+	Preheater      Preheater
 	PreheatService *PreheatService
-	stop *atomic.Value
+
+	// ctx is canceled when the worker is stopped or timeout,
+	// and all the operations of the worker should be stopped then.
+	ctx    context.Context
+	cancel context.CancelFunc
 	worker IWorker
 }
 
 func newBaseWorker(task *mgr.PreheatTask, preheater Preheater, preheatService *PreheatService) *BaseWorker {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*TIMEOUT)
 	worker := &BaseWorker{
-		Task: task,
-		Preheater: preheater,
+		Task:           task,
+		Preheater:      preheater,
 		PreheatService: preheatService,
-		stop: new(atomic.Value),
+		ctx:            ctx,
+		cancel:         cancel,
 	}
 	worker.worker = worker
 	return worker
@@ -58,22 +70,27 @@ func newBaseWorker(task *mgr.PreheatTask, preheater Preheater, preheatService *P
 
 func (w *BaseWorker) Run() {
 	go func() {
-		defer func(){
+		defer func() {
 			e := recover()
 			if e != nil {
+				logrus.Errorf("preheat task %s panic: %v", w.Task.ID, e)
 				debug.PrintStack()
 			}
 		}()
+		defer w.cancel()
 
-		if w.worker.preRun() {
-			timer := time.NewTimer(time.Second*TIMEOUT)
-			ch := w.worker.query()
+		if !w.isRunning() {
+			// the worker is stopped before running
+			w.worker.failed(w.ctx.Err())
+		} else if w.worker.preRun() {
 			select {
-			case <-timer.C:
-				w.worker.failed("timeout")
-			case err := <-ch:
+			case <-w.ctx.Done():
+				w.worker.failed(w.ctx.Err())
+			case err := <-w.worker.query():
 				if err != nil {
-					w.worker.failed(err.Error())
+					w.worker.failed(err)
+				} else {
+					w.succeed()
 				}
 			}
 		}
@@ -81,12 +98,14 @@ func (w *BaseWorker) Run() {
 	}()
 }
 
+// Stop cancels the context of the worker, and the running preheat task
+// will be failed with the CANCELED error.
 func (w *BaseWorker) Stop() {
-	w.stop.Store(true)
+	w.cancel()
 }
 
 func (w *BaseWorker) isRunning() bool {
-	return w.stop.Load() == nil
+	return w.ctx.Err() == nil
 }
 
 func (w *BaseWorker) preRun() bool {
@@ -101,15 +120,29 @@ func (w *BaseWorker) query() chan error {
 	panic("not implement")
 }
 
+// succeed and failed only update the fields of the result, because the
+// others such as the peers may be updated by the worker in the meantime.
 func (w *BaseWorker) succeed() {
-	w.Task.FinishTime = time.Now().UnixNano()/int64(time.Millisecond)
+	w.Task.FinishTime = time.Now().UnixNano() / int64(time.Millisecond)
 	w.Task.Status = types.PreheatStatusSUCCESS
-	w.PreheatService.Update(w.Task.ID, w.Task)
+	w.PreheatService.Update(w.Task.ID, &mgr.PreheatTask{
+		FinishTime: w.Task.FinishTime,
+		Status:     w.Task.Status,
+	})
 }
 
-func (w *BaseWorker) failed(errMsg string) {
-	w.Task.FinishTime = time.Now().UnixNano()/int64(time.Millisecond)
+func (w *BaseWorker) failed(err error) {
+	preheatErr := toPreheatError(err, w.Task.URL)
+	logrus.Errorf("preheat task %s failed: %s %s", w.Task.ID, preheatErr.Code, preheatErr.Message)
+
+	w.Task.FinishTime = time.Now().UnixNano() / int64(time.Millisecond)
 	w.Task.Status = types.PreheatStatusFAILED
-	w.Task.ErrorMsg = errMsg
-	w.PreheatService.Update(w.Task.ID, w.Task)
+	w.Task.Error = preheatErr
+	w.Task.ErrorMsg = preheatErr.Message
+	w.PreheatService.Update(w.Task.ID, &mgr.PreheatTask{
+		FinishTime: w.Task.FinishTime,
+		Status:     w.Task.Status,
+		Error:      w.Task.Error,
+		ErrorMsg:   w.Task.ErrorMsg,
+	})
 }
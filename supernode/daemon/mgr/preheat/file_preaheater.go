@@ -13,19 +13,20 @@
  * See the License for the specific language governing permissions and
  * limitations under the License.
  */
+
 package preheat
 
 import (
-	"errors"
-	"fmt"
 	"time"
 
 	"github.com/dragonflyoss/Dragonfly/apis/types"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+
+	"github.com/sirupsen/logrus"
 )
 
 func init() {
-	RegisterPreheater("file", &FilePreheat{BasePreheater:new(BasePreheater)})
+	RegisterPreheater("file", &FilePreheat{BasePreheater: new(BasePreheater)})
 }
 
 type FilePreheat struct {
@@ -36,72 +37,66 @@ func (p *FilePreheat) Type() string {
 	return "file"
 }
 
-/**
- * Create a worker to preheat the task.
- */
-func (p *FilePreheat) NewWorker(task *mgr.PreheatTask , service *PreheatService) IWorker {
+func (p *FilePreheat) NewWorker(task *mgr.PreheatTask, service *PreheatService) IWorker {
 	worker := &FileWorker{BaseWorker: newBaseWorker(task, p, service)}
 	worker.worker = worker
 	p.addWorker(task.ID, worker)
 	return worker
 }
 
+// FileWorker preheats a file by registering it to the supernode,
+// and the file is downloaded into the supernode CDN.
 type FileWorker struct {
 	*BaseWorker
-	progress *PreheatProgress
+	taskID string
 }
 
 func (w *FileWorker) preRun() bool {
 	w.Task.Status = types.PreheatStatusRUNNING
 	w.PreheatService.Update(w.Task.ID, w.Task)
-	var err error
-	w.progress, err = w.PreheatService.ExecutePreheat(w.Task)
+
+	taskID, err := w.PreheatService.register(w.ctx, w.Task)
 	if err != nil {
-		w.failed(err.Error())
+		w.failed(err)
 		return false
 	}
+	w.taskID = taskID
 	return true
 }
 
-func (w *FileWorker) afterRun() {
-	if w.progress != nil {
-		w.progress.cmd.Process.Kill()
-	}
-	w.BaseWorker.afterRun()
-}
-
 func (w *FileWorker) query() chan error {
 	result := make(chan error, 1)
-	go func(){
-		time.Sleep(time.Second*2)
+	go func() {
+		ticker := time.NewTicker(queryInterval)
+		defer ticker.Stop()
+
 		for w.isRunning() {
-			if w.Task.FinishTime > 0 {
-				w.Preheater.Cancel(w.Task.ID)
+			progress, err := w.PreheatService.getProgress(w.ctx, w.taskID)
+			if err != nil {
+				result <- err
 				return
 			}
-			if w.progress == nil {
-				w.succeed()
-				return
-			}
-			status := w.progress.cmd.ProcessState
-			if status != nil && status.Exited() {
-				if !status.Success() {
-					errMsg := fmt.Sprintf("dfget failed: %s err: %s",  status.String(), w.progress.errmsg.String())
-					w.failed(errMsg)
-					w.Preheater.Cancel(w.Task.ID)
-					result <- errors.New(errMsg)
-					return
-				} else {
-					w.succeed()
-					w.Preheater.Cancel(w.Task.ID)
-					result <- nil
-					return
+			w.PreheatService.Update(w.Task.ID, &mgr.PreheatTask{
+				PieceTotal:         progress.PieceTotal,
+				FinishedPieceCount: progress.FinishedPieceCount,
+			})
+
+			if finished, err := progress.finished(); finished {
+				logrus.Infof("preheat task %s finished with the cdn status %s of taskID(%s)",
+					w.Task.ID, progress.CdnStatus, w.taskID)
+				if err == nil && len(w.Task.Peers) > 0 {
+					err = w.PreheatService.pushToPeers(w.ctx, w.Task)
 				}
+				result <- err
+				return
 			}
 
-			time.Sleep(time.Second*10)
+			select {
+			case <-w.ctx.Done():
+				return
+			case <-ticker.C:
+			}
 		}
 	}()
 	return result
 }
-
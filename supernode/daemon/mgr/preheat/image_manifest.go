@@ -0,0 +1,145 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package preheat
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// the media types of the manifests.
+const (
+	mediaTypeDockerManifestV1   = "application/vnd.docker.distribution.manifest.v1+prettyjws"
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+)
+
+// manifestAccept is the Accept header to get the manifests.
+var manifestAccept = strings.Join([]string{
+	mediaTypeOCIIndex,
+	mediaTypeDockerManifestList,
+	mediaTypeOCIManifest,
+	mediaTypeDockerManifest,
+	mediaTypeDockerManifestV1,
+}, ", ")
+
+// manifest contains the fields of the image manifests of all the schemas and
+// the manifest lists, which are used to find the blobs to be preheated.
+type manifest struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	MediaType     string        `json:"mediaType,omitempty"`
+	Config        *descriptor   `json:"config,omitempty"`
+	Layers        []*descriptor `json:"layers,omitempty"`
+
+	// Manifests are the images of a manifest list, or the referrers.
+	Manifests []*descriptor `json:"manifests,omitempty"`
+
+	// FSLayers are the layers of a schema 1 manifest.
+	FSLayers []struct {
+		BlobSum string `json:"blobSum"`
+	} `json:"fsLayers,omitempty"`
+}
+
+type descriptor struct {
+	MediaType string    `json:"mediaType,omitempty"`
+	Digest    string    `json:"digest"`
+	Platform  *platform `json:"platform,omitempty"`
+}
+
+type platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// parsePlatform parses the platform in the format of os/architecture[/variant].
+func parsePlatform(s string) (*platform, error) {
+	fields := strings.Split(s, "/")
+	if len(fields) < 2 || len(fields) > 3 || fields[0] == "" || fields[1] == "" {
+		return nil, fmt.Errorf("invalid platform %q, must be in the format of os/architecture[/variant]", s)
+	}
+	p := &platform{OS: fields[0], Architecture: fields[1]}
+	if len(fields) == 3 {
+		p.Variant = fields[2]
+	}
+	return p, nil
+}
+
+func parsePlatforms(ss []string) ([]*platform, error) {
+	platforms := make([]*platform, 0, len(ss))
+	for _, s := range ss {
+		p, err := parsePlatform(s)
+		if err != nil {
+			return nil, err
+		}
+		platforms = append(platforms, p)
+	}
+	return platforms, nil
+}
+
+func (p *platform) String() string {
+	if p == nil {
+		return ""
+	}
+	if p.Variant == "" {
+		return p.OS + "/" + p.Architecture
+	}
+	return p.OS + "/" + p.Architecture + "/" + p.Variant
+}
+
+// matchAny returns whether p matches any of the filters, and a filter without
+// the variant matches all the variants.
+func (p *platform) matchAny(filters []*platform) bool {
+	if p == nil {
+		return false
+	}
+	for _, f := range filters {
+		if f.OS == p.OS && f.Architecture == p.Architecture && (f.Variant == "" || f.Variant == p.Variant) {
+			return true
+		}
+	}
+	return false
+}
+
+// platformsSign returns the sign of the platforms and the referrers of an
+// image preheat task, which distinguishes the tasks preheating different
+// blobs of the same image.
+func platformsSign(platforms []string, referrers bool) string {
+	sign := strings.Join(platforms, ",")
+	if referrers {
+		sign += "+referrers"
+	}
+	return sign
+}
+
+// registryError is the error response of the registry.
+type registryError struct {
+	code int
+	msg  string
+}
+
+func (e *registryError) Error() string {
+	return e.msg
+}
+
+func isNotFound(err error) bool {
+	e, ok := err.(*registryError)
+	return ok && e.code == http.StatusNotFound
+}
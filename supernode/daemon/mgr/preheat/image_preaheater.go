@@ -16,8 +16,9 @@
 package preheat
 
 import (
+	"context"
+	"crypto/sha256"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"io/ioutil"
@@ -62,7 +63,6 @@ func (p *ImagePreheat) NewWorker(task *mgr.PreheatTask, service *PreheatService)
 
 type ImageWorker struct {
 	*BaseWorker
-	progress *PreheatProgress
 	protocol string
 	domain   string
 	name     string
@@ -71,7 +71,7 @@ type ImageWorker struct {
 func (w *ImageWorker) preRun() bool {
 	err := w.preheatLayers()
 	if err != nil {
-		w.failed(err.Error())
+		w.failed(err)
 		return false
 	}
 	return true
@@ -80,48 +80,91 @@ func (w *ImageWorker) preRun() bool {
 func (w *ImageWorker) query() chan error {
 	result := make(chan error, 1)
 	go func() {
-		time.Sleep(time.Second * 2)
+		ticker := time.NewTicker(queryInterval)
+		defer ticker.Stop()
+
 		for w.isRunning() {
 			running := len(w.Task.Children)
+			var pieceTotal, finishedPieceCount int32
+			var childPeers [][]*types.PreheatPeerStatus
+			var failedChild *mgr.PreheatTask
+			layers := copyLayers(w.Task.Layers)
+			layerIndex := make(map[string]*types.PreheatLayerStatus, len(layers))
+			for _, l := range layers {
+				if !l.Cached {
+					layerIndex[l.ID] = l
+				}
+			}
 			for _, child := range w.Task.Children {
 				childTask := w.PreheatService.Get(child)
 				if childTask == nil {
 					continue
 				}
+				childPeers = append(childPeers, childTask.Peers)
+				if l, ok := layerIndex[child]; ok {
+					l.Status = childTask.Status
+					l.ErrorMsg = childTask.ErrorMsg
+					l.PieceTotal = childTask.PieceTotal
+					l.FinishedPieceCount = childTask.FinishedPieceCount
+				}
+				pieceTotal += childTask.PieceTotal
+				finishedPieceCount += childTask.FinishedPieceCount
 				if childTask.FinishTime > 0 {
 					running--
 				}
-				if childTask.Status == types.PreheatStatusFAILED {
-					errMsg := childTask.URL + " " + childTask.ErrorMsg
-					w.Preheater.Cancel(w.Task.ID)
-					result <- errors.New(errMsg)
-					logrus.Errorf("PreheatImage Task [%s] prehead failed for %s", w.Task.ID, errMsg)
-					return
+				if childTask.Status == types.PreheatStatusFAILED && failedChild == nil {
+					failedChild = childTask
 				}
 			}
+			update := &mgr.PreheatTask{
+				PieceTotal:         pieceTotal,
+				FinishedPieceCount: finishedPieceCount,
+				Layers:             layers,
+			}
+			if len(w.Task.Peers) > 0 {
+				update.Peers = mergePeers(w.Task.Peers, childPeers)
+			}
+			w.PreheatService.Update(w.Task.ID, update)
 
+			if failedChild != nil {
+				logrus.Errorf("PreheatImage Task [%s] prehead failed for %s %s", w.Task.ID, failedChild.URL, failedChild.ErrorMsg)
+				result <- childError(failedChild)
+				return
+			}
 			if running <= 0 {
-				w.succeed()
-				w.Preheater.Cancel(w.Task.ID)
 				result <- nil
 				return
 			}
-			time.Sleep(time.Second * 10)
+
+			select {
+			case <-w.ctx.Done():
+				return
+			case <-ticker.C:
+			}
 		}
 	}()
 	return result
 }
 
+// childError returns the error of the failed layer preheat task.
+func childError(child *mgr.PreheatTask) error {
+	if child.Error != nil {
+		return newPreheatError(child.Error.Code, child.URL, "%s", child.Error.Message)
+	}
+	return newPreheatError(types.PreheatErrorCodeINTERNAL, child.URL, "%s", child.ErrorMsg)
+}
+
 func (w *ImageWorker) preheatLayers() (err error) {
 	task := w.Task
 	layers, err := w.getLayers(task.URL, task.Headers, true)
 	if err != nil {
-		return
+		return newPreheatError(types.PreheatErrorCodeMANIFESTFAILED, task.URL, "%v", err)
 	}
 
 	children := make([]string, 0)
+	statuses := make([]*types.PreheatLayerStatus, 0)
 	for _, layer := range layers {
-		logrus.Debugf("preheat layer:%s parentId:%s", layer.url, task.ID)
+		logrus.Debugf("preheat %s:%s parentId:%s", layer.kind, layer.url, task.ID)
 		if layer.url == "" {
 			continue
 		}
@@ -130,29 +173,83 @@ func (w *ImageWorker) preheatLayers() (err error) {
 		child.URL = layer.url
 		child.Type = "file"
 		child.Headers = layer.headers
-		child.ID, err = w.PreheatService.Create(child)
+		child.Peers = waitingPeers(task.Peers)
+		// the blob cached before needn't be preheated again unless it's
+		// pushed to the peers.
+		if len(child.Peers) == 0 && w.PreheatService.cached(w.ctx, child) {
+			logrus.Debugf("skip the cached %s:%s parentId:%s", layer.kind, layer.url, task.ID)
+			statuses = append(statuses, &types.PreheatLayerStatus{
+				Cached:   true,
+				Digest:   layer.digest,
+				Kind:     layer.kind,
+				Platform: layer.platform,
+				Status:   types.PreheatStatusSUCCESS,
+			})
+			continue
+		}
+		// the child is being preheated by its worker once it's created,
+		// so it mustn't be modified here. The finished child of a previous
+		// preheat task is preheated again since the blob isn't cached.
+		id, err := w.PreheatService.create(child, true)
 		if err != nil {
-			return
+			return err
 		}
-		if child.ID != "" {
-			children = append(children, child.ID)
+		if id != "" {
+			children = append(children, id)
+			statuses = append(statuses, &types.PreheatLayerStatus{
+				ID:       id,
+				Digest:   layer.digest,
+				Kind:     layer.kind,
+				Platform: layer.platform,
+				Status:   types.PreheatStatusWAITING,
+			})
 		}
 	}
 
 	w.Task.Children = children
+	w.Task.Layers = statuses
 	w.Task.Status = types.PreheatStatusRUNNING
 	w.PreheatService.Update(w.Task.ID, w.Task)
 	return
 }
 
+// getLayers returns the blobs to be preheated of the image referenced by url,
+// which are the layers and the config of the image, or the ones of the images
+// of the selected platforms if it's a manifest list. The blobs of the referrers
+// of the images are returned too if required.
 func (w *ImageWorker) getLayers(url string, header map[string]string, retryIfUnAuth bool) (layers []*Layer, err error) {
+	m, digest, header, err := w.getManifest(url, header, retryIfUnAuth)
+	if err != nil {
+		return
+	}
+
+	if len(m.Manifests) > 0 {
+		if layers, err = w.indexLayers(m, header); err != nil {
+			return
+		}
+	} else {
+		layers = w.imageLayers(m, header, "")
+	}
+	if w.Task.Referrers {
+		layers = append(layers, w.referrerLayers(digest, header)...)
+	}
+	return uniqueLayers(layers), nil
+}
+
+// getManifest gets the manifest of url and returns it with its digest and the
+// headers which are authorized to access the registry.
+func (w *ImageWorker) getManifest(url string, header map[string]string, retryIfUnAuth bool) (m *manifest, digest string, authHeader map[string]string, err error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return
 	}
+	req = req.WithContext(w.ctx)
 	for k, v := range header {
 		req.Header.Add(k, v)
 	}
+	// the registries may convert the manifest lists to the schema 1 manifests
+	// if the client doesn't accept them.
+	req.Header.Set("Accept", manifestAccept)
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return
@@ -165,15 +262,127 @@ func (w *ImageWorker) getLayers(url string, header map[string]string, retryIfUnA
 			token := w.getAuthToken(resp.Header)
 			if token != "" {
 				authHeader := map[string]string{"Authorization": "Bearer " + token}
-				return w.getLayers(url, authHeader, false)
+				return w.getManifest(url, authHeader, false)
 			}
 		}
-		err = fmt.Errorf("%s %s", resp.Status, string(body))
+		err = &registryError{code: resp.StatusCode, msg: fmt.Sprintf("%s %s", resp.Status, string(body))}
+		return
+	}
+
+	m = new(manifest)
+	if err = json.Unmarshal(body, m); err != nil {
+		return
+	}
+	digest = resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		digest = fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+	}
+	return m, digest, header, nil
+}
+
+// resolveImageURL returns the url of the image in which the tag is replaced by
+// the digest of the manifest, thus a new preheat task is created for the tag
+// once it's pushed again. The url referenced by a digest is returned as it is.
+func resolveImageURL(ctx context.Context, task *mgr.PreheatTask) (string, error) {
+	result := IMAGE_MANIFESTS_PATTERN.FindStringSubmatch(task.URL)
+	if len(result) != 5 {
+		return "", fmt.Errorf("invalid image url %s", task.URL)
+	}
+	if strings.Contains(result[4], ":") {
+		return task.URL, nil
+	}
+	w := &ImageWorker{
+		BaseWorker: &BaseWorker{Task: task, ctx: ctx},
+		protocol:   result[1],
+		domain:     result[2],
+		name:       result[3],
+	}
+	_, digest, _, err := w.getManifest(task.URL, task.Headers, true)
+	if err != nil {
+		return "", err
+	}
+	return w.manifestUrl(digest), nil
+}
+
+// indexLayers returns the blobs of the images of the selected platforms in the manifest list.
+func (w *ImageWorker) indexLayers(index *manifest, header map[string]string) (layers []*Layer, err error) {
+	filters, err := parsePlatforms(w.Task.Platforms)
+	if err != nil {
 		return
 	}
 
-	layers = w.parseLayers(body, header)
+	for _, d := range index.Manifests {
+		if len(filters) > 0 && !d.Platform.matchAny(filters) {
+			continue
+		}
+		m, _, _, err := w.getManifest(w.manifestUrl(d.Digest), header, false)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, w.imageLayers(m, header, d.Platform.String())...)
+		if w.Task.Referrers {
+			layers = append(layers, w.referrerLayers(d.Digest, header)...)
+		}
+	}
+	if len(layers) == 0 && len(filters) > 0 {
+		return nil, fmt.Errorf("no image of the platforms %v", w.Task.Platforms)
+	}
+	return
+}
+
+// imageLayers returns the config and the layers of the image manifest.
+func (w *ImageWorker) imageLayers(m *manifest, header map[string]string, platform string) (layers []*Layer) {
+	add := func(digest, kind string) {
+		layers = append(layers, &Layer{
+			digest:   digest,
+			url:      w.layerUrl(digest),
+			headers:  header,
+			kind:     kind,
+			platform: platform,
+		})
+	}
+
+	if m.Config != nil && m.Config.Digest != "" {
+		add(m.Config.Digest, types.PreheatLayerStatusKindConfig)
+	}
+	for _, l := range m.Layers {
+		add(l.Digest, types.PreheatLayerStatusKindLayer)
+	}
+	// the schema 1 manifests
+	for _, l := range m.FSLayers {
+		add(l.BlobSum, types.PreheatLayerStatusKindLayer)
+	}
+	return
+}
+
+// referrerLayers returns the blobs of the referrers of the manifest, such as
+// the signatures and the SBOMs. The referrers are optional, so the errors are
+// only logged.
+func (w *ImageWorker) referrerLayers(digest string, header map[string]string) (layers []*Layer) {
+	index, _, _, err := w.getManifest(w.referrersUrl(digest), header, false)
+	if isNotFound(err) {
+		// the registry doesn't support the referrers API, try the referrers
+		// tag schema: <alg>-<ref>
+		index, _, _, err = w.getManifest(w.manifestUrl(strings.Replace(digest, ":", "-", 1)), header, false)
+	}
+	if err != nil {
+		if !isNotFound(err) {
+			logrus.Warnf("PreheatImage Task [%s] failed to get the referrers of %s: %v", w.Task.ID, digest, err)
+		}
+		return
+	}
 
+	for _, d := range index.Manifests {
+		m, _, _, err := w.getManifest(w.manifestUrl(d.Digest), header, false)
+		if err != nil {
+			logrus.Warnf("PreheatImage Task [%s] failed to get the referrer %s of %s: %v", w.Task.ID, d.Digest, digest, err)
+			continue
+		}
+		for _, l := range w.imageLayers(m, header, "") {
+			l.kind = types.PreheatLayerStatusKindReferrer
+			layers = append(layers, l)
+		}
+	}
 	return
 }
 
@@ -223,39 +432,6 @@ func (w *ImageWorker) authUrl(wwwAuth []string) string {
 	return fmt.Sprintf("%s?%s", host, query)
 }
 
-func (w *ImageWorker) parseLayers(body []byte, header map[string]string) (layers []*Layer) {
-	var meta = make(map[string]interface{})
-	json.Unmarshal(body, &meta)
-	schemaVersion := fmt.Sprintf("%v", meta["schemaVersion"])
-	var layerDigest []string
-	if schemaVersion == "1" {
-		layerDigest = w.parseLayerDigest(meta, "fsLayers", "blobSum")
-	} else {
-		mediaType := fmt.Sprintf("%s", meta["mediaType"])
-		switch mediaType {
-		case "application/vnd.docker.distribution.manifest.list.v2+json", "application/vnd.oci.image.index.v1+json":
-			manifestDigest := w.parseLayerDigest(meta, "manifests", "digest")
-			for _, digest := range manifestDigest {
-				list, _ := w.getLayers(w.manifestUrl(digest), header, false)
-				layers = append(layers, list...)
-			}
-			return
-		default:
-			layerDigest = w.parseLayerDigest(meta, "layers", "digest")
-		}
-	}
-
-	for _, digest := range layerDigest {
-		layers = append(layers, &Layer{
-			digest:  digest,
-			url:     w.layerUrl(digest),
-			headers: header,
-		})
-	}
-
-	return
-}
-
 func (w *ImageWorker) layerUrl(digest string) string {
 	return fmt.Sprintf("%s://%s/v2/%s/blobs/%s", w.protocol, w.domain, w.name, digest)
 }
@@ -264,26 +440,29 @@ func (w *ImageWorker) manifestUrl(digest string) string {
 	return fmt.Sprintf("%s://%s/v2/%s/manifests/%s", w.protocol, w.domain, w.name, digest)
 }
 
-func (w *ImageWorker) parseLayerDigest(meta map[string]interface{}, layerKey string, digestKey string) (layers []string) {
-	data := meta[layerKey]
-	if data == nil {
-		return
-	}
-	array, _ := data.([]interface{})
-	if array == nil {
-		return
-	}
-	for _, it := range array {
-		layer, _ := it.(map[string]interface{})
-		if layer != nil {
-			layers = append(layers, fmt.Sprintf("%v", layer[digestKey]))
+func (w *ImageWorker) referrersUrl(digest string) string {
+	return fmt.Sprintf("%s://%s/v2/%s/referrers/%s", w.protocol, w.domain, w.name, digest)
+}
+
+// uniqueLayers removes the duplicated blobs shared by the images, and the
+// first one is kept.
+func uniqueLayers(layers []*Layer) []*Layer {
+	seen := make(map[string]bool)
+	result := make([]*Layer, 0, len(layers))
+	for _, l := range layers {
+		if seen[l.digest] {
+			continue
 		}
+		seen[l.digest] = true
+		result = append(result, l)
 	}
-	return layers
+	return result
 }
 
 type Layer struct {
-	digest  string
-	url     string
-	headers map[string]string
+	digest   string
+	url      string
+	headers  map[string]string
+	kind     string
+	platform string
 }
@@ -13,18 +13,54 @@
  * See the License for the specific language governing permissions and
  * limitations under the License.
  */
+
 package preheat
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/mock"
+	dftask "github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/task"
+
+	"github.com/go-check/check"
+	"github.com/golang/mock/gomock"
 )
 
-func TestParseLayers(t *testing.T) {
+// fakeRegistry serves the manifests by the path, and the digest of a manifest
+// is the last element of its path.
+func fakeRegistry(t interface {
+	Errorf(format string, args ...interface{})
+}, manifests map[string]*manifest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept"), mediaTypeOCIIndex) {
+			t.Errorf("missing the Accept header of %s", r.URL.Path)
+		}
+		m, ok := manifests[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+		json.NewEncoder(w).Encode(m)
+	}))
+}
+
+func newTestImageWorker(url string, platforms []string, referrers bool) *ImageWorker {
 	task := &mgr.PreheatTask{
-		URL: "https://registry.cn-zhangjiakou.aliyuncs.com/v2/acs/alpine/manifests/3.6",
-		Headers: map[string]string{},
+		URL:       url,
+		Headers:   map[string]string{},
+		Platforms: platforms,
+		Referrers: referrers,
 	}
 	worker := &ImageWorker{BaseWorker: newBaseWorker(task, nil, nil)}
 	result := IMAGE_MANIFESTS_PATTERN.FindSubmatch([]byte(task.URL))
@@ -33,11 +69,225 @@ func TestParseLayers(t *testing.T) {
 		worker.domain = string(result[2])
 		worker.name = string(result[3])
 	}
-	layers, err := worker.getLayers(task.URL, task.Headers, true)
+	return worker
+}
+
+// describeLayers returns the layers in the format of kind:platform:digest.
+func describeLayers(layers []*Layer) (result []string) {
+	for _, l := range layers {
+		result = append(result, l.kind+":"+l.platform+":"+l.digest)
+	}
+	return
+}
+
+func TestParseLayers(t *testing.T) {
+	server := fakeRegistry(t, map[string]*manifest{
+		"/v2/acs/alpine/manifests/3.6": {
+			SchemaVersion: 2,
+			MediaType:     mediaTypeDockerManifest,
+			Config:        &descriptor{Digest: "sha256:c"},
+			Layers:        []*descriptor{{Digest: "sha256:l1"}, {Digest: "sha256:l2"}, {Digest: "sha256:l1"}},
+		},
+	})
+	defer server.Close()
+
+	worker := newTestImageWorker(server.URL+"/v2/acs/alpine/manifests/3.6", nil, false)
+	layers, err := worker.getLayers(worker.Task.URL, worker.Task.Headers, true)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(layers) != 4 {
-		t.Fatal("parse layer failed")
+	expected := []string{"config::sha256:c", "layer::sha256:l1", "layer::sha256:l2"}
+	if !reflect.DeepEqual(describeLayers(layers), expected) {
+		t.Fatalf("parse layer failed: %v", describeLayers(layers))
+	}
+	if layers[1].url != server.URL+"/v2/acs/alpine/blobs/sha256:l1" {
+		t.Fatalf("unexpected layer url: %s", layers[1].url)
+	}
+}
+
+func TestParseLayersOfManifestList(t *testing.T) {
+	server := fakeRegistry(t, map[string]*manifest{
+		"/v2/app/manifests/sha256:index": {
+			SchemaVersion: 2,
+			MediaType:     mediaTypeOCIIndex,
+			Manifests: []*descriptor{
+				{Digest: "sha256:amd64", Platform: &platform{OS: "linux", Architecture: "amd64"}},
+				{Digest: "sha256:arm64", Platform: &platform{OS: "linux", Architecture: "arm64", Variant: "v8"}},
+			},
+		},
+		"/v2/app/manifests/sha256:amd64": {
+			SchemaVersion: 2,
+			Config:        &descriptor{Digest: "sha256:c1"},
+			Layers:        []*descriptor{{Digest: "sha256:base"}, {Digest: "sha256:amd64-layer"}},
+		},
+		"/v2/app/manifests/sha256:arm64": {
+			SchemaVersion: 2,
+			Config:        &descriptor{Digest: "sha256:c2"},
+			Layers:        []*descriptor{{Digest: "sha256:base"}, {Digest: "sha256:arm64-layer"}},
+		},
+		// the referrers of the arm64 image are listed by the referrers API
+		"/v2/app/referrers/sha256:arm64": {
+			SchemaVersion: 2,
+			Manifests:     []*descriptor{{Digest: "sha256:sig"}},
+		},
+		"/v2/app/manifests/sha256:sig": {
+			SchemaVersion: 2,
+			Config:        &descriptor{Digest: "sha256:sig-config"},
+			Layers:        []*descriptor{{Digest: "sha256:sig-layer"}},
+		},
+		// the referrers of the manifest list are listed by the tag schema
+		"/v2/app/manifests/sha256-index": {
+			SchemaVersion: 2,
+			Manifests:     []*descriptor{{Digest: "sha256:sbom"}},
+		},
+		"/v2/app/manifests/sha256:sbom": {
+			SchemaVersion: 2,
+			Layers:        []*descriptor{{Digest: "sha256:sbom-layer"}},
+		},
+	})
+	defer server.Close()
+	url := server.URL + "/v2/app/manifests/sha256:index"
+
+	var cases = []struct {
+		platforms []string
+		referrers bool
+		expected  []string
+	}{
+		{
+			expected: []string{
+				"config:linux/amd64:sha256:c1",
+				"layer:linux/amd64:sha256:base",
+				"layer:linux/amd64:sha256:amd64-layer",
+				"config:linux/arm64/v8:sha256:c2",
+				"layer:linux/arm64/v8:sha256:arm64-layer",
+			},
+		},
+		{
+			platforms: []string{"linux/arm64"},
+			expected: []string{
+				"config:linux/arm64/v8:sha256:c2",
+				"layer:linux/arm64/v8:sha256:base",
+				"layer:linux/arm64/v8:sha256:arm64-layer",
+			},
+		},
+		{
+			platforms: []string{"linux/arm64/v8"},
+			referrers: true,
+			expected: []string{
+				"config:linux/arm64/v8:sha256:c2",
+				"layer:linux/arm64/v8:sha256:base",
+				"layer:linux/arm64/v8:sha256:arm64-layer",
+				"referrer::sha256:sig-config",
+				"referrer::sha256:sig-layer",
+				"referrer::sha256:sbom-layer",
+			},
+		},
+	}
+
+	for _, v := range cases {
+		worker := newTestImageWorker(url, v.platforms, v.referrers)
+		layers, err := worker.getLayers(url, nil, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(describeLayers(layers), v.expected) {
+			t.Errorf("unexpected layers of %v: %v", v.platforms, describeLayers(layers))
+		}
 	}
+
+	for _, platforms := range [][]string{{"linux/arm64/v7"}, {"windows/amd64"}, {"linux"}} {
+		worker := newTestImageWorker(url, platforms, false)
+		if _, err := worker.getLayers(url, nil, true); err == nil {
+			t.Errorf("expected an error of the platforms %v", platforms)
+		}
+	}
+}
+
+func (s *PreheatServiceTestSuite) TestPreheatImageLayers(c *check.C) {
+	server := fakeRegistry(c, map[string]*manifest{
+		"/v2/app/manifests/v1": {
+			SchemaVersion: 2,
+			MediaType:     mediaTypeDockerManifestList,
+			Manifests: []*descriptor{
+				{Digest: "sha256:amd64", Platform: &platform{OS: "linux", Architecture: "amd64"}},
+				{Digest: "sha256:arm64", Platform: &platform{OS: "linux", Architecture: "arm64"}},
+			},
+		},
+		"/v2/app/manifests/sha256:arm64": {
+			SchemaVersion: 2,
+			Config:        &descriptor{Digest: "sha256:c"},
+			Layers:        []*descriptor{{Digest: "sha256:l1"}, {Digest: "sha256:l2"}},
+		},
+	})
+	defer server.Close()
+
+	// the layer l2 has been cached in the supernode CDN and is skipped
+	_, cachedID := s.service.taskCreateRequest(&mgr.PreheatTask{URL: server.URL + "/v2/app/blobs/sha256:l2"})
+	cdn := newFakeCDN(s.mockTaskMgr, cachedID)
+
+	id, err := s.service.Create(&mgr.PreheatTask{
+		Type:      "image",
+		URL:       server.URL + "/v2/app/manifests/v1",
+		Platforms: []string{"linux/arm64"},
+	})
+	c.Assert(err, check.IsNil)
+	// the preheat task of the same image for different platforms is another one
+	c.Check(id, check.Not(check.Equals), s.service.createTaskID(server.URL+"/v2/app/manifests/v1", "", "", nil))
+
+	task := s.waitFinished(c, id)
+	c.Check(task.Status, check.Equals, types.PreheatStatusSUCCESS)
+	c.Check(cdn.registeredCount(), check.Equals, 2)
+	c.Assert(task.Children, check.HasLen, 2)
+	c.Assert(task.Layers, check.HasLen, 3)
+	for i, digest := range []string{"sha256:c", "sha256:l1", "sha256:l2"} {
+		c.Check(task.Layers[i].Digest, check.Equals, digest)
+		c.Check(task.Layers[i].Platform, check.Equals, "linux/arm64")
+		c.Check(task.Layers[i].Status, check.Equals, types.PreheatStatusSUCCESS)
+	}
+	c.Check(task.Layers[0].ID, check.Equals, task.Children[0])
+	c.Check(task.Layers[1].ID, check.Equals, task.Children[1])
+	c.Check(task.Layers[2].ID, check.Equals, "")
+	c.Check(task.Layers[2].Cached, check.Equals, true)
+	c.Check(task.Layers[0].Kind, check.Equals, types.PreheatLayerStatusKindConfig)
+	c.Check(task.Layers[1].Kind, check.Equals, types.PreheatLayerStatusKindLayer)
+}
+
+// fakeCDN mocks the taskMgr, the registered tasks are cached in the CDN
+// successfully at once.
+type fakeCDN struct {
+	sync.Mutex
+	cached     map[string]bool
+	registered int
+}
+
+func newFakeCDN(taskMgr *mock.MockTaskMgr, cachedIDs ...string) *fakeCDN {
+	cdn := &fakeCDN{cached: make(map[string]bool)}
+	for _, id := range cachedIDs {
+		cdn.cached[id] = true
+	}
+	taskMgr.EXPECT().Register(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *types.TaskCreateRequest) (*types.TaskCreateResponse, error) {
+			cdn.Lock()
+			defer cdn.Unlock()
+			id := dftask.GenerateTaskID(req)
+			cdn.cached[id] = true
+			cdn.registered++
+			return &types.TaskCreateResponse{ID: id}, nil
+		}).AnyTimes()
+	taskMgr.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, taskID string) (*types.TaskInfo, error) {
+			cdn.Lock()
+			defer cdn.Unlock()
+			if !cdn.cached[taskID] {
+				return nil, errortypes.ErrDataNotFound
+			}
+			return &types.TaskInfo{ID: taskID, CdnStatus: types.TaskInfoCdnStatusSUCCESS}, nil
+		}).AnyTimes()
+	return cdn
+}
+
+func (cdn *fakeCDN) registeredCount() int {
+	cdn.Lock()
+	defer cdn.Unlock()
+	return cdn.registered
 }
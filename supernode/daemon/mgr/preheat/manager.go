@@ -25,6 +25,7 @@ import (
 	dferr "github.com/dragonflyoss/Dragonfly/pkg/errortypes"
 	"github.com/dragonflyoss/Dragonfly/supernode/config"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
 )
 
 var _ mgr.PreheatManager = &Manager{}
@@ -34,19 +35,30 @@ type Manager struct {
 	service *PreheatService
 }
 
-func NewManager(cfg *config.Config) (mgr.PreheatManager, error) {
-	return &Manager{service: NewPreheatService(cfg.HomeDir)}, nil
+// NewManager returns a new Manager, which preheats the tasks by registering
+// them to the supernode through taskMgr and tracks the progress of them in CDN.
+// The file is also pushed to the selected peers through peerMgr if required.
+// The preheat schedules are kept in backend, and each of them is run by the
+// member of the cluster which owns it if the backend is shared.
+func NewManager(cfg *config.Config, taskMgr mgr.TaskMgr, cdnMgr mgr.CDNMgr, progressMgr mgr.ProgressMgr,
+	peerMgr mgr.PeerMgr, clusterMgr mgr.ClusterMgr, backend state.Backend) (mgr.PreheatManager, error) {
+	service, err := NewPreheatService(cfg, taskMgr, cdnMgr, progressMgr, peerMgr, clusterMgr, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{service: service}, nil
 }
 
 func (m *Manager) Create(ctx context.Context, task *types.PreheatCreateRequest) (preheatID string, err error) {
-	preheatTask := new(mgr.PreheatTask)
-	preheatTask.Type = *task.Type
-	preheatTask.URL = *task.URL
-	preheatTask.Filter = task.Filter
-	preheatTask.Identifier = task.Identifier
-	preheatTask.Headers = task.Headers
+	preheatTask, err := m.service.newTask(ctx, task)
+	if err != nil {
+		return "", err
+	}
 	logrus.Debugf("create preheat: Type[%s] URL[%s] Filter[%s] Identifier[%s] Headers[%v]",
 		preheatTask.Type, preheatTask.URL, preheatTask.Filter, preheatTask.Identifier, preheatTask.Headers)
+	if len(preheatTask.Peers) > 0 {
+		logrus.Infof("preheat %s to %d peers", preheatTask.URL, len(preheatTask.Peers))
+	}
 	return m.service.Create(preheatTask)
 }
 
@@ -67,3 +79,32 @@ func (m *Manager) GetAll(ctx context.Context) (preheatTasks []*mgr.PreheatTask,
 	preheatTasks = m.service.GetAll()
 	return
 }
+
+func (m *Manager) CreateSchedule(ctx context.Context, request *types.PreheatScheduleCreateRequest) (scheduleID string, err error) {
+	return m.service.CreateSchedule(ctx, request)
+}
+
+func (m *Manager) GetSchedule(ctx context.Context, scheduleID string) (schedule *mgr.PreheatSchedule, err error) {
+	schedule = m.service.GetSchedule(scheduleID)
+	if schedule == nil {
+		err = dferr.New(http.StatusNotFound, "preheat schedule "+scheduleID+" doesn't exists")
+	}
+	return
+}
+
+func (m *Manager) GetAllSchedules(ctx context.Context) (schedules []*mgr.PreheatSchedule, err error) {
+	schedules = m.service.GetAllSchedules()
+	return
+}
+
+func (m *Manager) DeleteSchedule(ctx context.Context, scheduleID string) (err error) {
+	return m.service.DeleteSchedule(scheduleID)
+}
+
+func (m *Manager) EnableSchedule(ctx context.Context, scheduleID string, enable bool) (schedule *mgr.PreheatSchedule, err error) {
+	return m.service.EnableSchedule(scheduleID, enable)
+}
+
+func (m *Manager) StartSchedule(ctx context.Context) {
+	m.service.StartSchedule(ctx)
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package preheat
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+
+	"github.com/pkg/errors"
+)
+
+// preheatError is an error with the code of types.PreheatError,
+// which tells why and where the preheat task failed.
+type preheatError struct {
+	code string
+	url  string
+	msg  string
+}
+
+func newPreheatError(code, url, format string, args ...interface{}) error {
+	return &preheatError{
+		code: code,
+		url:  url,
+		msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+func (e *preheatError) Error() string {
+	return fmt.Sprintf("%s: %s", e.code, e.msg)
+}
+
+// toPreheatError converts err to the structured error of the preheat task,
+// and the url is the one of the file which fails to be preheated if err doesn't
+// specify it.
+func toPreheatError(err error, url string) *types.PreheatError {
+	if e, ok := errors.Cause(err).(*preheatError); ok {
+		if e.url != "" {
+			url = e.url
+		}
+		return &types.PreheatError{Code: e.code, Message: e.msg, URL: url}
+	}
+
+	code := types.PreheatErrorCodeINTERNAL
+	switch cause := errors.Cause(err); {
+	case cause == context.Canceled:
+		code = types.PreheatErrorCodeCANCELED
+	case cause == context.DeadlineExceeded:
+		code = types.PreheatErrorCodeTIMEOUT
+	case errortypes.IsURLNotReachable(err):
+		code = types.PreheatErrorCodeSOURCEUNREACHABLE
+	case errortypes.IsAuthenticationRequired(err):
+		code = types.PreheatErrorCodeAUTHREQUIRED
+	case errortypes.IsTenantQuotaExceeded(err):
+		code = types.PreheatErrorCodeQUOTAEXCEEDED
+	case errortypes.IsCDNFail(err):
+		code = types.PreheatErrorCodeCDNFAILED
+	}
+	return &types.PreheatError{Code: code, Message: err.Error(), URL: url}
+}
@@ -0,0 +1,310 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package preheat
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/api"
+	dferr "github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// peerConcurrency is the max count of the peers which are requested concurrently.
+	peerConcurrency = 16
+
+	// maxPeerQueryFailures is the max count of the continuous failures to query
+	// the status of a peer, and the peer is failed beyond it.
+	maxPeerQueryFailures = 3
+)
+
+// selectPeers resolves the selector into the target peers of a preheat task.
+// The peers registered by the same uploader are treated as one peer, and the
+// latest registered one is selected.
+func (svc *PreheatService) selectPeers(ctx context.Context, selector *types.PreheatPeerSelector) ([]*types.PreheatPeerStatus, error) {
+	if selector == nil {
+		return nil, nil
+	}
+	if len(selector.PeerIds) == 0 && len(selector.Labels) == 0 && selector.PerIdc <= 0 {
+		return nil, dferr.New(http.StatusBadRequest, "the peer selector is empty")
+	}
+	// the peers only accept the preheat requests signed with the auth token.
+	if svc.cfg.Auth.Token == "" {
+		return nil, dferr.New(http.StatusBadRequest, "auth.token is required to preheat the peers")
+	}
+
+	selected := make(map[string]*types.PeerInfo)
+	for _, id := range selector.PeerIds {
+		peer, err := svc.peerMgr.Get(ctx, id)
+		if err != nil {
+			return nil, dferr.Newf(http.StatusBadRequest, "peer %s isn't registered", id)
+		}
+		if svc.cfg.IsSuperPID(peer.ID) || peer.Port <= 0 {
+			return nil, dferr.Newf(http.StatusBadRequest, "peer %s has no uploader", id)
+		}
+		selected[peerAddr(peer)] = peer
+	}
+
+	if len(selector.Labels) > 0 || selector.PerIdc > 0 {
+		peers, err := svc.peerMgr.List(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		candidates := make([]*types.PeerInfo, 0)
+		for _, peer := range latestPeers(peers) {
+			if svc.cfg.IsSuperPID(peer.ID) || peer.Port <= 0 || !matchLabels(peer.Labels, selector.Labels) {
+				continue
+			}
+			candidates = append(candidates, peer)
+		}
+		if selector.PerIdc > 0 {
+			candidates = pickPerIDC(candidates, int(selector.PerIdc))
+		}
+		for _, peer := range candidates {
+			if _, ok := selected[peerAddr(peer)]; !ok {
+				selected[peerAddr(peer)] = peer
+			}
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, dferr.New(http.StatusBadRequest, "no peer matches the selector")
+	}
+	result := make([]*types.PreheatPeerStatus, 0, len(selected))
+	for _, peer := range selected {
+		result = append(result, &types.PreheatPeerStatus{
+			PeerID: peer.ID,
+			IDC:    peer.IDC,
+			IP:     peer.IP,
+			Port:   peer.Port,
+			Status: types.PreheatStatusWAITING,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].PeerID < result[j].PeerID
+	})
+	return result, nil
+}
+
+// pushToPeers asks the target peers to download the file of the preheat task
+// in the background and waits for all of them to finish. The statuses of the
+// peers are updated into the repository during the waiting.
+func (svc *PreheatService) pushToPeers(ctx context.Context, task *mgr.PreheatTask) error {
+	peers := copyPeers(task.Peers)
+	req := &api.PreheatRequest{
+		ID:         task.ID,
+		URL:        task.URL,
+		Identifier: task.Identifier,
+		Filter:     task.Filter,
+		Supernode:  net.JoinHostPort(svc.cfg.AdvertiseIP, strconv.Itoa(svc.cfg.ListenPort)),
+	}
+	for k, v := range task.Headers {
+		req.Headers = append(req.Headers, k+":"+v)
+	}
+
+	forEachPeer(peers, func(peer *types.PreheatPeerStatus) {
+		if err := svc.uploaderAPI.Preheat(peer.IP.String(), int(peer.Port), req, svc.cfg.Auth.Token); err != nil {
+			logrus.Warnf("failed to push preheat task %s to peer %s: %v", task.ID, peer.PeerID, err)
+			peer.Status = types.PreheatStatusFAILED
+			peer.ErrorMsg = err.Error()
+			return
+		}
+		peer.Status = types.PreheatStatusRUNNING
+	})
+	svc.Update(task.ID, &mgr.PreheatTask{Peers: peers})
+
+	ticker := time.NewTicker(queryInterval)
+	defer ticker.Stop()
+	failures := make(map[string]int)
+	var mu sync.Mutex
+	for countPeers(peers, types.PreheatStatusRUNNING) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		forEachPeer(peers, func(peer *types.PreheatPeerStatus) {
+			if peer.Status != types.PreheatStatusRUNNING {
+				return
+			}
+			resp, err := svc.uploaderAPI.GetPreheat(peer.IP.String(), int(peer.Port), task.ID)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if failures[peer.PeerID]++; failures[peer.PeerID] >= maxPeerQueryFailures {
+					peer.Status = types.PreheatStatusFAILED
+					peer.ErrorMsg = err.Error()
+				}
+				return
+			}
+			failures[peer.PeerID] = 0
+			if resp.Status == string(types.PreheatStatusSUCCESS) || resp.Status == string(types.PreheatStatusFAILED) {
+				peer.Status = types.PreheatStatus(resp.Status)
+				peer.ErrorMsg = resp.ErrorMsg
+			}
+		})
+		svc.Update(task.ID, &mgr.PreheatTask{Peers: peers})
+	}
+
+	if failed := countPeers(peers, types.PreheatStatusFAILED); failed > 0 {
+		return newPreheatError(types.PreheatErrorCodePEERFAILED, task.URL,
+			"%d of %d peers failed to download the file", failed, len(peers))
+	}
+	return nil
+}
+
+// mergePeers merges the statuses of the target peers of the children into
+// the ones of the parent. A peer is FAILED if it fails in any child, and it's
+// SUCCESS only when it succeeds in all the children.
+func mergePeers(parent []*types.PreheatPeerStatus, children [][]*types.PreheatPeerStatus) []*types.PreheatPeerStatus {
+	result := copyPeers(parent)
+	for _, peer := range result {
+		statuses := make(map[types.PreheatStatus]int)
+		for _, child := range children {
+			for _, p := range child {
+				if p.PeerID != peer.PeerID {
+					continue
+				}
+				statuses[p.Status]++
+				if p.Status == types.PreheatStatusFAILED && peer.ErrorMsg == "" {
+					peer.ErrorMsg = p.ErrorMsg
+				}
+			}
+		}
+		switch {
+		case statuses[types.PreheatStatusFAILED] > 0:
+			peer.Status = types.PreheatStatusFAILED
+		case statuses[types.PreheatStatusSUCCESS] == len(children) && len(children) > 0:
+			peer.Status = types.PreheatStatusSUCCESS
+		case statuses[types.PreheatStatusRUNNING] > 0 || statuses[types.PreheatStatusSUCCESS] > 0:
+			peer.Status = types.PreheatStatusRUNNING
+		}
+	}
+	return result
+}
+
+// peersSign returns the sign of the target peers which identifies
+// the preheat tasks of the same file to different peers.
+func peersSign(peers []*types.PreheatPeerStatus) string {
+	ids := make([]string, 0, len(peers))
+	for _, p := range peers {
+		ids = append(ids, p.PeerID)
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+// waitingPeers returns the copies of the peers whose status is reset to WAITING.
+func waitingPeers(peers []*types.PreheatPeerStatus) []*types.PreheatPeerStatus {
+	result := copyPeers(peers)
+	for _, p := range result {
+		p.Status = types.PreheatStatusWAITING
+		p.ErrorMsg = ""
+	}
+	return result
+}
+
+// forEachPeer calls f for every peer concurrently.
+func forEachPeer(peers []*types.PreheatPeerStatus, f func(peer *types.PreheatPeerStatus)) {
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, peerConcurrency)
+	for _, peer := range peers {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(peer *types.PreheatPeerStatus) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			f(peer)
+		}(peer)
+	}
+	wg.Wait()
+}
+
+func countPeers(peers []*types.PreheatPeerStatus, status types.PreheatStatus) (count int) {
+	for _, p := range peers {
+		if p.Status == status {
+			count++
+		}
+	}
+	return
+}
+
+// latestPeers returns the latest registered peer of every uploader.
+func latestPeers(peers []*types.PeerInfo) []*types.PeerInfo {
+	latest := make(map[string]*types.PeerInfo)
+	for _, peer := range peers {
+		addr := peerAddr(peer)
+		if p, ok := latest[addr]; !ok || time.Time(p.Created).Before(time.Time(peer.Created)) {
+			latest[addr] = peer
+		}
+	}
+	result := make([]*types.PeerInfo, 0, len(latest))
+	for _, peer := range latest {
+		result = append(result, peer)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// pickPerIDC picks n random peers of every IDC.
+func pickPerIDC(peers []*types.PeerInfo, n int) []*types.PeerInfo {
+	idcs := make(map[string][]*types.PeerInfo)
+	for _, peer := range peers {
+		idcs[peer.IDC] = append(idcs[peer.IDC], peer)
+	}
+	result := make([]*types.PeerInfo, 0)
+	for _, list := range idcs {
+		rand.Shuffle(len(list), func(i, j int) {
+			list[i], list[j] = list[j], list[i]
+		})
+		if len(list) > n {
+			list = list[:n]
+		}
+		result = append(result, list...)
+	}
+	return result
+}
+
+func matchLabels(labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func peerAddr(peer *types.PeerInfo) string {
+	return net.JoinHostPort(peer.IP.String(), strconv.Itoa(int(peer.Port)))
+}
@@ -0,0 +1,221 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package preheat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/api"
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+
+	"github.com/go-check/check"
+	"github.com/go-openapi/strfmt"
+	"github.com/golang/mock/gomock"
+)
+
+// fakeUploaderAPI simulates the uploaders of the peers by IP. A preheat job
+// is RUNNING at the first query and then becomes the status in results.
+type fakeUploaderAPI struct {
+	api.UploaderAPI
+	sync.Mutex
+	pushErrs map[string]error
+	results  map[string]string
+	requests map[string]*api.PreheatRequest
+	queried  map[string]bool
+	secret   string
+}
+
+func (f *fakeUploaderAPI) Preheat(ip string, port int, req *api.PreheatRequest, secret string) error {
+	f.Lock()
+	defer f.Unlock()
+	f.requests[ip] = req
+	f.secret = secret
+	return f.pushErrs[ip]
+}
+
+func (f *fakeUploaderAPI) GetPreheat(ip string, port int, id string) (*api.PreheatResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	if !f.queried[ip] {
+		f.queried[ip] = true
+		return &api.PreheatResponse{ID: id, Status: "RUNNING"}, nil
+	}
+	if f.results[ip] == "" {
+		return nil, fmt.Errorf("404:preheat job not found")
+	}
+	return &api.PreheatResponse{ID: id, Status: f.results[ip], ErrorMsg: "msg of " + ip}, nil
+}
+
+func (s *PreheatServiceTestSuite) TestPreheatFileToPeers(c *check.C) {
+	uploader := &fakeUploaderAPI{
+		pushErrs: map[string]error{"10.0.0.3": fmt.Errorf("connection refused")},
+		results:  map[string]string{"10.0.0.1": "SUCCESS", "10.0.0.2": "FAILED"},
+		requests: make(map[string]*api.PreheatRequest),
+		queried:  make(map[string]bool),
+	}
+	s.service.uploaderAPI = uploader
+	s.cfg.AdvertiseIP = "10.0.0.100"
+	s.mockTaskMgr.EXPECT().Register(gomock.Any(), gomock.Any()).Return(&types.TaskCreateResponse{ID: "taskID"}, nil)
+	s.mockTaskMgr.EXPECT().Get(gomock.Any(), "taskID").Return(&types.TaskInfo{
+		ID:         "taskID",
+		CdnStatus:  types.TaskInfoCdnStatusSUCCESS,
+		PieceTotal: 0,
+	}, nil)
+
+	peers := []*types.PreheatPeerStatus{
+		{PeerID: "p1", IP: "10.0.0.1", Port: 15001, Status: types.PreheatStatusWAITING},
+		{PeerID: "p2", IP: "10.0.0.2", Port: 15001, Status: types.PreheatStatusWAITING},
+		{PeerID: "p3", IP: "10.0.0.3", Port: 15001, Status: types.PreheatStatusWAITING},
+		{PeerID: "p4", IP: "10.0.0.4", Port: 15001, Status: types.PreheatStatusWAITING},
+	}
+	id, err := s.service.Create(&mgr.PreheatTask{
+		Type:    "file",
+		URL:     "http://aa.bb.com/foo",
+		Headers: map[string]string{"Authorization": "Basic foo"},
+		Peers:   peers,
+	})
+	c.Assert(err, check.IsNil)
+	// the preheat task of the same file to different peers is another one
+	c.Check(id, check.Not(check.Equals), s.service.createTaskID("http://aa.bb.com/foo", "", "",
+		map[string]string{"Authorization": "Basic foo"}))
+
+	task := s.waitFinished(c, id)
+	c.Check(task.Status, check.Equals, types.PreheatStatusFAILED)
+	c.Assert(task.Error, check.NotNil)
+	c.Check(task.Error.Code, check.Equals, types.PreheatErrorCodePEERFAILED)
+	c.Check(task.Error.Message, check.Equals, "3 of 4 peers failed to download the file")
+	c.Assert(task.Peers, check.HasLen, 4)
+	c.Check(task.Peers[0].Status, check.Equals, types.PreheatStatusSUCCESS)
+	c.Check(task.Peers[1].Status, check.Equals, types.PreheatStatusFAILED)
+	c.Check(task.Peers[1].ErrorMsg, check.Equals, "msg of 10.0.0.2")
+	c.Check(task.Peers[2].Status, check.Equals, types.PreheatStatusFAILED)
+	c.Check(task.Peers[2].ErrorMsg, check.Equals, "connection refused")
+	// the peer is failed after querying it fails continuously
+	c.Check(task.Peers[3].Status, check.Equals, types.PreheatStatusFAILED)
+
+	uploader.Lock()
+	defer uploader.Unlock()
+	req := uploader.requests["10.0.0.1"]
+	// the requests are signed with the auth token
+	c.Check(uploader.secret, check.Equals, "token")
+	c.Assert(req, check.NotNil)
+	c.Check(req.ID, check.Equals, id)
+	c.Check(req.URL, check.Equals, "http://aa.bb.com/foo")
+	c.Check(req.Headers, check.DeepEquals, []string{"Authorization:Basic foo"})
+	c.Check(req.Supernode, check.Equals, fmt.Sprintf("10.0.0.100:%d", s.cfg.ListenPort))
+}
+
+func (s *PreheatServiceTestSuite) TestSelectPeers(c *check.C) {
+	now := time.Now()
+	peer := func(id, ip, idc string, port int32, created time.Duration, labels map[string]string) *types.PeerInfo {
+		return &types.PeerInfo{
+			ID:      id,
+			IDC:     idc,
+			IP:      strfmt.IPv4(ip),
+			Port:    port,
+			Labels:  labels,
+			Created: strfmt.DateTime(now.Add(created)),
+		}
+	}
+	peers := []*types.PeerInfo{
+		peer("superPID", "10.0.0.100", "hz", 8001, 0, nil),
+		peer("a-old", "10.0.0.1", "hz", 15001, -time.Minute, map[string]string{"zone": "east"}),
+		peer("a", "10.0.0.1", "hz", 15001, 0, map[string]string{"zone": "east"}),
+		peer("b", "10.0.0.2", "hz", 15001, 0, map[string]string{"zone": "west"}),
+		peer("c", "10.0.0.3", "sh", 15001, 0, map[string]string{"zone": "east"}),
+		peer("d", "10.0.0.4", "sh", 0, 0, map[string]string{"zone": "east"}),
+	}
+	s.mockPeerMgr.EXPECT().List(gomock.Any(), gomock.Any()).Return(peers, nil).AnyTimes()
+	s.mockPeerMgr.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, id string) (*types.PeerInfo, error) {
+			for _, p := range peers {
+				if p.ID == id {
+					return p, nil
+				}
+			}
+			return nil, errortypes.ErrDataNotFound
+		}).AnyTimes()
+
+	ids := func(selector *types.PreheatPeerSelector) []string {
+		result, err := s.service.selectPeers(context.Background(), selector)
+		c.Assert(err, check.IsNil)
+		list := make([]string, 0)
+		for _, p := range result {
+			c.Check(p.Status, check.Equals, types.PreheatStatusWAITING)
+			list = append(list, p.PeerID)
+		}
+		return list
+	}
+	c.Check(ids(&types.PreheatPeerSelector{PeerIds: []string{"b"}}), check.DeepEquals, []string{"b"})
+	c.Check(ids(&types.PreheatPeerSelector{Labels: map[string]string{"zone": "east"}}),
+		check.DeepEquals, []string{"a", "c"})
+	c.Check(ids(&types.PreheatPeerSelector{PeerIds: []string{"b"}, Labels: map[string]string{"zone": "east"}}),
+		check.DeepEquals, []string{"a", "b", "c"})
+	// the selected peer of the same uploader isn't selected twice
+	c.Check(ids(&types.PreheatPeerSelector{PeerIds: []string{"a-old"}, Labels: map[string]string{"zone": "east"}}),
+		check.DeepEquals, []string{"a-old", "c"})
+	c.Check(ids(&types.PreheatPeerSelector{PerIdc: 1}), check.HasLen, 2)
+	c.Check(ids(&types.PreheatPeerSelector{PerIdc: 5}), check.DeepEquals, []string{"a", "b", "c"})
+
+	result, err := s.service.selectPeers(context.Background(), nil)
+	c.Check(err, check.IsNil)
+	c.Check(result, check.IsNil)
+	for _, selector := range []*types.PreheatPeerSelector{
+		{},
+		{PeerIds: []string{"x"}},
+		{PeerIds: []string{"d"}},
+		{PeerIds: []string{"superPID"}},
+		{Labels: map[string]string{"zone": "north"}},
+	} {
+		_, err := s.service.selectPeers(context.Background(), selector)
+		dfErr, ok := err.(*errortypes.DfError)
+		c.Assert(ok, check.Equals, true, check.Commentf("selector: %+v", selector))
+		c.Check(dfErr.Code, check.Equals, http.StatusBadRequest)
+	}
+
+	// the peers can't be preheated without the auth token to sign the requests
+	s.cfg.Auth.Token = ""
+	_, err = s.service.selectPeers(context.Background(), &types.PreheatPeerSelector{PeerIds: []string{"b"}})
+	c.Check(err, check.NotNil)
+}
+
+func (s *PreheatServiceTestSuite) TestMergePeers(c *check.C) {
+	status := func(statuses ...types.PreheatStatus) []*types.PreheatPeerStatus {
+		peers := make([]*types.PreheatPeerStatus, 0)
+		for i, st := range statuses {
+			peers = append(peers, &types.PreheatPeerStatus{PeerID: fmt.Sprintf("p%d", i), Status: st})
+		}
+		return peers
+	}
+	W, R, S, F := types.PreheatStatusWAITING, types.PreheatStatusRUNNING, types.PreheatStatusSUCCESS, types.PreheatStatusFAILED
+
+	merged := mergePeers(status(W, W, W, W), [][]*types.PreheatPeerStatus{
+		status(W, S, S, F),
+		status(W, R, S, S),
+	})
+	c.Assert(merged, check.HasLen, 4)
+	c.Check(merged[0].Status, check.Equals, W)
+	c.Check(merged[1].Status, check.Equals, R)
+	c.Check(merged[2].Status, check.Equals, S)
+	c.Check(merged[3].Status, check.Equals, F)
+}
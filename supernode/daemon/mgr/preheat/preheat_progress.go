@@ -13,26 +13,66 @@
  * See the License for the specific language governing permissions and
  * limitations under the License.
  */
+
 package preheat
 
 import (
-	"bytes"
-	"os/exec"
+	"context"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/algorithm"
 )
 
+// PreheatProgress is the progress of the task registered by a preheat task,
+// which is tracked by the pieces downloaded into the supernode CDN.
 type PreheatProgress struct {
-	output string
-	cmd *exec.Cmd
-	errmsg *bytes.Buffer
+	TaskID             string
+	CdnStatus          string
+	PieceTotal         int32
+	FinishedPieceCount int32
+}
+
+// finished returns whether the CDN of the task is finished, and
+// returns the error when the CDN failed.
+func (p *PreheatProgress) finished() (bool, error) {
+	switch p.CdnStatus {
+	case types.TaskInfoCdnStatusSUCCESS:
+		return true, nil
+	case types.TaskInfoCdnStatusFAILED, types.TaskInfoCdnStatusSOURCEERROR:
+		return true, newPreheatError(types.PreheatErrorCodeCDNFAILED, "",
+			"the cdn status of task %s is %s", p.TaskID, p.CdnStatus)
+	}
+	return false, nil
 }
 
-func NewPreheatProgress(output string, cmd *exec.Cmd) *PreheatProgress {
-	p := &PreheatProgress{
-		output: output,
-		cmd: cmd,
-		errmsg: bytes.NewBuffer(make([]byte, 0, 128)),
+// getProgress returns the progress of the task with taskID.
+func (svc *PreheatService) getProgress(ctx context.Context, taskID string) (*PreheatProgress, error) {
+	task, err := svc.taskMgr.Get(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &PreheatProgress{
+		TaskID:    taskID,
+		CdnStatus: task.CdnStatus,
+	}
+	if task.PieceTotal > 0 {
+		progress.PieceTotal = task.PieceTotal
+	}
+	if task.CdnStatus == types.TaskInfoCdnStatusSUCCESS {
+		progress.FinishedPieceCount = progress.PieceTotal
+		return progress, nil
+	}
+
+	superPID := svc.cfg.GetSuperPID()
+	for pieceNum := 0; pieceNum < int(progress.PieceTotal); pieceNum++ {
+		peerIDs, err := svc.progressMgr.GetPeerIDsByPieceNum(ctx, taskID, pieceNum)
+		if err != nil {
+			continue
+		}
+		if algorithm.ContainsString(peerIDs, superPID) {
+			progress.FinishedPieceCount++
+		}
 	}
-	cmd.Stderr = p.errmsg
-	cmd.Stdout = p.errmsg
-	return p
-}
\ No newline at end of file
+	return progress, nil
+}
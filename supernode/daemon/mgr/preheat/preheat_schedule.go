@@ -0,0 +1,297 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package preheat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/digest"
+	dferr "github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/timeutils"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+
+	"github.com/sirupsen/logrus"
+)
+
+// scheduleInterval is the interval to check whether the preheat schedules are due.
+var scheduleInterval = 10 * time.Second
+
+const (
+	// scheduleRunsLimit is the count of the latest runs kept by a preheat schedule.
+	scheduleRunsLimit = 10
+
+	// resolveTimeout is the timeout to resolve the digest of an image.
+	resolveTimeout = time.Minute
+)
+
+// CreateSchedule creates a preheat schedule, the preheat definition of which is
+// preheated periodically according to the cron expression once it's enabled.
+func (svc *PreheatService) CreateSchedule(ctx context.Context, req *types.PreheatScheduleCreateRequest) (string, error) {
+	if _, err := timeutils.ParseCron(*req.Cron); err != nil {
+		return "", dferr.Newf(http.StatusBadRequest, "invalid cron %q: %v", *req.Cron, err)
+	}
+	if GetPreheater(strings.ToLower(*req.Preheat.Type)) == nil {
+		return "", dferr.New(http.StatusBadRequest, *req.Preheat.Type+" isn't supported")
+	}
+	if _, err := parsePlatforms(req.Preheat.Platforms); err != nil {
+		return "", dferr.New(http.StatusBadRequest, err.Error())
+	}
+	if len(req.Preheat.Headers) > 0 && svc.cfg.PreheatSecret == "" {
+		return "", dferr.New(http.StatusBadRequest, "the headers of a preheat schedule are refused without preheatSecret of the supernode")
+	}
+
+	now := time.Now()
+	schedule := &mgr.PreheatSchedule{
+		ID:         digest.Sha256(fmt.Sprintf("%s%s%s%d", req.Name, *req.Cron, *req.Preheat.URL, now.UnixNano())),
+		Name:       req.Name,
+		Cron:       *req.Cron,
+		Request:    req.Preheat,
+		Enabled:    !req.Disabled,
+		CreateTime: millis(now),
+	}
+	if schedule.Enabled {
+		schedule.NextRunTime = nextRunTime(schedule.Cron, now)
+	}
+	if err := svc.repository.AddSchedule(schedule); err != nil {
+		return "", err
+	}
+	logrus.Infof("create preheat schedule %s: Cron[%s] Type[%s] URL[%s]",
+		schedule.ID, schedule.Cron, *req.Preheat.Type, *req.Preheat.URL)
+	return schedule.ID, nil
+}
+
+// GetSchedule returns the preheat schedule with the up-to-date status of its runs.
+func (svc *PreheatService) GetSchedule(id string) *mgr.PreheatSchedule {
+	schedule := svc.repository.GetSchedule(id)
+	if schedule == nil {
+		return nil
+	}
+	return svc.refreshRuns(schedule)
+}
+
+// GetAllSchedules returns all the preheat schedules.
+func (svc *PreheatService) GetAllSchedules() []*mgr.PreheatSchedule {
+	schedules := svc.repository.GetAllSchedules()
+	for i, schedule := range schedules {
+		schedules[i] = svc.refreshRuns(schedule)
+	}
+	return schedules
+}
+
+// DeleteSchedule deletes a preheat schedule, the preheat tasks created by it
+// are kept until they're expired.
+func (svc *PreheatService) DeleteSchedule(id string) error {
+	existed, err := svc.repository.DeleteSchedule(id)
+	if err != nil {
+		return err
+	}
+	if !existed {
+		return dferr.New(http.StatusNotFound, "preheat schedule "+id+" doesn't exists")
+	}
+	return nil
+}
+
+// EnableSchedule enables or disables a preheat schedule. The next run of an
+// enabled schedule is calculated from now, so the runs missed when it's
+// disabled are skipped.
+func (svc *PreheatService) EnableSchedule(id string, enable bool) (*mgr.PreheatSchedule, error) {
+	now := time.Now()
+	schedule, err := svc.repository.UpdateSchedule(id, func(s *mgr.PreheatSchedule) {
+		if s.Enabled == enable {
+			return
+		}
+		s.Enabled = enable
+		s.NextRunTime = 0
+		if enable {
+			s.NextRunTime = nextRunTime(s.Cron, now)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		return nil, dferr.New(http.StatusNotFound, "preheat schedule "+id+" doesn't exists")
+	}
+	return svc.refreshRuns(schedule), nil
+}
+
+// StartSchedule starts to run the due preheat schedules with a new goroutine.
+func (svc *PreheatService) StartSchedule(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(scheduleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				svc.runSchedules(ctx, time.Now())
+			}
+		}
+	}()
+}
+
+// runSchedules runs the enabled preheat schedules owned by this supernode which
+// are due at now. The schedule missed when the supernode is down runs once it's
+// checked.
+func (svc *PreheatService) runSchedules(ctx context.Context, now time.Time) {
+	for _, schedule := range svc.repository.GetAllSchedules() {
+		if !svc.ownsSchedule(ctx, schedule.ID) {
+			continue
+		}
+		schedule = svc.refreshRuns(schedule)
+		if !schedule.Enabled || schedule.NextRunTime <= 0 || schedule.NextRunTime > millis(now) {
+			continue
+		}
+		svc.runSchedule(ctx, schedule, now)
+	}
+}
+
+// runSchedule creates a preheat task of the schedule and records the run.
+func (svc *PreheatService) runSchedule(ctx context.Context, schedule *mgr.PreheatSchedule, now time.Time) {
+	run := &mgr.PreheatScheduleRun{
+		StartTime: millis(now),
+		Status:    types.PreheatStatusWAITING,
+	}
+	var err error
+	if run.PreheatID, run.URL, err = svc.preheatSchedule(ctx, schedule); err != nil {
+		logrus.Errorf("failed to run preheat schedule %s: %v", schedule.ID, err)
+		run.Status = types.PreheatStatusFAILED
+		run.ErrorMsg = err.Error()
+		run.FinishTime = millis(time.Now())
+	} else {
+		logrus.Infof("run preheat schedule %s: preheat %s by %s", schedule.ID, run.URL, run.PreheatID)
+	}
+
+	_, err = svc.repository.UpdateScheduleRuns(schedule.ID, func(s *mgr.PreheatSchedule) bool {
+		s.Runs = append(s.Runs, run)
+		if len(s.Runs) > scheduleRunsLimit {
+			s.Runs = s.Runs[len(s.Runs)-scheduleRunsLimit:]
+		}
+		if s.Enabled {
+			s.NextRunTime = nextRunTime(s.Cron, now)
+		}
+		return true
+	})
+	if err != nil {
+		logrus.Errorf("failed to save preheat schedule %s: %v", schedule.ID, err)
+	}
+}
+
+// preheatSchedule creates a preheat task of the schedule and returns its id
+// and the url preheated. The tag of an image is resolved to the digest every
+// run, and the blobs of it which have been cached are skipped. The finished
+// preheat task of the same url is preheated again.
+func (svc *PreheatService) preheatSchedule(ctx context.Context, schedule *mgr.PreheatSchedule) (id, url string, err error) {
+	task, err := svc.newTask(ctx, schedule.Request)
+	if err != nil {
+		return "", *schedule.Request.URL, err
+	}
+	if strings.ToLower(task.Type) == "image" {
+		resolveCtx, cancel := context.WithTimeout(ctx, resolveTimeout)
+		url, err := resolveImageURL(resolveCtx, task)
+		cancel()
+		if err != nil {
+			return "", task.URL, newPreheatError(types.PreheatErrorCodeMANIFESTFAILED, task.URL, "%v", err)
+		}
+		task.URL = url
+	}
+	id, err = svc.create(task, true)
+	return id, task.URL, err
+}
+
+// refreshRuns updates the status of the unfinished runs of the schedule on
+// this supernode by their preheat tasks, and returns the updated schedule.
+// The runs on the other supernodes are refreshed by themselves since the
+// preheat tasks are only known by the supernodes which create them.
+func (svc *PreheatService) refreshRuns(schedule *mgr.PreheatSchedule) *mgr.PreheatSchedule {
+	if !hasUnfinishedRun(schedule) {
+		return schedule
+	}
+
+	updated, err := svc.repository.UpdateScheduleRuns(schedule.ID, func(s *mgr.PreheatSchedule) bool {
+		changed := false
+		for _, run := range s.Runs {
+			if run.FinishTime > 0 {
+				continue
+			}
+			task := svc.Get(run.PreheatID)
+			if task == nil {
+				task = &mgr.PreheatTask{
+					Status:     types.PreheatStatusFAILED,
+					ErrorMsg:   "the preheat task doesn't exist",
+					FinishTime: millis(time.Now()),
+				}
+			}
+			changed = changed || task.Status != run.Status || task.FinishTime > 0
+			run.Status = task.Status
+			run.ErrorMsg = task.ErrorMsg
+			run.FinishTime = task.FinishTime
+		}
+		return changed
+	})
+	if err != nil {
+		logrus.Errorf("failed to save preheat schedule %s: %v", schedule.ID, err)
+	}
+	if updated == nil {
+		return schedule
+	}
+	return updated
+}
+
+// ownsSchedule returns whether the schedule is run by this supernode. The
+// schedules shared by the etcd backend are run by their owners in the cluster
+// mode, otherwise every supernode runs the schedules it knows.
+func (svc *PreheatService) ownsSchedule(ctx context.Context, id string) bool {
+	if svc.cfg.StateBackend != config.StateBackendEtcd || svc.clusterMgr == nil {
+		return true
+	}
+	return svc.clusterMgr.IsOwner(ctx, id)
+}
+
+func hasUnfinishedRun(schedule *mgr.PreheatSchedule) bool {
+	for _, run := range schedule.Runs {
+		if run.FinishTime == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// nextRunTime returns the next time in millis after now matching the cron
+// expression, or 0 if there is no such time.
+func nextRunTime(cron string, now time.Time) int64 {
+	schedule, err := timeutils.ParseCron(cron)
+	if err != nil {
+		return 0
+	}
+	next := schedule.Next(now)
+	if next.IsZero() {
+		return 0
+	}
+	return millis(next)
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,319 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package preheat
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
+
+	"github.com/go-check/check"
+)
+
+// fakeTagRegistry serves the manifests by the digests, and the tag latest
+// is resolved to the digest set by push.
+type fakeTagRegistry struct {
+	sync.Mutex
+	*httptest.Server
+	latest    string
+	manifests map[string]*manifest
+}
+
+func newFakeTagRegistry(manifests map[string]*manifest) *fakeTagRegistry {
+	r := &fakeTagRegistry{manifests: manifests}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.Lock()
+		defer r.Unlock()
+		reference := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+		if reference == "latest" {
+			reference = r.latest
+		}
+		m, ok := r.manifests[reference]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", reference)
+		json.NewEncoder(w).Encode(m)
+	}))
+	return r
+}
+
+func (r *fakeTagRegistry) push(digest string) {
+	r.Lock()
+	defer r.Unlock()
+	r.latest = digest
+}
+
+func (s *PreheatServiceTestSuite) TestScheduleRuns(c *check.C) {
+	registry := newFakeTagRegistry(map[string]*manifest{
+		"sha256:d1": {
+			SchemaVersion: 2,
+			Config:        &descriptor{Digest: "sha256:c1"},
+			Layers:        []*descriptor{{Digest: "sha256:l1"}},
+		},
+		"sha256:d2": {
+			SchemaVersion: 2,
+			Config:        &descriptor{Digest: "sha256:c2"},
+			Layers:        []*descriptor{{Digest: "sha256:l1"}, {Digest: "sha256:l2"}},
+		},
+	})
+	defer registry.Close()
+	registry.push("sha256:d1")
+	cdn := newFakeCDN(s.mockTaskMgr)
+
+	now := time.Now()
+	ctx := context.Background()
+	id, err := s.service.CreateSchedule(ctx, newScheduleRequest("@every 1h", "image", registry.URL+"/v2/app/manifests/latest"))
+	c.Assert(err, check.IsNil)
+	schedule := s.service.GetSchedule(id)
+	c.Assert(schedule, check.NotNil)
+	c.Check(schedule.Enabled, check.Equals, true)
+	c.Check(schedule.NextRunTime >= millis(now.Add(time.Hour)), check.Equals, true)
+
+	// the schedule isn't due
+	s.service.runSchedules(ctx, now)
+	c.Check(s.service.GetSchedule(id).Runs, check.HasLen, 0)
+
+	// the tag is resolved to the digest
+	s.service.runSchedules(ctx, now.Add(2*time.Hour))
+	run := s.waitRun(c, id, 1)
+	c.Check(run.Status, check.Equals, types.PreheatStatusSUCCESS)
+	c.Check(run.URL, check.Equals, registry.URL+"/v2/app/manifests/sha256:d1")
+	c.Check(cdn.registeredCount(), check.Equals, 2)
+
+	// the tag is pushed again, and the cached layer is skipped
+	registry.push("sha256:d2")
+	s.service.runSchedules(ctx, now.Add(4*time.Hour))
+	run = s.waitRun(c, id, 2)
+	c.Check(run.Status, check.Equals, types.PreheatStatusSUCCESS)
+	c.Check(run.URL, check.Equals, registry.URL+"/v2/app/manifests/sha256:d2")
+	c.Check(cdn.registeredCount(), check.Equals, 4)
+	task := s.service.Get(run.PreheatID)
+	c.Assert(task.Layers, check.HasLen, 3)
+	c.Check(task.Layers[1].Digest, check.Equals, "sha256:l1")
+	c.Check(task.Layers[1].Cached, check.Equals, true)
+
+	// the finished preheat task of the same digest is preheated again
+	s.service.runSchedules(ctx, now.Add(6*time.Hour))
+	rerun := s.waitRun(c, id, 3)
+	c.Check(rerun.Status, check.Equals, types.PreheatStatusSUCCESS)
+	c.Check(rerun.PreheatID, check.Equals, run.PreheatID)
+	c.Check(rerun.StartTime > run.StartTime, check.Equals, true)
+	c.Check(cdn.registeredCount(), check.Equals, 4)
+
+	// the disabled schedule doesn't run
+	schedule, err = s.service.EnableSchedule(id, false)
+	c.Assert(err, check.IsNil)
+	c.Check(schedule.Enabled, check.Equals, false)
+	c.Check(schedule.NextRunTime, check.Equals, int64(0))
+	s.service.runSchedules(ctx, now.Add(100*time.Hour))
+	c.Check(s.service.GetSchedule(id).Runs, check.HasLen, 3)
+
+	// the schedules are persisted
+	repository, err := NewPreheatTaskRepository(nil, s.service.repository.node, "",
+		filepath.Join(s.cfg.HomeDir, "preheat", schedulesFile))
+	c.Assert(err, check.IsNil)
+	persisted := repository.GetSchedule(id)
+	c.Assert(persisted, check.NotNil)
+	c.Check(persisted.Enabled, check.Equals, false)
+	c.Check(persisted.Runs, check.DeepEquals, s.service.GetSchedule(id).Runs)
+	c.Check(*persisted.Request.URL, check.Equals, registry.URL+"/v2/app/manifests/latest")
+}
+
+func (s *PreheatServiceTestSuite) TestScheduleHeadersSealed(c *check.C) {
+	s.cfg.PreheatSecret = "secret"
+	service, err := NewPreheatService(s.cfg, s.mockTaskMgr, s.mockCDNMgr, s.mockProgressMgr, s.mockPeerMgr, nil, nil)
+	c.Assert(err, check.IsNil)
+
+	req := newScheduleRequest("@daily", "file", "http://aa.bb.com/foo")
+	req.Preheat.Headers = map[string]string{"Authorization": "Bearer credential"}
+	id, err := service.CreateSchedule(context.Background(), req)
+	c.Assert(err, check.IsNil)
+	c.Check(service.GetSchedule(id).Request.Headers, check.DeepEquals, req.Preheat.Headers)
+
+	// the headers are sealed in the schedules file only readable by the owner
+	path := filepath.Join(s.cfg.HomeDir, "preheat", schedulesFile)
+	info, err := os.Stat(path)
+	c.Assert(err, check.IsNil)
+	c.Check(info.Mode().Perm(), check.Equals, os.FileMode(0600))
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, check.IsNil)
+	c.Check(strings.Contains(string(data), "credential"), check.Equals, false)
+
+	repository, err := NewPreheatTaskRepository(nil, "", "secret", path)
+	c.Assert(err, check.IsNil)
+	c.Check(repository.GetSchedule(id).Request.Headers, check.DeepEquals, req.Preheat.Headers)
+	repository, err = NewPreheatTaskRepository(nil, "", "another", path)
+	c.Assert(err, check.IsNil)
+	c.Check(repository.GetSchedule(id).Request.Headers, check.HasLen, 0)
+}
+
+func (s *PreheatServiceTestSuite) TestSharedSchedules(c *check.C) {
+	registry := newFakeTagRegistry(map[string]*manifest{})
+	defer registry.Close()
+
+	// the supernodes share the schedules by the backend, and each schedule is
+	// only run by its owner
+	backend := state.NewMemoryBackend()
+	owners := &fakeOwners{owner: "127.0.0.1:8002"}
+	newService := func(port int) *PreheatService {
+		cfg := config.NewConfig()
+		cfg.HomeDir = c.MkDir()
+		cfg.AdvertiseIP = "127.0.0.1"
+		cfg.ListenPort = port
+		cfg.StateBackend = config.StateBackendEtcd
+		svc, err := NewPreheatService(cfg, s.mockTaskMgr, s.mockCDNMgr, s.mockProgressMgr, s.mockPeerMgr,
+			&fakeClusterMgr{node: "127.0.0.1:" + strconv.Itoa(port), owners: owners}, backend)
+		c.Assert(err, check.IsNil)
+		return svc
+	}
+	svc1, svc2 := newService(8001), newService(8002)
+
+	now := time.Now()
+	ctx := context.Background()
+	id, err := svc1.CreateSchedule(ctx, newScheduleRequest("@every 1h", "image", registry.URL+"/v2/app/manifests/latest"))
+	c.Assert(err, check.IsNil)
+	c.Assert(svc2.GetSchedule(id), check.NotNil)
+
+	svc1.runSchedules(ctx, now.Add(2*time.Hour))
+	c.Check(svc2.GetSchedule(id).Runs, check.HasLen, 0)
+	svc2.runSchedules(ctx, now.Add(2*time.Hour))
+	c.Assert(svc1.GetSchedule(id).Runs, check.HasLen, 1)
+	c.Check(svc1.GetSchedule(id).Runs[0].Status, check.Equals, types.PreheatStatusFAILED)
+
+	// the due schedule is run by the new owner
+	owners.set("127.0.0.1:8001")
+	svc1.runSchedules(ctx, now.Add(4*time.Hour))
+	c.Assert(svc2.GetSchedule(id).Runs, check.HasLen, 2)
+	c.Check(svc2.GetSchedule(id).Runs[1].StartTime, check.Equals, millis(now.Add(4*time.Hour)))
+
+	_, err = svc1.EnableSchedule(id, false)
+	c.Assert(err, check.IsNil)
+	c.Check(svc2.GetSchedule(id).Enabled, check.Equals, false)
+	c.Assert(svc2.DeleteSchedule(id), check.IsNil)
+	c.Check(svc1.GetAllSchedules(), check.HasLen, 0)
+	values, err := backend.List(state.BucketPreheatScheduleRun)
+	c.Assert(err, check.IsNil)
+	c.Check(values, check.HasLen, 0)
+	// the schedules aren't persisted into the file with the etcd backend
+	_, err = os.Stat(filepath.Join(svc1.cfg.HomeDir, "preheat", schedulesFile))
+	c.Check(os.IsNotExist(err), check.Equals, true)
+}
+
+func (s *PreheatServiceTestSuite) TestScheduleRunsLimit(c *check.C) {
+	registry := newFakeTagRegistry(map[string]*manifest{})
+	defer registry.Close()
+
+	now := time.Now()
+	ctx := context.Background()
+	id, err := s.service.CreateSchedule(ctx, newScheduleRequest("*/5 * * * *", "image", registry.URL+"/v2/app/manifests/latest"))
+	c.Assert(err, check.IsNil)
+	for i := 1; i <= scheduleRunsLimit+2; i++ {
+		s.service.runSchedules(ctx, now.Add(time.Duration(i)*time.Hour))
+	}
+
+	schedule := s.service.GetSchedule(id)
+	c.Assert(schedule.Runs, check.HasLen, scheduleRunsLimit)
+	c.Check(schedule.Runs[0].StartTime, check.Equals, millis(now.Add(3*time.Hour)))
+	for _, run := range schedule.Runs {
+		c.Check(run.Status, check.Equals, types.PreheatStatusFAILED)
+		c.Check(run.FinishTime > 0, check.Equals, true)
+		c.Check(run.ErrorMsg, check.Not(check.Equals), "")
+	}
+}
+
+func (s *PreheatServiceTestSuite) TestCreateScheduleInvalid(c *check.C) {
+	withHeaders := newScheduleRequest("@daily", "file", "http://aa.bb.com/foo")
+	withHeaders.Preheat.Headers = map[string]string{"Authorization": "Bearer credential"}
+	var cases = []*types.PreheatScheduleCreateRequest{
+		newScheduleRequest("* * *", "file", "http://aa.bb.com/foo"),
+		newScheduleRequest("61 * * * *", "file", "http://aa.bb.com/foo"),
+		newScheduleRequest("@daily", "foo", "http://aa.bb.com/foo"),
+		// the headers are refused without the secret to seal them
+		withHeaders,
+	}
+	for _, req := range cases {
+		_, err := s.service.CreateSchedule(context.Background(), req)
+		e, ok := err.(*errortypes.DfError)
+		c.Assert(ok, check.Equals, true)
+		c.Check(e.Code, check.Equals, http.StatusBadRequest)
+	}
+	c.Check(s.service.GetAllSchedules(), check.HasLen, 0)
+
+	_, err := s.service.EnableSchedule("foo", true)
+	c.Check(err, check.NotNil)
+	c.Check(s.service.DeleteSchedule("foo"), check.NotNil)
+}
+
+func newScheduleRequest(cron, typ, url string) *types.PreheatScheduleCreateRequest {
+	return &types.PreheatScheduleCreateRequest{
+		Cron:    &cron,
+		Preheat: &types.PreheatCreateRequest{Type: &typ, URL: &url},
+	}
+}
+
+// fakeOwners decides the owner of all the schedules.
+type fakeOwners struct {
+	sync.Mutex
+	owner string
+}
+
+func (o *fakeOwners) set(owner string) {
+	o.Lock()
+	defer o.Unlock()
+	o.owner = owner
+}
+
+type fakeClusterMgr struct {
+	mgr.ClusterMgr
+	node   string
+	owners *fakeOwners
+}
+
+func (cm *fakeClusterMgr) IsOwner(ctx context.Context, taskID string) bool {
+	cm.owners.Lock()
+	defer cm.owners.Unlock()
+	return cm.owners.owner == cm.node
+}
+
+// waitRun waits the nth run of the schedule finished, and returns it.
+func (s *PreheatServiceTestSuite) waitRun(c *check.C, id string, n int) *mgr.PreheatScheduleRun {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if schedule := s.service.GetSchedule(id); len(schedule.Runs) >= n && schedule.Runs[n-1].FinishTime > 0 {
+			return schedule.Runs[n-1]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatalf("the run %d of preheat schedule %s isn't finished in time", n, id)
+	return nil
+}
@@ -17,41 +17,80 @@
 package preheat
 
 import (
+	"context"
 	"fmt"
-	"github.com/pborman/uuid"
-	"github.com/sirupsen/logrus"
+	"net"
 	"net/http"
-	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/dfget/core/api"
 	"github.com/dragonflyoss/Dragonfly/pkg/digest"
 	dferr "github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/httputils"
 	"github.com/dragonflyoss/Dragonfly/pkg/netutils"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/task"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
+
+	"github.com/sirupsen/logrus"
 )
 
 const (
 	key = ">I$pg-~AS~sP'rqu_`Oh&lz#9]\"=;nE%"
-	dfgetPath = "/usr/local/bin/dfget"
+
+	// preheatCallSystem is the call system of the tasks registered by preheat.
+	preheatCallSystem = "dragonfly_preheat"
+
+	// schedulesFile is the file under ${HomeDir}/preheat which persists
+	// the preheat schedules if they're kept in memory.
+	schedulesFile = "schedules.json"
 )
 
 type PreheatService struct {
-	PreheatPath string
-	repository *PreheatTaskRepository
+	cfg         *config.Config
+	taskMgr     mgr.TaskMgr
+	cdnMgr      mgr.CDNMgr
+	progressMgr mgr.ProgressMgr
+	peerMgr     mgr.PeerMgr
+	clusterMgr  mgr.ClusterMgr
+	uploaderAPI api.UploaderAPI
+	repository  *PreheatTaskRepository
 }
 
-func NewPreheatService(homeDir string) *PreheatService {
-	return &PreheatService{
-		repository: NewPreheatTaskRepository(),
-		PreheatPath: filepath.Join(homeDir, "repo", "preheat"),
+// NewPreheatService returns a new PreheatService. The preheat schedules are
+// kept in the backend shared by the supernodes, and they're persisted into
+// the schedules file too unless the backend is etcd, which survives
+// the restarts by itself.
+func NewPreheatService(cfg *config.Config, taskMgr mgr.TaskMgr, cdnMgr mgr.CDNMgr, progressMgr mgr.ProgressMgr,
+	peerMgr mgr.PeerMgr, clusterMgr mgr.ClusterMgr, backend state.Backend) (*PreheatService, error) {
+	schedulesPath := filepath.Join(cfg.HomeDir, "preheat", schedulesFile)
+	if cfg.StateBackend == config.StateBackendEtcd {
+		schedulesPath = ""
+	}
+	node := net.JoinHostPort(cfg.AdvertiseIP, strconv.Itoa(cfg.ListenPort))
+	repository, err := NewPreheatTaskRepository(backend, node, cfg.PreheatSecret, schedulesPath)
+	if err != nil {
+		return nil, err
 	}
+	return &PreheatService{
+		cfg:         cfg,
+		taskMgr:     taskMgr,
+		cdnMgr:      cdnMgr,
+		progressMgr: progressMgr,
+		peerMgr:     peerMgr,
+		clusterMgr:  clusterMgr,
+		uploaderAPI: api.NewUploaderAPI(httputils.DefaultTimeout),
+		repository:  repository,
+	}, nil
 }
 
 // Get detailed preheat task information
-func (svc *PreheatService) 	Get(id string) *mgr.PreheatTask {
+func (svc *PreheatService) Get(id string) *mgr.PreheatTask {
 	if id == "" {
 		return nil
 	}
@@ -59,57 +98,135 @@ func (svc *PreheatService) 	Get(id string) *mgr.PreheatTask {
 }
 
 // Get all preheat tasks
-func (svc *PreheatService) 	GetAll() []*mgr.PreheatTask {
+func (svc *PreheatService) GetAll() []*mgr.PreheatTask {
 	return svc.repository.GetAll()
 }
 
-// Delete a preheat task.
-func (svc *PreheatService) 	Delete(id string) {
+// Delete a preheat task, and the running workers of it and its children are canceled.
+func (svc *PreheatService) Delete(id string) {
 	task := svc.repository.Get(id)
 	if task != nil && len(task.Children) > 0 {
 		for _, childId := range task.Children {
+			svc.cancel(svc.repository.Get(childId))
 			svc.repository.Delete(childId)
 		}
 	}
+	svc.cancel(task)
 	svc.repository.Delete(id)
 }
 
 // update a preheat task
-func (svc *PreheatService) 	Update(id string, task *mgr.PreheatTask) bool {
+func (svc *PreheatService) Update(id string, task *mgr.PreheatTask) bool {
 	return svc.repository.Update(id, task)
 }
 
+// newTask returns a preheat task of the request, the target peers of which
+// are selected by the peer selector of the request.
+func (svc *PreheatService) newTask(ctx context.Context, req *types.PreheatCreateRequest) (*mgr.PreheatTask, error) {
+	preheatTask := new(mgr.PreheatTask)
+	preheatTask.Type = *req.Type
+	preheatTask.URL = *req.URL
+	preheatTask.Filter = req.Filter
+	preheatTask.Identifier = req.Identifier
+	preheatTask.Headers = req.Headers
+	preheatTask.Platforms = req.Platforms
+	preheatTask.Referrers = req.Referrers
+	if _, err := parsePlatforms(req.Platforms); err != nil {
+		return nil, dferr.New(http.StatusBadRequest, err.Error())
+	}
+	var err error
+	if preheatTask.Peers, err = svc.selectPeers(ctx, req.Peers); err != nil {
+		return nil, err
+	}
+	return preheatTask, nil
+}
+
 // create a preheat task
-func (svc *PreheatService) 	Create(task *mgr.PreheatTask) (string, error) {
+func (svc *PreheatService) Create(task *mgr.PreheatTask) (string, error) {
+	return svc.create(task, false)
+}
+
+// create creates a preheat task. If the preheat task with the same ID is
+// running, the ID of it is returned. If it has finished, the preheat task is
+// preheated again when replaceFinished is true, otherwise an error with
+// http.StatusAlreadyReported is returned.
+func (svc *PreheatService) create(task *mgr.PreheatTask, replaceFinished bool) (string, error) {
 	preheater := GetPreheater(strings.ToLower(task.Type))
 	if preheater == nil {
-		return "", dferr.New(400, task.Type + " isn't supported")
+		return "", dferr.New(400, task.Type+" isn't supported")
 	}
-	task.ID = svc.createTaskID(task.URL, task.Filter, task.Identifier, task.Headers)
+	task.ID = svc.createTaskID(task.URL, task.Filter, task.Identifier+peersSign(task.Peers)+platformsSign(task.Platforms, task.Referrers), task.Headers)
 	task.StartTime = time.Now().UnixNano() / int64(time.Millisecond)
 	task.Status = types.PreheatStatusWAITING
-	previous, _ := svc.repository.Add(task)
-	if previous != nil && previous.FinishTime > 0 {
-		return "", dferr.New(http.StatusAlreadyReported, "preheat task already exists, id:" + task.ID)
+	var previous *mgr.PreheatTask
+	if replaceFinished {
+		previous, _ = svc.repository.AddOrReplace(task)
+	} else {
+		previous, _ = svc.repository.Add(task)
+	}
+	if previous != nil {
+		if previous.FinishTime > 0 {
+			return "", dferr.New(http.StatusAlreadyReported, "preheat task already exists, id:"+task.ID)
+		}
+		// the preheat task is running
+		return previous.ID, nil
 	}
 	preheater.NewWorker(task, svc).Run()
 	return task.ID, nil
 }
 
-// execute preheat task
-func (svc *PreheatService) 	ExecutePreheat(task *mgr.PreheatTask) (progress *PreheatProgress, err error) {
-	targetName := uuid.New()
-	targetPath := filepath.Join(svc.PreheatPath, targetName)
-	cmd := svc.createCommand(task.URL, task.Headers, task.Filter, task.Identifier, targetPath)
-	logrus.Infof("ExecutePreheat CMD: %s", cmd.String())
-	err = cmd.Start()
-	go cmd.Wait()
+// register registers the file of the preheat task to the supernode as the
+// supernode itself, which triggers the CDN to download the file, and returns
+// the taskID of the file.
+func (svc *PreheatService) register(ctx context.Context, preheatTask *mgr.PreheatTask) (string, error) {
+	req, taskID := svc.taskCreateRequest(preheatTask)
+	req.CID = svc.cfg.GetSuperCID(taskID)
+	path, err := svc.cdnMgr.GetHTTPPath(ctx, &types.TaskInfo{ID: taskID})
+	if err != nil {
+		return "", err
+	}
+	req.Path = path
+
+	resp, err := svc.taskMgr.Register(ctx, req)
 	if err != nil {
-		err = dferr.New(500, err.Error())
+		return "", err
+	}
+	logrus.Infof("success to register taskID(%s) for preheat task %s", resp.ID, preheatTask.ID)
+	return resp.ID, nil
+}
+
+// taskCreateRequest returns the request to register the file of the preheat
+// task and the taskID of the file.
+func (svc *PreheatService) taskCreateRequest(preheatTask *mgr.PreheatTask) (*types.TaskCreateRequest, string) {
+	req := &types.TaskCreateRequest{
+		CallSystem: preheatCallSystem,
+		Headers:    preheatTask.Headers,
+		Identifier: preheatTask.Identifier,
+		PeerID:     svc.cfg.GetSuperPID(),
+		RawURL:     preheatTask.URL,
+	}
+	if preheatTask.Filter != "" {
+		req.Filter = strings.Split(preheatTask.Filter, "&")
+	}
+	return req, task.GenerateTaskID(req)
+}
+
+// cached returns whether the file of the preheat task has been cached in
+// the supernode CDN successfully.
+func (svc *PreheatService) cached(ctx context.Context, preheatTask *mgr.PreheatTask) bool {
+	_, taskID := svc.taskCreateRequest(preheatTask)
+	info, err := svc.taskMgr.Get(ctx, taskID)
+	return err == nil && info != nil && info.CdnStatus == types.TaskInfoCdnStatusSUCCESS
+}
+
+// cancel cancels the running worker of the preheat task.
+func (svc *PreheatService) cancel(task *mgr.PreheatTask) {
+	if task == nil {
 		return
 	}
-	progress = NewPreheatProgress(targetPath, cmd)
-	return
+	if preheater := GetPreheater(strings.ToLower(task.Type)); preheater != nil {
+		preheater.Remove(task.ID)
+	}
 }
 
 func (svc *PreheatService) createTaskID(url, filter, identifier string, header map[string]string) string {
@@ -123,22 +240,3 @@ func (svc *PreheatService) createTaskID(url, filter, identifier string, header m
 	}
 	return digest.Sha256(id)
 }
-
-func (svc *PreheatService) createCommand(url string, header map[string]string, filter, identifier, tmpTarget string) *exec.Cmd {
-	netRate := 50
-	rate := fmt.Sprintf("%dM", netRate / 2)
-
-	args := []string{"-u", url, "-o", tmpTarget, "--callsystem", "dragonfly_preheat", "--totallimit", rate, "-s", rate}
-	if (header != nil) {
-		for k, v := range header {
-			args = append(args, []string{"--header", fmt.Sprintf("%s:%s", k, v)}...)
-		}
-	}
-	if filter != "" {
-		args = append(args, []string{"-f", filter}...)
-	}
-	if identifier != "" {
-		args = append(args, []string{"-i", identifier}...)
-	}
-	return exec.Command(dfgetPath, args...)
-}
@@ -0,0 +1,226 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package preheat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/mock"
+
+	"github.com/go-check/check"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+)
+
+func Test(t *testing.T) {
+	check.TestingT(t)
+}
+
+func init() {
+	check.Suite(&PreheatServiceTestSuite{})
+}
+
+type PreheatServiceTestSuite struct {
+	mockCtl         *gomock.Controller
+	mockTaskMgr     *mock.MockTaskMgr
+	mockCDNMgr      *mock.MockCDNMgr
+	mockProgressMgr *mock.MockProgressMgr
+	mockPeerMgr     *mock.MockPeerMgr
+
+	cfg     *config.Config
+	service *PreheatService
+}
+
+func (s *PreheatServiceTestSuite) SetUpSuite(c *check.C) {
+	queryInterval = 10 * time.Millisecond
+}
+
+func (s *PreheatServiceTestSuite) SetUpTest(c *check.C) {
+	s.mockCtl = gomock.NewController(c)
+	s.mockTaskMgr = mock.NewMockTaskMgr(s.mockCtl)
+	s.mockCDNMgr = mock.NewMockCDNMgr(s.mockCtl)
+	s.mockProgressMgr = mock.NewMockProgressMgr(s.mockCtl)
+	s.mockPeerMgr = mock.NewMockPeerMgr(s.mockCtl)
+
+	s.cfg = config.NewConfig()
+	s.cfg.SetSuperPID("superPID")
+	s.cfg.HomeDir = c.MkDir()
+	s.cfg.Auth.Token = "token"
+	var err error
+	s.service, err = NewPreheatService(s.cfg, s.mockTaskMgr, s.mockCDNMgr, s.mockProgressMgr, s.mockPeerMgr, nil, nil)
+	c.Assert(err, check.IsNil)
+	s.mockCDNMgr.EXPECT().GetHTTPPath(gomock.Any(), gomock.Any()).Return("/download/foo", nil).AnyTimes()
+}
+
+func (s *PreheatServiceTestSuite) TearDownTest(c *check.C) {
+	s.mockCtl.Finish()
+}
+
+func (s *PreheatServiceTestSuite) TestPreheatFile(c *check.C) {
+	s.mockTaskMgr.EXPECT().Register(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *types.TaskCreateRequest) (*types.TaskCreateResponse, error) {
+			c.Check(req.PeerID, check.Equals, s.cfg.GetSuperPID())
+			c.Check(s.cfg.IsSuperCID(req.CID), check.Equals, true)
+			c.Check(req.CallSystem, check.Equals, preheatCallSystem)
+			c.Check(req.Filter, check.DeepEquals, []string{"a", "b"})
+			return &types.TaskCreateResponse{ID: "taskID"}, nil
+		})
+	gomock.InOrder(
+		s.mockTaskMgr.EXPECT().Get(gomock.Any(), "taskID").Return(&types.TaskInfo{
+			ID:         "taskID",
+			CdnStatus:  types.TaskInfoCdnStatusRUNNING,
+			PieceTotal: 2,
+		}, nil),
+		s.mockTaskMgr.EXPECT().Get(gomock.Any(), "taskID").Return(&types.TaskInfo{
+			ID:         "taskID",
+			CdnStatus:  types.TaskInfoCdnStatusSUCCESS,
+			PieceTotal: 2,
+		}, nil),
+	)
+	s.mockProgressMgr.EXPECT().GetPeerIDsByPieceNum(gomock.Any(), "taskID", 0).Return([]string{"superPID"}, nil)
+	s.mockProgressMgr.EXPECT().GetPeerIDsByPieceNum(gomock.Any(), "taskID", 1).Return([]string{"fooPID"}, nil)
+
+	id, err := s.service.Create(&mgr.PreheatTask{
+		Type:   "file",
+		URL:    "http://aa.bb.com/foo?a=1&b=2",
+		Filter: "a&b",
+	})
+	c.Assert(err, check.IsNil)
+
+	task := s.waitFinished(c, id)
+	c.Check(task.Status, check.Equals, types.PreheatStatusSUCCESS)
+	c.Check(task.Error, check.IsNil)
+	c.Check(task.PieceTotal, check.Equals, int32(2))
+	c.Check(task.FinishedPieceCount, check.Equals, int32(2))
+}
+
+func (s *PreheatServiceTestSuite) TestPreheatFileRegisterFailed(c *check.C) {
+	s.mockTaskMgr.EXPECT().Register(gomock.Any(), gomock.Any()).Return(nil,
+		errors.Wrapf(errortypes.ErrURLNotReachable, "url: %s", "http://aa.bb.com/foo"))
+
+	id, err := s.service.Create(&mgr.PreheatTask{Type: "file", URL: "http://aa.bb.com/foo"})
+	c.Assert(err, check.IsNil)
+
+	task := s.waitFinished(c, id)
+	c.Check(task.Status, check.Equals, types.PreheatStatusFAILED)
+	c.Assert(task.Error, check.NotNil)
+	c.Check(task.Error.Code, check.Equals, types.PreheatErrorCodeSOURCEUNREACHABLE)
+	c.Check(task.Error.URL, check.Equals, "http://aa.bb.com/foo")
+	c.Check(task.ErrorMsg, check.Equals, task.Error.Message)
+}
+
+func (s *PreheatServiceTestSuite) TestPreheatFileCDNFailed(c *check.C) {
+	s.mockTaskMgr.EXPECT().Register(gomock.Any(), gomock.Any()).Return(&types.TaskCreateResponse{ID: "taskID"}, nil)
+	s.mockTaskMgr.EXPECT().Get(gomock.Any(), "taskID").Return(&types.TaskInfo{
+		ID:         "taskID",
+		CdnStatus:  types.TaskInfoCdnStatusFAILED,
+		PieceTotal: -1,
+	}, nil)
+
+	id, err := s.service.Create(&mgr.PreheatTask{Type: "file", URL: "http://aa.bb.com/foo"})
+	c.Assert(err, check.IsNil)
+
+	task := s.waitFinished(c, id)
+	c.Check(task.Status, check.Equals, types.PreheatStatusFAILED)
+	c.Assert(task.Error, check.NotNil)
+	c.Check(task.Error.Code, check.Equals, types.PreheatErrorCodeCDNFAILED)
+}
+
+func (s *PreheatServiceTestSuite) TestDeleteRunningPreheat(c *check.C) {
+	querying := make(chan struct{})
+	s.mockTaskMgr.EXPECT().Register(gomock.Any(), gomock.Any()).Return(&types.TaskCreateResponse{ID: "taskID"}, nil)
+	s.mockTaskMgr.EXPECT().Get(gomock.Any(), "taskID").DoAndReturn(
+		func(ctx context.Context, taskID string) (*types.TaskInfo, error) {
+			select {
+			case <-querying:
+			default:
+				close(querying)
+			}
+			return &types.TaskInfo{ID: taskID, CdnStatus: types.TaskInfoCdnStatusRUNNING}, nil
+		}).AnyTimes()
+
+	id, err := s.service.Create(&mgr.PreheatTask{Type: "file", URL: "http://aa.bb.com/foo"})
+	c.Assert(err, check.IsNil)
+	v, ok := workerMap.Load(id)
+	c.Assert(ok, check.Equals, true)
+	worker := v.(*FileWorker)
+
+	// creating the running preheat task again returns the same one
+	id2, err := s.service.Create(&mgr.PreheatTask{Type: "file", URL: "http://aa.bb.com/foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(id2, check.Equals, id)
+
+	select {
+	case <-querying:
+	case <-time.After(5 * time.Second):
+		c.Fatal("preheat task isn't running in time")
+	}
+	s.service.Delete(id)
+	c.Check(s.service.Get(id), check.IsNil)
+
+	select {
+	case <-worker.ctx.Done():
+		c.Check(worker.ctx.Err(), check.Equals, context.Canceled)
+	case <-time.After(5 * time.Second):
+		c.Fatal("preheat task isn't canceled in time")
+	}
+}
+
+func (s *PreheatServiceTestSuite) TestToPreheatError(c *check.C) {
+	var cases = []struct {
+		err  error
+		code string
+		url  string
+	}{
+		{err: context.Canceled, code: types.PreheatErrorCodeCANCELED, url: "foo"},
+		{err: context.DeadlineExceeded, code: types.PreheatErrorCodeTIMEOUT, url: "foo"},
+		{err: errors.Wrap(errortypes.ErrAuthenticationRequired, "foo"), code: types.PreheatErrorCodeAUTHREQUIRED, url: "foo"},
+		{err: errortypes.ErrTenantQuotaExceeded, code: types.PreheatErrorCodeQUOTAEXCEEDED, url: "foo"},
+		{err: errors.New("foo"), code: types.PreheatErrorCodeINTERNAL, url: "foo"},
+		{
+			err:  newPreheatError(types.PreheatErrorCodeCDNFAILED, "layer", "cdn failed"),
+			code: types.PreheatErrorCodeCDNFAILED,
+			url:  "layer",
+		},
+	}
+
+	for _, v := range cases {
+		e := toPreheatError(v.err, "foo")
+		c.Check(e.Code, check.Equals, v.code)
+		c.Check(e.URL, check.Equals, v.url)
+		c.Check(e.Validate(nil), check.IsNil)
+	}
+}
+
+// waitFinished waits until the preheat task is finished.
+func (s *PreheatServiceTestSuite) waitFinished(c *check.C, id string) *mgr.PreheatTask {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if task := s.service.Get(id); task != nil && task.FinishTime > 0 {
+			return task
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatalf("preheat task %s isn't finished in time", id)
+	return nil
+}
@@ -13,101 +13,654 @@
  * See the License for the specific language governing permissions and
  * limitations under the License.
  */
+
 package preheat
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
-const(
+const (
 	// preheat image cache one week
-	EXPIRED_TIME = 7 * 24 * 3600 * 1000;
+	EXPIRED_TIME = 7 * 24 * 3600 * 1000
 )
 
+// PreheatTaskRepository stores the preheat tasks and the preheat schedules.
+// The tasks are updated by the workers concurrently, so the repository stores
+// and returns the copies of them.
+// The schedules are kept in the state backend so that they're shared by the
+// supernodes, while the runs of a schedule are kept by every supernode which
+// runs it. The headers of the schedules are sealed by the secret since they
+// may carry the credentials of the registries. The schedules are also persisted
+// into schedulesPath if it's set, which is used by the backend that doesn't
+// survive the restarts of the supernode.
 type PreheatTaskRepository struct {
-	preheatTasks *sync.Map
+	sync.RWMutex
+	preheatTasks map[string]*mgr.PreheatTask
+
+	schedulesLock sync.RWMutex
+	backend       state.Backend
+	aead          cipher.AEAD
+	schedulesPath string
+
+	// node identifies this supernode in the keys of the runs of the schedules.
+	node string
+}
+
+// storedSchedule is a schedule kept in the state backend, the headers of the
+// preheat request of which are replaced by the sealed ones.
+type storedSchedule struct {
+	ID            string
+	Name          string
+	Cron          string
+	Request       *types.PreheatCreateRequest
+	SealedHeaders []byte `json:",omitempty"`
+	Enabled       bool
+	CreateTime    int64
+	NextRunTime   int64
+}
+
+// scheduleRuns is the runs of a schedule on a supernode.
+type scheduleRuns struct {
+	NextRunTime int64
+	Runs        []*mgr.PreheatScheduleRun
 }
 
-func NewPreheatTaskRepository() *PreheatTaskRepository {
+// NewPreheatTaskRepository returns a new PreheatTaskRepository. The schedules
+// are kept in a memory backend if backend is nil, and the ones with headers are
+// refused if secret is empty.
+func NewPreheatTaskRepository(backend state.Backend, node, secret, schedulesPath string) (*PreheatTaskRepository, error) {
+	if backend == nil {
+		backend = state.NewMemoryBackend()
+	}
 	r := &PreheatTaskRepository{
-		preheatTasks: new(sync.Map),
+		preheatTasks:  make(map[string]*mgr.PreheatTask),
+		backend:       backend,
+		schedulesPath: schedulesPath,
+		node:          node,
 	}
-	return r
+	if !stringutils.IsEmptyStr(secret) {
+		key := sha256.Sum256([]byte(secret))
+		block, err := aes.NewCipher(key[:])
+		if err != nil {
+			return nil, err
+		}
+		if r.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.loadSchedules(); err != nil {
+		logrus.Errorf("failed to load preheat schedules from %s: %v", schedulesPath, err)
+	}
+	return r, nil
 }
 
-func(r *PreheatTaskRepository) Get(id string) *mgr.PreheatTask {
-	t, ok := r.preheatTasks.Load(id)
-	if ok {
-		return t.(*mgr.PreheatTask)
+func (r *PreheatTaskRepository) Get(id string) *mgr.PreheatTask {
+	r.RLock()
+	defer r.RUnlock()
+	if t, ok := r.preheatTasks[id]; ok {
+		return copyTask(t)
 	}
 	return nil
 }
 
-func(r *PreheatTaskRepository) GetAll() []*mgr.PreheatTask {
-	list := make([]*mgr.PreheatTask, 0)
-	r.preheatTasks.Range(func(key, value interface{}) bool{
-		list = append(list, value.(*mgr.PreheatTask))
-		return true
-	})
+func (r *PreheatTaskRepository) GetAll() []*mgr.PreheatTask {
+	r.RLock()
+	defer r.RUnlock()
+	list := make([]*mgr.PreheatTask, 0, len(r.preheatTasks))
+	for _, t := range r.preheatTasks {
+		list = append(list, copyTask(t))
+	}
 	return list
 }
 
-func(r *PreheatTaskRepository) GetAllIds() []string {
-	list := make([]string, 0)
-	r.preheatTasks.Range(func(key, value interface{}) bool{
-		list = append(list, key.(string))
-		return true
-	})
+func (r *PreheatTaskRepository) GetAllIds() []string {
+	r.RLock()
+	defer r.RUnlock()
+	list := make([]string, 0, len(r.preheatTasks))
+	for id := range r.preheatTasks {
+		list = append(list, id)
+	}
 	return list
 }
 
-func(r *PreheatTaskRepository) Add(task *mgr.PreheatTask) (*mgr.PreheatTask, error) {
-	t, _ := r.preheatTasks.LoadOrStore(task.ID, task)
-	return t.(*mgr.PreheatTask), nil
+// Add adds the task if it doesn't exist, otherwise returns the existing one.
+func (r *PreheatTaskRepository) Add(task *mgr.PreheatTask) (previous *mgr.PreheatTask, err error) {
+	r.Lock()
+	defer r.Unlock()
+	if t, ok := r.preheatTasks[task.ID]; ok {
+		return copyTask(t), nil
+	}
+	r.preheatTasks[task.ID] = copyTask(task)
+	return nil, nil
+}
+
+// AddOrReplace adds the task if it doesn't exist or the existing one has
+// finished, otherwise returns the existing running one.
+func (r *PreheatTaskRepository) AddOrReplace(task *mgr.PreheatTask) (previous *mgr.PreheatTask, err error) {
+	r.Lock()
+	defer r.Unlock()
+	if t, ok := r.preheatTasks[task.ID]; ok && t.FinishTime == 0 {
+		return copyTask(t), nil
+	}
+	r.preheatTasks[task.ID] = copyTask(task)
+	return nil, nil
+}
+
+func (r *PreheatTaskRepository) Update(id string, task *mgr.PreheatTask) bool {
+	r.Lock()
+	defer r.Unlock()
+	t, ok := r.preheatTasks[id]
+	if !ok {
+		return false
+	}
+	if task.ParentID != "" {
+		t.ParentID = task.ParentID
+	}
+	if task.Children != nil {
+		t.Children = task.Children
+	}
+	if task.Status != "" {
+		t.Status = task.Status
+	}
+	if task.StartTime > 0 {
+		t.StartTime = task.StartTime
+	}
+	if task.FinishTime > 0 {
+		t.FinishTime = task.FinishTime
+	}
+	if task.ErrorMsg != "" {
+		t.ErrorMsg = task.ErrorMsg
+	}
+	if task.Error != nil {
+		t.Error = task.Error
+	}
+	if task.PieceTotal > 0 {
+		t.PieceTotal = task.PieceTotal
+	}
+	if task.FinishedPieceCount > 0 {
+		t.FinishedPieceCount = task.FinishedPieceCount
+	}
+	if task.Peers != nil {
+		t.Peers = copyPeers(task.Peers)
+	}
+	if task.Layers != nil {
+		t.Layers = copyLayers(task.Layers)
+	}
+	return true
+}
+
+func (r *PreheatTaskRepository) Delete(id string) bool {
+	r.Lock()
+	defer r.Unlock()
+	_, existed := r.preheatTasks[id]
+	delete(r.preheatTasks, id)
+	return existed
+}
+
+func (r *PreheatTaskRepository) IsExpired(id string) bool {
+	t := r.Get(id)
+	return t != nil && r.expired(t.StartTime)
+}
+
+func (r *PreheatTaskRepository) expired(timestamp int64) bool {
+	return time.Now().UnixNano()/int64(time.Millisecond) > timestamp+EXPIRED_TIME
 }
 
-func(r *PreheatTaskRepository) Update(id string, task *mgr.PreheatTask) bool {
-	v, _ := r.preheatTasks.Load(id)
-	t, _ := v.(*mgr.PreheatTask)
-	if t != nil {
-		if task.ParentID != "" {
-			t.ParentID = task.ParentID
+// GetSchedule returns the schedule with the runs of all the supernodes, and
+// nil if it doesn't exist.
+func (r *PreheatTaskRepository) GetSchedule(id string) *mgr.PreheatSchedule {
+	r.schedulesLock.RLock()
+	defer r.schedulesLock.RUnlock()
+	stored, err := r.getStoredSchedule(id)
+	if err != nil {
+		if !errortypes.IsDataNotFound(err) {
+			logrus.Errorf("failed to get preheat schedule %s: %v", id, err)
 		}
-		if task.Children != nil {
-			t.Children = task.Children
+		return nil
+	}
+	runs, err := r.listRuns()
+	if err != nil {
+		logrus.Errorf("failed to list the runs of preheat schedules: %v", err)
+	}
+	return r.view(stored, runs[id])
+}
+
+func (r *PreheatTaskRepository) GetAllSchedules() []*mgr.PreheatSchedule {
+	r.schedulesLock.RLock()
+	defer r.schedulesLock.RUnlock()
+	values, err := r.backend.List(state.BucketPreheatSchedule)
+	if err != nil {
+		logrus.Errorf("failed to list preheat schedules: %v", err)
+		return []*mgr.PreheatSchedule{}
+	}
+	runs, err := r.listRuns()
+	if err != nil {
+		logrus.Errorf("failed to list the runs of preheat schedules: %v", err)
+	}
+	list := make([]*mgr.PreheatSchedule, 0, len(values))
+	for id, value := range values {
+		stored := &storedSchedule{}
+		if err := json.Unmarshal(value, stored); err != nil {
+			logrus.Errorf("failed to decode preheat schedule %s: %v", id, err)
+			continue
 		}
-		if task.Status != "" {
-			t.Status = task.Status
+		list = append(list, r.view(stored, runs[id]))
+	}
+	return list
+}
+
+// AddSchedule adds the schedule into the state backend, the headers of the
+// preheat request of which are sealed by the secret.
+func (r *PreheatTaskRepository) AddSchedule(schedule *mgr.PreheatSchedule) error {
+	r.schedulesLock.Lock()
+	defer r.schedulesLock.Unlock()
+	if err := r.putStoredSchedule(schedule); err != nil {
+		return err
+	}
+	return r.saveSchedules()
+}
+
+// UpdateSchedule updates the schedule shared by all the supernodes by the
+// function update, such as whether it's enabled and the next run time from
+// then on. The runs of the schedule aren't passed to update, and the preheat
+// request of it is never updated. It returns nil if the schedule doesn't exist.
+func (r *PreheatTaskRepository) UpdateSchedule(id string, update func(*mgr.PreheatSchedule)) (*mgr.PreheatSchedule, error) {
+	r.schedulesLock.Lock()
+	defer r.schedulesLock.Unlock()
+	stored, err := r.getStoredSchedule(id)
+	if err != nil {
+		if errortypes.IsDataNotFound(err) {
+			return nil, nil
 		}
-		if task.StartTime > 0 {
-			t.StartTime = task.StartTime
+		return nil, err
+	}
+	schedule := r.view(stored, nil)
+	update(schedule)
+	stored.Name = schedule.Name
+	stored.Cron = schedule.Cron
+	stored.Enabled = schedule.Enabled
+	stored.NextRunTime = schedule.NextRunTime
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.backend.Put(state.BucketPreheatSchedule, id, data); err != nil {
+		return nil, err
+	}
+	runs, err := r.listRuns()
+	if err != nil {
+		return nil, err
+	}
+	return r.updated(id, runs[id])
+}
+
+// UpdateScheduleRuns updates the runs and the next run time of the schedule on
+// this supernode by the function update, and saves them if update returns true.
+// It returns nil if the schedule doesn't exist.
+func (r *PreheatTaskRepository) UpdateScheduleRuns(id string, update func(*mgr.PreheatSchedule) bool) (*mgr.PreheatSchedule, error) {
+	r.schedulesLock.Lock()
+	defer r.schedulesLock.Unlock()
+	schedule, runs, err := r.localSchedule(id)
+	if schedule == nil || err != nil {
+		return nil, err
+	}
+	if !update(schedule) {
+		return r.updated(id, runs)
+	}
+	local := &scheduleRuns{NextRunTime: schedule.NextRunTime, Runs: schedule.Runs}
+	data, err := json.Marshal(local)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.backend.Put(state.BucketPreheatScheduleRun, r.runsKey(id), data); err != nil {
+		return nil, err
+	}
+	runs[r.node] = local
+	return r.updated(id, runs)
+}
+
+// DeleteSchedule deletes the schedule and its runs of all the supernodes.
+func (r *PreheatTaskRepository) DeleteSchedule(id string) (bool, error) {
+	r.schedulesLock.Lock()
+	defer r.schedulesLock.Unlock()
+	if _, err := r.getStoredSchedule(id); err != nil {
+		if errortypes.IsDataNotFound(err) {
+			return false, nil
 		}
-		if task.FinishTime > 0 {
-			t.FinishTime = task.FinishTime
+		return false, err
+	}
+	runs, err := r.listRuns()
+	if err != nil {
+		return false, err
+	}
+	for node := range runs[id] {
+		if err := r.backend.Delete(state.BucketPreheatScheduleRun, node+"/"+id); err != nil {
+			return false, err
 		}
-		return true
 	}
-	return false
+	if err := r.backend.Delete(state.BucketPreheatSchedule, id); err != nil {
+		return false, err
+	}
+	return true, r.saveSchedules()
 }
 
-func(r *PreheatTaskRepository) Delete(id string) bool {
-	_, existed := r.preheatTasks.Load(id)
-	r.preheatTasks.Delete(id)
-	return existed
+// localSchedule returns the schedule with the runs and the next run time of
+// this supernode, and the runs of all the supernodes.
+func (r *PreheatTaskRepository) localSchedule(id string) (*mgr.PreheatSchedule, map[string]*scheduleRuns, error) {
+	stored, err := r.getStoredSchedule(id)
+	if err != nil {
+		if errortypes.IsDataNotFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	runs, err := r.listRuns()
+	if err != nil {
+		return nil, nil, err
+	}
+	if runs[id] == nil {
+		runs[id] = make(map[string]*scheduleRuns)
+	}
+	schedule := r.view(stored, nil)
+	if local, ok := runs[id][r.node]; ok {
+		schedule.Runs = local.Runs
+		if schedule.Enabled && local.NextRunTime > schedule.NextRunTime {
+			schedule.NextRunTime = local.NextRunTime
+		}
+	}
+	return schedule, runs[id], nil
 }
 
-func(r *PreheatTaskRepository) IsExpired(id string) bool {
-	t, _ := r.preheatTasks.Load(id)
-	if t == nil {
-		return false
+// updated persists the schedules and returns the updated schedule.
+func (r *PreheatTaskRepository) updated(id string, runs map[string]*scheduleRuns) (*mgr.PreheatSchedule, error) {
+	stored, err := r.getStoredSchedule(id)
+	if err != nil {
+		return nil, err
 	}
-	return t != nil && r.expired(t.(*mgr.PreheatTask).StartTime)
+	return r.view(stored, runs), r.saveSchedules()
 }
 
-func (r *PreheatTaskRepository) expired(timestamp int64) bool {
-	return time.Now().UnixNano()/int64(time.Millisecond) > timestamp+EXPIRED_TIME
+// view returns the schedule with the runs of the supernodes, the latest
+// scheduleRunsLimit runs of which are kept. The next run time is the later
+// one of the time it's enabled and the time this supernode runs it next.
+func (r *PreheatTaskRepository) view(stored *storedSchedule, runs map[string]*scheduleRuns) *mgr.PreheatSchedule {
+	schedule := &mgr.PreheatSchedule{
+		ID:          stored.ID,
+		Name:        stored.Name,
+		Cron:        stored.Cron,
+		Request:     stored.Request,
+		Enabled:     stored.Enabled,
+		CreateTime:  stored.CreateTime,
+		NextRunTime: stored.NextRunTime,
+		Runs:        []*mgr.PreheatScheduleRun{},
+	}
+	if len(stored.SealedHeaders) > 0 {
+		headers, err := r.openHeaders(stored.SealedHeaders)
+		if err != nil {
+			logrus.Errorf("failed to open the headers of preheat schedule %s: %v", stored.ID, err)
+		}
+		req := *stored.Request
+		req.Headers = headers
+		schedule.Request = &req
+	}
+	for node, local := range runs {
+		schedule.Runs = append(schedule.Runs, local.Runs...)
+		if node == r.node && schedule.Enabled && local.NextRunTime > schedule.NextRunTime {
+			schedule.NextRunTime = local.NextRunTime
+		}
+	}
+	sort.SliceStable(schedule.Runs, func(i, j int) bool {
+		return schedule.Runs[i].StartTime < schedule.Runs[j].StartTime
+	})
+	if len(schedule.Runs) > scheduleRunsLimit {
+		schedule.Runs = schedule.Runs[len(schedule.Runs)-scheduleRunsLimit:]
+	}
+	return schedule
+}
+
+func (r *PreheatTaskRepository) getStoredSchedule(id string) (*storedSchedule, error) {
+	data, err := r.backend.Get(state.BucketPreheatSchedule, id)
+	if err != nil {
+		return nil, err
+	}
+	stored := &storedSchedule{}
+	if err := json.Unmarshal(data, stored); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode preheat schedule %s", id)
+	}
+	return stored, nil
+}
+
+// putStoredSchedule puts the schedule into the state backend except its runs,
+// and the headers of the preheat request are sealed.
+func (r *PreheatTaskRepository) putStoredSchedule(schedule *mgr.PreheatSchedule) error {
+	stored := &storedSchedule{
+		ID:          schedule.ID,
+		Name:        schedule.Name,
+		Cron:        schedule.Cron,
+		Request:     schedule.Request,
+		Enabled:     schedule.Enabled,
+		CreateTime:  schedule.CreateTime,
+		NextRunTime: schedule.NextRunTime,
+	}
+	if schedule.Request != nil && len(schedule.Request.Headers) > 0 {
+		sealed, err := r.sealHeaders(schedule.Request.Headers)
+		if err != nil {
+			return err
+		}
+		req := *schedule.Request
+		req.Headers = nil
+		stored.Request = &req
+		stored.SealedHeaders = sealed
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	return r.backend.Put(state.BucketPreheatSchedule, schedule.ID, data)
+}
+
+// listRuns returns the runs of all the schedules by the schedule ids and
+// the supernodes.
+func (r *PreheatTaskRepository) listRuns() (map[string]map[string]*scheduleRuns, error) {
+	values, err := r.backend.List(state.BucketPreheatScheduleRun)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]map[string]*scheduleRuns)
+	for key, value := range values {
+		i := strings.LastIndex(key, "/")
+		if i < 0 {
+			continue
+		}
+		runs := &scheduleRuns{}
+		if err := json.Unmarshal(value, runs); err != nil {
+			logrus.Errorf("failed to decode the runs of preheat schedule %s: %v", key, err)
+			continue
+		}
+		node, id := key[:i], key[i+1:]
+		if result[id] == nil {
+			result[id] = make(map[string]*scheduleRuns)
+		}
+		result[id][node] = runs
+	}
+	return result, nil
+}
+
+// runsKey returns the key of the runs of the schedule on this supernode.
+func (r *PreheatTaskRepository) runsKey(id string) string {
+	return r.node + "/" + id
+}
+
+// sealHeaders encrypts the headers by AES-GCM with the key derived from the
+// secret, the nonce is prepended to the result.
+func (r *PreheatTaskRepository) sealHeaders(headers map[string]string) ([]byte, error) {
+	if r.aead == nil {
+		return nil, fmt.Errorf("preheatSecret is required to keep the headers of preheat schedules")
+	}
+	data, err := json.Marshal(headers)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, r.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return r.aead.Seal(nonce, nonce, data, nil), nil
 }
 
+func (r *PreheatTaskRepository) openHeaders(sealed []byte) (map[string]string, error) {
+	if r.aead == nil {
+		return nil, fmt.Errorf("preheatSecret is required to open the headers")
+	}
+	if len(sealed) < r.aead.NonceSize() {
+		return nil, fmt.Errorf("invalid sealed headers")
+	}
+	n := r.aead.NonceSize()
+	data, err := r.aead.Open(nil, sealed[:n], sealed[n:], nil)
+	if err != nil {
+		return nil, err
+	}
+	headers := make(map[string]string)
+	if err := json.Unmarshal(data, &headers); err != nil {
+		return nil, err
+	}
+	return headers, nil
+}
+
+// persistedSchedules is the content of schedulesPath, which is a copy of
+// the schedules and their runs in the state backend.
+type persistedSchedules struct {
+	Schedules map[string]json.RawMessage `json:"schedules"`
+	Runs      map[string]json.RawMessage `json:"runs"`
+}
+
+// saveSchedules writes the schedules in the state backend into a temporary
+// file only readable by the owner, and renames it to schedulesPath, so the
+// file is never half written.
+func (r *PreheatTaskRepository) saveSchedules() error {
+	if stringutils.IsEmptyStr(r.schedulesPath) {
+		return nil
+	}
+	persisted := &persistedSchedules{
+		Schedules: make(map[string]json.RawMessage),
+		Runs:      make(map[string]json.RawMessage),
+	}
+	for bucket, m := range map[string]map[string]json.RawMessage{
+		state.BucketPreheatSchedule:    persisted.Schedules,
+		state.BucketPreheatScheduleRun: persisted.Runs,
+	} {
+		values, err := r.backend.List(bucket)
+		if err != nil {
+			return err
+		}
+		for k, v := range values {
+			m[k] = v
+		}
+	}
+	data, err := json.Marshal(persisted)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.schedulesPath), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(r.schedulesPath), filepath.Base(r.schedulesPath)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), r.schedulesPath)
+}
+
+// loadSchedules puts the schedules and their runs in schedulesPath into
+// the state backend.
+func (r *PreheatTaskRepository) loadSchedules() error {
+	if stringutils.IsEmptyStr(r.schedulesPath) {
+		return nil
+	}
+	data, err := ioutil.ReadFile(r.schedulesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	persisted := &persistedSchedules{}
+	if err := json.Unmarshal(data, persisted); err != nil {
+		return err
+	}
+	for bucket, m := range map[string]map[string]json.RawMessage{
+		state.BucketPreheatSchedule:    persisted.Schedules,
+		state.BucketPreheatScheduleRun: persisted.Runs,
+	} {
+		for k, v := range m {
+			if err := r.backend.Put(bucket, k, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// copyTask returns a shallow copy of the task, the slices and maps of
+// the task are replaced rather than modified in place except the peers
+// and the layers which are copied.
+func copyTask(task *mgr.PreheatTask) *mgr.PreheatTask {
+	t := *task
+	t.Peers = copyPeers(task.Peers)
+	t.Layers = copyLayers(task.Layers)
+	return &t
+}
+
+func copyPeers(peers []*types.PreheatPeerStatus) []*types.PreheatPeerStatus {
+	if peers == nil {
+		return nil
+	}
+	result := make([]*types.PreheatPeerStatus, len(peers))
+	for i, p := range peers {
+		peer := *p
+		result[i] = &peer
+	}
+	return result
+}
+
+func copyLayers(layers []*types.PreheatLayerStatus) []*types.PreheatLayerStatus {
+	if layers == nil {
+		return nil
+	}
+	result := make([]*types.PreheatLayerStatus, len(layers))
+	for i, l := range layers {
+		layer := *l
+		result[i] = &layer
+	}
+	return result
+}
@@ -0,0 +1,254 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package preheat
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	dferr "github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+)
+
+// the sources of the webhook events.
+const (
+	// WebhookSourceHarbor is the webhook of Harbor.
+	WebhookSourceHarbor = "harbor"
+
+	// WebhookSourceDistribution is the notification of Docker Distribution.
+	WebhookSourceDistribution = "distribution"
+
+	// WebhookSourceGitLab is the notification of the GitLab container registry,
+	// which is in the format of Docker Distribution.
+	WebhookSourceGitLab = "gitlab"
+
+	// WebhookSourceQuay is the repository push notification of Quay.
+	WebhookSourceQuay = "quay"
+)
+
+const (
+	// WebhookSignatureHeader carries the HMAC-SHA256 signature of the event
+	// signed by the webhook secret, in the format of "sha256=<hex>".
+	WebhookSignatureHeader = "X-Dragonfly-Signature"
+
+	gitlabTokenHeader = "X-Gitlab-Token"
+)
+
+// WebhookImage is an image pushed to a registry.
+type WebhookImage struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ManifestURL returns the URL of the manifest of the image, which is
+// referenced by the digest if it's known.
+func (img *WebhookImage) ManifestURL(insecure bool) string {
+	scheme := "https"
+	if insecure {
+		scheme = "http"
+	}
+	reference := img.Tag
+	if img.Digest != "" {
+		reference = img.Digest
+	}
+	return fmt.Sprintf("%s://%s/v2/%s/manifests/%s", scheme, img.Registry, img.Repository, reference)
+}
+
+// VerifyWebhookSecret checks whether the event is sent by the registry which
+// knows the secret. The event can be signed by the secret in the
+// X-Dragonfly-Signature header, or carry the secret in the way the source
+// supports:
+//   - harbor, distribution, quay: the Authorization header, with or without the Bearer scheme.
+//   - gitlab: the X-Gitlab-Token header.
+//
+// The secret is never taken from the URL, which is likely to be logged by the proxies.
+func VerifyWebhookSecret(source, secret string, req *http.Request, body []byte) error {
+	if secret == "" {
+		return dferr.New(http.StatusForbidden, "webhook secret isn't configured")
+	}
+
+	if signature := req.Header.Get(WebhookSignatureHeader); signature != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(signature), []byte(expected)) {
+			return dferr.New(http.StatusUnauthorized, "invalid webhook signature")
+		}
+		return nil
+	}
+
+	var token string
+	switch source {
+	case WebhookSourceHarbor, WebhookSourceDistribution, WebhookSourceQuay:
+		token = strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	case WebhookSourceGitLab:
+		token = req.Header.Get(gitlabTokenHeader)
+	default:
+		return dferr.Newf(http.StatusNotFound, "unknown webhook source %s", source)
+	}
+	// the digests are compared so that the time doesn't depend on the length of the secret.
+	tokenDigest, secretDigest := sha256.Sum256([]byte(token)), sha256.Sum256([]byte(secret))
+	if !hmac.Equal(tokenDigest[:], secretDigest[:]) {
+		return dferr.New(http.StatusUnauthorized, "invalid webhook secret")
+	}
+	return nil
+}
+
+// ParseWebhookEvents returns the images pushed in the events sent by the source.
+// The other events, such as pulling or deleting the images, are ignored.
+func ParseWebhookEvents(source string, body []byte) ([]*WebhookImage, error) {
+	var (
+		images []*WebhookImage
+		err    error
+	)
+	switch source {
+	case WebhookSourceHarbor:
+		images, err = parseHarborEvent(body)
+	case WebhookSourceDistribution, WebhookSourceGitLab:
+		images, err = parseDistributionEvents(body)
+	case WebhookSourceQuay:
+		images, err = parseQuayEvent(body)
+	default:
+		return nil, dferr.Newf(http.StatusNotFound, "unknown webhook source %s", source)
+	}
+	if err != nil {
+		return nil, dferr.Newf(http.StatusBadRequest, "invalid %s event: %v", source, err)
+	}
+	return images, nil
+}
+
+type harborEvent struct {
+	Type      string `json:"type"`
+	EventData struct {
+		Resources []struct {
+			Digest      string `json:"digest"`
+			Tag         string `json:"tag"`
+			ResourceURL string `json:"resource_url"`
+		} `json:"resources"`
+		Repository struct {
+			RepoFullName string `json:"repo_full_name"`
+		} `json:"repository"`
+	} `json:"event_data"`
+}
+
+func parseHarborEvent(body []byte) ([]*WebhookImage, error) {
+	event := &harborEvent{}
+	if err := json.Unmarshal(body, event); err != nil {
+		return nil, err
+	}
+	// PUSH_ARTIFACT is sent by Harbor 2.x and pushImage by Harbor 1.x.
+	if event.Type != "PUSH_ARTIFACT" && event.Type != "pushImage" {
+		return nil, nil
+	}
+
+	var images []*WebhookImage
+	for _, r := range event.EventData.Resources {
+		// the resource url is in the format of "<registry>/<repository>:<tag>"
+		registry := strings.SplitN(r.ResourceURL, "/", 2)[0]
+		if registry == "" || event.EventData.Repository.RepoFullName == "" {
+			continue
+		}
+		images = append(images, &WebhookImage{
+			Registry:   registry,
+			Repository: event.EventData.Repository.RepoFullName,
+			Tag:        r.Tag,
+			Digest:     r.Digest,
+		})
+	}
+	return images, nil
+}
+
+type distributionEvents struct {
+	Events []struct {
+		Action string `json:"action"`
+		Target struct {
+			MediaType  string `json:"mediaType"`
+			Digest     string `json:"digest"`
+			Repository string `json:"repository"`
+			URL        string `json:"url"`
+			Tag        string `json:"tag"`
+		} `json:"target"`
+		Request struct {
+			Host string `json:"host"`
+		} `json:"request"`
+	} `json:"events"`
+}
+
+func parseDistributionEvents(body []byte) ([]*WebhookImage, error) {
+	events := &distributionEvents{}
+	if err := json.Unmarshal(body, events); err != nil {
+		return nil, err
+	}
+
+	var images []*WebhookImage
+	for _, e := range events.Events {
+		// only the tagged manifests are preheated, the blobs and the manifests
+		// of the platforms in a manifest list are pushed without tags.
+		if e.Action != "push" || e.Target.Tag == "" || e.Target.Repository == "" {
+			continue
+		}
+		registry := e.Request.Host
+		if u, err := url.Parse(e.Target.URL); err == nil && u.Host != "" {
+			registry = u.Host
+		}
+		if registry == "" {
+			continue
+		}
+		images = append(images, &WebhookImage{
+			Registry:   registry,
+			Repository: e.Target.Repository,
+			Tag:        e.Target.Tag,
+			Digest:     e.Target.Digest,
+		})
+	}
+	return images, nil
+}
+
+type quayEvent struct {
+	Repository  string   `json:"repository"`
+	DockerURL   string   `json:"docker_url"`
+	UpdatedTags []string `json:"updated_tags"`
+}
+
+func parseQuayEvent(body []byte) ([]*WebhookImage, error) {
+	event := &quayEvent{}
+	if err := json.Unmarshal(body, event); err != nil {
+		return nil, err
+	}
+	// the docker url is in the format of "<registry>/<repository>"
+	registry := strings.SplitN(event.DockerURL, "/", 2)[0]
+	if registry == "" || event.Repository == "" {
+		return nil, nil
+	}
+
+	var images []*WebhookImage
+	for _, tag := range event.UpdatedTags {
+		images = append(images, &WebhookImage{
+			Registry:   registry,
+			Repository: event.Repository,
+			Tag:        tag,
+		})
+	}
+	return images, nil
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package preheat
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-check/check"
+)
+
+func (s *PreheatServiceTestSuite) TestParseWebhookEvents(c *check.C) {
+	var cases = []struct {
+		source string
+		body   string
+		images []*WebhookImage
+	}{
+		{
+			source: WebhookSourceHarbor,
+			body: `{"type":"PUSH_ARTIFACT","event_data":{"resources":[{"digest":"sha256:aaa","tag":"v1",` +
+				`"resource_url":"harbor.example.com/library/nginx:v1"}],"repository":{"repo_full_name":"library/nginx"}}}`,
+			images: []*WebhookImage{{Registry: "harbor.example.com", Repository: "library/nginx", Tag: "v1", Digest: "sha256:aaa"}},
+		},
+		{
+			source: WebhookSourceHarbor,
+			body:   `{"type":"PULL_ARTIFACT","event_data":{"resources":[{"resource_url":"harbor.example.com/library/nginx:v1"}]}}`,
+		},
+		{
+			source: WebhookSourceDistribution,
+			body: `{"events":[` +
+				`{"action":"push","target":{"digest":"sha256:bbb","repository":"app","tag":"v2",` +
+				`"url":"https://hub.example.com/v2/app/manifests/sha256:bbb"},"request":{"host":"hub"}},` +
+				`{"action":"push","target":{"digest":"sha256:ccc","repository":"app"},"request":{"host":"hub"}},` +
+				`{"action":"pull","target":{"digest":"sha256:bbb","repository":"app","tag":"v2"},"request":{"host":"hub"}}]}`,
+			images: []*WebhookImage{{Registry: "hub.example.com", Repository: "app", Tag: "v2", Digest: "sha256:bbb"}},
+		},
+		{
+			source: WebhookSourceGitLab,
+			body:   `{"events":[{"action":"push","target":{"repository":"group/app","tag":"v3"},"request":{"host":"gitlab.example.com:5050"}}]}`,
+			images: []*WebhookImage{{Registry: "gitlab.example.com:5050", Repository: "group/app", Tag: "v3"}},
+		},
+		{
+			source: WebhookSourceQuay,
+			body:   `{"repository":"ns/app","docker_url":"quay.io/ns/app","updated_tags":["latest","v4"]}`,
+			images: []*WebhookImage{
+				{Registry: "quay.io", Repository: "ns/app", Tag: "latest"},
+				{Registry: "quay.io", Repository: "ns/app", Tag: "v4"},
+			},
+		},
+	}
+
+	for _, v := range cases {
+		images, err := ParseWebhookEvents(v.source, []byte(v.body))
+		c.Assert(err, check.IsNil)
+		c.Assert(images, check.DeepEquals, v.images, check.Commentf("%s: %s", v.source, v.body))
+	}
+
+	_, err := ParseWebhookEvents(WebhookSourceQuay, []byte("{"))
+	c.Assert(err, check.NotNil)
+	_, err = ParseWebhookEvents("unknown", []byte("{}"))
+	c.Assert(err, check.NotNil)
+
+	img := &WebhookImage{Registry: "hub.example.com", Repository: "library/nginx", Tag: "v1"}
+	c.Assert(img.ManifestURL(false), check.Equals, "https://hub.example.com/v2/library/nginx/manifests/v1")
+	img.Digest = "sha256:aaa"
+	c.Assert(img.ManifestURL(true), check.Equals, "http://hub.example.com/v2/library/nginx/manifests/sha256:aaa")
+}
+
+func (s *PreheatServiceTestSuite) TestVerifyWebhookSecret(c *check.C) {
+	body := []byte(`{"events":[]}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	var cases = []struct {
+		source  string
+		target  string
+		headers map[string]string
+		valid   bool
+	}{
+		{source: WebhookSourceHarbor, headers: map[string]string{"Authorization": "secret"}, valid: true},
+		{source: WebhookSourceDistribution, headers: map[string]string{"Authorization": "Bearer secret"}, valid: true},
+		{source: WebhookSourceDistribution, headers: map[string]string{"Authorization": "Bearer other"}},
+		{source: WebhookSourceGitLab, headers: map[string]string{gitlabTokenHeader: "secret"}, valid: true},
+		{source: WebhookSourceGitLab, headers: map[string]string{"Authorization": "secret"}},
+		{source: WebhookSourceQuay, headers: map[string]string{"Authorization": "secret"}, valid: true},
+		{source: WebhookSourceQuay, target: "/?secret=secret"},
+		{source: WebhookSourceQuay},
+		{source: WebhookSourceHarbor, headers: map[string]string{"Authorization": "secretsecret"}},
+		{source: WebhookSourceQuay, headers: map[string]string{WebhookSignatureHeader: signature}, valid: true},
+		{source: WebhookSourceHarbor, headers: map[string]string{WebhookSignatureHeader: "sha256=00"}},
+		{source: "unknown", headers: map[string]string{"Authorization": "secret"}},
+	}
+
+	for _, v := range cases {
+		target := v.target
+		if target == "" {
+			target = "/"
+		}
+		req := httptest.NewRequest(http.MethodPost, target, nil)
+		for k, h := range v.headers {
+			req.Header.Set(k, h)
+		}
+		err := VerifyWebhookSecret(v.source, "secret", req, body)
+		c.Assert(err == nil, check.Equals, v.valid, check.Commentf("%s %s %v: %v", v.source, target, v.headers, err))
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Authorization", "")
+	c.Assert(VerifyWebhookSecret(WebhookSourceQuay, "", req, body), check.NotNil)
+}
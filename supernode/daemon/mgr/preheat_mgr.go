@@ -31,6 +31,11 @@ type PreheatTask struct {
 	Identifier string
 	Headers    map[string]string
 
+	// Platforms and Referrers decide which blobs of an image are preheated,
+	// see types.PreheatCreateRequest.
+	Platforms []string
+	Referrers bool
+
 	// ParentID records its parent preheat task id. Sometimes the current
 	// preheat task is not created by user directly. Such as preheating an
 	// image, it contains several layers that should be preheated together.
@@ -42,6 +47,52 @@ type PreheatTask struct {
 	StartTime  int64
 	FinishTime int64
 	ErrorMsg   string
+
+	// Error is the structured error when the preheat task failed.
+	Error *types.PreheatError
+
+	// PieceTotal and FinishedPieceCount track the progress of the pieces
+	// downloaded into the supernode CDN.
+	PieceTotal         int32
+	FinishedPieceCount int32
+
+	// Peers are the target peers which download the file in the background
+	// after it's preheated into the supernode CDN.
+	Peers []*types.PreheatPeerStatus
+
+	// Layers are the status of the blobs preheated by the children of an
+	// image preheat task.
+	Layers []*types.PreheatLayerStatus
+}
+
+// PreheatSchedule stores a preheat definition which is preheated periodically
+// according to the cron expression.
+type PreheatSchedule struct {
+	ID      string
+	Name    string
+	Cron    string
+	Request *types.PreheatCreateRequest
+	Enabled bool
+
+	CreateTime  int64
+	NextRunTime int64
+
+	// Runs are the latest runs of the schedule, the oldest one is the first.
+	Runs []*PreheatScheduleRun
+}
+
+// PreheatScheduleRun records a run of a preheat schedule.
+type PreheatScheduleRun struct {
+	// PreheatID is the id of the preheat task created by the run.
+	PreheatID string
+	// URL is the url preheated by the run, in which the tag of an image
+	// is resolved to the digest.
+	URL string
+
+	Status     types.PreheatStatus
+	StartTime  int64
+	FinishTime int64
+	ErrorMsg   string
 }
 
 // PreheatManager provides basic operations of preheat.
@@ -58,4 +109,24 @@ type PreheatManager interface {
 
 	// GetAll gets all preheat tasks that unexpired.
 	GetAll(ctx context.Context) (preheatTask []*PreheatTask, err error)
+
+	// CreateSchedule creates a preheat schedule which creates a preheat task
+	// periodically according to the cron expression.
+	CreateSchedule(ctx context.Context, request *types.PreheatScheduleCreateRequest) (scheduleID string, err error)
+
+	// GetSchedule gets a preheat schedule and its latest runs by scheduleID.
+	GetSchedule(ctx context.Context, scheduleID string) (schedule *PreheatSchedule, err error)
+
+	// GetAllSchedules gets all preheat schedules.
+	GetAllSchedules(ctx context.Context) (schedules []*PreheatSchedule, err error)
+
+	// DeleteSchedule deletes a preheat schedule by scheduleID, the preheat
+	// tasks created by it are kept.
+	DeleteSchedule(ctx context.Context, scheduleID string) (err error)
+
+	// EnableSchedule enables or disables a preheat schedule.
+	EnableSchedule(ctx context.Context, scheduleID string, enable bool) (schedule *PreheatSchedule, err error)
+
+	// StartSchedule starts to run the due preheat schedules with a new goroutine.
+	StartSchedule(ctx context.Context)
 }
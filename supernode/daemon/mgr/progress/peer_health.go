@@ -0,0 +1,199 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package progress
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// latencyBase is the average latency of serving a piece
+	// which halves the health score of a peer.
+	latencyBase = 5 * time.Second
+
+	// md5ErrorWeight is how many times a corrupted piece lowers the score
+	// more than a failed piece.
+	md5ErrorWeight = 2
+)
+
+// peerHealth maintains the events which decide the health of a peer.
+// All the counts decay exponentially with halfLife, so that a peer recovers
+// gradually after a transient failure.
+type peerHealth struct {
+	sync.Mutex
+
+	halfLife   time.Duration
+	decayTime  time.Time
+	activeTime time.Time
+
+	// successes and failures are the pieces which the other peers downloaded from the peer.
+	successes float64
+	failures  float64
+	md5Errors float64
+	downs     float64
+
+	// latencySum is the sum of the seconds of the successes taken.
+	latencySum   float64
+	latencyCount float64
+
+	// clientSuccesses and clientFailures are the pieces which the peer downloaded from the others.
+	clientSuccesses float64
+	clientFailures  float64
+}
+
+func newPeerHealth(halfLife time.Duration) *peerHealth {
+	now := time.Now()
+	return &peerHealth{
+		halfLife:   halfLife,
+		decayTime:  now,
+		activeTime: now,
+	}
+}
+
+// decay lowers the counts according to the time elapsed since the last decay.
+func (h *peerHealth) decay(now time.Time) {
+	elapsed := now.Sub(h.decayTime)
+	if h.halfLife <= 0 || elapsed <= 0 {
+		return
+	}
+	h.decayTime = now
+
+	factor := math.Pow(0.5, float64(elapsed)/float64(h.halfLife))
+	h.successes *= factor
+	h.failures *= factor
+	h.md5Errors *= factor
+	h.downs *= factor
+	h.latencySum *= factor
+	h.latencyCount *= factor
+	h.clientSuccesses *= factor
+	h.clientFailures *= factor
+}
+
+// serviceSucceeded records that the piece was downloaded from the peer in latency.
+func (h *peerHealth) serviceSucceeded(latency time.Duration) {
+	h.Lock()
+	defer h.Unlock()
+	now := time.Now()
+	h.decay(now)
+	h.activeTime = now
+	h.successes++
+	if latency > 0 {
+		h.latencySum += latency.Seconds()
+		h.latencyCount++
+	}
+}
+
+// serviceFailed records that the piece failed to be downloaded from the peer.
+func (h *peerHealth) serviceFailed() {
+	h.Lock()
+	defer h.Unlock()
+	h.decay(time.Now())
+	h.failures++
+}
+
+// serviceSlow records that the peer was too slow to serve a piece and the piece
+// was downloaded from another peer. It isn't a failure, but is counted as
+// a latency of latencyBase because the piece wasn't completed.
+func (h *peerHealth) serviceSlow() {
+	h.Lock()
+	defer h.Unlock()
+	h.decay(time.Now())
+	h.latencySum += latencyBase.Seconds()
+	h.latencyCount++
+}
+
+// md5NotMatch records that the peer served a corrupted piece.
+func (h *peerHealth) md5NotMatch() {
+	h.Lock()
+	defer h.Unlock()
+	h.decay(time.Now())
+	h.md5Errors++
+}
+
+// serviceDown records that the peer went offline.
+func (h *peerHealth) serviceDown() {
+	h.Lock()
+	defer h.Unlock()
+	h.decay(time.Now())
+	h.downs++
+}
+
+// clientSucceeded records that the peer downloaded a piece from the others.
+func (h *peerHealth) clientSucceeded() {
+	h.Lock()
+	defer h.Unlock()
+	now := time.Now()
+	h.decay(now)
+	h.activeTime = now
+	h.clientSuccesses++
+}
+
+// clientFailed records that the peer failed to download a piece from the others.
+func (h *peerHealth) clientFailed() {
+	h.Lock()
+	defer h.Unlock()
+	now := time.Now()
+	h.decay(now)
+	h.activeTime = now
+	h.clientFailures++
+}
+
+// active records that the peer is talking with supernode.
+func (h *peerHealth) active() {
+	h.Lock()
+	defer h.Unlock()
+	h.activeTime = time.Now()
+}
+
+// score returns the health of the peer as a server in [0, 1].
+// A new peer starts with 1, and the score is the product of:
+//   - the success rate of serving pieces,
+//   - the penalty of the corrupted pieces and the times of being offline,
+//   - the factor of the average latency of serving a piece,
+//   - the freshness which falls from 1 to 0.5 when the peer isn't active.
+func (h *peerHealth) score() float64 {
+	h.Lock()
+	defer h.Unlock()
+	now := time.Now()
+	h.decay(now)
+
+	score := (h.successes + 1) / (h.successes + h.failures + 1)
+	score /= 1 + md5ErrorWeight*h.md5Errors
+	score /= 1 + h.downs
+
+	// the average latency is shrunk towards zero by a prior sample,
+	// so that the latency is forgotten when the peer doesn't serve for a while.
+	latency := h.latencySum / (h.latencyCount + 1)
+	score *= latencyBase.Seconds() / (latencyBase.Seconds() + latency)
+
+	if h.halfLife > 0 {
+		idle := now.Sub(h.activeTime)
+		score *= 0.5 + 0.5*math.Pow(0.5, float64(idle)/float64(h.halfLife))
+	}
+	return score
+}
+
+// clientScore returns the health of the peer as a client in [0, 1],
+// which is the success rate of downloading pieces from the other peers.
+func (h *peerHealth) clientScore() float64 {
+	h.Lock()
+	defer h.Unlock()
+	h.decay(time.Now())
+	return (h.clientSuccesses + 1) / (h.clientSuccesses + h.clientFailures + 1)
+}
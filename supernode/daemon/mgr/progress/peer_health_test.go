@@ -0,0 +1,173 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package progress
+
+import (
+	"context"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+
+	"github.com/go-check/check"
+)
+
+func init() {
+	check.Suite(&PeerHealthTestSuite{})
+}
+
+type PeerHealthTestSuite struct{}
+
+func (s *PeerHealthTestSuite) TestScore(c *check.C) {
+	h := newPeerHealth(time.Minute)
+	c.Check(h.score() > 0.99, check.Equals, true)
+	c.Check(h.clientScore(), check.Equals, 1.0)
+
+	h.serviceFailed()
+	failed := h.score()
+	c.Check(failed < 0.51 && failed > 0.49, check.Equals, true)
+	h.serviceSucceeded(0)
+	c.Check(h.score() > failed, check.Equals, true)
+
+	// a corrupted piece lowers the score more than a failure
+	h = newPeerHealth(time.Minute)
+	h.md5NotMatch()
+	c.Check(h.score() < failed, check.Equals, true)
+
+	h = newPeerHealth(time.Minute)
+	h.serviceDown()
+	c.Check(h.score() < 0.51, check.Equals, true)
+
+	// the slow peer gets a lower score
+	fast, slow := newPeerHealth(time.Minute), newPeerHealth(time.Minute)
+	fast.serviceSucceeded(100 * time.Millisecond)
+	slow.serviceSucceeded(10 * time.Second)
+	c.Check(fast.score() > slow.score(), check.Equals, true)
+
+	h = newPeerHealth(time.Minute)
+	for i := 0; i < 5; i++ {
+		h.clientFailed()
+	}
+	c.Check(h.clientScore() < config.DefaultPeerHealthThreshold, check.Equals, true)
+	c.Check(h.score() > 0.99, check.Equals, true)
+}
+
+func (s *PeerHealthTestSuite) TestDecay(c *check.C) {
+	h := newPeerHealth(time.Minute)
+	for i := 0; i < 5; i++ {
+		h.serviceFailed()
+	}
+	c.Check(h.score() < config.DefaultPeerHealthThreshold, check.Equals, true)
+
+	// the failures are forgotten gradually
+	h.decayTime = h.decayTime.Add(-time.Minute)
+	h.activeTime = time.Now()
+	score := h.score()
+	c.Check(h.failures > 2.49 && h.failures < 2.51, check.Equals, true)
+	c.Check(score > config.DefaultPeerHealthThreshold, check.Equals, true)
+
+	h.decayTime = h.decayTime.Add(-time.Hour)
+	h.activeTime = time.Now()
+	c.Check(h.score() > 0.99, check.Equals, true)
+}
+
+func (s *PeerHealthTestSuite) TestFreshness(c *check.C) {
+	h := newPeerHealth(time.Minute)
+	h.activeTime = h.activeTime.Add(-time.Minute)
+	score := h.score()
+	c.Check(score > 0.74 && score < 0.76, check.Equals, true)
+
+	// the idle peer is still healthy
+	h.activeTime = h.activeTime.Add(-time.Hour)
+	c.Check(h.score() > 0.49, check.Equals, true)
+
+	h.active()
+	c.Check(h.score() > 0.99, check.Equals, true)
+}
+
+func (s *PeerHealthTestSuite) TestUpdateHealth(c *check.C) {
+	ctx := context.Background()
+	cfg := config.NewConfig()
+	cfg.SetCIDPrefix("127.0.0.1")
+	pm, err := NewManager(cfg, nil)
+	c.Assert(err, check.IsNil)
+
+	c.Assert(pm.InitProgress(ctx, "taskID", "peerA", "cidA", config.P2pPattern, ""), check.IsNil)
+	c.Assert(pm.InitProgress(ctx, "taskID", "peerB", "cidB", config.P2pPattern, ""), check.IsNil)
+	c.Assert(pm.UpdateClientProgress(ctx, "taskID", "cidA", "peerB", 0, config.PieceRUNNING), check.IsNil)
+	c.Assert(pm.UpdateProgress(ctx, "taskID", "cidA", "peerA", "peerB", 0, config.PieceFAILED), check.IsNil)
+
+	peerA, err := pm.GetPeerStateByPeerID(ctx, "peerA")
+	c.Assert(err, check.IsNil)
+	peerB, err := pm.GetPeerStateByPeerID(ctx, "peerB")
+	c.Assert(err, check.IsNil)
+	c.Check(peerA.Score > 0.99, check.Equals, true)
+	c.Check(peerA.ClientScore < 1, check.Equals, true)
+	c.Check(peerB.Score < 1, check.Equals, true)
+	c.Check(peerB.ClientScore, check.Equals, 1.0)
+	c.Check(peerB.ServiceErrorCount.Get(), check.Equals, int32(1))
+
+	// the health is kept when the peer downloads another task
+	c.Assert(pm.InitProgress(ctx, "taskID2", "peerB", "cidB2", config.P2pPattern, ""), check.IsNil)
+	peerB, err = pm.GetPeerStateByPeerID(ctx, "peerB")
+	c.Assert(err, check.IsNil)
+	c.Check(peerB.Score < 1, check.Equals, true)
+
+	c.Assert(pm.ReportPeerError(ctx, "peerB", "TIMEOUT"), check.IsNil)
+	peerB2, err := pm.GetPeerStateByPeerID(ctx, "peerB")
+	c.Assert(err, check.IsNil)
+	c.Check(peerB2.Score < peerB.Score, check.Equals, true)
+
+	c.Assert(pm.UpdatePeerServiceDown(ctx, "peerB"), check.IsNil)
+	peerB, err = pm.GetPeerStateByPeerID(ctx, "peerB")
+	c.Assert(err, check.IsNil)
+	c.Check(peerB.Score, check.Equals, 0.0)
+}
+
+func (s *PeerHealthTestSuite) TestHedgedPiece(c *check.C) {
+	ctx := context.Background()
+	cfg := config.NewConfig()
+	cfg.SetCIDPrefix("127.0.0.1")
+	pm, err := NewManager(cfg, nil)
+	c.Assert(err, check.IsNil)
+
+	for _, peerID := range []string{"peerA", "peerB", "peerC"} {
+		c.Assert(pm.InitProgress(ctx, "taskID", peerID, "cid"+peerID[4:], config.P2pPattern, ""), check.IsNil)
+	}
+	c.Assert(pm.UpdateProgress(ctx, "taskID", "cidA", "peerA", "peerB", 0, config.PieceRUNNING), check.IsNil)
+	peerB, err := pm.GetPeerStateByPeerID(ctx, "peerB")
+	c.Assert(err, check.IsNil)
+	c.Check(peerB.ProducerLoad.Get(), check.Equals, int32(1))
+
+	// the hedged request to peerC wins, and the load reserved for peerB is released
+	c.Assert(pm.UpdateProgress(ctx, "taskID", "cidA", "peerA", "peerC", 0, config.PieceSUCCESS), check.IsNil)
+	peerB, err = pm.GetPeerStateByPeerID(ctx, "peerB")
+	c.Assert(err, check.IsNil)
+	c.Check(peerB.ProducerLoad.Get(), check.Equals, int32(0))
+	peerC, err := pm.GetPeerStateByPeerID(ctx, "peerC")
+	c.Assert(err, check.IsNil)
+	c.Check(peerC.ProducerLoad.Get(), check.Equals, int32(0))
+	c.Check(peerC.Score > 0.99, check.Equals, true)
+
+	// peerB is reported as slow, which isn't counted as an error
+	c.Assert(pm.ReportPeerError(ctx, "peerB", types.PieceErrorRequestErrorTypePIECESLOW), check.IsNil)
+	slowB, err := pm.GetPeerStateByPeerID(ctx, "peerB")
+	c.Assert(err, check.IsNil)
+	c.Check(slowB.Score < peerB.Score, check.Equals, true)
+	c.Check(slowB.Score > 0.49, check.Equals, true)
+	c.Check(slowB.ServiceErrorCount == nil || slowB.ServiceErrorCount.Get() == 0, check.Equals, true)
+}
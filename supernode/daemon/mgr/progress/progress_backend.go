@@ -0,0 +1,198 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package progress
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
+
+	"github.com/sirupsen/logrus"
+	"github.com/willf/bitset"
+)
+
+// flushProgressInterval is the interval to write the changed progress to the state backend.
+const flushProgressInterval = time.Second
+
+// The progress replicated by the state backend is the piece bitSet of every client
+// and the peers on which every piece exists, so that the client can continue
+// downloading from another supernode without losing the progress.
+// The other progress is rebuilt by every supernode when the peers register again.
+
+// markClientDirty marks the client progress to be written to the state backend.
+func (pm *Manager) markClientDirty(clientID string) {
+	if pm.backend == nil || pm.cfg.IsSuperCID(clientID) {
+		return
+	}
+	pm.dirtyClients.Store(clientID, true)
+}
+
+// markPieceDirty marks the piece progress to be written to the state backend.
+func (pm *Manager) markPieceDirty(key string) {
+	if pm.backend == nil {
+		return
+	}
+	pm.dirtyPieces.Store(key, true)
+}
+
+// StartFlushProgress starts to write the changed progress to the state backend
+// periodically with a new goroutine, and writes it for the last time when ctx is done.
+func (pm *Manager) StartFlushProgress(ctx context.Context) {
+	if pm.backend == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(flushProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				pm.flushProgress()
+				return
+			case <-ticker.C:
+				pm.flushProgress()
+			}
+		}
+	}()
+}
+
+// flushProgress writes the changed progress to the state backend and deletes
+// the removed one from it in a batch. The progress is marked as changed again
+// if the batch fails.
+func (pm *Manager) flushProgress() {
+	var (
+		ops     []*state.Op
+		clients []string
+		pieces  []string
+	)
+
+	pm.dirtyClients.Range(func(key, value interface{}) bool {
+		clientID := key.(string)
+		pm.dirtyClients.Delete(clientID)
+
+		cs, err := pm.clientProgress.getAsClientState(clientID)
+		if err != nil {
+			ops = append(ops, &state.Op{Bucket: state.BucketClientProgress, Key: clientID, Delete: true})
+			clients = append(clients, clientID)
+			return true
+		}
+		data, err := cs.pieceBitSet.Clone().MarshalJSON()
+		if err != nil {
+			logrus.Warnf("failed to encode client progress of clientID(%s): %v", clientID, err)
+			return true
+		}
+		ops = append(ops, &state.Op{Bucket: state.BucketClientProgress, Key: clientID, Value: data})
+		clients = append(clients, clientID)
+		return true
+	})
+
+	pm.dirtyPieces.Range(func(k, value interface{}) bool {
+		key := k.(string)
+		pm.dirtyPieces.Delete(key)
+
+		ps, err := pm.pieceProgress.getAsPieceState(key)
+		if err != nil {
+			ops = append(ops, &state.Op{Bucket: state.BucketPieceProgress, Key: key, Delete: true})
+			pieces = append(pieces, key)
+			return true
+		}
+		data, err := json.Marshal(ps.getAvailablePeers())
+		if err != nil {
+			logrus.Warnf("failed to encode piece progress of key(%s): %v", key, err)
+			return true
+		}
+		ops = append(ops, &state.Op{Bucket: state.BucketPieceProgress, Key: key, Value: data})
+		pieces = append(pieces, key)
+		return true
+	})
+
+	if len(ops) == 0 {
+		return
+	}
+	if err := pm.backend.Batch(ops); err != nil {
+		logrus.Warnf("failed to save the progress of %d clients and %d pieces: %v", len(clients), len(pieces), err)
+		for _, clientID := range clients {
+			pm.dirtyClients.Store(clientID, true)
+		}
+		for _, key := range pieces {
+			pm.dirtyPieces.Store(key, true)
+		}
+	}
+}
+
+func (pm *Manager) deleteFromBackend(bucket, key string) {
+	if err := pm.backend.Delete(bucket, key); err != nil && !errortypes.IsDataNotFound(err) {
+		logrus.Warnf("failed to delete key(%s) from bucket(%s): %v", key, bucket, err)
+	}
+}
+
+// restoreClientState returns the client progress saved in the state backend,
+// and a new one if not found.
+func (pm *Manager) restoreClientState(clientID string) *clientState {
+	cs := newClientState()
+	if pm.backend == nil {
+		return cs
+	}
+
+	data, err := pm.backend.Get(state.BucketClientProgress, clientID)
+	if err != nil {
+		return cs
+	}
+	pieceBitSet := &bitset.BitSet{}
+	if err := pieceBitSet.UnmarshalJSON(data); err != nil {
+		logrus.Warnf("failed to decode client progress of clientID(%s): %v", clientID, err)
+		return cs
+	}
+	cs.pieceBitSet = pieceBitSet
+	logrus.Infof("success to restore client progress of clientID(%s) with %d pieces", clientID, pieceBitSet.Count())
+	return cs
+}
+
+// restorePieceState loads the piece progress saved in the state backend
+// when it's missing in memory.
+func (pm *Manager) restorePieceState(key string) {
+	if pm.backend == nil {
+		return
+	}
+	if _, err := pm.pieceProgress.get(key); err == nil {
+		return
+	}
+
+	data, err := pm.backend.Get(state.BucketPieceProgress, key)
+	if err != nil {
+		return
+	}
+	var peerIDs []string
+	if err := json.Unmarshal(data, &peerIDs); err != nil {
+		logrus.Warnf("failed to decode piece progress of key(%s): %v", key, err)
+		return
+	}
+
+	ps := newPieceState()
+	for _, peerID := range peerIDs {
+		ps.add(peerID)
+		// the peers registered on another supernode are treated as healthy ones
+		if _, err := pm.peerProgress.get(peerID); err != nil {
+			pm.peerProgress.LoadOrStore(peerID, newPeerState(pm.cfg.PeerHealthHalfLife))
+		}
+	}
+	pm.pieceProgress.LoadOrStore(key, ps)
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package progress
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/timeutils"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
+
+	"github.com/go-check/check"
+)
+
+func init() {
+	check.Suite(&ProgressBackendTestSuite{})
+}
+
+type ProgressBackendTestSuite struct {
+}
+
+func (s *ProgressBackendTestSuite) TestRestoreProgress(c *check.C) {
+	ctx := context.Background()
+	backend := state.NewMemoryBackend()
+
+	cfg1 := config.NewConfig()
+	cfg1.SetCIDPrefix("192.168.0.1")
+	pm1, err := NewManager(cfg1, backend)
+	c.Assert(err, check.IsNil)
+
+	c.Assert(pm1.InitProgress(ctx, "taskID", "peer1", "cid1", config.P2pPattern, ""), check.IsNil)
+	c.Assert(pm1.InitProgress(ctx, "taskID", "peer2", "cid2", config.P2pPattern, ""), check.IsNil)
+	c.Assert(pm1.UpdateProgress(ctx, "taskID", "cid1", "peer1", "peer2", 0, config.PieceSUCCESS), check.IsNil)
+	c.Assert(pm1.UpdateProgress(ctx, "taskID", "cid1", "peer1", "peer2", 1, config.PieceSUCCESS), check.IsNil)
+	pm1.flushProgress()
+
+	// another supernode restores the progress of the client
+	cfg2 := config.NewConfig()
+	cfg2.SetCIDPrefix("192.168.0.2")
+	pm2, err := NewManager(cfg2, backend)
+	c.Assert(err, check.IsNil)
+
+	c.Assert(pm2.InitProgress(ctx, "taskID", "peer3", "cid1", config.P2pPattern, ""), check.IsNil)
+	cs, err := pm2.clientProgress.getAsClientState("cid1")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.pieceBitSet.Test(uint(getStartIndexByPieceNum(0)+config.PieceSUCCESS)), check.Equals, true)
+	c.Check(cs.pieceBitSet.Test(uint(getStartIndexByPieceNum(1)+config.PieceSUCCESS)), check.Equals, true)
+
+	peerIDs, err := pm2.GetPeerIDsByPieceNum(ctx, "taskID", 1)
+	c.Assert(err, check.IsNil)
+	c.Check(peerIDs, check.DeepEquals, []string{"peer1"})
+	_, err = pm2.GetPeerStateByPeerID(ctx, "peer1")
+	c.Check(err, check.IsNil)
+
+	// the deleted progress is removed from the backend
+	c.Assert(pm1.DeleteCID(ctx, "cid1"), check.IsNil)
+	c.Assert(pm1.DeleteTaskID(ctx, "taskID", 2), check.IsNil)
+	pm1.flushProgress()
+	_, err = backend.Get(state.BucketClientProgress, "cid1")
+	c.Check(errortypes.IsDataNotFound(err), check.Equals, true)
+	_, err = backend.Get(state.BucketPieceProgress, "1@taskID")
+	c.Check(errortypes.IsDataNotFound(err), check.Equals, true)
+}
+
+// countingBackend counts the writes to the backend.
+type countingBackend struct {
+	*state.MemoryBackend
+	sync.Mutex
+	puts    int
+	batches int
+}
+
+func (b *countingBackend) Put(bucket, key string, value []byte) error {
+	b.Lock()
+	b.puts++
+	b.Unlock()
+	return b.MemoryBackend.Put(bucket, key, value)
+}
+
+func (b *countingBackend) Batch(ops []*state.Op) error {
+	b.Lock()
+	b.batches++
+	b.Unlock()
+	return b.MemoryBackend.Batch(ops)
+}
+
+func (b *countingBackend) counts() (int, int) {
+	b.Lock()
+	defer b.Unlock()
+	return b.puts, b.batches
+}
+
+func (s *ProgressBackendTestSuite) TestFlushProgress(c *check.C) {
+	ctx := context.Background()
+	backend := &countingBackend{MemoryBackend: state.NewMemoryBackend()}
+	cfg := config.NewConfig()
+	cfg.SetCIDPrefix("192.168.0.1")
+	pm, err := NewManager(cfg, backend)
+	c.Assert(err, check.IsNil)
+
+	c.Assert(pm.InitProgress(ctx, "taskID", "peer1", "cid1", config.P2pPattern, ""), check.IsNil)
+	c.Assert(pm.InitProgress(ctx, "taskID", "peer2", "cid2", config.P2pPattern, ""), check.IsNil)
+	for i := 0; i < 10; i++ {
+		c.Assert(pm.UpdateProgress(ctx, "taskID", "cid1", "peer1", "peer2", i, config.PieceSUCCESS), check.IsNil)
+	}
+
+	// all the changed progress is written in a batch when ctx is done
+	flushCtx, cancel := context.WithCancel(ctx)
+	pm.StartFlushProgress(flushCtx)
+	cancel()
+	for i := 0; i < 100; i++ {
+		if _, batches := backend.counts(); batches > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	puts, batches := backend.counts()
+	c.Check(puts, check.Equals, 0)
+	c.Check(batches, check.Equals, 1)
+	pieces, err := backend.List(state.BucketPieceProgress)
+	c.Assert(err, check.IsNil)
+	c.Check(pieces, check.HasLen, 10)
+	_, err = backend.Get(state.BucketClientProgress, "cid1")
+	c.Check(err, check.IsNil)
+
+	// the loop is stopped
+	c.Assert(pm.UpdateProgress(ctx, "taskID", "cid1", "peer1", "peer2", 10, config.PieceSUCCESS), check.IsNil)
+	time.Sleep(flushProgressInterval + 100*time.Millisecond)
+	_, batches = backend.counts()
+	c.Check(batches, check.Equals, 1)
+
+	// nothing is written if nothing changed
+	pm.flushProgress()
+	pm.flushProgress()
+	_, batches = backend.counts()
+	c.Check(batches, check.Equals, 2)
+}
+
+func (s *ProgressBackendTestSuite) TestQuarantinePeer(c *check.C) {
+	ctx := context.Background()
+	backend := state.NewMemoryBackend()
+
+	pm1, err := NewManager(config.NewConfig(), backend)
+	c.Assert(err, check.IsNil)
+	pm2, err := NewManager(config.NewConfig(), backend)
+	c.Assert(err, check.IsNil)
+
+	now := timeutils.GetCurrentTimeMillis()
+	c.Assert(pm1.QuarantinePeer(ctx, "127.0.0.1:15001", now+60*1000), check.IsNil)
+	c.Assert(pm1.QuarantinePeer(ctx, "127.0.0.1:15002", now-1), check.IsNil)
+	c.Check(pm1.QuarantinePeer(ctx, "", now), check.NotNil)
+
+	// the quarantine takes effect on the other supernodes too
+	for _, pm := range []*Manager{pm1, pm2} {
+		c.Check(pm.IsPeerQuarantined(ctx, "127.0.0.1:15001"), check.Equals, true)
+		c.Check(pm.IsPeerQuarantined(ctx, "127.0.0.1:15002"), check.Equals, false)
+		c.Check(pm.IsPeerQuarantined(ctx, "127.0.0.1:15003"), check.Equals, false)
+	}
+
+	// the expired quarantine is removed from the backend
+	_, err = backend.Get(state.BucketQuarantine, "127.0.0.1:15002")
+	c.Check(errortypes.IsDataNotFound(err), check.Equals, true)
+}
@@ -27,6 +27,7 @@ import (
 // DeleteTaskID deletes the super progress with specified taskID.
 func (pm *Manager) DeleteTaskID(ctx context.Context, taskID string, pieceTotal int) (err error) {
 	pm.superLoad.remove(taskID)
+	pm.seeds.remove(taskID)
 	pm.superProgress.remove(taskID)
 
 	for i := 0; i < pieceTotal; i++ {
@@ -35,12 +36,14 @@ func (pm *Manager) DeleteTaskID(ctx context.Context, taskID string, pieceTotal i
 			return err
 		}
 		pm.pieceProgress.remove(key)
+		pm.markPieceDirty(key)
 	}
 	return nil
 }
 
 // DeleteCID deletes the client progress with specified clientID.
 func (pm *Manager) DeleteCID(ctx context.Context, clientID string) (err error) {
+	pm.markClientDirty(clientID)
 	return pm.clientProgress.remove(clientID)
 }
 
@@ -51,6 +54,7 @@ func (pm *Manager) DeletePeerID(ctx context.Context, peerID string) error {
 	// So we leave it to be deleted when scheduled.
 	pm.deletePeerIDFromPeerProgress(ctx, peerID)
 	pm.deletePeerIDFromBlackInfo(ctx, peerID)
+	pm.deletePeerIDFromSeeds(peerID)
 
 	return nil
 }
@@ -92,10 +96,14 @@ func (pm *Manager) deletePeerIDByPieceNum(ctx context.Context, taskID string, pi
 // the peer no longer provides the service for the pieceNum of taskID.
 func (pm *Manager) deletePeerIDByPieceProgressKey(ctx context.Context, pieceProgressKey string, peerID string) error {
 	ps, err := pm.pieceProgress.getAsPieceState(pieceProgressKey)
-	if err != nil && !errortypes.IsDataNotFound(err) {
+	if err != nil {
+		if errortypes.IsDataNotFound(err) {
+			return nil
+		}
 		return err
 	}
 
 	ps.delete(peerID)
+	pm.markPieceDirty(pieceProgressKey)
 	return nil
 }
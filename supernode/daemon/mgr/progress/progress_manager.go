@@ -19,14 +19,17 @@ package progress
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/atomiccount"
 	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
 	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
 	"github.com/dragonflyoss/Dragonfly/pkg/syncmap"
 	"github.com/dragonflyoss/Dragonfly/pkg/timeutils"
 	"github.com/dragonflyoss/Dragonfly/supernode/config"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -75,11 +78,26 @@ type Manager struct {
 	// key:taskID string, value:superLoadState *superLoadState
 	superLoad *stateSyncMap
 
+	// seeds maintains the peers elected as seed nodes for each task.
+	// key:taskID string, value:seedState *seedState
+	seeds *stateSyncMap
+
+	// quarantine maintains the peer servers which are kept from serving the other peers.
+	// key:peerAddr string, value:expireTime int64
+	quarantine *syncmap.SyncMap
+
+	// backend replicates the client progress and piece progress, it's optional.
+	backend state.Backend
+
+	// dirtyClients and dirtyPieces maintain the progress to be written to the backend.
+	dirtyClients *syncmap.SyncMap
+	dirtyPieces  *syncmap.SyncMap
+
 	cfg *config.Config
 }
 
 // NewManager returns a new Manager.
-func NewManager(cfg *config.Config) (*Manager, error) {
+func NewManager(cfg *config.Config, backend state.Backend) (*Manager, error) {
 	manager := &Manager{
 		cfg:             cfg,
 		superProgress:   newStateSyncMap(),
@@ -88,6 +106,11 @@ func NewManager(cfg *config.Config) (*Manager, error) {
 		pieceProgress:   newStateSyncMap(),
 		clientBlackInfo: syncmap.NewSyncMap(),
 		superLoad:       newStateSyncMap(),
+		seeds:           newStateSyncMap(),
+		quarantine:      syncmap.NewSyncMap(),
+		backend:         backend,
+		dirtyClients:    syncmap.NewSyncMap(),
+		dirtyPieces:     syncmap.NewSyncMap(),
 	}
 
 	manager.startMonitorSuperLoad()
@@ -95,7 +118,7 @@ func NewManager(cfg *config.Config) (*Manager, error) {
 }
 
 // InitProgress inits the correlation information between peers and pieces, etc.
-func (pm *Manager) InitProgress(ctx context.Context, taskID, peerID, clientID string, peerPattern config.Pattern) (err error) {
+func (pm *Manager) InitProgress(ctx context.Context, taskID, peerID, clientID string, peerPattern config.Pattern, tenant string) (err error) {
 	// validate the param
 	if stringutils.IsEmptyStr(taskID) {
 		return errors.Wrap(errortypes.ErrEmptyValue, "taskID")
@@ -113,7 +136,8 @@ func (pm *Manager) InitProgress(ctx context.Context, taskID, peerID, clientID st
 	}
 
 	// init peer node if the clientID represents a ordinary peer node.
-	if err := pm.clientProgress.add(clientID, newClientState()); err != nil {
+	// The progress will be restored if the client has registered on another supernode.
+	if err := pm.clientProgress.add(clientID, pm.restoreClientState(clientID)); err != nil {
 		return err
 	}
 	defer func() {
@@ -123,12 +147,19 @@ func (pm *Manager) InitProgress(ctx context.Context, taskID, peerID, clientID st
 			}
 		}
 	}()
+	ps := newPeerState(pm.cfg.PeerHealthHalfLife)
 	if peerPattern != config.P2pPattern {
 		logrus.Infof("peer pattern not p2p taskID(%s),peerID(%s),clientID(%s)", taskID, peerID, clientID)
-		return pm.peerProgress.add(peerID, newCdnPeerState())
+		ps = newCdnPeerState(pm.cfg.PeerHealthHalfLife)
+	}
+	ps.tenant = tenant
+	// the health is kept when the peer starts to download another task.
+	if old, err := pm.peerProgress.getAsPeerState(peerID); err == nil && old.health != nil {
+		ps.health = old.health
+		ps.health.active()
 	}
 
-	return pm.peerProgress.add(peerID, newPeerState())
+	return pm.peerProgress.add(peerID, ps)
 }
 
 // UpdateProgress updates the correlation information between peers and pieces.
@@ -144,6 +175,9 @@ func (pm *Manager) UpdateProgress(ctx context.Context, taskID, srcCID, srcPID, d
 		return errors.Wrapf(errortypes.ErrEmptyValue, "srcPID for taskID:%s", taskID)
 	}
 
+	// the peer reporting the progress is alive.
+	pm.markPeerActive(srcPID)
+
 	// Step1: update the PieceProgress
 	// Add one more peer for this piece when the srcPID successfully downloads the piece.
 	if pieceStatus == config.PieceSUCCESS {
@@ -157,6 +191,14 @@ func (pm *Manager) UpdateProgress(ctx context.Context, taskID, srcCID, srcPID, d
 	}
 
 	// Step2: update the clientProgress and superProgress
+	// The latency and the scheduled peer are got before the running piece is cleared.
+	latency := pm.getPieceLatency(srcCID, pieceNum)
+	loadPID := dstPID
+	if pieceStatus != config.PieceRUNNING {
+		if scheduledPID := pm.getScheduledPeer(srcCID, pieceNum); scheduledPID != "" {
+			loadPID = scheduledPID
+		}
+	}
 	result, err := pm.updateClientProgress(taskID, srcCID, dstPID, pieceNum, pieceStatus)
 	if err != nil {
 		logrus.Errorf("failed to update ClientProgress taskID(%s) srcCID(%s) dstPID(%s) pieceNum(%d) pieceStatus(%d): %v",
@@ -173,7 +215,7 @@ func (pm *Manager) UpdateProgress(ctx context.Context, taskID, srcCID, srcPID, d
 	}
 
 	// Step3: update the peerProgress
-	if err := pm.updatePeerProgress(taskID, srcPID, dstPID, pieceNum, pieceStatus); err != nil {
+	if err := pm.updatePeerProgress(taskID, srcPID, dstPID, loadPID, pieceNum, pieceStatus, latency); err != nil {
 		logrus.Errorf("failed to update PeerProgress taskID(%s) srcCID(%s) dstPID(%s) pieceNum(%d) pieceStatus(%d): %v",
 			taskID, srcCID, dstPID, pieceNum, pieceStatus, err)
 		return err
@@ -237,6 +279,25 @@ func (pm *Manager) GetPieceProgressByCID(ctx context.Context, taskID, clientID,
 	return getAvailablePieces(clientBitset, cdnBitset, runningPieces)
 }
 
+// GetRunningPiecesByCID gets the pieces being downloaded by the specified clientID
+// and the peers which they are downloaded from.
+func (pm *Manager) GetRunningPiecesByCID(ctx context.Context, clientID string) (map[int]string, error) {
+	cs, err := pm.clientProgress.getAsClientState(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	runningPieces := make(map[int]string)
+	for _, pieceNum := range cs.runningPiece.ListKeyAsIntSlice() {
+		dstPID, err := cs.runningPiece.GetAsString(strconv.Itoa(pieceNum))
+		if err != nil {
+			continue
+		}
+		runningPieces[pieceNum] = dstPID
+	}
+	return runningPieces, nil
+}
+
 // GetPeerIDsByPieceNum gets all peerIDs with specified taskID and pieceNum.
 // It will return nil when no peers are available.
 func (pm *Manager) GetPeerIDsByPieceNum(ctx context.Context, taskID string, pieceNum int) (peerIDs []string, err error) {
@@ -244,6 +305,7 @@ func (pm *Manager) GetPeerIDsByPieceNum(ctx context.Context, taskID string, piec
 	if err != nil {
 		return nil, err
 	}
+	pm.restorePieceState(key)
 	ps, err := pm.pieceProgress.getAsPieceState(key)
 	if err != nil {
 		return nil, err
@@ -259,14 +321,25 @@ func (pm *Manager) GetPeerStateByPeerID(ctx context.Context, peerID string) (*mg
 		return nil, err
 	}
 
-	return &mgr.PeerState{
+	result := &mgr.PeerState{
 		PeerID:            peerID,
 		ServiceDownTime:   peerState.serviceDownTime,
 		ClientErrorCount:  peerState.clientErrorCount,
 		ServiceErrorCount: peerState.serviceErrorCount,
 		ProducerLoad:      peerState.producerLoad,
 		PeerPattern:       peerState.peerPattern,
-	}, nil
+		Tenant:            peerState.tenant,
+		Score:             1,
+		ClientScore:       1,
+	}
+	if peerState.health != nil {
+		result.Score = peerState.health.score()
+		result.ClientScore = peerState.health.clientScore()
+	}
+	if peerState.serviceDownTime > 0 {
+		result.Score = 0
+	}
+	return result, nil
 }
 
 // UpdatePeerServiceDown does update operation when a peer server offline.
@@ -281,6 +354,43 @@ func (pm *Manager) UpdatePeerServiceDown(ctx context.Context, peerID string) (er
 	}
 
 	peerState.serviceDownTime = timeutils.GetCurrentTimeMillis()
+	if peerState.health != nil {
+		peerState.health.serviceDown()
+	}
+	pm.deletePeerIDFromSeeds(peerID)
+	return nil
+}
+
+// ReportPeerError records an error that the other peers ran into when downloading
+// from the peer. The error is counted in addition to the failed piece reported by
+// the downloader, because it's confirmed to be caused by the peer.
+// A slow piece only lowers the health of the peer and isn't counted as an error.
+func (pm *Manager) ReportPeerError(ctx context.Context, peerID, errorType string) error {
+	peerState, err := pm.peerProgress.getAsPeerState(peerID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get peer state peerID(%s)", peerID)
+	}
+
+	if errorType == types.PieceErrorRequestErrorTypePIECESLOW {
+		if peerState.health != nil {
+			peerState.health.serviceSlow()
+		}
+		logrus.Debugf("peerID(%s) is too slow to serve a piece", peerID)
+		return nil
+	}
+
+	if peerState.serviceErrorCount == nil {
+		peerState.serviceErrorCount = atomiccount.NewAtomicInt(0)
+	}
+	count := peerState.serviceErrorCount.Add(1)
+	if peerState.health != nil {
+		if errorType == types.PieceErrorRequestErrorTypeFILEMD5NOTMATCH {
+			peerState.health.md5NotMatch()
+		} else {
+			peerState.health.serviceFailed()
+		}
+	}
+	logrus.Debugf("peerID(%s) served with error %s, service error count: %d", peerID, errorType, count)
 	return nil
 }
 
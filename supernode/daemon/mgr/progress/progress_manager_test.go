@@ -17,9 +17,11 @@
 package progress
 
 import (
+	"context"
 	"testing"
 
 	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
 
 	"github.com/go-check/check"
 	"github.com/willf/bitset"
@@ -100,3 +102,34 @@ func (s *ProgressManagerTestSuite) TestGetAvailablePieces(c *check.C) {
 		c.Check(result, check.DeepEquals, v.expected)
 	}
 }
+
+func (s *ProgressManagerTestSuite) TestGetRunningPiecesAndSuperLoad(c *check.C) {
+	ctx := context.Background()
+	cfg := config.NewConfig()
+	cfg.SetCIDPrefix("127.0.0.1")
+	pm, err := NewManager(cfg, nil)
+	c.Assert(err, check.IsNil)
+
+	c.Assert(pm.InitProgress(ctx, "taskID", "peerA", "cidA", config.P2pPattern, ""), check.IsNil)
+	c.Assert(pm.InitProgress(ctx, "taskID", "peerB", "cidB", config.P2pPattern, ""), check.IsNil)
+	c.Assert(pm.UpdateClientProgress(ctx, "taskID", "cidA", "peerB", 0, config.PieceRUNNING), check.IsNil)
+	c.Assert(pm.UpdateClientProgress(ctx, "taskID", "cidA", "peerC", 1, config.PieceRUNNING), check.IsNil)
+	c.Assert(pm.UpdateClientProgress(ctx, "taskID", "cidA", "peerC", 1, config.PieceSUCCESS), check.IsNil)
+
+	runningPieces, err := pm.GetRunningPiecesByCID(ctx, "cidA")
+	c.Assert(err, check.IsNil)
+	c.Assert(runningPieces, check.DeepEquals, map[int]string{0: "peerB"})
+
+	_, err = pm.GetRunningPiecesByCID(ctx, "foo")
+	c.Assert(errortypes.IsDataNotFound(err), check.Equals, true)
+
+	load, err := pm.GetSuperLoad(ctx, "taskID")
+	c.Assert(err, check.IsNil)
+	c.Assert(load, check.Equals, int32(0))
+	updated, err := pm.UpdateSuperLoad(ctx, "taskID", 2, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(updated, check.Equals, true)
+	load, err = pm.GetSuperLoad(ctx, "taskID")
+	c.Assert(err, check.IsNil)
+	c.Assert(load, check.Equals, int32(2))
+}
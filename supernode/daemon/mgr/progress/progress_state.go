@@ -41,6 +41,11 @@ type clientState struct {
 	// runningPiece maintains the pieces currently being downloaded from dstCID to srcCID.
 	// key:pieceNum,value:dstPID
 	runningPiece *syncmap.SyncMap
+
+	// runningStartTime maintains when the running pieces were scheduled
+	// to measure the latency of the peers serving them.
+	// key:pieceNum,value:time.Time
+	runningStartTime *syncmap.SyncMap
 }
 
 type peerState struct {
@@ -66,6 +71,12 @@ type peerState struct {
 
 	// ServicePattern default 0 is p2p, 1 is cdn.
 	peerPattern config.Pattern
+
+	// tenant is the tenant which the peer belongs to.
+	tenant string
+
+	// health maintains the events which decide the health score of the peer.
+	health *peerHealth
 }
 
 type superLoadState struct {
@@ -84,26 +95,29 @@ func newSuperState() *superState {
 
 func newClientState() *clientState {
 	return &clientState{
-		pieceBitSet:  &bitset.BitSet{},
-		runningPiece: syncmap.NewSyncMap(),
+		pieceBitSet:      &bitset.BitSet{},
+		runningPiece:     syncmap.NewSyncMap(),
+		runningStartTime: syncmap.NewSyncMap(),
 	}
 }
 
-func newPeerState() *peerState {
+func newPeerState(halfLife time.Duration) *peerState {
 	return &peerState{
 		producerLoad:      atomiccount.NewAtomicInt(0),
 		clientErrorCount:  atomiccount.NewAtomicInt(0),
 		serviceErrorCount: atomiccount.NewAtomicInt(0),
 		peerPattern:       config.P2pPattern,
+		health:            newPeerHealth(halfLife),
 	}
 }
 
-func newCdnPeerState() *peerState {
+func newCdnPeerState(halfLife time.Duration) *peerState {
 	return &peerState{
 		producerLoad:      atomiccount.NewAtomicInt(0),
 		clientErrorCount:  atomiccount.NewAtomicInt(0),
 		serviceErrorCount: atomiccount.NewAtomicInt(0),
 		peerPattern:       config.CdnPattern,
+		health:            newPeerHealth(halfLife),
 	}
 }
 
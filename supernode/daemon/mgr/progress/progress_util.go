@@ -19,6 +19,7 @@ package progress
 import (
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/dragonflyoss/Dragonfly/pkg/atomiccount"
 	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
@@ -38,6 +39,7 @@ func (pm *Manager) updatePieceProgress(taskID, srcPID string, pieceNum int) erro
 		return err
 	}
 
+	pm.restorePieceState(key)
 	pstate, err := pm.pieceProgress.getAsPieceState(key)
 	if err != nil {
 		if !errortypes.IsDataNotFound(err) {
@@ -61,7 +63,11 @@ func (pm *Manager) updatePieceProgress(taskID, srcPID string, pieceNum int) erro
 		return nil
 	}
 
-	return pstate.add(srcPID)
+	if err := pstate.add(srcPID); err != nil {
+		return err
+	}
+	pm.markPieceDirty(key)
+	return nil
 }
 
 // updateClientProgress updates the client progress when clientID is not a supernode,
@@ -86,8 +92,13 @@ func (pm *Manager) updateClientProgress(taskID, srcCID, dstPID string, pieceNum,
 	if err != nil {
 		return false, err
 	}
+	updateRunningStartTime(cs.runningStartTime, pieceNum, pieceStatus)
 
-	return updatePieceBitSet(cs.pieceBitSet, pieceNum, pieceStatus), nil
+	updated := updatePieceBitSet(cs.pieceBitSet, pieceNum, pieceStatus)
+	if updated {
+		pm.markClientDirty(srcCID)
+	}
+	return updated, nil
 }
 
 // updateRunningPiece updates the relationship between the running piece and srcCID and dstPID,
@@ -109,6 +120,58 @@ func updateRunningPiece(dstPIDMap *syncmap.SyncMap, srcCID, dstPID string, piece
 	return nil
 }
 
+// markPeerActive records that the peer is talking with supernode.
+func (pm *Manager) markPeerActive(peerID string) {
+	ps, err := pm.peerProgress.getAsPeerState(peerID)
+	if err != nil || ps.health == nil {
+		return
+	}
+	ps.health.active()
+}
+
+// updateRunningStartTime records the time when the piece is scheduled to be downloaded,
+// and clears it when the piece is no longer running.
+func updateRunningStartTime(startTimeMap *syncmap.SyncMap, pieceNum, pieceStatus int) {
+	if startTimeMap == nil {
+		return
+	}
+	pieceNumString := strconv.Itoa(pieceNum)
+	if pieceStatus == config.PieceRUNNING {
+		startTimeMap.Add(pieceNumString, time.Now())
+		return
+	}
+	startTimeMap.Delete(pieceNumString)
+}
+
+// getPieceLatency returns the time since the piece was scheduled to srcCID,
+// it's 0 if the piece isn't running.
+func (pm *Manager) getPieceLatency(srcCID string, pieceNum int) time.Duration {
+	cs, err := pm.clientProgress.getAsClientState(srcCID)
+	if err != nil || cs.runningStartTime == nil {
+		return 0
+	}
+	startTime, err := cs.runningStartTime.GetAsTime(strconv.Itoa(pieceNum))
+	if err != nil {
+		return 0
+	}
+	return time.Since(startTime)
+}
+
+// getScheduledPeer returns the peer which the running piece of srcCID is scheduled to,
+// and the load of the piece is reserved for it. It's different from the peer the result
+// is reported against if a hedged request to another peer wins.
+func (pm *Manager) getScheduledPeer(srcCID string, pieceNum int) string {
+	cs, err := pm.clientProgress.getAsClientState(srcCID)
+	if err != nil {
+		return ""
+	}
+	dstPID, err := cs.runningPiece.GetAsString(strconv.Itoa(pieceNum))
+	if err != nil {
+		return ""
+	}
+	return dstPID
+}
+
 // updatePieceBitSet adds a new piece for srcCID when it successfully downloads the piece.
 func updatePieceBitSet(pieceBitSet *bitset.BitSet, pieceNum, pieceStatus int) bool {
 	if pieceBitSet.Test(uint(getStartIndexByPieceNum(pieceNum) + config.PieceSUCCESS)) {
@@ -131,21 +194,31 @@ func updatePieceBitSet(pieceBitSet *bitset.BitSet, pieceNum, pieceStatus int) bo
 	return true
 }
 
-// updatePeerProgress updates the peer progress.
-func (pm *Manager) updatePeerProgress(taskID, srcPID, dstPID string, pieceNum, pieceStatus int) error {
-	var dstPeerState *peerState
-
-	// update producerLoad of dstPID
-	if !stringutils.IsEmptyStr(dstPID) {
-		dstPeerState, err := pm.peerProgress.getAsPeerState(dstPID)
+// updatePeerProgress updates the peer progress, the health of srcPID and dstPID
+// with the latency of the piece, and the load of loadPID.
+func (pm *Manager) updatePeerProgress(taskID, srcPID, dstPID, loadPID string, pieceNum, pieceStatus int, latency time.Duration) error {
+	// update producerLoad of loadPID, which the load of the piece is reserved for
+	if !stringutils.IsEmptyStr(loadPID) {
+		loadPeerState, err := pm.peerProgress.getAsPeerState(loadPID)
 		if err != nil && !errortypes.IsDataNotFound(err) {
 			return err
 		}
 		if err == nil {
-			if dstPeerState.producerLoad == nil {
-				dstPeerState.producerLoad = atomiccount.NewAtomicInt(0)
+			if loadPeerState.producerLoad == nil {
+				loadPeerState.producerLoad = atomiccount.NewAtomicInt(0)
 			}
-			updateProducerLoad(dstPeerState.producerLoad, taskID, dstPID, pieceNum, pieceStatus)
+			updateProducerLoad(loadPeerState.producerLoad, taskID, loadPID, pieceNum, pieceStatus)
+		}
+	}
+
+	var (
+		dstPeerState *peerState
+		err          error
+	)
+	if !stringutils.IsEmptyStr(dstPID) {
+		dstPeerState, err = pm.peerProgress.getAsPeerState(dstPID)
+		if err != nil && !errortypes.IsDataNotFound(err) {
+			return err
 		}
 	}
 
@@ -161,16 +234,38 @@ func (pm *Manager) updatePeerProgress(taskID, srcPID, dstPID string, pieceNum, p
 	// update ClientErrorInfo/serviceErrorInfo
 	if pieceStatus == config.PieceSUCCESS || pieceStatus == config.PieceSEMISUC {
 		processPeerSucInfo(srcPeerState, dstPeerState)
+		processPeerSucHealth(srcPeerState, dstPeerState, latency)
 	}
 	if pieceStatus == config.PieceFAILED {
 		if err := pm.updateBlackInfo(srcPID, dstPID); err != nil {
 			return err
 		}
 		processPeerFailInfo(srcPeerState, dstPeerState)
+		processPeerFailHealth(srcPeerState, dstPeerState)
 	}
 	return nil
 }
 
+// processPeerSucHealth records the success in the health of srcPeerState and dstPeerState.
+func processPeerSucHealth(srcPeerState, dstPeerState *peerState, latency time.Duration) {
+	if srcPeerState != nil && srcPeerState.health != nil {
+		srcPeerState.health.clientSucceeded()
+	}
+	if dstPeerState != nil && dstPeerState.health != nil {
+		dstPeerState.health.serviceSucceeded(latency)
+	}
+}
+
+// processPeerFailHealth records the failure in the health of srcPeerState and dstPeerState.
+func processPeerFailHealth(srcPeerState, dstPeerState *peerState) {
+	if srcPeerState != nil && srcPeerState.health != nil {
+		srcPeerState.health.clientFailed()
+	}
+	if dstPeerState != nil && dstPeerState.health != nil {
+		dstPeerState.health.serviceFailed()
+	}
+}
+
 func (pm *Manager) updateBlackInfo(srcPID, dstPID string) error {
 	// update black List
 	blackList, err := pm.clientBlackInfo.GetAsMap(srcPID)
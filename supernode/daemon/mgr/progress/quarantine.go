@@ -0,0 +1,82 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package progress
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
+	"github.com/dragonflyoss/Dragonfly/pkg/timeutils"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// The quarantined peers are written to the state backend at once, so that
+// the other supernodes of the cluster stop scheduling them too.
+
+// QuarantinePeer keeps the peer server at peerAddr(ip:port) from being scheduled
+// to serve the other peers until the expireTime in milliseconds.
+func (pm *Manager) QuarantinePeer(ctx context.Context, peerAddr string, expireTime int64) error {
+	if stringutils.IsEmptyStr(peerAddr) {
+		return errors.Wrap(errortypes.ErrEmptyValue, "peerAddr")
+	}
+
+	pm.quarantine.Store(peerAddr, expireTime)
+	if pm.backend == nil {
+		return nil
+	}
+	if err := pm.backend.Put(state.BucketQuarantine, peerAddr, []byte(strconv.FormatInt(expireTime, 10))); err != nil {
+		return errors.Wrapf(err, "failed to save the quarantine of peer(%s)", peerAddr)
+	}
+	return nil
+}
+
+// IsPeerQuarantined returns whether the peer server at peerAddr(ip:port) is quarantined now.
+func (pm *Manager) IsPeerQuarantined(ctx context.Context, peerAddr string) bool {
+	now := timeutils.GetCurrentTimeMillis()
+	if v, ok := pm.quarantine.Load(peerAddr); ok {
+		if expireTime, ok := v.(int64); ok && expireTime > now {
+			return true
+		}
+		pm.quarantine.Delete(peerAddr)
+	}
+	if pm.backend == nil {
+		return false
+	}
+
+	// the peer may be quarantined by another supernode
+	data, err := pm.backend.Get(state.BucketQuarantine, peerAddr)
+	if err != nil {
+		return false
+	}
+	expireTime, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		logrus.Warnf("failed to decode the quarantine of peer(%s): %v", peerAddr, err)
+		pm.deleteFromBackend(state.BucketQuarantine, peerAddr)
+		return false
+	}
+	if expireTime <= now {
+		pm.deleteFromBackend(state.BucketQuarantine, peerAddr)
+		return false
+	}
+	pm.quarantine.Store(peerAddr, expireTime)
+	return true
+}
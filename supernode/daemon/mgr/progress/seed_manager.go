@@ -0,0 +1,129 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package progress
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
+
+	"github.com/pkg/errors"
+)
+
+// seedState maintains the peers elected as seed nodes for a task.
+type seedState struct {
+	sync.RWMutex
+	// peerIDs are kept in the order of rank.
+	peerIDs []string
+	// elected is whether the seeds have been elected for the task.
+	elected bool
+}
+
+func newSeedState() *seedState {
+	return &seedState{}
+}
+
+func (ss *seedState) indexOf(peerID string) int {
+	for i, v := range ss.peerIDs {
+		if v == peerID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (ss *seedState) delete(peerID string) {
+	ss.Lock()
+	defer ss.Unlock()
+	if i := ss.indexOf(peerID); i >= 0 {
+		ss.peerIDs = append(ss.peerIDs[:i], ss.peerIDs[i+1:]...)
+	}
+}
+
+// UpdateSeeds replaces the seed nodes of taskID with the ones returned by elect.
+// The elect is called with the current seeds and whether they have been elected
+// before, and the updates of the same task are serialized.
+func (pm *Manager) UpdateSeeds(ctx context.Context, taskID string, elect func(peerIDs []string, elected bool) []string) ([]string, error) {
+	if stringutils.IsEmptyStr(taskID) {
+		return nil, errors.Wrap(errortypes.ErrEmptyValue, "taskID")
+	}
+
+	v, _ := pm.seeds.LoadOrStore(taskID, newSeedState())
+	ss, ok := v.(*seedState)
+	if !ok {
+		return nil, errortypes.ErrConvertFailed
+	}
+
+	ss.Lock()
+	defer ss.Unlock()
+	current := make([]string, len(ss.peerIDs))
+	copy(current, ss.peerIDs)
+	ss.peerIDs = elect(current, ss.elected)
+	ss.elected = true
+
+	peerIDs := make([]string, len(ss.peerIDs))
+	copy(peerIDs, ss.peerIDs)
+	return peerIDs, nil
+}
+
+// GetSeedPeerIDs gets the peerIDs of the seed nodes with specified taskID.
+func (pm *Manager) GetSeedPeerIDs(ctx context.Context, taskID string) ([]string, error) {
+	v, err := pm.seeds.Get(taskID)
+	if err != nil {
+		if errortypes.IsDataNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ss, ok := v.(*seedState)
+	if !ok {
+		return nil, errortypes.ErrConvertFailed
+	}
+
+	ss.RLock()
+	defer ss.RUnlock()
+	peerIDs := make([]string, len(ss.peerIDs))
+	copy(peerIDs, ss.peerIDs)
+	return peerIDs, nil
+}
+
+// DeleteSeed removes the peer from the seed nodes of taskID.
+func (pm *Manager) DeleteSeed(ctx context.Context, taskID, peerID string) error {
+	v, err := pm.seeds.Get(taskID)
+	if err != nil {
+		if errortypes.IsDataNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if ss, ok := v.(*seedState); ok {
+		ss.delete(peerID)
+	}
+	return nil
+}
+
+// deletePeerIDFromSeeds removes the peer from the seed nodes of all tasks.
+func (pm *Manager) deletePeerIDFromSeeds(peerID string) {
+	pm.seeds.Range(func(key, value interface{}) bool {
+		if ss, ok := value.(*seedState); ok {
+			ss.delete(peerID)
+		}
+		return true
+	})
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package progress
+
+import (
+	"context"
+
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+
+	"github.com/go-check/check"
+)
+
+func (s *ProgressManagerTestSuite) TestSeeds(c *check.C) {
+	ctx := context.Background()
+	cfg := config.NewConfig()
+	cfg.SetCIDPrefix("127.0.0.1")
+	pm, err := NewManager(cfg, nil)
+	c.Assert(err, check.IsNil)
+
+	peerIDs, err := pm.GetSeedPeerIDs(ctx, "taskID")
+	c.Assert(err, check.IsNil)
+	c.Assert(peerIDs, check.HasLen, 0)
+
+	// the seeds are elected from the scratch at first
+	peerIDs, err = pm.UpdateSeeds(ctx, "taskID", func(current []string, elected bool) []string {
+		c.Check(current, check.HasLen, 0)
+		c.Check(elected, check.Equals, false)
+		return []string{"peerA", "peerB"}
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(peerIDs, check.DeepEquals, []string{"peerA", "peerB"})
+
+	peerIDs, err = pm.GetSeedPeerIDs(ctx, "taskID")
+	c.Assert(err, check.IsNil)
+	c.Assert(peerIDs, check.DeepEquals, []string{"peerA", "peerB"})
+
+	// the seat is released when the seed deletes the resource
+	c.Assert(pm.DeleteSeed(ctx, "taskID", "peerA"), check.IsNil)
+	peerIDs, err = pm.UpdateSeeds(ctx, "taskID", func(current []string, elected bool) []string {
+		c.Check(current, check.DeepEquals, []string{"peerB"})
+		c.Check(elected, check.Equals, true)
+		return append(current, "peerC")
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(peerIDs, check.DeepEquals, []string{"peerB", "peerC"})
+
+	// the seed is removed from all tasks when it's offline
+	c.Assert(pm.InitProgress(ctx, "taskID", "peerB", "cidB", config.P2pPattern, ""), check.IsNil)
+	c.Assert(pm.UpdatePeerServiceDown(ctx, "peerB"), check.IsNil)
+	peerIDs, err = pm.GetSeedPeerIDs(ctx, "taskID")
+	c.Assert(err, check.IsNil)
+	c.Assert(peerIDs, check.DeepEquals, []string{"peerC"})
+
+	c.Assert(pm.DeleteTaskID(ctx, "taskID", 0), check.IsNil)
+	peerIDs, err = pm.GetSeedPeerIDs(ctx, "taskID")
+	c.Assert(err, check.IsNil)
+	c.Assert(peerIDs, check.HasLen, 0)
+}
@@ -51,6 +51,24 @@ func (pm *Manager) UpdateSuperLoad(ctx context.Context, taskID string, delta, li
 	return true, nil
 }
 
+// GetSuperLoad gets the number of pieces being downloaded from the supernode for taskID.
+// It returns 0 if nothing has been downloaded from the supernode for the task.
+func (pm *Manager) GetSuperLoad(ctx context.Context, taskID string) (int32, error) {
+	v, err := pm.superLoad.get(taskID)
+	if err != nil {
+		if errortypes.IsDataNotFound(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	loadState, ok := v.(*superLoadState)
+	if !ok {
+		return 0, errortypes.ErrConvertFailed
+	}
+
+	return loadState.loadValue.Get(), nil
+}
+
 // startMonitorSuperLoad starts a new goroutine to check the superload periodically and
 // reset the superload to zero if there is no update for a long time for one task to
 // avoid being occupied when supernode doesn't receive the message from peers that downloading piece from supernode for a variety of reasons.
@@ -45,12 +45,27 @@ type PeerState struct {
 
 	// ServicePattern default 0 is p2p, 1 is cdn.
 	PeerPattern config.Pattern
+
+	// Tenant is the tenant which the peer belongs to.
+	Tenant string
+
+	// Score is the health score in [0, 1] of the peer serving the other peers.
+	// It's 0 if the peer is offline.
+	Score float64
+
+	// ClientScore is the health score in [0, 1] of the peer downloading from the other peers.
+	ClientScore float64
 }
 
 // ProgressMgr is responsible for maintaining the correspondence between peer and pieces.
 type ProgressMgr interface {
 	// InitProgress inits the correlation information between peers and pieces, etc.
-	InitProgress(ctx context.Context, taskID, peerID, clientID string, peerPattern config.Pattern) error
+	// The tenant is recorded in the state of the peer for scheduling.
+	InitProgress(ctx context.Context, taskID, peerID, clientID string, peerPattern config.Pattern, tenant string) error
+
+	// StartFlushProgress starts to write the changed progress to the state backend
+	// with a new goroutine until ctx is done.
+	StartFlushProgress(ctx context.Context)
 
 	// UpdateProgress updates the correlation information between peers and pieces.
 	// 1. update the info about srcCID to tell the scheduler that corresponding peer has the piece now.
@@ -65,6 +80,10 @@ type ProgressMgr interface {
 	// The filter parameter depends on the specific implementation.
 	GetPieceProgressByCID(ctx context.Context, taskID, clientID, filter string) (pieceNums []int, err error)
 
+	// GetRunningPiecesByCID gets the pieces being downloaded by the specified clientID
+	// and the peers which they are downloaded from, the key is pieceNum and the value is dstPID.
+	GetRunningPiecesByCID(ctx context.Context, clientID string) (runningPieces map[int]string, err error)
+
 	// GetPeerIDsByPieceNum gets all peerIDs with specified taskID and pieceNum.
 	GetPeerIDsByPieceNum(ctx context.Context, taskID string, pieceNum int) (peerIDs []string, err error)
 
@@ -81,17 +100,45 @@ type ProgressMgr interface {
 	// It's considered as a failure when then superload is greater than limit after adding delta.
 	UpdatePeerServiceDown(ctx context.Context, peerID string) (err error)
 
+	// ReportPeerError records an error of errorType that the other peers ran into
+	// when downloading from the peer, which lowers the health score of the peer.
+	// PIECE_SLOW lowers the score without counting an error.
+	ReportPeerError(ctx context.Context, peerID, errorType string) error
+
 	// GetPeersByTaskID gets all peers info with specified taskID.
 	GetPeersByTaskID(ctx context.Context, taskID string) (peersInfo []*types.PeerInfo, err error)
 
 	// GetBlackInfoByPeerID gets black info with specified peerID.
 	GetBlackInfoByPeerID(ctx context.Context, peerID string) (dstPIDMap *syncmap.SyncMap, err error)
 
+	// QuarantinePeer keeps the peer server at peerAddr(ip:port) from being scheduled to serve
+	// the other peers until the expireTime in milliseconds, it takes effect on all supernodes
+	// of the cluster. The peer server is identified by its address because the peerID changes
+	// when the peer restarts or registers to another supernode.
+	QuarantinePeer(ctx context.Context, peerAddr string, expireTime int64) error
+
+	// IsPeerQuarantined returns whether the peer server at peerAddr(ip:port) is quarantined now.
+	IsPeerQuarantined(ctx context.Context, peerAddr string) bool
+
 	// UpdateSuperLoad updates the superLoad with delta.
 	//
 	// The value will be rolled back if it exceeds the limit after updated and returns false.
 	UpdateSuperLoad(ctx context.Context, taskID string, delta, limit int32) (updated bool, err error)
 
+	// GetSuperLoad gets the number of pieces being downloaded from the supernode for taskID.
+	GetSuperLoad(ctx context.Context, taskID string) (load int32, err error)
+
+	// UpdateSeeds replaces the seed nodes of taskID with the ones returned by elect.
+	// The elect is called with the current seeds and whether they have been elected
+	// before, and the updates of the same task are serialized.
+	UpdateSeeds(ctx context.Context, taskID string, elect func(peerIDs []string, elected bool) []string) ([]string, error)
+
+	// GetSeedPeerIDs gets the peerIDs of the seed nodes with specified taskID in the order of rank.
+	GetSeedPeerIDs(ctx context.Context, taskID string) (peerIDs []string, err error)
+
+	// DeleteSeed removes the peer from the seed nodes of taskID.
+	DeleteSeed(ctx context.Context, taskID, peerID string) error
+
 	// DeleteTaskID deletes the super progress with specified taskID.
 	DeleteTaskID(ctx context.Context, taskID string, pieceTotal int) (err error)
 
@@ -23,7 +23,7 @@ import (
 	"time"
 
 	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
-	"github.com/dragonflyoss/Dragonfly/pkg/syncmap"
+	"github.com/dragonflyoss/Dragonfly/pkg/tracing"
 	"github.com/dragonflyoss/Dragonfly/supernode/config"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
 
@@ -31,6 +31,10 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// seedWeight is how many times a seed node is as likely to be scheduled
+// as the other peers with the same health score.
+const seedWeight = 4
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
@@ -41,18 +45,31 @@ var _ mgr.SchedulerMgr = &Manager{}
 type Manager struct {
 	cfg         *config.Config
 	progressMgr mgr.ProgressMgr
+	peerMgr     mgr.PeerMgr
 }
 
 // NewManager returns a new Manager.
-func NewManager(cfg *config.Config, progressMgr mgr.ProgressMgr) (*Manager, error) {
+func NewManager(cfg *config.Config, progressMgr mgr.ProgressMgr, peerMgr mgr.PeerMgr) (*Manager, error) {
 	return &Manager{
 		cfg:         cfg,
 		progressMgr: progressMgr,
+		peerMgr:     peerMgr,
 	}, nil
 }
 
 // Schedule gets scheduler result with specified taskID, clientID and peerID through some rules.
-func (sm *Manager) Schedule(ctx context.Context, taskID, clientID, peerID string) ([]*mgr.PieceResult, error) {
+func (sm *Manager) Schedule(ctx context.Context, taskID, clientID, peerID string) (result []*mgr.PieceResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, "scheduler.Schedule", tracing.SpanKindInternal)
+	defer func() {
+		span.SetAttribute("task.id", taskID)
+		span.SetAttribute("pieces", len(result))
+		// waiting for the pieces is not a failure
+		if !errortypes.IsPeerWait(err) && !errortypes.IsPeerContinue(err) {
+			span.SetError(err)
+		}
+		span.End()
+	}()
+
 	// get available pieces
 	pieceAvailable, err := sm.progressMgr.GetPieceProgressByCID(ctx, taskID, clientID, "available")
 	if err != nil {
@@ -141,21 +158,26 @@ func (sm *Manager) sortExecutor(ctx context.Context, pieceNums []int, centerNum
 }
 
 func (sm *Manager) getPieceResults(ctx context.Context, taskID, clientID, srcPID string, pieceNums []int, runningCount int) ([]*mgr.PieceResult, error) {
-	// validate ClientErrorCount
+	// validate the health of the client
 	var useSupernode bool
 	srcPeerState, err := sm.progressMgr.GetPeerStateByPeerID(ctx, srcPID)
 	if err != nil {
 		return nil, err
 	}
-	if srcPeerState.ClientErrorCount.Get() > int32(sm.cfg.FailureCountLimit) {
-		logrus.Warnf("scheduler: peerID: %s got errors for %d times which reaches error limit: %d for taskID(%s)",
-			srcPID, srcPeerState.ClientErrorCount.Get(), sm.cfg.FailureCountLimit, taskID)
+	if srcPeerState.ClientScore < sm.cfg.PeerHealthThreshold {
+		logrus.Warnf("scheduler: peerID: %s got health score %.3f as a client which is below threshold: %.3f for taskID(%s)",
+			srcPID, srcPeerState.ClientScore, sm.cfg.PeerHealthThreshold, taskID)
 		useSupernode = true
 	}
 
+	seedPIDs := sm.getSeedPIDs(ctx, taskID)
+
 	pieceResults := make([]*mgr.PieceResult, 0)
 	for i := 0; i < len(pieceNums); i++ {
-		var dstPID string
+		var (
+			dstPID        string
+			alternatePIDs []string
+		)
 		if useSupernode {
 			dstPID = sm.cfg.GetSuperPID()
 		} else {
@@ -165,7 +187,8 @@ func (sm *Manager) getPieceResults(ctx context.Context, taskID, clientID, srcPID
 			if err != nil {
 				return nil, errors.Wrapf(errortypes.ErrUnknownError, "failed to get peerIDs for pieceNum: %d of taskID: %s", pieceNums[i], taskID)
 			}
-			dstPID = sm.tryGetPID(ctx, taskID, pieceNums[i], srcPID, peerIDs)
+			dstPID = sm.tryGetPID(ctx, taskID, pieceNums[i], srcPeerState.Tenant, peerIDs, seedPIDs)
+			alternatePIDs = sm.getAlternatePIDs(ctx, taskID, srcPID, srcPeerState.Tenant, dstPID, peerIDs, seedPIDs)
 		}
 
 		if dstPID == "" {
@@ -190,9 +213,10 @@ func (sm *Manager) getPieceResults(ctx context.Context, taskID, clientID, srcPID
 		}
 
 		pieceResults = append(pieceResults, &mgr.PieceResult{
-			TaskID:   taskID,
-			PieceNum: pieceNums[i],
-			DstPID:   dstPID,
+			TaskID:        taskID,
+			PieceNum:      pieceNums[i],
+			DstPID:        dstPID,
+			AlternatePIDs: alternatePIDs,
 		})
 
 		runningCount++
@@ -204,14 +228,18 @@ func (sm *Manager) getPieceResults(ctx context.Context, taskID, clientID, srcPID
 	return pieceResults, nil
 }
 
-// tryGetPID returns an available dstPID from ps.pieceContainer.
-func (sm *Manager) tryGetPID(ctx context.Context, taskID string, pieceNum int, srcPID string, peerIDs []string) (dstPID string) {
+// tryGetPID returns an available dstPID from peerIDs.
+// The healthy peers are sampled randomly weighted by their health scores,
+// and the seed nodes are weighted seedWeight times as much as the others.
+// Only the peers whose tenant can share with srcTenant are available.
+func (sm *Manager) tryGetPID(ctx context.Context, taskID string, pieceNum int, srcTenant string, peerIDs []string, seedPIDs map[string]bool) (dstPID string) {
 	defer func() {
 		if dstPID == "" {
 			dstPID = sm.cfg.GetSuperPID()
 		}
 	}()
 
+	var candidates []*candidate
 	for i := 0; i < len(peerIDs); i++ {
 		// if failed to get peerState, and then it should not be needed.
 		peerState, err := sm.progressMgr.GetPeerStateByPeerID(ctx, peerIDs[i])
@@ -228,53 +256,161 @@ func (sm *Manager) tryGetPID(ctx context.Context, taskID string, pieceNum int, s
 			continue
 		}
 
-		// if service has failed for EliminationLimit times, and then it should not be needed.
-		if peerState.ServiceErrorCount != nil {
-			serviceErrorCount := peerState.ServiceErrorCount.Get()
-			if int(serviceErrorCount) >= sm.cfg.EliminationLimit {
-				logrus.Warnf("scheduler: the peer(%s) has been eliminated to because of too many errors(%d) occurred as a peer server", peerIDs[i], serviceErrorCount)
-				sm.deletePeerIDByPieceNum(ctx, taskID, pieceNum, peerIDs[i])
-				continue
+		if !sm.isAvailable(ctx, srcTenant, peerIDs[i], peerState) {
+			continue
+		}
+		candidates = append(candidates, &candidate{peerID: peerIDs[i], peerState: peerState, seed: seedPIDs[peerIDs[i]]})
+	}
+
+	for len(candidates) > 0 {
+		i := sampleByWeight(candidates)
+		if load := candidates[i].peerState.ProducerLoad; load != nil {
+			if load.Add(1) <= int32(sm.cfg.PeerUpLimit) {
+				return candidates[i].peerID
 			}
+			load.Add(-1)
 		}
-		//if the cdn  pattern  ,try the next one
-		if peerState.PeerPattern != config.P2pPattern {
-			logrus.Debugf("scheduler: it is not p2p pattern taskID(%s) peerID(%s): %v", taskID, peerIDs[i], err)
+		candidates = append(candidates[:i], candidates[i+1:]...)
+	}
+	return
+}
+
+// candidate is a peer which can be scheduled to serve the piece.
+type candidate struct {
+	peerID    string
+	peerState *mgr.PeerState
+	// seed is whether the peer is elected as a seed node of the task.
+	seed bool
+}
+
+// weight returns the health score of the candidate, which is
+// multiplied by seedWeight if it's a seed node.
+func (c *candidate) weight() float64 {
+	if c.seed {
+		return c.peerState.Score * seedWeight
+	}
+	return c.peerState.Score
+}
+
+// isAvailable returns whether the online peer can serve the peers of srcTenant.
+func (sm *Manager) isAvailable(ctx context.Context, srcTenant, peerID string, peerState *mgr.PeerState) bool {
+	// if the cdn pattern, try the next one.
+	if peerState.PeerPattern != config.P2pPattern {
+		logrus.Debugf("scheduler: it is not p2p pattern peerID(%s)", peerID)
+		return false
+	}
+	// if the peer belongs to another tenant which can't be shared, try the next one.
+	if !sm.cfg.CanShareTenants(srcTenant, peerState.Tenant) {
+		return false
+	}
+	// if the peer isn't healthy now, try the next one.
+	// It'll be scheduled again when the score recovers.
+	if peerState.Score < sm.cfg.PeerHealthThreshold {
+		logrus.Debugf("scheduler: the peer(%s) got health score %.3f which is below threshold: %.3f",
+			peerID, peerState.Score, sm.cfg.PeerHealthThreshold)
+		return false
+	}
+	// if the peer has served corrupted pieces too many times, try the next one.
+	peerInfo, err := sm.peerMgr.Get(ctx, peerID)
+	if err != nil {
+		logrus.Debugf("scheduler: failed to get peer info of peerID(%s): %v", peerID, err)
+		return false
+	}
+	return !sm.progressMgr.IsPeerQuarantined(ctx, mgr.PeerAddr(peerInfo))
+}
+
+// sampleByWeight returns the index of a candidate chosen randomly
+// with the probability proportional to its weight.
+func sampleByWeight(candidates []*candidate) int {
+	var total float64
+	for _, c := range candidates {
+		total += c.weight()
+	}
+	if total <= 0 {
+		return rand.Intn(len(candidates))
+	}
+
+	r := rand.Float64() * total
+	for i, c := range candidates {
+		r -= c.weight()
+		if r < 0 {
+			return i
+		}
+	}
+	return len(candidates) - 1
+}
+
+// getAlternatePIDs returns at most HedgeAlternateLimit peers which have the piece
+// except the dstPID in the descending order of weight, and dfget can send hedged
+// requests to them.
+// Different from tryGetPID, it doesn't increase the load of the peers returned
+// because the hedged requests are only sent when the dstPID is too slow.
+// The load reserved for the dstPID is released with the result of the piece
+// even if it's reported against an alternative peer.
+func (sm *Manager) getAlternatePIDs(ctx context.Context, taskID, srcPID, srcTenant, dstPID string, peerIDs []string, seedPIDs map[string]bool) []string {
+	if sm.cfg.HedgeAlternateLimit <= 0 {
+		return nil
+	}
+
+	var candidates []*candidate
+	for _, peerID := range peerIDs {
+		if peerID == dstPID || peerID == srcPID || sm.cfg.IsSuperPID(peerID) {
 			continue
 		}
-		// if the v is in the blackList, try the next one.
-		blackInfo, err := sm.progressMgr.GetBlackInfoByPeerID(ctx, srcPID)
-		if err != nil && !errortypes.IsDataNotFound(err) {
-			logrus.Warnf("scheduler: failed to get blackInfo for peerID %s: %v", peerIDs[i], err)
+
+		peerState, err := sm.progressMgr.GetPeerStateByPeerID(ctx, peerID)
+		if err != nil {
+			continue
+		}
+		if peerState.ServiceDownTime > 0 {
+			continue
+		}
+		if peerState.ProducerLoad != nil &&
+			peerState.ProducerLoad.Get() >= int32(sm.cfg.PeerUpLimit) {
 			continue
 		}
-		if blackInfo != nil && isExistInMap(blackInfo, peerIDs[i]) {
+		if !sm.isAvailable(ctx, srcTenant, peerID, peerState) {
 			continue
 		}
+		candidates = append(candidates, &candidate{peerID: peerID, peerState: peerState, seed: seedPIDs[peerID]})
+	}
 
-		if peerState.ProducerLoad != nil {
-			if peerState.ProducerLoad.Add(1) <= int32(sm.cfg.PeerUpLimit) {
-				return peerIDs[i]
-			}
-			peerState.ProducerLoad.Add(-1)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].weight() > candidates[j].weight()
+	})
+	var alternatePIDs []string
+	for _, c := range candidates {
+		if len(alternatePIDs) >= sm.cfg.HedgeAlternateLimit {
+			break
 		}
+		alternatePIDs = append(alternatePIDs, c.peerID)
 	}
-	return
+	return alternatePIDs
 }
 
-func (sm *Manager) deletePeerIDByPieceNum(ctx context.Context, taskID string, pieceNum int, peerID string) {
-	if err := sm.progressMgr.DeletePeerIDByPieceNum(ctx, taskID, pieceNum, peerID); err != nil {
-		logrus.Warnf("scheduler: failed to delete the peerID %s for pieceNum %d of taskID: %s: %v", peerID, pieceNum, taskID, err)
+// getSeedPIDs returns the peers elected as seed nodes of the task
+// if the seed pattern is enabled.
+func (sm *Manager) getSeedPIDs(ctx context.Context, taskID string) map[string]bool {
+	if sm.cfg.SeedLimit <= 0 {
+		return nil
 	}
+
+	peerIDs, err := sm.progressMgr.GetSeedPeerIDs(ctx, taskID)
+	if err != nil {
+		logrus.Warnf("scheduler: failed to get seed peerIDs for taskID(%s): %v", taskID, err)
+		return nil
+	}
+	seedPIDs := make(map[string]bool, len(peerIDs))
+	for _, peerID := range peerIDs {
+		seedPIDs[peerID] = true
+	}
+	return seedPIDs
 }
 
-// isExistInMap returns whether the key exists in the mmap.
-func isExistInMap(mmap *syncmap.SyncMap, key string) bool {
-	if mmap == nil {
-		return false
+func (sm *Manager) deletePeerIDByPieceNum(ctx context.Context, taskID string, pieceNum int, peerID string) {
+	if err := sm.progressMgr.DeletePeerIDByPieceNum(ctx, taskID, pieceNum, peerID); err != nil {
+		logrus.Warnf("scheduler: failed to delete the peerID %s for pieceNum %d of taskID: %s: %v", peerID, pieceNum, taskID, err)
 	}
-	_, err := mmap.Get(key)
-	return err == nil
 }
 
 // get the center value of the piece num being downloaded
@@ -22,8 +22,10 @@ import (
 	"reflect"
 	"testing"
 
-	"github.com/dragonflyoss/Dragonfly/pkg/syncmap"
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/atomiccount"
 	"github.com/dragonflyoss/Dragonfly/supernode/config"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/mock"
 
 	"github.com/go-check/check"
@@ -52,7 +54,7 @@ func (s *SchedulerMgrTestSuite) SetUpSuite(c *check.C) {
 
 	cfg := config.NewConfig()
 	cfg.SetSuperPID("fooPid")
-	s.manager, _ = NewManager(cfg, s.mockProgressMgr)
+	s.manager, _ = NewManager(cfg, s.mockProgressMgr, mock.NewMockPeerMgr(s.mockCtl))
 }
 
 func (s *SchedulerMgrTestSuite) TearDownSuite(c *check.C) {
@@ -122,14 +124,178 @@ func (s *SchedulerMgrTestSuite) TestGetCenterNum(c *check.C) {
 	}
 }
 
-func (s *SchedulerMgrTestSuite) TestIsExistInMap(c *check.C) {
-	mmap := syncmap.NewSyncMap()
-	mmap.Add("a", "value")
-	mmap.Add("b", "value")
-	mmap.Add("c", "value")
+// newMockPeerMgr serves the peers at 127.0.0.1 with the ports in the order of peerIDs,
+// and returns the addresses of them.
+func newMockPeerMgr(mockCtl *gomock.Controller, peerIDs ...string) (*mock.MockPeerMgr, map[string]string) {
+	peerMgr := mock.NewMockPeerMgr(mockCtl)
+	addrs := make(map[string]string)
+	for i, peerID := range peerIDs {
+		peerInfo := &types.PeerInfo{IP: "127.0.0.1", Port: int32(15001 + i)}
+		peerMgr.EXPECT().Get(gomock.Any(), peerID).Return(peerInfo, nil).AnyTimes()
+		addrs[peerID] = mgr.PeerAddr(peerInfo)
+	}
+	return peerMgr, addrs
+}
+
+func (s *SchedulerMgrTestSuite) TestGetAlternatePIDs(c *check.C) {
+	mockCtl := gomock.NewController(c)
+	defer mockCtl.Finish()
+	progressMgr := mock.NewMockProgressMgr(mockCtl)
+
+	cfg := config.NewConfig()
+	cfg.SetSuperPID("superPid")
+	cfg.HedgeAlternateLimit = 2
+	peerMgr, addrs := newMockPeerMgr(mockCtl, "down", "cdn", "unhealthy", "busy", "tenant", "ok1", "ok2", "quarantined", "ok3")
+	manager, _ := NewManager(cfg, progressMgr, peerMgr)
+
+	peerStates := map[string]*mgr.PeerState{
+		"down":        {ServiceDownTime: 1, PeerPattern: config.P2pPattern, Score: 1},
+		"cdn":         {PeerPattern: config.CdnPattern, Score: 1},
+		"unhealthy":   {PeerPattern: config.P2pPattern, Score: 0.1},
+		"busy":        {ProducerLoad: atomiccount.NewAtomicInt(int32(cfg.PeerUpLimit)), PeerPattern: config.P2pPattern, Score: 1},
+		"tenant":      {PeerPattern: config.P2pPattern, Tenant: "other", Score: 1},
+		"ok1":         {ProducerLoad: atomiccount.NewAtomicInt(0), PeerPattern: config.P2pPattern, Score: 1},
+		"ok2":         {PeerPattern: config.P2pPattern, Score: 0.5},
+		"quarantined": {PeerPattern: config.P2pPattern, Score: 1},
+		"ok3":         {PeerPattern: config.P2pPattern, Score: 1},
+	}
+	for k, v := range peerStates {
+		progressMgr.EXPECT().GetPeerStateByPeerID(gomock.Any(), k).Return(v, nil).AnyTimes()
+		progressMgr.EXPECT().IsPeerQuarantined(gomock.Any(), addrs[k]).Return(k == "quarantined").AnyTimes()
+	}
+
+	peerIDs := []string{"src", "dst", "superPid", "down", "cdn", "unhealthy", "busy", "tenant", "quarantined", "ok1", "ok2", "ok3"}
+	result := manager.getAlternatePIDs(context.Background(), "task", "src", "", "dst", peerIDs, nil)
+	c.Check(result, check.DeepEquals, []string{"ok1", "ok3"})
+	c.Check(peerStates["ok1"].ProducerLoad.Get(), check.Equals, int32(0))
+
+	// the seed nodes are weighted more than the others
+	result = manager.getAlternatePIDs(context.Background(), "task", "src", "", "dst", peerIDs, map[string]bool{"ok2": true})
+	c.Check(result, check.DeepEquals, []string{"ok2", "ok1"})
+
+	cfg.HedgeAlternateLimit = 0
+	c.Check(manager.getAlternatePIDs(context.Background(), "task", "src", "", "dst", peerIDs, nil), check.IsNil)
+}
+
+func (s *SchedulerMgrTestSuite) TestTryGetPIDWithTenants(c *check.C) {
+	mockCtl := gomock.NewController(c)
+	defer mockCtl.Finish()
+	progressMgr := mock.NewMockProgressMgr(mockCtl)
+
+	cfg := config.NewConfig()
+	cfg.SetSuperPID("superPid")
+	cfg.Tenants = []*config.TenantConfig{{ID: "a"}, {ID: "b"}}
+	peerMgr, _ := newMockPeerMgr(mockCtl, "peerB", "peerA")
+	manager, _ := NewManager(cfg, progressMgr, peerMgr)
+
+	peerStates := map[string]*mgr.PeerState{
+		"peerB": {ProducerLoad: atomiccount.NewAtomicInt(0), PeerPattern: config.P2pPattern, Tenant: "b", Score: 1},
+		"peerA": {ProducerLoad: atomiccount.NewAtomicInt(0), PeerPattern: config.P2pPattern, Tenant: "a", Score: 1},
+	}
+	for k, v := range peerStates {
+		progressMgr.EXPECT().GetPeerStateByPeerID(gomock.Any(), k).Return(v, nil).AnyTimes()
+	}
+	progressMgr.EXPECT().IsPeerQuarantined(gomock.Any(), gomock.Any()).Return(false).AnyTimes()
+
+	peerIDs := []string{"peerB", "peerA"}
+	c.Check(manager.tryGetPID(context.Background(), "task", 0, "a", peerIDs, nil), check.Equals, "peerA")
+	c.Check(manager.tryGetPID(context.Background(), "task", 0, "c", peerIDs, nil), check.Equals, "superPid")
+
+	// the peers of the shared tenants can download pieces from each other
+	cfg.Tenants[0].Shared = true
+	cfg.Tenants[1].Shared = true
+	c.Check(manager.tryGetPID(context.Background(), "task", 0, "a", []string{"peerB"}, nil), check.Equals, "peerB")
+}
+
+func (s *SchedulerMgrTestSuite) TestTryGetPIDWithQuarantine(c *check.C) {
+	mockCtl := gomock.NewController(c)
+	defer mockCtl.Finish()
+	progressMgr := mock.NewMockProgressMgr(mockCtl)
+
+	cfg := config.NewConfig()
+	cfg.SetSuperPID("superPid")
+	peerMgr, addrs := newMockPeerMgr(mockCtl, "corrupted", "ok")
+	manager, _ := NewManager(cfg, progressMgr, peerMgr)
+
+	peerStates := map[string]*mgr.PeerState{
+		"corrupted": {ProducerLoad: atomiccount.NewAtomicInt(0), PeerPattern: config.P2pPattern, Score: 1},
+		"ok":        {ProducerLoad: atomiccount.NewAtomicInt(0), PeerPattern: config.P2pPattern, Score: 1},
+	}
+	for k, v := range peerStates {
+		progressMgr.EXPECT().GetPeerStateByPeerID(gomock.Any(), k).Return(v, nil).AnyTimes()
+	}
+	progressMgr.EXPECT().IsPeerQuarantined(gomock.Any(), addrs["corrupted"]).Return(true).AnyTimes()
+	progressMgr.EXPECT().IsPeerQuarantined(gomock.Any(), addrs["ok"]).Return(false).AnyTimes()
+
+	// the quarantined peer keeps the piece but isn't scheduled
+	c.Check(manager.tryGetPID(context.Background(), "task", 0, "", []string{"corrupted", "ok"}, nil), check.Equals, "ok")
+	c.Check(manager.tryGetPID(context.Background(), "task", 0, "", []string{"corrupted"}, nil), check.Equals, "superPid")
+	c.Check(peerStates["corrupted"].ProducerLoad.Get(), check.Equals, int32(0))
+}
+
+func (s *SchedulerMgrTestSuite) TestTryGetPIDWithScore(c *check.C) {
+	mockCtl := gomock.NewController(c)
+	defer mockCtl.Finish()
+	progressMgr := mock.NewMockProgressMgr(mockCtl)
+
+	cfg := config.NewConfig()
+	cfg.SetSuperPID("superPid")
+	cfg.PeerUpLimit = 1000
+	peerMgr, _ := newMockPeerMgr(mockCtl, "unhealthy", "healthy", "degraded", "seed")
+	manager, _ := NewManager(cfg, progressMgr, peerMgr)
+
+	peerStates := map[string]*mgr.PeerState{
+		"unhealthy": {ProducerLoad: atomiccount.NewAtomicInt(0), PeerPattern: config.P2pPattern, Score: 0.1},
+		"healthy":   {ProducerLoad: atomiccount.NewAtomicInt(0), PeerPattern: config.P2pPattern, Score: 0.9},
+		"degraded":  {ProducerLoad: atomiccount.NewAtomicInt(0), PeerPattern: config.P2pPattern, Score: 0.3},
+		"seed":      {ProducerLoad: atomiccount.NewAtomicInt(0), PeerPattern: config.P2pPattern, Score: 0.3},
+	}
+	for k, v := range peerStates {
+		progressMgr.EXPECT().GetPeerStateByPeerID(gomock.Any(), k).Return(v, nil).AnyTimes()
+	}
+	progressMgr.EXPECT().IsPeerQuarantined(gomock.Any(), gomock.Any()).Return(false).AnyTimes()
+
+	// the peers are sampled by the score, and the unhealthy one is never scheduled
+	peerIDs := []string{"unhealthy", "healthy", "degraded"}
+	counts := make(map[string]int)
+	for i := 0; i < 400; i++ {
+		counts[manager.tryGetPID(context.Background(), "task", 0, "", peerIDs, nil)]++
+	}
+	c.Check(counts["unhealthy"], check.Equals, 0)
+	c.Check(counts["degraded"] > 0, check.Equals, true)
+	c.Check(counts["healthy"] > counts["degraded"], check.Equals, true)
+
+	// the seed nodes are weighted more than the peers with the same score
+	seedPIDs := map[string]bool{"seed": true}
+	counts = make(map[string]int)
+	for i := 0; i < 400; i++ {
+		counts[manager.tryGetPID(context.Background(), "task", 0, "", []string{"degraded", "seed"}, seedPIDs)]++
+	}
+	c.Check(counts["seed"] > counts["degraded"], check.Equals, true)
+	peerStates["seed"].ProducerLoad.Set(int32(cfg.PeerUpLimit))
+	c.Check(manager.tryGetPID(context.Background(), "task", 0, "", []string{"healthy", "seed"}, seedPIDs), check.Equals, "healthy")
+
+	// the peer is scheduled again when the score recovers
+	c.Check(manager.tryGetPID(context.Background(), "task", 0, "", []string{"unhealthy"}, nil), check.Equals, "superPid")
+	peerStates["unhealthy"].Score = 0.5
+	c.Check(manager.tryGetPID(context.Background(), "task", 0, "", []string{"unhealthy"}, nil), check.Equals, "unhealthy")
+}
+
+func (s *SchedulerMgrTestSuite) TestGetSeedPIDs(c *check.C) {
+	mockCtl := gomock.NewController(c)
+	defer mockCtl.Finish()
+	progressMgr := mock.NewMockProgressMgr(mockCtl)
+
+	cfg := config.NewConfig()
+	manager, _ := NewManager(cfg, progressMgr, mock.NewMockPeerMgr(mockCtl))
+
+	// the seed pattern is disabled
+	c.Check(manager.getSeedPIDs(context.Background(), "task"), check.IsNil)
 
-	c.Check(isExistInMap(mmap, "a"), check.Equals, true)
-	c.Check(isExistInMap(mmap, "d"), check.Equals, false)
+	cfg.SeedLimit = 2
+	progressMgr.EXPECT().GetSeedPeerIDs(gomock.Any(), "task").Return([]string{"seed1", "seed2"}, nil)
+	seedPIDs := manager.getSeedPIDs(context.Background(), "task")
+	c.Check(seedPIDs, check.DeepEquals, map[string]bool{"seed1": true, "seed2": true})
 }
 
 func (s *SchedulerMgrTestSuite) BenchmarkGetPieceCountMap(c *check.C) {
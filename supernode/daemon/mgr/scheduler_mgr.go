@@ -25,6 +25,10 @@ type PieceResult struct {
 	TaskID   string
 	PieceNum int
 	DstPID   string
+
+	// AlternatePIDs are the other peers which also have the piece,
+	// dfget can send hedged requests to them when DstPID is too slow.
+	AlternatePIDs []string
 }
 
 // SchedulerMgr is responsible for calculating scheduling results according to certain rules.
@@ -41,7 +41,7 @@ type Manager struct {
 
 // NewManager returns a new Manager.
 func NewManager(cfg *config.Config, cacheStore *store.Store, progressManager mgr.ProgressMgr,
-	originClient httpclient.OriginHTTPClient, register prometheus.Registerer) (mgr.CDNMgr, error) {
+	clusterManager mgr.ClusterMgr, originClient httpclient.OriginHTTPClient, register prometheus.Registerer) (mgr.CDNMgr, error) {
 	return &Manager{
 		cfg:             cfg,
 		progressManager: progressManager,
@@ -100,3 +100,8 @@ func (cm *Manager) Delete(ctx context.Context, taskID string, force bool) error
 func (cm *Manager) GetGCTaskIDs(ctx context.Context, taskMgr mgr.TaskMgr) ([]string, error) {
 	return nil, nil
 }
+
+// ListCachedFiles returns nothing as no file is cached.
+func (cm *Manager) ListCachedFiles(ctx context.Context) ([]*mgr.CachedFile, error) {
+	return nil, nil
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/dragonflyoss/Dragonfly/pkg/syncmap"
 	"github.com/dragonflyoss/Dragonfly/supernode/config"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
 	dutil "github.com/dragonflyoss/Dragonfly/supernode/daemon/util"
 	"github.com/dragonflyoss/Dragonfly/supernode/httpclient"
 	"github.com/dragonflyoss/Dragonfly/supernode/util"
@@ -88,25 +89,28 @@ type Manager struct {
 	progressMgr  mgr.ProgressMgr
 	cdnMgr       mgr.CDNMgr
 	schedulerMgr mgr.SchedulerMgr
+	tenantMgr    mgr.TenantMgr
 }
 
 // NewManager returns a new Manager Object.
 func NewManager(cfg *config.Config, peerMgr mgr.PeerMgr, dfgetTaskMgr mgr.DfgetTaskMgr,
-	progressMgr mgr.ProgressMgr, cdnMgr mgr.CDNMgr, schedulerMgr mgr.SchedulerMgr,
-	originClient httpclient.OriginHTTPClient, register prometheus.Registerer) (*Manager, error) {
-	return &Manager{
+	progressMgr mgr.ProgressMgr, cdnMgr mgr.CDNMgr, schedulerMgr mgr.SchedulerMgr, tenantMgr mgr.TenantMgr,
+	originClient httpclient.OriginHTTPClient, backend state.Backend, register prometheus.Registerer) (*Manager, error) {
+	tm := &Manager{
 		cfg:                     cfg,
-		taskStore:               dutil.NewStore(),
 		peerMgr:                 peerMgr,
 		dfgetTaskMgr:            dfgetTaskMgr,
 		progressMgr:             progressMgr,
 		cdnMgr:                  cdnMgr,
 		schedulerMgr:            schedulerMgr,
+		tenantMgr:               tenantMgr,
 		accessTimeMap:           syncmap.NewSyncMap(),
 		taskURLUnReachableStore: syncmap.NewSyncMap(),
 		originClient:            originClient,
 		metrics:                 newMetrics(register),
-	}, nil
+	}
+	tm.taskStore = dutil.NewBackendStore(backend, state.BucketTask, tm.decodeTask)
+	return tm, nil
 }
 
 // Register will not only register a task.
@@ -135,32 +139,44 @@ func (tm *Manager) Register(ctx context.Context, req *types.TaskCreateRequest) (
 		logrus.Warnf("failed to update accessTime for taskID(%s): %v", task.ID, err)
 	}
 
-	// Step3: add a new DfgetTask
-	dfgetTask, err := tm.addDfgetTask(ctx, req, task)
-	if err != nil {
-		logrus.Infof("failed to add dfgetTask %+v: %v", dfgetTask, err)
-		return nil, err
-	}
-
-	logrus.Debugf("success to add dfgetTask %+v", dfgetTask)
-	defer func() {
+	// The supernode registers the task by itself to preheat it,
+	// and the dfgetTask and progress of the CDN node are initialized
+	// when the CDN is triggered.
+	if !tm.cfg.IsSuperCID(req.CID) {
+		// Step3: add a new DfgetTask
+		var dfgetTask *types.DfGetTask
+		dfgetTask, err = tm.addDfgetTask(ctx, req, task)
 		if err != nil {
-			if err := tm.dfgetTaskMgr.Delete(ctx, req.CID, task.ID); err != nil {
-				logrus.Errorf("failed to delete the dfgetTask with taskID %s peerID %s: %v", task.ID, req.PeerID, err)
-			}
-			logrus.Infof("success to rollback the dfgetTask %+v", dfgetTask)
+			logrus.Infof("failed to add dfgetTask %+v: %v", dfgetTask, err)
+			return nil, err
 		}
-	}()
 
-	// Step4: init Progress
-	if err := tm.progressMgr.InitProgress(ctx, task.ID, req.PeerID, req.CID, req.PeerPattern); err != nil {
-		return nil, err
+		logrus.Debugf("success to add dfgetTask %+v", dfgetTask)
+		defer func() {
+			if err != nil {
+				if err := tm.dfgetTaskMgr.Delete(ctx, req.CID, task.ID); err != nil {
+					logrus.Errorf("failed to delete the dfgetTask with taskID %s peerID %s: %v", task.ID, req.PeerID, err)
+				}
+				logrus.Infof("success to rollback the dfgetTask %+v", dfgetTask)
+			}
+		}()
+
+		// Step4: init Progress
+		if err := tm.progressMgr.InitProgress(ctx, task.ID, req.PeerID, req.CID, req.PeerPattern, req.Tenant); err != nil {
+			return nil, err
+		}
+		logrus.Debugf("success to init progress for taskID: %s peerID: %s cID: %s", task.ID, req.PeerID, req.CID)
+		// TODO: defer rollback init Progress
 	}
-	logrus.Debugf("success to init progress for taskID: %s peerID: %s cID: %s", task.ID, req.PeerID, req.CID)
-	// TODO: defer rollback init Progress
 
-	// Step5: trigger CDN
+	// Step5: elect the peer as a seed node if the task is hot
+	asSeed := tm.electSeed(ctx, task, req)
+
+	// Step6: trigger CDN
 	if err := tm.triggerCdnSyncAction(ctx, task); err != nil {
+		if errortypes.IsTenantQuotaExceeded(err) {
+			return nil, err
+		}
 		return nil, errors.Wrapf(errortypes.ErrSystemError, "failed to trigger cdn: %v", err)
 	}
 
@@ -173,6 +189,7 @@ func (tm *Manager) Register(ctx context.Context, req *types.TaskCreateRequest) (
 		FileLength: task.HTTPFileLength,
 		PieceSize:  task.PieceSize,
 		CdnSource:  cdnSource,
+		AsSeed:     asSeed,
 	}, nil
 }
 
@@ -187,9 +204,39 @@ func (tm *Manager) GetAccessTime(ctx context.Context) (*syncmap.SyncMap, error)
 }
 
 // List returns a list of tasks with filter.
-// TODO: implement it.
-func (tm *Manager) List(ctx context.Context, filter map[string]string) ([]*types.TaskInfo, error) {
-	return nil, nil
+func (tm *Manager) List(ctx context.Context, filter *mgr.TaskFilter) ([]*types.TaskInfo, error) {
+	if filter == nil {
+		filter = &mgr.TaskFilter{}
+	}
+	if filter.PageFilter != nil {
+		if err := dutil.ValidateFilter(filter.PageFilter, SortKeys); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	var matched []interface{}
+	for _, v := range tm.taskStore.List() {
+		task, ok := v.(*types.TaskInfo)
+		if !ok {
+			continue
+		}
+		accessTime, _ := tm.accessTimeMap.GetAsTime(task.ID)
+		if matchTask(filter, task, now.Sub(accessTime)) {
+			matched = append(matched, task)
+		}
+	}
+
+	if filter.PageFilter != nil {
+		less := tm.getLessFunc(matched, filter.PageFilter)
+		matched = dutil.GetPageValues(matched, filter.PageFilter.PageNum, filter.PageFilter.PageSize, less)
+	}
+
+	tasks := make([]*types.TaskInfo, 0, len(matched))
+	for _, v := range matched {
+		tasks = append(tasks, v.(*types.TaskInfo))
+	}
+	return tasks, nil
 }
 
 // CheckTaskStatus checks the task status.
@@ -212,6 +259,9 @@ func (tm *Manager) CheckTaskStatus(ctx context.Context, taskID string) (bool, er
 
 // Delete deletes a task.
 func (tm *Manager) Delete(ctx context.Context, taskID string) error {
+	if task, err := tm.getTask(taskID); err == nil {
+		tm.tenantMgr.DeleteTask(ctx, task.Tenant, taskID)
+	}
 	tm.accessTimeMap.Delete(taskID)
 	tm.taskURLUnReachableStore.Delete(taskID)
 	tm.taskStore.Delete(taskID)
@@ -282,7 +332,13 @@ func (tm *Manager) UpdatePieceStatus(ctx context.Context, taskID, pieceRange str
 
 	// when a peer success to download a piece from supernode,
 	// and the load of supernode for the taskID should be decremented by one.
-	if tm.cfg.IsSuperPID(pieceUpdateRequest.DstPID) {
+	// The load is reserved for the peer which the piece is scheduled to,
+	// which isn't the DstPID if a hedged request to another peer wins.
+	scheduledPID := pieceUpdateRequest.DstPID
+	if runningPieces, err := tm.progressMgr.GetRunningPiecesByCID(ctx, pieceUpdateRequest.ClientID); err == nil && runningPieces[pieceNum] != "" {
+		scheduledPID = runningPieces[pieceNum]
+	}
+	if tm.cfg.IsSuperPID(scheduledPID) {
 		_, err := tm.progressMgr.UpdateSuperLoad(ctx, taskID, -1, -1)
 		if err != nil {
 			logrus.Warnf("failed to update superLoad taskID(%s) clientID(%s): %v", taskID, pieceUpdateRequest.ClientID, err)
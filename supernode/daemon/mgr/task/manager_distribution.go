@@ -0,0 +1,153 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package task
+
+import (
+	"context"
+	"sort"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+
+	"github.com/sirupsen/logrus"
+)
+
+// GetDistribution returns which peers hold which pieces of the task, who is
+// downloading from whom, the load and errors of the peers and the superload.
+// The holders of pieces, the blacklists and the downloading sources of peers
+// are omitted if compact is true.
+func (tm *Manager) GetDistribution(ctx context.Context, taskID string, compact bool) (*types.TaskDistribution, error) {
+	task, err := tm.getTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	superLoad, err := tm.progressMgr.GetSuperLoad(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	distribution := &types.TaskDistribution{
+		TaskID:          taskID,
+		PieceTotal:      task.PieceTotal,
+		SuperLoad:       superLoad,
+		Pieces:          make([]*types.PieceDistribution, 0, task.PieceTotal),
+		Peers:           make([]*types.PeerDistribution, 0),
+		EliminatedPeers: make([]string, 0),
+	}
+
+	for i := 0; i < int(task.PieceTotal); i++ {
+		peerIDs, err := tm.progressMgr.GetPeerIDsByPieceNum(ctx, taskID, i)
+		if err != nil && !errortypes.IsDataNotFound(err) {
+			return nil, err
+		}
+		piece := &types.PieceDistribution{
+			PieceNum:    int32(i),
+			HolderCount: int32(len(peerIDs)),
+		}
+		if !compact {
+			sort.Strings(peerIDs)
+			piece.Holders = peerIDs
+		}
+		distribution.Pieces = append(distribution.Pieces, piece)
+	}
+
+	cids, err := tm.dfgetTaskMgr.GetCIDsByTaskID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	for _, cid := range cids {
+		peer, err := tm.getPeerDistribution(ctx, taskID, cid, compact)
+		if err != nil {
+			logrus.Warnf("failed to get the distribution of taskID(%s) clientID(%s): %v", taskID, cid, err)
+			continue
+		}
+		distribution.Peers = append(distribution.Peers, peer)
+		if peer.Eliminated {
+			distribution.EliminatedPeers = append(distribution.EliminatedPeers, peer.PeerID)
+		}
+	}
+	sort.Slice(distribution.Peers, func(i, j int) bool {
+		return distribution.Peers[i].PeerID < distribution.Peers[j].PeerID
+	})
+	sort.Strings(distribution.EliminatedPeers)
+
+	return distribution, nil
+}
+
+// getPeerDistribution returns the load and errors of the peer which downloads
+// the task with the specified clientID.
+func (tm *Manager) getPeerDistribution(ctx context.Context, taskID, clientID string, compact bool) (*types.PeerDistribution, error) {
+	dfgetTask, err := tm.dfgetTaskMgr.Get(ctx, clientID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	peerState, err := tm.progressMgr.GetPeerStateByPeerID(ctx, dfgetTask.PeerID)
+	if err != nil {
+		return nil, err
+	}
+
+	peer := &types.PeerDistribution{
+		PeerID:      dfgetTask.PeerID,
+		CID:         clientID,
+		Offline:     peerState.ServiceDownTime > 0,
+		Score:       peerState.Score,
+		ClientScore: peerState.ClientScore,
+	}
+	if peerState.ProducerLoad != nil {
+		peer.ProducerLoad = peerState.ProducerLoad.Get()
+	}
+	if peerState.ClientErrorCount != nil {
+		peer.ClientErrorCount = peerState.ClientErrorCount.Get()
+	}
+	if peerState.ServiceErrorCount != nil {
+		peer.ServiceErrorCount = peerState.ServiceErrorCount.Get()
+	}
+	peer.Eliminated = peerState.Score < tm.cfg.PeerHealthThreshold
+
+	// The successful pieces can't be got until the supernode has downloaded some pieces.
+	successPieces, err := tm.progressMgr.GetPieceProgressByCID(ctx, taskID, clientID, "success")
+	if err == nil {
+		peer.PieceCount = int32(len(successPieces))
+	}
+
+	runningPieces, err := tm.progressMgr.GetRunningPiecesByCID(ctx, clientID)
+	if err != nil && !errortypes.IsDataNotFound(err) {
+		return nil, err
+	}
+	peer.ConsumerLoad = int32(len(runningPieces))
+	if compact {
+		return peer, nil
+	}
+
+	if len(runningPieces) > 0 {
+		peer.DownloadingFrom = make(map[string]int32)
+		for _, dstPID := range runningPieces {
+			peer.DownloadingFrom[dstPID]++
+		}
+	}
+	blackInfo, err := tm.progressMgr.GetBlackInfoByPeerID(ctx, dfgetTask.PeerID)
+	if err != nil && !errortypes.IsDataNotFound(err) {
+		return nil, err
+	}
+	if blackInfo != nil {
+		peer.Blacklist = blackInfo.ListKeyAsStringSlice()
+		sort.Strings(peer.Blacklist)
+	}
+
+	return peer, nil
+}
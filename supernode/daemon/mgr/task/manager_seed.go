@@ -0,0 +1,200 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package task
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+
+	"github.com/sirupsen/logrus"
+)
+
+// seedCandidate is a peer which can be elected as a seed node.
+type seedCandidate struct {
+	peerID string
+	// score is the health score of the peer serving the other peers.
+	score float64
+	// uploadRate is the bandwidth the peer serves the other peers with.
+	uploadRate int64
+	// load is the number of the pieces the peer is serving.
+	load int32
+	// uptime is how long the peer has been in the P2P network.
+	uptime time.Duration
+	// rank is the resources of the peer weighted by its health score.
+	rank float64
+}
+
+// electSeed elects the seed nodes of the task when it's hot, and returns whether
+// the peer of req is one of them. When the task becomes hot, all the eligible
+// peers which have registered the task are ranked by their resources, and then
+// every new eligible peer competes with the current seeds for the seats.
+// Only the peers which apply for being seeds or are long-lived dfdaemons are eligible.
+func (tm *Manager) electSeed(ctx context.Context, task *types.TaskInfo, req *types.TaskCreateRequest) bool {
+	if tm.cfg.SeedLimit <= 0 || req.PeerPattern != config.P2pPattern {
+		return false
+	}
+
+	cids, err := tm.dfgetTaskMgr.GetCIDsByTaskID(ctx, task.ID)
+	if err != nil {
+		logrus.Warnf("failed to get clientIDs of taskID(%s): %v", task.ID, err)
+		return false
+	}
+	peerCount := 0
+	for _, cid := range cids {
+		if !tm.cfg.IsSuperCID(cid) {
+			peerCount++
+		}
+	}
+	if peerCount < tm.cfg.SeedHotThreshold {
+		return false
+	}
+
+	seeds, err := tm.progressMgr.UpdateSeeds(ctx, task.ID, func(current []string, elected bool) []string {
+		peerIDs := current
+		if !elected {
+			peerIDs = tm.getEligiblePeerIDs(ctx, task.ID, cids)
+		}
+		if req.AsSeed || req.Dfdaemon {
+			peerIDs = appendIfAbsent(peerIDs, req.PeerID)
+		}
+		return tm.rankSeeds(ctx, peerIDs, tm.cfg.SeedLimit)
+	})
+	if err != nil {
+		logrus.Warnf("failed to elect seeds for taskID(%s): %v", task.ID, err)
+		return false
+	}
+	logrus.Debugf("elect peerIDs(%v) as the seed nodes of taskID(%s)", seeds, task.ID)
+
+	for _, peerID := range seeds {
+		if peerID == req.PeerID {
+			logrus.Infof("success to elect peerID(%s) as a seed node of taskID(%s)", req.PeerID, task.ID)
+			return true
+		}
+	}
+	return false
+}
+
+// getEligiblePeerIDs returns the peers of the clients which apply for being
+// seeds or are dfdaemons.
+func (tm *Manager) getEligiblePeerIDs(ctx context.Context, taskID string, cids []string) []string {
+	var peerIDs []string
+	for _, cid := range cids {
+		if tm.cfg.IsSuperCID(cid) {
+			continue
+		}
+		dfgetTask, err := tm.dfgetTaskMgr.Get(ctx, cid, taskID)
+		if err != nil {
+			logrus.Debugf("failed to get dfgetTask of clientID(%s) taskID(%s): %v", cid, taskID, err)
+			continue
+		}
+		if dfgetTask.AsSeed || dfgetTask.Dfdaemon {
+			peerIDs = appendIfAbsent(peerIDs, dfgetTask.PeerID)
+		}
+	}
+	return peerIDs
+}
+
+// rankSeeds returns at most limit peers which are healthy in the order of rank.
+// The bandwidth, the spare upload slots and the uptime of every peer are scaled
+// into [0, 1] among the candidates, and the average of them weighted by the health
+// score is the rank, so that a well-resourced but unreliable peer isn't preferred.
+func (tm *Manager) rankSeeds(ctx context.Context, peerIDs []string, limit int) []string {
+	now := time.Now()
+	var (
+		candidates    []*seedCandidate
+		maxUploadRate int64
+		maxUptime     time.Duration
+	)
+	for _, peerID := range peerIDs {
+		peerState, err := tm.progressMgr.GetPeerStateByPeerID(ctx, peerID)
+		if err != nil {
+			logrus.Debugf("failed to get peer state of peerID(%s): %v", peerID, err)
+			continue
+		}
+		if peerState.ServiceDownTime > 0 || peerState.Score < tm.cfg.PeerHealthThreshold {
+			continue
+		}
+		peerInfo, err := tm.peerMgr.Get(ctx, peerID)
+		if err != nil {
+			logrus.Debugf("failed to get peer info of peerID(%s): %v", peerID, err)
+			continue
+		}
+		if tm.progressMgr.IsPeerQuarantined(ctx, mgr.PeerAddr(peerInfo)) {
+			continue
+		}
+
+		c := &seedCandidate{
+			peerID:     peerID,
+			score:      peerState.Score,
+			uploadRate: peerInfo.UploadRate,
+			uptime:     now.Sub(time.Time(peerInfo.Created)),
+		}
+		if peerState.ProducerLoad != nil {
+			c.load = peerState.ProducerLoad.Get()
+		}
+		if c.uploadRate > maxUploadRate {
+			maxUploadRate = c.uploadRate
+		}
+		if c.uptime > maxUptime {
+			maxUptime = c.uptime
+		}
+		candidates = append(candidates, c)
+	}
+
+	for _, c := range candidates {
+		bandwidth, uptime, spare := 1.0, 1.0, 1.0
+		if maxUploadRate > 0 {
+			bandwidth = float64(c.uploadRate) / float64(maxUploadRate)
+		}
+		if maxUptime > 0 {
+			uptime = float64(c.uptime) / float64(maxUptime)
+		}
+		if tm.cfg.PeerUpLimit > 0 {
+			spare = 1 - float64(c.load)/float64(tm.cfg.PeerUpLimit)
+			if spare < 0 {
+				spare = 0
+			}
+		}
+		c.rank = c.score * (bandwidth + uptime + spare) / 3
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].rank > candidates[j].rank
+	})
+
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	seeds := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		seeds = append(seeds, c.peerID)
+	}
+	return seeds
+}
+
+func appendIfAbsent(peerIDs []string, peerID string) []string {
+	for _, v := range peerIDs {
+		if v == peerID {
+			return peerIDs
+		}
+	}
+	return append(peerIDs, peerID)
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/atomiccount"
+	"github.com/dragonflyoss/Dragonfly/pkg/rate"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/mock"
+
+	"github.com/go-check/check"
+	"github.com/go-openapi/strfmt"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func (s *TaskUtilTestSuite) TestElectSeed(c *check.C) {
+	mockCtl := gomock.NewController(c)
+	defer mockCtl.Finish()
+	peerMgr := mock.NewMockPeerMgr(mockCtl)
+	dfgetTaskMgr := mock.NewMockDfgetTaskMgr(mockCtl)
+	progressMgr := mock.NewMockProgressMgr(mockCtl)
+
+	cfg := config.NewConfig()
+	cfg.SetCIDPrefix("127.0.0.1")
+	cfg.SeedLimit = 1
+	cfg.SeedHotThreshold = 3
+	tm, _ := NewManager(cfg, peerMgr, dfgetTaskMgr, progressMgr, nil, nil, nil, nil, nil, prometheus.NewRegistry())
+
+	ctx := context.Background()
+	task := &types.TaskInfo{ID: "taskID"}
+	newRequest := func(peerID string, asSeed bool) *types.TaskCreateRequest {
+		return &types.TaskCreateRequest{PeerID: peerID, AsSeed: asSeed, PeerPattern: config.P2pPattern}
+	}
+
+	now := time.Now()
+	peers := map[string]struct {
+		dfgetTask *types.DfGetTask
+		info      *types.PeerInfo
+		score     float64
+	}{
+		"early": {
+			dfgetTask: &types.DfGetTask{PeerID: "early", Dfdaemon: true},
+			info:      &types.PeerInfo{UploadRate: int64(100 * rate.MB), Created: strfmt.DateTime(now.Add(-time.Hour))},
+			score:     1,
+		},
+		"plain": {
+			dfgetTask: &types.DfGetTask{PeerID: "plain"},
+			info:      &types.PeerInfo{UploadRate: int64(rate.GB), Created: strfmt.DateTime(now.Add(-2 * time.Hour))},
+			score:     1,
+		},
+		"slow": {
+			dfgetTask: &types.DfGetTask{PeerID: "slow", AsSeed: true},
+			info:      &types.PeerInfo{UploadRate: int64(10 * rate.MB), Created: strfmt.DateTime(now)},
+			score:     1,
+		},
+		"fast": {
+			info:  &types.PeerInfo{UploadRate: int64(rate.GB), Created: strfmt.DateTime(now.Add(-2 * time.Hour))},
+			score: 1,
+		},
+		"bad": {
+			info:  &types.PeerInfo{UploadRate: int64(rate.GB), Created: strfmt.DateTime(now.Add(-2 * time.Hour))},
+			score: 0.1,
+		},
+	}
+	for peerID, p := range peers {
+		peerMgr.EXPECT().Get(ctx, peerID).Return(p.info, nil).AnyTimes()
+		progressMgr.EXPECT().GetPeerStateByPeerID(ctx, peerID).Return(&mgr.PeerState{
+			ProducerLoad: atomiccount.NewAtomicInt(0),
+			Score:        p.score,
+		}, nil).AnyTimes()
+	}
+	progressMgr.EXPECT().IsPeerQuarantined(ctx, gomock.Any()).Return(false).AnyTimes()
+	for cid, peerID := range map[string]string{"cid1": "early", "cid2": "plain", "cid3": "slow"} {
+		dfgetTaskMgr.EXPECT().Get(ctx, cid, "taskID").Return(peers[peerID].dfgetTask, nil).AnyTimes()
+	}
+
+	var (
+		seeds   []string
+		elected bool
+	)
+	progressMgr.EXPECT().UpdateSeeds(ctx, "taskID", gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ string, elect func([]string, bool) []string) ([]string, error) {
+			seeds, elected = elect(seeds, elected), true
+			return seeds, nil
+		}).AnyTimes()
+
+	// the task isn't hot
+	dfgetTaskMgr.EXPECT().GetCIDsByTaskID(ctx, "taskID").Return([]string{cfg.GetSuperCID("taskID"), "cid1", "cid3"}, nil)
+	c.Check(tm.electSeed(ctx, task, newRequest("slow", true)), check.Equals, false)
+	c.Check(seeds, check.HasLen, 0)
+
+	// the peers registered before the task becomes hot are ranked, and the
+	// one which doesn't apply for being a seed isn't elected.
+	dfgetTaskMgr.EXPECT().GetCIDsByTaskID(ctx, "taskID").Return([]string{"cid1", "cid2", "cid3"}, nil).AnyTimes()
+	c.Check(tm.electSeed(ctx, task, newRequest("plain", false)), check.Equals, false)
+	c.Check(seeds, check.DeepEquals, []string{"early"})
+
+	// the unhealthy peer isn't elected even if it's well-resourced
+	c.Check(tm.electSeed(ctx, task, newRequest("bad", true)), check.Equals, false)
+	c.Check(seeds, check.DeepEquals, []string{"early"})
+
+	// the peer with more resources takes the seat
+	c.Check(tm.electSeed(ctx, task, newRequest("fast", true)), check.Equals, true)
+	c.Check(seeds, check.DeepEquals, []string{"fast"})
+
+	cfg.SeedLimit = 0
+	c.Check(tm.electSeed(ctx, task, newRequest("fast", true)), check.Equals, false)
+}
@@ -18,12 +18,18 @@ package task
 
 import (
 	"context"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/atomiccount"
 	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/syncmap"
 	"github.com/dragonflyoss/Dragonfly/supernode/config"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/mock"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/tenant"
 	dutil "github.com/dragonflyoss/Dragonfly/supernode/daemon/util"
 	cMock "github.com/dragonflyoss/Dragonfly/supernode/httpclient/mock"
 
@@ -65,11 +71,12 @@ func (s *TaskMgrTestSuite) SetUpSuite(c *check.C) {
 
 	s.mockCDNMgr.EXPECT().TriggerCDN(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
 	s.mockDfgetTaskMgr.EXPECT().Add(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
-	s.mockProgressMgr.EXPECT().InitProgress(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	s.mockProgressMgr.EXPECT().InitProgress(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 	s.mockOriginClient.EXPECT().GetContentLength(gomock.Any(), gomock.Any()).Return(int64(1000), 200, nil)
 	cfg := config.NewConfig()
+	tenantMgr, _ := tenant.NewManager(cfg, nil, nil, prometheus.NewRegistry())
 	s.taskManager, _ = NewManager(cfg, s.mockPeerMgr, s.mockDfgetTaskMgr,
-		s.mockProgressMgr, s.mockCDNMgr, s.mockSchedulerMgr, s.mockOriginClient, prometheus.NewRegistry())
+		s.mockProgressMgr, s.mockCDNMgr, s.mockSchedulerMgr, tenantMgr, s.mockOriginClient, nil, prometheus.NewRegistry())
 }
 
 func (s *TaskMgrTestSuite) TearDownSuite(c *check.C) {
@@ -108,6 +115,55 @@ func (s *TaskMgrTestSuite) TestCheckTaskStatus(c *check.C) {
 	c.Check(isSuccess, check.Equals, true)
 }
 
+func (s *TaskMgrTestSuite) TestRegisterBySupernode(c *check.C) {
+	mockCtl := gomock.NewController(c)
+	defer mockCtl.Finish()
+	mockCDNMgr := mock.NewMockCDNMgr(mockCtl)
+	mockDfgetTaskMgr := mock.NewMockDfgetTaskMgr(mockCtl)
+	mockProgressMgr := mock.NewMockProgressMgr(mockCtl)
+	mockOriginClient := cMock.NewMockOriginHTTPClient(mockCtl)
+
+	cfg := config.NewConfig()
+	cfg.SetSuperPID("superPID")
+	tenantMgr, _ := tenant.NewManager(cfg, nil, nil, prometheus.NewRegistry())
+	taskManager, _ := NewManager(cfg, s.mockPeerMgr, mockDfgetTaskMgr,
+		mockProgressMgr, mockCDNMgr, s.mockSchedulerMgr, tenantMgr, mockOriginClient, nil, prometheus.NewRegistry())
+	taskManager.taskStore = dutil.NewStore()
+
+	req := &types.TaskCreateRequest{
+		CallSystem: "dragonfly_preheat",
+		Path:       "/download/foo",
+		RawURL:     "http://aa.bb.com/preheat",
+		PeerID:     cfg.GetSuperPID(),
+	}
+	taskID := GenerateTaskID(req)
+	req.CID = cfg.GetSuperCID(taskID)
+
+	// only the dfgetTask and progress of the CDN node are initialized
+	done := make(chan struct{})
+	mockOriginClient.EXPECT().GetContentLength(gomock.Any(), gomock.Any()).Return(int64(1000), 200, nil)
+	mockCDNMgr.EXPECT().GetHTTPPath(gomock.Any(), gomock.Any()).Return("/download/foo", nil)
+	mockDfgetTaskMgr.EXPECT().Add(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	mockProgressMgr.EXPECT().InitProgress(gomock.Any(), taskID, cfg.GetSuperPID(), req.CID,
+		gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	mockCDNMgr.EXPECT().TriggerCDN(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, task *types.TaskInfo) (*types.TaskInfo, error) {
+			close(done)
+			return &types.TaskInfo{CdnStatus: types.TaskInfoCdnStatusSUCCESS, FileLength: 1000}, nil
+		})
+
+	resp, err := taskManager.Register(context.Background(), req)
+	c.Assert(err, check.IsNil)
+	c.Check(resp.ID, check.Equals, taskID)
+	c.Check(resp.AsSeed, check.Equals, false)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		c.Fatal("cdn is not triggered")
+	}
+}
+
 func (s *TaskMgrTestSuite) TestUpdateTaskInfo(c *check.C) {
 	s.taskManager.taskStore = dutil.NewStore()
 	req := &types.TaskCreateRequest{
@@ -166,3 +222,124 @@ func (s *TaskMgrTestSuite) TestUpdateTaskInfo(c *check.C) {
 	c.Check(task.CdnStatus, check.Equals, types.TaskInfoCdnStatusSUCCESS)
 	c.Check(task.FileLength, check.Equals, int64(2000))
 }
+
+func (s *TaskMgrTestSuite) TestList(c *check.C) {
+	s.taskManager.taskStore = dutil.NewStore()
+	now := time.Now()
+	tasks := []struct {
+		task       *types.TaskInfo
+		accessTime time.Time
+	}{
+		{&types.TaskInfo{ID: "a", RawURL: "http://a.com/1", CdnStatus: types.TaskInfoCdnStatusSUCCESS,
+			Tenant: "foo", HTTPFileLength: 100}, now.Add(-2 * time.Hour)},
+		{&types.TaskInfo{ID: "b", RawURL: "http://a.com/2", CdnStatus: types.TaskInfoCdnStatusFAILED,
+			HTTPFileLength: 300}, now.Add(-time.Minute)},
+		{&types.TaskInfo{ID: "c", RawURL: "http://b.com/1", CdnStatus: types.TaskInfoCdnStatusSUCCESS,
+			Tenant: "foo", HTTPFileLength: 200}, now.Add(-time.Hour)},
+	}
+	for _, t := range tasks {
+		s.taskManager.taskStore.Put(t.task.ID, t.task)
+		s.taskManager.accessTimeMap.Add(t.task.ID, t.accessTime)
+	}
+
+	ids := func(filter *mgr.TaskFilter) []string {
+		result, err := s.taskManager.List(context.Background(), filter)
+		c.Assert(err, check.IsNil)
+		var ids []string
+		for _, t := range result {
+			ids = append(ids, t.ID)
+		}
+		return ids
+	}
+
+	c.Check(len(ids(nil)), check.Equals, 3)
+	c.Check(ids(&mgr.TaskFilter{URLPattern: regexp.MustCompile(`^http://a\.com/`),
+		PageFilter: &dutil.PageFilter{SortKey: []string{SortKeyID}, SortDirect: dutil.ASCDIRECT}}), check.DeepEquals, []string{"a", "b"})
+	c.Check(ids(&mgr.TaskFilter{CdnStatus: []string{types.TaskInfoCdnStatusFAILED}}), check.DeepEquals, []string{"b"})
+	c.Check(ids(&mgr.TaskFilter{Tenant: "foo", MaxAge: 90 * time.Minute}), check.DeepEquals, []string{"c"})
+	c.Check(ids(&mgr.TaskFilter{MinAge: 30 * time.Minute,
+		PageFilter: &dutil.PageFilter{SortDirect: dutil.ASCDIRECT}}), check.DeepEquals, []string{"a", "c"})
+	c.Check(ids(&mgr.TaskFilter{MinSize: 150, MaxSize: 250}), check.DeepEquals, []string{"c"})
+
+	// sort by the file length in descending order and fetch the second page
+	c.Check(ids(&mgr.TaskFilter{PageFilter: &dutil.PageFilter{
+		PageNum:    1,
+		PageSize:   2,
+		SortKey:    []string{SortKeyFileLength},
+		SortDirect: "DESC",
+	}}), check.DeepEquals, []string{"a"})
+
+	_, err := s.taskManager.List(context.Background(), &mgr.TaskFilter{
+		PageFilter: &dutil.PageFilter{SortKey: []string{"foo"}, SortDirect: dutil.ASCDIRECT},
+	})
+	c.Check(err, check.NotNil)
+}
+
+func (s *TaskMgrTestSuite) TestGetDistribution(c *check.C) {
+	ctx := context.Background()
+	s.taskManager.taskStore = dutil.NewStore()
+	s.taskManager.taskStore.Put("distTaskID", &types.TaskInfo{ID: "distTaskID", PieceTotal: 2})
+
+	blackInfo := syncmap.NewSyncMap()
+	blackInfo.Add("peerC", atomiccount.NewAtomicInt(1))
+	s.mockProgressMgr.EXPECT().GetSuperLoad(gomock.Any(), "distTaskID").Return(int32(3), nil).AnyTimes()
+	s.mockProgressMgr.EXPECT().GetPeerIDsByPieceNum(gomock.Any(), "distTaskID", 0).Return([]string{"peerB", "peerA"}, nil).AnyTimes()
+	s.mockProgressMgr.EXPECT().GetPeerIDsByPieceNum(gomock.Any(), "distTaskID", 1).Return(nil, nil).AnyTimes()
+	s.mockDfgetTaskMgr.EXPECT().GetCIDsByTaskID(gomock.Any(), "distTaskID").Return([]string{"cidB", "cidA"}, nil).AnyTimes()
+	s.mockDfgetTaskMgr.EXPECT().Get(gomock.Any(), "cidA", "distTaskID").Return(&types.DfGetTask{CID: "cidA", PeerID: "peerA"}, nil).AnyTimes()
+	s.mockDfgetTaskMgr.EXPECT().Get(gomock.Any(), "cidB", "distTaskID").Return(&types.DfGetTask{CID: "cidB", PeerID: "peerB"}, nil).AnyTimes()
+	s.mockProgressMgr.EXPECT().GetPeerStateByPeerID(gomock.Any(), "peerA").Return(&mgr.PeerState{
+		PeerID:            "peerA",
+		ProducerLoad:      atomiccount.NewAtomicInt(1),
+		ClientErrorCount:  atomiccount.NewAtomicInt(1),
+		ServiceErrorCount: atomiccount.NewAtomicInt(0),
+		Score:             0.8,
+		ClientScore:       0.5,
+	}, nil).AnyTimes()
+	s.mockProgressMgr.EXPECT().GetPeerStateByPeerID(gomock.Any(), "peerB").Return(&mgr.PeerState{
+		PeerID:            "peerB",
+		ProducerLoad:      atomiccount.NewAtomicInt(0),
+		ServiceErrorCount: atomiccount.NewAtomicInt(5),
+		Score:             0.1,
+		ClientScore:       1,
+	}, nil).AnyTimes()
+	s.mockProgressMgr.EXPECT().GetPieceProgressByCID(gomock.Any(), "distTaskID", "cidA", "success").Return([]int{0}, nil).AnyTimes()
+	s.mockProgressMgr.EXPECT().GetPieceProgressByCID(gomock.Any(), "distTaskID", "cidB", "success").Return([]int{0}, nil).AnyTimes()
+	s.mockProgressMgr.EXPECT().GetRunningPiecesByCID(gomock.Any(), "cidA").Return(map[int]string{1: "peerB"}, nil).AnyTimes()
+	s.mockProgressMgr.EXPECT().GetRunningPiecesByCID(gomock.Any(), "cidB").Return(map[int]string{}, nil).AnyTimes()
+	s.mockProgressMgr.EXPECT().GetBlackInfoByPeerID(gomock.Any(), "peerA").Return(blackInfo, nil).AnyTimes()
+	s.mockProgressMgr.EXPECT().GetBlackInfoByPeerID(gomock.Any(), "peerB").Return(nil, errortypes.ErrDataNotFound).AnyTimes()
+
+	distribution, err := s.taskManager.GetDistribution(ctx, "distTaskID", false)
+	c.Assert(err, check.IsNil)
+	c.Check(distribution.SuperLoad, check.Equals, int32(3))
+	c.Check(distribution.Pieces, check.DeepEquals, []*types.PieceDistribution{
+		{PieceNum: 0, HolderCount: 2, Holders: []string{"peerA", "peerB"}},
+		{PieceNum: 1, HolderCount: 0},
+	})
+	c.Assert(distribution.Peers, check.HasLen, 2)
+	c.Check(distribution.Peers[0], check.DeepEquals, &types.PeerDistribution{
+		PeerID:           "peerA",
+		CID:              "cidA",
+		PieceCount:       1,
+		ProducerLoad:     1,
+		ConsumerLoad:     1,
+		ClientErrorCount: 1,
+		Score:            0.8,
+		ClientScore:      0.5,
+		DownloadingFrom:  map[string]int32{"peerB": 1},
+		Blacklist:        []string{"peerC"},
+	})
+	c.Check(distribution.Peers[1].Eliminated, check.Equals, true)
+	c.Check(distribution.EliminatedPeers, check.DeepEquals, []string{"peerB"})
+
+	distribution, err = s.taskManager.GetDistribution(ctx, "distTaskID", true)
+	c.Assert(err, check.IsNil)
+	c.Check(distribution.Pieces[0].Holders, check.IsNil)
+	c.Check(distribution.Peers[0].DownloadingFrom, check.IsNil)
+	c.Check(distribution.Peers[0].Blacklist, check.IsNil)
+	c.Check(distribution.Peers[0].ConsumerLoad, check.Equals, int32(1))
+
+	_, err = s.taskManager.GetDistribution(ctx, "foo", false)
+	c.Check(errortypes.IsDataNotFound(err), check.Equals, true)
+}
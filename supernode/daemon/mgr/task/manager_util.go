@@ -18,6 +18,7 @@ package task
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
@@ -31,6 +32,7 @@ import (
 	"github.com/dragonflyoss/Dragonfly/pkg/timeutils"
 	"github.com/dragonflyoss/Dragonfly/supernode/config"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	dutil "github.com/dragonflyoss/Dragonfly/supernode/daemon/util"
 	"github.com/dragonflyoss/Dragonfly/supernode/util"
 
 	"github.com/pkg/errors"
@@ -39,10 +41,7 @@ import (
 
 // addOrUpdateTask adds a new task or update the exist task to taskStore.
 func (tm *Manager) addOrUpdateTask(ctx context.Context, req *types.TaskCreateRequest, failAccessInterval time.Duration) (*types.TaskInfo, error) {
-	taskURL := req.TaskURL
-	if stringutils.IsEmptyStr(req.TaskURL) {
-		taskURL = netutils.FilterURLParam(req.RawURL, req.Filter)
-	}
+	taskURL := getTaskURL(req)
 	taskID := generateTaskID(taskURL, req.Md5, req.Identifier, req.Headers)
 
 	util.GetLock(taskID, true)
@@ -68,6 +67,7 @@ func (tm *Manager) addOrUpdateTask(ctx context.Context, req *types.TaskCreateReq
 		TaskURL:    taskURL,
 		CdnStatus:  types.TaskInfoCdnStatusWAITING,
 		PieceTotal: -1,
+		Tenant:     req.Tenant,
 	}
 
 	if v, err := tm.taskStore.Get(taskID); err == nil {
@@ -176,7 +176,7 @@ func (tm *Manager) updateTask(taskID string, updateTaskInfo *types.TaskInfo) err
 		tm.metrics.tasks.WithLabelValues(task.CdnStatus).Dec()
 		tm.metrics.tasks.WithLabelValues(updateTaskInfo.CdnStatus).Inc()
 		task.CdnStatus = updateTaskInfo.CdnStatus
-		return nil
+		return tm.taskStore.Put(taskID, task)
 	}
 
 	// only update the task info when the new CDNStatus equals success
@@ -200,11 +200,26 @@ func (tm *Manager) updateTask(taskID string, updateTaskInfo *types.TaskInfo) err
 	tm.metrics.tasks.WithLabelValues(updateTaskInfo.CdnStatus).Inc()
 	task.CdnStatus = updateTaskInfo.CdnStatus
 
-	return nil
+	return tm.taskStore.Put(taskID, task)
+}
+
+// decodeTask decodes the task loaded from the state backend which is registered
+// by another supernode. The CDN status is reset to waiting because the CDN files
+// are local to every supernode, and the task will be downloaded by the CDN of
+// this supernode when it's registered.
+func (tm *Manager) decodeTask(data []byte) (interface{}, error) {
+	task := &types.TaskInfo{}
+	if err := json.Unmarshal(data, task); err != nil {
+		return nil, err
+	}
+	task.CdnStatus = types.TaskInfoCdnStatusWAITING
+	tm.metrics.tasks.WithLabelValues(task.CdnStatus).Inc()
+	return task, nil
 }
 
 func (tm *Manager) addDfgetTask(ctx context.Context, req *types.TaskCreateRequest, task *types.TaskInfo) (*types.DfGetTask, error) {
 	dfgetTask := &types.DfGetTask{
+		AsSeed:      req.AsSeed,
 		CID:         req.CID,
 		CallSystem:  req.CallSystem,
 		Dfdaemon:    req.Dfdaemon,
@@ -229,8 +244,15 @@ func (tm *Manager) triggerCdnSyncAction(ctx context.Context, task *types.TaskInf
 		return nil
 	}
 
+	// the task is counted in the quotas of the tenant which creates it
+	if err := tm.tenantMgr.AcquireTask(ctx, task.Tenant, task.ID, task.HTTPFileLength); err != nil {
+		logrus.Warnf("failed to trigger cdn for taskID %s: %v", task.ID, err)
+		return err
+	}
+
 	if isWait(task.CdnStatus) {
 		if err := tm.initCdnNode(ctx, task); err != nil {
+			tm.tenantMgr.ReleaseTask(ctx, task.Tenant, task.ID, 0)
 			logrus.Errorf("failed to init cdn node for taskID %s: %v", task.ID, err)
 			return err
 		}
@@ -240,6 +262,7 @@ func (tm *Manager) triggerCdnSyncAction(ctx context.Context, task *types.TaskInf
 	if err := tm.updateTask(task.ID, &types.TaskInfo{
 		CdnStatus: types.TaskInfoCdnStatusRUNNING,
 	}); err != nil {
+		tm.tenantMgr.ReleaseTask(ctx, task.Tenant, task.ID, 0)
 		return err
 	}
 
@@ -250,6 +273,12 @@ func (tm *Manager) triggerCdnSyncAction(ctx context.Context, task *types.TaskInf
 			tm.metrics.triggerCdnFailCount.WithLabelValues().Inc()
 			logrus.Errorf("taskID(%s) trigger cdn get error: %v", task.ID, err)
 		}
+
+		var fileLength int64
+		if updateTaskInfo != nil && isSuccessCDN(updateTaskInfo.CdnStatus) {
+			fileLength = updateTaskInfo.FileLength
+		}
+		tm.tenantMgr.ReleaseTask(ctx, task.Tenant, task.ID, fileLength)
 		tm.updateTask(task.ID, updateTaskInfo)
 		logrus.Infof("success to update task cdn %+v", updateTaskInfo)
 	}()
@@ -276,7 +305,7 @@ func (tm *Manager) initCdnNode(ctx context.Context, task *types.TaskInfo) error
 		return errors.Wrapf(err, "failed to add cdn dfgetTask for taskID %s", task.ID)
 	}
 
-	return tm.progressMgr.InitProgress(ctx, task.ID, pid, cid, config.P2pPattern)
+	return tm.progressMgr.InitProgress(ctx, task.ID, pid, cid, config.P2pPattern, task.Tenant)
 }
 
 func (tm *Manager) processTaskStart(ctx context.Context, srcCID string, task *types.TaskInfo, dfgetTask *types.DfGetTask) (bool, interface{}, error) {
@@ -383,6 +412,16 @@ func (tm *Manager) parseAvailablePeers(ctx context.Context, clientID string, tas
 			pieceInfo.PeerIP = dfgetTask.SupernodeIP
 		}
 
+		for _, alternatePID := range v.AlternatePIDs {
+			alternate, err := tm.getPieceSource(ctx, v.TaskID, alternatePID)
+			if err != nil {
+				logrus.Warnf("failed to get alternative source(%s) for pieceNum(%d) taskID(%s): %v",
+					alternatePID, v.PieceNum, v.TaskID, err)
+				continue
+			}
+			pieceInfo.Alternates = append(pieceInfo.Alternates, alternate)
+		}
+
 		pieceInfos = append(pieceInfos, pieceInfo)
 	}
 
@@ -390,33 +429,44 @@ func (tm *Manager) parseAvailablePeers(ctx context.Context, clientID string, tas
 }
 
 func (tm *Manager) pieceResultToPieceInfo(ctx context.Context, pr *mgr.PieceResult, pieceSize int32) (*types.PieceInfo, error) {
-	cid, err := tm.dfgetTaskMgr.GetCIDByPeerIDAndTaskID(ctx, pr.DstPID, pr.TaskID)
+	pieceInfo, err := tm.getPieceSource(ctx, pr.TaskID, pr.DstPID)
 	if err != nil {
 		return nil, err
 	}
-	dfgetTask, err := tm.dfgetTaskMgr.Get(ctx, cid, pr.TaskID)
+
+	pieceMD5, err := tm.cdnMgr.GetPieceMD5(ctx, pr.TaskID, pr.PieceNum, "", "default")
 	if err != nil {
-		return nil, err
+		logrus.Warnf("failed to get piece MD5 taskID(%s) pieceNum(%d): %v", pr.TaskID, pr.PieceNum, err)
+		pieceMD5 = ""
 	}
+	pieceInfo.PieceMD5 = pieceMD5
+	pieceInfo.PieceRange = rangeutils.CalculatePieceRange(pr.PieceNum, pieceSize)
+	pieceInfo.PieceSize = pieceSize
+	return pieceInfo, nil
+}
 
-	peer, err := tm.peerMgr.Get(ctx, pr.DstPID)
+// getPieceSource returns a PieceInfo which only contains where to download
+// the pieces of the task from the peer.
+func (tm *Manager) getPieceSource(ctx context.Context, taskID, peerID string) (*types.PieceInfo, error) {
+	cid, err := tm.dfgetTaskMgr.GetCIDByPeerIDAndTaskID(ctx, peerID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	dfgetTask, err := tm.dfgetTaskMgr.Get(ctx, cid, taskID)
 	if err != nil {
 		return nil, err
 	}
 
-	pieceMD5, err := tm.cdnMgr.GetPieceMD5(ctx, pr.TaskID, pr.PieceNum, "", "default")
+	peer, err := tm.peerMgr.Get(ctx, peerID)
 	if err != nil {
-		logrus.Warnf("failed to get piece MD5 taskID(%s) pieceNum(%d): %v", pr.TaskID, pr.PieceNum, err)
-		pieceMD5 = ""
+		return nil, err
 	}
+
 	return &types.PieceInfo{
-		PID:        pr.DstPID,
-		Path:       dfgetTask.Path,
-		PeerIP:     peer.IP.String(),
-		PeerPort:   peer.Port,
-		PieceMD5:   pieceMD5,
-		PieceRange: rangeutils.CalculatePieceRange(pr.PieceNum, pieceSize),
-		PieceSize:  pieceSize,
+		PID:      peerID,
+		Path:     dfgetTask.Path,
+		PeerIP:   peer.IP.String(),
+		PeerPort: peer.Port,
 	}, nil
 }
 
@@ -500,6 +550,20 @@ func validateParams(req *types.TaskCreateRequest) error {
 	return nil
 }
 
+// GenerateTaskID returns the ID of the task which the request registers.
+func GenerateTaskID(req *types.TaskCreateRequest) string {
+	return generateTaskID(getTaskURL(req), req.Md5, req.Identifier, req.Headers)
+}
+
+// getTaskURL returns the URL which identifies the task, the params in
+// the filter are removed from the raw URL if the task URL is not specified.
+func getTaskURL(req *types.TaskCreateRequest) string {
+	if stringutils.IsEmptyStr(req.TaskURL) {
+		return netutils.FilterURLParam(req.RawURL, req.Filter)
+	}
+	return req.TaskURL
+}
+
 // generateTaskID generates taskID with taskURL,md5 and identifier
 // and returns the SHA-256 checksum of the data.
 func generateTaskID(taskURL, md5, identifier string, header map[string]string) string {
@@ -585,3 +649,80 @@ func (tm *Manager) processPeerPatternCdn(ctx context.Context, peerID string) {
 
 	}
 }
+
+// the keys which the tasks can be sorted by.
+const (
+	SortKeyID         = "id"
+	SortKeyAccessTime = "accessTime"
+	SortKeyFileLength = "fileLength"
+)
+
+// SortKeys is the set of the keys which the tasks can be sorted by.
+var SortKeys = map[string]bool{
+	SortKeyID:         true,
+	SortKeyAccessTime: true,
+	SortKeyFileLength: true,
+}
+
+// matchTask returns whether the task matches all the conditions of filter.
+func matchTask(filter *mgr.TaskFilter, task *types.TaskInfo, age time.Duration) bool {
+	if filter.URLPattern != nil && !filter.URLPattern.MatchString(task.RawURL) {
+		return false
+	}
+	if len(filter.CdnStatus) > 0 {
+		matched := false
+		for _, status := range filter.CdnStatus {
+			if status == task.CdnStatus {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if !stringutils.IsEmptyStr(filter.Tenant) && filter.Tenant != task.Tenant {
+		return false
+	}
+	if (filter.MinAge > 0 && age < filter.MinAge) || (filter.MaxAge > 0 && age > filter.MaxAge) {
+		return false
+	}
+	if (filter.MinSize > 0 && task.HTTPFileLength < filter.MinSize) ||
+		(filter.MaxSize > 0 && task.HTTPFileLength > filter.MaxSize) {
+		return false
+	}
+	return true
+}
+
+// getLessFunc returns the less function to sort the tasks by the first sort key
+// of pageFilter, the tasks are sorted by the access time by default.
+func (tm *Manager) getLessFunc(tasks []interface{}, pageFilter *dutil.PageFilter) func(i, j int) bool {
+	sortKey := SortKeyAccessTime
+	if len(pageFilter.SortKey) > 0 {
+		sortKey = pageFilter.SortKey[0]
+	}
+
+	lessTemp := func(i, j int) bool {
+		ti, tj := tasks[i].(*types.TaskInfo), tasks[j].(*types.TaskInfo)
+		switch sortKey {
+		case SortKeyFileLength:
+			if ti.HTTPFileLength != tj.HTTPFileLength {
+				return ti.HTTPFileLength < tj.HTTPFileLength
+			}
+		case SortKeyAccessTime:
+			ai, _ := tm.accessTimeMap.GetAsTime(ti.ID)
+			aj, _ := tm.accessTimeMap.GetAsTime(tj.ID)
+			if !ai.Equal(aj) {
+				return ai.Before(aj)
+			}
+		}
+		return ti.ID < tj.ID
+	}
+
+	if dutil.IsDESC(pageFilter.SortDirect) {
+		return func(i, j int) bool {
+			return lessTemp(j, i)
+		}
+	}
+	return lessTemp
+}
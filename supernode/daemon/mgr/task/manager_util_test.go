@@ -18,10 +18,12 @@ package task
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/dragonflyoss/Dragonfly/apis/types"
 	"github.com/dragonflyoss/Dragonfly/supernode/config"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/mock"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/tenant"
 	cMock "github.com/dragonflyoss/Dragonfly/supernode/httpclient/mock"
 
 	"github.com/go-check/check"
@@ -55,8 +57,10 @@ func (s *TaskUtilTestSuite) SetUpSuite(c *check.C) {
 	s.mockProgressMgr = mock.NewMockProgressMgr(s.mockCtl)
 	s.mockSchedulerMgr = mock.NewMockSchedulerMgr(s.mockCtl)
 	s.mockOriginClient = cMock.NewMockOriginHTTPClient(s.mockCtl)
-	s.taskManager, _ = NewManager(config.NewConfig(), s.mockPeerMgr, s.mockDfgetTaskMgr,
-		s.mockProgressMgr, s.mockCDNMgr, s.mockSchedulerMgr, s.mockOriginClient, prometheus.NewRegistry())
+	cfg := config.NewConfig()
+	tenantMgr, _ := tenant.NewManager(cfg, nil, nil, prometheus.NewRegistry())
+	s.taskManager, _ = NewManager(cfg, s.mockPeerMgr, s.mockDfgetTaskMgr,
+		s.mockProgressMgr, s.mockCDNMgr, s.mockSchedulerMgr, tenantMgr, s.mockOriginClient, nil, prometheus.NewRegistry())
 
 	s.mockOriginClient.EXPECT().GetContentLength(gomock.Any(), gomock.Any()).Return(int64(1000), 200, nil)
 }
@@ -181,3 +185,21 @@ func (s *TaskUtilTestSuite) TestTriggerCdnSyncAction(c *check.C) {
 		}
 	}
 }
+
+func (s *TaskUtilTestSuite) TestDecodeTask(c *check.C) {
+	data, err := json.Marshal(&types.TaskInfo{
+		ID:             "taskID",
+		CdnStatus:      types.TaskInfoCdnStatusSUCCESS,
+		HTTPFileLength: 100,
+		PieceSize:      10,
+	})
+	c.Assert(err, check.IsNil)
+
+	v, err := s.taskManager.decodeTask(data)
+	c.Assert(err, check.IsNil)
+	task := v.(*types.TaskInfo)
+	c.Check(task.ID, check.Equals, "taskID")
+	c.Check(task.HTTPFileLength, check.Equals, int64(100))
+	// the CDN of this supernode should download the task again
+	c.Check(task.CdnStatus, check.Equals, types.TaskInfoCdnStatusWAITING)
+}
@@ -18,10 +18,13 @@ package mgr
 
 import (
 	"context"
+	"regexp"
+	"time"
 
 	"github.com/dragonflyoss/Dragonfly/apis/types"
 	"github.com/dragonflyoss/Dragonfly/pkg/syncmap"
 	"github.com/dragonflyoss/Dragonfly/supernode/config"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/util"
 )
 
 // PieceStatusMap maintains the mapping relationship between PieceUpdateRequestResult and PieceStatus code.
@@ -31,6 +34,32 @@ var PieceStatusMap = map[string]int{
 	types.PieceUpdateRequestPieceStatusSUCCESS: config.PieceSUCCESS,
 }
 
+// TaskFilter contains the conditions to filter the tasks,
+// and the zero value matches all the tasks.
+type TaskFilter struct {
+	// URLPattern is the regular expression which the raw URL of the task should match.
+	URLPattern *regexp.Regexp
+
+	// CdnStatus is the list of the cdn status which the task should be in.
+	CdnStatus []string
+
+	// Tenant is the tenant which creates the task.
+	Tenant string
+
+	// MinAge and MaxAge are the range of the age of the task, which is the duration
+	// since the task was accessed last time. 0 means no limit.
+	MinAge time.Duration
+	MaxAge time.Duration
+
+	// MinSize and MaxSize are the range of the source file length of the task.
+	// 0 means no limit.
+	MinSize int64
+	MaxSize int64
+
+	// PageFilter pages and sorts the tasks, all the tasks are returned if it's nil.
+	PageFilter *util.PageFilter
+}
+
 // TaskMgr as an interface defines all operations against Task.
 // A Task will store some meta info about the taskFile, pieces and something else.
 // A Task has a one-to-one correspondence with a file on the disk which is identified by taskID.
@@ -47,7 +76,7 @@ type TaskMgr interface {
 	GetAccessTime(ctx context.Context) (*syncmap.SyncMap, error)
 
 	// List returns the list tasks with filter.
-	List(ctx context.Context, filter map[string]string) ([]*types.TaskInfo, error)
+	List(ctx context.Context, filter *TaskFilter) ([]*types.TaskInfo, error)
 
 	// CheckTaskStatus checks whether the taskID corresponding file exists.
 	CheckTaskStatus(ctx context.Context, taskID string) (bool, error)
@@ -66,6 +95,10 @@ type TaskMgr interface {
 	// just like this: which pieces can be downloaded from which peers.
 	GetPieces(ctx context.Context, taskID, clientID string, piecePullRequest *types.PiecePullRequest) (isFinished bool, data interface{}, err error)
 
+	// GetDistribution returns the distribution of the pieces of the task among the peers
+	// and the load of them. The details are omitted if compact is true.
+	GetDistribution(ctx context.Context, taskID string, compact bool) (*types.TaskDistribution, error)
+
 	// UpdatePieceStatus updates the piece status with specified parameters.
 	// A task file is divided into several pieces logically.
 	// We use a sting called pieceRange to identify a piece.
@@ -0,0 +1,366 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/metricsutils"
+	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultTenantLabel is the label value of the default tenant in metrics.
+	defaultTenantLabel = "default"
+
+	quotaConcurrentTasks = "concurrentTasks"
+	quotaCDNDisk         = "cdnDisk"
+)
+
+var _ mgr.TenantMgr = &Manager{}
+
+type metrics struct {
+	runningTasks       *prometheus.GaugeVec
+	cdnDiskUsage       *prometheus.GaugeVec
+	quotaExceededCount *prometheus.CounterVec
+}
+
+func newMetrics(register prometheus.Registerer) *metrics {
+	return &metrics{
+		runningTasks: metricsutils.NewGauge(config.SubsystemSupernode, "tenant_running_tasks",
+			"Current number of the tasks being downloaded by cdn of tenants", []string{"tenant"}, register),
+
+		cdnDiskUsage: metricsutils.NewGauge(config.SubsystemSupernode, "tenant_cdn_disk_usage_bytes",
+			"Current size of the cdn files of tenants in bytes", []string{"tenant"}, register),
+
+		quotaExceededCount: metricsutils.NewCounter(config.SubsystemSupernode, "tenant_quota_exceeded_total",
+			"Total times of refusing tasks because the quota of tenants is exceeded", []string{"tenant", "quota"}, register),
+	}
+}
+
+// taskUsage records a task of a tenant on a supernode, which is kept in the
+// state backend so that the quotas are shared by the supernodes.
+type taskUsage struct {
+	Tenant     string `json:"tenant"`
+	Running    bool   `json:"running,omitempty"`
+	FileLength int64  `json:"fileLength,omitempty"`
+}
+
+// Manager is an implementation of the interface of TenantMgr.
+type Manager struct {
+	cfg     *config.Config
+	metrics *metrics
+	backend state.Backend
+
+	// node identifies this supernode in the keys of the state backend.
+	node string
+
+	// tokens maps the tokens to the tenants.
+	tokens map[string]string
+
+	sync.Mutex
+}
+
+// NewManager returns a new Manager.
+// The cdn disk usage is rebuilt from the files cached by cdnMgr, and the usage
+// is kept in memory if the backend is nil.
+func NewManager(cfg *config.Config, cdnMgr mgr.CDNMgr, backend state.Backend, register prometheus.Registerer) (*Manager, error) {
+	tokens := make(map[string]string)
+	ids := make(map[string]bool)
+	for _, t := range cfg.Tenants {
+		if t == nil || stringutils.IsEmptyStr(t.ID) {
+			return nil, fmt.Errorf("tenant ID is required")
+		}
+		if ids[t.ID] {
+			return nil, fmt.Errorf("duplicated tenant: %s", t.ID)
+		}
+		ids[t.ID] = true
+
+		for _, token := range t.Tokens {
+			if _, ok := tokens[token]; ok {
+				return nil, fmt.Errorf("duplicated token of tenant: %s", t.ID)
+			}
+			tokens[token] = t.ID
+		}
+	}
+
+	if backend == nil {
+		backend = state.NewMemoryBackend()
+	}
+	tm := &Manager{
+		cfg:     cfg,
+		metrics: newMetrics(register),
+		backend: backend,
+		node:    net.JoinHostPort(cfg.AdvertiseIP, strconv.Itoa(cfg.ListenPort)),
+		tokens:  tokens,
+	}
+	if cdnMgr != nil {
+		if err := tm.rebuild(context.Background(), cdnMgr); err != nil {
+			return nil, errors.Wrapf(err, "failed to rebuild the cdn disk usage of tenants")
+		}
+	}
+	return tm, nil
+}
+
+// GetTenant returns the tenant of the request. The tenant token takes
+// precedence over the tenant ID, and the tenant ID is refused if the
+// tenant isn't configured or it's configured with tokens.
+func (tm *Manager) GetTenant(ctx context.Context, req *http.Request) (string, error) {
+	if token := req.Header.Get(config.TenantTokenHeader); !stringutils.IsEmptyStr(token) {
+		tenant, ok := tm.tokens[token]
+		if !ok {
+			return "", errortypes.NewHTTPError(http.StatusUnauthorized, "invalid tenant token")
+		}
+		return tenant, nil
+	}
+
+	tenant := req.Header.Get(config.TenantHeader)
+	if stringutils.IsEmptyStr(tenant) {
+		return "", nil
+	}
+	// the unknown tenants would bypass the quotas and isolate the peers at will.
+	t := tm.cfg.GetTenant(tenant)
+	if t == nil {
+		return "", errortypes.NewHTTPError(http.StatusForbidden, fmt.Sprintf("unknown tenant %s", tenant))
+	}
+	if len(t.Tokens) > 0 {
+		return "", errortypes.NewHTTPError(http.StatusUnauthorized,
+			fmt.Sprintf("tenant %s requires a token", tenant))
+	}
+	return tenant, nil
+}
+
+// AcquireTask checks the concurrent tasks and cdn disk quotas of the tenant
+// in all supernodes sharing the state backend.
+func (tm *Manager) AcquireTask(ctx context.Context, tenant, taskID string, fileLength int64) error {
+	tm.Lock()
+	defer tm.Unlock()
+
+	usage, err := tm.getUsage(tenant, taskID)
+	if err != nil {
+		return errors.Wrapf(errortypes.ErrSystemError, "failed to get the usage of taskID(%s): %v", taskID, err)
+	}
+	if usage.Running {
+		return nil
+	}
+
+	running, diskUsage, err := tm.listUsage(tenant)
+	if err != nil {
+		return errors.Wrapf(errortypes.ErrSystemError, "failed to list the usage of tenant %s: %v", tenant, err)
+	}
+	if t := tm.cfg.GetTenant(tenant); t != nil {
+		if t.MaxConcurrentTasks > 0 && len(running) >= t.MaxConcurrentTasks && !running[taskID] {
+			tm.metrics.quotaExceededCount.WithLabelValues(label(tenant), quotaConcurrentTasks).Inc()
+			return errors.Wrapf(errortypes.ErrTenantQuotaExceeded,
+				"tenant %s has %d running tasks", tenant, len(running))
+		}
+
+		if fileLength < 0 {
+			fileLength = 0
+		}
+		if t.CDNDiskQuota > 0 && diskUsage-usage.FileLength+fileLength > int64(t.CDNDiskQuota) {
+			tm.metrics.quotaExceededCount.WithLabelValues(label(tenant), quotaCDNDisk).Inc()
+			return errors.Wrapf(errortypes.ErrTenantQuotaExceeded,
+				"tenant %s uses %d bytes of cdn disk", tenant, diskUsage)
+		}
+	}
+
+	usage.Tenant = tenant
+	usage.Running = true
+	if err := tm.putUsage(tenant, taskID, usage); err != nil {
+		return errors.Wrapf(errortypes.ErrSystemError, "failed to save the usage of taskID(%s): %v", taskID, err)
+	}
+	running[taskID] = true
+	tm.metrics.runningTasks.WithLabelValues(label(tenant)).Set(float64(len(running)))
+	return nil
+}
+
+// ReleaseTask releases the running task and updates the cdn disk usage of the tenant.
+func (tm *Manager) ReleaseTask(ctx context.Context, tenant, taskID string, fileLength int64) {
+	tm.Lock()
+	defer tm.Unlock()
+
+	usage, err := tm.getUsage(tenant, taskID)
+	if err != nil {
+		logrus.Warnf("failed to get the usage of taskID(%s): %v", taskID, err)
+		return
+	}
+	usage.Tenant = tenant
+	usage.Running = false
+	if fileLength > 0 {
+		usage.FileLength = fileLength
+	}
+	if usage.FileLength > 0 {
+		err = tm.putUsage(tenant, taskID, usage)
+	} else {
+		err = tm.deleteUsage(tenant, taskID)
+	}
+	if err != nil {
+		logrus.Warnf("failed to save the usage of taskID(%s): %v", taskID, err)
+	}
+	tm.updateMetrics(tenant)
+	logrus.Debugf("tenant %s released taskID(%s) with file length %d", tenant, taskID, fileLength)
+}
+
+// DeleteTask removes the task from the running tasks and cdn disk usage of the tenant.
+func (tm *Manager) DeleteTask(ctx context.Context, tenant, taskID string) {
+	tm.Lock()
+	defer tm.Unlock()
+
+	if err := tm.deleteUsage(tenant, taskID); err != nil {
+		logrus.Warnf("failed to delete the usage of taskID(%s): %v", taskID, err)
+	}
+	tm.updateMetrics(tenant)
+}
+
+// rebuild replaces the usage of this supernode in the state backend with the files
+// cached on the local disk, as the running tasks are interrupted by the restart.
+func (tm *Manager) rebuild(ctx context.Context, cdnMgr mgr.CDNMgr) error {
+	files, err := cdnMgr.ListCachedFiles(ctx)
+	if err != nil {
+		return err
+	}
+	values, err := tm.backend.List(state.BucketTenantTask)
+	if err != nil {
+		return err
+	}
+
+	tm.Lock()
+	defer tm.Unlock()
+
+	prefix := tm.node + "/"
+	for key := range values {
+		if strings.HasPrefix(key, prefix) {
+			if err := tm.backend.Delete(state.BucketTenantTask, key); err != nil && !errortypes.IsDataNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	tenants := make(map[string]bool)
+	for _, f := range files {
+		if f.FileLength <= 0 {
+			continue
+		}
+		if err := tm.putUsage(f.Tenant, f.TaskID, &taskUsage{Tenant: f.Tenant, FileLength: f.FileLength}); err != nil {
+			return err
+		}
+		tenants[f.Tenant] = true
+	}
+	for tenant := range tenants {
+		tm.updateMetrics(tenant)
+	}
+	logrus.Infof("success to rebuild the cdn disk usage of tenants from %d cached files", len(files))
+	return nil
+}
+
+// listUsage returns the running tasks and cdn disk usage of the tenant.
+// It must be called with the lock held.
+func (tm *Manager) listUsage(tenant string) (map[string]bool, int64, error) {
+	values, err := tm.backend.List(state.BucketTenantTask)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	running := make(map[string]bool)
+	var diskUsage int64
+	for key, data := range values {
+		usage := &taskUsage{}
+		if err := json.Unmarshal(data, usage); err != nil {
+			logrus.Warnf("failed to decode the usage of key %s: %v", key, err)
+			continue
+		}
+		if usage.Tenant != tenant {
+			continue
+		}
+		if usage.Running {
+			running[key[strings.LastIndex(key, "/")+1:]] = true
+		}
+		diskUsage += usage.FileLength
+	}
+	return running, diskUsage, nil
+}
+
+func (tm *Manager) updateMetrics(tenant string) {
+	running, diskUsage, err := tm.listUsage(tenant)
+	if err != nil {
+		logrus.Warnf("failed to list the usage of tenant %s: %v", tenant, err)
+		return
+	}
+	tm.metrics.runningTasks.WithLabelValues(label(tenant)).Set(float64(len(running)))
+	tm.metrics.cdnDiskUsage.WithLabelValues(label(tenant)).Set(float64(diskUsage))
+}
+
+// getUsage returns the usage of the task of the tenant on this supernode, and an empty one if not found.
+func (tm *Manager) getUsage(tenant, taskID string) (*taskUsage, error) {
+	usage := &taskUsage{}
+	data, err := tm.backend.Get(state.BucketTenantTask, tm.usageKey(tenant, taskID))
+	if err != nil {
+		if errortypes.IsDataNotFound(err) {
+			return usage, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+func (tm *Manager) putUsage(tenant, taskID string, usage *taskUsage) error {
+	data, err := json.Marshal(usage)
+	if err != nil {
+		return err
+	}
+	return tm.backend.Put(state.BucketTenantTask, tm.usageKey(tenant, taskID), data)
+}
+
+func (tm *Manager) deleteUsage(tenant, taskID string) error {
+	err := tm.backend.Delete(state.BucketTenantTask, tm.usageKey(tenant, taskID))
+	if err != nil && !errortypes.IsDataNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// usageKey returns the key of the task of the tenant on this supernode in the state backend.
+func (tm *Manager) usageKey(tenant, taskID string) string {
+	return tm.node + "/" + tenant + "/" + taskID
+}
+
+// label returns the label value of the tenant in metrics.
+func label(tenant string) string {
+	if stringutils.IsEmptyStr(tenant) {
+		return defaultTenantLabel
+	}
+	return tenant
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/mock"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
+
+	"github.com/go-check/check"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test(t *testing.T) {
+	check.TestingT(t)
+}
+
+func init() {
+	check.Suite(&TenantMgrTestSuite{})
+}
+
+type TenantMgrTestSuite struct {
+}
+
+func newTestManager(c *check.C, tenants ...*config.TenantConfig) *Manager {
+	cfg := config.NewConfig()
+	cfg.Tenants = tenants
+	tm, err := NewManager(cfg, nil, nil, prometheus.NewRegistry())
+	c.Assert(err, check.IsNil)
+	return tm
+}
+
+func newRequest(headers map[string]string) *http.Request {
+	req, _ := http.NewRequest(http.MethodPost, "/peer/registry", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req
+}
+
+func (s *TenantMgrTestSuite) TestNewManager(c *check.C) {
+	var cases = []struct {
+		tenants []*config.TenantConfig
+		valid   bool
+	}{
+		{tenants: nil, valid: true},
+		{tenants: []*config.TenantConfig{{ID: "a"}, {ID: "b"}}, valid: true},
+		{tenants: []*config.TenantConfig{{ID: ""}}, valid: false},
+		{tenants: []*config.TenantConfig{{ID: "a"}, {ID: "a"}}, valid: false},
+		{tenants: []*config.TenantConfig{{ID: "a", Tokens: []string{"t"}}, {ID: "b", Tokens: []string{"t"}}}, valid: false},
+	}
+
+	for _, v := range cases {
+		cfg := config.NewConfig()
+		cfg.Tenants = v.tenants
+		_, err := NewManager(cfg, nil, nil, prometheus.NewRegistry())
+		c.Check(err == nil, check.Equals, v.valid, check.Commentf("tenants: %v", v.tenants))
+	}
+}
+
+func (s *TenantMgrTestSuite) TestGetTenant(c *check.C) {
+	tm := newTestManager(c,
+		&config.TenantConfig{ID: "a", Tokens: []string{"token-a"}},
+		&config.TenantConfig{ID: "b"},
+	)
+	ctx := context.Background()
+
+	var cases = []struct {
+		headers  map[string]string
+		expected string
+		valid    bool
+	}{
+		{headers: nil, expected: "", valid: true},
+		{headers: map[string]string{config.TenantHeader: "b"}, expected: "b", valid: true},
+		{headers: map[string]string{config.TenantHeader: "c"}, valid: false},
+		{headers: map[string]string{config.TenantTokenHeader: "token-a"}, expected: "a", valid: true},
+		{headers: map[string]string{config.TenantTokenHeader: "token-a", config.TenantHeader: "b"}, expected: "a", valid: true},
+		{headers: map[string]string{config.TenantHeader: "a"}, valid: false},
+		{headers: map[string]string{config.TenantTokenHeader: "invalid"}, valid: false},
+	}
+
+	for _, v := range cases {
+		tenant, err := tm.GetTenant(ctx, newRequest(v.headers))
+		c.Check(err == nil, check.Equals, v.valid, check.Commentf("headers: %v", v.headers))
+		c.Check(tenant, check.Equals, v.expected, check.Commentf("headers: %v", v.headers))
+	}
+}
+
+func (s *TenantMgrTestSuite) TestConcurrentTasksQuota(c *check.C) {
+	tm := newTestManager(c, &config.TenantConfig{ID: "a", MaxConcurrentTasks: 2})
+	ctx := context.Background()
+
+	c.Assert(tm.AcquireTask(ctx, "a", "task1", 10), check.IsNil)
+	c.Assert(tm.AcquireTask(ctx, "a", "task2", 10), check.IsNil)
+	// acquire a running task again
+	c.Assert(tm.AcquireTask(ctx, "a", "task2", 10), check.IsNil)
+
+	err := tm.AcquireTask(ctx, "a", "task3", 10)
+	c.Assert(errortypes.IsTenantQuotaExceeded(err), check.Equals, true)
+	c.Assert(prom_testutil.ToFloat64(tm.metrics.quotaExceededCount.WithLabelValues("a", quotaConcurrentTasks)), check.Equals, float64(1))
+
+	// the other tenants are not limited
+	c.Assert(tm.AcquireTask(ctx, "", "task3", 10), check.IsNil)
+	c.Assert(tm.AcquireTask(ctx, "b", "task3", 10), check.IsNil)
+
+	tm.ReleaseTask(ctx, "a", "task1", 0)
+	c.Assert(tm.AcquireTask(ctx, "a", "task3", 10), check.IsNil)
+	c.Assert(prom_testutil.ToFloat64(tm.metrics.runningTasks.WithLabelValues("a")), check.Equals, float64(2))
+	c.Assert(prom_testutil.ToFloat64(tm.metrics.runningTasks.WithLabelValues(defaultTenantLabel)), check.Equals, float64(1))
+}
+
+func (s *TenantMgrTestSuite) TestCDNDiskQuota(c *check.C) {
+	tm := newTestManager(c, &config.TenantConfig{ID: "a", CDNDiskQuota: 100})
+	ctx := context.Background()
+
+	c.Assert(tm.AcquireTask(ctx, "a", "task1", 60), check.IsNil)
+	tm.ReleaseTask(ctx, "a", "task1", 60)
+	c.Assert(prom_testutil.ToFloat64(tm.metrics.cdnDiskUsage.WithLabelValues("a")), check.Equals, float64(60))
+
+	err := tm.AcquireTask(ctx, "a", "task2", 50)
+	c.Assert(errortypes.IsTenantQuotaExceeded(err), check.Equals, true)
+
+	// the task which has been cached is not counted twice
+	c.Assert(tm.AcquireTask(ctx, "a", "task1", 60), check.IsNil)
+	tm.ReleaseTask(ctx, "a", "task1", 60)
+
+	tm.DeleteTask(ctx, "a", "task1")
+	c.Assert(prom_testutil.ToFloat64(tm.metrics.cdnDiskUsage.WithLabelValues("a")), check.Equals, float64(0))
+	c.Assert(tm.AcquireTask(ctx, "a", "task2", 50), check.IsNil)
+}
+
+func (s *TenantMgrTestSuite) TestSharedUsage(c *check.C) {
+	backend := state.NewMemoryBackend()
+	tenants := []*config.TenantConfig{{ID: "a", MaxConcurrentTasks: 2, CDNDiskQuota: 100}}
+	newManager := func(port int) *Manager {
+		cfg := config.NewConfig()
+		cfg.Tenants = tenants
+		cfg.ListenPort = port
+		tm, err := NewManager(cfg, nil, backend, prometheus.NewRegistry())
+		c.Assert(err, check.IsNil)
+		return tm
+	}
+	tm1, tm2 := newManager(8002), newManager(8003)
+	ctx := context.Background()
+
+	// the quotas are shared by the supernodes
+	c.Assert(tm1.AcquireTask(ctx, "a", "task1", 60), check.IsNil)
+	c.Assert(tm2.AcquireTask(ctx, "a", "task2", 10), check.IsNil)
+	err := tm2.AcquireTask(ctx, "a", "task3", 10)
+	c.Assert(errortypes.IsTenantQuotaExceeded(err), check.Equals, true)
+
+	tm1.ReleaseTask(ctx, "a", "task1", 60)
+	tm2.ReleaseTask(ctx, "a", "task2", 30)
+	err = tm2.AcquireTask(ctx, "a", "task3", 20)
+	c.Assert(errortypes.IsTenantQuotaExceeded(err), check.Equals, true)
+
+	tm1.DeleteTask(ctx, "a", "task1")
+	c.Assert(tm2.AcquireTask(ctx, "a", "task3", 20), check.IsNil)
+	c.Assert(prom_testutil.ToFloat64(tm2.metrics.runningTasks.WithLabelValues("a")), check.Equals, float64(1))
+}
+
+func (s *TenantMgrTestSuite) TestRebuild(c *check.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+
+	backend := state.NewMemoryBackend()
+	cfg := config.NewConfig()
+	cfg.Tenants = []*config.TenantConfig{{ID: "a", CDNDiskQuota: 100}}
+	ctx := context.Background()
+
+	// the usage left by the last run of this supernode is replaced
+	tm, err := NewManager(cfg, nil, backend, prometheus.NewRegistry())
+	c.Assert(err, check.IsNil)
+	c.Assert(tm.AcquireTask(ctx, "a", "task1", 50), check.IsNil)
+	c.Assert(tm.AcquireTask(ctx, "a", "task2", 50), check.IsNil)
+
+	cdnMgr := mock.NewMockCDNMgr(ctrl)
+	cdnMgr.EXPECT().ListCachedFiles(gomock.Any()).Return([]*mgr.CachedFile{
+		{TaskID: "task1", Tenant: "a", FileLength: 70},
+		{TaskID: "task3", Tenant: "b", FileLength: 10},
+	}, nil)
+	tm, err = NewManager(cfg, cdnMgr, backend, prometheus.NewRegistry())
+	c.Assert(err, check.IsNil)
+	c.Assert(prom_testutil.ToFloat64(tm.metrics.cdnDiskUsage.WithLabelValues("a")), check.Equals, float64(70))
+	c.Assert(prom_testutil.ToFloat64(tm.metrics.runningTasks.WithLabelValues("a")), check.Equals, float64(0))
+
+	err = tm.AcquireTask(ctx, "a", "task2", 50)
+	c.Assert(errortypes.IsTenantQuotaExceeded(err), check.Equals, true)
+	c.Assert(tm.AcquireTask(ctx, "a", "task2", 30), check.IsNil)
+}
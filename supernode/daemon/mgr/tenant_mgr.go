@@ -0,0 +1,42 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgr
+
+import (
+	"context"
+	"net/http"
+)
+
+// TenantMgr as an interface defines all operations about the tenants and their quotas.
+type TenantMgr interface {
+	// GetTenant returns the tenant of the request which is taken from the
+	// X-Dragonfly-Tenant-Token or X-Dragonfly-Tenant header.
+	// An empty string will be returned for the default tenant, and the
+	// tenants which aren't configured are refused.
+	GetTenant(ctx context.Context, req *http.Request) (string, error)
+
+	// AcquireTask checks the quotas of the tenant before the task created by it
+	// is downloaded by CDN, and counts the task as running if not exceeded.
+	AcquireTask(ctx context.Context, tenant, taskID string, fileLength int64) error
+
+	// ReleaseTask releases the running task of the tenant when CDN finishes,
+	// and the fileLength is counted in the CDN disk usage of the tenant if it's positive.
+	ReleaseTask(ctx context.Context, tenant, taskID string, fileLength int64)
+
+	// DeleteTask removes the task from the CDN disk usage of the tenant.
+	DeleteTask(ctx context.Context, tenant, taskID string)
+}
@@ -0,0 +1,331 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/httputils"
+	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultEtcdPrefix is the prefix of the keys written by supernode to etcd.
+	DefaultEtcdPrefix = "/dragonfly/supernode/"
+
+	defaultEtcdTimeout = 5 * time.Second
+
+	// maxEtcdTxnOps is the default limit of the operations in a transaction of etcd.
+	maxEtcdTxnOps = 128
+)
+
+// The paths of the etcd v3 JSON gateway.
+const (
+	etcdRangePath        = "/v3/kv/range"
+	etcdPutPath          = "/v3/kv/put"
+	etcdDeleteRangePath  = "/v3/kv/deleterange"
+	etcdTxnPath          = "/v3/kv/txn"
+	etcdAuthenticatePath = "/v3/auth/authenticate"
+)
+
+var _ Backend = &EtcdBackend{}
+
+// EtcdOpt is the option to create an EtcdBackend.
+type EtcdOpt struct {
+	// Endpoints are the client URLs of the etcd members, e.g. http://192.168.0.1:2379.
+	Endpoints []string
+
+	// Prefix is prepended to all keys, DefaultEtcdPrefix is used if it's empty.
+	Prefix string
+
+	// Username and Password authenticate supernode to etcd if etcd enables auth.
+	Username string
+	Password string
+
+	// Timeout is the max time of a request to etcd.
+	Timeout time.Duration
+}
+
+// EtcdBackend keeps the state in an etcd cluster shared by the supernodes.
+// Every bucket is a key range under the prefix, and the requests are sent to
+// the JSON gateway of etcd v3, so that no etcd client is required.
+type EtcdBackend struct {
+	opt *EtcdOpt
+
+	mu       sync.Mutex
+	endpoint int
+	token    string
+}
+
+// etcdKeyValue is a key-value pair in the etcd requests and responses.
+// The keys and values are encoded in base64 by encoding/json.
+type etcdKeyValue struct {
+	Key      []byte `json:"key,omitempty"`
+	RangeEnd []byte `json:"range_end,omitempty"`
+	Value    []byte `json:"value,omitempty"`
+}
+
+type etcdRangeResponse struct {
+	Kvs []*etcdKeyValue `json:"kvs,omitempty"`
+}
+
+type etcdDeleteRangeResponse struct {
+	Deleted string `json:"deleted,omitempty"`
+}
+
+type etcdRequestOp struct {
+	RequestPut         *etcdKeyValue `json:"request_put,omitempty"`
+	RequestDeleteRange *etcdKeyValue `json:"request_delete_range,omitempty"`
+}
+
+type etcdTxnRequest struct {
+	Success []*etcdRequestOp `json:"success"`
+}
+
+type etcdAuthenticateRequest struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+type etcdAuthenticateResponse struct {
+	Token string `json:"token"`
+}
+
+type etcdErrorResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// NewEtcdBackend returns a new EtcdBackend.
+func NewEtcdBackend(opt *EtcdOpt) (*EtcdBackend, error) {
+	if opt == nil || len(opt.Endpoints) == 0 {
+		return nil, errors.Wrap(errortypes.ErrEmptyValue, "etcd endpoints")
+	}
+	if stringutils.IsEmptyStr(opt.Prefix) {
+		opt.Prefix = DefaultEtcdPrefix
+	}
+	if opt.Timeout <= 0 {
+		opt.Timeout = defaultEtcdTimeout
+	}
+	return &EtcdBackend{opt: opt}, nil
+}
+
+// Put a key-value pair into the bucket.
+func (eb *EtcdBackend) Put(bucket, key string, value []byte) error {
+	if stringutils.IsEmptyStr(key) {
+		return errors.Wrap(errortypes.ErrEmptyValue, "key")
+	}
+	return eb.call(etcdPutPath, &etcdKeyValue{Key: eb.key(bucket, key), Value: value}, nil)
+}
+
+// Get the value of the key in the bucket.
+func (eb *EtcdBackend) Get(bucket, key string) ([]byte, error) {
+	resp := &etcdRangeResponse{}
+	if err := eb.call(etcdRangePath, &etcdKeyValue{Key: eb.key(bucket, key)}, resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, errors.Wrapf(errortypes.ErrDataNotFound, "bucket %s key %s", bucket, key)
+	}
+	return resp.Kvs[0].Value, nil
+}
+
+// Delete the key-value pair from the bucket.
+func (eb *EtcdBackend) Delete(bucket, key string) error {
+	resp := &etcdDeleteRangeResponse{}
+	if err := eb.call(etcdDeleteRangePath, &etcdKeyValue{Key: eb.key(bucket, key)}, resp); err != nil {
+		return err
+	}
+	if resp.Deleted == "" || resp.Deleted == "0" {
+		return errors.Wrapf(errortypes.ErrDataNotFound, "bucket %s key %s", bucket, key)
+	}
+	return nil
+}
+
+// List returns all key-value pairs in the bucket.
+func (eb *EtcdBackend) List(bucket string) (map[string][]byte, error) {
+	prefix := eb.key(bucket, "")
+	resp := &etcdRangeResponse{}
+	if err := eb.call(etcdRangePath, &etcdKeyValue{Key: prefix, RangeEnd: prefixEnd(prefix)}, resp); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]byte, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		result[strings.TrimPrefix(string(kv.Key), string(prefix))] = kv.Value
+	}
+	return result, nil
+}
+
+// Batch applies the operations in transactions of at most maxEtcdTxnOps operations.
+func (eb *EtcdBackend) Batch(ops []*Op) error {
+	for start := 0; start < len(ops); start += maxEtcdTxnOps {
+		end := start + maxEtcdTxnOps
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		txn := &etcdTxnRequest{}
+		for _, op := range ops[start:end] {
+			if stringutils.IsEmptyStr(op.Key) {
+				return errors.Wrap(errortypes.ErrEmptyValue, "key")
+			}
+			kv := &etcdKeyValue{Key: eb.key(op.Bucket, op.Key)}
+			if op.Delete {
+				txn.Success = append(txn.Success, &etcdRequestOp{RequestDeleteRange: kv})
+				continue
+			}
+			kv.Value = op.Value
+			txn.Success = append(txn.Success, &etcdRequestOp{RequestPut: kv})
+		}
+		if err := eb.call(etcdTxnPath, txn, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close does nothing for the EtcdBackend.
+func (eb *EtcdBackend) Close() error {
+	return nil
+}
+
+func (eb *EtcdBackend) key(bucket, key string) []byte {
+	return []byte(eb.opt.Prefix + bucket + "/" + key)
+}
+
+// prefixEnd returns the end of the range which contains all keys with the prefix.
+func prefixEnd(prefix []byte) []byte {
+	end := make([]byte, len(prefix))
+	copy(end, prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// the prefix is all 0xff, and "\x00" means the end of all keys.
+	return []byte{0}
+}
+
+// call sends the request to etcd and decodes the response into resp.
+// The other endpoints are tried if the current one is unreachable, and the
+// auth token is refreshed once if etcd rejects it.
+func (eb *EtcdBackend) call(path string, req, resp interface{}) error {
+	for retried := false; ; retried = true {
+		code, body, err := eb.post(path, req)
+		if err != nil {
+			return err
+		}
+		if code == http.StatusOK {
+			if resp == nil {
+				return nil
+			}
+			return json.Unmarshal(body, resp)
+		}
+
+		if retried || stringutils.IsEmptyStr(eb.opt.Username) || !isAuthError(code, body) {
+			return etcdError(path, code, body)
+		}
+		// the token may expire, and authenticate again.
+		eb.mu.Lock()
+		eb.token = ""
+		eb.mu.Unlock()
+	}
+}
+
+// post sends the request to the endpoints in turn until one of them responds.
+func (eb *EtcdBackend) post(path string, req interface{}) (int, []byte, error) {
+	eb.mu.Lock()
+	start := eb.endpoint
+	eb.mu.Unlock()
+
+	var lastErr error
+	for i := 0; i < len(eb.opt.Endpoints); i++ {
+		index := (start + i) % len(eb.opt.Endpoints)
+		endpoint := strings.TrimSuffix(eb.opt.Endpoints[index], "/")
+
+		headers, err := eb.authHeaders(endpoint)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		code, body, err := httputils.PostJSONWithHeaders(endpoint+path, headers, req, eb.opt.Timeout)
+		if err != nil {
+			lastErr = errors.Wrapf(err, "failed to send request to etcd %s", endpoint)
+			continue
+		}
+
+		eb.mu.Lock()
+		eb.endpoint = index
+		eb.mu.Unlock()
+		return code, body, nil
+	}
+	return 0, nil, lastErr
+}
+
+// authHeaders returns the headers carrying the auth token,
+// and authenticates to etcd if there is no token yet.
+func (eb *EtcdBackend) authHeaders(endpoint string) (map[string]string, error) {
+	if stringutils.IsEmptyStr(eb.opt.Username) {
+		return nil, nil
+	}
+
+	eb.mu.Lock()
+	token := eb.token
+	eb.mu.Unlock()
+	if token == "" {
+		code, body, err := httputils.PostJSONWithHeaders(endpoint+etcdAuthenticatePath, nil,
+			&etcdAuthenticateRequest{Name: eb.opt.Username, Password: eb.opt.Password}, eb.opt.Timeout)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to authenticate to etcd %s", endpoint)
+		}
+		if code != http.StatusOK {
+			return nil, etcdError(etcdAuthenticatePath, code, body)
+		}
+		resp := &etcdAuthenticateResponse{}
+		if err := json.Unmarshal(body, resp); err != nil {
+			return nil, err
+		}
+		token = resp.Token
+
+		eb.mu.Lock()
+		eb.token = token
+		eb.mu.Unlock()
+	}
+	return map[string]string{"Authorization": token}, nil
+}
+
+func etcdError(path string, code int, body []byte) error {
+	resp := &etcdErrorResponse{}
+	if err := json.Unmarshal(body, resp); err == nil && resp.Message != "" {
+		return fmt.Errorf("etcd %s failed with status %d: %s", path, code, resp.Message)
+	}
+	return fmt.Errorf("etcd %s failed with status %d: %s", path, code, string(body))
+}
+
+func isAuthError(code int, body []byte) bool {
+	return code == http.StatusUnauthorized || strings.Contains(string(body), "auth token")
+}
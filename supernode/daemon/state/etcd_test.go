@@ -0,0 +1,208 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+
+	"github.com/go-check/check"
+)
+
+type EtcdBackendTestSuite struct{}
+
+func init() {
+	check.Suite(&EtcdBackendTestSuite{})
+}
+
+// fakeEtcd serves the etcd v3 JSON gateway with the keys in memory.
+type fakeEtcd struct {
+	sync.Mutex
+	kvs   map[string][]byte
+	token string
+	txns  int
+}
+
+func newFakeEtcd(token string) *fakeEtcd {
+	return &fakeEtcd{kvs: make(map[string][]byte), token: token}
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if r.URL.Path == etcdAuthenticatePath {
+		req := &etcdAuthenticateRequest{}
+		json.NewDecoder(r.Body).Decode(req)
+		if req.Name != "supernode" || req.Password != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(&etcdErrorResponse{Message: "authentication failed"})
+			return
+		}
+		json.NewEncoder(w).Encode(&etcdAuthenticateResponse{Token: f.token})
+		return
+	}
+	if f.token != "" && r.Header.Get("Authorization") != f.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(&etcdErrorResponse{Message: "etcdserver: invalid auth token"})
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	req := &etcdKeyValue{}
+	json.Unmarshal(body, req)
+	switch r.URL.Path {
+	case etcdPutPath:
+		f.kvs[string(req.Key)] = req.Value
+		w.Write([]byte("{}"))
+	case etcdRangePath:
+		resp := &etcdRangeResponse{}
+		for k, v := range f.kvs {
+			if k == string(req.Key) || (req.RangeEnd != nil &&
+				bytes.Compare([]byte(k), req.Key) >= 0 && bytes.Compare([]byte(k), req.RangeEnd) < 0) {
+				resp.Kvs = append(resp.Kvs, &etcdKeyValue{Key: []byte(k), Value: v})
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	case etcdDeleteRangePath:
+		resp := &etcdDeleteRangeResponse{}
+		if _, ok := f.kvs[string(req.Key)]; ok {
+			delete(f.kvs, string(req.Key))
+			resp.Deleted = strconv.Itoa(1)
+		}
+		json.NewEncoder(w).Encode(resp)
+	case etcdTxnPath:
+		f.txns++
+		txn := &etcdTxnRequest{}
+		json.Unmarshal(body, txn)
+		for _, op := range txn.Success {
+			if op.RequestPut != nil {
+				f.kvs[string(op.RequestPut.Key)] = op.RequestPut.Value
+			}
+			if op.RequestDeleteRange != nil {
+				delete(f.kvs, string(op.RequestDeleteRange.Key))
+			}
+		}
+		w.Write([]byte("{}"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *EtcdBackendTestSuite) TestPutGetDelete(c *check.C) {
+	etcd := newFakeEtcd("")
+	server := httptest.NewServer(etcd)
+	defer server.Close()
+
+	eb, err := NewEtcdBackend(&EtcdOpt{Endpoints: []string{server.URL}})
+	c.Assert(err, check.IsNil)
+
+	c.Assert(eb.Put("b1", "k1", []byte("v1")), check.IsNil)
+	c.Assert(eb.Put("b1", "k2", []byte("v2")), check.IsNil)
+	c.Assert(eb.Put("b11", "k1", []byte("v3")), check.IsNil)
+	c.Check(eb.Put("b1", "", []byte("v")), check.NotNil)
+	c.Check(string(etcd.kvs[DefaultEtcdPrefix+"b1/k1"]), check.Equals, "v1")
+
+	v, err := eb.Get("b1", "k1")
+	c.Assert(err, check.IsNil)
+	c.Check(string(v), check.Equals, "v1")
+
+	list, err := eb.List("b1")
+	c.Assert(err, check.IsNil)
+	c.Check(list, check.DeepEquals, map[string][]byte{"k1": []byte("v1"), "k2": []byte("v2")})
+
+	c.Assert(eb.Delete("b1", "k1"), check.IsNil)
+	_, err = eb.Get("b1", "k1")
+	c.Check(errortypes.IsDataNotFound(err), check.Equals, true)
+	c.Check(errortypes.IsDataNotFound(eb.Delete("b1", "k1")), check.Equals, true)
+}
+
+func (s *EtcdBackendTestSuite) TestBatch(c *check.C) {
+	etcd := newFakeEtcd("")
+	server := httptest.NewServer(etcd)
+	defer server.Close()
+
+	eb, err := NewEtcdBackend(&EtcdOpt{Endpoints: []string{server.URL}})
+	c.Assert(err, check.IsNil)
+	c.Assert(eb.Put("b1", "k0", []byte("v0")), check.IsNil)
+
+	ops := []*Op{{Bucket: "b1", Key: "k0", Delete: true}}
+	for i := 1; i <= maxEtcdTxnOps; i++ {
+		ops = append(ops, &Op{Bucket: "b1", Key: fmt.Sprintf("k%d", i), Value: []byte("v")})
+	}
+	c.Assert(eb.Batch(ops), check.IsNil)
+	// the operations are split into the transactions within the limit of etcd
+	c.Check(etcd.txns, check.Equals, 2)
+	list, err := eb.List("b1")
+	c.Assert(err, check.IsNil)
+	c.Check(list, check.HasLen, maxEtcdTxnOps)
+	_, err = eb.Get("b1", "k0")
+	c.Check(errortypes.IsDataNotFound(err), check.Equals, true)
+}
+
+func (s *EtcdBackendTestSuite) TestFailover(c *check.C) {
+	etcd := newFakeEtcd("")
+	server := httptest.NewServer(etcd)
+	defer server.Close()
+	down := httptest.NewServer(etcd)
+	down.Close()
+
+	eb, err := NewEtcdBackend(&EtcdOpt{Endpoints: []string{down.URL, server.URL}})
+	c.Assert(err, check.IsNil)
+	c.Assert(eb.Put("b1", "k1", []byte("v1")), check.IsNil)
+	// the reachable endpoint is used afterwards
+	c.Check(eb.endpoint, check.Equals, 1)
+
+	_, err = NewEtcdBackend(&EtcdOpt{})
+	c.Check(err, check.NotNil)
+}
+
+func (s *EtcdBackendTestSuite) TestAuthenticate(c *check.C) {
+	etcd := newFakeEtcd("token1")
+	server := httptest.NewServer(etcd)
+	defer server.Close()
+
+	eb, err := NewEtcdBackend(&EtcdOpt{Endpoints: []string{server.URL}, Username: "supernode", Password: "secret"})
+	c.Assert(err, check.IsNil)
+	c.Assert(eb.Put("b1", "k1", []byte("v1")), check.IsNil)
+
+	// the token is refreshed after it expires
+	etcd.Lock()
+	etcd.token = "token2"
+	etcd.Unlock()
+	v, err := eb.Get("b1", "k1")
+	c.Assert(err, check.IsNil)
+	c.Check(string(v), check.Equals, "v1")
+
+	wrong, err := NewEtcdBackend(&EtcdOpt{Endpoints: []string{server.URL}, Username: "supernode", Password: "wrong"})
+	c.Assert(err, check.IsNil)
+	c.Check(wrong.Put("b1", "k1", []byte("v1")), check.NotNil)
+}
+
+func (s *EtcdBackendTestSuite) TestPrefixEnd(c *check.C) {
+	c.Check(prefixEnd([]byte("a/")), check.DeepEquals, []byte("a0"))
+	c.Check(prefixEnd([]byte{'a', 0xff}), check.DeepEquals, []byte("b"))
+	c.Check(prefixEnd([]byte{0xff}), check.DeepEquals, []byte{0})
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package state
+
+import (
+	"sync"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
+
+	"github.com/pkg/errors"
+)
+
+var _ Backend = &MemoryBackend{}
+
+// MemoryBackend keeps the state in process memory.
+// It's the default backend.
+type MemoryBackend struct {
+	sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+// NewMemoryBackend returns a new MemoryBackend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		buckets: make(map[string]map[string][]byte),
+	}
+}
+
+// Put a key-value pair into the bucket.
+func (mb *MemoryBackend) Put(bucket, key string, value []byte) error {
+	if stringutils.IsEmptyStr(key) {
+		return errors.Wrap(errortypes.ErrEmptyValue, "key")
+	}
+
+	mb.Lock()
+	defer mb.Unlock()
+
+	b, ok := mb.buckets[bucket]
+	if !ok {
+		b = make(map[string][]byte)
+		mb.buckets[bucket] = b
+	}
+	b[key] = copyBytes(value)
+	return nil
+}
+
+// Get the value of the key in the bucket.
+func (mb *MemoryBackend) Get(bucket, key string) ([]byte, error) {
+	mb.RLock()
+	defer mb.RUnlock()
+
+	v, ok := mb.buckets[bucket][key]
+	if !ok {
+		return nil, errors.Wrapf(errortypes.ErrDataNotFound, "bucket %s key %s", bucket, key)
+	}
+	return copyBytes(v), nil
+}
+
+// Delete the key-value pair from the bucket.
+func (mb *MemoryBackend) Delete(bucket, key string) error {
+	mb.Lock()
+	defer mb.Unlock()
+
+	b, ok := mb.buckets[bucket]
+	if !ok {
+		return errors.Wrapf(errortypes.ErrDataNotFound, "bucket %s key %s", bucket, key)
+	}
+	if _, ok := b[key]; !ok {
+		return errors.Wrapf(errortypes.ErrDataNotFound, "bucket %s key %s", bucket, key)
+	}
+	delete(b, key)
+	return nil
+}
+
+// List returns all key-value pairs in the bucket.
+func (mb *MemoryBackend) List(bucket string) (map[string][]byte, error) {
+	mb.RLock()
+	defer mb.RUnlock()
+
+	result := make(map[string][]byte, len(mb.buckets[bucket]))
+	for k, v := range mb.buckets[bucket] {
+		result[k] = copyBytes(v)
+	}
+	return result, nil
+}
+
+// Batch applies the operations at once.
+func (mb *MemoryBackend) Batch(ops []*Op) error {
+	for _, op := range ops {
+		if stringutils.IsEmptyStr(op.Key) {
+			return errors.Wrap(errortypes.ErrEmptyValue, "key")
+		}
+	}
+
+	mb.Lock()
+	defer mb.Unlock()
+
+	for _, op := range ops {
+		b, ok := mb.buckets[op.Bucket]
+		if op.Delete {
+			if ok {
+				delete(b, op.Key)
+			}
+			continue
+		}
+		if !ok {
+			b = make(map[string][]byte)
+			mb.buckets[op.Bucket] = b
+		}
+		b[op.Key] = copyBytes(op.Value)
+	}
+	return nil
+}
+
+// Close does nothing for the MemoryBackend.
+func (mb *MemoryBackend) Close() error {
+	return nil
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	result := make([]byte, len(b))
+	copy(result, b)
+	return result
+}
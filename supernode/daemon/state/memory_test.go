@@ -0,0 +1,79 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package state
+
+import (
+	"testing"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+
+	"github.com/go-check/check"
+)
+
+func Test(t *testing.T) {
+	check.TestingT(t)
+}
+
+type MemoryBackendTestSuite struct{}
+
+func init() {
+	check.Suite(&MemoryBackendTestSuite{})
+}
+
+func (s *MemoryBackendTestSuite) TestPutGetDelete(c *check.C) {
+	mb := NewMemoryBackend()
+
+	c.Assert(mb.Put("b1", "k1", []byte("v1")), check.IsNil)
+	c.Assert(mb.Put("b2", "k1", []byte("v2")), check.IsNil)
+	c.Check(mb.Put("b1", "", []byte("v")), check.NotNil)
+
+	v, err := mb.Get("b1", "k1")
+	c.Assert(err, check.IsNil)
+	c.Check(string(v), check.Equals, "v1")
+
+	// the returned value is a copy
+	v[0] = 'x'
+	v, _ = mb.Get("b1", "k1")
+	c.Check(string(v), check.Equals, "v1")
+
+	list, err := mb.List("b2")
+	c.Assert(err, check.IsNil)
+	c.Check(list, check.DeepEquals, map[string][]byte{"k1": []byte("v2")})
+
+	c.Assert(mb.Delete("b1", "k1"), check.IsNil)
+	_, err = mb.Get("b1", "k1")
+	c.Check(errortypes.IsDataNotFound(err), check.Equals, true)
+	c.Check(errortypes.IsDataNotFound(mb.Delete("b1", "k1")), check.Equals, true)
+}
+
+func (s *MemoryBackendTestSuite) TestBatch(c *check.C) {
+	mb := NewMemoryBackend()
+	mb.Put("b1", "k1", []byte("v1"))
+
+	c.Assert(mb.Batch([]*Op{
+		{Bucket: "b1", Key: "k1", Delete: true},
+		{Bucket: "b1", Key: "k2", Value: []byte("v2")},
+		{Bucket: "b2", Key: "k3", Delete: true},
+	}), check.IsNil)
+	list, _ := mb.List("b1")
+	c.Check(list, check.DeepEquals, map[string][]byte{"k2": []byte("v2")})
+
+	// nothing is applied if any key is empty
+	c.Check(mb.Batch([]*Op{{Bucket: "b1", Key: "k3"}, {Bucket: "b1"}}), check.NotNil)
+	_, err := mb.Get("b1", "k3")
+	c.Check(errortypes.IsDataNotFound(err), check.Equals, true)
+}
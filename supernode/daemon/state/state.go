@@ -0,0 +1,90 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package state provides the backends which keep the task, peer, dfgetTask
+// and progress state of supernode, so that several supernodes can share
+// the same state and serve the same tasks.
+package state
+
+import (
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+
+	"github.com/pkg/errors"
+)
+
+// The buckets in which the managers of supernode keep their state.
+const (
+	BucketTask               = "task"
+	BucketPeer               = "peer"
+	BucketDfgetTask          = "dfgetTask"
+	BucketPeerTask           = "peerTask"
+	BucketClientProgress     = "clientProgress"
+	BucketPieceProgress      = "pieceProgress"
+	BucketQuarantine         = "quarantine"
+	BucketCorruption         = "corruption"
+	BucketTenantTask         = "tenantTask"
+	BucketPreheatSchedule    = "preheatSchedule"
+	BucketPreheatScheduleRun = "preheatScheduleRun"
+)
+
+// Op is a write operation in a batch.
+type Op struct {
+	Bucket string
+	Key    string
+	Value  []byte
+
+	// Delete removes the key instead of putting the value,
+	// and it's not an error if the key doesn't exist.
+	Delete bool
+}
+
+// Backend stores the state as key-value pairs grouped by buckets.
+// The ErrDataNotFound error will be returned if the key cannot be found.
+type Backend interface {
+	// Put a key-value pair into the bucket.
+	Put(bucket, key string, value []byte) error
+
+	// Get the value of the key in the bucket.
+	Get(bucket, key string) ([]byte, error)
+
+	// Delete the key-value pair from the bucket.
+	Delete(bucket, key string) error
+
+	// List returns all key-value pairs in the bucket.
+	List(bucket string) (map[string][]byte, error)
+
+	// Batch applies the operations with as few writes to the backend as possible.
+	// Every key should appear only once in the operations.
+	Batch(ops []*Op) error
+
+	// Close stops the backend and releases the resources.
+	Close() error
+}
+
+// New creates the backend configured by cfg.StateBackend.
+func New(cfg *config.Config) (Backend, error) {
+	switch cfg.StateBackend {
+	case "", config.StateBackendMemory:
+		return NewMemoryBackend(), nil
+	case config.StateBackendEtcd:
+		return NewEtcdBackend(&EtcdOpt{
+			Endpoints: cfg.EtcdEndpoints,
+			Username:  cfg.EtcdUsername,
+			Password:  cfg.EtcdPassword,
+		})
+	}
+	return nil, errors.Errorf("unsupported state backend: %s", cfg.StateBackend)
+}
@@ -17,27 +17,138 @@
 package util
 
 import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
 	"github.com/dragonflyoss/Dragonfly/pkg/syncmap"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
+// DecodeFunc decodes the value stored in the state backend.
+type DecodeFunc func(data []byte) (interface{}, error)
+
 // Store maintains some metadata information in memory.
+//
+// When a state backend is given, the values are also written through to
+// the bucket of the backend and read through from it, so the supernodes
+// sharing the backend see each other's updates. The value in memory is
+// only returned while it's the same as the one in the backend.
 type Store struct {
 	*syncmap.SyncMap
+
+	backend state.Backend
+	bucket  string
+	decode  DecodeFunc
+
+	// encoded keeps the encoded values last written to or read from the backend.
+	encoded sync.Map
 }
 
 // NewStore returns a new Store.
 func NewStore() *Store {
-	return &Store{syncmap.NewSyncMap()}
+	return &Store{SyncMap: syncmap.NewSyncMap()}
+}
+
+// NewBackendStore returns a new Store backed by the bucket of the state backend.
+// It's the same as NewStore if the backend is nil.
+func NewBackendStore(backend state.Backend, bucket string, decode DecodeFunc) *Store {
+	return &Store{
+		SyncMap: syncmap.NewSyncMap(),
+		backend: backend,
+		bucket:  bucket,
+		decode:  decode,
+	}
 }
 
 // Put a key-value pair into the store.
+// The value should be put again after being modified to write it through to the backend.
 func (s *Store) Put(key string, value interface{}) error {
-	return s.Add(key, value)
+	if err := s.Add(key, value); err != nil {
+		return err
+	}
+	if s.backend == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := s.backend.Put(s.bucket, key, data); err != nil {
+		return err
+	}
+	s.encoded.Store(key, data)
+	return nil
+}
+
+// Get returns the value of the key.
+// The value is read from the backend if there is one, and the value in memory
+// is replaced when it's updated by the others. The value in memory is returned
+// if the backend fails.
+func (s *Store) Get(key string) (interface{}, error) {
+	v, err := s.SyncMap.Get(key)
+	if s.backend == nil {
+		return v, err
+	}
+
+	data, berr := s.backend.Get(s.bucket, key)
+	if berr != nil {
+		if errortypes.IsDataNotFound(berr) {
+			// deleted by the others
+			s.forget(key)
+			return nil, berr
+		}
+		logrus.Warnf("failed to get key %s in bucket %s: %v", key, s.bucket, berr)
+		return v, err
+	}
+	return s.sync(key, data)
+}
+
+// sync returns the value in memory if it's the same as the data read from the backend,
+// otherwise it decodes the data and replaces the value in memory.
+func (s *Store) sync(key string, data []byte) (interface{}, error) {
+	if v, ok := s.Load(key); ok {
+		if encoded, ok := s.encoded.Load(key); ok && bytes.Equal(encoded.([]byte), data) {
+			return v, nil
+		}
+	}
+
+	value, err := s.decode(data)
+	if err != nil {
+		logrus.Warnf("failed to decode the value of key %s in bucket %s: %v", key, s.bucket, err)
+		return nil, errors.Wrapf(errortypes.ErrSystemError, "failed to decode key %s: %v", key, err)
+	}
+	s.Store(key, value)
+	s.encoded.Store(key, data)
+	return value, nil
+}
+
+// forget removes the value in memory without touching the backend.
+func (s *Store) forget(key string) {
+	s.SyncMap.Delete(key)
+	s.encoded.Delete(key)
 }
 
 // Delete a key-value pair from the store with specified key.
 func (s *Store) Delete(key string) error {
-	return s.Remove(key)
+	err := s.Remove(key)
+	if s.backend == nil {
+		return err
+	}
+	s.encoded.Delete(key)
+
+	if berr := s.backend.Delete(s.bucket, key); berr != nil {
+		if errortypes.IsDataNotFound(berr) {
+			return err
+		}
+		return berr
+	}
+	return nil
 }
 
 // List returns all key-value pairs in the store.
@@ -48,7 +159,32 @@ func (s *Store) List() []interface{} {
 		metaSlice = append(metaSlice, value)
 		return true
 	}
-	s.Range(rangeFunc)
 
+	if s.backend == nil {
+		s.Range(rangeFunc)
+		return metaSlice
+	}
+
+	values, err := s.backend.List(s.bucket)
+	if err != nil {
+		logrus.Warnf("failed to list bucket %s: %v", s.bucket, err)
+		s.Range(rangeFunc)
+		return metaSlice
+	}
+	for key, data := range values {
+		value, err := s.sync(key, data)
+		if err != nil {
+			continue
+		}
+		metaSlice = append(metaSlice, value)
+	}
+
+	// drop the values deleted by the others
+	s.Range(func(key, value interface{}) bool {
+		if _, ok := values[key.(string)]; !ok {
+			s.forget(key.(string))
+		}
+		return true
+	})
 	return metaSlice
 }
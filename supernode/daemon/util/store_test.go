@@ -17,9 +17,11 @@
 package util
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
 
 	"github.com/go-check/check"
 )
@@ -109,3 +111,71 @@ func (s *StoreTestSuite) TestStore(c *check.C) {
 		c.Check(errortypes.IsDataNotFound(err), check.Equals, true)
 	}
 }
+
+type backendStruct struct {
+	Name  string
+	Count int
+}
+
+func decodeBackendStruct(data []byte) (interface{}, error) {
+	v := &backendStruct{}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (s *StoreTestSuite) TestBackendStore(c *check.C) {
+	backend := state.NewMemoryBackend()
+	store1 := NewBackendStore(backend, "test", decodeBackendStruct)
+	store2 := NewBackendStore(backend, "test", decodeBackendStruct)
+
+	v := &backendStruct{Name: "a", Count: 1}
+	c.Assert(store1.Put("a", v), check.IsNil)
+
+	// the value missing in memory is loaded from the backend
+	value, err := store2.Get("a")
+	c.Assert(err, check.IsNil)
+	c.Check(value, check.DeepEquals, v)
+	c.Check(store2.List(), check.HasLen, 1)
+
+	// the modified value is written through after put again
+	v.Count = 2
+	c.Assert(store1.Put("a", v), check.IsNil)
+	c.Assert(store1.Put("b", &backendStruct{Name: "b"}), check.IsNil)
+	store3 := NewBackendStore(backend, "test", decodeBackendStruct)
+	value, err = store3.Get("a")
+	c.Assert(err, check.IsNil)
+	c.Check(value.(*backendStruct).Count, check.Equals, 2)
+	c.Check(store3.List(), check.HasLen, 2)
+
+	// the value kept in memory is replaced after being updated by the others
+	value, err = store2.Get("a")
+	c.Assert(err, check.IsNil)
+	c.Check(value.(*backendStruct).Count, check.Equals, 2)
+	c.Assert(store3.Put("a", &backendStruct{Name: "a", Count: 3}), check.IsNil)
+	value, err = store2.Get("a")
+	c.Assert(err, check.IsNil)
+	c.Check(value.(*backendStruct).Count, check.Equals, 3)
+
+	// the unchanged value in memory is returned as it is
+	value, err = store3.Get("a")
+	c.Assert(err, check.IsNil)
+	c.Check(value, check.Not(check.Equals), v)
+	same, err := store3.Get("a")
+	c.Assert(err, check.IsNil)
+	c.Check(same == value, check.Equals, true)
+
+	c.Assert(store2.Delete("a"), check.IsNil)
+	_, err = backend.Get("test", "a")
+	c.Check(errortypes.IsDataNotFound(err), check.Equals, true)
+	_, err = NewBackendStore(backend, "test", decodeBackendStruct).Get("a")
+	c.Check(errortypes.IsDataNotFound(err), check.Equals, true)
+
+	// the value deleted by the others is dropped from memory
+	_, err = store1.Get("a")
+	c.Check(errortypes.IsDataNotFound(err), check.Equals, true)
+	c.Check(store1.List(), check.HasLen, 1)
+	_, ok := store1.Load("a")
+	c.Check(ok, check.Equals, false)
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/dragonflyoss/Dragonfly/pkg/netutils"
 	"github.com/dragonflyoss/Dragonfly/pkg/rangeutils"
 	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/task"
 )
 
 // RegisterResponseData is the data when registering supernode successfully.
@@ -47,6 +48,10 @@ type RegisterResponseData struct {
 
 	// in seed pattern, if as seed, SeedTaskID is the taskID of seed file.
 	SeedTaskID string `json:"seedTaskID"`
+
+	// in cluster mode, Owner is the address of the supernode which owns the task
+	// when the code is CodeTaskRedirect.
+	Owner string `json:"owner,omitempty"`
 }
 
 // PullPieceTaskResponseContinueData is the data when successfully pulling piece task
@@ -61,6 +66,18 @@ type PullPieceTaskResponseContinueData struct {
 	PeerPort  int    `json:"peerPort"`
 	Path      string `json:"path"`
 	DownLink  int    `json:"downLink"`
+
+	// Alternates are the other peers which also have the piece,
+	// dfget can send hedged requests to them when the peer above is too slow.
+	Alternates []*PieceSourceData `json:"alternates,omitempty"`
+}
+
+// PieceSourceData describes where to download a piece from.
+type PieceSourceData struct {
+	Cid      string `json:"cid"`
+	PeerIP   string `json:"peerIp"`
+	PeerPort int    `json:"peerPort"`
+	Path     string `json:"path"`
 }
 
 var statusMap = map[string]string{
@@ -102,11 +119,34 @@ func (s *Server) registry(ctx context.Context, rw http.ResponseWriter, req *http
 		return errors.Wrap(errortypes.ErrInvalidValue, err.Error())
 	}
 
+	// redirect dfget to the owner of the task in cluster mode,
+	// the clients which don't support the cluster are served locally,
+	// and the CDN downloads the file through the owner.
+	if owner := s.taskOwner(ctx, request); owner != "" {
+		logrus.Infof("redirect the registration of url %s from %s to the owner %s", request.RawURL, request.IP, owner)
+		return EncodeResponse(rw, http.StatusOK, &types.ResultInfo{
+			Code: constants.CodeTaskRedirect,
+			Msg:  constants.GetMsgByCode(constants.CodeTaskRedirect),
+			Data: &RegisterResponseData{
+				Owner: owner,
+			},
+		})
+	}
+
+	tenant, err := s.TenantMgr.GetTenant(ctx, req)
+	if err != nil {
+		return err
+	}
+
 	peerCreateRequest := &types.PeerCreateRequest{
-		IP:       request.IP,
-		HostName: strfmt.Hostname(request.HostName),
-		Port:     request.Port,
-		Version:  request.Version,
+		IDC:        request.IDC,
+		IP:         request.IP,
+		HostName:   strfmt.Hostname(request.HostName),
+		Labels:     request.Labels,
+		Port:       request.Port,
+		Tenant:     tenant,
+		UploadRate: request.UploadRate,
+		Version:    request.Version,
 	}
 	peerCreateResponse, err := s.PeerMgr.Register(ctx, peerCreateRequest)
 	if err != nil {
@@ -131,13 +171,21 @@ func (s *Server) registry(ctx context.Context, rw http.ResponseWriter, req *http
 		PeerID:      peerID,
 		RawURL:      request.RawURL,
 		TaskURL:     request.TaskURL,
+		Tenant:      tenant,
 		SupernodeIP: request.SuperNodeIP,
 		PeerPattern: DownloadPattern,
+		AsSeed:      request.AsSeed || isReportResource(req),
 	}
 	s.originClient.RegisterTLSConfig(taskCreateRequest.RawURL, request.Insecure, request.RootCAs)
 	resp, err := s.TaskMgr.Register(ctx, taskCreateRequest)
 	if err != nil {
 		logrus.Errorf("failed to register task %+v: %v", taskCreateRequest, err)
+		if errortypes.IsTenantQuotaExceeded(err) {
+			return EncodeResponse(rw, http.StatusOK, &types.ResultInfo{
+				Code: constants.CodeTenantQuotaExceeded,
+				Msg:  err.Error(),
+			})
+		}
 		return err
 	}
 	logrus.Debugf("success to register task %+v", taskCreateRequest)
@@ -149,10 +197,31 @@ func (s *Server) registry(ctx context.Context, rw http.ResponseWriter, req *http
 			FileLength: resp.FileLength,
 			PieceSize:  resp.PieceSize,
 			CDNSource:  string(resp.CdnSource),
+			AsSeed:     resp.AsSeed,
 		},
 	})
 }
 
+// taskOwner returns the address of another supernode which owns the task
+// in cluster mode, and an empty string if the task should be served by itself.
+func (s *Server) taskOwner(ctx context.Context, request *types.TaskRegisterRequest) string {
+	if !request.Cluster || s.ClusterMgr == nil || !s.ClusterMgr.Enabled() {
+		return ""
+	}
+
+	taskID := task.GenerateTaskID(&types.TaskCreateRequest{
+		Headers:    netutils.ConvertHeaders(request.Headers),
+		Identifier: request.Identifier,
+		Md5:        request.Md5,
+		RawURL:     request.RawURL,
+		TaskURL:    request.TaskURL,
+	})
+	if s.ClusterMgr.IsOwner(ctx, taskID) {
+		return ""
+	}
+	return s.ClusterMgr.Owner(ctx, taskID)
+}
+
 func (s *Server) pullPieceTask(ctx context.Context, rw http.ResponseWriter, req *http.Request) (err error) {
 	params := req.URL.Query()
 	taskID := params.Get("taskId")
@@ -211,14 +280,15 @@ func (s *Server) pullPieceTask(ctx context.Context, rw http.ResponseWriter, req
 			continue
 		}
 		datas = append(datas, &PullPieceTaskResponseContinueData{
-			Range:     v.PieceRange,
-			PieceNum:  rangeutils.CalculatePieceNum(v.PieceRange),
-			PieceSize: v.PieceSize,
-			PieceMd5:  v.PieceMD5,
-			Cid:       cid,
-			PeerIP:    v.PeerIP,
-			PeerPort:  int(v.PeerPort),
-			Path:      v.Path,
+			Range:      v.PieceRange,
+			PieceNum:   rangeutils.CalculatePieceNum(v.PieceRange),
+			PieceSize:  v.PieceSize,
+			PieceMd5:   v.PieceMD5,
+			Cid:        cid,
+			PeerIP:     v.PeerIP,
+			PeerPort:   int(v.PeerPort),
+			Path:       v.Path,
+			Alternates: s.toPieceSourceData(ctx, taskID, v.Alternates),
 		})
 	}
 	return EncodeResponse(rw, http.StatusOK, &types.ResultInfo{
@@ -227,6 +297,23 @@ func (s *Server) pullPieceTask(ctx context.Context, rw http.ResponseWriter, req
 	})
 }
 
+func (s *Server) toPieceSourceData(ctx context.Context, taskID string, alternates []*types.PieceInfo) []*PieceSourceData {
+	var sources []*PieceSourceData
+	for _, v := range alternates {
+		cid, err := s.DfgetTaskMgr.GetCIDByPeerIDAndTaskID(ctx, v.PID, taskID)
+		if err != nil {
+			continue
+		}
+		sources = append(sources, &PieceSourceData{
+			Cid:      cid,
+			PeerIP:   v.PeerIP,
+			PeerPort: int(v.PeerPort),
+			Path:     v.Path,
+		})
+	}
+	return sources
+}
+
 func (s *Server) reportPiece(ctx context.Context, rw http.ResponseWriter, req *http.Request) (err error) {
 	params := req.URL.Query()
 	taskID := params.Get("taskId")
@@ -270,6 +357,17 @@ func (s *Server) reportServiceDown(ctx context.Context, rw http.ResponseWriter,
 	if err != nil {
 		return err
 	}
+
+	// the peer only deletes the resource of the task and still serves the others.
+	if isReportResource(req) {
+		if err := s.deleteResource(ctx, taskID, dfgetTask.PeerID); err != nil {
+			return err
+		}
+		return EncodeResponse(rw, http.StatusOK, &types.ResultInfo{
+			Code: constants.CodeGetPeerDown,
+		})
+	}
+
 	if err := s.ProgressMgr.UpdatePeerServiceDown(ctx, dfgetTask.PeerID); err != nil {
 		return err
 	}
@@ -279,6 +377,31 @@ func (s *Server) reportServiceDown(ctx context.Context, rw http.ResponseWriter,
 	})
 }
 
+// deleteResource stops scheduling the pieces of the task from the peer,
+// and removes the peer from the seed nodes of the task.
+func (s *Server) deleteResource(ctx context.Context, taskID, peerID string) error {
+	if err := s.ProgressMgr.DeleteSeed(ctx, taskID, peerID); err != nil {
+		return err
+	}
+
+	task, err := s.TaskMgr.Get(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	for pieceNum := 0; pieceNum < int(task.PieceTotal); pieceNum++ {
+		if err := s.ProgressMgr.DeletePeerIDByPieceNum(ctx, taskID, pieceNum, peerID); err != nil {
+			logrus.Warnf("failed to delete peerID(%s) for pieceNum(%d) of taskID(%s): %v", peerID, pieceNum, taskID, err)
+		}
+	}
+	logrus.Infof("peerID(%s) deleted the resource of taskID(%s)", peerID, taskID)
+	return nil
+}
+
+// isReportResource returns whether the request is sent by a peer to report its resource.
+func isReportResource(req *http.Request) bool {
+	return req.Header.Get("X-report-resource") == "true"
+}
+
 func (s *Server) reportPieceError(ctx context.Context, rw http.ResponseWriter, req *http.Request) (err error) {
 	logrus.Warnf("get report piece error request %v", req)
 
@@ -19,6 +19,8 @@ package api
 import (
 	"context"
 	"net/http"
+
+	"github.com/dragonflyoss/Dragonfly/supernode/server/auth"
 )
 
 // HandlerSpec describes an HTTP api
@@ -26,6 +28,10 @@ type HandlerSpec struct {
 	Method      string
 	Path        string
 	HandlerFunc HandlerFunc
+
+	// Role is the role required to call the API when the authentication
+	// is enabled, the admin role is required if it's empty.
+	Role auth.Role
 }
 
 // HandlerFunc is the http request handler.
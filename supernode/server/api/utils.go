@@ -29,6 +29,7 @@ import (
 	"github.com/dragonflyoss/Dragonfly/apis/types"
 	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
 	"github.com/dragonflyoss/Dragonfly/pkg/util"
+	"github.com/dragonflyoss/Dragonfly/supernode/server/auth"
 )
 
 // ValidateFunc validates the request parameters.
@@ -118,6 +119,7 @@ func listHandler(name string) *HandlerSpec {
 	h := &HandlerSpec{
 		Method: http.MethodGet,
 		Path:   "/",
+		Role:   auth.RoleReadOnly,
 		HandlerFunc: func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
 			c := apiCategories[name]
 			if c == nil {
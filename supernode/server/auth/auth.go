@@ -0,0 +1,141 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+)
+
+// Role decides which APIs an identity can access.
+type Role string
+
+const (
+	// RoleAnonymous is required by the APIs which are open to everyone.
+	RoleAnonymous Role = "anonymous"
+
+	// RoleReadOnly is required by the APIs which only read the state of supernode, such as metrics.
+	RoleReadOnly Role = "readonly"
+
+	// RolePeer is required by the APIs called by dfget to download files.
+	RolePeer Role = "peer"
+
+	// RoleAdmin is required by the APIs which change the state of supernode, such as deleting tasks.
+	// An identity with the admin role can access all the APIs.
+	RoleAdmin Role = "admin"
+
+	// RoleWebhook is required by the webhook APIs called by the registries,
+	// which are authenticated by the webhook secret instead of the authenticators.
+	// It can't be granted to any identity.
+	RoleWebhook Role = "webhook"
+)
+
+// ParseRole parses the role from string.
+func ParseRole(s string) (Role, error) {
+	switch r := Role(s); r {
+	case RoleReadOnly, RolePeer, RoleAdmin:
+		return r, nil
+	}
+	return "", fmt.Errorf("invalid role %q, must be in [%s, %s, %s]", s, RoleReadOnly, RolePeer, RoleAdmin)
+}
+
+// ParseRoles parses the roles from strings.
+func ParseRoles(ss []string) ([]Role, error) {
+	roles := make([]Role, 0, len(ss))
+	for _, s := range ss {
+		r, err := ParseRole(s)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, r)
+	}
+	return roles, nil
+}
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	// Name is the name of the caller.
+	Name string
+
+	// Roles is the roles granted to the caller.
+	Roles []Role
+
+	// Authenticator is the name of the authenticator which authenticates the caller.
+	Authenticator string
+}
+
+// HasRole returns whether the identity is allowed to access the APIs which require the role.
+func (id *Identity) HasRole(role Role) bool {
+	if role == RoleAnonymous {
+		return true
+	}
+	for _, r := range id.Roles {
+		if r == role || r == RoleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator authenticates the caller of a request.
+type Authenticator interface {
+	// Authenticate returns the identity of the caller.
+	// It returns nil identity and nil error if the request doesn't carry
+	// a credential which the authenticator recognizes, so that the next
+	// authenticator will be tried.
+	Authenticate(req *http.Request) (*Identity, error)
+}
+
+// Builder creates an Authenticator with the config.
+type Builder func(cfg *config.AuthConfig) (Authenticator, error)
+
+var (
+	builders     = make(map[string]Builder)
+	buildersLock sync.RWMutex
+)
+
+// Register registers the builder of an authenticator with the name,
+// which can be used in the authenticators of the supernode config.
+// The customized authenticators are plugged into supernode by it.
+func Register(name string, builder Builder) {
+	buildersLock.Lock()
+	defer buildersLock.Unlock()
+
+	builders[name] = builder
+}
+
+func getBuilder(name string) (Builder, bool) {
+	buildersLock.RLock()
+	defer buildersLock.RUnlock()
+
+	builder, ok := builders[name]
+	return builder, ok
+}
+
+// bearerToken returns the token in the Authorization header of req.
+func bearerToken(req *http.Request) string {
+	const prefix = "Bearer "
+	header := req.Header.Get("Authorization")
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(header[len(prefix):])
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/metricsutils"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const anonymousName = "anonymous"
+
+// the results of the auth decisions.
+const (
+	resultAllowed         = "allowed"
+	resultUnauthenticated = "unauthenticated"
+	resultForbidden       = "forbidden"
+)
+
+type metrics struct {
+	decisions *prometheus.CounterVec
+}
+
+func newMetrics(register prometheus.Registerer) *metrics {
+	return &metrics{
+		decisions: metricsutils.NewCounter(config.SubsystemSupernode, "auth_decisions_total",
+			"Total number of the auth decisions of the API requests by result", []string{"result"}, register),
+	}
+}
+
+// Authorizer authenticates the API requests by the authenticators in order,
+// checks the roles required by the APIs and writes the decisions to the audit log.
+type Authorizer struct {
+	enable         bool
+	authenticators []Authenticator
+	anonymous      *Identity
+	audit          *logrus.Logger
+	metrics        *metrics
+}
+
+// NewAuthorizer returns a new Authorizer.
+// All the requests are allowed if the authentication isn't enabled in cfg.
+func NewAuthorizer(cfg *config.AuthConfig, audit *logrus.Logger, register prometheus.Registerer) (*Authorizer, error) {
+	a := &Authorizer{
+		enable:  cfg.Enable,
+		audit:   audit,
+		metrics: newMetrics(register),
+	}
+	if !cfg.Enable {
+		return a, nil
+	}
+
+	names := cfg.Authenticators
+	if len(names) == 0 {
+		names = defaultAuthenticators(cfg)
+	}
+	for _, name := range names {
+		builder, ok := getBuilder(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown authenticator: %s", name)
+		}
+		authenticator, err := builder(cfg)
+		if err != nil {
+			return nil, err
+		}
+		a.authenticators = append(a.authenticators, authenticator)
+	}
+
+	roles, err := ParseRoles(cfg.AnonymousRoles)
+	if err != nil {
+		return nil, fmt.Errorf("invalid anonymousRoles: %v", err)
+	}
+	a.anonymous = &Identity{Name: anonymousName, Roles: roles}
+	return a, nil
+}
+
+// defaultAuthenticators returns the built-in authenticators which are configured.
+func defaultAuthenticators(cfg *config.AuthConfig) []string {
+	var names []string
+	if cfg.TokenFile != "" {
+		names = append(names, config.AuthenticatorToken)
+	}
+	if cfg.HMACSecret != "" {
+		names = append(names, config.AuthenticatorHMAC)
+	}
+	return names
+}
+
+// Authorize checks whether the caller of req has the role.
+// It returns an HTTPError with 401 if the caller can't be authenticated,
+// and 403 if the caller doesn't have the role.
+// The caller of the anonymous APIs is treated as anonymous if its credential
+// can't be authenticated, e.g. it's of a scheme no authenticator recognizes.
+func (a *Authorizer) Authorize(req *http.Request, role Role) (*Identity, error) {
+	if !a.enable {
+		return nil, nil
+	}
+
+	id, err := a.authenticate(req)
+	if err != nil {
+		if role != RoleAnonymous {
+			a.record(req, role, nil, resultUnauthenticated, err.Error())
+			return nil, errortypes.NewHTTPError(http.StatusUnauthorized, err.Error())
+		}
+		id = a.anonymous
+	}
+
+	if !id.HasRole(role) {
+		reason := fmt.Sprintf("%s is not granted the %s role", id.Name, role)
+		if id == a.anonymous {
+			a.record(req, role, id, resultUnauthenticated, reason)
+			return nil, errortypes.NewHTTPError(http.StatusUnauthorized, "authentication required")
+		}
+		a.record(req, role, id, resultForbidden, reason)
+		return nil, errortypes.NewHTTPError(http.StatusForbidden, reason)
+	}
+
+	a.record(req, role, id, resultAllowed, "")
+	return id, nil
+}
+
+func (a *Authorizer) authenticate(req *http.Request) (*Identity, error) {
+	for _, authenticator := range a.authenticators {
+		id, err := authenticator.Authenticate(req)
+		if err != nil {
+			return nil, err
+		}
+		if id != nil {
+			return id, nil
+		}
+	}
+
+	if _, ok := req.Header["Authorization"]; ok {
+		return nil, fmt.Errorf("invalid credential")
+	}
+	return a.anonymous, nil
+}
+
+// record writes the decision to the audit log.
+// The requests allowed to access the APIs other than the admin ones are
+// logged at debug level, as they're sent by every dfget frequently.
+func (a *Authorizer) record(req *http.Request, role Role, id *Identity, result, reason string) {
+	a.metrics.decisions.WithLabelValues(result).Inc()
+	if a.audit == nil {
+		return
+	}
+
+	fields := logrus.Fields{
+		"remote": req.RemoteAddr,
+		"method": req.Method,
+		"path":   req.URL.Path,
+		"role":   role,
+		"result": result,
+	}
+	if id != nil {
+		fields["identity"] = id.Name
+		fields["authenticator"] = id.Authenticator
+	}
+	if reason != "" {
+		fields["reason"] = reason
+	}
+
+	entry := a.audit.WithFields(fields)
+	switch {
+	case result != resultAllowed:
+		entry.Warn("auth decision")
+	case role == RoleAdmin:
+		entry.Info("auth decision")
+	default:
+		entry.Debug("auth decision")
+	}
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+
+	"github.com/go-check/check"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+func Test(t *testing.T) {
+	check.TestingT(t)
+}
+
+func init() {
+	check.Suite(&AuthorizerTestSuite{})
+}
+
+type AuthorizerTestSuite struct {
+	workHome  string
+	tokenFile string
+}
+
+func (s *AuthorizerTestSuite) SetUpSuite(c *check.C) {
+	s.workHome, _ = ioutil.TempDir("/tmp", "supernode-AuthorizerTestSuite-")
+	s.tokenFile = filepath.Join(s.workHome, "tokens.yml")
+	err := ioutil.WriteFile(s.tokenFile, []byte(`
+- name: ops
+  token: admin-token
+  roles: [admin]
+- name: grafana
+  token: readonly-token
+  roles: [readonly]
+- name: builder
+  token: peer-token
+  roles: [peer]
+`), 0600)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *AuthorizerTestSuite) TearDownSuite(c *check.C) {
+	if s.workHome != "" {
+		os.RemoveAll(s.workHome)
+	}
+}
+
+func newRequest(token string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks/foo", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func httpCode(err error) int {
+	if e, ok := err.(*errortypes.HTTPError); ok {
+		return e.Code
+	}
+	return 0
+}
+
+func (s *AuthorizerTestSuite) TestDisabled(c *check.C) {
+	a, err := NewAuthorizer(&config.AuthConfig{}, nil, prometheus.NewRegistry())
+	c.Assert(err, check.IsNil)
+
+	id, err := a.Authorize(newRequest(""), RoleAdmin)
+	c.Assert(err, check.IsNil)
+	c.Assert(id, check.IsNil)
+}
+
+func (s *AuthorizerTestSuite) TestNewAuthorizer(c *check.C) {
+	cases := []struct {
+		cfg   *config.AuthConfig
+		valid bool
+	}{
+		{cfg: &config.AuthConfig{Enable: true}, valid: true},
+		{cfg: &config.AuthConfig{Enable: true, TokenFile: s.tokenFile}, valid: true},
+		{cfg: &config.AuthConfig{Enable: true, Authenticators: []string{config.AuthenticatorToken}}, valid: false},
+		{cfg: &config.AuthConfig{Enable: true, Authenticators: []string{config.AuthenticatorHMAC}}, valid: false},
+		{cfg: &config.AuthConfig{Enable: true, Authenticators: []string{"foo"}}, valid: false},
+		{cfg: &config.AuthConfig{Enable: true, TokenFile: filepath.Join(s.workHome, "none")}, valid: false},
+		{cfg: &config.AuthConfig{Enable: true, AnonymousRoles: []string{"foo"}}, valid: false},
+	}
+
+	for i, tc := range cases {
+		_, err := NewAuthorizer(tc.cfg, nil, prometheus.NewRegistry())
+		c.Check(err == nil, check.Equals, tc.valid, check.Commentf("case %d: %v", i, err))
+	}
+}
+
+func (s *AuthorizerTestSuite) TestAuthorize(c *check.C) {
+	secret := "secret"
+	peerToken, err := GenerateHMACToken(secret, "dfget", []Role{RolePeer}, time.Hour)
+	c.Assert(err, check.IsNil)
+	expiredToken, err := GenerateHMACToken(secret, "dfget", []Role{RolePeer}, -time.Hour)
+	c.Assert(err, check.IsNil)
+	forgedToken, err := GenerateHMACToken("other", "dfget", []Role{RoleAdmin}, time.Hour)
+	c.Assert(err, check.IsNil)
+
+	audit := &bytes.Buffer{}
+	auditLogger := logrus.New()
+	auditLogger.Out = audit
+	a, err := NewAuthorizer(&config.AuthConfig{
+		Enable:     true,
+		TokenFile:  s.tokenFile,
+		HMACSecret: secret,
+	}, auditLogger, prometheus.NewRegistry())
+	c.Assert(err, check.IsNil)
+
+	cases := []struct {
+		token string
+		role  Role
+		name  string
+		code  int
+	}{
+		{token: "", role: RoleAnonymous, name: anonymousName},
+		{token: "", role: RolePeer, code: http.StatusUnauthorized},
+		{token: "invalid", role: RoleAnonymous, name: anonymousName},
+		{token: "invalid", role: RoleReadOnly, code: http.StatusUnauthorized},
+		{token: "admin-token", role: RoleAdmin, name: "ops"},
+		{token: "admin-token", role: RolePeer, name: "ops"},
+		{token: "readonly-token", role: RoleReadOnly, name: "grafana"},
+		{token: "readonly-token", role: RolePeer, code: http.StatusForbidden},
+		{token: "peer-token", role: RolePeer, name: "builder"},
+		{token: "peer-token", role: RoleAdmin, code: http.StatusForbidden},
+		{token: peerToken, role: RolePeer, name: "dfget"},
+		{token: peerToken, role: RoleReadOnly, code: http.StatusForbidden},
+		{token: expiredToken, role: RolePeer, code: http.StatusUnauthorized},
+		{token: forgedToken, role: RolePeer, code: http.StatusUnauthorized},
+	}
+
+	for i, tc := range cases {
+		id, err := a.Authorize(newRequest(tc.token), tc.role)
+		if tc.code != 0 {
+			c.Check(httpCode(err), check.Equals, tc.code, check.Commentf("case %d", i))
+			continue
+		}
+		c.Assert(err, check.IsNil, check.Commentf("case %d", i))
+		c.Check(id.Name, check.Equals, tc.name, check.Commentf("case %d", i))
+	}
+
+	// the credential of an unrecognized scheme is treated as anonymous by the anonymous APIs
+	req := newRequest("")
+	req.Header.Set("Authorization", "Basic b3BzOnB3ZA==")
+	id, err := a.Authorize(req, RoleAnonymous)
+	c.Assert(err, check.IsNil)
+	c.Check(id.Name, check.Equals, anonymousName)
+	_, err = a.Authorize(req, RolePeer)
+	c.Check(httpCode(err), check.Equals, http.StatusUnauthorized)
+
+	c.Check(audit.String(), check.Matches, `(?s).*identity=ops.*result=allowed.*`)
+	// the allowed requests to the non-admin APIs are logged at debug level
+	c.Check(audit.String(), check.Not(check.Matches), `(?s).*identity=builder.*result=allowed.*`)
+	c.Check(audit.String(), check.Matches, `(?s).*identity=grafana.*result=forbidden.*`)
+	c.Check(audit.String(), check.Not(check.Matches), `(?s).*admin-token.*`)
+}
+
+func (s *AuthorizerTestSuite) TestAnonymousRoles(c *check.C) {
+	a, err := NewAuthorizer(&config.AuthConfig{
+		Enable:         true,
+		AnonymousRoles: []string{string(RolePeer)},
+	}, nil, prometheus.NewRegistry())
+	c.Assert(err, check.IsNil)
+
+	_, err = a.Authorize(newRequest(""), RolePeer)
+	c.Assert(err, check.IsNil)
+	_, err = a.Authorize(newRequest(""), RoleAdmin)
+	c.Assert(httpCode(err), check.Equals, http.StatusUnauthorized)
+}
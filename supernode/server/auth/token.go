@@ -0,0 +1,104 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/dragonflyoss/Dragonfly/pkg/stringutils"
+	"github.com/dragonflyoss/Dragonfly/supernode/config"
+
+	"gopkg.in/yaml.v2"
+)
+
+func init() {
+	Register(config.AuthenticatorToken, newTokenAuthenticator)
+}
+
+// staticToken is an entry of the static token file.
+type staticToken struct {
+	Name  string   `yaml:"name"`
+	Token string   `yaml:"token"`
+	Roles []string `yaml:"roles"`
+}
+
+// tokenAuthenticator authenticates the bearer tokens listed in the static token file,
+// which is a yaml list like:
+//
+//   - name: ops
+//     token: 9b6f0d4c8a
+//     roles: [admin]
+type tokenAuthenticator struct {
+	tokens     []staticToken
+	identities []*Identity
+}
+
+func newTokenAuthenticator(cfg *config.AuthConfig) (Authenticator, error) {
+	if stringutils.IsEmptyStr(cfg.TokenFile) {
+		return nil, fmt.Errorf("tokenFile is required by the %s authenticator", config.AuthenticatorToken)
+	}
+
+	data, err := ioutil.ReadFile(cfg.TokenFile)
+	if err != nil {
+		return nil, err
+	}
+	var tokens []staticToken
+	if err := yaml.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse the token file %s: %v", cfg.TokenFile, err)
+	}
+
+	ta := &tokenAuthenticator{}
+	seen := make(map[string]bool)
+	for i, t := range tokens {
+		if stringutils.IsEmptyStr(t.Name) || stringutils.IsEmptyStr(t.Token) {
+			return nil, fmt.Errorf("the name and token of the entry %d in %s are required", i, cfg.TokenFile)
+		}
+		if seen[t.Token] {
+			return nil, fmt.Errorf("the token of %s is duplicated in %s", t.Name, cfg.TokenFile)
+		}
+		seen[t.Token] = true
+
+		roles, err := ParseRoles(t.Roles)
+		if err != nil {
+			return nil, fmt.Errorf("invalid roles of %s in %s: %v", t.Name, cfg.TokenFile, err)
+		}
+		ta.tokens = append(ta.tokens, t)
+		ta.identities = append(ta.identities, &Identity{
+			Name:          t.Name,
+			Roles:         roles,
+			Authenticator: config.AuthenticatorToken,
+		})
+	}
+	return ta, nil
+}
+
+func (ta *tokenAuthenticator) Authenticate(req *http.Request) (*Identity, error) {
+	token := bearerToken(req)
+	if token == "" {
+		return nil, nil
+	}
+
+	for i, t := range ta.tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(token)) == 1 {
+			return ta.identities[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -41,6 +41,10 @@ func (s *Server) registerPeer(ctx context.Context, rw http.ResponseWriter, req *
 		return errors.Wrap(errortypes.ErrInvalidValue, err.Error())
 	}
 
+	if request.Tenant, err = s.TenantMgr.GetTenant(ctx, req); err != nil {
+		return err
+	}
+
 	resp, err := s.PeerMgr.Register(ctx, request)
 	if err != nil {
 		return err
@@ -68,7 +72,7 @@ func (s *Server) getPeer(ctx context.Context, rw http.ResponseWriter, req *http.
 		return err
 	}
 
-	return EncodeResponse(rw, http.StatusOK, peer)
+	return EncodeResponse(rw, http.StatusOK, s.withPeerScore(ctx, peer))
 }
 
 // TODO: parse filter
@@ -81,6 +85,22 @@ func (s *Server) listPeers(ctx context.Context, rw http.ResponseWriter, req *htt
 	if err != nil {
 		return err
 	}
+	for i, peer := range peerList {
+		peerList[i] = s.withPeerScore(ctx, peer)
+	}
 
 	return EncodeResponse(rw, http.StatusOK, peerList)
 }
+
+// withPeerScore returns a copy of the peer with its health score,
+// the peer is returned as it is if it has no state in the progress.
+func (s *Server) withPeerScore(ctx context.Context, peer *types.PeerInfo) *types.PeerInfo {
+	peerState, err := s.ProgressMgr.GetPeerStateByPeerID(ctx, peer.ID)
+	if err != nil {
+		return peer
+	}
+
+	result := *peer
+	result.Score = &peerState.Score
+	return &result
+}
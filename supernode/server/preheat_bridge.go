@@ -23,7 +23,9 @@ import (
 
 	"github.com/dragonflyoss/Dragonfly/apis/types"
 	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
 	"github.com/dragonflyoss/Dragonfly/supernode/server/api"
+	"github.com/dragonflyoss/Dragonfly/supernode/server/auth"
 
 	"github.com/go-openapi/strfmt"
 	"github.com/gorilla/mux"
@@ -50,7 +52,11 @@ func (s *Server) getAllPreheatTasks(ctx context.Context, rw http.ResponseWriter,
 	if err != nil {
 		return httpErr(err)
 	}
-	return EncodeResponse(rw, http.StatusOK, tasks)
+	resp := make([]*types.PreheatInfo, 0, len(tasks))
+	for _, task := range tasks {
+		resp = append(resp, preheatInfo(task))
+	}
+	return EncodeResponse(rw, http.StatusOK, resp)
 }
 
 func (s *Server) getPreheatTask(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
@@ -59,14 +65,7 @@ func (s *Server) getPreheatTask(ctx context.Context, rw http.ResponseWriter, req
 	if err != nil {
 		return httpErr(err)
 	}
-	resp := types.PreheatInfo{
-		ID:         task.ID,
-		FinishTime: strfmt.DateTime(time.Unix(task.FinishTime/1000, task.FinishTime%1000*int64(time.Millisecond)).UTC()),
-		StartTime:  strfmt.DateTime(time.Unix(task.StartTime/1000, task.StartTime%1000*int64(time.Millisecond)).UTC()),
-		Status:     task.Status,
-		ErrorMsg:   task.ErrorMsg,
-	}
-	return EncodeResponse(rw, http.StatusOK, resp)
+	return EncodeResponse(rw, http.StatusOK, preheatInfo(task))
 }
 
 func (s *Server) deletePreheatTask(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
@@ -80,6 +79,22 @@ func (s *Server) deletePreheatTask(ctx context.Context, rw http.ResponseWriter,
 // ---------------------------------------------------------------------------
 // helper functions
 
+// preheatInfo converts the preheat task to the response of the APIs.
+func preheatInfo(task *mgr.PreheatTask) *types.PreheatInfo {
+	return &types.PreheatInfo{
+		ID:                 task.ID,
+		FinishTime:         strfmt.DateTime(time.Unix(task.FinishTime/1000, task.FinishTime%1000*int64(time.Millisecond)).UTC()),
+		StartTime:          strfmt.DateTime(time.Unix(task.StartTime/1000, task.StartTime%1000*int64(time.Millisecond)).UTC()),
+		Status:             task.Status,
+		ErrorMsg:           task.ErrorMsg,
+		Error:              task.Error,
+		PieceTotal:         task.PieceTotal,
+		FinishedPieceCount: task.FinishedPieceCount,
+		Peers:              task.Peers,
+		Layers:             task.Layers,
+	}
+}
+
 func httpErr(err error) error {
 	if e, ok := err.(*errortypes.DfError); ok {
 		return errortypes.NewHTTPError(e.Code, e.Msg)
@@ -90,9 +105,9 @@ func httpErr(err error) error {
 // preheatHandlers returns all the preheats handlers.
 func preheatHandlers(s *Server) []*api.HandlerSpec {
 	return []*api.HandlerSpec{
-		{Method: http.MethodPost, Path: "/preheats", HandlerFunc: s.createPreheatTask},
-		{Method: http.MethodGet, Path: "/preheats", HandlerFunc: s.getAllPreheatTasks},
-		{Method: http.MethodGet, Path: "/preheats/{id}", HandlerFunc: s.getPreheatTask},
-		{Method: http.MethodDelete, Path: "/preheats/{id}", HandlerFunc: s.deletePreheatTask},
+		{Method: http.MethodPost, Path: "/preheats", HandlerFunc: s.createPreheatTask, Role: auth.RoleAdmin},
+		{Method: http.MethodGet, Path: "/preheats", HandlerFunc: s.getAllPreheatTasks, Role: auth.RoleReadOnly},
+		{Method: http.MethodGet, Path: "/preheats/{id}", HandlerFunc: s.getPreheatTask, Role: auth.RoleReadOnly},
+		{Method: http.MethodDelete, Path: "/preheats/{id}", HandlerFunc: s.deletePreheatTask, Role: auth.RoleAdmin},
 	}
 }
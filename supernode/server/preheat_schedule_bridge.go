@@ -0,0 +1,145 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/server/api"
+	"github.com/dragonflyoss/Dragonfly/supernode/server/auth"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/gorilla/mux"
+)
+
+// ---------------------------------------------------------------------------
+// handlers of preheat schedule http apis
+
+func (s *Server) createPreheatSchedule(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+	request := &types.PreheatScheduleCreateRequest{}
+	if err := api.ParseJSONRequest(req.Body, request, request.Validate); err != nil {
+		return err
+	}
+	scheduleID, err := s.PreheatMgr.CreateSchedule(ctx, request)
+	if err != nil {
+		return httpErr(err)
+	}
+	schedule, err := s.PreheatMgr.GetSchedule(ctx, scheduleID)
+	if err != nil {
+		return httpErr(err)
+	}
+	return EncodeResponse(rw, http.StatusCreated, preheatSchedule(schedule))
+}
+
+func (s *Server) getAllPreheatSchedules(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+	schedules, err := s.PreheatMgr.GetAllSchedules(ctx)
+	if err != nil {
+		return httpErr(err)
+	}
+	resp := make([]*types.PreheatSchedule, 0, len(schedules))
+	for _, schedule := range schedules {
+		resp = append(resp, preheatSchedule(schedule))
+	}
+	return EncodeResponse(rw, http.StatusOK, resp)
+}
+
+func (s *Server) getPreheatSchedule(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+	id := mux.Vars(req)["id"]
+	schedule, err := s.PreheatMgr.GetSchedule(ctx, id)
+	if err != nil {
+		return httpErr(err)
+	}
+	return EncodeResponse(rw, http.StatusOK, preheatSchedule(schedule))
+}
+
+func (s *Server) deletePreheatSchedule(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+	id := mux.Vars(req)["id"]
+	if err := s.PreheatMgr.DeleteSchedule(ctx, id); err != nil {
+		return httpErr(err)
+	}
+	return EncodeResponse(rw, http.StatusOK, true)
+}
+
+func (s *Server) enablePreheatSchedule(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+	return s.updatePreheatScheduleEnabled(ctx, rw, req, true)
+}
+
+func (s *Server) disablePreheatSchedule(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+	return s.updatePreheatScheduleEnabled(ctx, rw, req, false)
+}
+
+func (s *Server) updatePreheatScheduleEnabled(ctx context.Context, rw http.ResponseWriter, req *http.Request, enable bool) error {
+	id := mux.Vars(req)["id"]
+	schedule, err := s.PreheatMgr.EnableSchedule(ctx, id, enable)
+	if err != nil {
+		return httpErr(err)
+	}
+	return EncodeResponse(rw, http.StatusOK, preheatSchedule(schedule))
+}
+
+// ---------------------------------------------------------------------------
+// helper functions
+
+// preheatSchedule converts the preheat schedule to the response of the APIs.
+func preheatSchedule(schedule *mgr.PreheatSchedule) *types.PreheatSchedule {
+	runs := make([]*types.PreheatScheduleRun, 0, len(schedule.Runs))
+	for _, run := range schedule.Runs {
+		runs = append(runs, &types.PreheatScheduleRun{
+			PreheatID:  run.PreheatID,
+			URL:        run.URL,
+			Status:     run.Status,
+			StartTime:  millisToDateTime(run.StartTime),
+			FinishTime: millisToDateTime(run.FinishTime),
+			ErrorMsg:   run.ErrorMsg,
+		})
+	}
+	return &types.PreheatSchedule{
+		ID:          schedule.ID,
+		Name:        schedule.Name,
+		Cron:        schedule.Cron,
+		Preheat:     schedule.Request,
+		Enabled:     schedule.Enabled,
+		CreateTime:  millisToDateTime(schedule.CreateTime),
+		NextRunTime: millisToDateTime(schedule.NextRunTime),
+		Runs:        runs,
+	}
+}
+
+// millisToDateTime converts the time in millis to strfmt.DateTime, and 0 is
+// converted to the zero time.
+func millisToDateTime(millis int64) strfmt.DateTime {
+	if millis <= 0 {
+		return strfmt.DateTime{}
+	}
+	return strfmt.DateTime(time.Unix(millis/1000, millis%1000*int64(time.Millisecond)).UTC())
+}
+
+// preheatScheduleHandlers returns all the preheat schedule handlers.
+func preheatScheduleHandlers(s *Server) []*api.HandlerSpec {
+	return []*api.HandlerSpec{
+		{Method: http.MethodPost, Path: "/preheat-schedules", HandlerFunc: s.createPreheatSchedule, Role: auth.RoleAdmin},
+		{Method: http.MethodGet, Path: "/preheat-schedules", HandlerFunc: s.getAllPreheatSchedules, Role: auth.RoleReadOnly},
+		{Method: http.MethodGet, Path: "/preheat-schedules/{id}", HandlerFunc: s.getPreheatSchedule, Role: auth.RoleReadOnly},
+		{Method: http.MethodDelete, Path: "/preheat-schedules/{id}", HandlerFunc: s.deletePreheatSchedule, Role: auth.RoleAdmin},
+		{Method: http.MethodPost, Path: "/preheat-schedules/{id}/enable", HandlerFunc: s.enablePreheatSchedule, Role: auth.RoleAdmin},
+		{Method: http.MethodPost, Path: "/preheat-schedules/{id}/disable", HandlerFunc: s.disablePreheatSchedule, Role: auth.RoleAdmin},
+	}
+}
@@ -54,6 +54,10 @@ func NewResultInfoWithError(err error) ResultInfo {
 		return NewResultInfoWithCodeError(constants.CodeURLNotReachable, err)
 	}
 
+	if errortypes.IsTenantQuotaExceeded(err) {
+		return NewResultInfoWithCodeError(constants.CodeTenantQuotaExceeded, err)
+	}
+
 	// IsConvertFailed
 	return NewResultInfoWithCodeError(constants.CodeSystemError, err)
 }
@@ -22,7 +22,9 @@ import (
 	"net/http/pprof"
 	"strings"
 
+	"github.com/dragonflyoss/Dragonfly/pkg/tracing"
 	"github.com/dragonflyoss/Dragonfly/supernode/server/api"
+	"github.com/dragonflyoss/Dragonfly/supernode/server/auth"
 	"github.com/dragonflyoss/Dragonfly/version"
 
 	"github.com/gorilla/mux"
@@ -40,9 +42,9 @@ func createRouter(s *Server) *mux.Router {
 
 	r := mux.NewRouter()
 	if s.Config.Debug || s.Config.EnableProfiler {
-		initDebugRoutes(r)
+		initDebugRoutes(r, s)
 	}
-	initAPIRoutes(r)
+	initAPIRoutes(r, s)
 	return r
 }
 
@@ -55,35 +57,42 @@ func registerCoreHandlers(s *Server) {
 func registerV1(s *Server) {
 	v1Handlers := []*api.HandlerSpec{
 		// peer
-		{Method: http.MethodPost, Path: "/peers", HandlerFunc: s.registerPeer},
-		{Method: http.MethodDelete, Path: "/peers/{id}", HandlerFunc: s.deRegisterPeer},
-		{Method: http.MethodGet, Path: "/peers/{id}", HandlerFunc: s.getPeer},
-		{Method: http.MethodGet, Path: "/peers", HandlerFunc: s.listPeers},
-		{Method: http.MethodGet, Path: "/tasks/{id}", HandlerFunc: s.getTaskInfo},
-		{Method: http.MethodPost, Path: "/peer/network", HandlerFunc: s.fetchP2PNetworkInfo},
-		{Method: http.MethodPost, Path: "/peer/heartbeat", HandlerFunc: s.reportPeerHealth},
+		{Method: http.MethodPost, Path: "/peers", HandlerFunc: s.registerPeer, Role: auth.RolePeer},
+		{Method: http.MethodDelete, Path: "/peers/{id}", HandlerFunc: s.deRegisterPeer, Role: auth.RoleAdmin},
+		{Method: http.MethodGet, Path: "/peers/{id}", HandlerFunc: s.getPeer, Role: auth.RoleReadOnly},
+		{Method: http.MethodGet, Path: "/peers", HandlerFunc: s.listPeers, Role: auth.RoleReadOnly},
+		{Method: http.MethodGet, Path: "/tasks/{id}", HandlerFunc: s.getTaskInfo, Role: auth.RoleReadOnly},
+		{Method: http.MethodPost, Path: "/peer/network", HandlerFunc: s.fetchP2PNetworkInfo, Role: auth.RolePeer},
+		{Method: http.MethodPost, Path: "/peer/heartbeat", HandlerFunc: s.reportPeerHealth, Role: auth.RolePeer},
 
 		// task
-		{Method: http.MethodDelete, Path: "/tasks/{id}", HandlerFunc: s.deleteTask},
+		{Method: http.MethodGet, Path: "/tasks", HandlerFunc: s.listTasks, Role: auth.RoleReadOnly},
+		{Method: http.MethodGet, Path: "/tasks/{id}/distribution", HandlerFunc: s.getTaskDistribution, Role: auth.RoleReadOnly},
+		{Method: http.MethodDelete, Path: "/tasks/{id}", HandlerFunc: s.deleteTask, Role: auth.RoleAdmin},
+		{Method: http.MethodDelete, Path: "/tasks", HandlerFunc: s.deleteTasks, Role: auth.RoleAdmin},
 
 		// piece
-		{Method: http.MethodGet, Path: "/tasks/{id}/pieces/{pieceRange}/error", HandlerFunc: s.handlePieceError},
+		{Method: http.MethodGet, Path: "/tasks/{id}/pieces/{pieceRange}/error", HandlerFunc: s.handlePieceError, Role: auth.RolePeer},
 	}
 
 	api.V1.Register(v1Handlers...)
 	// add preheat APIs to v1 category
 	api.V1.Register(preheatHandlers(s)...)
+	// add preheat schedule APIs to v1 category
+	api.V1.Register(preheatScheduleHandlers(s)...)
+	// add webhook APIs to v1 category
+	api.V1.Register(webhookHandlers(s)...)
 }
 
 func registerSystem(s *Server) {
 	systemHandlers := []*api.HandlerSpec{
 		// system
-		{Method: http.MethodGet, Path: "/_ping", HandlerFunc: s.ping},
-		{Method: http.MethodGet, Path: "/version", HandlerFunc: version.HandlerWithCtx},
+		{Method: http.MethodGet, Path: "/_ping", HandlerFunc: s.ping, Role: auth.RoleAnonymous},
+		{Method: http.MethodGet, Path: "/version", HandlerFunc: version.HandlerWithCtx, Role: auth.RoleAnonymous},
 
 		// metrics
-		{Method: http.MethodGet, Path: "/metrics", HandlerFunc: handleMetrics},
-		{Method: http.MethodPost, Path: "/task/metrics", HandlerFunc: m.handleMetricsReport},
+		{Method: http.MethodGet, Path: "/metrics", HandlerFunc: handleMetrics, Role: auth.RoleReadOnly},
+		{Method: http.MethodPost, Path: "/task/metrics", HandlerFunc: m.handleMetricsReport, Role: auth.RolePeer},
 	}
 	api.Legacy.Register(systemHandlers...)
 }
@@ -91,19 +100,20 @@ func registerSystem(s *Server) {
 func registerLegacy(s *Server) {
 	legacyHandlers := []*api.HandlerSpec{
 		// v0.3
-		{Method: http.MethodPost, Path: "/peer/registry", HandlerFunc: s.registry},
-		{Method: http.MethodGet, Path: "/peer/task", HandlerFunc: s.pullPieceTask},
-		{Method: http.MethodGet, Path: "/peer/piece/suc", HandlerFunc: s.reportPiece},
-		{Method: http.MethodGet, Path: "/peer/service/down", HandlerFunc: s.reportServiceDown},
-		{Method: http.MethodGet, Path: "/peer/piece/error", HandlerFunc: s.reportPieceError},
-		{Method: http.MethodPost, Path: "/peer/network", HandlerFunc: s.fetchP2PNetworkInfo},
-		{Method: http.MethodPost, Path: "/peer/heartbeat", HandlerFunc: s.reportPeerHealth},
+		{Method: http.MethodPost, Path: "/peer/registry", HandlerFunc: s.registry, Role: auth.RolePeer},
+		{Method: http.MethodGet, Path: "/peer/task", HandlerFunc: s.pullPieceTask, Role: auth.RolePeer},
+		{Method: http.MethodGet, Path: "/peer/piece/suc", HandlerFunc: s.reportPiece, Role: auth.RolePeer},
+		{Method: http.MethodGet, Path: "/peer/service/down", HandlerFunc: s.reportServiceDown, Role: auth.RolePeer},
+		{Method: http.MethodGet, Path: "/peer/piece/error", HandlerFunc: s.reportPieceError, Role: auth.RolePeer},
+		{Method: http.MethodPost, Path: "/peer/network", HandlerFunc: s.fetchP2PNetworkInfo, Role: auth.RolePeer},
+		{Method: http.MethodPost, Path: "/peer/heartbeat", HandlerFunc: s.reportPeerHealth, Role: auth.RolePeer},
 	}
 	api.Legacy.Register(legacyHandlers...)
 	api.Legacy.Register(preheatHandlers(s)...)
+	api.Legacy.Register(preheatScheduleHandlers(s)...)
 }
 
-func initAPIRoutes(r *mux.Router) {
+func initAPIRoutes(r *mux.Router, s *Server) {
 	add := func(prefix string, h *api.HandlerSpec) {
 		path := h.Path
 		if path == "" || path[0] != '/' {
@@ -113,10 +123,10 @@ func initAPIRoutes(r *mux.Router) {
 			path = prefix + h.Path
 		}
 		r.Path(path).Methods(h.Method).
-			Handler(m.instrumentHandler(path, api.WrapHandler(h.HandlerFunc)))
+			Handler(m.instrumentHandler(path, api.WrapHandler(traceHandler(h.Method, path, s.authorize(h)))))
 		// for sdk client
 		r.Path(versionMatcher + path).Methods(h.Method).
-			Handler(m.instrumentHandler(path, api.WrapHandler(h.HandlerFunc)))
+			Handler(m.instrumentHandler(path, api.WrapHandler(traceHandler(h.Method, path, s.authorize(h)))))
 	}
 
 	api.V1.Range(add)
@@ -124,12 +134,70 @@ func initAPIRoutes(r *mux.Router) {
 	api.Legacy.Range(add)
 }
 
-func initDebugRoutes(r *mux.Router) {
-	r.PathPrefix("/debug/pprof/cmdline").HandlerFunc(pprof.Cmdline)
-	r.PathPrefix("/debug/pprof/profile").HandlerFunc(pprof.Profile)
-	r.PathPrefix("/debug/pprof/symbol").HandlerFunc(pprof.Symbol)
-	r.PathPrefix("/debug/pprof/trace").HandlerFunc(pprof.Trace)
-	r.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+func initDebugRoutes(r *mux.Router, s *Server) {
+	add := func(prefix string, f http.HandlerFunc) {
+		h := &api.HandlerSpec{
+			Role: auth.RoleAdmin,
+			HandlerFunc: func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+				f(rw, req)
+				return nil
+			},
+		}
+		r.PathPrefix(prefix).Handler(api.WrapHandler(s.authorize(h)))
+	}
+
+	add("/debug/pprof/cmdline", pprof.Cmdline)
+	add("/debug/pprof/profile", pprof.Profile)
+	add("/debug/pprof/symbol", pprof.Symbol)
+	add("/debug/pprof/trace", pprof.Trace)
+	add("/debug/pprof/", pprof.Index)
+}
+
+// authorize wraps the handler of h to check the role required by h
+// before handling the request.
+func (s *Server) authorize(h *api.HandlerSpec) api.HandlerFunc {
+	role := h.Role
+	if role == "" {
+		role = auth.RoleAdmin
+	}
+	if role == auth.RoleWebhook {
+		// the webhook handlers check the webhook secret by themselves.
+		return h.HandlerFunc
+	}
+
+	return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		if _, err := s.Authorizer.Authorize(req, role); err != nil {
+			return err
+		}
+		return h.HandlerFunc(ctx, rw, req)
+	}
+}
+
+// untracedPaths are the paths which are called periodically
+// by the probes or the monitoring system rather than dfget.
+var untracedPaths = map[string]bool{
+	"/_ping":   true,
+	"/metrics": true,
+}
+
+// traceHandler wraps the handler to trace the request as a child of the
+// span in the traceparent header of the request.
+func traceHandler(method, path string, h api.HandlerFunc) api.HandlerFunc {
+	if untracedPaths[path] {
+		return h
+	}
+	name := method + " " + path
+	return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		ctx, span := tracing.StartSpan(tracing.Extract(ctx, req.Header), name, tracing.SpanKindServer)
+		defer span.End()
+		span.SetAttribute("http.method", req.Method)
+		span.SetAttribute("http.route", path)
+		span.SetAttribute("net.peer.addr", req.RemoteAddr)
+
+		err := h(ctx, rw, req)
+		span.SetError(err)
+		return err
+	}
 }
 
 func handleMetrics(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
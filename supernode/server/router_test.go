@@ -17,18 +17,25 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"math/rand"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/dragonflyoss/Dragonfly/apis/types"
 	"github.com/dragonflyoss/Dragonfly/pkg/httputils"
+	"github.com/dragonflyoss/Dragonfly/pkg/tracing"
 	"github.com/dragonflyoss/Dragonfly/supernode/config"
 	_ "github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/cdn"
 	_ "github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/sourcecdn"
@@ -68,11 +75,16 @@ func (rs *RouterTestSuite) SetUpSuite(c *check.C) {
 			Debug:      true,
 			HomeDir:    tmpDir,
 			CDNPattern: config.CDNPatternLocal,
+			Webhook: config.WebhookConfig{
+				Enable: true,
+				Secret: "secret",
+				Rules:  []*config.WebhookRule{{Repository: "library/*", Insecure: true}},
+			},
 		},
 		Plugins:  nil,
 		Storages: nil,
 	}
-	s, err := New(testConf, logrus.StandardLogger(), prometheus.NewRegistry())
+	s, err := New(testConf, logrus.StandardLogger(), logrus.StandardLogger(), prometheus.NewRegistry())
 	c.Check(err, check.IsNil)
 	version.DFVersion = &types.DragonflyVersion{
 		Version:   "test",
@@ -151,3 +163,230 @@ func (rs *RouterTestSuite) TestHTTPMetrics(c *check.C) {
 			int(prom_testutil.ToFloat64(counter.WithLabelValues(strconv.Itoa(http.StatusOK), "/_ping"))))
 	}
 }
+
+func (rs *RouterTestSuite) TestTaskListHandler(c *check.C) {
+	for _, tc := range []struct {
+		method string
+		suffix string
+		code   int
+	}{
+		{http.MethodGet, "", 200},
+		{http.MethodGet, "?url=^http://a.com/&cdnStatus=SUCCESS,FAILED&maxAge=1h&minSize=1MB", 200},
+		{http.MethodGet, "?sortKey=fileLength&sortDirect=DESC&pageSize=10", 200},
+		{http.MethodGet, "?url=(", 400},
+		{http.MethodGet, "?cdnStatus=foo", 400},
+		{http.MethodGet, "?minAge=foo", 400},
+		{http.MethodGet, "?sortKey=foo", 400},
+
+		// a filter is required unless all is true
+		{http.MethodDelete, "", 400},
+		{http.MethodDelete, "?all=true&dryRun=true", 200},
+		{http.MethodDelete, "?tenant=foo", 200},
+
+		// the task doesn't exist
+		{http.MethodGet, "/foo/distribution", 404},
+	} {
+		req, err := http.NewRequest(tc.method, "http://"+rs.addr+"/api/v1/tasks"+tc.suffix, nil)
+		c.Assert(err, check.IsNil)
+		resp, err := http.DefaultClient.Do(req)
+		c.Assert(err, check.IsNil)
+		resp.Body.Close()
+		c.Check(resp.StatusCode, check.Equals, tc.code, check.Commentf("%s %s", tc.method, tc.suffix))
+	}
+}
+
+func (rs *RouterTestSuite) TestPreheatHandler(c *check.C) {
+	body := `{"type":"file","url":"http://127.0.0.1:1/preheat"}`
+	resp, err := http.Post("http://"+rs.addr+"/api/v1/preheats", "application/json", strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+	created := &types.PreheatCreateResponse{}
+	c.Assert(json.NewDecoder(resp.Body).Decode(created), check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusCreated)
+
+	// the preheat task fails since the url isn't reachable
+	preheat := &types.PreheatInfo{}
+	for i := 0; i < 100 && preheat.Status != types.PreheatStatusFAILED; i++ {
+		code, res, err := httputils.Get("http://"+rs.addr+"/api/v1/preheats/"+created.ID, 0)
+		c.Assert(err, check.IsNil)
+		c.Assert(code, check.Equals, http.StatusOK)
+		c.Assert(json.Unmarshal(res, preheat), check.IsNil)
+		time.Sleep(50 * time.Millisecond)
+	}
+	c.Check(preheat.Status, check.Equals, types.PreheatStatusFAILED)
+	c.Assert(preheat.Error, check.NotNil)
+	c.Check(preheat.Error.URL, check.Equals, "http://127.0.0.1:1/preheat")
+
+	code, res, err := httputils.Get("http://"+rs.addr+"/api/v1/preheats", 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(code, check.Equals, http.StatusOK)
+	var preheats []*types.PreheatInfo
+	c.Assert(json.Unmarshal(res, &preheats), check.IsNil)
+	c.Assert(len(preheats), check.Equals, 1)
+	c.Check(preheats[0].ID, check.Equals, created.ID)
+}
+
+func (rs *RouterTestSuite) TestPreheatScheduleHandler(c *check.C) {
+	url := "http://" + rs.addr + "/api/v1/preheat-schedules"
+	resp, err := http.Post(url, "application/json",
+		strings.NewReader(`{"cron":"61 * * * *","preheat":{"type":"file","url":"http://127.0.0.1:1/preheat"}}`))
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusBadRequest)
+
+	resp, err = http.Post(url, "application/json",
+		strings.NewReader(`{"name":"nightly","cron":"0 2 * * *","preheat":{"type":"file","url":"http://127.0.0.1:1/preheat"}}`))
+	c.Assert(err, check.IsNil)
+	created := &types.PreheatSchedule{}
+	c.Assert(json.NewDecoder(resp.Body).Decode(created), check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusCreated)
+	c.Check(created.Name, check.Equals, "nightly")
+	c.Check(created.Enabled, check.Equals, true)
+	c.Check(time.Time(created.NextRunTime).After(time.Now()), check.Equals, true)
+
+	resp, err = http.Post(url+"/"+created.ID+"/disable", "application/json", nil)
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusOK)
+
+	code, res, err := httputils.Get(url, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(code, check.Equals, http.StatusOK)
+	var schedules []*types.PreheatSchedule
+	c.Assert(json.Unmarshal(res, &schedules), check.IsNil)
+	c.Assert(len(schedules), check.Equals, 1)
+	c.Check(schedules[0].ID, check.Equals, created.ID)
+	c.Check(schedules[0].Enabled, check.Equals, false)
+
+	req, err := http.NewRequest(http.MethodDelete, url+"/"+created.ID, nil)
+	c.Assert(err, check.IsNil)
+	resp, err = http.DefaultClient.Do(req)
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusOK)
+
+	code, _, err = httputils.Get(url+"/"+created.ID, 0)
+	c.Assert(err, check.IsNil)
+	c.Check(code, check.Equals, http.StatusNotFound)
+}
+
+func (rs *RouterTestSuite) TestWebhookHandler(c *check.C) {
+	url := "http://" + rs.addr + "/api/v1/preheats/webhooks/quay"
+	body := `{"repository":"library/nginx","docker_url":"127.0.0.1:1/library/nginx","updated_tags":["v1"]}`
+
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusUnauthorized)
+
+	// the secret in the URL isn't accepted
+	resp, err = http.Post(url+"?secret=secret", "application/json", strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusUnauthorized)
+
+	resp, err = postWebhook(url, "secret", body)
+	c.Assert(err, check.IsNil)
+	var created []*types.PreheatCreateResponse
+	c.Assert(json.NewDecoder(resp.Body).Decode(&created), check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusOK)
+	c.Assert(len(created), check.Equals, 1)
+
+	code, res, err := httputils.Get("http://"+rs.addr+"/api/v1/preheats/"+created[0].ID, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(code, check.Equals, http.StatusOK)
+	preheat := &types.PreheatInfo{}
+	c.Assert(json.Unmarshal(res, preheat), check.IsNil)
+	c.Check(preheat.ID, check.Equals, created[0].ID)
+
+	// the images which match none of the rules are ignored
+	body = `{"repository":"dev/nginx","docker_url":"127.0.0.1:1/dev/nginx","updated_tags":["v1"]}`
+	resp, err = postWebhook(url, "secret", body)
+	c.Assert(err, check.IsNil)
+	created = nil
+	c.Assert(json.NewDecoder(resp.Body).Decode(&created), check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, http.StatusOK)
+	c.Assert(len(created), check.Equals, 0)
+}
+
+// postWebhook posts the event to the webhook with the secret in the Authorization header.
+func postWebhook(url, secret, body string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+secret)
+	return http.DefaultClient.Do(req)
+}
+
+func (rs *RouterTestSuite) TestTraceHandler(c *check.C) {
+	tmpDir, err := ioutil.TempDir("/tmp", "supernode-traceHandler-")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(tmpDir)
+	file := filepath.Join(tmpDir, "spans.json")
+	shutdown, err := tracing.Init("supernode", tracing.Config{File: file})
+	c.Assert(err, check.IsNil)
+
+	var handlerCtx context.Context
+	h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		handlerCtx = ctx
+		return errors.New("failed")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	err = traceHandler(http.MethodGet, "/api/v1/tasks", h)(context.Background(), httptest.NewRecorder(), req)
+	c.Assert(err, check.NotNil)
+
+	// the probes are not traced
+	req = httptest.NewRequest(http.MethodGet, "/_ping", nil)
+	traceHandler(http.MethodGet, "/_ping", h)(context.Background(), httptest.NewRecorder(), req)
+	c.Assert(tracing.SpanContextFromContext(handlerCtx).IsValid(), check.Equals, false)
+	shutdown()
+
+	spans, err := tracing.ReadSpans(file)
+	c.Assert(err, check.IsNil)
+	c.Assert(spans, check.HasLen, 1)
+	c.Assert(spans[0].Name, check.Equals, "GET /api/v1/tasks")
+	c.Assert(spans[0].Kind, check.Equals, tracing.SpanKindServer)
+	c.Assert(spans[0].TraceID, check.Equals, "4bf92f3577b34da6a3ce929d0e0e4736")
+	c.Assert(spans[0].ParentSpanID, check.Equals, "00f067aa0ba902b7")
+	c.Assert(spans[0].Status.Message, check.Equals, "failed")
+}
+
+type fakeClusterMgr struct {
+	owner string
+}
+
+func (m *fakeClusterMgr) StartHealthCheck(ctx context.Context) {}
+
+func (m *fakeClusterMgr) Enabled() bool {
+	return true
+}
+
+func (m *fakeClusterMgr) Owner(ctx context.Context, taskID string) string {
+	return m.owner
+}
+
+func (m *fakeClusterMgr) IsOwner(ctx context.Context, taskID string) bool {
+	return false
+}
+
+func (m *fakeClusterMgr) Members(ctx context.Context) map[string]bool {
+	return map[string]bool{m.owner: true}
+}
+
+func (rs *RouterTestSuite) TestTaskOwner(c *check.C) {
+	s := &Server{ClusterMgr: &fakeClusterMgr{owner: "127.0.0.2:8002"}}
+	req := &types.TaskRegisterRequest{RawURL: "http://a.com/b"}
+
+	// the client which doesn't support the cluster is served locally
+	c.Check(s.taskOwner(context.Background(), req), check.Equals, "")
+
+	req.Cluster = true
+	c.Check(s.taskOwner(context.Background(), req), check.Equals, "127.0.0.2:8002")
+}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/dragonflyoss/Dragonfly/supernode/config"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/cluster"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/dfgettask"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/gc"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/peer"
@@ -33,7 +34,10 @@ import (
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/progress"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/scheduler"
 	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/task"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/tenant"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/state"
 	"github.com/dragonflyoss/Dragonfly/supernode/httpclient"
+	"github.com/dragonflyoss/Dragonfly/supernode/server/auth"
 	"github.com/dragonflyoss/Dragonfly/supernode/store"
 	"github.com/dragonflyoss/Dragonfly/version"
 
@@ -53,12 +57,16 @@ type Server struct {
 	GCMgr         mgr.GCMgr
 	PieceErrorMgr mgr.PieceErrorMgr
 	PreheatMgr    mgr.PreheatManager
+	ClusterMgr    mgr.ClusterMgr
+	TenantMgr     mgr.TenantMgr
+	StateBackend  state.Backend
+	Authorizer    *auth.Authorizer
 
 	originClient httpclient.OriginHTTPClient
 }
 
 // New creates a brand new server instance.
-func New(cfg *config.Config, logger *logrus.Logger, register prometheus.Registerer) (*Server, error) {
+func New(cfg *config.Config, logger *logrus.Logger, auditLogger *logrus.Logger, register prometheus.Registerer) (*Server, error) {
 	var err error
 	// register supernode build information
 	version.NewBuildInfo("supernode", register)
@@ -74,34 +82,49 @@ func New(cfg *config.Config, logger *logrus.Logger, register prometheus.Register
 		return nil, err
 	}
 
+	stateBackend, err := state.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	originClient := httpclient.NewOriginClient()
-	peerMgr, err := peer.NewManager(register)
+	peerMgr, err := peer.NewManager(stateBackend, register)
+	if err != nil {
+		return nil, err
+	}
+
+	dfgetTaskMgr, err := dfgettask.NewManager(cfg, stateBackend, register)
 	if err != nil {
 		return nil, err
 	}
 
-	dfgetTaskMgr, err := dfgettask.NewManager(cfg, register)
+	progressMgr, err := progress.NewManager(cfg, stateBackend)
 	if err != nil {
 		return nil, err
 	}
 
-	progressMgr, err := progress.NewManager(cfg)
+	schedulerMgr, err := scheduler.NewManager(cfg, progressMgr, peerMgr)
 	if err != nil {
 		return nil, err
 	}
 
-	schedulerMgr, err := scheduler.NewManager(cfg, progressMgr)
+	clusterMgr, err := cluster.NewManager(cfg, register)
 	if err != nil {
 		return nil, err
 	}
 
-	cdnMgr, err := mgr.GetCDNManager(cfg, storeLocal, progressMgr, originClient, register)
+	cdnMgr, err := mgr.GetCDNManager(cfg, storeLocal, progressMgr, clusterMgr, originClient, register)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantMgr, err := tenant.NewManager(cfg, cdnMgr, stateBackend, register)
 	if err != nil {
 		return nil, err
 	}
 
 	taskMgr, err := task.NewManager(cfg, peerMgr, dfgetTaskMgr, progressMgr, cdnMgr,
-		schedulerMgr, originClient, register)
+		schedulerMgr, tenantMgr, originClient, stateBackend, register)
 	if err != nil {
 		return nil, err
 	}
@@ -111,12 +134,17 @@ func New(cfg *config.Config, logger *logrus.Logger, register prometheus.Register
 		return nil, err
 	}
 
-	pieceErrorMgr, err := pieceerror.NewManager(cfg, gcMgr, cdnMgr)
+	pieceErrorMgr, err := pieceerror.NewManager(cfg, gcMgr, cdnMgr, taskMgr, peerMgr, dfgetTaskMgr, progressMgr, stateBackend, register)
+	if err != nil {
+		return nil, err
+	}
+
+	preheatMgr, err := preheat.NewManager(cfg, taskMgr, cdnMgr, progressMgr, peerMgr, clusterMgr, stateBackend)
 	if err != nil {
 		return nil, err
 	}
 
-	preheatMgr, err := preheat.NewManager(cfg)
+	authorizer, err := auth.NewAuthorizer(&cfg.Auth, auditLogger, register)
 	if err != nil {
 		return nil, err
 	}
@@ -130,6 +158,10 @@ func New(cfg *config.Config, logger *logrus.Logger, register prometheus.Register
 		GCMgr:         gcMgr,
 		PieceErrorMgr: pieceErrorMgr,
 		PreheatMgr:    preheatMgr,
+		ClusterMgr:    clusterMgr,
+		TenantMgr:     tenantMgr,
+		StateBackend:  stateBackend,
+		Authorizer:    authorizer,
 		originClient:  originClient,
 	}, nil
 }
@@ -147,8 +179,11 @@ func (s *Server) Start() error {
 	}
 
 	// start to handle piece error
+	s.ProgressMgr.StartFlushProgress(context.Background())
 	s.PieceErrorMgr.StartHandleError(context.Background())
 	s.GCMgr.StartGC(context.Background())
+	s.PreheatMgr.StartSchedule(context.Background())
+	s.ClusterMgr.StartHealthCheck(context.Background())
 
 	server := &http.Server{
 		Handler:           router,
@@ -18,12 +18,44 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/pkg/fileutils"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/task"
+	dutil "github.com/dragonflyoss/Dragonfly/supernode/daemon/util"
 
 	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// the query parameters to filter the tasks.
+const (
+	taskFilterURL       = "url"
+	taskFilterCdnStatus = "cdnStatus"
+	taskFilterTenant    = "tenant"
+	taskFilterMinAge    = "minAge"
+	taskFilterMaxAge    = "maxAge"
+	taskFilterMinSize   = "minSize"
+	taskFilterMaxSize   = "maxSize"
 )
 
+var cdnStatusSet = map[string]bool{
+	types.TaskInfoCdnStatusWAITING:     true,
+	types.TaskInfoCdnStatusRUNNING:     true,
+	types.TaskInfoCdnStatusFAILED:      true,
+	types.TaskInfoCdnStatusSUCCESS:     true,
+	types.TaskInfoCdnStatusSOURCEERROR: true,
+}
+
 func (s *Server) deleteTask(ctx context.Context, rw http.ResponseWriter, req *http.Request) (err error) {
 	id := mux.Vars(req)["id"]
 	params := req.URL.Query()
@@ -44,3 +76,136 @@ func (s *Server) getTaskInfo(ctx context.Context, rw http.ResponseWriter, req *h
 	return EncodeResponse(rw, http.StatusOK, task)
 
 }
+
+// getTaskDistribution returns the distribution of the pieces of the task among the peers,
+// and the details are omitted if compact is true to fit in dashboards.
+func (s *Server) getTaskDistribution(ctx context.Context, rw http.ResponseWriter, req *http.Request) (err error) {
+	id := mux.Vars(req)["id"]
+	compact, _ := strconv.ParseBool(req.URL.Query().Get("compact"))
+
+	distribution, err := s.TaskMgr.GetDistribution(ctx, id, compact)
+	if err != nil {
+		if errortypes.IsDataNotFound(err) {
+			return errortypes.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return err
+	}
+	return EncodeResponse(rw, http.StatusOK, distribution)
+}
+
+func (s *Server) listTasks(ctx context.Context, rw http.ResponseWriter, req *http.Request) (err error) {
+	filter, err := parseTaskFilter(req)
+	if err != nil {
+		return err
+	}
+	if filter.PageFilter, err = dutil.ParseFilter(req, task.SortKeys); err != nil {
+		return errortypes.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	tasks, err := s.TaskMgr.List(ctx, filter)
+	if err != nil {
+		return err
+	}
+	return EncodeResponse(rw, http.StatusOK, tasks)
+}
+
+// deleteTasks deletes all the tasks matching the filter in the query, and
+// the cache files of the tasks are also evicted if full is true.
+// A filter is required unless all is true to avoid deleting all the tasks by mistake,
+// and the matched tasks are only returned without deleting if dryRun is true.
+func (s *Server) deleteTasks(ctx context.Context, rw http.ResponseWriter, req *http.Request) (err error) {
+	params := req.URL.Query()
+	full, _ := strconv.ParseBool(params.Get("full"))
+	all, _ := strconv.ParseBool(params.Get("all"))
+	dryRun, _ := strconv.ParseBool(params.Get("dryRun"))
+
+	if !all && !hasTaskFilter(params) {
+		return errortypes.NewHTTPError(http.StatusBadRequest, "a filter is required to delete tasks unless all is true")
+	}
+	filter, err := parseTaskFilter(req)
+	if err != nil {
+		return err
+	}
+
+	tasks, err := s.TaskMgr.List(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if !dryRun {
+		for _, t := range tasks {
+			s.GCMgr.GCTask(ctx, t.ID, full)
+		}
+		logrus.Infof("success to delete %d tasks by filter %s with full %t", len(tasks), req.URL.RawQuery, full)
+	}
+	return EncodeResponse(rw, http.StatusOK, tasks)
+}
+
+// parseTaskFilter parses the conditions to filter the tasks from the query of req.
+func parseTaskFilter(req *http.Request) (*mgr.TaskFilter, error) {
+	params := req.URL.Query()
+	filter := &mgr.TaskFilter{
+		Tenant: params.Get(taskFilterTenant),
+	}
+
+	if pattern := params.Get(taskFilterURL); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, invalidTaskFilter(taskFilterURL, err)
+		}
+		filter.URLPattern = re
+	}
+
+	for _, v := range params[taskFilterCdnStatus] {
+		for _, status := range strings.Split(v, ",") {
+			status = strings.ToUpper(strings.TrimSpace(status))
+			if !cdnStatusSet[status] {
+				return nil, invalidTaskFilter(taskFilterCdnStatus, fmt.Errorf("unknown status %s", status))
+			}
+			filter.CdnStatus = append(filter.CdnStatus, status)
+		}
+	}
+
+	for key, d := range map[string]*time.Duration{
+		taskFilterMinAge: &filter.MinAge,
+		taskFilterMaxAge: &filter.MaxAge,
+	} {
+		if v := params.Get(key); v != "" {
+			age, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, invalidTaskFilter(key, err)
+			}
+			*d = age
+		}
+	}
+
+	for key, size := range map[string]*int64{
+		taskFilterMinSize: &filter.MinSize,
+		taskFilterMaxSize: &filter.MaxSize,
+	} {
+		if v := params.Get(key); v != "" {
+			fsize, err := fileutils.StringToFSize(v)
+			if err != nil {
+				return nil, invalidTaskFilter(key, err)
+			}
+			*size = int64(fsize)
+		}
+	}
+
+	return filter, nil
+}
+
+func hasTaskFilter(params url.Values) bool {
+	for _, key := range []string{
+		taskFilterURL, taskFilterCdnStatus, taskFilterTenant,
+		taskFilterMinAge, taskFilterMaxAge, taskFilterMinSize, taskFilterMaxSize,
+	} {
+		if params.Get(key) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func invalidTaskFilter(key string, err error) error {
+	return errortypes.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid %s: %v", key, err))
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright The Dragonfly Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/dragonflyoss/Dragonfly/apis/types"
+	"github.com/dragonflyoss/Dragonfly/pkg/errortypes"
+	"github.com/dragonflyoss/Dragonfly/supernode/daemon/mgr/preheat"
+	"github.com/dragonflyoss/Dragonfly/supernode/server/api"
+	"github.com/dragonflyoss/Dragonfly/supernode/server/auth"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// maxWebhookEventSize is the max size of the body of a webhook event.
+const maxWebhookEventSize = 1 << 20
+
+// ---------------------------------------------------------------------------
+// handlers of webhook http apis
+
+// receiveWebhook creates the image preheat tasks for the images pushed in the
+// event which match the webhook rules. The images which are being preheated or
+// have been preheated are skipped.
+func (s *Server) receiveWebhook(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+	cfg := &s.Config.Webhook
+	if !cfg.Enable {
+		return errortypes.NewHTTPError(http.StatusNotFound, "webhook is disabled")
+	}
+
+	source := mux.Vars(req)["source"]
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxWebhookEventSize))
+	if err != nil {
+		return errortypes.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := preheat.VerifyWebhookSecret(source, cfg.Secret, req, body); err != nil {
+		logrus.Warnf("refuse the %s webhook event from %s: %v", source, req.RemoteAddr, err)
+		return httpErr(err)
+	}
+	images, err := preheat.ParseWebhookEvents(source, body)
+	if err != nil {
+		return httpErr(err)
+	}
+
+	resp := make([]*types.PreheatCreateResponse, 0, len(images))
+	created := make(map[string]bool)
+	for _, img := range images {
+		rule := cfg.MatchWebhookRule(img.Registry, img.Repository, img.Tag)
+		if rule == nil {
+			logrus.Debugf("ignore the image %s/%s:%s pushed to %s webhook: no rule matched",
+				img.Registry, img.Repository, img.Tag, source)
+			continue
+		}
+		url := img.ManifestURL(rule.Insecure)
+		if created[url] {
+			continue
+		}
+		created[url] = true
+
+		preheatType := "image"
+		request := &types.PreheatCreateRequest{
+			Type:      &preheatType,
+			URL:       &url,
+			Headers:   rule.Headers,
+			Platforms: rule.Platforms,
+			Referrers: rule.Referrers,
+		}
+		id, err := s.PreheatMgr.Create(ctx, request)
+		if e, ok := err.(*errortypes.DfError); ok && e.Code == http.StatusAlreadyReported {
+			logrus.Infof("skip the image %s pushed to %s webhook: %s", url, source, e.Msg)
+			continue
+		}
+		if err != nil {
+			return httpErr(err)
+		}
+		logrus.Infof("preheat the image %s pushed to %s webhook, id: %s", url, source, id)
+		resp = append(resp, &types.PreheatCreateResponse{ID: id})
+	}
+	return EncodeResponse(rw, http.StatusOK, resp)
+}
+
+// webhookHandlers returns all the webhook handlers.
+func webhookHandlers(s *Server) []*api.HandlerSpec {
+	return []*api.HandlerSpec{
+		{Method: http.MethodPost, Path: "/preheats/webhooks/{source}", HandlerFunc: s.receiveWebhook, Role: auth.RoleWebhook},
+	}
+}